@@ -0,0 +1,160 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// AbsoluteCaptureTime is the time a frame was captured, sent in the
+// abs-capture-time header extension. Receivers use it to measure the
+// end-to-end latency, and to synchronize streams that went through different
+// relays.
+type AbsoluteCaptureTime struct {
+	// Timestamp is the wallclock time of the capturer when the frame was
+	// captured
+	Timestamp time.Time
+
+	// ClockOffset is the estimated offset of the clock of the capturer to the
+	// clock of the sender, added to Timestamp to get the capture time in the
+	// clock of the sender. Relays forwarding the frames of another capturer
+	// set it, it is nil when the sender captured the frame.
+	ClockOffset *time.Duration
+}
+
+func (c AbsoluteCaptureTime) marshal() []byte {
+	b := make([]byte, 8, 16)
+	binary.BigEndian.PutUint64(b, ntpTimestamp(c.Timestamp))
+	if c.ClockOffset != nil {
+		// Signed 32.32 fixed point seconds
+		offset := int64(*c.ClockOffset/time.Second)<<32 + int64(*c.ClockOffset%time.Second)<<32/int64(time.Second)
+		b = b[:16]
+		binary.BigEndian.PutUint64(b[8:], uint64(offset))
+	}
+	return b
+}
+
+func unmarshalAbsoluteCaptureTime(b []byte) (AbsoluteCaptureTime, bool) {
+	if len(b) != 8 && len(b) != 16 {
+		return AbsoluteCaptureTime{}, false
+	}
+
+	c := AbsoluteCaptureTime{Timestamp: ntpTime(binary.BigEndian.Uint64(b))}
+	if len(b) == 16 {
+		offset := int64(binary.BigEndian.Uint64(b[8:]))
+		d := time.Duration(offset>>32)*time.Second + time.Duration((offset&0xFFFFFFFF)*int64(time.Second)>>32)
+		c.ClockOffset = &d
+	}
+	return c, true
+}
+
+// captureTimeStamper picks the packets of an RTPSender that carry the capture
+// time: the first packet of a frame every absCaptureTimeInterval. Receivers
+// extrapolate the capture time of the other frames from their RTP timestamps.
+type captureTimeStamper struct {
+	mu sync.Mutex
+
+	started   bool
+	timestamp uint32
+	lastSent  time.Time
+}
+
+// stamp returns the capture time to send with header, the time the first
+// packet of its frame was sent at, false if header doesn't carry one
+func (s *captureTimeStamper) stamp(header *rtp.Header, now time.Time) (AbsoluteCaptureTime, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if header.Padding || s.started && header.Timestamp == s.timestamp {
+		return AbsoluteCaptureTime{}, false
+	}
+	s.started = true
+	s.timestamp = header.Timestamp
+	if !s.lastSent.IsZero() && now.Sub(s.lastSent) < absCaptureTimeInterval {
+		return AbsoluteCaptureTime{}, false
+	}
+	s.lastSent = now
+	return AbsoluteCaptureTime{Timestamp: now}, true
+}
+
+// setCaptureTimeExtensionID makes the sender send the capture time of its
+// frames in the header extension with the id the remote negotiated, 0 for
+// none
+func (r *RTPSender) setCaptureTimeExtensionID(id uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.captureTimeExtensionID = id
+}
+
+// AbsoluteCaptureTime returns the capture time of the last packet read that
+// carried one, when the remote negotiated the abs-capture-time header
+// extension. It reports false until one arrived.
+func (t *Track) AbsoluteCaptureTime() (AbsoluteCaptureTime, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.captureTime == nil {
+		return AbsoluteCaptureTime{}, false
+	}
+	return *t.captureTime, true
+}
+
+// EndToEndLatency returns the time from the capture of the last frame that
+// carried its capture time to the read of its packet. The ClockOffset of
+// relays is accounted for, but the clock of the sender is assumed to be
+// synchronized with the local one, like with NTP. It reports false until a
+// capture time arrived.
+func (t *Track) EndToEndLatency() (time.Duration, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.latency, t.captureTime != nil
+}
+
+// observeCaptureTime updates the capture time and the latency with the
+// capture time of a packet read at now
+func (t *Track) observeCaptureTime(captureTime AbsoluteCaptureTime, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	captured := captureTime.Timestamp
+	if captureTime.ClockOffset != nil {
+		captured = captured.Add(*captureTime.ClockOffset)
+	}
+	t.captureTime = &captureTime
+	t.latency = now.Sub(captured)
+}
+
+// collectLatencyStats adds the InboundRTPStreamStats of the remote Tracks
+// that measured their end-to-end latency
+func (pc *PeerConnection) collectLatencyStats(collector *statsReportCollector) {
+	for _, transceiver := range pc.GetTransceivers() {
+		receiver := transceiver.Receiver()
+		if receiver == nil {
+			continue
+		}
+		track := receiver.Track()
+		if track == nil {
+			continue
+		}
+		latency, ok := track.EndToEndLatency()
+		if !ok {
+			continue
+		}
+
+		collector.Collecting()
+		stats := InboundRTPStreamStats{
+			Timestamp:       statsTimestampNow(),
+			Type:            StatsTypeInboundRTP,
+			ID:              fmt.Sprintf("InboundRTPStream-%d", track.SSRC()),
+			SSRC:            track.SSRC(),
+			Kind:            track.Kind().String(),
+			EndToEndLatency: latency.Seconds(),
+		}
+		collector.Collect(stats.ID, stats)
+	}
+}
@@ -0,0 +1,85 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbsoluteCaptureTimeMarshal(t *testing.T) {
+	captured := time.Date(2020, 7, 1, 12, 0, 0, 500000000, time.UTC)
+
+	c, ok := unmarshalAbsoluteCaptureTime(AbsoluteCaptureTime{Timestamp: captured}.marshal())
+	assert.True(t, ok)
+	assert.True(t, captured.Equal(c.Timestamp))
+	assert.Nil(t, c.ClockOffset)
+
+	for _, offset := range []time.Duration{1500 * time.Millisecond, -250 * time.Millisecond} {
+		offset := offset
+		b := AbsoluteCaptureTime{Timestamp: captured, ClockOffset: &offset}.marshal()
+		assert.Len(t, b, 16)
+		c, ok = unmarshalAbsoluteCaptureTime(b)
+		assert.True(t, ok)
+		assert.InDelta(t, offset, *c.ClockOffset, float64(time.Microsecond))
+	}
+
+	_, ok = unmarshalAbsoluteCaptureTime([]byte{0x01})
+	assert.False(t, ok)
+}
+
+func TestRTPSenderCaptureTime(t *testing.T) {
+	s := &RTPSender{}
+	header := &rtp.Header{Version: 2, SSRC: 1000, Timestamp: 3000}
+	assert.Equal(t, header, s.withHeaderExtensions(header), "nothing negotiated")
+
+	s.setCaptureTimeExtensionID(8)
+	first := s.withHeaderExtensions(header)
+	assert.Len(t, first.GetExtension(8), 8)
+	assert.False(t, header.Extension, "the header of the Track is shared")
+
+	// The first packet of the next frame within the interval carries none
+	next := &rtp.Header{Version: 2, SSRC: 1000, Timestamp: 6000}
+	assert.Equal(t, next, s.withHeaderExtensions(next))
+
+	now := time.Now()
+	stamper := &captureTimeStamper{}
+	c, ok := stamper.stamp(&rtp.Header{Timestamp: 3000}, now)
+	assert.True(t, ok)
+	assert.Equal(t, now, c.Timestamp)
+	_, ok = stamper.stamp(&rtp.Header{Timestamp: 3000}, now.Add(2*time.Second))
+	assert.False(t, ok, "not the first packet of the frame")
+	_, ok = stamper.stamp(&rtp.Header{Timestamp: 6000, Padding: true}, now.Add(2*time.Second))
+	assert.False(t, ok, "padding")
+	_, ok = stamper.stamp(&rtp.Header{Timestamp: 6000}, now.Add(2*time.Second))
+	assert.True(t, ok)
+}
+
+func TestTrackEndToEndLatency(t *testing.T) {
+	track := &Track{kind: RTPCodecTypeAudio, headerExtensions: []RTPHeaderExtensionParameter{{URI: absCaptureTimeURI, ID: 8}}}
+	_, ok := track.EndToEndLatency()
+	assert.False(t, ok)
+
+	p := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 1000}, Payload: []byte{0x00}}
+	captured := time.Now().Add(-time.Second)
+	assert.NoError(t, p.SetExtension(8, AbsoluteCaptureTime{Timestamp: captured}.marshal()))
+	b, err := p.Marshal()
+	assert.NoError(t, err)
+	track.observeHeaderExtensions(b)
+
+	latency, ok := track.EndToEndLatency()
+	assert.True(t, ok)
+	assert.InDelta(t, time.Second, latency, float64(100*time.Millisecond))
+	c, ok := track.AbsoluteCaptureTime()
+	assert.True(t, ok)
+	assert.InDelta(t, 0, c.Timestamp.Sub(captured), float64(time.Microsecond))
+
+	// A relay corrects the capture time by the offset of its clock
+	offset := 400 * time.Millisecond
+	track.observeCaptureTime(AbsoluteCaptureTime{Timestamp: captured, ClockOffset: &offset}, captured.Add(time.Second))
+	latency, _ = track.EndToEndLatency()
+	assert.Equal(t, 600*time.Millisecond, latency)
+}
@@ -13,11 +13,12 @@ import (
 type API struct {
 	settingEngine *SettingEngine
 	mediaEngine   *MediaEngine
+	hooks         *Hooks
 }
 
 // NewAPI Creates a new API object for keeping semi-global settings to WebRTC objects
 func NewAPI(options ...func(*API)) *API {
-	a := &API{}
+	a := &API{hooks: &Hooks{}}
 
 	for _, o := range options {
 		o(a)
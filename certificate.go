@@ -10,8 +10,10 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/pion/dtls/v2/pkg/crypto/fingerprint"
@@ -95,9 +97,11 @@ func (c Certificate) Expires() time.Time {
 // is computed with the digest algorithm used in the certificate signature.
 func (c Certificate) GetFingerprints() ([]DTLSFingerprint, error) {
 	fingerprintAlgorithms := []crypto.Hash{crypto.SHA256}
-	res := make([]DTLSFingerprint, len(fingerprintAlgorithms))
+	if hash := signatureHash(c.x509Cert.SignatureAlgorithm); hash != 0 && hash != crypto.SHA256 {
+		fingerprintAlgorithms = append(fingerprintAlgorithms, hash)
+	}
 
-	i := 0
+	res := make([]DTLSFingerprint, 0, len(fingerprintAlgorithms))
 	for _, algo := range fingerprintAlgorithms {
 		name, err := fingerprint.StringFromHash(algo)
 		if err != nil {
@@ -107,13 +111,30 @@ func (c Certificate) GetFingerprints() ([]DTLSFingerprint, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create fingerprint: %v", err)
 		}
-		res[i] = DTLSFingerprint{
+		res = append(res, DTLSFingerprint{
 			Algorithm: name,
 			Value:     value,
-		}
+		})
 	}
 
-	return res[:i+1], nil
+	return res, nil
+}
+
+// signatureHash returns the digest algorithm of a signature algorithm, or 0
+// if it has none fingerprints can be computed with
+func signatureHash(algo x509.SignatureAlgorithm) crypto.Hash {
+	switch algo {
+	case x509.SHA1WithRSA, x509.ECDSAWithSHA1:
+		return crypto.SHA1
+	case x509.SHA256WithRSA, x509.SHA256WithRSAPSS, x509.ECDSAWithSHA256:
+		return crypto.SHA256
+	case x509.SHA384WithRSA, x509.SHA384WithRSAPSS, x509.ECDSAWithSHA384:
+		return crypto.SHA384
+	case x509.SHA512WithRSA, x509.SHA512WithRSAPSS, x509.ECDSAWithSHA512:
+		return crypto.SHA512
+	default:
+		return 0
+	}
 }
 
 // GenerateCertificate causes the creation of an X.509 certificate and
@@ -157,3 +178,70 @@ func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
 func CertificateFromX509(privateKey crypto.PrivateKey, certificate *x509.Certificate) Certificate {
 	return Certificate{privateKey, certificate}
 }
+
+// X509Certificate returns the x509 certificate of c
+func (c Certificate) X509Certificate() *x509.Certificate {
+	return c.x509Cert
+}
+
+// PEM encodes the private key and the x509 certificate of c, so they can be
+// stored and reused later on with CertificateFromPEM. Reusing a Certificate
+// keeps the fingerprints of the PeerConnections stable.
+func (c Certificate) PEM() (string, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(c.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err = pem.Encode(&b, &pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}); err != nil {
+		return "", err
+	}
+	if err = pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: c.x509Cert.Raw}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// CertificateFromPEM creates a Certificate from the PEM encoded private key
+// and x509 certificate in pems, like the output of Certificate.PEM. PKCS #8,
+// SEC 1 EC and PKCS #1 RSA private keys are accepted.
+func CertificateFromPEM(pems string) (*Certificate, error) {
+	var privateKey crypto.PrivateKey
+	var cert *x509.Certificate
+
+	rest := []byte(pems)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "CERTIFICATE":
+			cert, err = x509.ParseCertificate(block.Bytes)
+		}
+		if err != nil {
+			return nil, &rtcerr.InvalidAccessError{Err: err}
+		}
+	}
+
+	if privateKey == nil || cert == nil {
+		return nil, &rtcerr.InvalidAccessError{Err: ErrCertificatePEM}
+	}
+	switch privateKey.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+	default:
+		return nil, &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}
+	}
+
+	certificate := CertificateFromX509(privateKey, cert)
+	return &certificate, nil
+}
@@ -0,0 +1,499 @@
+// +build !js
+
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef struct {
+	// candidate is the JSON of an RTCIceCandidateInit, NULL once gathering completed
+	void (*on_ice_candidate)(void *user, const char *candidate);
+	void (*on_connection_state_change)(void *user, const char *state);
+	// track is a handle to release with webrtc_release
+	void (*on_track)(void *user, uintptr_t track, const char *kind, const char *codec);
+	// data_channel is a handle to release with webrtc_release, its callbacks
+	// are set with webrtc_data_channel_set_callbacks
+	void (*on_data_channel)(void *user, uintptr_t data_channel, const char *label);
+} webrtc_peerconnection_callbacks;
+
+typedef struct {
+	void (*on_open)(void *user);
+	void (*on_close)(void *user);
+	void (*on_message)(void *user, const void *data, int len, int is_string);
+} webrtc_data_channel_callbacks;
+
+// Go can't call C function pointers, these call the ones that are set
+
+static void call_on_ice_candidate(webrtc_peerconnection_callbacks *c, void *user, const char *candidate) {
+	if (c->on_ice_candidate) c->on_ice_candidate(user, candidate);
+}
+
+static void call_on_connection_state_change(webrtc_peerconnection_callbacks *c, void *user, const char *state) {
+	if (c->on_connection_state_change) c->on_connection_state_change(user, state);
+}
+
+static int call_on_track(webrtc_peerconnection_callbacks *c, void *user, uintptr_t track, const char *kind, const char *codec) {
+	if (!c->on_track) return 0;
+	c->on_track(user, track, kind, codec);
+	return 1;
+}
+
+static int call_on_data_channel(webrtc_peerconnection_callbacks *c, void *user, uintptr_t data_channel, const char *label) {
+	if (!c->on_data_channel) return 0;
+	c->on_data_channel(user, data_channel, label);
+	return 1;
+}
+
+static void call_on_open(webrtc_data_channel_callbacks *c, void *user) {
+	if (c->on_open) c->on_open(user);
+}
+
+static void call_on_close(webrtc_data_channel_callbacks *c, void *user) {
+	if (c->on_close) c->on_close(user);
+}
+
+static void call_on_message(webrtc_data_channel_callbacks *c, void *user, const void *data, int len, int is_string) {
+	if (c->on_message) c->on_message(user, data, len, is_string);
+}
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"errors"
+	"unsafe"
+
+	"github.com/pion/randutil"
+	"github.com/pion/webrtc/v2"
+)
+
+var (
+	errUnknownCodec  = errors.New("libwebrtc: unknown codec")
+	errNullCallbacks = errors.New("libwebrtc: callbacks must not be NULL")
+)
+
+// objects are the values C has handles to
+var objects = &handles{}
+
+// configuration is the JSON of an RTCConfiguration, the members
+// libwebrtc supports
+type configuration struct {
+	ICEServers []struct {
+		URLs       []string `json:"urls"`
+		Username   string   `json:"username"`
+		Credential string   `json:"credential"`
+	} `json:"iceServers"`
+	ICETransportPolicy string `json:"iceTransportPolicy"`
+}
+
+func (c configuration) toWebRTC() webrtc.Configuration {
+	config := webrtc.Configuration{}
+	for _, server := range c.ICEServers {
+		config.ICEServers = append(config.ICEServers, webrtc.ICEServer{
+			URLs:           server.URLs,
+			Username:       server.Username,
+			Credential:     server.Credential,
+			CredentialType: webrtc.ICECredentialTypePassword,
+		})
+	}
+	if c.ICETransportPolicy != "" {
+		config.ICETransportPolicy = webrtc.NewICETransportPolicy(c.ICETransportPolicy)
+	}
+	return config
+}
+
+type peerConnection struct {
+	pc        *webrtc.PeerConnection
+	media     *webrtc.MediaEngine
+	callbacks C.webrtc_peerconnection_callbacks
+	user      unsafe.Pointer
+}
+
+type dataChannel struct {
+	d *webrtc.DataChannel
+}
+
+func cError(err error) *C.char {
+	if err == nil {
+		return nil
+	}
+	return C.CString(err.Error())
+}
+
+func getPeerConnection(handle C.uintptr_t) (*peerConnection, error) {
+	v, err := objects.get(uintptr(handle))
+	if err != nil {
+		return nil, err
+	}
+	p, ok := v.(*peerConnection)
+	if !ok {
+		return nil, errInvalidHandle
+	}
+	return p, nil
+}
+
+func getTrack(handle C.uintptr_t) (*webrtc.Track, error) {
+	v, err := objects.get(uintptr(handle))
+	if err != nil {
+		return nil, err
+	}
+	t, ok := v.(*webrtc.Track)
+	if !ok {
+		return nil, errInvalidHandle
+	}
+	return t, nil
+}
+
+func getDataChannel(handle C.uintptr_t) (*dataChannel, error) {
+	v, err := objects.get(uintptr(handle))
+	if err != nil {
+		return nil, err
+	}
+	d, ok := v.(*dataChannel)
+	if !ok {
+		return nil, errInvalidHandle
+	}
+	return d, nil
+}
+
+//export webrtc_free
+func webrtc_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// webrtc_release releases the handle of a Track or DataChannel
+//
+//export webrtc_release
+func webrtc_release(handle C.uintptr_t) *C.char {
+	return cError(objects.remove(uintptr(handle)))
+}
+
+// webrtc_peerconnection_new creates a PeerConnection with the default
+// codecs from the JSON of an RTCConfiguration, NULL for the default one.
+// callbacks is copied, the members can be NULL.
+//
+//export webrtc_peerconnection_new
+func webrtc_peerconnection_new(configJSON *C.char, callbacks *C.webrtc_peerconnection_callbacks, user unsafe.Pointer, handle *C.uintptr_t) *C.char {
+	config := configuration{}
+	if configJSON != nil {
+		if err := json.Unmarshal([]byte(C.GoString(configJSON)), &config); err != nil {
+			return cError(err)
+		}
+	}
+
+	media := &webrtc.MediaEngine{}
+	media.RegisterDefaultCodecs()
+	pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(*media)).NewPeerConnection(config.toWebRTC())
+	if err != nil {
+		return cError(err)
+	}
+
+	p := &peerConnection{pc: pc, media: media, user: user}
+	if callbacks != nil {
+		p.callbacks = *callbacks
+	}
+	p.handleEvents()
+	*handle = C.uintptr_t(objects.add(p))
+	return nil
+}
+
+func (p *peerConnection) handleEvents() {
+	p.pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		if c == nil {
+			C.call_on_ice_candidate(&p.callbacks, p.user, nil)
+			return
+		}
+		candidate, err := json.Marshal(c.ToJSON())
+		if err != nil {
+			return
+		}
+		cCandidate := C.CString(string(candidate))
+		defer C.free(unsafe.Pointer(cCandidate))
+		C.call_on_ice_candidate(&p.callbacks, p.user, cCandidate)
+	})
+	p.pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		cState := C.CString(state.String())
+		defer C.free(unsafe.Pointer(cState))
+		C.call_on_connection_state_change(&p.callbacks, p.user, cState)
+	})
+	p.pc.OnTrack(func(track *webrtc.Track, _ *webrtc.RTPReceiver) {
+		handle := objects.add(track)
+		cKind, cCodec := C.CString(track.Kind().String()), C.CString(track.Codec().Name)
+		defer C.free(unsafe.Pointer(cKind))
+		defer C.free(unsafe.Pointer(cCodec))
+		if C.call_on_track(&p.callbacks, p.user, C.uintptr_t(handle), cKind, cCodec) == 0 {
+			_ = objects.remove(handle)
+		}
+	})
+	p.pc.OnDataChannel(func(d *webrtc.DataChannel) {
+		handle := objects.add(&dataChannel{d: d})
+		cLabel := C.CString(d.Label())
+		defer C.free(unsafe.Pointer(cLabel))
+		if C.call_on_data_channel(&p.callbacks, p.user, C.uintptr_t(handle), cLabel) == 0 {
+			_ = objects.remove(handle)
+		}
+	})
+}
+
+// webrtc_peerconnection_close closes the PeerConnection and releases its
+// handle
+//
+//export webrtc_peerconnection_close
+func webrtc_peerconnection_close(handle C.uintptr_t) *C.char {
+	p, err := getPeerConnection(handle)
+	if err != nil {
+		return cError(err)
+	}
+	_ = objects.remove(uintptr(handle))
+	return cError(p.pc.Close())
+}
+
+// webrtc_peerconnection_create_offer creates the JSON of an offer, to free
+// with webrtc_free
+//
+//export webrtc_peerconnection_create_offer
+func webrtc_peerconnection_create_offer(handle C.uintptr_t, offer **C.char) *C.char {
+	p, err := getPeerConnection(handle)
+	if err != nil {
+		return cError(err)
+	}
+	desc, err := p.pc.CreateOffer(nil)
+	if err != nil {
+		return cError(err)
+	}
+	return marshalDescription(desc, offer)
+}
+
+// webrtc_peerconnection_create_answer creates the JSON of an answer to the
+// remote offer, to free with webrtc_free
+//
+//export webrtc_peerconnection_create_answer
+func webrtc_peerconnection_create_answer(handle C.uintptr_t, answer **C.char) *C.char {
+	p, err := getPeerConnection(handle)
+	if err != nil {
+		return cError(err)
+	}
+	desc, err := p.pc.CreateAnswer(nil)
+	if err != nil {
+		return cError(err)
+	}
+	return marshalDescription(desc, answer)
+}
+
+func marshalDescription(desc webrtc.SessionDescription, out **C.char) *C.char {
+	b, err := json.Marshal(desc)
+	if err != nil {
+		return cError(err)
+	}
+	*out = C.CString(string(b))
+	return nil
+}
+
+// webrtc_peerconnection_set_local_description sets the local offer or
+// answer, from its JSON
+//
+//export webrtc_peerconnection_set_local_description
+func webrtc_peerconnection_set_local_description(handle C.uintptr_t, descJSON *C.char) *C.char {
+	p, err := getPeerConnection(handle)
+	if err != nil {
+		return cError(err)
+	}
+	desc := webrtc.SessionDescription{}
+	if err = json.Unmarshal([]byte(C.GoString(descJSON)), &desc); err != nil {
+		return cError(err)
+	}
+	return cError(p.pc.SetLocalDescription(desc))
+}
+
+// webrtc_peerconnection_set_remote_description sets the offer or answer of
+// the remote, from its JSON
+//
+//export webrtc_peerconnection_set_remote_description
+func webrtc_peerconnection_set_remote_description(handle C.uintptr_t, descJSON *C.char) *C.char {
+	p, err := getPeerConnection(handle)
+	if err != nil {
+		return cError(err)
+	}
+	desc := webrtc.SessionDescription{}
+	if err = json.Unmarshal([]byte(C.GoString(descJSON)), &desc); err != nil {
+		return cError(err)
+	}
+	return cError(p.pc.SetRemoteDescription(desc))
+}
+
+// webrtc_peerconnection_local_description returns the JSON of the local
+// description, with the candidates gathered so far, to free with
+// webrtc_free. It is NULL if none is set.
+//
+//export webrtc_peerconnection_local_description
+func webrtc_peerconnection_local_description(handle C.uintptr_t, desc **C.char) *C.char {
+	p, err := getPeerConnection(handle)
+	if err != nil {
+		return cError(err)
+	}
+	local := p.pc.LocalDescription()
+	if local == nil {
+		*desc = nil
+		return nil
+	}
+	return marshalDescription(*local, desc)
+}
+
+// webrtc_peerconnection_add_ice_candidate adds a candidate of the remote,
+// from the JSON of its RTCIceCandidateInit
+//
+//export webrtc_peerconnection_add_ice_candidate
+func webrtc_peerconnection_add_ice_candidate(handle C.uintptr_t, candidateJSON *C.char) *C.char {
+	p, err := getPeerConnection(handle)
+	if err != nil {
+		return cError(err)
+	}
+	candidate := webrtc.ICECandidateInit{}
+	if err = json.Unmarshal([]byte(C.GoString(candidateJSON)), &candidate); err != nil {
+		return cError(err)
+	}
+	return cError(p.pc.AddICECandidate(candidate))
+}
+
+// webrtc_peerconnection_add_track creates a Track sending codec, like
+// "opus" or "VP8", and adds it to the PeerConnection. Its handle is to
+// release with webrtc_release.
+//
+//export webrtc_peerconnection_add_track
+func webrtc_peerconnection_add_track(handle C.uintptr_t, codec, id, label *C.char, track *C.uintptr_t) *C.char {
+	p, err := getPeerConnection(handle)
+	if err != nil {
+		return cError(err)
+	}
+	codecs := p.media.GetCodecsByName(C.GoString(codec))
+	if len(codecs) == 0 {
+		return cError(errUnknownCodec)
+	}
+
+	ssrc, generator := uint32(0), randutil.NewMathRandomGenerator()
+	for ssrc == 0 {
+		ssrc = generator.Uint32()
+	}
+	t, err := p.pc.NewTrack(codecs[0].PayloadType, ssrc, C.GoString(id), C.GoString(label))
+	if err != nil {
+		return cError(err)
+	}
+	if _, err = p.pc.AddTrack(t); err != nil {
+		return cError(err)
+	}
+	*track = C.uintptr_t(objects.add(t))
+	return nil
+}
+
+// webrtc_track_write_rtp sends an RTP packet on a local Track
+//
+//export webrtc_track_write_rtp
+func webrtc_track_write_rtp(handle C.uintptr_t, packet unsafe.Pointer, length C.int) *C.char {
+	t, err := getTrack(handle)
+	if err != nil {
+		return cError(err)
+	}
+	_, err = t.Write(C.GoBytes(packet, length))
+	return cError(err)
+}
+
+// webrtc_track_read_rtp reads the next RTP packet of a remote Track into buf,
+// of size bytes, and sets n to its length
+//
+//export webrtc_track_read_rtp
+func webrtc_track_read_rtp(handle C.uintptr_t, buf unsafe.Pointer, size C.int, n *C.int) *C.char {
+	t, err := getTrack(handle)
+	if err != nil {
+		return cError(err)
+	}
+	read, err := t.Read((*[1 << 30]byte)(buf)[:size:size])
+	if err != nil {
+		return cError(err)
+	}
+	*n = C.int(read)
+	return nil
+}
+
+// webrtc_peerconnection_create_data_channel creates a reliable and ordered
+// DataChannel. Its handle is to release with webrtc_release.
+//
+//export webrtc_peerconnection_create_data_channel
+func webrtc_peerconnection_create_data_channel(handle C.uintptr_t, label *C.char, callbacks *C.webrtc_data_channel_callbacks, user unsafe.Pointer, channel *C.uintptr_t) *C.char {
+	p, err := getPeerConnection(handle)
+	if err != nil {
+		return cError(err)
+	}
+	d, err := p.pc.CreateDataChannel(C.GoString(label), nil)
+	if err != nil {
+		return cError(err)
+	}
+	dc := &dataChannel{d: d}
+	if callbacks != nil {
+		dc.setCallbacks(*callbacks, user)
+	}
+	*channel = C.uintptr_t(objects.add(dc))
+	return nil
+}
+
+// webrtc_data_channel_set_callbacks sets the callbacks of a DataChannel,
+// callbacks is copied and can't be NULL. The messages received before are
+// dropped.
+//
+//export webrtc_data_channel_set_callbacks
+func webrtc_data_channel_set_callbacks(handle C.uintptr_t, callbacks *C.webrtc_data_channel_callbacks, user unsafe.Pointer) *C.char {
+	if callbacks == nil {
+		return cError(errNullCallbacks)
+	}
+	dc, err := getDataChannel(handle)
+	if err != nil {
+		return cError(err)
+	}
+	dc.setCallbacks(*callbacks, user)
+	return nil
+}
+
+func (dc *dataChannel) setCallbacks(callbacks C.webrtc_data_channel_callbacks, user unsafe.Pointer) {
+	dc.d.OnOpen(func() {
+		C.call_on_open(&callbacks, user)
+	})
+	dc.d.OnClose(func() {
+		C.call_on_close(&callbacks, user)
+	})
+	dc.d.OnMessage(func(msg webrtc.DataChannelMessage) {
+		isString := C.int(0)
+		if msg.IsString {
+			isString = 1
+		}
+		data := C.CBytes(msg.Data)
+		defer C.free(data)
+		C.call_on_message(&callbacks, user, data, C.int(len(msg.Data)), isString)
+	})
+}
+
+// webrtc_data_channel_send sends a message, as text if is_string isn't 0
+//
+//export webrtc_data_channel_send
+func webrtc_data_channel_send(handle C.uintptr_t, data unsafe.Pointer, length C.int, isString C.int) *C.char {
+	dc, err := getDataChannel(handle)
+	if err != nil {
+		return cError(err)
+	}
+	b := C.GoBytes(data, length)
+	if isString != 0 {
+		return cError(dc.d.SendText(string(b)))
+	}
+	return cError(dc.d.Send(b))
+}
+
+// webrtc_data_channel_close closes a DataChannel, its handle stays valid
+// until released
+//
+//export webrtc_data_channel_close
+func webrtc_data_channel_close(handle C.uintptr_t) *C.char {
+	dc, err := getDataChannel(handle)
+	if err != nil {
+		return cError(err)
+	}
+	return cError(dc.d.Close())
+}
@@ -0,0 +1,54 @@
+// +build !js
+
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+var errInvalidHandle = errors.New("libwebrtc: invalid handle")
+
+// handles maps the handles given to C to the Go values, as C can't keep
+// pointers to Go memory
+type handles struct {
+	mu     sync.Mutex
+	last   uintptr
+	values map[uintptr]interface{}
+}
+
+// add returns a new handle of v, handles are never 0
+func (h *handles) add(v interface{}) uintptr {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.values == nil {
+		h.values = map[uintptr]interface{}{}
+	}
+	h.last++
+	h.values[h.last] = v
+	return h.last
+}
+
+func (h *handles) get(handle uintptr) (interface{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	v, ok := h.values[handle]
+	if !ok {
+		return nil, errInvalidHandle
+	}
+	return v, nil
+}
+
+// remove releases handle, it is invalid afterwards
+func (h *handles) remove(handle uintptr) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.values[handle]; !ok {
+		return errInvalidHandle
+	}
+	delete(h.values, handle)
+	return nil
+}
@@ -0,0 +1,32 @@
+// +build !js
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandles(t *testing.T) {
+	h := &handles{}
+
+	_, err := h.get(0)
+	assert.Equal(t, errInvalidHandle, err)
+
+	a, b := h.add("a"), h.add("b")
+	assert.NotEqual(t, uintptr(0), a)
+	assert.NotEqual(t, a, b)
+
+	v, err := h.get(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", v)
+
+	assert.NoError(t, h.remove(a))
+	assert.Equal(t, errInvalidHandle, h.remove(a))
+	_, err = h.get(a)
+	assert.Equal(t, errInvalidHandle, err)
+
+	// Handles aren't reused
+	assert.NotEqual(t, a, h.add("c"))
+}
@@ -0,0 +1,19 @@
+// +build !js
+
+// Command libwebrtc exports Pion WebRTC as a C shared library, so non-Go
+// applications can link it as their media engine:
+//
+//	go build -buildmode=c-shared -o libwebrtc.so ./cmd/libwebrtc
+//
+// The build writes libwebrtc.h next to the library, declaring the webrtc_
+// functions and the callback structs. Go values are referred to from C by
+// handles. Structured values are passed as the JSON of their JavaScript
+// counterpart, like {"type":"offer","sdp":"..."} for session descriptions,
+// so the signaling of browsers can be relayed as is.
+//
+// The functions that can fail return NULL, or an error message to release
+// with webrtc_free. Callbacks are called from threads of the Go runtime,
+// the strings they are given are only valid during the call.
+package main
+
+func main() {}
@@ -1,5 +1,7 @@
 package webrtc
 
+import "time"
+
 const (
 	// Unknown defines default public constant to use for "enum" like struct
 	// comparisons when no value was defined.
@@ -11,4 +13,124 @@ const (
 	receiveMTU = 1460
 
 	mediaSectionApplication = "application"
+
+	sdpAttributeMaxMessageSize = "max-message-size"
+	sdpAttributeBundleOnly     = "bundle-only"
+	sdpAttributeRTCPMuxOnly    = "rtcp-mux-only"
+	sdpAttributeSSRCGroup      = "ssrc-group"
+	sdpAttributeExtmap         = "extmap"
+
+	// sdesMidURI is the header extension carrying the MID of the media
+	// section of a stream, RFC 8843 15.2, sdesRTPStreamIDURI the one carrying
+	// its RID, RFC 8852 4.3
+	sdesMidURI         = "urn:ietf:params:rtp-hdrext:sdes:mid"
+	sdesRTPStreamIDURI = "urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id"
+
+	// The IDs of the SDES header extensions offered, transport-cc uses 3
+	sdesMidExtensionID         = 4
+	sdesRTPStreamIDExtensionID = 5
+
+	// videoOrientationURI is the header extension carrying the coordination
+	// of video orientation, 3GPP TS 26.114 7.4.5, offered with
+	// videoOrientationExtensionID
+	videoOrientationURI         = "urn:3gpp:video-orientation"
+	videoOrientationExtensionID = 6
+
+	// playoutDelayURI is the header extension carrying the playout delay of
+	// video frames, offered with playoutDelayExtensionID
+	// http://www.webrtc.org/experiments/rtp-hdrext/playout-delay
+	playoutDelayURI         = "http://www.webrtc.org/experiments/rtp-hdrext/playout-delay"
+	playoutDelayExtensionID = 7
+
+	// absCaptureTimeURI is the header extension carrying the capture time of
+	// frames, offered with absCaptureTimeExtensionID and sent every
+	// absCaptureTimeInterval
+	// http://www.webrtc.org/experiments/rtp-hdrext/abs-capture-time
+	absCaptureTimeURI         = "http://www.webrtc.org/experiments/rtp-hdrext/abs-capture-time"
+	absCaptureTimeExtensionID = 8
+	absCaptureTimeInterval    = time.Second
+
+	// cnameLength is the length of the CNAME of a PeerConnection
+	cnameLength = 16
+
+	// sdesInterval is how often the SDES of the senders is sent, RFC 3550 6.5
+	sdesInterval = 5 * time.Second
+
+	sdpBandwidthAS   = "AS"
+	sdpBandwidthTIAS = "TIAS"
+
+	// closeDrainInterval is how often CloseWithContext checks if the
+	// DataChannels are done sending
+	closeDrainInterval = 10 * time.Millisecond
+
+	// gatheringPollInterval is how often LocalDescriptionContext checks if
+	// ICE gathering is complete
+	gatheringPollInterval = 10 * time.Millisecond
+
+	// poolGatheringTimeout is how long a PeerConnectionPool waits for the
+	// candidates of a PeerConnection before giving up on it
+	poolGatheringTimeout = 30 * time.Second
+
+	// trackMuteTimeout is how long no packet may arrive on a remote Track
+	// before it is considered muted
+	trackMuteTimeout = 2 * time.Second
+
+	// trackEndedTimeout is how long no packet may arrive on a remote Track
+	// before it is considered ended, when the remote left without a BYE
+	trackEndedTimeout = 30 * time.Second
+
+	// The bounds and defaults of the duration and the gap of DTMF tones,
+	// and the pause a comma in the tones stands for
+	dtmfDefaultDuration = 100 * time.Millisecond
+	dtmfMinDuration     = 40 * time.Millisecond
+	dtmfMaxDuration     = 6 * time.Second
+	dtmfDefaultGap      = 70 * time.Millisecond
+	dtmfMinGap          = 30 * time.Millisecond
+	dtmfCommaPause      = 2 * time.Second
+
+	// dtmfPacketInterval is how often a DTMF tone being played is sent
+	dtmfPacketInterval = 50 * time.Millisecond
+
+	// dtmfEndPackets is how often the end of a DTMF tone is sent, RFC 4733 2.5.1.4
+	dtmfEndPackets = 3
+
+	// bandwidthLimitBurst is how much of a bandwidth or rate limit is sent
+	// at once after being idle, bandwidthLimitInterval the shortest delay
+	// waited for
+	bandwidthLimitBurst    = 100 * time.Millisecond
+	bandwidthLimitInterval = 5 * time.Millisecond
+
+	// pacedQueueSize is how many packets a paced or rate limited RTPSender
+	// keeps waiting for their turn, the ones written when it is full are
+	// dropped
+	pacedQueueSize = 256
+
+	// The defaults of the ProberConfig
+	defaultProbeStartBitrate    = 300000
+	defaultProbeClusterDuration = 500 * time.Millisecond
+	defaultProbeMultiplier      = 2
+	defaultProbeSuccessRatio    = 0.8
+	defaultProbeDropRatio       = 0.7
+	defaultProbeRecoveryDelay   = 3 * time.Second
+
+	// maxPaddingSize is the padding of padding-only packets, the most a
+	// packet can have
+	maxPaddingSize = 255
+
+	// layerSwitchPLIInterval is how long a LayerSwitcher waits for a
+	// keyframe of the target layer before requesting another one
+	layerSwitchPLIInterval = 500 * time.Millisecond
+
+	// minBitrateInterval is how often an RTPSender with a minimum bitrate
+	// tops the media sent up with padding
+	minBitrateInterval = 100 * time.Millisecond
+
+	// readContextLinger is how long the goroutine watching the context of
+	// ReadContext calls waits for another one before stopping
+	readContextLinger = time.Second
+
+	// The PeerConnectionPool defaults, the idle time is well under the usual
+	// NAT binding timeouts
+	defaultPeerConnectionPoolSize    = 1
+	defaultPeerConnectionPoolMaxIdle = time.Minute
 )
@@ -34,6 +34,7 @@ type DataChannel struct {
 	id                         *uint16
 	readyState                 DataChannelState
 	bufferedAmountLowThreshold uint64
+	bufferedAmountHighWater    uint64
 	detachCalled               bool
 
 	// The binaryType represents attribute MUST, on getting, return the value to
@@ -54,6 +55,14 @@ type DataChannel struct {
 	sctpTransport *SCTPTransport
 	dataChannel   *datachannel.DataChannel
 
+	// rateLimit is the quota of the DataChannel, rateLimiter enforces it
+	rateLimit   uint64
+	rateLimiter *Pacer
+
+	// closed is closed once the DataChannel is closing, the sends waiting
+	// for the rate limits give up
+	closed chan interface{}
+
 	// A reference to the associated api object used by this datachannel
 	api *API
 	log logging.LeveledLogger
@@ -94,6 +103,7 @@ func (api *API) newDataChannel(params *DataChannelParameters, log logging.Levele
 		maxPacketLifeTime: params.MaxPacketLifeTime,
 		maxRetransmits:    params.MaxRetransmits,
 		readyState:        DataChannelStateConnecting,
+		closed:            make(chan interface{}),
 		api:               api,
 		log:               log,
 	}, nil
@@ -333,9 +343,15 @@ func (d *DataChannel) Send(data []byte) error {
 	err := d.ensureOpen()
 	if err != nil {
 		return err
+	} else if err = d.ensureMessageSize(len(data)); err != nil {
+		return err
 	}
 
+	if err = d.waitRateLimits(len(data)); err != nil {
+		return err
+	}
 	_, err = d.dataChannel.WriteDataChannel(data, false)
+	d.observeBufferedAmount()
 	return err
 }
 
@@ -344,9 +360,15 @@ func (d *DataChannel) SendText(s string) error {
 	err := d.ensureOpen()
 	if err != nil {
 		return err
+	} else if err = d.ensureMessageSize(len(s)); err != nil {
+		return err
 	}
 
+	if err = d.waitRateLimits(len(s)); err != nil {
+		return err
+	}
 	_, err = d.dataChannel.WriteDataChannel([]byte(s), true)
+	d.observeBufferedAmount()
 	return err
 }
 
@@ -359,6 +381,17 @@ func (d *DataChannel) ensureOpen() error {
 	return nil
 }
 
+func (d *DataChannel) ensureMessageSize(size int) error {
+	d.mu.RLock()
+	sctpTransport := d.sctpTransport
+	d.mu.RUnlock()
+
+	if sctpTransport != nil && float64(size) > sctpTransport.MaxMessageSize() {
+		return &rtcerr.TypeError{Err: ErrDataChannelMessageTooLarge}
+	}
+	return nil
+}
+
 // Detach allows you to detach the underlying datachannel. This provides
 // an idiomatic API to work with, however it disables the OnMessage callback.
 // Before calling Detach you have to enable this behavior by calling
@@ -548,12 +581,28 @@ func (d *DataChannel) getStatsID() string {
 	return d.statsID
 }
 
-func (d *DataChannel) collectStats(collector *statsReportCollector) {
-	collector.Collecting()
-
+// observeBufferedAmount raises the high-water mark of the bufferedAmount to
+// the current one
+func (d *DataChannel) observeBufferedAmount() {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if d.dataChannel == nil {
+		return
+	}
+	if amount := d.dataChannel.BufferedAmount(); amount > d.bufferedAmountHighWater {
+		d.bufferedAmountHighWater = amount
+	}
+}
+
+// Stats returns the statistics of the DataChannel, the same as its entry in
+// the report of PeerConnection.GetStats without collecting the others.
+func (d *DataChannel) Stats() DataChannelStats {
+	d.observeBufferedAmount()
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
 	stats := DataChannelStats{
 		Timestamp: statsTimestampNow(),
 		Type:      StatsTypeDataChannel,
@@ -574,7 +623,15 @@ func (d *DataChannel) collectStats(collector *statsReportCollector) {
 		stats.MessagesReceived = d.dataChannel.MessagesReceived()
 		stats.BytesReceived = d.dataChannel.BytesReceived()
 	}
+	stats.BufferedAmountHighWater = d.bufferedAmountHighWater
+
+	return stats
+}
+
+func (d *DataChannel) collectStats(collector *statsReportCollector) {
+	collector.Collecting()
 
+	stats := d.Stats()
 	collector.Collect(stats.ID, stats)
 }
 
@@ -583,4 +640,11 @@ func (d *DataChannel) setReadyState(r DataChannelState) {
 	defer d.mu.Unlock()
 
 	d.readyState = r
+	if r == DataChannelStateClosing || r == DataChannelStateClosed {
+		select {
+		case <-d.closed:
+		default:
+			close(d.closed)
+		}
+	}
 }
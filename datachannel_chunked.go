@@ -0,0 +1,264 @@
+package webrtc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+const (
+	// chunkSize is small enough to be accepted by every implementation
+	chunkSize = 16 * 1024
+
+	// Sending pauses while more than chunkHighWatermark bytes are buffered and
+	// resumes once BufferedAmount drops to chunkLowWatermark
+	chunkHighWatermark = 1024 * 1024
+	chunkLowWatermark  = chunkHighWatermark / 2
+
+	// chunkPollInterval bounds how long a blocked send goes without checking
+	// that the DataChannel is still open
+	chunkPollInterval = 100 * time.Millisecond
+
+	chunkFlagFinal  = 1 << 0
+	chunkFlagString = 1 << 1
+	// chunkFlagAbort discards the chunks of the message received so far
+	chunkFlagAbort = 1 << 2
+)
+
+// ChunkedDataChannel sends messages of any size over a DataChannel by
+// splitting them into chunks of 16 KiB and reassembles them on the remote
+// side. Every chunk starts with a one byte header, so both peers have to wrap
+// the DataChannel in a ChunkedDataChannel.
+//
+// The DataChannel has to be ordered and reliable. The ChunkedDataChannel
+// takes over its OnMessage and OnBufferedAmountLow handlers and its
+// BufferedAmountLowThreshold.
+type ChunkedDataChannel struct {
+	dataChannel    *DataChannel
+	maxMessageSize int
+
+	// sendMu keeps the chunks of two messages from interleaving
+	sendMu            sync.Mutex
+	bufferedAmountLow chan struct{}
+
+	// Reassembly state, only used by handleChunk which the DataChannel calls
+	// for one message at a time
+	message  []byte
+	dropping bool
+
+	mu               sync.RWMutex
+	onMessageHandler func(DataChannelMessage)
+	onErrorHandler   func(error)
+}
+
+// NewChunkedDataChannel wraps d in a ChunkedDataChannel. Received messages
+// larger than maxMessageSize bytes are dropped.
+func NewChunkedDataChannel(d *DataChannel, maxMessageSize int) (*ChunkedDataChannel, error) {
+	if !d.Ordered() || d.MaxRetransmits() != nil || d.MaxPacketLifeTime() != nil {
+		return nil, &rtcerr.InvalidAccessError{Err: ErrDataChannelNotReliable}
+	}
+
+	c := &ChunkedDataChannel{
+		dataChannel:       d,
+		maxMessageSize:    maxMessageSize,
+		bufferedAmountLow: make(chan struct{}, 1),
+	}
+
+	d.SetBufferedAmountLowThreshold(chunkLowWatermark)
+	d.OnBufferedAmountLow(func() {
+		select {
+		case c.bufferedAmountLow <- struct{}{}:
+		default:
+		}
+	})
+	d.OnMessage(c.handleChunk)
+
+	return c, nil
+}
+
+// DataChannel returns the wrapped DataChannel
+func (c *ChunkedDataChannel) DataChannel() *DataChannel {
+	return c.dataChannel
+}
+
+// OnMessage sets an event handler which is invoked with every reassembled
+// message. Messages are delivered in the order they were sent.
+func (c *ChunkedDataChannel) OnMessage(f func(msg DataChannelMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onMessageHandler = f
+}
+
+// OnError sets an event handler which is invoked when a received message is
+// dropped, with ErrChunkedMessageTooLarge or ErrChunkedMessageInvalid.
+func (c *ChunkedDataChannel) OnError(f func(err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onErrorHandler = f
+}
+
+func (c *ChunkedDataChannel) onError(err error) {
+	c.mu.RLock()
+	hdlr := c.onErrorHandler
+	c.mu.RUnlock()
+
+	if hdlr != nil {
+		go hdlr(err)
+	}
+}
+
+func (c *ChunkedDataChannel) handleChunk(msg DataChannelMessage) {
+	if len(msg.Data) == 0 {
+		c.message = nil
+		c.dropping = false
+		c.onError(ErrChunkedMessageInvalid)
+		return
+	}
+
+	flags, payload := msg.Data[0], msg.Data[1:]
+	final := flags&chunkFlagFinal != 0
+
+	if flags&chunkFlagAbort != 0 {
+		c.message = nil
+		c.dropping = false
+		return
+	}
+
+	if c.dropping {
+		c.dropping = !final
+		return
+	}
+
+	if len(c.message)+len(payload) > c.maxMessageSize {
+		c.message = nil
+		c.dropping = !final
+		c.onError(ErrChunkedMessageTooLarge)
+		return
+	}
+	c.message = append(c.message, payload...)
+
+	if !final {
+		return
+	}
+
+	message := c.message
+	c.message = nil
+	if message == nil {
+		message = []byte{}
+	}
+
+	c.mu.RLock()
+	hdlr := c.onMessageHandler
+	c.mu.RUnlock()
+
+	if hdlr != nil {
+		hdlr(DataChannelMessage{IsString: flags&chunkFlagString != 0, Data: message})
+	}
+}
+
+// Send sends the binary message to the remote ChunkedDataChannel. It blocks
+// while the DataChannel has too much data buffered.
+func (c *ChunkedDataChannel) Send(data []byte) error {
+	return c.SendContext(context.Background(), data)
+}
+
+// SendContext is Send, giving up with ctx.Err() once ctx is done. The remote
+// ChunkedDataChannel discards a message that was partly sent.
+func (c *ChunkedDataChannel) SendContext(ctx context.Context, data []byte) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	return c.sendChunks(ctx, data, 0)
+}
+
+// SendText sends the text message to the remote ChunkedDataChannel. It blocks
+// while the DataChannel has too much data buffered.
+func (c *ChunkedDataChannel) SendText(s string) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	return c.sendChunks(context.Background(), []byte(s), chunkFlagString)
+}
+
+// SendReader sends everything read from r until io.EOF as one binary message,
+// without holding it in memory. If reading fails the message is aborted, the
+// remote doesn't receive it, and the error is returned.
+func (c *ChunkedDataChannel) SendReader(r io.Reader) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	buf := make([]byte, chunkSize)
+	pending := 0
+	for {
+		n, readErr := io.ReadFull(r, buf[pending:])
+		pending += n
+
+		switch readErr {
+		case nil:
+			// A full chunk may be the last one, the message then ends with an
+			// empty final chunk once the next read returns io.EOF
+			if err := c.sendChunk(context.Background(), buf[:pending], 0); err != nil {
+				return err
+			}
+			pending = 0
+		case io.EOF, io.ErrUnexpectedEOF:
+			return c.sendChunk(context.Background(), buf[:pending], chunkFlagFinal)
+		default:
+			// The remote discards the chunks already sent instead of
+			// delivering a truncated message
+			_ = c.dataChannel.Send([]byte{chunkFlagAbort})
+			return readErr
+		}
+	}
+}
+
+func (c *ChunkedDataChannel) sendChunks(ctx context.Context, data []byte, flags byte) error {
+	for first := true; ; first = false {
+		chunk, chunkFlags := data, flags|chunkFlagFinal
+		if len(data) > chunkSize {
+			chunk, chunkFlags = data[:chunkSize], flags
+		}
+
+		if err := c.sendChunk(ctx, chunk, chunkFlags); err != nil {
+			if !first && ctx.Err() != nil {
+				// The abort chunk is tiny, it doesn't wait for the buffer to drain
+				_ = c.dataChannel.Send([]byte{chunkFlagAbort})
+			}
+			return err
+		}
+		if chunkFlags&chunkFlagFinal != 0 {
+			return nil
+		}
+		data = data[chunkSize:]
+	}
+}
+
+func (c *ChunkedDataChannel) sendChunk(ctx context.Context, payload []byte, flags byte) error {
+	if err := c.waitBufferedAmountLow(ctx); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 1+len(payload))
+	chunk[0] = flags
+	copy(chunk[1:], payload)
+	return c.dataChannel.Send(chunk)
+}
+
+func (c *ChunkedDataChannel) waitBufferedAmountLow(ctx context.Context) error {
+	for c.dataChannel.BufferedAmount() > chunkHighWatermark {
+		if c.dataChannel.ReadyState() != DataChannelStateOpen {
+			return &rtcerr.InvalidStateError{Err: ErrDataChannelNotOpen}
+		}
+
+		select {
+		case <-c.bufferedAmountLow:
+		case <-time.After(chunkPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingReader fails every Read with err
+type failingReader struct {
+	err error
+}
+
+func (r failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestChunkedDataChannel_Reassembly(t *testing.T) {
+	c := &ChunkedDataChannel{maxMessageSize: 4}
+
+	var received []DataChannelMessage
+	c.OnMessage(func(msg DataChannelMessage) {
+		received = append(received, msg)
+	})
+	errs := make(chan error, 2)
+	c.OnError(func(err error) {
+		errs <- err
+	})
+
+	for _, chunk := range [][]byte{
+		{0, 'a', 'b'},
+		{chunkFlagFinal | chunkFlagString, 'c'},
+		{chunkFlagFinal},
+		// Too large, dropped until the final chunk
+		{0, 1, 2, 3},
+		{0, 4, 5},
+		{chunkFlagFinal, 6},
+		{chunkFlagFinal, 7, 8, 9, 10},
+		// Aborted by the sender
+		{0, 11},
+		{chunkFlagAbort},
+		{chunkFlagFinal, 12},
+	} {
+		c.handleChunk(DataChannelMessage{Data: chunk})
+	}
+
+	assert.Equal(t, []DataChannelMessage{
+		{IsString: true, Data: []byte("abc")},
+		{Data: []byte{}},
+		{Data: []byte{7, 8, 9, 10}},
+		{Data: []byte{12}},
+	}, received)
+	assert.Equal(t, ErrChunkedMessageTooLarge, <-errs)
+
+	c.handleChunk(DataChannelMessage{Data: []byte{}})
+	assert.Equal(t, ErrChunkedMessageInvalid, <-errs)
+}
+
+func TestChunkedDataChannel(t *testing.T) {
+	to := test.TimeOut(time.Second * 30)
+	defer to.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	var maxRetransmits uint16
+	unreliable, err := offerPC.CreateDataChannel("unreliable", &DataChannelInit{MaxRetransmits: &maxRetransmits})
+	assert.NoError(t, err)
+	_, err = NewChunkedDataChannel(unreliable, 1024)
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrDataChannelNotReliable}, err)
+
+	blob := make([]byte, 4*1024*1024+1)
+	_, err = rand.Read(blob)
+	assert.NoError(t, err)
+
+	received := make(chan DataChannelMessage, 4)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != "chunked" {
+			return
+		}
+		c, chunkedErr := NewChunkedDataChannel(d, len(blob))
+		assert.NoError(t, chunkedErr)
+		c.OnMessage(func(msg DataChannelMessage) {
+			received <- msg
+		})
+	})
+
+	dc, err := offerPC.CreateDataChannel("chunked", nil)
+	assert.NoError(t, err)
+	c, err := NewChunkedDataChannel(dc, len(blob))
+	assert.NoError(t, err)
+
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	<-opened
+
+	assert.NoError(t, c.Send(blob))
+	assert.NoError(t, c.SendText("hello"))
+	assert.NoError(t, c.SendReader(bytes.NewReader(blob[:3*chunkSize])))
+
+	// A failing reader aborts its message, the next one still arrives whole
+	errRead := errors.New("read failed")
+	assert.Equal(t, errRead, c.SendReader(io.MultiReader(bytes.NewReader(blob[:2*chunkSize+1]), failingReader{errRead})))
+	assert.NoError(t, c.SendText("after abort"))
+
+	msg := <-received
+	assert.False(t, msg.IsString)
+	assert.True(t, bytes.Equal(blob, msg.Data))
+	assert.Equal(t, DataChannelMessage{IsString: true, Data: []byte("hello")}, <-received)
+	msg = <-received
+	assert.True(t, bytes.Equal(blob[:3*chunkSize], msg.Data))
+	assert.Equal(t, DataChannelMessage{IsString: true, Data: []byte("after abort")}, <-received)
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/pion/datachannel"
 	"github.com/pion/logging"
 	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -528,3 +529,56 @@ func TestEOF(t *testing.T) {
 		<-dcbClosedCh // (2)
 	})
 }
+
+func TestDataChannel_MaxMessageSize(t *testing.T) {
+	to := test.TimeOut(time.Second * 20)
+	defer to.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetSCTPMaxMessageSize(1024)
+	s.SetSCTPMaxReceiveBufferSize(64 * 1024)
+
+	offerPC, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	answerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerSCTPClosed := make(chan error)
+	answerPC.sctpTransport.OnError(func(err error) {
+		answerSCTPClosed <- err
+	})
+
+	received := make(chan int)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			received <- len(msg.Data)
+		})
+	})
+
+	dc, err := offerPC.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	<-opened
+
+	// The offer did not advertise max-message-size, pion can send up to 65535 bytes
+	assert.Equal(t, float64(1024), offerPC.sctpTransport.MaxMessageSize())
+	assert.Equal(t, float64(65535), answerPC.sctpTransport.MaxMessageSize())
+
+	assert.Equal(t, &rtcerr.TypeError{Err: ErrDataChannelMessageTooLarge}, dc.Send(make([]byte, 1025)))
+	assert.NoError(t, dc.Send(make([]byte, 1024)))
+	assert.Equal(t, 1024, <-received)
+
+	// Closing the offer closes the association of the answer
+	assert.NoError(t, offerPC.Close())
+	assert.Equal(t, ErrSCTPAssociationClosed, <-answerSCTPClosed)
+	assert.Equal(t, SCTPTransportStateClosed, answerPC.sctpTransport.State())
+	assert.NoError(t, answerPC.Close())
+}
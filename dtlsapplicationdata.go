@@ -0,0 +1,93 @@
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/pion/transport/packetio"
+)
+
+const (
+	// applicationDataReceiveMTU is the largest DTLS record read, like SCTP
+	// reads them
+	applicationDataReceiveMTU = 8192
+
+	// sctpBufferSize is the maximum amount of SCTP buffered until the
+	// SCTPTransport reads it
+	sctpBufferSize = 1000 * 1000
+)
+
+// applicationDataMarker starts the raw application data. SCTP packets start
+// with their source port, which can't be 0, RFC 4960 3.1.
+var applicationDataMarker = []byte{0, 0}
+
+// applicationDataConn is the DTLS connection as the SCTPTransport sees it.
+// It reads the DTLS records and takes the raw application data out before
+// SCTP reads the rest.
+type applicationDataConn struct {
+	net.Conn
+
+	sctp    *packetio.Buffer
+	handler func() func([]byte)
+}
+
+func newApplicationDataConn(conn net.Conn, handler func() func([]byte)) *applicationDataConn {
+	c := &applicationDataConn{
+		Conn:    conn,
+		sctp:    packetio.NewBuffer(),
+		handler: handler,
+	}
+	c.sctp.SetLimitSize(sctpBufferSize)
+
+	go c.readLoop()
+	return c
+}
+
+func (c *applicationDataConn) readLoop() {
+	b := make([]byte, applicationDataReceiveMTU)
+	for {
+		n, err := c.Conn.Read(b)
+		if err != nil {
+			_ = c.sctp.Close()
+			return
+		}
+
+		if bytes.HasPrefix(b[:n], applicationDataMarker) {
+			if hdlr := c.handler(); hdlr != nil {
+				data := make([]byte, n-len(applicationDataMarker))
+				copy(data, b[len(applicationDataMarker):n])
+				hdlr(data)
+			}
+			continue
+		}
+
+		// SCTP that isn't read in time is lost, like on the network
+		if _, err = c.sctp.Write(b[:n]); err != nil && err != packetio.ErrFull {
+			return
+		}
+	}
+}
+
+// Read reads the SCTP packets
+func (c *applicationDataConn) Read(b []byte) (int, error) {
+	return c.sctp.Read(b)
+}
+
+// Close closes the DTLS connection
+func (c *applicationDataConn) Close() error {
+	_ = c.sctp.Close()
+	return c.Conn.Close()
+}
+
+// writeApplicationData sends data as raw application data
+func (c *applicationDataConn) writeApplicationData(data []byte) (int, error) {
+	record := make([]byte, 0, len(applicationDataMarker)+len(data))
+	record = append(record, applicationDataMarker...)
+	record = append(record, data...)
+	if _, err := c.Conn.Write(record); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
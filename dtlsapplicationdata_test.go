@@ -0,0 +1,38 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationDataConn(t *testing.T) {
+	local, remote := net.Pipe()
+
+	received := make(chan []byte, 1)
+	c := newApplicationDataConn(local, func() func([]byte) {
+		return func(data []byte) { received <- data }
+	})
+	remoteConn := newApplicationDataConn(remote, func() func([]byte) { return nil })
+
+	// The SCTP common header starts with the source port 5000
+	sctpPacket := []byte{0x13, 0x88, 0x13, 0x88, 1, 2, 3, 4}
+	go func() {
+		_, _ = remoteConn.writeApplicationData([]byte("feedback"))
+		_, _ = remote.Write(sctpPacket)
+	}()
+
+	assert.Equal(t, []byte("feedback"), <-received)
+	b := make([]byte, applicationDataReceiveMTU)
+	n, err := c.Read(b)
+	assert.NoError(t, err)
+	assert.Equal(t, sctpPacket, b[:n], "SCTP only reads the SCTP")
+
+	assert.NoError(t, c.Close())
+	_, err = c.Read(b)
+	assert.Error(t, err)
+	assert.NoError(t, remoteConn.Close())
+}
@@ -3,19 +3,19 @@
 package webrtc
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
-	"errors"
-	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/pion/dtls/v2"
 	"github.com/pion/dtls/v2/pkg/crypto/fingerprint"
+	"github.com/pion/logging"
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/srtp"
@@ -24,6 +24,13 @@ import (
 	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
+// srtpProtectionProfiles maps the DTLS-SRTP protection profiles to the ones
+// of the srtp package. The AEAD AES-GCM profiles of RFC 7714 are not
+// implemented by srtp yet.
+var srtpProtectionProfiles = map[dtls.SRTPProtectionProfile]srtp.ProtectionProfile{
+	dtls.SRTP_AES128_CM_HMAC_SHA1_80: srtp.ProtectionProfileAes128CmHmacSha1_80,
+}
+
 // DTLSTransport allows an application access to information about the DTLS
 // transport over which RTP and RTCP packets are sent and received by
 // RTPSender and RTPReceiver, as well other data such as SCTP packets sent
@@ -39,8 +46,16 @@ type DTLSTransport struct {
 
 	onStateChangeHdlr func(DTLSTransportState)
 
+	// onStateChangeHook is set by the PeerConnection at creation to aggregate
+	// its connection state, next to the handler of the user
+	onStateChangeHook func(DTLSTransportState)
+
 	conn *dtls.Conn
 
+	// applicationDataConn carries the SCTP and the raw application data on conn
+	applicationDataConn   *applicationDataConn
+	onApplicationDataHdlr func([]byte)
+
 	srtpSession   *srtp.SessionSRTP
 	srtcpSession  *srtp.SessionSRTCP
 	srtpEndpoint  *mux.Endpoint
@@ -48,7 +63,16 @@ type DTLSTransport struct {
 
 	dtlsMatcher mux.MatchFunc
 
+	captureLock sync.RWMutex
+	capture     *packetCapture
+
+	// egressRateLimit is the quota of the RTP and the DataChannels sent,
+	// egressLimiter enforces it
+	egressRateLimit uint64
+	egressLimiter   *Pacer
+
 	api *API
+	log logging.LeveledLogger
 }
 
 // NewDTLSTransport creates a new DTLSTransport.
@@ -60,6 +84,10 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 		api:          api,
 		state:        DTLSTransportStateNew,
 		dtlsMatcher:  mux.MatchDTLS,
+		log:          api.settingEngine.LoggerFactory.NewLogger("ortc"),
+
+		egressRateLimit: api.settingEngine.egressRateLimit,
+		egressLimiter:   newRateLimiter(api.settingEngine.egressRateLimit),
 	}
 
 	if len(certificates) > 0 {
@@ -96,6 +124,9 @@ func (t *DTLSTransport) ICETransport() *ICETransport {
 // onStateChange requires the caller holds the lock
 func (t *DTLSTransport) onStateChange(state DTLSTransportState) {
 	t.state = state
+	if t.onStateChangeHook != nil {
+		t.onStateChangeHook(state)
+	}
 	hdlr := t.onStateChangeHdlr
 	if hdlr != nil {
 		hdlr(state)
@@ -110,6 +141,38 @@ func (t *DTLSTransport) OnStateChange(f func(DTLSTransportState)) {
 	t.onStateChangeHdlr = f
 }
 
+// OnApplicationData sets a handler called with the raw application data the
+// remote sent with WriteApplicationData. It is called synchronously from the
+// loop reading the DTLS connection, so it must not block.
+func (t *DTLSTransport) OnApplicationData(f func([]byte)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.onApplicationDataHdlr = f
+}
+
+func (t *DTLSTransport) applicationDataHandler() func([]byte) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.onApplicationDataHdlr
+}
+
+// WriteApplicationData sends data to the remote as raw DTLS application data,
+// next to the SCTP of the DataChannels, for custom protocols that don't need
+// the reliability or ordering of SCTP. Every write is a DTLS record of its
+// own, marked so the remote tells it from SCTP. Only a remote that handles
+// it with OnApplicationData can read it, other stacks drop it as a broken
+// SCTP packet.
+func (t *DTLSTransport) WriteApplicationData(data []byte) (int, error) {
+	t.lock.RLock()
+	conn := t.applicationDataConn
+	t.lock.RUnlock()
+
+	if conn == nil {
+		return 0, ErrDTLSNotStarted
+	}
+	return conn.writeApplicationData(data)
+}
+
 // State returns the current dtls transport state.
 func (t *DTLSTransport) State() DTLSTransportState {
 	t.lock.RLock()
@@ -117,6 +180,12 @@ func (t *DTLSTransport) State() DTLSTransportState {
 	return t.state
 }
 
+// GetCertificates returns the certificates the DTLSTransport can present to
+// the remote peer
+func (t *DTLSTransport) GetCertificates() []Certificate {
+	return append([]Certificate{}, t.certificates...)
+}
+
 // GetLocalParameters returns the DTLS parameters of the local DTLSTransport upon construction.
 func (t *DTLSTransport) GetLocalParameters() (DTLSParameters, error) {
 	fingerprints := []DTLSFingerprint{}
@@ -144,6 +213,17 @@ func (t *DTLSTransport) GetRemoteCertificate() []byte {
 	return t.remoteCertificate
 }
 
+// GetSRTPProtectionProfile returns the SRTP protection profile negotiated by
+// the DTLS handshake, false until it is done
+func (t *DTLSTransport) GetSRTPProtectionProfile() (dtls.SRTPProtectionProfile, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.conn == nil {
+		return 0, false
+	}
+	return t.conn.SelectedSRTPProtectionProfile()
+}
+
 func (t *DTLSTransport) startSRTP() error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
@@ -151,11 +231,20 @@ func (t *DTLSTransport) startSRTP() error {
 	if t.srtpSession != nil && t.srtcpSession != nil {
 		return nil
 	} else if t.conn == nil {
-		return fmt.Errorf("the DTLS transport has not started yet")
+		return ErrNoRTPSession
+	}
+
+	dtlsProfile, ok := t.conn.SelectedSRTPProtectionProfile()
+	if !ok {
+		return ErrNoSRTPProtectionProfile
+	}
+	profile, ok := srtpProtectionProfiles[dtlsProfile]
+	if !ok {
+		return &rtcerr.NotSupportedError{Err: ErrSRTPProtectionProfile}
 	}
 
 	srtpConfig := &srtp.Config{
-		Profile:       srtp.ProtectionProfileAes128CmHmacSha1_80,
+		Profile:       profile,
 		LoggerFactory: t.api.settingEngine.LoggerFactory,
 	}
 	if t.api.settingEngine.replayProtection.SRTP != nil {
@@ -186,20 +275,25 @@ func (t *DTLSTransport) startSRTP() error {
 		)
 	}
 
+	t.log.Debugf("starting SRTP with protection profile %#04x", uint16(dtlsProfile))
+
 	connState := t.conn.ConnectionState()
 	err := srtpConfig.ExtractSessionKeysFromDTLS(&connState, t.role() == DTLSRoleClient)
 	if err != nil {
-		return fmt.Errorf("failed to extract sctp session keys: %v", err)
+		t.log.Errorf("failed to extract SRTP session keys: %v", err)
+		return errorWithDetail(ErrSRTPSessionKeys, err.Error())
 	}
 
 	srtpSession, err := srtp.NewSessionSRTP(t.srtpEndpoint, srtpConfig)
 	if err != nil {
-		return fmt.Errorf("failed to start srtp: %v", err)
+		t.log.Errorf("failed to start SRTP session: %v", err)
+		return errorWithDetail(ErrSRTPSessionStart, err.Error())
 	}
 
 	srtcpSession, err := srtp.NewSessionSRTCP(t.srtcpEndpoint, srtpConfig)
 	if err != nil {
-		return fmt.Errorf("failed to start srtp: %v", err)
+		t.log.Errorf("failed to start SRTCP session: %v", err)
+		return errorWithDetail(ErrSRTPSessionStart, err.Error())
 	}
 
 	t.srtpSession = srtpSession
@@ -211,7 +305,7 @@ func (t *DTLSTransport) RTPSession() (rtp.Session, error) {
 	t.lock.RLock()
 	if t.srtpSession != nil {
 		t.lock.RUnlock()
-		return t.srtpSession, nil
+		return &captureRTPSession{t.srtpSession, t}, nil
 	}
 	t.lock.RUnlock()
 
@@ -219,14 +313,14 @@ func (t *DTLSTransport) RTPSession() (rtp.Session, error) {
 		return nil, err
 	}
 
-	return t.srtpSession, nil
+	return &captureRTPSession{t.srtpSession, t}, nil
 }
 
 func (t *DTLSTransport) RTCPSession() (rtcp.Session, error) {
 	t.lock.RLock()
 	if t.srtcpSession != nil {
 		t.lock.RUnlock()
-		return t.srtcpSession, nil
+		return &captureRTCPSession{t.srtcpSession, t}, nil
 	}
 	t.lock.RUnlock()
 
@@ -234,7 +328,7 @@ func (t *DTLSTransport) RTCPSession() (rtcp.Session, error) {
 		return nil, err
 	}
 
-	return t.srtcpSession, nil
+	return &captureRTCPSession{t.srtcpSession, t}, nil
 }
 
 func (t *DTLSTransport) role() DTLSRole {
@@ -274,26 +368,29 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		}
 
 		if t.state != DTLSTransportStateNew {
-			return DTLSRole(0), nil, &rtcerr.InvalidStateError{Err: fmt.Errorf("attempted to start DTLSTransport that is not in new state: %s", t.state)}
+			return DTLSRole(0), nil, &rtcerr.InvalidStateError{Err: errorWithDetail(ErrDTLSTransportNotNew, t.state.String())}
 		}
 
 		t.srtpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTP)
 		t.srtcpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTCP)
 		t.remoteParameters = remoteParameters
 
-		cert := t.certificates[0]
+		certificates := make([]tls.Certificate, 0, len(t.certificates))
+		for _, cert := range t.certificates {
+			certificates = append(certificates, tls.Certificate{
+				Certificate: [][]byte{cert.x509Cert.Raw},
+				PrivateKey:  cert.privateKey,
+			})
+		}
 		t.onStateChange(DTLSTransportStateConnecting)
 
 		return t.role(), &dtls.Config{
-			Certificates: []tls.Certificate{
-				{
-					Certificate: [][]byte{cert.x509Cert.Raw},
-					PrivateKey:  cert.privateKey,
-				}},
-			SRTPProtectionProfiles: []dtls.SRTPProtectionProfile{dtls.SRTP_AES128_CM_HMAC_SHA1_80},
+			Certificates:           certificates,
+			SRTPProtectionProfiles: t.api.settingEngine.getSRTPProtectionProfiles(),
 			ClientAuth:             dtls.RequireAnyClientCert,
 			LoggerFactory:          t.api.settingEngine.LoggerFactory,
 			InsecureSkipVerify:     true,
+			CipherSuites:           t.api.settingEngine.dtls.cipherSuites,
 		}, nil
 	}
 
@@ -308,8 +405,16 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		dtlsConfig.ReplayProtectionWindow = int(*t.api.settingEngine.replayProtection.DTLS)
 	}
 
+	dtlsConfig.FlightInterval = t.api.settingEngine.dtls.retransmissionInterval
+	if timeout := t.api.settingEngine.dtls.handshakeTimeout; timeout != 0 {
+		dtlsConfig.ConnectContextMaker = func() (context.Context, func()) {
+			return context.WithTimeout(context.Background(), timeout)
+		}
+	}
+
 	// Connect as DTLS Client/Server, function is blocking and we
 	// must not hold the DTLSTransport lock
+	t.log.Debugf("starting DTLS handshake as %s", role)
 	if role == DTLSRoleClient {
 		dtlsConn, err = dtls.Client(dtlsEndpoint, dtlsConfig)
 	} else {
@@ -321,36 +426,58 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	defer t.lock.Unlock()
 
 	if err != nil {
+		t.log.Warnf("DTLS handshake failed: %v", err)
+		t.onStateChange(DTLSTransportStateFailed)
+		return err
+	}
+	t.log.Debug("DTLS handshake complete")
+
+	if remoteCerts := dtlsConn.ConnectionState().PeerCertificates; len(remoteCerts) != 0 {
+		t.remoteCertificate = remoteCerts[0]
+	}
+
+	// The transport only becomes connected once the remote certificate is
+	// accepted, so no media or application data is read before
+	if err = t.verifyRemoteCertificate(); err != nil {
+		t.log.Warnf("DTLS remote certificate rejected: %v", err)
+		_ = dtlsConn.Close()
 		t.onStateChange(DTLSTransportStateFailed)
 		return err
 	}
 
 	t.conn = dtlsConn
+	t.applicationDataConn = newApplicationDataConn(dtlsConn, t.applicationDataHandler)
 	t.onStateChange(DTLSTransportStateConnected)
+	return nil
+}
 
-	if t.api.settingEngine.disableCertificateFingerprintVerification {
+func (t *DTLSTransport) verifyRemoteCertificate() error {
+	pinnedFingerprints := t.api.settingEngine.dtls.remoteFingerprints
+	if t.api.settingEngine.disableCertificateFingerprintVerification && len(pinnedFingerprints) == 0 {
 		return nil
 	}
 
 	// Check the fingerprint if a certificate was exchanged
-	remoteCerts := t.conn.ConnectionState().PeerCertificates
-	if len(remoteCerts) == 0 {
-		t.onStateChange(DTLSTransportStateFailed)
-		return fmt.Errorf("peer didn't provide certificate via DTLS")
+	if len(t.remoteCertificate) == 0 {
+		return ErrNoRemoteCertificate
 	}
-	t.remoteCertificate = remoteCerts[0]
 
 	parsedRemoteCert, err := x509.ParseCertificate(t.remoteCertificate)
 	if err != nil {
-		t.onStateChange(DTLSTransportStateFailed)
 		return err
 	}
 
-	err = t.validateFingerPrint(parsedRemoteCert)
-	if err != nil {
-		t.onStateChange(DTLSTransportStateFailed)
+	if !t.api.settingEngine.disableCertificateFingerprintVerification {
+		if err = validateFingerPrint(parsedRemoteCert, t.remoteParameters.Fingerprints); err != nil {
+			return err
+		}
+	}
+	if len(pinnedFingerprints) != 0 {
+		if err = validateFingerPrint(parsedRemoteCert, pinnedFingerprints); err != nil {
+			return ErrRemoteFingerprintNotAllowed
+		}
 	}
-	return err
+	return nil
 }
 
 // Stop stops and closes the DTLSTransport object.
@@ -383,11 +510,13 @@ func (t *DTLSTransport) Stop() error {
 	return util.FlattenErrs(closeErrs)
 }
 
-func (t *DTLSTransport) validateFingerPrint(remoteCert *x509.Certificate) error {
-	for _, fp := range t.remoteParameters.Fingerprints {
-		hashAlgo, err := fingerprint.HashFromString(fp.Algorithm)
+func validateFingerPrint(remoteCert *x509.Certificate, fingerprints []DTLSFingerprint) error {
+	for _, fp := range fingerprints {
+		// Fingerprints with algorithms we do not know may still be followed
+		// by one we do
+		hashAlgo, err := fingerprint.HashFromString(strings.ToLower(fp.Algorithm))
 		if err != nil {
-			return err
+			continue
 		}
 
 		remoteValue, err := fingerprint.Fingerprint(remoteCert, hashAlgo)
@@ -400,12 +529,12 @@ func (t *DTLSTransport) validateFingerPrint(remoteCert *x509.Certificate) error
 		}
 	}
 
-	return errors.New("no matching fingerprint")
+	return ErrNoMatchingFingerprint
 }
 
 func (t *DTLSTransport) ensureICEConn() error {
 	if t.iceTransport == nil || t.iceTransport.State() == ICETransportStateNew {
-		return errors.New("ICE connection not started")
+		return ErrICEConnectionNotStarted
 	}
 
 	return nil
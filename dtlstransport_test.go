@@ -4,10 +4,14 @@ package webrtc
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"regexp"
 	"testing"
 	"time"
 
+	"github.com/pion/dtls/v2"
 	"github.com/pion/transport/test"
 	"github.com/stretchr/testify/assert"
 )
@@ -134,3 +138,64 @@ func TestPeerConnection_DTLSRoleSettingEngine(t *testing.T) {
 		runTest(DTLSRoleClient)
 	})
 }
+
+func TestPeerConnection_DTLSPolicy(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	offerCertificate, err := GenerateCertificate(sk)
+	assert.NoError(t, err)
+	offerFingerprints, err := offerCertificate.GetFingerprints()
+	assert.NoError(t, err)
+
+	runTest := func(t *testing.T, pinned []DTLSFingerprint, expectedState PeerConnectionState) *PeerConnection {
+		s := SettingEngine{}
+		s.SetDTLSCipherSuites(dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA)
+		s.SetDTLSRemoteFingerprints(pinned...)
+
+		offerPC, err := NewPeerConnection(Configuration{Certificates: []Certificate{*offerCertificate}})
+		assert.NoError(t, err)
+		answerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		done := make(chan struct{})
+		answerPC.OnConnectionStateChange(func(connectionState PeerConnectionState) {
+			if connectionState == expectedState {
+				close(done)
+			}
+		})
+
+		assert.NoError(t, signalPair(offerPC, answerPC))
+		<-done
+
+		assert.NoError(t, offerPC.Close())
+		return answerPC
+	}
+
+	t.Run("Allowed", func(t *testing.T) {
+		answerPC := runTest(t, offerFingerprints, PeerConnectionStateConnected)
+
+		profile, ok := answerPC.dtlsTransport.GetSRTPProtectionProfile()
+		assert.True(t, ok)
+		assert.Equal(t, dtls.SRTP_AES128_CM_HMAC_SHA1_80, profile)
+
+		assert.Equal(t, offerCertificate.X509Certificate().Raw, answerPC.dtlsTransport.GetRemoteCertificate())
+		assert.NoError(t, answerPC.Close())
+	})
+
+	t.Run("Not Allowed", func(t *testing.T) {
+		answerPC := runTest(t, []DTLSFingerprint{{Algorithm: "sha-256", Value: "AA:AA"}}, PeerConnectionStateFailed)
+
+		// Nothing is read from the rejected connection
+		answerPC.dtlsTransport.lock.RLock()
+		assert.Nil(t, answerPC.dtlsTransport.conn)
+		assert.Nil(t, answerPC.dtlsTransport.applicationDataConn)
+		answerPC.dtlsTransport.lock.RUnlock()
+		assert.NoError(t, answerPC.Close())
+	})
+}
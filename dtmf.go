@@ -0,0 +1,201 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// dtmfTones are the DTMF tones in the order of their telephone-event
+	// codes, RFC 4733 3.2
+	dtmfTones = "0123456789*#ABCD"
+
+	telephoneEventLength    = 4
+	telephoneEventEndBit    = 0x80
+	telephoneEventVolumeMax = 0x3F
+
+	telephoneEventMaxDuration = 0xFFFF
+
+	// dtmfVolume is the power level tones are sent with, -10 dBm0
+	dtmfVolume = 10
+)
+
+var errTelephoneEventTooShort = errors.New("telephone-event payload is too short")
+
+// telephoneEvent is the payload of a telephone-event packet, RFC 4733 2.3
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|     event     |E|R| volume    |          duration             |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+type telephoneEvent struct {
+	event    uint8
+	end      bool
+	volume   uint8
+	duration uint16
+}
+
+func (e telephoneEvent) marshal() []byte {
+	b := make([]byte, telephoneEventLength)
+	b[0] = e.event
+	b[1] = e.volume & telephoneEventVolumeMax
+	if e.end {
+		b[1] |= telephoneEventEndBit
+	}
+	binary.BigEndian.PutUint16(b[2:], e.duration)
+	return b
+}
+
+func (e *telephoneEvent) unmarshal(b []byte) error {
+	if len(b) < telephoneEventLength {
+		return errTelephoneEventTooShort
+	}
+	e.event = b[0]
+	e.end = b[1]&telephoneEventEndBit != 0
+	e.volume = b[1] & telephoneEventVolumeMax
+	e.duration = binary.BigEndian.Uint16(b[2:])
+	return nil
+}
+
+// dtmfEvent returns the telephone-event code of a DTMF tone
+func dtmfEvent(tone byte) (uint8, bool) {
+	i := strings.IndexByte(dtmfTones, tone)
+	return uint8(i), i >= 0
+}
+
+// dtmfTone returns the DTMF tone of a telephone-event code, events that are
+// not DTMF tones, like the ones of fax and modem tones, have none
+func dtmfTone(event uint8) (string, bool) {
+	if int(event) >= len(dtmfTones) {
+		return "", false
+	}
+	return dtmfTones[event : event+1], true
+}
+
+// DTMFTone is a DTMF tone received as telephone-events
+type DTMFTone struct {
+	// Tone is one of 0-9, A-D, # and *
+	Tone string
+
+	// Duration is how long the tone lasted so far, or in total once it ended
+	Duration time.Duration
+
+	// End is false when the tone starts and true when it ended
+	End bool
+}
+
+// dtmfDetector turns the telephone-events of a stream into DTMFTones. Every
+// packet of a tone repeats its start, its end is retransmitted, so a start
+// and an end are reported once per tone.
+type dtmfDetector struct {
+	mu sync.Mutex
+
+	started   bool
+	ended     bool
+	event     uint8
+	timestamp uint32
+
+	// segments counts the ticks of the segments of a long tone before the
+	// current one, RFC 4733 2.5.1.3
+	segments uint32
+}
+
+// detect parses a telephone-event packet, it returns the DTMFTone to report
+// if the packet starts or ends a tone
+func (d *dtmfDetector) detect(p *rtp.Packet, clockRate uint32) (DTMFTone, bool) {
+	e := telephoneEvent{}
+	if err := e.unmarshal(p.Payload); err != nil || clockRate == 0 {
+		return DTMFTone{}, false
+	}
+	tone, ok := dtmfTone(e.event)
+	if !ok {
+		return DTMFTone{}, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	isNew := true
+	if d.started && e.event == d.event {
+		switch p.Timestamp - d.timestamp {
+		case 0:
+			isNew = false
+		case telephoneEventMaxDuration:
+			if !d.ended {
+				isNew = false
+				d.segments += telephoneEventMaxDuration
+			}
+		}
+	}
+	if isNew {
+		d.segments = 0
+		d.started, d.ended = true, false
+	} else if d.ended {
+		// A retransmitted end
+		return DTMFTone{}, false
+	}
+	d.event, d.timestamp = e.event, p.Timestamp
+
+	detected := DTMFTone{
+		Tone:     tone,
+		Duration: time.Duration(uint64(d.segments+uint32(e.duration)) * uint64(time.Second) / uint64(clockRate)),
+		End:      e.end,
+	}
+	switch {
+	case e.end:
+		d.ended = true
+		return detected, true
+	case isNew:
+		return detected, true
+	default:
+		return DTMFTone{}, false
+	}
+}
+
+// dtmfToneQueue calls the OnDTMFTone handler from one goroutine at a time,
+// so it gets the start and the end of every tone in order
+type dtmfToneQueue struct {
+	mu          sync.Mutex
+	queue       []queuedDTMFTone
+	dispatching bool
+}
+
+type queuedDTMFTone struct {
+	tone DTMFTone
+	hdlr func(DTMFTone)
+}
+
+// push queues tone for hdlr, the handler set when it was detected
+func (q *dtmfToneQueue) push(tone DTMFTone, hdlr func(DTMFTone)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.queue = append(q.queue, queuedDTMFTone{tone, hdlr})
+	if !q.dispatching {
+		q.dispatching = true
+		go q.dispatch()
+	}
+}
+
+// dispatch calls the handlers of the queued tones until the queue is empty
+func (q *dtmfToneQueue) dispatch() {
+	for {
+		q.mu.Lock()
+		if len(q.queue) == 0 {
+			q.dispatching = false
+			q.mu.Unlock()
+			return
+		}
+		queued := q.queue[0]
+		q.queue = q.queue[1:]
+		q.mu.Unlock()
+
+		queued.hdlr(queued.tone)
+	}
+}
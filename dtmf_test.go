@@ -0,0 +1,101 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelephoneEvent(t *testing.T) {
+	e := telephoneEvent{event: 11, end: true, volume: 10, duration: 800}
+	raw := e.marshal()
+	assert.Equal(t, []byte{0x0B, 0x8A, 0x03, 0x20}, raw)
+
+	parsed := telephoneEvent{}
+	assert.NoError(t, parsed.unmarshal(raw))
+	assert.Equal(t, e, parsed)
+	assert.Equal(t, errTelephoneEventTooShort, parsed.unmarshal(raw[:3]))
+}
+
+func TestDTMFTones(t *testing.T) {
+	for i := range dtmfTones {
+		event, ok := dtmfEvent(dtmfTones[i])
+		assert.True(t, ok)
+		tone, ok := dtmfTone(event)
+		assert.True(t, ok)
+		assert.Equal(t, dtmfTones[i:i+1], tone)
+	}
+
+	_, ok := dtmfEvent('E')
+	assert.False(t, ok)
+	_, ok = dtmfTone(16)
+	assert.False(t, ok)
+}
+
+func TestDTMFDetector(t *testing.T) {
+	d := &dtmfDetector{}
+	detect := func(timestamp uint32, event uint8, end bool, duration uint16) (DTMFTone, bool) {
+		payload := telephoneEvent{event: event, end: end, duration: duration}.marshal()
+		return d.detect(&rtp.Packet{Header: rtp.Header{Timestamp: timestamp}, Payload: payload}, 8000)
+	}
+
+	tone, ok := detect(1000, 5, false, 400)
+	assert.True(t, ok)
+	assert.Equal(t, DTMFTone{Tone: "5", Duration: 50 * time.Millisecond}, tone)
+
+	_, ok = detect(1000, 5, false, 800)
+	assert.False(t, ok)
+
+	// The end is reported once
+	tone, ok = detect(1000, 5, true, 1200)
+	assert.True(t, ok)
+	assert.Equal(t, DTMFTone{Tone: "5", Duration: 150 * time.Millisecond, End: true}, tone)
+	for i := 0; i < 2; i++ {
+		_, ok = detect(1000, 5, true, 1200)
+		assert.False(t, ok)
+	}
+
+	// The same tone again starts at another timestamp, a lost start is
+	// reported with the end
+	tone, ok = detect(3000, 5, true, 800)
+	assert.True(t, ok)
+	assert.Equal(t, DTMFTone{Tone: "5", Duration: 100 * time.Millisecond, End: true}, tone)
+
+	// A long tone is sent in segments
+	_, ok = detect(5000, 10, false, 0xFFFF)
+	assert.True(t, ok)
+	_, ok = detect(5000+0xFFFF, 10, false, 400)
+	assert.False(t, ok)
+	tone, ok = detect(5000+0xFFFF, 10, true, 800)
+	assert.True(t, ok)
+	assert.Equal(t, DTMFTone{Tone: "*", Duration: (0xFFFF + 800) * time.Second / 8000, End: true}, tone)
+
+	// Events other than DTMF tones and malformed payloads are ignored
+	_, ok = detect(7000, 32, false, 400)
+	assert.False(t, ok)
+	_, ok = d.detect(&rtp.Packet{Payload: []byte{1}}, 8000)
+	assert.False(t, ok)
+}
+
+func TestDTMFToneQueue(t *testing.T) {
+	q := &dtmfToneQueue{}
+	received := make(chan DTMFTone, 100)
+	hdlr := func(tone DTMFTone) {
+		// A slow handler doesn't let the next tone overtake
+		time.Sleep(time.Millisecond)
+		received <- tone
+	}
+
+	for i := 0; i < 50; i++ {
+		q.push(DTMFTone{Tone: "1", Duration: time.Duration(i)}, hdlr)
+		q.push(DTMFTone{Tone: "1", Duration: time.Duration(i), End: true}, hdlr)
+	}
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, DTMFTone{Tone: "1", Duration: time.Duration(i)}, <-received)
+		assert.Equal(t, DTMFTone{Tone: "1", Duration: time.Duration(i), End: true}, <-received)
+	}
+}
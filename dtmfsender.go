@@ -0,0 +1,221 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/internal/util"
+)
+
+// DTMFSender sends DTMF tones on the stream of an audio RTPSender, as
+// telephone-events of RFC 4733. The PeerConnections have to negotiate a
+// telephone-event codec with the clock rate of the audio codec, see
+// NewRTPTelephoneEventCodec.
+type DTMFSender struct {
+	sender *RTPSender
+
+	mu               sync.Mutex
+	toneBuffer       string
+	duration, gap    time.Duration
+	playing          bool
+	onToneChangeHdlr func(tone string)
+}
+
+func newDTMFSender(sender *RTPSender) *DTMFSender {
+	return &DTMFSender{sender: sender}
+}
+
+// InsertDTMF plays tones, a string of the tones 0-9, A-D, # and *. A comma
+// pauses for two seconds. Every tone lasts duration, which is clamped to
+// between 40ms and 6s, and is followed by gap, which is at least 30ms. Zero
+// durations choose the defaults of 100ms and 70ms.
+//
+// The tones replace the ones not played yet, an empty string cancels them.
+// The tone being played is finished.
+func (d *DTMFSender) InsertDTMF(tones string, duration, gap time.Duration) error {
+	tones = strings.ToUpper(tones)
+	for i := range tones {
+		if _, ok := dtmfEvent(tones[i]); !ok && tones[i] != ',' {
+			return ErrInvalidDTMFTone
+		}
+	}
+	if !d.CanInsertDTMF() {
+		return ErrNoTelephoneEventCodec
+	}
+
+	switch {
+	case duration == 0:
+		duration = dtmfDefaultDuration
+	case duration < dtmfMinDuration:
+		duration = dtmfMinDuration
+	case duration > dtmfMaxDuration:
+		duration = dtmfMaxDuration
+	}
+	if gap == 0 {
+		gap = dtmfDefaultGap
+	} else if gap < dtmfMinGap {
+		gap = dtmfMinGap
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.toneBuffer = tones
+	d.duration, d.gap = duration, gap
+	if !d.playing && tones != "" {
+		d.playing = true
+		go d.play()
+	}
+	return nil
+}
+
+// CanInsertDTMF reports whether the RTPSender is sending and the remote
+// accepts a telephone-event codec with the clock rate of the Track, so tones
+// can be played
+func (d *DTMFSender) CanInsertDTMF() bool {
+	_, _, ok := d.sender.telephoneEventCodec()
+	return ok
+}
+
+// ToneBuffer returns the tones not played yet
+func (d *DTMFSender) ToneBuffer() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.toneBuffer
+}
+
+// OnToneChange sets an event handler which is called when a tone starts to
+// be played, and with an empty string once all tones were played
+func (d *DTMFSender) OnToneChange(f func(tone string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onToneChangeHdlr = f
+}
+
+func (d *DTMFSender) onToneChange(tone string) {
+	d.mu.Lock()
+	hdlr := d.onToneChangeHdlr
+	d.mu.Unlock()
+
+	// Called in the playing goroutine, so the tones are reported in order
+	if hdlr != nil {
+		hdlr(tone)
+	}
+}
+
+// play plays the tone buffer until it is empty
+func (d *DTMFSender) play() {
+	for {
+		d.mu.Lock()
+		if d.toneBuffer == "" {
+			d.playing = false
+			d.mu.Unlock()
+			d.onToneChange("")
+			return
+		}
+		tone := d.toneBuffer[0]
+		d.toneBuffer = d.toneBuffer[1:]
+		duration, gap := d.duration, d.gap
+		d.mu.Unlock()
+
+		d.onToneChange(string(tone))
+		var played bool
+		if tone == ',' {
+			played = d.sleep(dtmfCommaPause)
+		} else {
+			played = d.sendTone(tone, duration) == nil && d.sleep(gap)
+		}
+
+		if !played {
+			d.mu.Lock()
+			d.toneBuffer = ""
+			d.playing = false
+			d.mu.Unlock()
+			return
+		}
+	}
+}
+
+// sendTone sends the telephone-event of tone every dtmfPacketInterval while
+// it lasts, followed by its end
+func (d *DTMFSender) sendTone(tone byte, duration time.Duration) error {
+	track, payloadType, ok := d.sender.telephoneEventCodec()
+	if !ok {
+		return ErrNoTelephoneEventCodec
+	}
+	event, _ := dtmfEvent(tone)
+	clockRate := track.Codec().ClockRate
+
+	track.mu.RLock()
+	timestamp := track.timestamp
+	track.mu.RUnlock()
+
+	// Before any media the tone starts the stream
+	initialSequenceNumber := uint16(util.RandUint32())
+
+	// A tone too long for the duration field is sent in segments that each
+	// start where the one before ended, RFC 4733 2.5.1.3
+	var segment uint32
+	send := func(elapsed time.Duration, marker, end bool) error {
+		ticks := uint32(uint64(elapsed) * uint64(clockRate) / uint64(time.Second))
+		for ticks-segment > telephoneEventMaxDuration {
+			segment += telephoneEventMaxDuration
+		}
+
+		header := &rtp.Header{
+			Version:        2,
+			Marker:         marker,
+			PayloadType:    payloadType,
+			SequenceNumber: d.sender.padding.reserve(initialSequenceNumber),
+			Timestamp:      timestamp + segment,
+			SSRC:           track.SSRC(),
+		}
+		payload := telephoneEvent{
+			event:    event,
+			end:      end,
+			volume:   dtmfVolume,
+			duration: uint16(ticks - segment),
+		}
+		_, err := d.sender.sendRTP(header, payload.marshal(), false)
+		return err
+	}
+
+	// Each packet tells how long the tone lasted so far
+	for elapsed := time.Duration(0); elapsed < duration; {
+		next := elapsed + dtmfPacketInterval
+		if next > duration {
+			next = duration
+		}
+		if err := send(next, elapsed == 0, false); err != nil {
+			return err
+		}
+		if !d.sleep(next - elapsed) {
+			return ErrSenderStopped
+		}
+		elapsed = next
+	}
+
+	for i := 0; i < dtmfEndPackets; i++ {
+		if err := send(duration, false, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sleep waits for d, it reports false if the RTPSender was stopped first
+func (d *DTMFSender) sleep(duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-d.sender.stopCalled:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,183 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDTMFPair(t *testing.T) (pcOffer, pcAnswer *PeerConnection) {
+	mediaEngine := MediaEngine{}
+	mediaEngine.RegisterDefaultCodecs()
+	mediaEngine.RegisterCodec(NewRTPTelephoneEventCodec(DefaultPayloadTypeTelephoneEvent, 48000))
+	api := NewAPI(WithMediaEngine(mediaEngine))
+
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err = api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	return pcOffer, pcAnswer
+}
+
+func TestDTMFSender(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer := newDTMFPair(t)
+
+	videoTrack, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	videoSender, err := pcOffer.AddTrack(videoTrack)
+	assert.NoError(t, err)
+	assert.Nil(t, videoSender.DTMF())
+
+	audioTrack, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion")
+	assert.NoError(t, err)
+	audioSender, err := pcOffer.AddTrack(audioTrack)
+	assert.NoError(t, err)
+
+	dtmf := audioSender.DTMF()
+	assert.Equal(t, dtmf, audioSender.DTMF())
+	assert.False(t, dtmf.CanInsertDTMF())
+	assert.Equal(t, ErrNoTelephoneEventCodec, dtmf.InsertDTMF("1", 0, 0))
+	assert.Equal(t, ErrInvalidDTMFTone, dtmf.InsertDTMF("1E", 0, 0))
+
+	events := make(chan telephoneEvent, 64)
+	received := make(chan DTMFTone, 4)
+	var gaps int32
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		r.OnDTMFTone(func(tone DTMFTone) {
+			received <- tone
+		})
+
+		var lastSequenceNumber uint16
+		for started := false; ; started = true {
+			p, readErr := track.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			if started && p.SequenceNumber != lastSequenceNumber+1 {
+				atomic.AddInt32(&gaps, 1)
+			}
+			lastSequenceNumber = p.SequenceNumber
+			if p.PayloadType != DefaultPayloadTypeTelephoneEvent {
+				continue
+			}
+			e := telephoneEvent{}
+			assert.NoError(t, e.unmarshal(p.Payload))
+			events <- e
+		}
+	})
+
+	connected := make(chan struct{})
+	pcOffer.OnICEConnectionStateChange(func(state ICEConnectionState) {
+		if state == ICEConnectionStateConnected {
+			close(connected)
+		}
+	})
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-connected
+
+	stopAudio := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stopAudio:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			_ = audioTrack.WriteSample(media.Sample{Data: []byte{0xF8, 0xFF, 0xFE}, Samples: 960})
+		}
+	}()
+
+	var mu sync.Mutex
+	tones := []string{}
+	played := make(chan struct{})
+	dtmf.OnToneChange(func(tone string) {
+		mu.Lock()
+		defer mu.Unlock()
+		tones = append(tones, tone)
+		if tone == "" {
+			close(played)
+		}
+	})
+
+	assert.True(t, dtmf.CanInsertDTMF())
+	assert.NoError(t, dtmf.InsertDTMF("1#", 100*time.Millisecond, 0))
+	assert.Equal(t, "#", dtmf.ToneBuffer())
+	<-played
+	assert.Equal(t, []string{"1", "#", ""}, tones)
+
+	// Every tone lasts 100ms, 4800 ticks at 48kHz, and its end is sent three
+	// times
+	for _, event := range []uint8{1, 11} {
+		ends := 0
+		for ends < dtmfEndPackets {
+			e := <-events
+			assert.Equal(t, event, e.event)
+			if e.end {
+				ends++
+				assert.Equal(t, uint16(4800), e.duration)
+			} else {
+				assert.True(t, e.duration <= 4800)
+			}
+		}
+	}
+
+	// The receiver reports the start and the end of every tone
+	for _, tone := range []string{"1", "#"} {
+		start := <-received
+		assert.Equal(t, tone, start.Tone)
+		assert.False(t, start.End)
+		assert.Equal(t, DTMFTone{Tone: tone, Duration: 100 * time.Millisecond, End: true}, <-received)
+	}
+
+	// The telephone-events continue the sequence numbers of the audio
+	assert.Equal(t, int32(0), atomic.LoadInt32(&gaps))
+
+	close(stopAudio)
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestDTMFSender_NotNegotiated(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// Only the offerer has a telephone-event codec
+	mediaEngine := MediaEngine{}
+	mediaEngine.RegisterDefaultCodecs()
+	mediaEngine.RegisterCodec(NewRTPTelephoneEventCodec(DefaultPayloadTypeTelephoneEvent, 48000))
+	pcOffer, err := NewAPI(WithMediaEngine(mediaEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	audioTrack, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion")
+	assert.NoError(t, err)
+	audioSender, err := pcOffer.AddTrack(audioTrack)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	for !audioSender.hasSent() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.False(t, audioSender.DTMF().CanInsertDTMF())
+	assert.Equal(t, ErrNoTelephoneEventCodec, audioSender.DTMF().InsertDTMF("1", 0, 0))
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -4,6 +4,22 @@ import (
 	"errors"
 )
 
+// The base errors, the errors of the RTPSenders, RTPReceivers, Tracks and of
+// the negotiation wrap one of them so they can be matched with errors.Is
+var (
+	// ErrInvalidState indicates an operation that the object can't perform
+	// in its current state, e.g. after it was stopped
+	ErrInvalidState = errors.New("invalid state")
+
+	// ErrInvalidArgument indicates an operation was called with an argument
+	// it doesn't accept
+	ErrInvalidArgument = errors.New("invalid argument")
+
+	// ErrNegotiationFailed indicates the session descriptions or the DTLS
+	// handshake didn't agree on something required
+	ErrNegotiationFailed = errors.New("negotiation failed")
+)
+
 var (
 	// ErrUnknownType indicates an error with Unknown info.
 	ErrUnknownType = errors.New("unknown")
@@ -34,6 +50,18 @@ var (
 	// chosen to generate a certificate is not supported.
 	ErrPrivateKeyType = errors.New("private key type not supported")
 
+	// ErrCertificatePEM indicates that a PEM encoded Certificate does not
+	// have both a private key and a certificate.
+	ErrCertificatePEM = errors.New("PEM must contain a private key and a certificate")
+
+	// ErrSRTPProtectionProfile indicates that an SRTP protection profile is
+	// not supported.
+	ErrSRTPProtectionProfile = errors.New("SRTP protection profile not supported")
+
+	// ErrRemoteFingerprintNotAllowed indicates the remote certificate does not
+	// match any of the fingerprints set with SettingEngine.SetDTLSRemoteFingerprints.
+	ErrRemoteFingerprintNotAllowed = errors.New("remote certificate does not match an allowed fingerprint")
+
 	// ErrModifyingPeerIdentity indicates that an attempt to modify
 	// PeerIdentity was made after PeerConnection has been initialized.
 	ErrModifyingPeerIdentity = errors.New("peerIdentity cannot be modified")
@@ -84,6 +112,30 @@ var (
 	// generate SDP Answers with different SDP Semantics than the received Offer
 	ErrIncorrectSDPSemantics = errors.New("offer SDP semantics does not match configuration")
 
+	// ErrDataChannelMessageTooLarge indicates that a message passed to
+	// DataChannel.Send is larger than SCTPTransport.MaxMessageSize.
+	ErrDataChannelMessageTooLarge = errors.New("message is larger than the max message size")
+
+	// ErrSCTPAssociationClosed indicates that the SCTP association was closed
+	// by the remote peer or the network, without a call to SCTPTransport.Stop.
+	ErrSCTPAssociationClosed = errors.New("SCTP association closed unexpectedly")
+
+	// ErrICEConsentExpired indicates that a packet was not sent because the
+	// ICETransport failed and lost consent to send, see RFC 7675.
+	ErrICEConsentExpired = errors.New("ICE consent to send expired")
+
+	// ErrDataChannelNotReliable indicates that a ChunkedDataChannel was created
+	// on a DataChannel that is unordered or may drop messages.
+	ErrDataChannelNotReliable = errors.New("data channel is not ordered and reliable")
+
+	// ErrChunkedMessageTooLarge indicates that a ChunkedDataChannel dropped a
+	// received message larger than its max message size.
+	ErrChunkedMessageTooLarge = errors.New("chunked message is larger than the max message size")
+
+	// ErrChunkedMessageInvalid indicates that a ChunkedDataChannel received a
+	// message without a chunk header.
+	ErrChunkedMessageInvalid = errors.New("message has no chunk header")
+
 	// ErrProtocolTooLarge indicates that value given for a DataChannelInit protocol is
 	//longer then 65535 bytes
 	ErrProtocolTooLarge = errors.New("protocol is larger then 65535 bytes")
@@ -104,6 +156,10 @@ var (
 	// has an conflicting fingerprints
 	ErrSessionDescriptionConflictingFingerprints = errors.New("SetRemoteDescription called with multiple conflicting fingerprint")
 
+	// ErrSessionDescriptionMissingRTCPMux indicates SetRemoteDescription was called with a SessionDescription that
+	// does not multiplex RTCP with RTP in every media section
+	ErrSessionDescriptionMissingRTCPMux = errors.New("SetRemoteDescription called with a media section without rtcp-mux")
+
 	// ErrSessionDescriptionMissingIceUfrag indicates SetRemoteDescription was called with a SessionDescription that
 	// is missing an ice-ufrag value
 	ErrSessionDescriptionMissingIceUfrag = errors.New("SetRemoteDescription called with no ice-ufrag")
@@ -119,4 +175,193 @@ var (
 	// ErrSessionDescriptionConflictingIcePwd indicates SetRemoteDescription was called with a SessionDescription that
 	// contains multiple conflicting ice-pwd values
 	ErrSessionDescriptionConflictingIcePwd = errors.New("SetRemoteDescription called with multiple conflicting ice-pwd values")
+
+	// ErrRTPEncodingSimulcast indicates more than one SendEncodings were given to AddTransceiver,
+	// a RTPSender only sends the stream of its Track
+	ErrRTPEncodingSimulcast = errors.New("sending simulcast with multiple SendEncodings is not supported")
+
+	// ErrICECandidateUfragMismatch indicates AddICECandidate was called with a candidate whose
+	// usernameFragment does not match the one of the current RemoteDescription
+	ErrICECandidateUfragMismatch = errors.New("ICE candidate usernameFragment does not match remote description")
+
+	// ErrNilTransport indicates an RTPSender or RTPReceiver was constructed without a transport
+	ErrNilTransport = newWrappedError(ErrInvalidArgument, "DTLSTransport must not be nil")
+
+	// ErrNilTrack indicates an RTPSender was given a nil Track
+	ErrNilTrack = newWrappedError(ErrInvalidArgument, "track must not be nil")
+
+	// ErrSenderStopped indicates an operation on an RTPSender that was stopped
+	ErrSenderStopped = newWrappedError(ErrInvalidState, "RTPSender has been stopped")
+
+	// ErrSenderWithRemoteTrack indicates an RTPSender was given a remote Track to send
+	ErrSenderWithRemoteTrack = newWrappedError(ErrInvalidArgument, "RTPSender can not be constructed with remote track")
+
+	// ErrSendAlreadyCalled indicates RTPSender.Send was called a second time
+	ErrSendAlreadyCalled = newWrappedError(ErrInvalidState, "Send has already been called")
+
+	// ErrReceiveAlreadyCalled indicates RTPReceiver.Receive was called a second time
+	ErrReceiveAlreadyCalled = newWrappedError(ErrInvalidState, "Receive has already been called")
+
+	// ErrTrackKindMismatch indicates ReplaceTrack was called with a Track of another kind
+	ErrTrackKindMismatch = newWrappedError(ErrInvalidArgument, "new track kind does not match original")
+
+	// ErrTrackChannelsMismatch indicates ReplaceTrack was called with an audio Track that has
+	// a different number of channels, which requires renegotiation
+	ErrTrackChannelsMismatch = newWrappedError(ErrInvalidArgument, "new track has different number of channels from original")
+
+	// ErrReadLocalTrack indicates a Track created to send media was read from
+	ErrReadLocalTrack = newWrappedError(ErrInvalidState, "this is a local track and must not be read from")
+
+	// ErrCloneLocalTrack indicates a Track created to send media was cloned
+	ErrCloneLocalTrack = newWrappedError(ErrInvalidState, "this is a local track and can not be cloned")
+
+	// ErrWriteRemoteTrack indicates a Track receiving media was written to
+	ErrWriteRemoteTrack = newWrappedError(ErrInvalidState, "this is a remote track and must not be written to")
+
+	// ErrZeroSSRC indicates NewTrack was called with an SSRC of 0
+	ErrZeroSSRC = newWrappedError(ErrInvalidArgument, "SSRC supplied to NewTrack() must be non-zero")
+
+	// ErrNoRTPSession indicates RTP or RTCP was used before the DTLSTransport started, so
+	// there is no SRTP session yet
+	ErrNoRTPSession = newWrappedError(ErrInvalidState, "the DTLS transport has not started yet")
+
+	// ErrDTLSNotStarted indicates raw application data was written before the
+	// DTLS handshake completed
+	ErrDTLSNotStarted = errors.New("the DTLS transport has not completed its handshake yet")
+
+	// ErrNoSRTPProtectionProfile indicates the DTLS handshake did not negotiate an SRTP
+	// protection profile
+	ErrNoSRTPProtectionProfile = newWrappedError(ErrNegotiationFailed, "no SRTP protection profile was negotiated")
+
+	// ErrMediaSectionMissingMid indicates a RemoteDescription has a media section without a mid
+	ErrMediaSectionMissingMid = newWrappedError(ErrNegotiationFailed, "RemoteDescription contained media section without mid value")
+
+	// ErrTransceiverNotFound indicates no RTPTransceiver matches a media section of the
+	// RemoteDescription
+	ErrTransceiverNotFound = newWrappedError(ErrNegotiationFailed, "cannot find transceiver with mid")
+
+	// ErrOneTransceiverInit indicates AddTransceiverFromKind or AddTransceiverFromTrack was
+	// called with more than one RtpTransceiverInit
+	ErrOneTransceiverInit = newWrappedError(ErrInvalidArgument, "only one RtpTransceiverInit may be given")
+
+	// ErrTransceiverDirectionNotSupported indicates AddTransceiverFromKind or
+	// AddTransceiverFromTrack was called with a direction they don't support
+	ErrTransceiverDirectionNotSupported = newWrappedError(ErrInvalidArgument, "transceiver direction not supported")
+
+	// ErrCodecPayloaderNotSet indicates PeerConnection.NewTrack was called with a codec that
+	// has no Payloader
+	ErrCodecPayloaderNotSet = newWrappedError(ErrInvalidArgument, "codec payloader not set")
+
+	// ErrMediaEngineInUse indicates the first remote offer was set with
+	// SettingEngine.PopulateMediaEngineFromRemoteOffer after transceivers were
+	// created with the codecs of the API
+	ErrMediaEngineInUse = newWrappedError(ErrInvalidState, "MediaEngine can not be populated from the remote offer once transceivers exist")
+
+	// ErrIdentityProviderNotSupported indicates an identity provider was
+	// configured or an identity assertion requested, which isn't implemented
+	ErrIdentityProviderNotSupported = newWrappedError(ErrInvalidState, "identity providers are not supported")
+
+	// ErrInvalidSDPType indicates a SessionDescription with a type that isn't
+	// valid for the operation was set
+	ErrInvalidSDPType = newWrappedError(ErrNegotiationFailed, "invalid SDP type")
+
+	// ErrSDPDoesNotMatchOffer indicates SetLocalDescription was called with an
+	// offer that isn't the last one CreateOffer returned
+	ErrSDPDoesNotMatchOffer = newWrappedError(ErrNegotiationFailed, "new sdp does not match previous offer")
+
+	// ErrSDPDoesNotMatchAnswer indicates SetLocalDescription was called with an
+	// answer that isn't the last one CreateAnswer returned
+	ErrSDPDoesNotMatchAnswer = newWrappedError(ErrNegotiationFailed, "new sdp does not match previous answer")
+
+	// ErrInvalidStateChange indicates a session description can't be set in
+	// the current signaling state
+	ErrInvalidStateChange = newWrappedError(ErrInvalidState, "invalid state change op")
+
+	// ErrSRTPSessionKeys indicates the SRTP keys could not be extracted from
+	// the DTLS connection
+	ErrSRTPSessionKeys = newWrappedError(ErrNegotiationFailed, "failed to extract SRTP session keys")
+
+	// ErrSRTPSessionStart indicates the SRTP or SRTCP session could not be
+	// started once the DTLS handshake completed
+	ErrSRTPSessionStart = newWrappedError(ErrNegotiationFailed, "failed to start srtp")
+
+	// ErrNoRemoteCertificate indicates the remote didn't provide a certificate
+	// in the DTLS handshake
+	ErrNoRemoteCertificate = newWrappedError(ErrNegotiationFailed, "peer didn't provide certificate via DTLS")
+
+	// ErrNoMatchingFingerprint indicates the certificate of the remote matches
+	// none of the fingerprints of its session description
+	ErrNoMatchingFingerprint = newWrappedError(ErrNegotiationFailed, "no matching fingerprint")
+
+	// ErrICEConnectionNotStarted indicates a DTLSTransport or QUICTransport
+	// was started before its ICETransport
+	ErrICEConnectionNotStarted = newWrappedError(ErrInvalidState, "ICE connection not started")
+
+	// ErrDTLSTransportNotNew indicates a DTLSTransport was started a second
+	// time
+	ErrDTLSTransportNotNew = newWrappedError(ErrInvalidState, "attempted to start DTLSTransport that is not in new state")
+
+	// ErrRTCPWriteStream indicates the stream to write RTCP on could not be
+	// opened
+	ErrRTCPWriteStream = newWrappedError(ErrInvalidState, "WriteRTCP failed to open WriteStream")
+
+	// ErrInvalidDTMFTone indicates DTMFSender.InsertDTMF was called with a
+	// tone that isn't one of 0-9, A-D, #, * or a comma
+	ErrInvalidDTMFTone = errors.New("invalid DTMF tone")
+
+	// ErrNoTelephoneEventCodec indicates DTMFSender.InsertDTMF was called
+	// while no telephone-event codec with the clock rate of the track is
+	// negotiated
+	ErrNoTelephoneEventCodec = errors.New("no telephone-event codec for the clock rate of the track")
+
+	// ErrInvalidVideoRotation indicates RTPSender.SetVideoOrientation was
+	// called with a rotation that isn't 0, 90, 180 or 270 degrees
+	ErrInvalidVideoRotation = errors.New("video rotation must be 0, 90, 180 or 270 degrees")
+
+	// ErrInvalidPlayoutDelay indicates RTPSender.SetPlayoutDelay was called
+	// with a minimum above the maximum, or a delay out of 0 to 40.95 seconds
+	ErrInvalidPlayoutDelay = errors.New("playout delay must be between 0 and 40.95 seconds, the minimum not above the maximum")
+
+	// ErrRTPNotAccessible indicates a method reading or writing RTP or RTCP
+	// packets was called in the browser, whose WebRTC API doesn't expose them
+	ErrRTPNotAccessible = errors.New("the browser WebRTC API gives no access to RTP and RTCP packets")
 )
+
+// wrappedError is an error of its own that unwraps to a base error
+type wrappedError struct {
+	base error
+	msg  string
+}
+
+func newWrappedError(base error, msg string) error {
+	return &wrappedError{base: base, msg: msg}
+}
+
+func (e *wrappedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the base error
+func (e *wrappedError) Unwrap() error {
+	return e.base
+}
+
+// detailedError adds a detail to the message of an error and unwraps to it,
+// like fmt.Errorf with %w which needs Go 1.13
+type detailedError struct {
+	err    error
+	detail string
+}
+
+func errorWithDetail(err error, detail string) error {
+	return &detailedError{err: err, detail: detail}
+}
+
+func (e *detailedError) Error() string {
+	return e.err.Error() + ": " + e.detail
+}
+
+// Unwrap returns the error the detail was added to
+func (e *detailedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,33 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorHierarchy(t *testing.T) {
+	unwrap := func(err error) error {
+		wrapper, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return nil
+		}
+		return wrapper.Unwrap()
+	}
+
+	assert.Equal(t, ErrInvalidState, unwrap(ErrSenderStopped))
+	assert.Equal(t, "RTPSender has been stopped", ErrSenderStopped.Error())
+	assert.Equal(t, ErrInvalidArgument, unwrap(ErrTrackKindMismatch))
+	assert.Equal(t, ErrNegotiationFailed, unwrap(ErrTransceiverNotFound))
+
+	err := errorWithDetail(ErrTransceiverNotFound, `"0"`)
+	assert.Equal(t, `cannot find transceiver with mid: "0"`, err.Error())
+	assert.Equal(t, ErrTransceiverNotFound, unwrap(err))
+	assert.Equal(t, ErrNegotiationFailed, unwrap(unwrap(err)))
+
+	// The negotiation and DTLS errors are part of it as well
+	assert.Equal(t, ErrNegotiationFailed, unwrap(ErrSDPDoesNotMatchOffer))
+	assert.Equal(t, ErrNegotiationFailed, unwrap(ErrNoMatchingFingerprint))
+	assert.Equal(t, ErrInvalidState, unwrap(ErrICEConnectionNotStarted))
+	assert.Equal(t, ErrInvalidState, unwrap(unwrap(errorWithDetail(ErrInvalidStateChange, "SetLocal(answer)"))))
+}
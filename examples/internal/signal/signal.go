@@ -4,15 +4,12 @@ package signal
 
 import (
 	"bufio"
-	"bytes"
-	"compress/gzip"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"strings"
+
+	"github.com/pion/webrtc/v2/pkg/signaling"
 )
 
 // Allows compressing offer/answer to bypass terminal input limits.
@@ -45,67 +42,22 @@ func MustReadStdin() string {
 // Encode encodes the input in base64
 // It can optionally zip the input before encoding
 func Encode(obj interface{}) string {
-	b, err := json.Marshal(obj)
-	if err != nil {
-		panic(err)
-	}
-
-	if compress {
-		b = zip(b)
-	}
-
-	return base64.StdEncoding.EncodeToString(b)
-}
-
-// Decode decodes the input from base64
-// It can optionally unzip the input after decoding
-func Decode(in string, obj interface{}) {
-	b, err := base64.StdEncoding.DecodeString(in)
-	if err != nil {
-		panic(err)
-	}
-
+	encode := signaling.Encode
 	if compress {
-		b = unzip(b)
-	}
-
-	err = json.Unmarshal(b, obj)
-	if err != nil {
-		panic(err)
+		encode = signaling.EncodeCompressed
 	}
-}
 
-func zip(in []byte) []byte {
-	var b bytes.Buffer
-	gz := gzip.NewWriter(&b)
-	_, err := gz.Write(in)
-	if err != nil {
-		panic(err)
-	}
-	err = gz.Flush()
-	if err != nil {
-		panic(err)
-	}
-	err = gz.Close()
+	out, err := encode(obj)
 	if err != nil {
 		panic(err)
 	}
-	return b.Bytes()
+	return out
 }
 
-func unzip(in []byte) []byte {
-	var b bytes.Buffer
-	_, err := b.Write(in)
-	if err != nil {
-		panic(err)
-	}
-	r, err := gzip.NewReader(&b)
-	if err != nil {
-		panic(err)
-	}
-	res, err := ioutil.ReadAll(r)
-	if err != nil {
+// Decode decodes the input from base64
+// It unzips the input if it was zipped
+func Decode(in string, obj interface{}) {
+	if err := signaling.Decode(in, obj); err != nil {
 		panic(err)
 	}
-	return res
 }
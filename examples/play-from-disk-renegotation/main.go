@@ -11,6 +11,7 @@ import (
 	"github.com/pion/webrtc/v2"
 	"github.com/pion/webrtc/v2/pkg/media"
 	"github.com/pion/webrtc/v2/pkg/media/ivfreader"
+	"github.com/pion/webrtc/v2/pkg/media/pacer"
 )
 
 var peerConnection *webrtc.PeerConnection //nolint
@@ -119,7 +120,8 @@ func writeVideoToTrack(t *webrtc.Track) {
 
 	// Send our video file frame at a time. Pace our sending so we send it at the same speed it should be played back as.
 	// This isn't required since the video is timestamped, but we will such much higher loss if we send all at once.
-	sleepTime := time.Millisecond * time.Duration((float32(header.TimebaseNumerator)/float32(header.TimebaseDenominator))*1000)
+	frameDuration := time.Duration(float64(time.Second) * float64(header.TimebaseNumerator) / float64(header.TimebaseDenominator))
+	p := pacer.New(t, int(t.Codec().ClockRate))
 	for {
 		frame, _, err := ivf.ParseNextFrame()
 		if err != nil {
@@ -127,8 +129,7 @@ func writeVideoToTrack(t *webrtc.Track) {
 			return
 		}
 
-		time.Sleep(sleepTime)
-		if err = t.WriteSample(media.Sample{Data: frame, Samples: 90000}); err != nil {
+		if err = p.WriteSample(media.Sample{Data: frame, Samples: media.NSamples(frameDuration, int(t.Codec().ClockRate))}); err != nil {
 			fmt.Printf("Finish writing video track: %s ", err)
 			return
 		}
@@ -3,14 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
 	"math/rand"
 	"os"
-	"time"
 
 	"github.com/pion/webrtc/v2"
 	"github.com/pion/webrtc/v2/examples/internal/signal"
-	"github.com/pion/webrtc/v2/pkg/media"
 	"github.com/pion/webrtc/v2/pkg/media/ivfreader"
 	"github.com/pion/webrtc/v2/pkg/media/oggreader"
 )
@@ -74,7 +71,7 @@ func main() {
 				panic(ivfErr)
 			}
 
-			ivf, header, ivfErr := ivfreader.NewWith(file)
+			ivf, _, ivfErr := ivfreader.NewWith(file)
 			if ivfErr != nil {
 				panic(ivfErr)
 			}
@@ -82,25 +79,14 @@ func main() {
 			// Wait for connection established
 			<-iceConnectedCtx.Done()
 
-			// Send our video file frame at a time. Pace our sending so we send it at the same speed it should be played back as.
+			// Send our video file frame at a time. Play paces our sending so we send it at the same speed it should be played back as.
 			// This isn't required since the video is timestamped, but we will such much higher loss if we send all at once.
-			sleepTime := time.Millisecond * time.Duration((float32(header.TimebaseNumerator)/float32(header.TimebaseDenominator))*1000)
-			for {
-				frame, _, ivfErr := ivf.ParseNextFrame()
-				if ivfErr == io.EOF {
-					fmt.Printf("All video frames parsed and sent")
-					os.Exit(0)
-				}
-
-				if ivfErr != nil {
-					panic(ivfErr)
-				}
-
-				time.Sleep(sleepTime)
-				if ivfErr = videoTrack.WriteSample(media.Sample{Data: frame, Samples: 90000}); ivfErr != nil {
-					panic(ivfErr)
-				}
+			if ivfErr = ivf.Play(videoTrack); ivfErr != nil {
+				panic(ivfErr)
 			}
+
+			fmt.Printf("All video frames parsed and sent")
+			os.Exit(0)
 		}()
 	}
 
@@ -130,30 +116,13 @@ func main() {
 			// Wait for connection established
 			<-iceConnectedCtx.Done()
 
-			// Keep track of last granule, the difference is the amount of samples in the buffer
-			var lastGranule uint64
-			for {
-				pageData, pageHeader, oggErr := ogg.ParseNextPage()
-				if oggErr == io.EOF {
-					fmt.Printf("All audio pages parsed and sent")
-					os.Exit(0)
-				}
-
-				if oggErr != nil {
-					panic(oggErr)
-				}
-
-				// The amount of samples is the difference between the last and current timestamp
-				sampleCount := float64((pageHeader.GranulePosition - lastGranule))
-				lastGranule = pageHeader.GranulePosition
-
-				if oggErr = audioTrack.WriteSample(media.Sample{Data: pageData, Samples: uint32(sampleCount)}); oggErr != nil {
-					panic(oggErr)
-				}
-
-				// Convert seconds to Milliseconds, Sleep doesn't accept floats
-				time.Sleep(time.Duration((sampleCount/48000)*1000) * time.Millisecond)
+			// Play paces the pages using their granule positions, the difference is the amount of samples in the page
+			if oggErr = ogg.Play(audioTrack); oggErr != nil {
+				panic(oggErr)
 			}
+
+			fmt.Printf("All audio pages parsed and sent")
+			os.Exit(0)
 		}()
 	}
 
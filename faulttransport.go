@@ -0,0 +1,233 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// LossModel decides which packets are lost, it is called once per packet
+type LossModel interface {
+	Lose(r *rand.Rand) bool
+}
+
+// UniformLoss loses every packet with the same probability
+type UniformLoss float64
+
+// Lose implements LossModel
+func (l UniformLoss) Lose(r *rand.Rand) bool {
+	return r.Float64() < float64(l)
+}
+
+// GilbertElliottLoss is the two state loss model of Gilbert and Elliott,
+// where losses come in bursts while the channel is in the bad state.
+type GilbertElliottLoss struct {
+	// GoodToBad and BadToGood are the probabilities to change state after
+	// each packet
+	GoodToBad float64
+	BadToGood float64
+
+	// LossGood and LossBad are the probabilities a packet is lost in each
+	// state, usually 0 and 1
+	LossGood float64
+	LossBad  float64
+
+	bad bool
+}
+
+// Lose implements LossModel
+func (l *GilbertElliottLoss) Lose(r *rand.Rand) bool {
+	if l.bad {
+		l.bad = r.Float64() >= l.BadToGood
+	} else {
+		l.bad = r.Float64() < l.GoodToBad
+	}
+
+	if l.bad {
+		return r.Float64() < l.LossBad
+	}
+	return r.Float64() < l.LossGood
+}
+
+// FaultConfig describes the faults injected into the packets written to
+// one session of a FaultTransport
+type FaultConfig struct {
+	// Loss drops packets, nil loses none
+	Loss LossModel
+
+	// Delay and Jitter postpone every packet by Delay plus a random duration
+	// up to Jitter, packets overtake each other when Jitter is larger than
+	// their spacing
+	Delay  time.Duration
+	Jitter time.Duration
+
+	// Duplicate is the probability a packet is written twice
+	Duplicate float64
+
+	// Reorder is the probability a packet is held back by ReorderDelay, so
+	// the packets written after it overtake it
+	Reorder      float64
+	ReorderDelay time.Duration
+
+	// Seed seeds the random decisions, so runs can be reproduced
+	Seed int64
+}
+
+// FaultTransport wraps a Transport and drops, delays, duplicates and
+// reorders the RTP and RTCP packets written through it, to test how an
+// application copes with an impaired network. It is given to NewRTPSender or
+// NewRTPReceiver in place of the DTLSTransport.
+type FaultTransport struct {
+	Transport
+
+	rtp, rtcp *faultInjector
+}
+
+// NewFaultTransport wraps transport, injecting rtpFaults into the RTP and
+// rtcpFaults into the RTCP it sends. A nil config leaves the packets of its
+// session untouched.
+func NewFaultTransport(transport Transport, rtpFaults, rtcpFaults *FaultConfig) *FaultTransport {
+	return &FaultTransport{
+		Transport: transport,
+		rtp:       newFaultInjector(rtpFaults),
+		rtcp:      newFaultInjector(rtcpFaults),
+	}
+}
+
+// RTPSession returns the RTP session of the wrapped Transport
+func (t *FaultTransport) RTPSession() (rtp.Session, error) {
+	session, err := t.Transport.RTPSession()
+	if err != nil || t.rtp == nil {
+		return session, err
+	}
+	return &faultRTPSession{session, t.rtp}, nil
+}
+
+// RTCPSession returns the RTCP session of the wrapped Transport
+func (t *FaultTransport) RTCPSession() (rtcp.Session, error) {
+	session, err := t.Transport.RTCPSession()
+	if err != nil || t.rtcp == nil {
+		return session, err
+	}
+	return &faultRTCPSession{session, t.rtcp}, nil
+}
+
+type faultInjector struct {
+	config FaultConfig
+
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+func newFaultInjector(config *FaultConfig) *faultInjector {
+	if config == nil {
+		return nil
+	}
+	return &faultInjector{
+		config: *config,
+		random: rand.New(rand.NewSource(config.Seed)), // nolint: gosec
+	}
+}
+
+// inject writes b with write as the config dictates. Packets that are
+// dropped or postponed are reported as written.
+func (f *faultInjector) inject(b []byte, write func([]byte) (int, error)) (int, error) {
+	f.mu.Lock()
+	if f.config.Loss != nil && f.config.Loss.Lose(f.random) {
+		f.mu.Unlock()
+		return len(b), nil
+	}
+
+	copies := 1
+	if f.random.Float64() < f.config.Duplicate {
+		copies++
+	}
+
+	delays := make([]time.Duration, copies)
+	for i := range delays {
+		delays[i] = f.config.Delay
+		if f.config.Jitter > 0 {
+			delays[i] += time.Duration(f.random.Int63n(int64(f.config.Jitter)))
+		}
+		if f.random.Float64() < f.config.Reorder {
+			delays[i] += f.config.ReorderDelay
+		}
+	}
+	f.mu.Unlock()
+
+	var (
+		n   int
+		err error
+	)
+	for _, delay := range delays {
+		if delay <= 0 {
+			n, err = write(b)
+			continue
+		}
+
+		// The caller may reuse b once Write returns
+		postponed := append([]byte{}, b...)
+		time.AfterFunc(delay, func() {
+			_, _ = write(postponed)
+		})
+		n = len(b)
+	}
+	return n, err
+}
+
+type faultRTPSession struct {
+	rtp.Session
+	f *faultInjector
+}
+
+func (s *faultRTPSession) OpenWriteStream() (rtp.WriteStream, error) {
+	stream, err := s.Session.OpenWriteStream()
+	if err != nil {
+		return nil, err
+	}
+	return &faultRTPWriteStream{stream, s.f}, nil
+}
+
+type faultRTPWriteStream struct {
+	rtp.WriteStream
+	f *faultInjector
+}
+
+func (s *faultRTPWriteStream) Write(b []byte) (int, error) {
+	return s.f.inject(b, s.WriteStream.Write)
+}
+
+func (s *faultRTPWriteStream) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	raw, err := header.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	return s.Write(append(raw, payload...))
+}
+
+type faultRTCPSession struct {
+	rtcp.Session
+	f *faultInjector
+}
+
+func (s *faultRTCPSession) OpenWriteStream() (rtcp.WriteStream, error) {
+	stream, err := s.Session.OpenWriteStream()
+	if err != nil {
+		return nil, err
+	}
+	return &faultRTCPWriteStream{stream, s.f}, nil
+}
+
+type faultRTCPWriteStream struct {
+	rtcp.WriteStream
+	f *faultInjector
+}
+
+func (s *faultRTCPWriteStream) Write(b []byte) (int, error) {
+	return s.f.inject(b, s.WriteStream.Write)
+}
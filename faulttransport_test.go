@@ -0,0 +1,108 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedWrites struct {
+	mu      sync.Mutex
+	packets []byte
+	written chan struct{}
+}
+
+func newRecordedWrites() *recordedWrites {
+	return &recordedWrites{written: make(chan struct{}, 16)}
+}
+
+func (r *recordedWrites) write(b []byte) (int, error) {
+	r.mu.Lock()
+	r.packets = append(r.packets, b[0])
+	r.mu.Unlock()
+	r.written <- struct{}{}
+	return len(b), nil
+}
+
+func (r *recordedWrites) get() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte{}, r.packets...)
+}
+
+func TestFaultInjector_Passthrough(t *testing.T) {
+	assert.Nil(t, newFaultInjector(nil))
+
+	f := newFaultInjector(&FaultConfig{})
+	r := newRecordedWrites()
+	for i := byte(0); i < 3; i++ {
+		n, err := f.inject([]byte{i, 0xFF}, r.write)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+	}
+	assert.Equal(t, []byte{0, 1, 2}, r.get())
+}
+
+func TestFaultInjector_LossAndDuplicate(t *testing.T) {
+	r := newRecordedWrites()
+
+	lossy := newFaultInjector(&FaultConfig{Loss: UniformLoss(1)})
+	n, err := lossy.inject([]byte{1}, r.write)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, r.get())
+
+	duplicating := newFaultInjector(&FaultConfig{Duplicate: 1})
+	_, err = duplicating.inject([]byte{2}, r.write)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{2, 2}, r.get())
+}
+
+func TestFaultInjector_Reorder(t *testing.T) {
+	f := newFaultInjector(&FaultConfig{Reorder: 1, ReorderDelay: 20 * time.Millisecond})
+	r := newRecordedWrites()
+
+	b := []byte{1}
+	_, err := f.inject(b, r.write)
+	assert.NoError(t, err)
+	b[0] = 0xFF // the postponed packet must not see later changes
+
+	f.config.Reorder = 0
+	_, err = f.inject([]byte{2}, r.write)
+	assert.NoError(t, err)
+
+	<-r.written
+	<-r.written
+	assert.Equal(t, []byte{2, 1}, r.get())
+}
+
+func TestGilbertElliottLoss(t *testing.T) {
+	random := rand.New(rand.NewSource(1)) // nolint: gosec
+
+	always := &GilbertElliottLoss{GoodToBad: 1, BadToGood: 0, LossGood: 0, LossBad: 1}
+	never := &GilbertElliottLoss{GoodToBad: 0, BadToGood: 1, LossGood: 0, LossBad: 1}
+	for i := 0; i < 10; i++ {
+		assert.True(t, always.Lose(random))
+		assert.False(t, never.Lose(random))
+	}
+
+	// Losses come in bursts, there are fewer runs of losses than losses
+	bursty := &GilbertElliottLoss{GoodToBad: 0.05, BadToGood: 0.25, LossGood: 0, LossBad: 1}
+	losses, bursts, lost := 0, 0, false
+	for i := 0; i < 10000; i++ {
+		l := bursty.Lose(random)
+		if l {
+			losses++
+			if !lost {
+				bursts++
+			}
+		}
+		lost = l
+	}
+	assert.True(t, losses > 2*bursts)
+}
@@ -0,0 +1,181 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Names of common fmtp parameters, see RFC 6184 for H264 and RFC 7587 for Opus
+const (
+	FmtpProfileLevelID        = "profile-level-id"
+	FmtpPacketizationMode     = "packetization-mode"
+	FmtpLevelAsymmetryAllowed = "level-asymmetry-allowed"
+	FmtpSpropParameterSets    = "sprop-parameter-sets"
+	FmtpMinPTime              = "minptime"
+	FmtpUseInbandFEC          = "useinbandfec"
+	FmtpMaxAverageBitrate     = "maxaveragebitrate"
+	FmtpStereo                = "stereo"
+	FmtpUseDTX                = "usedtx"
+)
+
+const (
+	fmtpParameterSeparator     = ";"
+	fmtpParameterNameSeparator = "="
+)
+
+// splitFmtp splits an fmtp line into its key=value parameters, in order
+func splitFmtp(line string) []string {
+	var params []string
+	for _, param := range strings.Split(line, fmtpParameterSeparator) {
+		if param = strings.TrimSpace(param); param != "" {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// splitFmtpParameter splits a key=value parameter, keys are case-insensitive
+func splitFmtpParameter(param string) (key, value string) {
+	kv := strings.SplitN(param, fmtpParameterNameSeparator, 2)
+	key = strings.ToLower(strings.TrimSpace(kv[0]))
+	if len(kv) == 2 {
+		value = strings.TrimSpace(kv[1])
+	}
+	return key, value
+}
+
+// parseFmtp returns the parameters of an fmtp line by their lowercase key
+func parseFmtp(line string) map[string]string {
+	params := map[string]string{}
+	for _, param := range splitFmtp(line) {
+		key, value := splitFmtpParameter(param)
+		params[key] = value
+	}
+	return params
+}
+
+// fmtpEqual reports whether two fmtp lines have the same parameters,
+// regardless of their order
+func fmtpEqual(a, b string) bool {
+	paramsA, paramsB := parseFmtp(a), parseFmtp(b)
+	if len(paramsA) != len(paramsB) {
+		return false
+	}
+	for key, value := range paramsA {
+		if other, ok := paramsB[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// FmtpParameters returns the parameters of the codec's SDPFmtpLine by their
+// lowercase name
+func (c RTPCodecCapability) FmtpParameters() map[string]string {
+	return parseFmtp(c.SDPFmtpLine)
+}
+
+// FmtpParameter returns the value of the fmtp parameter named key and whether
+// the codec's SDPFmtpLine has it
+func (c RTPCodecCapability) FmtpParameter(key string) (string, bool) {
+	value, ok := c.FmtpParameters()[strings.ToLower(key)]
+	return value, ok
+}
+
+// SetFmtpParameter sets the fmtp parameter named key to value, replacing its
+// previous value or appending it to SDPFmtpLine. Offers and answers carry the
+// codec's SDPFmtpLine as its a=fmtp attribute, so SDP doesn't have to be
+// modified by hand. An empty value removes the parameter.
+func (c *RTPCodecCapability) SetFmtpParameter(key, value string) {
+	key = strings.ToLower(strings.TrimSpace(key))
+
+	var params []string
+	found := false
+	for _, param := range splitFmtp(c.SDPFmtpLine) {
+		paramKey, _ := splitFmtpParameter(param)
+		if paramKey != key {
+			params = append(params, param)
+			continue
+		}
+		if !found && value != "" {
+			params = append(params, key+fmtpParameterNameSeparator+value)
+		}
+		found = true
+	}
+	if !found && value != "" {
+		params = append(params, key+fmtpParameterNameSeparator+value)
+	}
+
+	c.SDPFmtpLine = strings.Join(params, fmtpParameterSeparator)
+}
+
+// H264FmtpParameters are the fmtp parameters of an H264 codec, see RFC 6184
+type H264FmtpParameters struct {
+	// ProfileLevelID is the hexadecimal profile-level-id, e.g. "42e01f"
+	ProfileLevelID        string
+	PacketizationMode     uint8
+	LevelAsymmetryAllowed bool
+	SpropParameterSets    string
+}
+
+// SetH264FmtpParameters sets the H264 fmtp parameters of the codec. Empty
+// fields remove their parameter, except PacketizationMode which is always set.
+func (c *RTPCodecCapability) SetH264FmtpParameters(params H264FmtpParameters) {
+	levelAsymmetryAllowed := ""
+	if params.LevelAsymmetryAllowed {
+		levelAsymmetryAllowed = "1"
+	}
+
+	c.SetFmtpParameter(FmtpLevelAsymmetryAllowed, levelAsymmetryAllowed)
+	c.SetFmtpParameter(FmtpPacketizationMode, strconv.Itoa(int(params.PacketizationMode)))
+	c.SetFmtpParameter(FmtpProfileLevelID, strings.ToLower(params.ProfileLevelID))
+	c.SetFmtpParameter(FmtpSpropParameterSets, params.SpropParameterSets)
+}
+
+// OpusFmtpParameters are the fmtp parameters of an Opus codec, see RFC 7587
+type OpusFmtpParameters struct {
+	// MinPTime is the minimum packet duration in milliseconds
+	MinPTime uint16
+	// MaxAverageBitrate is in bits per second
+	MaxAverageBitrate uint32
+	UseInbandFEC      bool
+	Stereo            bool
+	UseDTX            bool
+}
+
+// SetOpusFmtpParameters sets the Opus fmtp parameters of the codec. Zero
+// fields remove their parameter.
+func (c *RTPCodecCapability) SetOpusFmtpParameters(params OpusFmtpParameters) {
+	flag := func(b bool) string {
+		if b {
+			return "1"
+		}
+		return ""
+	}
+	number := func(n uint32) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.FormatUint(uint64(n), 10)
+	}
+
+	c.SetFmtpParameter(FmtpMinPTime, number(uint32(params.MinPTime)))
+	c.SetFmtpParameter(FmtpUseInbandFEC, flag(params.UseInbandFEC))
+	c.SetFmtpParameter(FmtpMaxAverageBitrate, number(params.MaxAverageBitrate))
+	c.SetFmtpParameter(FmtpStereo, flag(params.Stereo))
+	c.SetFmtpParameter(FmtpUseDTX, flag(params.UseDTX))
+}
+
+// SetFmtpParameter sets the fmtp parameter named key of the registered codec
+// with payloadType, see RTPCodecCapability.SetFmtpParameter.
+// SetFmtpParameter is not safe for concurrent use.
+func (m *MediaEngine) SetFmtpParameter(payloadType uint8, key, value string) error {
+	codec, err := m.getCodec(payloadType)
+	if err != nil {
+		return err
+	}
+	codec.SetFmtpParameter(key, value)
+	return nil
+}
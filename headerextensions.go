@@ -0,0 +1,72 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// mediaHeaderExtensions are the header extensions negotiated for kind
+// besides the SDES ones, with the IDs they are offered with
+func mediaHeaderExtensions(kind RTPCodecType) []RTPHeaderExtensionParameter {
+	extensions := []RTPHeaderExtensionParameter{{URI: absCaptureTimeURI, ID: absCaptureTimeExtensionID}}
+	if kind == RTPCodecTypeVideo {
+		extensions = append(extensions,
+			RTPHeaderExtensionParameter{URI: videoOrientationURI, ID: videoOrientationExtensionID},
+			RTPHeaderExtensionParameter{URI: playoutDelayURI, ID: playoutDelayExtensionID},
+		)
+	}
+	return extensions
+}
+
+// defaultHeaderExtensions are the header extensions offered in the media
+// sections of kind that the remote didn't describe yet
+func defaultHeaderExtensions(kind RTPCodecType) []RTPHeaderExtensionParameter {
+	return append(defaultSDESHeaderExtensions(), mediaHeaderExtensions(kind)...)
+}
+
+// supportedHeaderExtensions returns the header extensions of the remote
+// offered for a media section of kind that are answered
+func supportedHeaderExtensions(kind RTPCodecType, extensions []RTPHeaderExtensionParameter) []RTPHeaderExtensionParameter {
+	supported := sdesHeaderExtensions(extensions)
+	for _, ext := range extensions {
+		if headerExtensionID(mediaHeaderExtensions(kind), ext.URI) != 0 {
+			supported = append(supported, ext)
+		}
+	}
+	return supported
+}
+
+// observeHeaderExtensions updates the video orientation and the capture time
+// of a remote Track with the ones the marshaled packet b carries
+func (t *Track) observeHeaderExtensions(b []byte) {
+	t.mu.RLock()
+	orientationID := uint8(0)
+	if t.kind == RTPCodecTypeVideo {
+		orientationID = headerExtensionID(t.headerExtensions, videoOrientationURI)
+	}
+	captureTimeID := headerExtensionID(t.headerExtensions, absCaptureTimeURI)
+	t.mu.RUnlock()
+
+	// Only unmarshal the packets with header extensions
+	if orientationID == 0 && captureTimeID == 0 || len(b) == 0 || b[0]&0x10 == 0 {
+		return
+	}
+	header := &rtp.Header{}
+	if err := header.Unmarshal(b); err != nil {
+		return
+	}
+
+	if orientationID != 0 {
+		if ext := header.GetExtension(orientationID); len(ext) != 0 {
+			t.observeOrientation(unmarshalVideoOrientation(ext[0]))
+		}
+	}
+	if captureTimeID != 0 {
+		if captureTime, ok := unmarshalAbsoluteCaptureTime(header.GetExtension(captureTimeID)); ok {
+			t.observeCaptureTime(captureTime, time.Now())
+		}
+	}
+}
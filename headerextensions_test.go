@@ -0,0 +1,34 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderExtensionsNegotiated(t *testing.T) {
+	assert.Equal(t, uint8(videoOrientationExtensionID), headerExtensionID(defaultHeaderExtensions(RTPCodecTypeVideo), videoOrientationURI))
+	assert.Equal(t, uint8(0), headerExtensionID(defaultHeaderExtensions(RTPCodecTypeAudio), videoOrientationURI))
+	assert.Equal(t, uint8(playoutDelayExtensionID), headerExtensionID(defaultHeaderExtensions(RTPCodecTypeVideo), playoutDelayURI))
+	assert.Equal(t, uint8(absCaptureTimeExtensionID), headerExtensionID(defaultHeaderExtensions(RTPCodecTypeAudio), absCaptureTimeURI))
+
+	offered := []RTPHeaderExtensionParameter{
+		{URI: "urn:ietf:params:rtp-hdrext:toffset", ID: 2},
+		{URI: videoOrientationURI, ID: 13},
+		{URI: sdesMidURI, ID: 4},
+		{URI: playoutDelayURI, ID: 12},
+		{URI: absCaptureTimeURI, ID: 14},
+	}
+	assert.Equal(t, []RTPHeaderExtensionParameter{
+		{URI: sdesMidURI, ID: 4},
+		{URI: videoOrientationURI, ID: 13},
+		{URI: playoutDelayURI, ID: 12},
+		{URI: absCaptureTimeURI, ID: 14},
+	}, supportedHeaderExtensions(RTPCodecTypeVideo, offered))
+	assert.Equal(t, []RTPHeaderExtensionParameter{
+		{URI: sdesMidURI, ID: 4},
+		{URI: absCaptureTimeURI, ID: 14},
+	}, supportedHeaderExtensions(RTPCodecTypeAudio, offered))
+}
@@ -0,0 +1,168 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is the kind of a lifecycle Event
+type EventType int
+
+const (
+	// EventNegotiationStarted is emitted when an offer moves the signaling
+	// state away from stable
+	EventNegotiationStarted EventType = iota + 1
+
+	// EventNegotiationCompleted is emitted when the signaling state returns
+	// to stable
+	EventNegotiationCompleted
+
+	// EventICECandidatePairSelected is emitted when ICE selects the
+	// candidate pair packets are sent on, see Event.CandidatePair
+	EventICECandidatePairSelected
+
+	// EventDTLSHandshakeCompleted is emitted when the DTLS handshake
+	// succeeds, see Event.Duration
+	EventDTLSHandshakeCompleted
+
+	// EventFirstMediaPacket is emitted when the first RTP packet of a remote
+	// Track arrives, see Event.Track
+	EventFirstMediaPacket
+
+	// EventTrackAdded is emitted when a local Track is added or a remote
+	// Track starts, see Event.Track
+	EventTrackAdded
+
+	// EventTrackRemoved is emitted when a local Track is removed or a remote
+	// Track is ended by a renegotiation, see Event.Track
+	EventTrackRemoved
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventNegotiationStarted:
+		return "negotiation-started"
+	case EventNegotiationCompleted:
+		return "negotiation-completed"
+	case EventICECandidatePairSelected:
+		return "ice-candidate-pair-selected"
+	case EventDTLSHandshakeCompleted:
+		return "dtls-handshake-completed"
+	case EventFirstMediaPacket:
+		return "first-media-packet"
+	case EventTrackAdded:
+		return "track-added"
+	case EventTrackRemoved:
+		return "track-removed"
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// Event describes a step in the lifecycle of a PeerConnection. Only the
+// fields that apply to the Type are set.
+type Event struct {
+	Type           EventType
+	Time           time.Time
+	PeerConnection *PeerConnection
+
+	CandidatePair *ICECandidatePair
+	Duration      time.Duration
+	Track         *Track
+}
+
+// Hooks dispatches the Events of all the PeerConnections created by an API
+// to the subscribed listeners. Listeners are called from a goroutine of the
+// Hooks, one Event at a time in the order the Events happened, so they may
+// call the PeerConnection. A listener that blocks delays the later Events.
+type Hooks struct {
+	mu        sync.RWMutex
+	nextID    uint64
+	listeners []hookListener
+
+	// queue holds the Events waiting for the dispatching goroutine, with the
+	// listeners subscribed when they happened
+	queueMu     sync.Mutex
+	queue       []queuedEvent
+	dispatching bool
+}
+
+type queuedEvent struct {
+	e         Event
+	listeners []hookListener
+}
+
+type hookListener struct {
+	id uint64
+	f  func(Event)
+}
+
+// Hooks returns the Hooks of the API
+func (api *API) Hooks() *Hooks {
+	return api.hooks
+}
+
+// Subscribe adds a listener for all Events, the returned function removes it
+func (h *Hooks) Subscribe(f func(Event)) (unsubscribe func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	id := h.nextID
+	h.listeners = append(h.listeners, hookListener{id, f})
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		for i, l := range h.listeners {
+			if l.id == id {
+				h.listeners = append(h.listeners[:i:i], h.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit queues e for the listeners, it never calls them itself since the
+// PeerConnection may hold its lock
+func (h *Hooks) emit(e Event) {
+	h.mu.RLock()
+	listeners := h.listeners
+	h.mu.RUnlock()
+
+	if len(listeners) == 0 {
+		return
+	}
+	e.Time = time.Now()
+
+	h.queueMu.Lock()
+	defer h.queueMu.Unlock()
+	h.queue = append(h.queue, queuedEvent{e, listeners})
+	if !h.dispatching {
+		h.dispatching = true
+		go h.dispatch()
+	}
+}
+
+// dispatch calls the listeners of the queued Events until the queue is empty
+func (h *Hooks) dispatch() {
+	for {
+		h.queueMu.Lock()
+		if len(h.queue) == 0 {
+			h.dispatching = false
+			h.queueMu.Unlock()
+			return
+		}
+		queued := h.queue[0]
+		h.queue[0] = queuedEvent{}
+		h.queue = h.queue[1:]
+		h.queueMu.Unlock()
+
+		for _, l := range queued.listeners {
+			l.f(queued.e)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks_Subscribe(t *testing.T) {
+	h := &Hooks{}
+
+	first := make(chan Event, 2)
+	second := make(chan EventType, 2)
+	unsubscribeFirst := h.Subscribe(func(e Event) { first <- e })
+	h.Subscribe(func(e Event) { second <- e.Type })
+
+	h.emit(Event{Type: EventTrackAdded})
+	unsubscribeFirst()
+	h.emit(Event{Type: EventTrackRemoved})
+
+	// In order, the unsubscribed listener only gets the Event before
+	assert.Equal(t, EventTrackAdded, <-second)
+	assert.Equal(t, EventTrackRemoved, <-second)
+	e := <-first
+	assert.Equal(t, EventTrackAdded, e.Type)
+	assert.False(t, e.Time.IsZero())
+	assert.Len(t, first, 0)
+}
+
+func TestHooks_Negotiation(t *testing.T) {
+	api := NewAPI()
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	events := make(chan Event, 10)
+	api.Hooks().Subscribe(func(e Event) { events <- e })
+
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+	assert.NoError(t, pc.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}))
+
+	for _, expected := range []EventType{EventNegotiationStarted, EventNegotiationCompleted} {
+		e := <-events
+		assert.Equal(t, expected, e.Type)
+		assert.Equal(t, pc, e.PeerConnection)
+	}
+	assert.NoError(t, pc.Close())
+}
+
+func TestHooks_ListenerCallsPeerConnection(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	mediaEngine := MediaEngine{}
+	mediaEngine.RegisterDefaultCodecs()
+	api := NewAPI(WithMediaEngine(mediaEngine))
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	// The remote Track is added with the lock of pcAnswer held
+	remoteTrackAdded := make(chan struct{})
+	api.Hooks().Subscribe(func(e Event) {
+		if e.Type != EventTrackAdded || e.PeerConnection != pcAnswer {
+			return
+		}
+		_, listenerErr := pcAnswer.AddTransceiverFromKind(RTPCodecTypeAudio)
+		assert.NoError(t, listenerErr)
+		close(remoteTrackAdded)
+	})
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	go func() {
+		for {
+			select {
+			case <-remoteTrackAdded:
+				return
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			}
+		}
+	}()
+	<-remoteTrackAdded
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -66,6 +66,30 @@ func newICECandidateFromICE(i ice.Candidate) (ICECandidate, error) {
 	return c, nil
 }
 
+// newICECandidateFromStats converts the stats of the ICE agent, which also
+// cover remote candidates, the related address is not known
+func newICECandidateFromStats(stats ice.CandidateStats) (*ICECandidate, error) {
+	typ, err := convertTypeFromICE(stats.CandidateType)
+	if err != nil {
+		return nil, err
+	}
+	protocol, err := NewICEProtocol(stats.NetworkType.NetworkShort())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ICECandidate{
+		statsID:    stats.ID,
+		Foundation: "foundation",
+		Priority:   stats.Priority,
+		Address:    stats.IP,
+		Protocol:   protocol,
+		Port:       uint16(stats.Port),
+		Component:  ice.ComponentRTP,
+		Typ:        typ,
+	}, nil
+}
+
 func (c ICECandidate) toICE() (ice.Candidate, error) {
 	candidateID := c.statsID
 	switch c.Typ {
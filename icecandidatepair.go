@@ -27,3 +27,30 @@ func NewICECandidatePair(local, remote *ICECandidate) *ICECandidatePair {
 		Remote:  remote,
 	}
 }
+
+// ICECandidatePairInfo describes a candidate pair of the checklist of an
+// ICETransport
+type ICECandidatePairInfo struct {
+	Pair      *ICECandidatePair
+	State     StatsICECandidatePairState
+	Nominated bool
+	Selected  bool
+
+	// Priority is the pair priority, pairs with a higher priority are
+	// preferred during nomination
+	Priority uint64
+}
+
+// candidatePairPriority computes the pair priority of RFC 8445 section 6.1.2.3
+func candidatePairPriority(local, remote uint32, controlling bool) uint64 {
+	g, d := uint64(remote), uint64(local)
+	if controlling {
+		g, d = d, g
+	}
+
+	min, max, cmp := g, d, uint64(0)
+	if g > d {
+		min, max, cmp = d, g, 1
+	}
+	return 1<<32*min + 2*max + cmp
+}
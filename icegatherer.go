@@ -3,11 +3,13 @@
 package webrtc
 
 import (
+	"net"
 	"sync"
 	"sync/atomic"
 
 	"github.com/pion/ice"
 	"github.com/pion/logging"
+	"github.com/pion/transport/vnet"
 )
 
 // ICEGatherer gathers local host, server reflexive and relay
@@ -19,11 +21,16 @@ type ICEGatherer struct {
 	log   logging.LeveledLogger
 	state ICEGathererState
 
+	iceServers       []ICEServer
 	validatedServers []*ice.URL
 	gatherPolicy     ICETransportPolicy
 
 	agent *ice.Agent
 
+	// restarted is set once the gatherer has been restarted, from then on
+	// fresh credentials are generated instead of the SettingEngine ones.
+	restarted bool
+
 	onLocalCandidateHdlr atomic.Value // func(candidate *ICECandidate)
 	onStateChangeHdlr    atomic.Value // func(state ICEGathererState)
 
@@ -37,6 +44,14 @@ func (api *API) NewICEGatherer(opts ICEGatherOptions) (*ICEGatherer, error) {
 	var validatedServers []*ice.URL
 	if len(opts.ICEServers) > 0 {
 		for _, server := range opts.ICEServers {
+			if api.settingEngine.iceCredentialProvider != nil {
+				// Credentials are fetched every time an agent is created
+				if err := server.validate(api.settingEngine.iceCredentialProvider); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
 			url, err := server.urls()
 			if err != nil {
 				return nil, err
@@ -48,6 +63,7 @@ func (api *API) NewICEGatherer(opts ICEGatherOptions) (*ICEGatherer, error) {
 	return &ICEGatherer{
 		state:            ICEGathererStateNew,
 		gatherPolicy:     opts.ICEGatherPolicy,
+		iceServers:       opts.ICEServers,
 		validatedServers: validatedServers,
 		api:              api,
 		log:              api.settingEngine.LoggerFactory.NewLogger("ice"),
@@ -62,11 +78,26 @@ func (g *ICEGatherer) createAgent() error {
 		return nil
 	}
 
+	urls := g.validatedServers
+	if provider := g.api.settingEngine.iceCredentialProvider; provider != nil {
+		urls = nil
+		for _, server := range g.iceServers {
+			serverURLs, err := server.fetchURLs(provider)
+			if err != nil {
+				return err
+			}
+			urls = append(urls, serverURLs...)
+		}
+	}
+
 	candidateTypes := []ice.CandidateType{}
-	if g.api.settingEngine.candidates.ICELite {
+	switch {
+	case g.api.settingEngine.candidates.ICELite:
 		candidateTypes = append(candidateTypes, ice.CandidateTypeHost)
-	} else if g.gatherPolicy == ICETransportPolicyRelay {
+	case g.gatherPolicy == ICETransportPolicyRelay:
 		candidateTypes = append(candidateTypes, ice.CandidateTypeRelay)
+	case g.api.settingEngine.candidates.DisableHost:
+		candidateTypes = append(candidateTypes, ice.CandidateTypeServerReflexive, ice.CandidateTypeRelay)
 	}
 
 	var nat1To1CandiTyp ice.CandidateType
@@ -79,15 +110,15 @@ func (g *ICEGatherer) createAgent() error {
 		nat1To1CandiTyp = ice.CandidateTypeUnspecified
 	}
 
-	var multicastDNSMode ice.MulticastDNSMode
-	if g.api.settingEngine.candidates.GenerateMulticastDNSCandidates {
-		multicastDNSMode = ice.MulticastDNSModeQueryAndGather
+	network := g.api.settingEngine.vnet
+	if filter := g.api.settingEngine.candidates.IPFilter; network == nil && filter != nil {
+		network = filteredNet(filter)
 	}
 
 	config := &ice.AgentConfig{
 		Trickle:                   g.api.settingEngine.candidates.ICETrickle,
 		Lite:                      g.api.settingEngine.candidates.ICELite,
-		Urls:                      g.validatedServers,
+		Urls:                      urls,
 		PortMin:                   g.api.settingEngine.ephemeralUDP.PortMin,
 		PortMax:                   g.api.settingEngine.ephemeralUDP.PortMax,
 		ConnectionTimeout:         g.api.settingEngine.timeout.ICEConnection,
@@ -102,11 +133,14 @@ func (g *ICEGatherer) createAgent() error {
 		InterfaceFilter:           g.api.settingEngine.candidates.InterfaceFilter,
 		NAT1To1IPs:                g.api.settingEngine.candidates.NAT1To1IPs,
 		NAT1To1IPCandidateType:    nat1To1CandiTyp,
-		Net:                       g.api.settingEngine.vnet,
-		MulticastDNSMode:          multicastDNSMode,
+		Net:                       network,
+		MulticastDNSMode:          g.api.settingEngine.candidates.MulticastDNSMode,
 		MulticastDNSHostName:      g.api.settingEngine.candidates.MulticastDNSHostName,
-		LocalUfrag:                g.api.settingEngine.candidates.UsernameFragment,
-		LocalPwd:                  g.api.settingEngine.candidates.Password,
+	}
+
+	if !g.restarted {
+		config.LocalUfrag = g.api.settingEngine.candidates.UsernameFragment
+		config.LocalPwd = g.api.settingEngine.candidates.Password
 	}
 
 	requestedNetworkTypes := g.api.settingEngine.candidates.ICENetworkTypes
@@ -137,11 +171,6 @@ func (g *ICEGatherer) Gather() error {
 		return err
 	}
 
-	onLocalCandidateHdlr := func(*ICECandidate) {}
-	if hdlr, ok := g.onLocalCandidateHdlr.Load().(func(candidate *ICECandidate)); ok && hdlr != nil {
-		onLocalCandidateHdlr = hdlr
-	}
-
 	g.lock.Lock()
 	isTrickle := g.api.settingEngine.candidates.ICETrickle
 	agent := g.agent
@@ -152,18 +181,26 @@ func (g *ICEGatherer) Gather() error {
 	}
 
 	g.setState(ICEGathererStateGathering)
+	for i := range g.api.settingEngine.candidates.StaticCandidates {
+		g.onLocalCandidate(&g.api.settingEngine.candidates.StaticCandidates[i])
+	}
 	if err := agent.OnCandidate(func(candidate ice.Candidate) {
 		if candidate != nil {
 			c, err := newICECandidateFromICE(candidate)
 			if err != nil {
 				g.log.Warnf("Failed to convert ice.Candidate: %s", err)
 				return
+			} else if !g.filterCandidate(c) {
+				g.log.Debugf("filtered local ICE candidate: %s", c)
+				return
 			}
-			onLocalCandidateHdlr(&c)
+			g.log.Debugf("gathered local ICE candidate: %s", c)
+			g.onLocalCandidate(&c)
 		} else {
+			g.log.Debug("ICE candidate gathering complete")
 			g.setState(ICEGathererStateComplete)
 
-			onLocalCandidateHdlr(nil)
+			g.onLocalCandidate(nil)
 		}
 	}); err != nil {
 		return err
@@ -188,6 +225,40 @@ func (g *ICEGatherer) Close() error {
 	return nil
 }
 
+// restart detaches the current agent and replaces it with a new one that has
+// fresh ICE credentials. The detached agent is returned as-is so that the
+// caller can keep using it until the new one is connected.
+func (g *ICEGatherer) restart() (*ice.Agent, error) {
+	g.lock.Lock()
+	prev := g.agent
+	g.agent = nil
+	g.restarted = true
+	g.lock.Unlock()
+
+	atomicStoreICEGathererState(&g.state, ICEGathererStateNew)
+	if err := g.createAgent(); err != nil {
+		return prev, err
+	}
+
+	return prev, nil
+}
+
+// undoRestart puts back agent, the one restart detached, and closes the agent
+// restart created in its place
+func (g *ICEGatherer) undoRestart(agent *ice.Agent) error {
+	g.lock.Lock()
+	restarted := g.agent
+	g.agent = agent
+	g.lock.Unlock()
+
+	// The detached agent had finished gathering before it was connected
+	g.setState(ICEGathererStateComplete)
+	if restarted != nil && restarted != agent {
+		return restarted.Close()
+	}
+	return nil
+}
+
 // GetLocalParameters returns the ICE parameters of the ICEGatherer.
 func (g *ICEGatherer) GetLocalParameters() (ICEParameters, error) {
 	if err := g.createAgent(); err != nil {
@@ -212,7 +283,50 @@ func (g *ICEGatherer) GetLocalCandidates() ([]ICECandidate, error) {
 		return nil, err
 	}
 
-	return newICECandidatesFromICE(iceCandidates)
+	candidates, err := newICECandidatesFromICE(iceCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	// The candidates of the SettingEngine are always signaled
+	filtered := append([]ICECandidate{}, g.api.settingEngine.candidates.StaticCandidates...)
+	for _, c := range candidates {
+		if g.filterCandidate(c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, nil
+}
+
+// filterCandidate reports if a local candidate is allowed by the gather policy
+// and passes the SettingEngine IPFilter
+func (g *ICEGatherer) filterCandidate(c ICECandidate) bool {
+	switch {
+	case g.api.settingEngine.candidates.ICELite:
+	case g.gatherPolicy == ICETransportPolicyRelay && c.Typ != ICECandidateTypeRelay:
+		return false
+	case g.api.settingEngine.candidates.DisableHost && c.Typ == ICECandidateTypeHost:
+		return false
+	}
+
+	filter := g.api.settingEngine.candidates.IPFilter
+	if filter == nil {
+		return true
+	}
+
+	var ip net.IP
+	switch c.Typ {
+	case ICECandidateTypeHost:
+		ip = net.ParseIP(c.Address)
+	case ICECandidateTypeSrflx:
+		ip = net.ParseIP(c.RelatedAddress)
+	}
+
+	// mDNS hostnames and relayed candidates don't expose a local IP
+	if ip == nil {
+		return true
+	}
+	return filter(ip)
 }
 
 // OnLocalCandidate sets an event handler which fires when a new local ICE candidate is available
@@ -221,6 +335,14 @@ func (g *ICEGatherer) OnLocalCandidate(f func(*ICECandidate)) {
 	g.onLocalCandidateHdlr.Store(f)
 }
 
+// onLocalCandidate looks the handler up for every candidate, so a handler set
+// while gathering is already in progress still sees the remaining candidates.
+func (g *ICEGatherer) onLocalCandidate(c *ICECandidate) {
+	if hdlr, ok := g.onLocalCandidateHdlr.Load().(func(candidate *ICECandidate)); ok && hdlr != nil {
+		hdlr(c)
+	}
+}
+
 // OnStateChange fires any time the ICEGatherer changes
 func (g *ICEGatherer) OnStateChange(f func(ICEGathererState)) {
 	g.onStateChangeHdlr.Store(f)
@@ -385,3 +507,37 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 		collector.Done()
 	}(collector, agent)
 }
+
+// filteredNet returns the network stack of the host without the addresses
+// filter rejects, so the agent never binds host candidates on them. A Net
+// that isn't virtual returns its own interfaces, they are replaced in place.
+func filteredNet(filter func(net.IP) bool) *vnet.Net {
+	network := vnet.NewNet(nil)
+	ifaces, err := network.Interfaces()
+	if err != nil {
+		return network
+	}
+
+	for i, iface := range ifaces {
+		filtered := vnet.NewInterface(net.Interface(iface.InterfaceBase))
+		addrs, err := iface.Addrs()
+		if err != nil {
+			// No address, nothing to filter
+			continue
+		}
+		for _, addr := range addrs {
+			var ip net.IP
+			switch addr := addr.(type) {
+			case *net.IPNet:
+				ip = addr.IP
+			case *net.IPAddr:
+				ip = addr.IP
+			}
+			if ip != nil && filter(ip) {
+				filtered.AddAddr(addr)
+			}
+		}
+		ifaces[i] = filtered
+	}
+	return network
+}
@@ -4,6 +4,7 @@ package webrtc
 
 import (
 	"context"
+	"net"
 	"strings"
 	"testing"
 	"time"
@@ -60,6 +61,145 @@ func TestNewICEGatherer_Success(t *testing.T) {
 	assert.NoError(t, gatherer.Close())
 }
 
+func TestICEGather_CredentialProvider(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	fetched := 0
+	s := SettingEngine{}
+	s.SetICECredentialProvider(func(server ICEServer) (string, string, error) {
+		fetched++
+		return "unittest", "ephemeral", nil
+	})
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{{URLs: []string{"turn:127.0.0.1?transport=tcp"}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fetched)
+
+	assert.NoError(t, gatherer.createAgent())
+	assert.Equal(t, 1, fetched)
+
+	prevAgent, err := gatherer.restart()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetched)
+	assert.NoError(t, prevAgent.Close())
+
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestICEGather_IPFilter(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	gatherCandidates := func(filter func(net.IP) bool) []ICECandidate {
+		s := SettingEngine{}
+		s.SetIPFilter(filter)
+
+		gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+		assert.NoError(t, err)
+
+		candidates, err := gatherer.GetLocalCandidates()
+		assert.NoError(t, err)
+		assert.NoError(t, gatherer.Close())
+		return candidates
+	}
+
+	all := gatherCandidates(func(net.IP) bool { return true })
+	assert.NotEmpty(t, all)
+	assert.Empty(t, gatherCandidates(func(net.IP) bool { return false }))
+
+	allowed := net.ParseIP(all[0].Address)
+	for _, c := range gatherCandidates(func(ip net.IP) bool { return ip.Equal(allowed) }) {
+		assert.Equal(t, all[0].Address, c.Address)
+	}
+}
+
+func TestICEGather_IPFilterAgent(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetIPFilter(func(net.IP) bool { return false })
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, gatherer.createAgent())
+
+	// The agent itself gathered nothing, not only the signaled candidates
+	candidates, err := gatherer.agent.GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.Empty(t, candidates)
+	assert.NoError(t, gatherer.Close())
+
+	ifaces, err := filteredNet(func(net.IP) bool { return true }).Interfaces()
+	assert.NoError(t, err)
+	all := 0
+	for _, iface := range ifaces {
+		addrs, _ := iface.Addrs()
+		all += len(addrs)
+	}
+	assert.NotZero(t, all)
+
+	ifaces, err = filteredNet(func(ip net.IP) bool { return ip.IsLoopback() }).Interfaces()
+	assert.NoError(t, err)
+	for _, iface := range ifaces {
+		addrs, _ := iface.Addrs()
+		for _, addr := range addrs {
+			assert.True(t, addr.(*net.IPNet).IP.IsLoopback())
+		}
+	}
+}
+
+func TestICEGather_CandidatePolicy(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	gatherCandidates := func(s SettingEngine, policy ICETransportPolicy) []ICECandidate {
+		gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{ICEGatherPolicy: policy})
+		assert.NoError(t, err)
+
+		candidates, err := gatherer.GetLocalCandidates()
+		assert.NoError(t, err)
+		assert.NoError(t, gatherer.Close())
+		return candidates
+	}
+
+	// Without ICE servers there is nothing left but host candidates
+	assert.NotEmpty(t, gatherCandidates(SettingEngine{}, ICETransportPolicyAll))
+	assert.Empty(t, gatherCandidates(SettingEngine{}, ICETransportPolicyRelay))
+
+	noHost := SettingEngine{}
+	noHost.DisableHostCandidates(true)
+	assert.Empty(t, gatherCandidates(noHost, ICETransportPolicyAll))
+
+	gatherer, err := NewAPI(WithSettingEngine(noHost)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	assert.False(t, gatherer.filterCandidate(ICECandidate{Typ: ICECandidateTypeHost}))
+	assert.True(t, gatherer.filterCandidate(ICECandidate{Typ: ICECandidateTypeRelay}))
+
+	gatherer, err = NewAPI().NewICEGatherer(ICEGatherOptions{ICEGatherPolicy: ICETransportPolicyRelay})
+	assert.NoError(t, err)
+	assert.False(t, gatherer.filterCandidate(ICECandidate{Typ: ICECandidateTypeSrflx}))
+	assert.True(t, gatherer.filterCandidate(ICECandidate{Typ: ICECandidateTypeRelay}))
+}
+
 func TestICEGather_LocalCandidateOrder(t *testing.T) {
 	// Limit runtime in case of deadlocks
 	lim := test.TimeOut(time.Second * 20)
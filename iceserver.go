@@ -9,6 +9,10 @@ import (
 
 // ICEServer describes a single STUN and TURN server that can be used by
 // the ICEAgent to establish a connection with a peer.
+//
+// TURN servers are reached over UDP by default. Use "turn:host?transport=tcp"
+// for TURN over TCP and "turns:host:443?transport=tcp" for TURN over TLS, which
+// also works behind firewalls that only allow HTTPS traffic.
 type ICEServer struct {
 	URLs           []string
 	Username       string
@@ -16,16 +20,28 @@ type ICEServer struct {
 	CredentialType ICECredentialType
 }
 
+// ICECredentialProvider fetches credentials for a TURN server. It is used for
+// time-limited credentials, like the ones handed out by a TURN REST API, and
+// is called every time candidates are gathered, including on ICE restarts.
+type ICECredentialProvider func(server ICEServer) (username, password string, err error)
+
 func (s ICEServer) parseURL(i int) (*ice.URL, error) {
 	return ice.ParseURL(s.URLs[i])
 }
 
-func (s ICEServer) validate() error {
+// validate checks the ICEServer. When a credential provider is set the TURN
+// credentials are fetched later on, so only the URLs are checked.
+func (s ICEServer) validate(provider ICECredentialProvider) error {
+	if provider != nil {
+		_, err := s.parseURLs()
+		return err
+	}
+
 	_, err := s.urls()
 	return err
 }
 
-func (s ICEServer) urls() ([]*ice.URL, error) {
+func (s ICEServer) parseURLs() ([]*ice.URL, error) {
 	urls := []*ice.URL{}
 
 	for i := range s.URLs {
@@ -33,7 +49,54 @@ func (s ICEServer) urls() ([]*ice.URL, error) {
 		if err != nil {
 			return nil, err
 		}
+		urls = append(urls, url)
+	}
+
+	return urls, nil
+}
+
+func (s ICEServer) hasTURNURL() (bool, error) {
+	urls, err := s.parseURLs()
+	if err != nil {
+		return false, err
+	}
+
+	for _, url := range urls {
+		if url.Scheme == ice.SchemeTypeTURN || url.Scheme == ice.SchemeTypeTURNS {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// fetchURLs is like urls but fetches the TURN credentials from provider first
+func (s ICEServer) fetchURLs(provider ICECredentialProvider) ([]*ice.URL, error) {
+	hasTURN, err := s.hasTURNURL()
+	if err != nil {
+		return nil, err
+	} else if !hasTURN {
+		return s.urls()
+	}
+
+	username, password, err := provider(s)
+	if err != nil {
+		return nil, &rtcerr.OperationError{Err: err}
+	}
 
+	s.Username = username
+	s.Credential = password
+	s.CredentialType = ICECredentialTypePassword
+	return s.urls()
+}
+
+func (s ICEServer) urls() ([]*ice.URL, error) {
+	urls, err := s.parseURLs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, url := range urls {
 		if url.Scheme == ice.SchemeTypeTURN || url.Scheme == ice.SchemeTypeTURNS {
 			// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.3.2)
 			if s.Username == "" || s.Credential == nil {
@@ -60,8 +123,6 @@ func (s ICEServer) urls() ([]*ice.URL, error) {
 				return nil, &rtcerr.InvalidAccessError{Err: ErrTurnCredentials}
 			}
 		}
-
-		urls = append(urls, url)
 	}
 
 	return urls, nil
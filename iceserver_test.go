@@ -3,6 +3,7 @@
 package webrtc
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/pion/ice"
@@ -31,6 +32,18 @@ func TestICEServer_validate(t *testing.T) {
 				},
 				CredentialType: ICECredentialTypeOauth,
 			}, true},
+			{ICEServer{
+				URLs:           []string{"turn:192.158.29.39?transport=tcp"},
+				Username:       "unittest",
+				Credential:     "placeholder",
+				CredentialType: ICECredentialTypePassword,
+			}, true},
+			{ICEServer{
+				URLs:           []string{"turns:192.158.29.39:443?transport=tcp"},
+				Username:       "unittest",
+				Credential:     "placeholder",
+				CredentialType: ICECredentialTypePassword,
+			}, true},
 		}
 
 		for i, testCase := range testCases {
@@ -82,3 +95,37 @@ func TestICEServer_validate(t *testing.T) {
 		}
 	})
 }
+
+func TestICEServer_fetchURLs(t *testing.T) {
+	server := ICEServer{
+		URLs: []string{"stun:192.158.29.39", "turns:192.158.29.39:443?transport=tcp"},
+	}
+	assert.Error(t, server.validate(nil))
+
+	provider := func(s ICEServer) (string, string, error) {
+		return "1600000000:unittest", "ephemeral", nil
+	}
+	assert.NoError(t, server.validate(provider))
+
+	urls, err := server.fetchURLs(provider)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+	assert.Equal(t, ice.SchemeTypeTURNS, urls[1].Scheme)
+	assert.Equal(t, ice.ProtoTypeTCP, urls[1].Proto)
+	assert.Equal(t, 443, urls[1].Port)
+	assert.Equal(t, "1600000000:unittest", urls[1].Username)
+	assert.Equal(t, "ephemeral", urls[1].Password)
+
+	providerErr := errors.New("credential service unavailable")
+	_, err = server.fetchURLs(func(s ICEServer) (string, string, error) {
+		return "", "", providerErr
+	})
+	assert.Equal(t, &rtcerr.OperationError{Err: providerErr}, err)
+
+	stunOnly := ICEServer{URLs: []string{"stun:192.158.29.39"}}
+	_, err = stunOnly.fetchURLs(func(s ICEServer) (string, string, error) {
+		t.Fatal("provider must not be called for STUN only servers")
+		return "", "", nil
+	})
+	assert.NoError(t, err)
+}
@@ -5,6 +5,7 @@ package webrtc
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,6 +15,10 @@ import (
 	"github.com/pion/webrtc/v2/internal/mux"
 )
 
+// defaultICEFailedTimeout is how long a disconnected ICETransport waits for
+// the connection to recover before it becomes failed
+const defaultICEFailedTimeout = 25 * time.Second
+
 // ICETransport allows an application access to information about the ICE
 // transport over which packets are sent and received.
 type ICETransport struct {
@@ -27,44 +32,137 @@ type ICETransport struct {
 	onConnectionStateChangeHdlr       atomic.Value // func(ICETransportState)
 	onSelectedCandidatePairChangeHdlr atomic.Value // func(*ICECandidatePair)
 
-	state ICETransportState
+	// onSelectedCandidatePairChangeHook is set by the PeerConnection at
+	// creation, separate from the handler the user sets
+	onSelectedCandidatePairChangeHook func(*ICECandidatePair)
+
+	state          ICETransportState
+	failedTimer    *time.Timer
+	consentExpired atomicBool
+
+	remoteParameters ICEParameters
+	selectedPair     atomic.Value // *ICECandidatePair
 
 	gatherer *ICEGatherer
+	agent    *ice.Agent // agent the mux is currently running on
 	conn     *ice.Conn
 	mux      *mux.Mux
 
+	// ctx is done once the transport is stopped, ending the connectivity
+	// checks of Start. restartCancel ends the ones of a pending restart when
+	// it is replaced by another one.
+	ctx           context.Context
+	cancel        context.CancelFunc
+	restartCancel context.CancelFunc
+
 	loggerFactory logging.LoggerFactory
 
 	log logging.LeveledLogger
 }
 
-// func (t *ICETransport) GetLocalCandidates() []ICECandidate {
-//
-// }
-//
 // func (t *ICETransport) GetRemoteCandidates() []ICECandidate {
 //
 // }
-//
-// func (t *ICETransport) GetSelectedCandidatePair() ICECandidatePair {
-//
-// }
-//
-// func (t *ICETransport) GetLocalParameters() ICEParameters {
-//
-// }
-//
-// func (t *ICETransport) GetRemoteParameters() ICEParameters {
-//
-// }
+
+// GetLocalCandidates returns the candidates gathered by the ICEGatherer of
+// the transport
+func (t *ICETransport) GetLocalCandidates() ([]ICECandidate, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if err := t.ensureGatherer(); err != nil {
+		return nil, err
+	}
+	return t.gatherer.GetLocalCandidates()
+}
+
+// GetSelectedCandidatePair returns the candidate pair packets are sent on,
+// nil until one is selected
+func (t *ICETransport) GetSelectedCandidatePair() *ICECandidatePair {
+	pair, _ := t.selectedPair.Load().(*ICECandidatePair)
+	return pair
+}
+
+// GetCandidatePairs returns the candidate pairs of the checklist of the
+// transport, highest priority first
+func (t *ICETransport) GetCandidatePairs() ([]ICECandidatePairInfo, error) {
+	t.lock.RLock()
+	agent := t.agent
+	controlling := t.role == ICERoleControlling
+	t.lock.RUnlock()
+
+	if agent == nil {
+		return nil, errors.New("ICEAgent does not exist, unable to get candidate pairs")
+	}
+
+	candidates := map[string]*ICECandidate{}
+	for _, stats := range append(agent.GetLocalCandidatesStats(), agent.GetRemoteCandidatesStats()...) {
+		c, err := newICECandidateFromStats(stats)
+		if err != nil {
+			return nil, err
+		}
+		candidates[stats.ID] = c
+	}
+
+	selected := t.GetSelectedCandidatePair()
+	pairs := []ICECandidatePairInfo{}
+	for _, stats := range agent.GetCandidatePairsStats() {
+		local, remote := candidates[stats.LocalCandidateID], candidates[stats.RemoteCandidateID]
+		if local == nil || remote == nil {
+			continue
+		}
+
+		state, err := toStatsICECandidatePairState(stats.State)
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, ICECandidatePairInfo{
+			Pair:      NewICECandidatePair(local, remote),
+			State:     state,
+			Nominated: stats.Nominated,
+			Selected: selected != nil && selected.Local.statsID == local.statsID &&
+				selected.Remote.statsID == remote.statsID,
+			Priority: candidatePairPriority(local.Priority, remote.Priority, controlling),
+		})
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Priority > pairs[j].Priority
+	})
+	return pairs, nil
+}
+
+// GetLocalParameters returns the ICE parameters of the ICEGatherer of the
+// transport
+func (t *ICETransport) GetLocalParameters() (ICEParameters, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if err := t.ensureGatherer(); err != nil {
+		return ICEParameters{}, err
+	}
+	return t.gatherer.GetLocalParameters()
+}
+
+// GetRemoteParameters returns the ICE parameters the transport was started
+// with
+func (t *ICETransport) GetRemoteParameters() ICEParameters {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.remoteParameters
+}
 
 // NewICETransport creates a new NewICETransport.
 func NewICETransport(gatherer *ICEGatherer, loggerFactory logging.LoggerFactory) *ICETransport {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ICETransport{
 		gatherer:      gatherer,
 		loggerFactory: loggerFactory,
 		log:           loggerFactory.NewLogger("ortc"),
 		state:         ICETransportStateNew,
+		ctx:           ctx,
+		cancel:        cancel,
 	}
 }
 
@@ -86,32 +184,18 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 		return errors.New("ICEAgent does not exist, unable to start ICETransport")
 	}
 
-	if err := agent.OnConnectionStateChange(func(iceState ice.ConnectionState) {
-		state := newICETransportStateFromICE(iceState)
-		t.lock.Lock()
-		t.state = state
-		t.lock.Unlock()
-
-		t.onConnectionStateChange(state)
-	}); err != nil {
-		return err
-	}
-	if err := agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
-		candidates, err := newICECandidatesFromICE([]ice.Candidate{local, remote})
-		if err != nil {
-			t.log.Warnf("Unable to convert ICE candidates to ICECandidates: %s", err)
-			return
-		}
-		t.onSelectedCandidatePairChange(NewICECandidatePair(&candidates[0], &candidates[1]))
-	}); err != nil {
+	if err := t.setAgentHandlers(agent); err != nil {
 		return err
 	}
+	t.agent = agent
+	t.remoteParameters = params
 
 	if role == nil {
 		controlled := ICERoleControlled
 		role = &controlled
 	}
 	t.role = *role
+	ctx := t.ctx
 
 	// Drop the lock here to allow trickle-ICE candidates to be
 	// added so that the agent can complete a connection
@@ -121,12 +205,12 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	var err error
 	switch *role {
 	case ICERoleControlling:
-		iceConn, err = agent.Dial(context.TODO(),
+		iceConn, err = agent.Dial(ctx,
 			params.UsernameFragment,
 			params.Password)
 
 	case ICERoleControlled:
-		iceConn, err = agent.Accept(context.TODO(),
+		iceConn, err = agent.Accept(ctx,
 			params.UsernameFragment,
 			params.Password)
 
@@ -143,7 +227,7 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	t.conn = iceConn
 
 	config := mux.Config{
-		Conn:          t.conn,
+		Conn:          &consentConn{Conn: iceConn, expired: &t.consentExpired},
 		BufferSize:    receiveMTU,
 		LoggerFactory: t.loggerFactory,
 	}
@@ -152,19 +236,245 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	return nil
 }
 
+func (t *ICETransport) setAgentHandlers(agent *ice.Agent) error {
+	if err := agent.OnConnectionStateChange(func(iceState ice.ConnectionState) {
+		state := newICETransportStateFromICE(iceState)
+		t.lock.Lock()
+		if t.agent != agent {
+			// Events from an agent replaced by an ICE restart are stale
+			t.lock.Unlock()
+			return
+		}
+		t.state = state
+		t.updateFailedTimer(state)
+		t.lock.Unlock()
+
+		t.onConnectionStateChange(state)
+	}); err != nil {
+		return err
+	}
+
+	return agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
+		t.lock.RLock()
+		isCurrent := t.agent == agent
+		t.lock.RUnlock()
+		if !isCurrent {
+			return
+		}
+
+		candidates, err := newICECandidatesFromICE([]ice.Candidate{local, remote})
+		if err != nil {
+			t.log.Warnf("Unable to convert ICE candidates to ICECandidates: %s", err)
+			return
+		}
+		pair := NewICECandidatePair(&candidates[0], &candidates[1])
+		t.log.Debugf("selected ICE candidate pair: %s", pair)
+		t.selectedPair.Store(pair)
+		t.onSelectedCandidatePairChange(pair)
+	})
+}
+
+// restart replaces the gatherer's agent with one using fresh credentials. The
+// existing connection keeps being used until completeRestart is called with
+// the remote parameters of the restarted session.
+func (t *ICETransport) restart() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.ensureGatherer(); err != nil {
+		return err
+	}
+
+	prev, err := t.gatherer.restart()
+	if err != nil {
+		return err
+	}
+
+	// A restart that didn't complete is replaced, stop its checks
+	if t.restartCancel != nil {
+		t.restartCancel()
+		t.restartCancel = nil
+	}
+
+	// The agent was never used for a connection, nothing else refers to it
+	if prev != nil && prev != t.agent {
+		return prev.Close()
+	}
+	return nil
+}
+
+// restartPending returns true if restart has been called but the mux is still
+// running on the previous agent.
+func (t *ICETransport) restartPending() bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.agent != nil && t.gatherer != nil && t.gatherer.getAgent() != t.agent
+}
+
+// completeRestart connects the restarted agent and moves the mux over to it.
+// Everything running on top of the mux (DTLS, SRTP, SCTP) is kept as is.
+func (t *ICETransport) completeRestart(params ICEParameters) error {
+	t.lock.Lock()
+	if t.mux == nil {
+		t.lock.Unlock()
+		return errors.New("ICETransport has not been started, unable to complete restart")
+	}
+
+	agent := t.gatherer.getAgent()
+	if agent == nil || agent == t.agent {
+		t.lock.Unlock()
+		return errors.New("no ICE restart in progress")
+	}
+
+	if err := t.setAgentHandlers(agent); err != nil {
+		t.lock.Unlock()
+		return err
+	}
+	role := t.role
+	ctx, cancel := context.WithCancel(t.ctx)
+	t.restartCancel = cancel
+
+	// Drop the lock while connecting so trickled candidates can be added
+	t.lock.Unlock()
+
+	var iceConn *ice.Conn
+	var err error
+	switch role {
+	case ICERoleControlling:
+		iceConn, err = agent.Dial(ctx, params.UsernameFragment, params.Password)
+	case ICERoleControlled:
+		iceConn, err = agent.Accept(ctx, params.UsernameFragment, params.Password)
+	default:
+		err = errors.New("unknown ICE Role")
+	}
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	if t.gatherer.getAgent() != agent {
+		// The restart was rolled back while connecting
+		t.lock.Unlock()
+		return errors.New("ICE restart was rolled back")
+	}
+	t.agent = agent
+	t.conn = iceConn
+	t.remoteParameters = params
+	stateChanged := t.state != ICETransportStateConnected
+	t.state = ICETransportStateConnected
+	t.updateFailedTimer(t.state)
+	t.lock.Unlock()
+
+	// Closes the previous ice.Conn and with it the previous agent
+	if err = t.mux.ReplaceConn(&consentConn{Conn: iceConn, expired: &t.consentExpired}); err != nil {
+		t.log.Warnf("Failed to close ICE connection replaced by restart: %s", err)
+	}
+
+	if stateChanged {
+		t.onConnectionStateChange(ICETransportStateConnected)
+	}
+	return nil
+}
+
+// cancelRestart undoes a restart that didn't complete, the gatherer gets back
+// the agent the mux runs on and with it the previous credentials
+func (t *ICETransport) cancelRestart() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.agent == nil || t.gatherer == nil || t.gatherer.getAgent() == t.agent {
+		return nil
+	}
+	if t.restartCancel != nil {
+		t.restartCancel()
+		t.restartCancel = nil
+	}
+	return t.gatherer.undoRestart(t.agent)
+}
+
 // Stop irreversibly stops the ICETransport.
 func (t *ICETransport) Stop() error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	t.cancel()
+
+	t.updateFailedTimer(ICETransportStateClosed)
+
 	if t.mux != nil {
-		return t.mux.Close()
+		if err := t.mux.Close(); err != nil {
+			return err
+		}
+
+		// An ICE restart that never completed leaves a second agent behind
+		if t.gatherer != nil && t.gatherer.getAgent() != t.agent {
+			return t.gatherer.Close()
+		}
+		return nil
 	} else if t.gatherer != nil {
 		return t.gatherer.Close()
 	}
 	return nil
 }
 
+// consentConn drops outgoing packets once consent to send expired, see
+// https://tools.ietf.org/html/rfc7675#section-5.1
+type consentConn struct {
+	*ice.Conn
+	expired *atomicBool
+}
+
+func (c *consentConn) Write(b []byte) (int, error) {
+	if c.expired.get() {
+		return 0, ErrICEConsentExpired
+	}
+	return c.Conn.Write(b)
+}
+
+// updateFailedTimer starts the failed timeout once the transport becomes
+// disconnected and stops it on any other state, the ICE agent never gives up
+// on a disconnected pair by itself. Requires the caller holds the lock.
+func (t *ICETransport) updateFailedTimer(state ICETransportState) {
+	if t.failedTimer != nil {
+		t.failedTimer.Stop()
+		t.failedTimer = nil
+	}
+
+	if state == ICETransportStateConnected || state == ICETransportStateCompleted {
+		t.consentExpired.set(false)
+	}
+	if state != ICETransportStateDisconnected {
+		return
+	}
+
+	timeout := defaultICEFailedTimeout
+	if t.gatherer != nil && t.gatherer.api.settingEngine.timeout.ICEFailed != nil {
+		timeout = *t.gatherer.api.settingEngine.timeout.ICEFailed
+	}
+	if timeout == 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() {
+		t.lock.Lock()
+		if t.failedTimer != timer {
+			t.lock.Unlock()
+			return
+		}
+		t.failedTimer = nil
+		t.state = ICETransportStateFailed
+		t.consentExpired.set(true)
+		t.lock.Unlock()
+
+		t.log.Warnf("ICE connection disconnected for %s, consent to send expired", timeout)
+		t.onConnectionStateChange(ICETransportStateFailed)
+	})
+	t.failedTimer = timer
+}
+
 // OnSelectedCandidatePairChange sets a handler that is invoked when a new
 // ICE candidate pair is selected
 func (t *ICETransport) OnSelectedCandidatePairChange(f func(*ICECandidatePair)) {
@@ -172,6 +482,9 @@ func (t *ICETransport) OnSelectedCandidatePairChange(f func(*ICECandidatePair))
 }
 
 func (t *ICETransport) onSelectedCandidatePairChange(pair *ICECandidatePair) {
+	if t.onSelectedCandidatePairChangeHook != nil {
+		t.onSelectedCandidatePairChangeHook(pair)
+	}
 	hdlr := t.onSelectedCandidatePairChangeHdlr.Load()
 	if hdlr != nil {
 		hdlr.(func(*ICECandidatePair))(pair)
@@ -64,3 +64,56 @@ func TestICETransport_OnSelectedCandidatePairChange(t *testing.T) {
 
 	closePairNow(t, pcOffer, pcAnswer)
 }
+
+func TestICETransport_GetCandidatePairs(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = pcOffer.iceTransport.GetCandidatePairs()
+	assert.Error(t, err)
+
+	connected := make(chan struct{})
+	pcOffer.OnConnectionStateChange(func(s PeerConnectionState) {
+		if s == PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-connected
+
+	pairs, err := pcOffer.iceTransport.GetCandidatePairs()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, pairs)
+
+	selected := pcOffer.iceTransport.GetSelectedCandidatePair()
+	foundSelected := false
+	for i, p := range pairs {
+		if i > 0 {
+			assert.True(t, pairs[i-1].Priority >= p.Priority)
+		}
+		if p.Selected {
+			foundSelected = true
+			assert.Equal(t, selected.String(), p.Pair.String())
+			assert.Equal(t, StatsICECandidatePairStateSucceeded, p.State)
+		}
+	}
+	assert.True(t, foundSelected)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestCandidatePairPriority(t *testing.T) {
+	// The controlling candidate priority is G, the controlled one D
+	assert.Equal(t, uint64(1<<32*1+2*2+0), candidatePairPriority(1, 2, true))
+	assert.Equal(t, uint64(1<<32*1+2*2+1), candidatePairPriority(1, 2, false))
+	assert.Equal(t, candidatePairPriority(2, 1, true), candidatePairPriority(1, 2, false))
+}
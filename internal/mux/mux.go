@@ -85,7 +85,7 @@ func (m *Mux) Close() error {
 	}
 	m.lock.Unlock()
 
-	err := m.nextConn.Close()
+	err := m.getConn().Close()
 	if err != nil {
 		return err
 	}
@@ -96,6 +96,24 @@ func (m *Mux) Close() error {
 	return nil
 }
 
+// ReplaceConn swaps the Conn the Mux reads from and writes to. Endpoints are
+// kept, so protocols running on top of them survive the switch. The previous
+// Conn is closed.
+func (m *Mux) ReplaceConn(conn net.Conn) error {
+	m.lock.Lock()
+	prev := m.nextConn
+	m.nextConn = conn
+	m.lock.Unlock()
+
+	return prev.Close()
+}
+
+func (m *Mux) getConn() net.Conn {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.nextConn
+}
+
 func (m *Mux) readLoop() {
 	defer func() {
 		close(m.closedCh)
@@ -103,8 +121,13 @@ func (m *Mux) readLoop() {
 
 	buf := make([]byte, m.bufferSize)
 	for {
-		n, err := m.nextConn.Read(buf)
+		conn := m.getConn()
+		n, err := conn.Read(buf)
 		if err != nil {
+			if m.getConn() != conn {
+				// Conn was swapped by ReplaceConn, continue reading from the new one
+				continue
+			}
 			return
 		}
 
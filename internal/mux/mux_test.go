@@ -101,3 +101,71 @@ func TestNoEndpoints(t *testing.T) {
 		panic("Failed to close network pipe")
 	}
 }
+
+func TestReplaceConn(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	ca, cb := net.Pipe()
+	m := NewMux(Config{
+		Conn:          ca,
+		BufferSize:    8192,
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	e := m.NewEndpoint(func([]byte) bool { return true })
+
+	roundTrip := func(remote net.Conn, msg []byte) {
+		go func() {
+			if _, err := remote.Write(msg); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		buf := make([]byte, 8192)
+		n, err := e.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != string(msg) {
+			t.Fatalf("unexpected payload %q", buf[:n])
+		}
+	}
+
+	roundTrip(cb, []byte("before"))
+
+	cc, cd := net.Pipe()
+	if err := m.ReplaceConn(cc); err != nil {
+		t.Fatal(err)
+	}
+
+	// The old Conn must be closed, and the Endpoint must read from the new one
+	if _, err := cb.Write([]byte("stale")); err == nil {
+		t.Fatal("write to replaced conn succeeded")
+	}
+	roundTrip(cd, []byte("after"))
+
+	// Writes must go out over the new Conn
+	go func() {
+		if _, err := e.Write([]byte("outbound")); err != nil {
+			t.Error(err)
+		}
+	}()
+	buf := make([]byte, 8192)
+	n, err := cd.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "outbound" {
+		t.Fatalf("unexpected payload %q", buf[:n])
+	}
+
+	if err := cd.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
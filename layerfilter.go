@@ -0,0 +1,108 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+)
+
+// LayerFilter decides which RTP packets written to a Track are forwarded to
+// the remote peers. It allows a forwarding unit to drop the layers of a
+// scalable stream a receiver doesn't need without decoding it.
+type LayerFilter interface {
+	// Filter returns if the packet should be sent. It may rewrite the header
+	// of p, the sequence numbers of forwarded packets have to stay contiguous.
+	Filter(p *rtp.Packet) (bool, error)
+}
+
+// VP9LayerFilter is a LayerFilter that only forwards the VP9 spatial and
+// temporal layers up to a target, using the layer indices of the VP9 payload
+// descriptor (draft-ietf-payload-vp9).
+//
+// Lowering the target takes effect on the next picture. Raising it waits for
+// a picture the receiver can decode: a keyframe for spatial layers or a
+// switching up point for temporal layers.
+type VP9LayerFilter struct {
+	mu sync.Mutex
+
+	targetSpatialLayer, targetTemporalLayer   uint8
+	currentSpatialLayer, currentTemporalLayer uint8
+
+	// number of packets dropped so far, forwarded sequence numbers are shifted by it
+	droppedPackets uint16
+}
+
+// NewVP9LayerFilter creates a VP9LayerFilter forwarding the layers up to
+// spatialLayer and temporalLayer
+func NewVP9LayerFilter(spatialLayer, temporalLayer uint8) *VP9LayerFilter {
+	return &VP9LayerFilter{
+		targetSpatialLayer:   spatialLayer,
+		targetTemporalLayer:  temporalLayer,
+		currentSpatialLayer:  spatialLayer,
+		currentTemporalLayer: temporalLayer,
+	}
+}
+
+// SetTarget changes the highest spatial and temporal layers forwarded
+func (f *VP9LayerFilter) SetTarget(spatialLayer, temporalLayer uint8) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.targetSpatialLayer = spatialLayer
+	f.targetTemporalLayer = temporalLayer
+}
+
+// Layers returns the spatial and temporal layers currently forwarded
+func (f *VP9LayerFilter) Layers() (spatialLayer, temporalLayer uint8) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.currentSpatialLayer, f.currentTemporalLayer
+}
+
+// Filter implements LayerFilter
+func (f *VP9LayerFilter) Filter(p *rtp.Packet) (bool, error) {
+	vp9 := &codecs.VP9Packet{}
+	if _, err := vp9.Unmarshal(p.Payload); err != nil {
+		return false, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Without layer indices the stream isn't scalable
+	if !vp9.L {
+		p.SequenceNumber -= f.droppedPackets
+		return true, nil
+	}
+
+	if vp9.B && vp9.SID == 0 {
+		f.switchLayers(vp9)
+	}
+
+	if vp9.SID > f.currentSpatialLayer || vp9.TID > f.currentTemporalLayer {
+		f.droppedPackets++
+		return false, nil
+	}
+
+	p.SequenceNumber -= f.droppedPackets
+	if vp9.E && vp9.SID == f.currentSpatialLayer {
+		// The higher layers of the picture are dropped, this ends it
+		p.Marker = true
+	}
+	return true, nil
+}
+
+// switchLayers moves to the target layers at the start of a picture
+func (f *VP9LayerFilter) switchLayers(vp9 *codecs.VP9Packet) {
+	if f.targetSpatialLayer < f.currentSpatialLayer || (f.targetSpatialLayer > f.currentSpatialLayer && !vp9.P) {
+		f.currentSpatialLayer = f.targetSpatialLayer
+	}
+
+	if f.targetTemporalLayer < f.currentTemporalLayer || (f.targetTemporalLayer > f.currentTemporalLayer && (vp9.U || !vp9.P)) {
+		f.currentTemporalLayer = f.targetTemporalLayer
+	}
+}
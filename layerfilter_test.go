@@ -0,0 +1,79 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// vp9Packet builds a non-flexible mode VP9 packet carrying layer indices
+func vp9Packet(sequenceNumber uint16, interPredicted, start, end bool, spatialLayer, temporalLayer uint8) *rtp.Packet {
+	header := byte(0x20) // L
+	if interPredicted {
+		header |= 0x40
+	}
+	if start {
+		header |= 0x08
+	}
+	if end {
+		header |= 0x04
+	}
+
+	return &rtp.Packet{
+		Header:  rtp.Header{SequenceNumber: sequenceNumber},
+		Payload: []byte{header, temporalLayer<<5 | spatialLayer<<1, 0x00, 0xAA},
+	}
+}
+
+func TestVP9LayerFilter(t *testing.T) {
+	f := NewVP9LayerFilter(0, 0)
+
+	type expected struct {
+		forward        bool
+		sequenceNumber uint16
+		marker         bool
+	}
+	assertFilter := func(p *rtp.Packet, e expected) {
+		forward, err := f.Filter(p)
+		assert.NoError(t, err)
+		assert.Equal(t, e.forward, forward)
+		if forward {
+			assert.Equal(t, e.sequenceNumber, p.SequenceNumber)
+			assert.Equal(t, e.marker, p.Marker)
+		}
+	}
+
+	// Keyframe with two spatial layers, only the base layer is forwarded
+	assertFilter(vp9Packet(10, false, true, true, 0, 0), expected{true, 10, true})
+	assertFilter(vp9Packet(11, false, true, true, 1, 0), expected{forward: false})
+
+	// A higher temporal layer is dropped too
+	assertFilter(vp9Packet(12, true, true, true, 0, 1), expected{forward: false})
+
+	// Raising the target waits for a keyframe or a switching up point
+	f.SetTarget(1, 1)
+	assertFilter(vp9Packet(13, true, true, true, 0, 0), expected{true, 11, true})
+	assertFilter(vp9Packet(14, true, true, true, 1, 0), expected{forward: false})
+
+	spatialLayer, temporalLayer := f.Layers()
+	assert.Equal(t, uint8(0), spatialLayer)
+	assert.Equal(t, uint8(0), temporalLayer)
+
+	assertFilter(vp9Packet(15, false, true, true, 0, 0), expected{true, 12, false})
+	assertFilter(vp9Packet(16, false, true, true, 1, 0), expected{true, 13, true})
+	assertFilter(vp9Packet(17, true, true, true, 0, 1), expected{true, 14, false})
+
+	// Lowering it takes effect on the next picture
+	f.SetTarget(0, 0)
+	assertFilter(vp9Packet(18, true, true, true, 0, 0), expected{true, 15, true})
+	assertFilter(vp9Packet(19, true, true, true, 1, 0), expected{forward: false})
+
+	// Packets without layer indices are always forwarded
+	assertFilter(&rtp.Packet{Header: rtp.Header{SequenceNumber: 20}, Payload: []byte{0x0C, 0xAA}}, expected{true, 16, false})
+
+	_, err := f.Filter(&rtp.Packet{})
+	assert.Error(t, err)
+}
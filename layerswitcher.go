@@ -0,0 +1,199 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+)
+
+// LayerSwitcher forwards one of the simulcast layers of a remote stream to a
+// local Track, like a forwarding unit does for each of its receivers. The
+// packets of every layer are written to it, only the ones of the current
+// layer are forwarded, rewritten by a StreamRewriter into one continuous
+// stream.
+//
+// A switch to another layer requests a keyframe of it and only happens at the
+// start of that keyframe, until then the current layer keeps being forwarded,
+// so the decoder of the receiver never sees a frame it can't decode:
+//
+//	s := webrtc.NewLayerSwitcher(localTrack, func(ssrc uint32) error {
+//		return pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}})
+//	})
+//	s.SetTarget(lowLayer.SSRC())
+//	// for each remote layer Track
+//	go func() {
+//		for {
+//			p, err := layer.ReadRTP()
+//			...
+//			_ = s.WriteRTP(p)
+//		}
+//	}()
+type LayerSwitcher struct {
+	mu sync.Mutex
+
+	codecName       string
+	rewriter        *StreamRewriter
+	write           func(*rtp.Packet) error
+	requestKeyframe func(ssrc uint32) error
+	onForward       func(source, forwarded *rtp.Packet)
+
+	// current is the SSRC of the layer forwarded, 0 before the first
+	// keyframe of the target arrived
+	current, target uint32
+	lastRequest     time.Time
+}
+
+// NewLayerSwitcher creates a LayerSwitcher forwarding to track.
+// requestKeyframe is called with the SSRC of a layer to request a keyframe of
+// it, usually by sending a PictureLossIndication.
+func NewLayerSwitcher(track *Track, requestKeyframe func(ssrc uint32) error) *LayerSwitcher {
+	return newLayerSwitcher(track.Codec().Name, NewStreamRewriterForTrack(track), track.WriteRTP, requestKeyframe)
+}
+
+func newLayerSwitcher(codecName string, rewriter *StreamRewriter, write func(*rtp.Packet) error, requestKeyframe func(ssrc uint32) error) *LayerSwitcher {
+	return &LayerSwitcher{
+		codecName:       codecName,
+		rewriter:        rewriter,
+		write:           write,
+		requestKeyframe: requestKeyframe,
+	}
+}
+
+// SetTarget switches to the layer of ssrc once a keyframe of it arrives, and
+// requests one
+func (s *LayerSwitcher) SetTarget(ssrc uint32) error {
+	s.mu.Lock()
+	if s.target == ssrc {
+		s.mu.Unlock()
+		return nil
+	}
+	s.target = ssrc
+	switching := s.current != ssrc
+	if switching {
+		s.lastRequest = time.Now()
+	}
+	s.mu.Unlock()
+
+	if !switching {
+		return nil
+	}
+	return s.requestKeyframe(ssrc)
+}
+
+// OnForward sets a handler called with every packet forwarded and its
+// rewritten copy, before the copy is written, like to keep the packets sent
+// to answer NACKs with. It is called synchronously and in order, it must not
+// call the LayerSwitcher.
+func (s *LayerSwitcher) OnForward(f func(source, forwarded *rtp.Packet)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onForward = f
+}
+
+// Layers returns the SSRC of the layer forwarded and of the one switched to,
+// they are the same once the switch happened
+func (s *LayerSwitcher) Layers() (current, target uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current, s.target
+}
+
+// WriteRTP forwards p if it belongs to the current layer, or switches to the
+// target layer if p starts a keyframe of it. p isn't modified.
+func (s *LayerSwitcher) WriteRTP(p *rtp.Packet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.SSRC == s.target && s.current != s.target {
+		if !isKeyframeStart(s.codecName, p.Payload) {
+			return s.requestKeyframeAgain()
+		}
+		s.current = s.target
+	}
+	if p.SSRC != s.current {
+		return nil
+	}
+
+	// The switch and the packets forwarded are serialized by mu, a packet of
+	// the previous layer can't follow the keyframe
+	forwarded := *p
+	if !s.rewriter.Rewrite(&forwarded) {
+		return nil
+	}
+	if s.onForward != nil {
+		s.onForward(p, &forwarded)
+	}
+	return s.write(&forwarded)
+}
+
+// requestKeyframeAgain requests another keyframe of the target when the last
+// request is older than layerSwitchPLIInterval, s.mu must be held
+func (s *LayerSwitcher) requestKeyframeAgain() error {
+	now := time.Now()
+	if now.Sub(s.lastRequest) < layerSwitchPLIInterval {
+		return nil
+	}
+	s.lastRequest = now
+	return s.requestKeyframe(s.target)
+}
+
+// isKeyframeStart reports if payload is the first packet of a keyframe of the
+// codec. Packets of codecs without keyframes, like audio, always are.
+func isKeyframeStart(codecName string, payload []byte) bool {
+	switch strings.ToUpper(codecName) {
+	case VP8:
+		vp8 := &codecs.VP8Packet{}
+		vp8Payload, err := vp8.Unmarshal(payload)
+		// The P bit of the frame tag is 0 for keyframes, RFC 6386 9.1
+		return err == nil && vp8.S == 1 && vp8.PID == 0 && vp8Payload[0]&0x01 == 0
+	case VP9:
+		vp9 := &codecs.VP9Packet{}
+		if _, err := vp9.Unmarshal(payload); err != nil {
+			return false
+		}
+		return vp9.B && !vp9.P && vp9.SID == 0
+	case H264:
+		return isH264KeyframeStart(payload)
+	default:
+		return true
+	}
+}
+
+// The H264 NAL unit types of RFC 6184 that start a keyframe or carry it
+const (
+	h264NALUTypeIDR   = 5
+	h264NALUTypeSPS   = 7
+	h264NALUTypeSTAPA = 24
+	h264NALUTypeFUA   = 28
+)
+
+func isH264KeyframeStart(payload []byte) bool {
+	if len(payload) < 2 {
+		return false
+	}
+
+	switch naluType := payload[0] & 0x1F; naluType {
+	case h264NALUTypeIDR, h264NALUTypeSPS:
+		return true
+	case h264NALUTypeSTAPA:
+		// 16 bit sizes each followed by a NAL unit
+		for i := 1; i+2 < len(payload); {
+			size := int(payload[i])<<8 | int(payload[i+1])
+			if t := payload[i+2] & 0x1F; t == h264NALUTypeIDR || t == h264NALUTypeSPS {
+				return true
+			}
+			i += 2 + size
+		}
+		return false
+	case h264NALUTypeFUA:
+		// The start bit of the FU header and the type of the fragmented unit
+		return payload[1]&0x80 != 0 && payload[1]&0x1F == h264NALUTypeIDR
+	default:
+		return false
+	}
+}
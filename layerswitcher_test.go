@@ -0,0 +1,102 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsKeyframeStart(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		codecName string
+		payload   []byte
+		keyframe  bool
+	}{
+		{"VP8 keyframe", VP8, []byte{0x10, 0x50, 0x04, 0x00}, true},
+		{"VP8 interframe", VP8, []byte{0x10, 0x51, 0x04, 0x00}, false},
+		{"VP8 continuation", VP8, []byte{0x00, 0x50, 0x04, 0x00}, false},
+		{"VP9 keyframe", VP9, vp9Packet(0, false, true, false, 0, 0).Payload, true},
+		{"VP9 interframe", VP9, vp9Packet(0, true, true, false, 0, 0).Payload, false},
+		{"VP9 upper spatial layer", VP9, vp9Packet(0, false, true, false, 1, 0).Payload, false},
+		{"H264 IDR", H264, []byte{0x65, 0x88}, true},
+		{"H264 non-IDR", H264, []byte{0x41, 0x9A}, false},
+		{"H264 STAP-A with SPS", H264, []byte{0x78, 0x00, 0x02, 0x09, 0xF0, 0x00, 0x02, 0x67, 0x42}, true},
+		{"H264 STAP-A without SPS", H264, []byte{0x78, 0x00, 0x02, 0x09, 0xF0}, false},
+		{"H264 FU-A start of IDR", H264, []byte{0x7C, 0x85, 0x88}, true},
+		{"H264 FU-A middle of IDR", H264, []byte{0x7C, 0x05, 0x88}, false},
+		{"Opus", Opus, []byte{0xFC}, true},
+	} {
+		assert.Equal(t, c.keyframe, isKeyframeStart(c.codecName, c.payload), c.name)
+	}
+}
+
+func TestLayerSwitcher(t *testing.T) {
+	requested := []uint32{}
+	written := []*rtp.Packet{}
+	s := newLayerSwitcher(VP9, NewStreamRewriter(5000, 96, 90000),
+		func(p *rtp.Packet) error {
+			written = append(written, p)
+			return nil
+		},
+		func(ssrc uint32) error {
+			requested = append(requested, ssrc)
+			return nil
+		})
+	sources := []uint32{}
+	s.OnForward(func(source, forwarded *rtp.Packet) {
+		assert.Equal(t, source.Payload, forwarded.Payload)
+		sources = append(sources, source.SSRC)
+	})
+
+	layerPacket := func(ssrc uint32, sequenceNumber uint16, keyframe bool) *rtp.Packet {
+		p := vp9Packet(sequenceNumber, !keyframe, true, true, 0, 0)
+		p.SSRC = ssrc
+		p.Timestamp = uint32(sequenceNumber) * 3000
+		return p
+	}
+
+	// Nothing is forwarded before the first keyframe of the target
+	assert.NoError(t, s.SetTarget(1))
+	assert.NoError(t, s.SetTarget(1))
+	assert.Equal(t, []uint32{1}, requested)
+	assert.NoError(t, s.WriteRTP(layerPacket(1, 10, false)))
+	assert.NoError(t, s.WriteRTP(layerPacket(2, 500, true)))
+	assert.Empty(t, written)
+	assert.Equal(t, []uint32{1}, requested, "the keyframe requests are rate limited")
+
+	assert.NoError(t, s.WriteRTP(layerPacket(1, 11, true)))
+	assert.NoError(t, s.WriteRTP(layerPacket(1, 12, false)))
+	current, target := s.Layers()
+	assert.Equal(t, uint32(1), current)
+	assert.Equal(t, uint32(1), target)
+
+	// The current layer is forwarded until a keyframe of the target arrives
+	assert.NoError(t, s.SetTarget(2))
+	assert.Equal(t, []uint32{1, 2}, requested)
+	assert.NoError(t, s.WriteRTP(layerPacket(2, 501, false)))
+	assert.NoError(t, s.WriteRTP(layerPacket(1, 13, false)))
+	assert.NoError(t, s.WriteRTP(layerPacket(2, 502, true)))
+	assert.NoError(t, s.WriteRTP(layerPacket(1, 14, false)))
+	assert.NoError(t, s.WriteRTP(layerPacket(2, 503, false)))
+	current, _ = s.Layers()
+	assert.Equal(t, uint32(2), current)
+
+	sequenceNumbers := []uint16{}
+	for _, p := range written {
+		assert.Equal(t, uint32(5000), p.SSRC)
+		sequenceNumbers = append(sequenceNumbers, p.SequenceNumber)
+	}
+	first := sequenceNumbers[0]
+	assert.Equal(t, []uint16{first, first + 1, first + 2, first + 3, first + 4}, sequenceNumbers)
+	assert.True(t, written[3].Timestamp-written[2].Timestamp > 0, "the timestamps continue")
+	assert.Equal(t, []uint32{1, 1, 1, 2, 2}, sources)
+
+	// Switching back to the current layer cancels the switch
+	assert.NoError(t, s.SetTarget(1))
+	assert.NoError(t, s.SetTarget(2))
+	assert.Equal(t, []uint32{1, 2, 1}, requested)
+}
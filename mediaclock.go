@@ -0,0 +1,119 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// ntpEpoch is the origin of NTP timestamps, 1 January 1900
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// MediaClock maps the RTP timestamps of remote Tracks from the same source,
+// like the audio and the video of a camera, onto the wallclock of the sender.
+// The RTCP Sender Reports of each stream tell which wallclock time an RTP
+// timestamp corresponds to, so samples of different Tracks that share a
+// wallclock time were captured together and have to be played out together.
+type MediaClock struct {
+	mu      sync.Mutex
+	streams map[uint32]*mediaClockStream
+}
+
+type mediaClockStream struct {
+	clockRate uint32
+
+	// wallclock and rtpTime are the mapping of the last Sender Report
+	hasReport bool
+	wallclock time.Time
+	rtpTime   uint32
+}
+
+// NewMediaClock creates a MediaClock without any stream
+func NewMediaClock() *MediaClock {
+	return &MediaClock{streams: map[uint32]*mediaClockStream{}}
+}
+
+// AddTrack adds the stream of the remote Track t, the Sender Reports of its
+// SSRC are used once it is added.
+func (c *MediaClock) AddTrack(t *Track) {
+	c.AddStream(t.SSRC(), t.Codec().ClockRate)
+}
+
+// AddStream adds the stream of ssrc whose RTP timestamps advance at clockRate
+func (c *MediaClock) AddStream(ssrc, clockRate uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.streams[ssrc]; !ok {
+		c.streams[ssrc] = &mediaClockStream{clockRate: clockRate}
+	}
+}
+
+// HandleSenderReport updates the mapping of the stream the Sender Report
+// describes, it can be used as the OnSenderReport handler of RTCPHandlers.
+func (c *MediaClock) HandleSenderReport(sr *rtcp.SenderReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stream, ok := c.streams[sr.SSRC]
+	if !ok {
+		return
+	}
+	stream.hasReport = true
+	stream.wallclock = ntpTime(sr.NTPTime)
+	stream.rtpTime = sr.RTPTime
+}
+
+// Time returns the wallclock time of the sender at which the sample with
+// the RTP timestamp of the stream of ssrc was captured. It reports false
+// until a Sender Report arrived for the stream.
+func (c *MediaClock) Time(ssrc, timestamp uint32) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stream, ok := c.streams[ssrc]
+	if !ok || !stream.hasReport || stream.clockRate == 0 {
+		return time.Time{}, false
+	}
+
+	// The difference is signed so samples from before the report map too,
+	// and the timestamps may wrap around in between
+	elapsed := int64(int32(timestamp - stream.rtpTime))
+	return stream.wallclock.Add(time.Duration(elapsed * int64(time.Second) / int64(stream.clockRate))), true
+}
+
+// Offset returns how much later the sample of the stream of ssrc with the RTP
+// timestamp has to be played out than the sample of the stream of otherSSRC
+// with otherTimestamp, so both streams play in sync. It reports false until
+// both streams got a Sender Report.
+func (c *MediaClock) Offset(ssrc, timestamp, otherSSRC, otherTimestamp uint32) (time.Duration, bool) {
+	t, ok := c.Time(ssrc, timestamp)
+	if !ok {
+		return 0, false
+	}
+	other, ok := c.Time(otherSSRC, otherTimestamp)
+	if !ok {
+		return 0, false
+	}
+	return t.Sub(other), true
+}
+
+// ntpTime converts a 64 bit NTP timestamp, seconds since 1900 in 32.32
+// fixed point, to a time.Time
+func ntpTime(ntp uint64) time.Time {
+	seconds := ntp >> 32
+	fraction := ntp & 0xFFFFFFFF
+	nanoseconds := (fraction * uint64(time.Second)) >> 32
+	return ntpEpoch.Add(time.Duration(seconds) * time.Second).Add(time.Duration(nanoseconds))
+}
+
+// ntpTimestamp converts t to a 64 bit NTP timestamp, the inverse of ntpTime
+func ntpTimestamp(t time.Time) uint64 {
+	d := t.Sub(ntpEpoch)
+	seconds := uint64(d / time.Second)
+	fraction := (uint64(d%time.Second) << 32) / uint64(time.Second)
+	return seconds<<32 | fraction
+}
@@ -0,0 +1,54 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaClock(t *testing.T) {
+	const audioSSRC, videoSSRC = 1, 2
+	captured := time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	c := NewMediaClock()
+	c.AddStream(audioSSRC, 48000)
+	c.AddStream(videoSSRC, 90000)
+
+	_, ok := c.Time(audioSSRC, 0)
+	assert.False(t, ok)
+
+	// Reports for an unknown SSRC are ignored
+	c.HandleSenderReport(&rtcp.SenderReport{SSRC: 3, NTPTime: ntpTimestamp(captured)})
+	_, ok = c.Time(3, 0)
+	assert.False(t, ok)
+
+	// Both streams report the same instant at different RTP times, the
+	// video one just before it wraps around
+	c.HandleSenderReport(&rtcp.SenderReport{SSRC: audioSSRC, NTPTime: ntpTimestamp(captured), RTPTime: 1000})
+	c.HandleSenderReport(&rtcp.SenderReport{SSRC: videoSSRC, NTPTime: ntpTimestamp(captured), RTPTime: 0xFFFFFFFF - 8999})
+
+	audioTime, ok := c.Time(audioSSRC, 1000+48000)
+	assert.True(t, ok)
+	assert.WithinDuration(t, captured.Add(time.Second), audioTime, time.Microsecond)
+
+	videoTime, ok := c.Time(videoSSRC, 0xFFFFFFFF-8999-9000)
+	assert.True(t, ok)
+	assert.WithinDuration(t, captured.Add(-100*time.Millisecond), videoTime, time.Microsecond)
+
+	// 1s of audio is played 1.1s after the video sample from 100ms before
+	// the report, across the wrap around of the video timestamps
+	offset, ok := c.Offset(audioSSRC, 1000+48000, videoSSRC, 0xFFFFFFFF-8999-9000)
+	assert.True(t, ok)
+	assert.InDelta(t, 1100*time.Millisecond, offset, float64(time.Microsecond))
+
+	offset, ok = c.Offset(videoSSRC, 1000, audioSSRC, 1000)
+	assert.True(t, ok)
+	assert.InDelta(t, 10000*time.Second/90000, offset, float64(time.Microsecond))
+
+	_, ok = c.Offset(audioSSRC, 0, 3, 0)
+	assert.False(t, ok)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
 	"github.com/pion/sdp/v2"
+	"github.com/pion/webrtc/v2/pkg/h265"
 )
 
 // PayloadTypes for the default codecs
@@ -22,6 +23,11 @@ const (
 	DefaultPayloadTypeVP9  = 98
 	DefaultPayloadTypeH264 = 102
 
+	// DefaultPayloadTypeTelephoneEvent is the payload type of the
+	// telephone-event codec at the clock rate of Opus, it is not registered
+	// by RegisterDefaultCodecs
+	DefaultPayloadTypeTelephoneEvent = 110
+
 	mediaNameAudio = "audio"
 	mediaNameVideo = "video"
 )
@@ -60,6 +66,30 @@ func (m *MediaEngine) RegisterDefaultCodecs() {
 	m.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
 }
 
+// minDynamicPayloadType is the first payload type without a static assignment
+const minDynamicPayloadType = 96
+
+// staticAudioPayloadTypes are the audio payload types assigned by RFC 3551
+var staticAudioPayloadTypes = map[uint8]sdp.Codec{
+	0:  {PayloadType: 0, Name: PCMU, ClockRate: 8000},
+	3:  {PayloadType: 3, Name: "GSM", ClockRate: 8000},
+	4:  {PayloadType: 4, Name: "G723", ClockRate: 8000},
+	5:  {PayloadType: 5, Name: "DVI4", ClockRate: 8000},
+	6:  {PayloadType: 6, Name: "DVI4", ClockRate: 16000},
+	7:  {PayloadType: 7, Name: "LPC", ClockRate: 8000},
+	8:  {PayloadType: 8, Name: PCMA, ClockRate: 8000},
+	9:  {PayloadType: 9, Name: G722, ClockRate: 8000},
+	10: {PayloadType: 10, Name: "L16", ClockRate: 44100, EncodingParameters: "2"},
+	11: {PayloadType: 11, Name: "L16", ClockRate: 44100},
+	12: {PayloadType: 12, Name: "QCELP", ClockRate: 8000},
+	13: {PayloadType: 13, Name: "CN", ClockRate: 8000},
+	14: {PayloadType: 14, Name: "MPA", ClockRate: 90000},
+	15: {PayloadType: 15, Name: "G728", ClockRate: 8000},
+	16: {PayloadType: 16, Name: "DVI4", ClockRate: 11025},
+	17: {PayloadType: 17, Name: "DVI4", ClockRate: 22050},
+	18: {PayloadType: 18, Name: "G729", ClockRate: 8000},
+}
+
 // PopulateFromSDP finds all codecs in sd and adds them to m, using the dynamic
 // payload types and parameters from sd.
 // PopulateFromSDP is intended for use when answering a request.
@@ -86,7 +116,12 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 			payloadType := uint8(pt)
 			payloadCodec, err := sdp.GetCodecForPayloadType(payloadType)
 			if err != nil {
-				return fmt.Errorf("could not find codec for payload type %d", payloadType)
+				// The rtpmap of a static payload type is optional
+				staticCodec, ok := staticAudioPayloadTypes[payloadType]
+				if !ok || md.MediaName.Media != mediaNameAudio {
+					return fmt.Errorf("could not find codec for payload type %d", payloadType)
+				}
+				payloadCodec = staticCodec
 			}
 
 			var codec *RTPCodec
@@ -105,12 +140,36 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 				codec = NewRTPVP9Codec(payloadType, payloadCodec.ClockRate)
 			case strings.EqualFold(payloadCodec.Name, H264):
 				codec = NewRTPH264Codec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, H265):
+				codec = NewRTPH265Codec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, TelephoneEvent):
+				codec = NewRTPTelephoneEventCodec(payloadType, payloadCodec.ClockRate)
+			case md.MediaName.Media == mediaNameAudio && payloadType < minDynamicPayloadType:
+				// Other static audio codecs are passed through as is
+				codec = NewRTPPassthroughAudioCodec(payloadCodec.Name, payloadType, payloadCodec.ClockRate, 0)
 			default:
 				// ignoring other codecs
 				continue
 			}
 
+			if _, err := m.getCodec(payloadType); err == nil {
+				// already populated from an earlier media section
+				continue
+			}
+
+			if channels, err := strconv.Atoi(payloadCodec.EncodingParameters); err == nil {
+				codec.Channels = uint16(channels)
+			}
 			codec.SDPFmtpLine = payloadCodec.Fmtp
+			codec.RTCPFeedback = nil
+			for _, feedback := range payloadCodec.RTCPFeedback {
+				parts := strings.SplitN(feedback, " ", 2)
+				rtcpFeedback := RTCPFeedback{Type: parts[0]}
+				if len(parts) == 2 {
+					rtcpFeedback.Parameter = parts[1]
+				}
+				codec.RTCPFeedback = append(codec.RTCPFeedback, rtcpFeedback)
+			}
 			m.RegisterCodec(codec)
 		}
 	}
@@ -144,7 +203,7 @@ func (m *MediaEngine) getCodecSDP(sdpCodec sdp.Codec) (*RTPCodec, error) {
 			codec.ClockRate == sdpCodec.ClockRate &&
 			(sdpCodec.EncodingParameters == "" ||
 				strconv.Itoa(int(codec.Channels)) == sdpCodec.EncodingParameters) &&
-			codec.SDPFmtpLine == sdpCodec.Fmtp { // pion/webrtc#43
+			fmtpEqual(codec.SDPFmtpLine, sdpCodec.Fmtp) { // pion/webrtc#43
 			return codec, nil
 		}
 	}
@@ -173,6 +232,10 @@ const (
 	VP8  = "VP8"
 	VP9  = "VP9"
 	H264 = "H264"
+	H265 = "H265"
+
+	// TelephoneEvent carries DTMF tones next to the audio, RFC 4733
+	TelephoneEvent = "telephone-event"
 )
 
 // NewRTPPCMUCodec is a helper to create a PCMU codec
@@ -211,6 +274,21 @@ func NewRTPG722Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 	return c
 }
 
+// NewRTPPassthroughAudioCodec is a helper to create an audio codec that is
+// sent without codec specific packetization, samples are only split at the MTU.
+// It allows bridging audio, like the static payload types used by SIP, without
+// transcoding it.
+func NewRTPPassthroughAudioCodec(name string, payloadType uint8, clockrate uint32, channels uint16) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		name,
+		clockrate,
+		channels,
+		"",
+		payloadType,
+		&codecs.G711Payloader{})
+	return c
+}
+
 // NewRTPOpusCodec is a helper to create an Opus codec
 func NewRTPOpusCodec(payloadType uint8, clockrate uint32) *RTPCodec {
 	c := NewRTPCodec(RTPCodecTypeAudio,
@@ -223,6 +301,21 @@ func NewRTPOpusCodec(payloadType uint8, clockrate uint32) *RTPCodec {
 	return c
 }
 
+// NewRTPTelephoneEventCodec is a helper to create a telephone-event codec
+// for the DTMF tones 0-9, *, # and A-D. It has no Payloader since its packets
+// are sent by the DTMFSender of an audio RTPSender, the clock rate has to be
+// the one of the audio codec.
+func NewRTPTelephoneEventCodec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		TelephoneEvent,
+		clockrate,
+		0,
+		"0-15",
+		payloadType,
+		nil)
+	return c
+}
+
 // NewRTPVP8Codec is a helper to create an VP8 codec
 func NewRTPVP8Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 	c := NewRTPCodec(RTPCodecTypeVideo,
@@ -298,39 +391,30 @@ func NewRTPH264CodecExt(payloadType uint8, clockrate uint32, rtcpfb []RTCPFeedba
 	return c
 }
 
-// RTPCodecType determines the type of a codec
-type RTPCodecType int
-
-const (
-
-	// RTPCodecTypeAudio indicates this is an audio codec
-	RTPCodecTypeAudio RTPCodecType = iota + 1
-
-	// RTPCodecTypeVideo indicates this is a video codec
-	RTPCodecTypeVideo
-)
-
-func (t RTPCodecType) String() string {
-	switch t {
-	case RTPCodecTypeAudio:
-		return "audio"
-	case RTPCodecTypeVideo:
-		return "video"
-	default:
-		return ErrUnknownType.Error()
-	}
+// NewRTPH265Codec is a helper to create an H265 codec.
+// It isn't part of RegisterDefaultCodecs since few browsers support it.
+func NewRTPH265Codec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeVideo,
+		H265,
+		clockrate,
+		0,
+		"",
+		payloadType,
+		&h265.Payloader{})
+	return c
 }
 
-// NewRTPCodecType creates a RTPCodecType from a string
-func NewRTPCodecType(r string) RTPCodecType {
-	switch {
-	case strings.EqualFold(r, "audio"):
-		return RTPCodecTypeAudio
-	case strings.EqualFold(r, "video"):
-		return RTPCodecTypeVideo
-	default:
-		return RTPCodecType(0)
-	}
+// NewRTPH265CodecExt is a helper to create an H265 codec
+func NewRTPH265CodecExt(payloadType uint8, clockrate uint32, rtcpfb []RTCPFeedback, fmtp string) *RTPCodec {
+	c := NewRTPCodecExt(RTPCodecTypeVideo,
+		H265,
+		clockrate,
+		0,
+		fmtp,
+		payloadType,
+		rtcpfb,
+		&h265.Payloader{})
+	return c
 }
 
 // RTPCodec represents a codec supported by the PeerConnection
@@ -392,15 +476,6 @@ func NewRTPCodecExt(
 	}
 }
 
-// RTPCodecCapability provides information about codec capabilities.
-type RTPCodecCapability struct {
-	MimeType     string
-	ClockRate    uint32
-	Channels     uint16
-	SDPFmtpLine  string
-	RTCPFeedback []RTCPFeedback
-}
-
 // RTPHeaderExtensionCapability is used to define a RFC5285 RTP header extension supported by the codec.
 type RTPHeaderExtensionCapability struct {
 	URI string
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/pion/sdp/v2"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -130,3 +131,182 @@ func TestGetCodecsByName(t *testing.T) {
 	assertGetCodecsByName(VP9)
 	assertGetCodecsByName(Opus)
 }
+
+func TestPopulateMediaEngineFromRemoteOffer(t *testing.T) {
+	const (
+		profileBaseline = "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f"
+		profileHigh     = "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640032"
+	)
+
+	offerer := MediaEngine{}
+	offerer.RegisterCodec(NewRTPH264CodecExt(125, 90000, []RTCPFeedback{{Type: "nack", Parameter: "pli"}}, profileBaseline))
+	offerer.RegisterCodec(NewRTPH264CodecExt(127, 90000, nil, profileHigh))
+	pcOffer, err := NewAPI(WithMediaEngine(offerer)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	s := SettingEngine{}
+	s.PopulateMediaEngineFromRemoteOffer(true)
+	pcAnswer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	// The API keeps its codecs, the transceiver of the offer uses the offered
+	// ones
+	assert.Empty(t, pcAnswer.api.mediaEngine.GetCodecsByName(H264))
+	assert.Equal(t, pcAnswer.getMediaEngine(), pcAnswer.GetTransceivers()[0].Receiver().mediaEngine)
+	codecs := pcAnswer.getMediaEngine().GetCodecsByName(H264)
+	if assert.Len(t, codecs, 2) {
+		assert.Equal(t, uint8(125), codecs[0].PayloadType)
+		assert.Equal(t, profileBaseline, codecs[0].SDPFmtpLine)
+		assert.Equal(t, []RTCPFeedback{{Type: "nack", Parameter: "pli"}}, codecs[0].RTCPFeedback)
+		assert.Equal(t, uint8(127), codecs[1].PayloadType)
+		assert.Equal(t, profileHigh, codecs[1].SDPFmtpLine)
+	}
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=fmtp:127 "+profileHigh)
+
+	// Transceivers created before the offer would keep the codecs of the API
+	pcLate, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = pcLate.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrMediaEngineInUse}, pcLate.SetRemoteDescription(offer))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	assert.NoError(t, pcLate.Close())
+}
+
+func TestPopulateFromSDP_H265(t *testing.T) {
+	const sdpH265 = `v=0
+o=- 884433216 1576829404 IN IP4 0.0.0.0
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 49
+c=IN IP4 0.0.0.0
+a=mid:0
+a=rtpmap:49 H265/90000
+a=fmtp:49 level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpH265}))
+
+	codecs := m.GetCodecsByName(H265)
+	if assert.Len(t, codecs, 1) {
+		assert.Equal(t, uint8(49), codecs[0].PayloadType)
+		assert.Equal(t, "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST", codecs[0].SDPFmtpLine)
+		assert.NotNil(t, codecs[0].Payloader)
+	}
+}
+
+func TestPopulateFromSDP_TelephoneEvent(t *testing.T) {
+	const sdpTelephoneEvent = `v=0
+o=- 884433216 1576829404 IN IP4 0.0.0.0
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 111 110
+c=IN IP4 0.0.0.0
+a=mid:0
+a=rtpmap:111 opus/48000/2
+a=rtpmap:110 telephone-event/48000
+a=fmtp:110 0-15
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpTelephoneEvent}))
+
+	codecs := m.GetCodecsByName(TelephoneEvent)
+	if assert.Len(t, codecs, 1) {
+		assert.Equal(t, uint8(110), codecs[0].PayloadType)
+		assert.Equal(t, uint32(48000), codecs[0].ClockRate)
+		assert.Equal(t, "0-15", codecs[0].SDPFmtpLine)
+	}
+}
+
+func TestPopulateFromSDP_StaticAudio(t *testing.T) {
+	const sdpSIP = `v=0
+o=- 884433216 1576829404 IN IP4 0.0.0.0
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 0 8 3 18
+c=IN IP4 0.0.0.0
+a=mid:0
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpSIP}))
+
+	for _, expected := range []struct {
+		payloadType uint8
+		name        string
+	}{
+		{0, PCMU},
+		{8, PCMA},
+		{3, "GSM"},
+		{18, "G729"},
+	} {
+		codec, err := m.getCodec(expected.payloadType)
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected.name, codec.Name)
+			assert.Equal(t, uint32(8000), codec.ClockRate)
+		}
+	}
+
+	// Passthrough codecs only split at the MTU
+	codec, err := m.getCodec(18)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{0x01, 0x02}, {0x03}}, codec.Payloader.Payload(2, []byte{0x01, 0x02, 0x03}))
+
+	assert.Error(t, m.PopulateFromSDP(SessionDescription{SDP: strings.Replace(sdpSIP, "audio", "video", 1)}))
+}
+
+func TestFmtpParameters(t *testing.T) {
+	codec := NewRTPH264Codec(DefaultPayloadTypeH264, 90000)
+	codec.SDPFmtpLine = "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f"
+
+	codec.SetFmtpParameter(FmtpProfileLevelID, "42e01f")
+	codec.SetFmtpParameter(FmtpSpropParameterSets, "Z0LAH9kAUAW7AQ==,aMuMsg==")
+	codec.SetFmtpParameter(FmtpLevelAsymmetryAllowed, "")
+	assert.Equal(t, "packetization-mode=1;profile-level-id=42e01f;sprop-parameter-sets=Z0LAH9kAUAW7AQ==,aMuMsg==", codec.SDPFmtpLine)
+
+	value, ok := codec.FmtpParameter("Sprop-Parameter-Sets")
+	assert.True(t, ok)
+	assert.Equal(t, "Z0LAH9kAUAW7AQ==,aMuMsg==", value)
+	_, ok = codec.FmtpParameter(FmtpLevelAsymmetryAllowed)
+	assert.False(t, ok)
+
+	codec.SetH264FmtpParameters(H264FmtpParameters{ProfileLevelID: "640C1F", LevelAsymmetryAllowed: true})
+	assert.Equal(t, map[string]string{
+		FmtpPacketizationMode:     "0",
+		FmtpProfileLevelID:        "640c1f",
+		FmtpLevelAsymmetryAllowed: "1",
+	}, codec.FmtpParameters())
+
+	opus := NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000)
+	opus.SetOpusFmtpParameters(OpusFmtpParameters{MinPTime: 10, MaxAverageBitrate: 64000, UseInbandFEC: true})
+	assert.Equal(t, "minptime=10;useinbandfec=1;maxaveragebitrate=64000", opus.SDPFmtpLine)
+
+	m := MediaEngine{}
+	m.RegisterCodec(opus)
+	assert.NoError(t, m.SetFmtpParameter(DefaultPayloadTypeOpus, FmtpStereo, "1"))
+	assert.Equal(t, ErrCodecNotFound, m.SetFmtpParameter(DefaultPayloadTypeVP8, FmtpStereo, "1"))
+
+	// Remote parameters may come in any order
+	found, err := m.getCodecSDP(sdp.Codec{
+		Name:               "opus",
+		ClockRate:          48000,
+		EncodingParameters: "2",
+		Fmtp:               "stereo=1; maxaveragebitrate=64000;useinbandfec=1;minptime=10",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, opus, found)
+}
@@ -0,0 +1,186 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+)
+
+// SignalingMessage is exchanged between the NegotiationManagers of two peers,
+// it carries a SessionDescription or an ICE candidate
+type SignalingMessage struct {
+	Description *SessionDescription `json:"description,omitempty"`
+	Candidate   *ICECandidateInit   `json:"candidate,omitempty"`
+}
+
+// NegotiationManager negotiates a PeerConnection with the perfect negotiation
+// pattern: it sends an offer whenever negotiation is needed, on either side,
+// and resolves offers that collide. The polite peer rolls its offer back and
+// answers the remote one, the impolite peer ignores the remote offer and
+// waits for the answer to its own. Exactly one of the two peers has to be
+// polite.
+//
+// Changes made while a negotiation is in progress are negotiated once it is
+// done. Only the messages passed to OnSignalingMessage have to be relayed to
+// HandleSignalingMessage on the other peer, in order.
+// https://w3c.github.io/webrtc-pc/#perfect-negotiation-example
+type NegotiationManager struct {
+	pc     *PeerConnection
+	polite bool
+
+	mu          sync.Mutex
+	ignoreOffer bool
+
+	// Messages are queued and sent outside of mu, so the handler can call
+	// HandleSignalingMessage of the other peer directly
+	outbox                  []SignalingMessage
+	sending                 bool
+	onSignalingMessageHdlr  func(SignalingMessage)
+	onNegotiationFailedHdlr func(error)
+}
+
+// NewNegotiationManager creates a NegotiationManager for pc. It takes over
+// the OnNegotiationNeeded and OnICECandidate handlers of pc.
+func NewNegotiationManager(pc *PeerConnection, polite bool) *NegotiationManager {
+	m := &NegotiationManager{pc: pc, polite: polite}
+
+	pc.OnNegotiationNeeded(m.negotiate)
+	pc.OnICECandidate(func(c *ICECandidate) {
+		// Without trickle the candidates are part of the descriptions
+		if c == nil || !pc.api.settingEngine.candidates.ICETrickle {
+			return
+		}
+		candidate := c.ToJSON()
+
+		m.mu.Lock()
+		m.outbox = append(m.outbox, SignalingMessage{Candidate: &candidate})
+		m.mu.Unlock()
+		m.flush()
+	})
+
+	return m
+}
+
+// OnSignalingMessage sets the handler sending messages to the other peer
+func (m *NegotiationManager) OnSignalingMessage(f func(SignalingMessage)) {
+	m.mu.Lock()
+	m.onSignalingMessageHdlr = f
+	m.mu.Unlock()
+	m.flush()
+}
+
+// OnNegotiationFailed sets an event handler which is invoked when an offer
+// started because negotiation is needed fails
+func (m *NegotiationManager) OnNegotiationFailed(f func(error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onNegotiationFailedHdlr = f
+}
+
+// HandleSignalingMessage applies a message from the other peer, answering
+// offers. Offers ignored by an impolite peer and their candidates are not
+// errors.
+func (m *NegotiationManager) HandleSignalingMessage(msg SignalingMessage) error {
+	m.mu.Lock()
+	err := m.handleSignalingMessage(msg)
+	m.mu.Unlock()
+
+	m.flush()
+	return err
+}
+
+func (m *NegotiationManager) handleSignalingMessage(msg SignalingMessage) error {
+	if msg.Candidate != nil {
+		if err := m.pc.AddICECandidate(*msg.Candidate); err != nil && !m.ignoreOffer {
+			return err
+		}
+		return nil
+	}
+	if msg.Description == nil {
+		return nil
+	}
+
+	// Operations are serialized by mu, so a collision is a remote offer
+	// while our own is waiting for its answer
+	offerCollision := msg.Description.Type == SDPTypeOffer && m.pc.SignalingState() != SignalingStateStable
+	m.ignoreOffer = !m.polite && offerCollision
+	if m.ignoreOffer {
+		m.pc.log.Info("ignoring colliding offer")
+		return nil
+	}
+
+	// A polite peer implicitly rolls its own offer back
+	if err := m.pc.SetRemoteDescription(*msg.Description); err != nil {
+		return err
+	}
+	if msg.Description.Type != SDPTypeOffer {
+		return nil
+	}
+
+	answer, err := m.pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	if err = m.pc.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	m.outbox = append(m.outbox, SignalingMessage{Description: m.pc.LocalDescription()})
+	return nil
+}
+
+func (m *NegotiationManager) negotiate() {
+	m.mu.Lock()
+	err := m.createOffer()
+	hdlr := m.onNegotiationFailedHdlr
+	m.mu.Unlock()
+
+	m.flush()
+	if err != nil {
+		m.pc.log.Warnf("negotiation failed: %v", err)
+		if hdlr != nil {
+			hdlr(err)
+		}
+	}
+}
+
+func (m *NegotiationManager) createOffer() error {
+	// Another negotiation is in progress, the PeerConnection fires
+	// OnNegotiationNeeded again once it is done
+	if m.pc.SignalingState() != SignalingStateStable {
+		return nil
+	}
+
+	offer, err := m.pc.CreateOffer(nil)
+	if err != nil {
+		return err
+	}
+	if err = m.pc.SetLocalDescription(offer); err != nil {
+		return err
+	}
+	m.outbox = append(m.outbox, SignalingMessage{Description: m.pc.LocalDescription()})
+	return nil
+}
+
+// flush sends the queued messages in order. Only one goroutine sends at a
+// time, the others leave their messages to it.
+func (m *NegotiationManager) flush() {
+	m.mu.Lock()
+	if m.sending || m.onSignalingMessageHdlr == nil {
+		m.mu.Unlock()
+		return
+	}
+	m.sending = true
+
+	for len(m.outbox) != 0 {
+		msg := m.outbox[0]
+		m.outbox = m.outbox[1:]
+		hdlr := m.onSignalingMessageHdlr
+		m.mu.Unlock()
+
+		hdlr(msg)
+
+		m.mu.Lock()
+	}
+	m.sending = false
+	m.mu.Unlock()
+}
@@ -0,0 +1,155 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// networkMonitor polls the addresses of the local interfaces once per
+// interval, and calls onChange when they differ from the previous poll
+type networkMonitor struct {
+	interval  time.Duration
+	addresses func() ([]string, error)
+	onChange  func()
+
+	mu      sync.Mutex
+	last    []string
+	hasLast bool
+	timer   *time.Timer
+	closed  bool
+}
+
+func newNetworkMonitor(interval time.Duration, addresses func() ([]string, error), onChange func()) *networkMonitor {
+	m := &networkMonitor{
+		interval:  interval,
+		addresses: addresses,
+		onChange:  onChange,
+	}
+	if last, err := addresses(); err == nil {
+		m.last, m.hasLast = last, true
+	}
+	m.timer = time.AfterFunc(interval, m.poll)
+	return m
+}
+
+func (m *networkMonitor) poll() {
+	addresses, err := m.addresses()
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	// A failed poll tells nothing, the next one compares with the last success
+	changed := false
+	if err == nil {
+		changed = m.hasLast && !equalStrings(m.last, addresses)
+		m.last, m.hasLast = addresses, true
+	}
+	m.timer = time.AfterFunc(m.interval, m.poll)
+	m.mu.Unlock()
+
+	if changed {
+		m.onChange()
+	}
+}
+
+func (m *networkMonitor) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	m.timer.Stop()
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// localAddresses returns the sorted addresses of the interfaces candidates
+// are gathered on: the ones that are up, aren't loopback and pass the
+// InterfaceFilter of the SettingEngine
+func (pc *PeerConnection) localAddresses() ([]string, error) {
+	filter := pc.api.settingEngine.candidates.InterfaceFilter
+	addresses := []string{}
+	add := func(name string, flags net.Flags, addrs []net.Addr) {
+		if flags&net.FlagUp == 0 || flags&net.FlagLoopback != 0 || (filter != nil && !filter(name)) {
+			return
+		}
+		for _, addr := range addrs {
+			addresses = append(addresses, addr.String())
+		}
+	}
+
+	if n := pc.api.settingEngine.vnet; n != nil && n.IsVirtual() {
+		ifcs, err := n.Interfaces()
+		if err != nil {
+			return nil, err
+		}
+		for _, ifc := range ifcs {
+			addrs, err := ifc.Addrs()
+			if err != nil {
+				return nil, err
+			}
+			add(ifc.Name, ifc.Flags, addrs)
+		}
+	} else {
+		ifcs, err := net.Interfaces()
+		if err != nil {
+			return nil, err
+		}
+		for _, ifc := range ifcs {
+			addrs, err := ifc.Addrs()
+			if err != nil {
+				return nil, err
+			}
+			add(ifc.Name, ifc.Flags, addrs)
+		}
+	}
+
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// networkChanged is called by the networkMonitor when the local addresses
+// changed. Once negotiated, ICE is restarted so candidates are gathered on
+// the new interfaces, the DTLS and SCTP associations and the Tracks are kept.
+func (pc *PeerConnection) networkChanged() {
+	if pc.isClosed.get() {
+		return
+	}
+
+	pc.mu.RLock()
+	hdlr := pc.onNetworkChangedHandler
+	pc.mu.RUnlock()
+
+	pc.log.Info("local network changed")
+	if hdlr != nil {
+		go hdlr()
+	}
+
+	if pc.CurrentRemoteDescription() != nil {
+		pc.RestartICE()
+	}
+}
+
+// OnNetworkChanged sets an event handler which is invoked when the addresses
+// of the local interfaces changed, like when a mobile switches from Wi-Fi to
+// LTE. See SettingEngine.SetNetworkMonitorInterval.
+func (pc *PeerConnection) OnNetworkChanged(f func()) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onNetworkChangedHandler = f
+}
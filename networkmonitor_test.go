@@ -0,0 +1,93 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkMonitor(t *testing.T) {
+	var mu sync.Mutex
+	addresses := []string{"192.168.1.2/24"}
+	var pollErr error
+	setAddresses := func(a []string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		addresses, pollErr = a, err
+	}
+
+	changes := make(chan struct{}, 10)
+	m := newNetworkMonitor(5*time.Millisecond, func() ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return addresses, pollErr
+	}, func() {
+		changes <- struct{}{}
+	})
+
+	expectNoChange := func() {
+		select {
+		case <-changes:
+			t.Fatal("unexpected change")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	expectNoChange()
+
+	// A failed poll isn't a change, only what differs from the last success
+	setAddresses(nil, errors.New("poll failed"))
+	expectNoChange()
+	setAddresses([]string{"10.0.0.2/8"}, nil)
+	<-changes
+	expectNoChange()
+
+	m.close()
+	setAddresses([]string{"192.168.1.2/24"}, nil)
+	expectNoChange()
+}
+
+func TestPeerConnection_NetworkChanged(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	networkChanged, negotiationNeeded := make(chan struct{}), make(chan struct{})
+	pcOffer.OnNetworkChanged(func() {
+		close(networkChanged)
+	})
+	pcOffer.OnNegotiationNeeded(func() {
+		close(negotiationNeeded)
+	})
+
+	params, err := pcOffer.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+
+	pcOffer.networkChanged()
+	<-networkChanged
+	<-negotiationNeeded
+
+	// The next offer restarts ICE without being asked to
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	ufrag, _, _, err := extractICEDetails(offer.parsed)
+	assert.NoError(t, err)
+	assert.NotEqual(t, params.UsernameFragment, ufrag)
+	assert.False(t, pcOffer.iceRestartNeeded.get())
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
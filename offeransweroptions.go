@@ -12,6 +12,11 @@ type OfferAnswerOptions struct {
 // creation process.
 type AnswerOptions struct {
 	OfferAnswerOptions
+
+	// RejectedMids are the mids of the offered media sections the answer
+	// rejects. They are answered in place with a zero port, and their
+	// transceivers are stopped once the answer is set.
+	RejectedMids []string
 }
 
 // OfferOptions structure describes the options used to control the offer
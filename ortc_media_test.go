@@ -0,0 +1,89 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTP_ORTCE2E(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	stackA, stackB, err := newORTCPair()
+	assert.NoError(t, err)
+	stackB.api.mediaEngine.RegisterDefaultCodecs()
+
+	assert.NoError(t, signalORTCPair(stackA, stackB))
+
+	pair := stackA.ice.GetSelectedCandidatePair()
+	assert.NotNil(t, pair)
+	localCandidates, err := stackA.ice.GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.Contains(t, localCandidates, *pair.Local)
+
+	remoteParameters, err := stackB.ice.GetLocalParameters()
+	assert.NoError(t, err)
+	assert.Equal(t, remoteParameters, stackA.ice.GetRemoteParameters())
+
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	coding := RTPCodingParameters{SSRC: track.SSRC(), PayloadType: track.PayloadType()}
+
+	sender, err := stackA.api.NewRTPSender(track, stackA.dtls)
+	assert.NoError(t, err)
+	assert.NoError(t, sender.Send(RTPSendParameters{Encodings: RTPEncodingParameters{coding}}))
+
+	// An unknown payload type fails before the receiver starts
+	unknown, err := stackB.api.NewRTPReceiver(RTPCodecTypeVideo, stackB.dtls)
+	assert.NoError(t, err)
+	unknownCoding := RTPCodingParameters{SSRC: 4321, PayloadType: 77}
+	assert.Error(t, unknown.Receive(RTPReceiveParameters{Encodings: RTPDecodingParameters{unknownCoding}}))
+	assert.Nil(t, unknown.Track())
+	assert.NoError(t, unknown.Stop())
+
+	receiver, err := stackB.api.NewRTPReceiver(RTPCodecTypeVideo, stackB.dtls)
+	assert.NoError(t, err)
+	assert.NoError(t, receiver.Receive(RTPReceiveParameters{Encodings: RTPDecodingParameters{coding}}))
+	assert.Equal(t, VP8, receiver.Track().Codec().Name)
+
+	received := make(chan struct{})
+	go func() {
+		packet, readErr := receiver.Track().ReadRTP()
+		assert.NoError(t, readErr)
+		assert.Equal(t, track.SSRC(), packet.SSRC)
+
+		b := make([]byte, receiveMTU)
+		lent := &rtp.Packet{}
+		assert.NoError(t, receiver.Track().ReadRTPInto(b, lent))
+		assert.Equal(t, track.SSRC(), lent.SSRC)
+		assert.Equal(t, &b[0], &lent.Raw[0])
+		close(received)
+	}()
+
+	func() {
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			case <-received:
+				return
+			}
+		}
+	}()
+
+	assert.NoError(t, sender.Stop())
+	assert.NoError(t, receiver.Stop())
+	assert.NoError(t, stackA.close())
+	assert.NoError(t, stackB.close())
+}
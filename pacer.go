@@ -0,0 +1,101 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// Pacer spaces out the RTP packets of the RTPSenders it is set on, so a
+// keyframe is sent at the estimated bandwidth instead of as one burst that
+// overflows the queues of routers on the path.
+//
+// The Pacer is a token bucket: after being idle up to burst bytes are sent
+// right away, then packets wait until the bitrate allows them. A Pacer set on
+// several RTPSenders paces their combined rate, which is what a bandwidth
+// estimate of the whole PeerConnection should be applied to.
+type Pacer struct {
+	mu       sync.Mutex
+	bitrate  uint64
+	burst    float64
+	interval time.Duration
+
+	// tokens are the bytes that can be sent right away, negative once packets
+	// are waiting for their turn
+	tokens float64
+	last   time.Time
+}
+
+// NewPacer creates a Pacer sending at bitrate bits per second and up to
+// burst bytes at once. Packets that would wait for less than interval are
+// sent right away, which keeps the Pacer from sleeping for every packet.
+func NewPacer(bitrate uint64, burst int, interval time.Duration) *Pacer {
+	return &Pacer{
+		bitrate:  bitrate,
+		burst:    float64(burst),
+		interval: interval,
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// SetBitrate changes the rate in bits per second, e.g. when the bandwidth
+// estimate changes. Packets already waiting keep their turn. A bitrate of 0
+// stops pacing.
+func (p *Pacer) SetBitrate(bitrate uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.refill(time.Now())
+	p.bitrate = bitrate
+}
+
+// Bitrate returns the rate in bits per second
+func (p *Pacer) Bitrate() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.bitrate
+}
+
+// refill adds the tokens earned since the last refill, p.mu must be held
+func (p *Pacer) refill(now time.Time) {
+	p.tokens += now.Sub(p.last).Seconds() * float64(p.bitrate) / 8
+	if p.tokens > p.burst {
+		p.tokens = p.burst
+	}
+	p.last = now
+}
+
+// wait blocks until size bytes may be sent. It returns false if cancel is
+// closed first, the bytes then still count as sent.
+func (p *Pacer) wait(size int, cancel <-chan interface{}) bool {
+	p.mu.Lock()
+	if p.bitrate == 0 {
+		p.mu.Unlock()
+		return true
+	}
+
+	p.refill(time.Now())
+	p.tokens -= float64(size)
+
+	var delay time.Duration
+	if p.tokens < 0 {
+		delay = time.Duration(-p.tokens * 8 / float64(p.bitrate) * float64(time.Second))
+	}
+	p.mu.Unlock()
+
+	if delay < p.interval {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-cancel:
+		return false
+	}
+}
@@ -0,0 +1,95 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// sequenceWriteStream records the sequence numbers of the packets written
+type sequenceWriteStream struct {
+	rtp.WriteStream
+	written chan uint16
+}
+
+func (s *sequenceWriteStream) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	s.written <- header.SequenceNumber
+	return header.MarshalSize() + len(payload), nil
+}
+
+func TestPacer(t *testing.T) {
+	t.Run("Burst", func(t *testing.T) {
+		// 100 kB/s after a burst of 10 kB
+		p := NewPacer(800000, 10000, time.Millisecond)
+
+		start := time.Now()
+		for i := 0; i < 10; i++ {
+			assert.True(t, p.wait(1000, nil))
+		}
+		assert.True(t, time.Since(start) < 50*time.Millisecond)
+
+		for i := 0; i < 10; i++ {
+			assert.True(t, p.wait(1000, nil))
+		}
+		elapsed := time.Since(start)
+		assert.True(t, elapsed >= 80*time.Millisecond, elapsed)
+		assert.True(t, elapsed < time.Second, elapsed)
+	})
+
+	t.Run("Unpaced", func(t *testing.T) {
+		p := NewPacer(0, 0, 0)
+
+		start := time.Now()
+		for i := 0; i < 100; i++ {
+			assert.True(t, p.wait(100000, nil))
+		}
+		assert.True(t, time.Since(start) < 50*time.Millisecond)
+
+		p.SetBitrate(8000)
+		assert.Equal(t, uint64(8000), p.Bitrate())
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		p := NewPacer(8000, 0, 0)
+
+		cancel := make(chan interface{})
+		close(cancel)
+		assert.False(t, p.wait(1000, cancel))
+	})
+	t.Run("Queue", func(t *testing.T) {
+		// Every packet waits for more than 100ms
+		p := NewPacer(8000, 0, 0)
+		stream := &sequenceWriteStream{written: make(chan uint16, pacedQueueSize)}
+		r := &RTPSender{
+			stopCalled: make(chan interface{}),
+			pacedQueue: make(chan *pacedPacket, pacedQueueSize),
+		}
+
+		start := time.Now()
+		header := &rtp.Header{Version: 2}
+		queued := 0
+		for i := 0; i < pacedQueueSize+10; i++ {
+			header.SequenceNumber = uint16(i)
+			packet, err := newPacedPacket(stream, header, make([]byte, 100), p, nil)
+			assert.NoError(t, err)
+			if r.queuePaced(packet) {
+				queued++
+			}
+		}
+		assert.True(t, time.Since(start) < 50*time.Millisecond, "queueing doesn't wait for the Pacer")
+		assert.True(t, queued <= pacedQueueSize+1, "the packets over the queue are dropped")
+
+		// The header was copied when the packet was queued
+		select {
+		case sequenceNumber := <-stream.written:
+			assert.Equal(t, uint16(0), sequenceNumber)
+		case <-time.After(time.Second):
+			assert.Fail(t, "the first packet wasn't sent")
+		}
+		close(r.stopCalled)
+	})
+}
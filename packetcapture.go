@@ -0,0 +1,279 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media/pcap"
+	"github.com/pion/webrtc/v2/pkg/media/rtpdump"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+// Addresses used in pcap captures when no candidate pair is selected
+var (
+	captureFallbackLocalAddr  = net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}
+	captureFallbackRemoteAddr = net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5001}
+)
+
+// packetCapture writes the packets of a DTLSTransport to one file
+type packetCapture struct {
+	start   time.Time
+	rtpdump *rtpdump.Writer
+	pcap    *pcap.Writer
+}
+
+// StartCapture writes every RTP and RTCP packet sent or received on the
+// transport to w, decrypted, until StopCapture is called. The capture can be
+// started and stopped at any time, replacing any capture in progress.
+// Packets are timestamped when they are read from or written to the
+// transport. If writing to w fails the capture stops.
+func (t *DTLSTransport) StartCapture(w io.Writer, format PacketCaptureFormat) error {
+	capture := &packetCapture{start: time.Now()}
+
+	var err error
+	switch format {
+	case PacketCaptureFormatRTPDump:
+		// The rtpdump header only has room for an IPv4 source
+		remote, _ := t.captureAddr(true)
+		if remote.IP.To4() == nil {
+			remote = captureFallbackRemoteAddr
+		}
+		capture.rtpdump, err = rtpdump.NewWriter(w, rtpdump.Header{
+			Start:  capture.start,
+			Source: remote.IP,
+			Port:   uint16(remote.Port),
+		})
+	case PacketCaptureFormatPCAP:
+		capture.pcap, err = pcap.NewWriter(w)
+	default:
+		return &rtcerr.InvalidAccessError{Err: ErrUnknownType}
+	}
+	if err != nil {
+		return err
+	}
+
+	t.captureLock.Lock()
+	t.capture = capture
+	t.captureLock.Unlock()
+	return nil
+}
+
+// StopCapture stops the capture started by StartCapture. Nothing is written
+// to the capture after it returns, so its writer can be closed.
+func (t *DTLSTransport) StopCapture() {
+	t.captureLock.Lock()
+	t.capture = nil
+	t.captureLock.Unlock()
+}
+
+func (t *DTLSTransport) capturing() bool {
+	t.captureLock.RLock()
+	defer t.captureLock.RUnlock()
+	return t.capture != nil
+}
+
+// captureAddr returns the local or remote address of the selected candidate
+// pair, or a fallback if there is none yet
+func (t *DTLSTransport) captureAddr(remote bool) (net.UDPAddr, bool) {
+	fallback := captureFallbackLocalAddr
+	if remote {
+		fallback = captureFallbackRemoteAddr
+	}
+
+	t.lock.RLock()
+	endpoint := t.srtpEndpoint
+	t.lock.RUnlock()
+	if endpoint == nil {
+		return fallback, false
+	}
+
+	addr := endpoint.LocalAddr()
+	if remote {
+		addr = endpoint.RemoteAddr()
+	}
+
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return *a, true
+	case *net.TCPAddr:
+		return net.UDPAddr{IP: a.IP, Port: a.Port, Zone: a.Zone}, true
+	default:
+		return fallback, false
+	}
+}
+
+// capturePacket writes packet to the capture in progress, if any
+func (t *DTLSTransport) capturePacket(now time.Time, outbound, isRTCP bool, packet []byte) {
+	t.captureLock.RLock()
+	capture := t.capture
+	if capture == nil {
+		t.captureLock.RUnlock()
+		return
+	}
+
+	var err error
+	if capture.rtpdump != nil {
+		err = capture.rtpdump.WritePacket(rtpdump.Packet{
+			Offset:  now.Sub(capture.start),
+			IsRTCP:  isRTCP,
+			Payload: packet,
+		})
+	} else {
+		p := pcap.Packet{Time: now, Payload: packet}
+		p.Source, _ = t.captureAddr(false)
+		p.Destination, _ = t.captureAddr(true)
+		if !outbound {
+			p.Source, p.Destination = p.Destination, p.Source
+		}
+		err = capture.pcap.WritePacket(p)
+	}
+	t.captureLock.RUnlock()
+
+	if err != nil {
+		t.log.Warnf("Failed to capture packet, stopping capture: %v", err)
+
+		t.captureLock.Lock()
+		if t.capture == capture {
+			t.capture = nil
+		}
+		t.captureLock.Unlock()
+	}
+}
+
+// captureRTPSession records the packets of the streams of an rtp.Session
+type captureRTPSession struct {
+	rtp.Session
+	t *DTLSTransport
+}
+
+func (s *captureRTPSession) OpenWriteStream() (rtp.WriteStream, error) {
+	stream, err := s.Session.OpenWriteStream()
+	if err != nil {
+		return nil, err
+	}
+	return &captureRTPWriteStream{stream, s.t}, nil
+}
+
+func (s *captureRTPSession) OpenReadStream(ssrc uint32) (rtp.ReadStream, error) {
+	stream, err := s.Session.OpenReadStream(ssrc)
+	if err != nil {
+		return nil, err
+	}
+	return &captureRTPReadStream{stream, s.t}, nil
+}
+
+func (s *captureRTPSession) AcceptStream() (rtp.ReadStream, uint32, error) {
+	stream, ssrc, err := s.Session.AcceptStream()
+	if err != nil {
+		return nil, 0, err
+	}
+	return &captureRTPReadStream{stream, s.t}, ssrc, nil
+}
+
+type captureRTPReadStream struct {
+	rtp.ReadStream
+	t *DTLSTransport
+}
+
+func (s *captureRTPReadStream) Read(b []byte) (int, error) {
+	n, err := s.ReadStream.Read(b)
+	if err == nil {
+		s.t.capturePacket(time.Now(), false, false, b[:n])
+	}
+	return n, err
+}
+
+func (s *captureRTPReadStream) ReadRTP(b []byte) (int, *rtp.Header, error) {
+	n, header, err := s.ReadStream.ReadRTP(b)
+	if err == nil {
+		s.t.capturePacket(time.Now(), false, false, b[:n])
+	}
+	return n, header, err
+}
+
+type captureRTPWriteStream struct {
+	rtp.WriteStream
+	t *DTLSTransport
+}
+
+func (s *captureRTPWriteStream) Write(b []byte) (int, error) {
+	now := time.Now()
+	n, err := s.WriteStream.Write(b)
+	if err == nil {
+		s.t.capturePacket(now, true, false, b)
+	}
+	return n, err
+}
+
+func (s *captureRTPWriteStream) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	now := time.Now()
+	n, err := s.WriteStream.WriteRTP(header, payload)
+	if err == nil && s.t.capturing() {
+		if raw, marshalErr := header.Marshal(); marshalErr == nil {
+			s.t.capturePacket(now, true, false, append(raw, payload...))
+		}
+	}
+	return n, err
+}
+
+// captureRTCPSession records the packets of the streams of an rtcp.Session
+type captureRTCPSession struct {
+	rtcp.Session
+	t *DTLSTransport
+}
+
+func (s *captureRTCPSession) OpenWriteStream() (rtcp.WriteStream, error) {
+	stream, err := s.Session.OpenWriteStream()
+	if err != nil {
+		return nil, err
+	}
+	return &captureRTCPWriteStream{stream, s.t}, nil
+}
+
+func (s *captureRTCPSession) OpenReadStream(ssrc uint32) (rtcp.ReadStream, error) {
+	stream, err := s.Session.OpenReadStream(ssrc)
+	if err != nil {
+		return nil, err
+	}
+	return &captureRTCPReadStream{stream, s.t}, nil
+}
+
+func (s *captureRTCPSession) AcceptStream() (rtcp.ReadStream, uint32, error) {
+	stream, ssrc, err := s.Session.AcceptStream()
+	if err != nil {
+		return nil, 0, err
+	}
+	return &captureRTCPReadStream{stream, s.t}, ssrc, nil
+}
+
+type captureRTCPReadStream struct {
+	rtcp.ReadStream
+	t *DTLSTransport
+}
+
+func (s *captureRTCPReadStream) Read(b []byte) (int, error) {
+	n, err := s.ReadStream.Read(b)
+	if err == nil {
+		s.t.capturePacket(time.Now(), false, true, b[:n])
+	}
+	return n, err
+}
+
+type captureRTCPWriteStream struct {
+	rtcp.WriteStream
+	t *DTLSTransport
+}
+
+func (s *captureRTCPWriteStream) Write(b []byte) (int, error) {
+	now := time.Now()
+	n, err := s.WriteStream.Write(b)
+	if err == nil {
+		s.t.capturePacket(now, true, true, b)
+	}
+	return n, err
+}
@@ -0,0 +1,33 @@
+package webrtc
+
+// PacketCaptureFormat is the file format written by a packet capture
+type PacketCaptureFormat int
+
+const (
+	// PacketCaptureFormatRTPDump is the rtpdump format of rtptools. It does
+	// not record the direction of packets, only their time and whether they
+	// are RTCP.
+	PacketCaptureFormatRTPDump PacketCaptureFormat = iota + 1
+
+	// PacketCaptureFormatPCAP is the libpcap format. Packets are framed in
+	// UDP datagrams between the addresses of the selected candidate pair so
+	// Wireshark can tell the directions apart.
+	PacketCaptureFormatPCAP
+)
+
+// This is done this way because of a linter.
+const (
+	packetCaptureFormatRTPDumpStr = "rtpdump"
+	packetCaptureFormatPCAPStr    = "pcap"
+)
+
+func (f PacketCaptureFormat) String() string {
+	switch f {
+	case PacketCaptureFormatRTPDump:
+		return packetCaptureFormatRTPDumpStr
+	case PacketCaptureFormatPCAP:
+		return packetCaptureFormatPCAPStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
@@ -0,0 +1,26 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacketCaptureFormat_String(t *testing.T) {
+	testCases := []struct {
+		format         PacketCaptureFormat
+		expectedString string
+	}{
+		{PacketCaptureFormat(Unknown), unknownStr},
+		{PacketCaptureFormatRTPDump, "rtpdump"},
+		{PacketCaptureFormatPCAP, "pcap"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.format.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
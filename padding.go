@@ -0,0 +1,214 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// paddingSequencer shifts the sequence numbers of the media of an RTPSender
+// by the packets it sends on its own in between, padding-only and DTMF ones,
+// so the stream stays continuous. The media of a Track is shared by its
+// senders, the packets of one sender can't take its sequence numbers.
+type paddingSequencer struct {
+	mu sync.Mutex
+
+	started bool
+	// reserved is set while only packets of the RTPSender were sent, the
+	// first media packet then continues after them
+	reserved           bool
+	offset             uint16
+	ssrc               uint32
+	payloadType        uint8
+	lastSequenceNumber uint16
+	lastTimestamp      uint32
+
+	// sent counts the bytes of media until takeSent
+	sent int
+}
+
+// shift returns header with the sequence number shifted, a copy once the
+// RTPSender sent packets of its own
+func (s *paddingSequencer) shift(header *rtp.Header) *rtp.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reserved {
+		s.reserved = false
+		s.offset = s.lastSequenceNumber + 1 - header.SequenceNumber
+	}
+	if s.offset != 0 {
+		h := *header
+		h.SequenceNumber += s.offset
+		header = &h
+	}
+
+	// Only move forward, retransmitted or reordered packets keep their place
+	if !s.started || int16(header.SequenceNumber-s.lastSequenceNumber) > 0 {
+		s.started = true
+		s.ssrc = header.SSRC
+		s.payloadType = header.PayloadType
+		s.lastSequenceNumber = header.SequenceNumber
+		s.lastTimestamp = header.Timestamp
+	}
+	return header
+}
+
+// addSent counts n bytes of media sent
+func (s *paddingSequencer) addSent(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent += n
+}
+
+// takeSent returns the bytes of media sent since the last call
+func (s *paddingSequencer) takeSent() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := s.sent
+	s.sent = 0
+	return sent
+}
+
+// padding returns the header of the next padding-only packet, false until
+// media was sent
+func (s *paddingSequencer) padding() (rtp.Header, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started || s.reserved {
+		return rtp.Header{}, false
+	}
+	s.offset++
+	s.lastSequenceNumber++
+	return rtp.Header{
+		Version:        2,
+		Padding:        true,
+		PayloadType:    s.payloadType,
+		SequenceNumber: s.lastSequenceNumber,
+		Timestamp:      s.lastTimestamp,
+		SSRC:           s.ssrc,
+	}, true
+}
+
+// reserve returns the sequence number of a packet of the RTPSender, after
+// the last one sent. initial is the one of the first packet of the stream
+// when nothing was sent yet.
+func (s *paddingSequencer) reserve(initial uint16) uint16 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		s.started, s.reserved = true, true
+		s.lastSequenceNumber = initial
+		return initial
+	}
+	s.offset++
+	s.lastSequenceNumber++
+	return s.lastSequenceNumber
+}
+
+// sendPadding sends a padding-only packet with size bytes of padding, at most
+// 255, in the stream of the RTPSender. Nothing is sent before the media.
+func (r *RTPSender) sendPadding(size int) (int, error) {
+	select {
+	case <-r.stopCalled:
+		return 0, ErrSenderStopped
+	case <-r.sendCalled:
+	default:
+		return 0, nil
+	}
+
+	header, ok := r.padding.padding()
+	if !ok {
+		return 0, nil
+	}
+
+	rtpSession, err := r.transport.RTPSession()
+	if err != nil {
+		return 0, err
+	}
+	writeStream, err := rtpSession.OpenWriteStream()
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	limiters := r.rateLimiters()
+	h := r.withHeaderExtensions(&header)
+	r.mu.RUnlock()
+
+	// RFC 3550 5.1, the last octet of the padding is its length
+	payload := make([]byte, size)
+	payload[size-1] = byte(size)
+
+	if !waitRateLimits(h.MarshalSize()+size, r.stopCalled, limiters...) {
+		return 0, ErrSenderStopped
+	}
+	return writeStream.WriteRTP(h, payload)
+}
+
+// SetMinBitrate keeps the stream of the RTPSender at bitrate bits per second
+// or more, by sending padding-only packets whenever the media falls below it.
+// A stream that doesn't go quiet, like a screen share with long static
+// periods, keeps the bandwidth estimate of the remote from collapsing. The
+// padding waits for the Pacer like the media. 0, the default, sends none.
+func (r *RTPSender) SetMinBitrate(bitrate uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.minBitrate = bitrate
+	if bitrate != 0 && !r.minBitrateRunning {
+		r.minBitrateRunning = true
+		go r.sendMinBitrate()
+	}
+}
+
+// MinBitrate returns the bitrate set with SetMinBitrate
+func (r *RTPSender) MinBitrate() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.minBitrate
+}
+
+// sendMinBitrate tops the media sent up with padding once per
+// minBitrateInterval, until the minimum bitrate is removed or the RTPSender
+// stopped
+func (r *RTPSender) sendMinBitrate() {
+	ticker := time.NewTicker(minBitrateInterval)
+	defer ticker.Stop()
+
+	r.padding.takeSent()
+	for {
+		select {
+		case <-r.stopCalled:
+			return
+		case <-ticker.C:
+		}
+
+		r.mu.Lock()
+		bitrate, pacer := r.minBitrate, r.pacer
+		if bitrate == 0 {
+			r.minBitrateRunning = false
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+
+		missing := int(float64(bitrate)/8*minBitrateInterval.Seconds()) - r.padding.takeSent()
+		for missing > 0 {
+			if pacer != nil && !pacer.wait(maxPaddingSize, r.stopCalled) {
+				return
+			}
+			n, err := r.sendPadding(maxPaddingSize)
+			if err != nil || n == 0 {
+				break
+			}
+			missing -= n
+		}
+	}
+}
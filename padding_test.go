@@ -0,0 +1,60 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaddingSequencer(t *testing.T) {
+	s := paddingSequencer{}
+	_, ok := s.padding()
+	assert.False(t, ok, "no padding before the media")
+
+	header := &rtp.Header{SSRC: 5000, PayloadType: 96, SequenceNumber: 65535, Timestamp: 3000}
+	assert.Equal(t, header, s.shift(header))
+
+	padding, ok := s.padding()
+	assert.True(t, ok)
+	assert.True(t, padding.Padding)
+	assert.Equal(t, uint32(5000), padding.SSRC)
+	assert.Equal(t, uint8(96), padding.PayloadType)
+	assert.Equal(t, uint16(0), padding.SequenceNumber)
+	assert.Equal(t, uint32(3000), padding.Timestamp)
+
+	// The media continues after the padding, without changing the header of
+	// the caller
+	next := &rtp.Header{SSRC: 5000, PayloadType: 96, SequenceNumber: 0, Timestamp: 6000}
+	shifted := s.shift(next)
+	assert.Equal(t, uint16(1), shifted.SequenceNumber)
+	assert.Equal(t, uint16(0), next.SequenceNumber)
+
+	padding, ok = s.padding()
+	assert.True(t, ok)
+	assert.Equal(t, uint16(2), padding.SequenceNumber)
+	assert.Equal(t, uint32(6000), padding.Timestamp)
+
+	// Only the media counts towards the minimum bitrate
+	s.addSent(1200)
+	s.addSent(300)
+	assert.Equal(t, 1500, s.takeSent())
+	assert.Equal(t, 0, s.takeSent())
+}
+
+func TestPaddingSequencer_Reserve(t *testing.T) {
+	// A DTMF packet before the media starts the stream
+	s := paddingSequencer{}
+	assert.Equal(t, uint16(100), s.reserve(100))
+	_, ok := s.padding()
+	assert.False(t, ok, "no padding before the media")
+	assert.Equal(t, uint16(101), s.reserve(500))
+	assert.Equal(t, uint16(102), s.shift(&rtp.Header{SequenceNumber: 7000}).SequenceNumber)
+	assert.Equal(t, uint16(103), s.shift(&rtp.Header{SequenceNumber: 7001}).SequenceNumber)
+
+	// Once the media started the packets of the RTPSender continue it
+	assert.Equal(t, uint16(104), s.reserve(500))
+	assert.Equal(t, uint16(105), s.shift(&rtp.Header{SequenceNumber: 7002}).SequenceNumber)
+}
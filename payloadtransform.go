@@ -0,0 +1,52 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+
+	"github.com/pion/rtp"
+)
+
+// PayloadTransform rewrites the payloads of the RTP packets of an RTPSender
+// before they are protected by SRTP, or of an RTPReceiver once they are
+// unprotected. The RTP header stays readable, so forwarding units can still
+// route the packets while the media is encrypted end to end by the
+// application, like with SFrame.
+//
+// The payload includes the codec payload descriptor. A transform that wants
+// forwarding units to see keyframes or layers has to leave it in the clear.
+type PayloadTransform interface {
+	// Transform returns the new payload of the packet with header. It must not
+	// modify header or payload, a sent payload may be shared by several
+	// RTPSenders. Returning an error drops the packet.
+	Transform(header *rtp.Header, payload []byte) ([]byte, error)
+}
+
+// PayloadTransformFunc is a function implementing PayloadTransform
+type PayloadTransformFunc func(header *rtp.Header, payload []byte) ([]byte, error)
+
+// Transform calls f(header, payload)
+func (f PayloadTransformFunc) Transform(header *rtp.Header, payload []byte) ([]byte, error) {
+	return f(header, payload)
+}
+
+// transformRTP applies transform to the packet in b[:n] and writes the result
+// back to b
+func transformRTP(transform PayloadTransform, b []byte, n int) (int, error) {
+	p := &rtp.Packet{}
+	if err := p.Unmarshal(b[:n]); err != nil {
+		return 0, err
+	}
+
+	payload, err := transform.Transform(&p.Header, p.Payload)
+	if err != nil {
+		return 0, err
+	}
+	p.Payload = payload
+
+	if p.MarshalSize() > len(b) {
+		return 0, io.ErrShortBuffer
+	}
+	return p.MarshalTo(b)
+}
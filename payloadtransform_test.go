@@ -0,0 +1,97 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// xorTransform is a toy cipher, prefixing the payload with a marker the
+// receiving side checks
+func xorTransform(encrypt bool) PayloadTransform {
+	return PayloadTransformFunc(func(header *rtp.Header, payload []byte) ([]byte, error) {
+		if !encrypt {
+			if len(payload) == 0 || payload[0] != 0xAA {
+				return nil, errors.New("not encrypted")
+			}
+			payload = payload[1:]
+		}
+
+		out := make([]byte, 0, len(payload)+1)
+		if encrypt {
+			out = append(out, 0xAA)
+		}
+		for _, b := range payload {
+			out = append(out, b^byte(header.SequenceNumber))
+		}
+		return out, nil
+	})
+}
+
+func TestTransformRTP(t *testing.T) {
+	p := &rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: 5}, Payload: []byte{0x01, 0x02}}
+	raw, err := p.Marshal()
+	assert.NoError(t, err)
+
+	b := make([]byte, receiveMTU)
+	n := copy(b, raw)
+	n, err = transformRTP(xorTransform(true), b, n)
+	assert.NoError(t, err)
+	assert.Equal(t, len(raw)+1, n)
+
+	n, err = transformRTP(xorTransform(false), b, n)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, b[:n])
+
+	_, err = transformRTP(xorTransform(true), b[:n], n)
+	assert.Equal(t, io.ErrShortBuffer, err)
+}
+
+func TestPeerConnection_PayloadTransform(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+	sender.SetPayloadTransform(xorTransform(true))
+
+	transceiver, err := pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	transceiver.Receiver().SetPayloadTransform(xorTransform(false))
+
+	trackDone, trackDoneFunc := context.WithCancel(context.Background())
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		for i := 0; i < 5; i++ {
+			packet, readErr := track.ReadRTP()
+			assert.NoError(t, readErr)
+			// The VP8 payload descriptor and the sample
+			assert.Equal(t, []byte{0x10, 0x00}, packet.Payload)
+		}
+		trackDoneFunc()
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(trackDone.Done(), t, []*Track{vp8Track})
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
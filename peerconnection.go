@@ -4,6 +4,7 @@
 package webrtc
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/pion/logging"
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/sdp/v2"
 
 	"github.com/pion/webrtc/v2/internal/util"
@@ -46,9 +48,13 @@ type PeerConnection struct {
 	idpLoginURL *string
 
 	isClosed                     *atomicBool
+	closed                       chan interface{} // closed with isClosed, to stop the loops of the PeerConnection
 	negotiationNeeded            bool
 	nonTrickleCandidatesSignaled *atomicBool
 
+	// iceRestartNeeded is set by RestartICE until the next offer restarts ICE
+	iceRestartNeeded *atomicBool
+
 	lastOffer  string
 	lastAnswer string
 
@@ -60,17 +66,52 @@ type PeerConnection struct {
 
 	rtpTransceivers []*RTPTransceiver
 
+	// undeclaredSSRCMu serializes starting receivers for the streams whose
+	// SSRC the remote didn't signal, which are routed concurrently
+	undeclaredSSRCMu sync.Mutex
+
+	// streamReceivers receive the streams after the first of the unified-plan
+	// media sections that carry several
+	streamReceivers []*RTPReceiver
+
+	// maxBitrate is the bandwidth limit of the session advertised, 0 for none
+	maxBitrate uint64
+
+	// cname groups the streams sent in the SDP and the SDES
+	cname string
+
+	// rtcpMuxRequired is set when RTCPMuxPolicyRequire was configured. The
+	// default policy is require as well, but doesn't reject remotes without
+	// rtcp-mux, which Pion always accepted.
+	rtcpMuxRequired bool
+
 	onSignalingStateChangeHandler     func(SignalingState)
 	onICEConnectionStateChangeHandler func(ICEConnectionState)
 	onConnectionStateChangeHandler    func(PeerConnectionState)
 	onTrackHandler                    func(*Track, *RTPReceiver)
 	onDataChannelHandler              func(*DataChannel)
+	onICERestartCompleteHandler       func()
+	onNegotiationNeededHandler        func()
+	onUnhandledRTPHandler             func(*rtp.Packet)
+	onNetworkChangedHandler           func()
 
 	iceGatherer   *ICEGatherer
 	iceTransport  *ICETransport
 	dtlsTransport *DTLSTransport
 	sctpTransport *SCTPTransport
 
+	// rtcpBatcher is nil unless SettingEngine.SetRTCPBatchInterval was used
+	rtcpBatcher *rtcpBatcher
+
+	// networkMonitor is nil unless SettingEngine.SetNetworkMonitorInterval
+	// was used
+	networkMonitor *networkMonitor
+
+	// mediaEngine holds the codecs of the connection, the ones of the API or,
+	// with PopulateMediaEngineFromRemoteOffer, the ones of the first remote
+	// offer
+	mediaEngine *MediaEngine
+
 	// A reference to the associated API state used by this connection
 	api *API
 	log logging.LeveledLogger
@@ -92,6 +133,7 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	// allow better readability to understand what is happening.
 	pc := &PeerConnection{
 		statsID: fmt.Sprintf("PeerConnection-%d", time.Now().UnixNano()),
+		cname:   util.MathRandAlpha(cnameLength),
 		ops:     newOperations(),
 		configuration: Configuration{
 			ICEServers:           []ICEServer{},
@@ -102,8 +144,10 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 			ICECandidatePoolSize: 0,
 		},
 		isClosed:                     &atomicBool{},
+		closed:                       make(chan interface{}),
 		negotiationNeeded:            false,
 		nonTrickleCandidatesSignaled: &atomicBool{},
+		iceRestartNeeded:             &atomicBool{},
 		lastOffer:                    "",
 		lastAnswer:                   "",
 		greaterMid:                   -1,
@@ -111,6 +155,8 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		iceConnectionState:           ICEConnectionStateNew,
 		connectionState:              PeerConnectionStateNew,
 
+		mediaEngine: api.mediaEngine,
+
 		api: api,
 		log: api.settingEngine.LoggerFactory.NewLogger("pc"),
 	}
@@ -142,6 +188,19 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	}
 	pc.dtlsTransport = dtlsTransport
 
+	// The DTLSTransport fires with its lock held, which updateConnectionState
+	// needs to read the state. The hook leaves OnStateChange to the user.
+	pc.dtlsTransport.onStateChangeHook = func(DTLSTransportState) {
+		go pc.updateConnectionState()
+	}
+
+	if interval := pc.api.settingEngine.rtcp.batchInterval; interval > 0 {
+		pc.rtcpBatcher = newRTCPBatcher(interval, util.RandUint32(), pc.writeRTCP, pc.log)
+	}
+	if interval := pc.api.settingEngine.networkMonitorInterval; interval > 0 {
+		pc.networkMonitor = newNetworkMonitor(interval, pc.localAddresses, pc.networkChanged)
+	}
+
 	// Create the SCTP transport
 	pc.sctpTransport = pc.api.NewSCTPTransport(pc.dtlsTransport)
 
@@ -195,6 +254,7 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 
 	if configuration.RTCPMuxPolicy != RTCPMuxPolicy(Unknown) {
 		pc.configuration.RTCPMuxPolicy = configuration.RTCPMuxPolicy
+		pc.rtcpMuxRequired = configuration.RTCPMuxPolicy == RTCPMuxPolicyRequire
 	}
 
 	if configuration.ICECandidatePoolSize != 0 {
@@ -212,7 +272,7 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 	sanitizedICEServers := configuration.getICEServers()
 	if len(sanitizedICEServers) > 0 {
 		for _, server := range sanitizedICEServers {
-			if err := server.validate(); err != nil {
+			if err := server.validate(pc.api.settingEngine.iceCredentialProvider); err != nil {
 				return err
 			}
 		}
@@ -236,6 +296,12 @@ func (pc *PeerConnection) onSignalingStateChange(newState SignalingState) {
 	pc.mu.RUnlock()
 
 	pc.log.Infof("signaling state changed to %s", newState)
+	switch newState {
+	case SignalingStateHaveLocalOffer, SignalingStateHaveRemoteOffer:
+		pc.api.hooks.emit(Event{Type: EventNegotiationStarted, PeerConnection: pc})
+	case SignalingStateStable:
+		pc.api.hooks.emit(Event{Type: EventNegotiationCompleted, PeerConnection: pc})
+	}
 	if hdlr != nil {
 		go hdlr(newState)
 	}
@@ -264,7 +330,8 @@ func (pc *PeerConnection) OnICEGatheringStateChange(f func(ICEGathererState)) {
 }
 
 // OnTrack sets an event handler which is called when remote track
-// arrives from a remote peer.
+// arrives from a remote peer. The Track carries what the remote signaled
+// for it, see Track.StreamIDs, Track.RID and Track.Codec.
 func (pc *PeerConnection) OnTrack(f func(*Track, *RTPReceiver)) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
@@ -282,6 +349,50 @@ func (pc *PeerConnection) onTrack(t *Track, r *RTPReceiver) {
 	}
 }
 
+// OnUnhandledRTP sets an event handler which is called with the RTP packets
+// of the streams no RTPReceiver took: the remote didn't signal their SSRC
+// and they couldn't be routed to a media section. It lets an SFU bind them
+// late, or log them, instead of having them dropped. The packets of a stream
+// are delivered in order from a goroutine of its own, starting with the
+// first one. Streams are only delivered if the handler was set when they
+// arrived.
+func (pc *PeerConnection) OnUnhandledRTP(f func(*rtp.Packet)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onUnhandledRTPHandler = f
+}
+
+func (pc *PeerConnection) unhandledRTPHandler() func(*rtp.Packet) {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return pc.onUnhandledRTPHandler
+}
+
+// readUnhandledRTP delivers the packets of stream to the OnUnhandledRTP
+// handler until it is closed, first is the packet already read from it
+func (pc *PeerConnection) readUnhandledRTP(stream rtp.ReadStream, first *rtp.Packet) {
+	if first != nil {
+		if hdlr := pc.unhandledRTPHandler(); hdlr != nil {
+			hdlr(first)
+		}
+	}
+
+	for {
+		b := make([]byte, receiveMTU)
+		n, err := stream.Read(b)
+		if err != nil {
+			return
+		}
+		p := &rtp.Packet{}
+		if err := p.Unmarshal(b[:n]); err != nil {
+			continue
+		}
+		if hdlr := pc.unhandledRTPHandler(); hdlr != nil {
+			hdlr(p)
+		}
+	}
+}
+
 // OnICEConnectionStateChange sets an event handler which is called
 // when an ICE connection state is changed.
 func (pc *PeerConnection) OnICEConnectionStateChange(f func(ICEConnectionState)) {
@@ -310,6 +421,137 @@ func (pc *PeerConnection) OnConnectionStateChange(f func(PeerConnectionState)) {
 	pc.onConnectionStateChangeHandler = f
 }
 
+// OnICERestartComplete sets an event handler which is called once an ICE
+// restart has finished and all traffic has moved to the new candidate pair.
+func (pc *PeerConnection) OnICERestartComplete(f func()) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onICERestartCompleteHandler = f
+}
+
+func (pc *PeerConnection) onICERestartComplete() {
+	pc.mu.RLock()
+	hdlr := pc.onICERestartCompleteHandler
+	pc.mu.RUnlock()
+
+	pc.log.Info("ICE restart complete")
+	if hdlr != nil {
+		go hdlr()
+	}
+}
+
+// OnNegotiationNeeded sets an event handler which is invoked when a change has
+// occurred which requires session negotiation, like adding a Track or the first
+// DataChannel. The handler is expected to start a new offer/answer exchange.
+func (pc *PeerConnection) OnNegotiationNeeded(f func()) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onNegotiationNeededHandler = f
+}
+
+// onNegotiationNeeded updates the negotiation-needed flag, the check is queued
+// on the operations chain so it runs after any in-flight descriptions are applied.
+// https://w3c.github.io/webrtc-pc/#updating-the-negotiation-needed-flag
+func (pc *PeerConnection) onNegotiationNeeded() {
+	if pc.isClosed.get() {
+		return
+	}
+	pc.ops.Enqueue(pc.negotiationNeededOp)
+}
+
+func (pc *PeerConnection) negotiationNeededOp() {
+	if pc.isClosed.get() || pc.SignalingState() != SignalingStateStable {
+		return
+	}
+
+	isNeeded := pc.checkNegotiationNeeded()
+
+	pc.mu.Lock()
+	if !isNeeded || pc.negotiationNeeded {
+		pc.negotiationNeeded = isNeeded
+		pc.mu.Unlock()
+		return
+	}
+	pc.negotiationNeeded = true
+	hdlr := pc.onNegotiationNeededHandler
+	pc.mu.Unlock()
+
+	pc.log.Info("negotiation needed")
+	if hdlr != nil {
+		go hdlr()
+	}
+}
+
+// checkNegotiationNeeded compares the transceivers and DataChannels with the
+// current local description.
+// https://w3c.github.io/webrtc-pc/#dfn-check-if-negotiation-is-needed
+func (pc *PeerConnection) checkNegotiationNeeded() bool {
+	pc.mu.RLock()
+	localDesc := pc.currentLocalDescription
+	transceivers := append([]*RTPTransceiver{}, pc.rtpTransceivers...)
+	pc.mu.RUnlock()
+
+	var dataChannelsRequested uint32
+	if pc.sctpTransport != nil {
+		pc.sctpTransport.lock.RLock()
+		dataChannelsRequested = pc.sctpTransport.dataChannelsRequested
+		pc.sctpTransport.lock.RUnlock()
+	}
+
+	if pc.iceRestartNeeded.get() {
+		return true
+	} else if localDesc == nil || localDesc.parsed == nil {
+		return len(transceivers) != 0 || dataChannelsRequested != 0
+	} else if descriptionIsPlanB(localDesc) {
+		// Plan-B media sections can't be mapped to a single transceiver
+		return false
+	}
+
+	remoteDirections := map[string]RTPTransceiverDirection{}
+	if remoteDesc := pc.CurrentRemoteDescription(); remoteDesc != nil && remoteDesc.parsed != nil {
+		for _, media := range remoteDesc.parsed.MediaDescriptions {
+			remoteDirections[getMidValue(media)] = getPeerDirection(media)
+		}
+	}
+
+	mediaSections := map[string]*sdp.MediaDescription{}
+	haveApplication := false
+	for _, media := range localDesc.parsed.MediaDescriptions {
+		if media.MediaName.Media == mediaSectionApplication {
+			haveApplication = true
+			continue
+		}
+		mediaSections[getMidValue(media)] = media
+	}
+
+	if dataChannelsRequested != 0 && !haveApplication {
+		return true
+	}
+
+	for _, t := range transceivers {
+		media, ok := mediaSections[t.Mid()]
+		if t.stopped.get() {
+			// A stopped transceiver has to reject its media section
+			if t.Mid() != "" && ok && !isMediaSectionRejected(media) {
+				return true
+			}
+			continue
+		} else if t.Mid() == "" || !ok {
+			return true
+		}
+
+		expectedDirection := t.Direction()
+		if localDesc.Type == SDPTypeAnswer {
+			expectedDirection = expectedDirection.intersect(remoteDirections[t.Mid()].reverse())
+		}
+		if getPeerDirection(media) != expectedDirection {
+			return true
+		}
+	}
+
+	return false
+}
+
 // SetConfiguration updates the configuration of this PeerConnection object.
 func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-setconfiguration (step #2)
@@ -373,7 +615,7 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 	if len(configuration.ICEServers) > 0 {
 		// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.3)
 		for _, server := range configuration.ICEServers {
-			if err := server.validate(); err != nil {
+			if err := server.validate(pc.api.settingEngine.iceCredentialProvider); err != nil {
 				return err
 			}
 		}
@@ -401,17 +643,25 @@ func (pc *PeerConnection) getStatsID() string {
 func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription, error) {
 	useIdentity := pc.idpLoginURL != nil
 	switch {
-	case options != nil:
-		return SessionDescription{}, fmt.Errorf("TODO handle options")
 	case useIdentity:
-		return SessionDescription{}, fmt.Errorf("TODO handle identity provider")
+		return SessionDescription{}, ErrIdentityProviderNotSupported
 	case pc.isClosed.get():
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	// https://www.w3.org/TR/webrtc/#dom-rtcofferoptions-icerestart
+	// RestartICE is only done once an offer is created
+	restartNeeded := pc.iceRestartNeeded.get()
+	iceRestart := restartNeeded || (options != nil && options.ICERestart)
+	if iceRestart && !pc.iceTransport.restartPending() {
+		if err := pc.restartICE(); err != nil {
+			return SessionDescription{}, err
+		}
+	}
+
 	isPlanB := pc.configuration.SDPSemantics == SDPSemanticsPlanB
 	if pc.currentRemoteDescription != nil {
-		isPlanB = descriptionIsPlanB(pc.RemoteDescription())
+		isPlanB = pc.remoteIsPlanB()
 	}
 
 	// include unmatched local transceivers
@@ -433,7 +683,7 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 			}
 		}
 		for _, t := range pc.GetTransceivers() {
-			if t.Mid() != "" {
+			if t.Mid() != "" || t.stopped.get() {
 				continue
 			}
 			pc.greaterMid++
@@ -452,12 +702,18 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 	if pc.currentRemoteDescription == nil {
 		d, err = pc.generateUnmatchedSDP(useIdentity)
 	} else {
-		d, err = pc.generateMatchedSDP(useIdentity, true /*includeUnmatched */, connectionRoleFromDtlsRole(defaultDtlsRoleOffer))
+		d, err = pc.generateMatchedSDP(useIdentity, true /*includeUnmatched */, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), nil)
 	}
 	if err != nil {
 		return SessionDescription{}, err
 	}
 
+	if transform := pc.api.settingEngine.sdpTransformers.local; transform != nil {
+		if err = transform(SDPTypeOffer, d); err != nil {
+			return SessionDescription{}, err
+		}
+	}
+
 	sdpBytes, err := d.Marshal()
 	if err != nil {
 		return SessionDescription{}, err
@@ -469,9 +725,33 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		parsed: d,
 	}
 	pc.lastOffer = desc.SDP
+	if restartNeeded {
+		pc.iceRestartNeeded.set(false)
+	}
 	return desc, nil
 }
 
+// RestartICE makes the next offer restart ICE, as if it was created with
+// OfferOptions.ICERestart, and fires OnNegotiationNeeded
+// https://w3c.github.io/webrtc-pc/#dom-rtcpeerconnection-restartice
+func (pc *PeerConnection) RestartICE() {
+	pc.iceRestartNeeded.set(true)
+	pc.onNegotiationNeeded()
+}
+
+// restartICE generates new ICE credentials and starts gathering a new set of
+// candidates. The current connection stays in use until the remote answers
+// with its own new credentials.
+func (pc *PeerConnection) restartICE() error {
+	if err := pc.iceTransport.restart(); err != nil {
+		return err
+	}
+
+	// Candidates of the new generation have to be signaled again
+	pc.nonTrickleCandidatesSignaled.set(false)
+	return nil
+}
+
 func (pc *PeerConnection) createICEGatherer() (*ICEGatherer, error) {
 	g, err := pc.api.NewICEGatherer(ICEGatherOptions{
 		ICEServers:      pc.configuration.getICEServers(),
@@ -486,10 +766,13 @@ func (pc *PeerConnection) createICEGatherer() (*ICEGatherer, error) {
 
 // Update the PeerConnectionState given the state of relevant transports
 // https://www.w3.org/TR/webrtc/#rtcpeerconnectionstate-enum
-func (pc *PeerConnection) updateConnectionState(iceConnectionState ICEConnectionState, dtlsTransportState DTLSTransportState) {
+func (pc *PeerConnection) updateConnectionState() {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
+	iceConnectionState := pc.iceConnectionState
+	dtlsTransportState := pc.dtlsTransport.State()
+
 	connectionState := PeerConnectionStateNew
 	switch {
 	// The RTCPeerConnection object's [[IsClosed]] slot is true.
@@ -500,20 +783,21 @@ func (pc *PeerConnection) updateConnectionState(iceConnectionState ICEConnection
 	case iceConnectionState == ICEConnectionStateFailed || dtlsTransportState == DTLSTransportStateFailed:
 		connectionState = PeerConnectionStateFailed
 
+	// Any of the RTCIceTransports or RTCDtlsTransports are in the "connecting" or
+	// "checking" state and none of them is in the "failed" state.
+	case iceConnectionState == ICEConnectionStateChecking || dtlsTransportState == DTLSTransportStateConnecting:
+		connectionState = PeerConnectionStateConnecting
+
 	// Any of the RTCIceTransports or RTCDtlsTransports are in the "disconnected"
-	// state and none of them are in the "failed" or "connecting" or "checking" state.  */
+	// state and none of them are in the "failed" or "connecting" or "checking" state.
 	case iceConnectionState == ICEConnectionStateDisconnected:
 		connectionState = PeerConnectionStateDisconnected
 
 	// All RTCIceTransports and RTCDtlsTransports are in the "connected", "completed" or "closed"
 	// state and at least one of them is in the "connected" or "completed" state.
-	case iceConnectionState == ICEConnectionStateConnected && dtlsTransportState == DTLSTransportStateConnected:
+	case (iceConnectionState == ICEConnectionStateConnected || iceConnectionState == ICEConnectionStateCompleted) &&
+		dtlsTransportState == DTLSTransportStateConnected:
 		connectionState = PeerConnectionStateConnected
-
-	//  Any of the RTCIceTransports or RTCDtlsTransports are in the "connecting" or
-	// "checking" state and none of them is in the "failed" state.
-	case iceConnectionState == ICEConnectionStateChecking && dtlsTransportState == DTLSTransportStateConnecting:
-		connectionState = PeerConnectionStateConnecting
 	}
 
 	if pc.connectionState == connectionState {
@@ -530,6 +814,9 @@ func (pc *PeerConnection) updateConnectionState(iceConnectionState ICEConnection
 
 func (pc *PeerConnection) createICETransport() *ICETransport {
 	t := pc.api.NewICETransport(pc.iceGatherer)
+	t.onSelectedCandidatePairChangeHook = func(pair *ICECandidatePair) {
+		pc.api.hooks.emit(Event{Type: EventICECandidatePairSelected, PeerConnection: pc, CandidatePair: pair})
+	}
 	t.OnConnectionStateChange(func(state ICETransportState) {
 		var cs ICEConnectionState
 		switch state {
@@ -552,7 +839,7 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 			return
 		}
 		pc.onICEConnectionStateChange(cs)
-		pc.updateConnectionState(cs, pc.dtlsTransport.State())
+		pc.updateConnectionState()
 	})
 
 	return t
@@ -562,12 +849,10 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescription, error) {
 	useIdentity := pc.idpLoginURL != nil
 	switch {
-	case options != nil:
-		return SessionDescription{}, fmt.Errorf("TODO handle options")
 	case pc.RemoteDescription() == nil:
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
 	case useIdentity:
-		return SessionDescription{}, fmt.Errorf("TODO handle identity provider")
+		return SessionDescription{}, ErrIdentityProviderNotSupported
 	case pc.isClosed.get():
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
@@ -577,11 +862,24 @@ func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescripti
 		connectionRole = connectionRoleFromDtlsRole(defaultDtlsRoleAnswer)
 	}
 
-	d, err := pc.generateMatchedSDP(useIdentity, false /*includeUnmatched */, connectionRole)
+	rejectedMids := map[string]bool{}
+	if options != nil {
+		for _, mid := range options.RejectedMids {
+			rejectedMids[mid] = true
+		}
+	}
+
+	d, err := pc.generateMatchedSDP(useIdentity, false /*includeUnmatched */, connectionRole, rejectedMids)
 	if err != nil {
 		return SessionDescription{}, err
 	}
 
+	if transform := pc.api.settingEngine.sdpTransformers.local; transform != nil {
+		if err = transform(SDPTypeAnswer, d); err != nil {
+			return SessionDescription{}, err
+		}
+	}
+
 	sdpBytes, err := d.Marshal()
 	if err != nil {
 		return SessionDescription{}, err
@@ -602,7 +900,7 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 	case pc.isClosed.get():
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	case newSDPType(sd.Type.String()) == SDPType(Unknown):
-		return &rtcerr.TypeError{Err: fmt.Errorf("the provided value '%d' is not a valid enum value of type SDPType", sd.Type)}
+		return &rtcerr.TypeError{Err: errorWithDetail(ErrInvalidSDPType, fmt.Sprintf("the provided value '%d' is not a valid enum value of type SDPType", sd.Type))}
 	}
 
 	nextState, err := func() (SignalingState, error) {
@@ -612,8 +910,8 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 		cur := pc.signalingState
 		setLocal := stateChangeOpSetLocal
 		setRemote := stateChangeOpSetRemote
-		newSDPDoesNotMatchOffer := &rtcerr.InvalidModificationError{Err: fmt.Errorf("new sdp does not match previous offer")}
-		newSDPDoesNotMatchAnswer := &rtcerr.InvalidModificationError{Err: fmt.Errorf("new sdp does not match previous answer")}
+		newSDPDoesNotMatchOffer := &rtcerr.InvalidModificationError{Err: ErrSDPDoesNotMatchOffer}
+		newSDPDoesNotMatchAnswer := &rtcerr.InvalidModificationError{Err: ErrSDPDoesNotMatchAnswer}
 
 		var nextState SignalingState
 		var err error
@@ -657,14 +955,20 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 					pc.pendingLocalDescription = sd
 				}
 			default:
-				return nextState, &rtcerr.OperationError{Err: fmt.Errorf("invalid state change op: %s(%s)", op, sd.Type)}
+				return nextState, &rtcerr.OperationError{Err: errorWithDetail(ErrInvalidStateChange, fmt.Sprintf("%s(%s)", op, sd.Type))}
 			}
 		case setRemote:
 			switch sd.Type {
 			// stable->SetRemote(offer)->have-remote-offer
+			// have-local-offer->SetRemote(offer)->have-remote-offer, the
+			// colliding offer implicitly rolls back our own
 			case SDPTypeOffer:
+				if cur == SignalingStateHaveLocalOffer {
+					cur = SignalingStateStable
+				}
 				nextState, err = checkNextSignalingState(cur, SignalingStateHaveRemoteOffer, setRemote, sd.Type)
 				if err == nil {
+					pc.pendingLocalDescription = nil
 					pc.pendingRemoteDescription = sd
 				}
 			// have-local-offer->SetRemote(answer)->stable
@@ -689,18 +993,34 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 					pc.pendingRemoteDescription = sd
 				}
 			default:
-				return nextState, &rtcerr.OperationError{Err: fmt.Errorf("invalid state change op: %s(%s)", op, sd.Type)}
+				return nextState, &rtcerr.OperationError{Err: errorWithDetail(ErrInvalidStateChange, fmt.Sprintf("%s(%s)", op, sd.Type))}
 			}
 		default:
-			return nextState, &rtcerr.OperationError{Err: fmt.Errorf("unhandled state change op: %q", op)}
+			return nextState, &rtcerr.OperationError{Err: errorWithDetail(ErrInvalidStateChange, fmt.Sprintf("unhandled %q", op))}
 		}
 
-		return nextState, nil
+		// Other invalid transitions keep the current state without failing,
+		// like they always did, only a rollback needs a pending offer
+		if sd.Type != SDPTypeRollback {
+			return nextState, nil
+		}
+		return nextState, err
 	}()
 
 	if err == nil {
+		pc.mu.Lock()
 		pc.signalingState = nextState
+		pc.mu.Unlock()
 		pc.onSignalingStateChange(nextState)
+
+		// A finished negotiation resets the flag, so the event fires again
+		// for changes that weren't part of it
+		if nextState == SignalingStateStable {
+			pc.mu.Lock()
+			pc.negotiationNeeded = false
+			pc.mu.Unlock()
+			pc.onNegotiationNeeded()
+		}
 	}
 	return err
 }
@@ -711,7 +1031,12 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	if desc.Type == SDPTypeRollback {
+		return pc.rollback(stateChangeOpSetLocal)
+	}
+
 	haveLocalDescription := pc.currentLocalDescription != nil
+	pc.log.Debugf("setting local description: %s", desc.Type)
 
 	// JSEP 5.4
 	if desc.SDP == "" {
@@ -722,7 +1047,7 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 			desc.SDP = pc.lastOffer
 		default:
 			return &rtcerr.InvalidModificationError{
-				Err: fmt.Errorf("invalid SDP type supplied to SetLocalDescription(): %s", desc.Type),
+				Err: errorWithDetail(ErrInvalidSDPType, fmt.Sprintf("%s supplied to SetLocalDescription()", desc.Type)),
 			}
 		}
 	}
@@ -738,6 +1063,11 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 	weAnswer := desc.Type == SDPTypeAnswer
 	remoteDesc := pc.RemoteDescription()
 	if weAnswer && remoteDesc != nil {
+		if !pc.remoteIsPlanB() {
+			if err := pc.stopRejectedTransceivers(desc.parsed); err != nil {
+				return err
+			}
+		}
 		pc.ops.Enqueue(func() {
 			pc.startRTP(haveLocalDescription, remoteDesc)
 		})
@@ -761,6 +1091,78 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 	return nil
 }
 
+// stopRejectedTransceivers stops the transceivers of the media sections desc
+// rejected, so they neither send nor receive on them
+func (pc *PeerConnection) stopRejectedTransceivers(desc *sdp.SessionDescription) error {
+	for _, media := range desc.MediaDescriptions {
+		midValue := getMidValue(media)
+		if midValue == "" || media.MediaName.Media == mediaSectionApplication || !isMediaSectionRejected(media) {
+			continue
+		}
+
+		if t, _ := findByMid(midValue, append([]*RTPTransceiver{}, pc.GetTransceivers()...)); t != nil && !t.stopped.get() {
+			if err := t.Stop(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rollback discards the pending description and undoes the changes it made to
+// the transceivers, see JSEP 4.1.8.2. Transceivers that were not part of the
+// last negotiation lose their mid and the ones only created for the remote
+// offer are removed. An ICE restart the offer started is undone, the previous
+// credentials stay in use.
+func (pc *PeerConnection) rollback(op stateChangeOp) error {
+	if err := pc.setDescription(&SessionDescription{Type: SDPTypeRollback}, op); err != nil {
+		return err
+	}
+	return pc.undoPendingDescription()
+}
+
+// undoPendingDescription undoes the changes to the transceivers and the ICE
+// restart of a pending description that has been discarded
+func (pc *PeerConnection) undoPendingDescription() error {
+	if err := pc.iceTransport.cancelRestart(); err != nil {
+		return err
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	negotiatedMids := map[string]bool{}
+	if pc.currentLocalDescription != nil && pc.currentLocalDescription.parsed != nil {
+		for _, media := range pc.currentLocalDescription.parsed.MediaDescriptions {
+			negotiatedMids[getMidValue(media)] = true
+		}
+	}
+
+	transceivers := []*RTPTransceiver{}
+	for _, t := range pc.rtpTransceivers {
+		if t.Mid() == "" || negotiatedMids[t.Mid()] {
+			transceivers = append(transceivers, t)
+			continue
+		}
+
+		if t.createdByRemote && t.Sender() == nil {
+			if err := t.Stop(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		t.clearMid()
+		if t.Sender() != nil {
+			t.Sender().clearNegotiated()
+		}
+		transceivers = append(transceivers, t)
+	}
+	pc.rtpTransceivers = transceivers
+
+	return nil
+}
+
 // LocalDescription returns PendingLocalDescription if it is not null and
 // otherwise it returns CurrentLocalDescription. This property is used to
 // determine if SetLocalDescription has already been called.
@@ -772,33 +1174,106 @@ func (pc *PeerConnection) LocalDescription() *SessionDescription {
 	return pc.CurrentLocalDescription()
 }
 
+// LocalDescriptionContext waits until ICE gathering is complete, so the
+// returned LocalDescription contains every local candidate, or until ctx is
+// done. It is meant to be called after SetLocalDescription, when signaling
+// doesn't support trickle ICE.
+func (pc *PeerConnection) LocalDescriptionContext(ctx context.Context) (*SessionDescription, error) {
+	for pc.ICEGatheringState() != ICEGatheringStateComplete {
+		if pc.isClosed.get() {
+			return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(gatheringPollInterval):
+		}
+	}
+	return pc.LocalDescription(), nil
+}
+
 // SetRemoteDescription sets the SessionDescription of the remote peer
 func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	if pc.isClosed.get() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
-	haveRemoteDescription := pc.currentRemoteDescription != nil
+	if desc.Type == SDPTypeRollback {
+		return pc.rollback(stateChangeOpSetRemote)
+	}
+
+	prevRemoteDescription := pc.currentRemoteDescription
+	haveRemoteDescription := prevRemoteDescription != nil
+	pc.log.Debugf("setting remote description: %s", desc.Type)
+	pc.log.Tracef("remote description SDP:\n%s", desc.SDP)
 
 	desc.parsed = &sdp.SessionDescription{}
 	if err := desc.parsed.Unmarshal([]byte(desc.SDP)); err != nil {
 		return err
 	}
+	if transform := pc.api.settingEngine.sdpTransformers.remote; transform != nil {
+		if err := transformDescription(&desc, transform); err != nil {
+			return err
+		}
+	}
+
+	var mediaEngine *MediaEngine
+	if desc.Type == SDPTypeOffer && !haveRemoteDescription && pc.api.settingEngine.populateMediaEngineFromRemoteOffer {
+		// The transceivers that exist have been created with the codecs of
+		// the API
+		if len(pc.GetTransceivers()) != 0 {
+			return &rtcerr.InvalidStateError{Err: ErrMediaEngineInUse}
+		}
+		mediaEngine = &MediaEngine{}
+		if err := mediaEngine.PopulateFromSDP(desc); err != nil {
+			return err
+		}
+	}
+
+	// No separate RTCP transport is allocated, RTCP is always multiplexed on
+	// the RTP transport
+	if !haveRTCPMux(desc.parsed) {
+		if pc.rtcpMuxRequired {
+			return &rtcerr.InvalidAccessError{Err: ErrSessionDescriptionMissingRTCPMux}
+		}
+		pc.log.Warnf("Remote description doesn't multiplex RTCP, only the RTCP it sends on the RTP transport is received")
+	}
+
+	// A colliding remote offer implicitly rolls back our own, like browsers
+	// do, once it has been applied
+	rollingBack := desc.Type == SDPTypeOffer && pc.SignalingState() == SignalingStateHaveLocalOffer
 	if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
 		return err
 	}
+	if rollingBack {
+		if err := pc.undoPendingDescription(); err != nil {
+			return err
+		}
+	}
+
+	if pc.rtcpBatcher != nil {
+		pc.rtcpBatcher.reducedSize.set(haveRTCPReducedSize(desc.parsed))
+	}
+
+	if mediaEngine != nil {
+		// Nothing has been started yet, so the offered codecs can replace ours
+		pc.mu.Lock()
+		pc.mediaEngine = mediaEngine
+		pc.mu.Unlock()
+	}
 
 	weOffer := desc.Type == SDPTypeAnswer
 
 	var t *RTPTransceiver
 	localTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
-	detectedPlanB := descriptionIsPlanB(pc.RemoteDescription())
+	detectedPlanB := pc.remoteIsPlanB()
 
 	if !weOffer && !detectedPlanB {
 		for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
 			midValue := getMidValue(media)
 			if midValue == "" {
-				return fmt.Errorf("RemoteDescription contained media section without mid value")
+				return ErrMediaSectionMissingMid
 			}
 
 			if media.MediaName.Media == mediaSectionApplication {
@@ -816,11 +1291,12 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 				t, localTransceivers = satisfyTypeAndDirection(kind, direction, localTransceivers)
 			}
 			if t == nil {
-				receiver, err := pc.api.NewRTPReceiver(kind, pc.dtlsTransport)
+				receiver, err := pc.newRTPReceiver(kind)
 				if err != nil {
 					return err
 				}
 				t = pc.newRTPTransceiver(receiver, nil, RTPTransceiverDirectionRecvonly, kind)
+				t.createdByRemote = true
 			}
 			if t.Mid() == "" {
 				_ = t.setMid(midValue)
@@ -828,7 +1304,53 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		}
 	}
 
+	if !detectedPlanB {
+		// The transceivers of media sections the remote rejected are stopped
+		if err := pc.stopRejectedTransceivers(pc.RemoteDescription().parsed); err != nil {
+			return err
+		}
+	}
+
+	pc.applyRemoteBandwidthLimits(desc.parsed, detectedPlanB)
+
 	if haveRemoteDescription {
+		remoteUfrag, remotePwd, candidates, err := extractICEDetails(desc.parsed)
+		if err != nil {
+			return err
+		}
+		prevUfrag, prevPwd, _, err := extractICEDetails(prevRemoteDescription.parsed)
+		if err != nil {
+			return err
+		}
+
+		// A change of credentials means the remote has restarted ICE (RFC 8445 S9).
+		// An answer can only carry new credentials if we asked for them.
+		iceRestart := remoteUfrag != prevUfrag || remotePwd != prevPwd
+		if iceRestart && !weOffer && !pc.iceTransport.restartPending() {
+			if err = pc.restartICE(); err != nil {
+				return err
+			}
+		}
+
+		if iceRestart {
+			for _, c := range candidates {
+				if err = pc.iceTransport.AddRemoteCandidate(c); err != nil {
+					return err
+				}
+			}
+
+			pc.ops.Enqueue(func() {
+				if err := pc.iceTransport.completeRestart(ICEParameters{
+					UsernameFragment: remoteUfrag,
+					Password:         remotePwd,
+				}); err != nil {
+					pc.log.Warnf("Failed to restart ICE: %s", err)
+					return
+				}
+				pc.onICERestartComplete()
+			})
+		}
+
 		if weOffer {
 			pc.ops.Enqueue(func() {
 				pc.startRTP(true, &desc)
@@ -842,7 +1364,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		remoteIsLite = true
 	}
 
-	fingerprint, fingerprintHash, err := extractFingerprint(desc.parsed)
+	fingerprints, err := extractFingerprints(desc.parsed)
 	if err != nil {
 		return err
 	}
@@ -869,7 +1391,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	// Start the networking in a new routine since it will block until
 	// the connection is actually established.
 	pc.ops.Enqueue(func() {
-		pc.startTransports(iceRole, dtlsRoleFromRemoteSDP(desc.parsed), remoteUfrag, remotePwd, fingerprint, fingerprintHash)
+		pc.startTransports(iceRole, dtlsRoleFromRemoteSDP(desc.parsed), remoteUfrag, remotePwd, fingerprints)
 		if weOffer {
 			pc.startRTP(false, &desc)
 		}
@@ -877,6 +1399,50 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	return nil
 }
 
+// applyRemoteBandwidthLimits limits the RTPSenders to the bandwidth the
+// remote description allows their media sections. A limit of the whole
+// session applies to every RTPSender on its own.
+func (pc *PeerConnection) applyRemoteBandwidthLimits(desc *sdp.SessionDescription, isPlanB bool) {
+	sessionLimit := extractBandwidthLimit(desc.Bandwidth)
+	limits := map[string]uint64{}
+	for _, media := range desc.MediaDescriptions {
+		key := getMidValue(media)
+		if isPlanB {
+			key = media.MediaName.Media
+		}
+		limits[key] = minBandwidthLimit(sessionLimit, extractBandwidthLimit(media.Bandwidth))
+	}
+
+	for _, t := range pc.GetTransceivers() {
+		key := t.Mid()
+		if isPlanB {
+			key = t.kind.String()
+		}
+		limit, ok := limits[key]
+		if !ok {
+			limit = sessionLimit
+		}
+		t.setRemoteMaxBitrate(limit)
+	}
+}
+
+// SetMaxBitrate limits the bandwidth the remote may send the whole session
+// with, in bits per second. The limit is advertised from the next offer or
+// answer on, 0 removes it. RTPTransceiver.SetMaxBitrate limits a single
+// media section.
+func (pc *PeerConnection) SetMaxBitrate(bitrate uint64) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.maxBitrate = bitrate
+}
+
+// MaxBitrate returns the limit set with SetMaxBitrate
+func (pc *PeerConnection) MaxBitrate() uint64 {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return pc.maxBitrate
+}
+
 func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPReceiver) {
 	err := receiver.Receive(RTPReceiveParameters{
 		Encodings: RTPDecodingParameters{
@@ -890,8 +1456,7 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 	// set track id and label early so they can be set as new track information
 	// is received from the SDP.
 	receiver.Track().mu.Lock()
-	receiver.Track().id = incoming.id
-	receiver.Track().label = incoming.label
+	receiver.Track().setDetails(incoming)
 	receiver.Track().mu.Unlock()
 
 	go func() {
@@ -899,11 +1464,12 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 			pc.log.Warnf("Could not determine PayloadType for SSRC %d", receiver.Track().SSRC())
 			return
 		}
+		pc.api.hooks.emit(Event{Type: EventFirstMediaPacket, PeerConnection: pc, Track: receiver.Track()})
 
 		pc.mu.RLock()
 		defer pc.mu.RUnlock()
 
-		codec, err := pc.api.mediaEngine.getCodec(receiver.Track().PayloadType())
+		codec, err := pc.mediaEngine.getCodec(receiver.Track().PayloadType())
 		if err != nil {
 			pc.log.Warnf("no codec could be found for payloadType %d", receiver.Track().PayloadType())
 			return
@@ -914,6 +1480,7 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 		receiver.Track().codec = codec
 		receiver.Track().mu.Unlock()
 
+		pc.api.hooks.emit(Event{Type: EventTrackAdded, PeerConnection: pc, Track: receiver.Track()})
 		if pc.onTrackHandler != nil {
 			pc.onTrack(receiver.Track(), receiver)
 		} else {
@@ -944,6 +1511,11 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks map[uint32]trackDetai
 			delete(incomingTracks, ssrc)
 		}
 	}
+	for _, r := range pc.getStreamReceivers() {
+		if r.Track() != nil {
+			delete(incomingTracks, r.Track().SSRC())
+		}
+	}
 
 	for ssrc, incoming := range incomingTracks {
 		for i := range localTransceivers {
@@ -978,6 +1550,81 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks map[uint32]trackDetai
 			}
 			pc.startReceiver(incoming, t.Receiver())
 		}
+		return
+	}
+
+	// A unified-plan media section can still carry several streams, like the
+	// ones of older SFUs. The streams after the first get receivers of their
+	// own, that aren't part of a transceiver.
+	for ssrc, incoming := range incomingTracks {
+		t, _ := findByMid(incoming.mid, append([]*RTPTransceiver{}, currentTransceivers...))
+		if t == nil || t.kind != incoming.kind || t.Receiver() == nil ||
+			(t.Direction() != RTPTransceiverDirectionRecvonly && t.Direction() != RTPTransceiverDirectionSendrecv) {
+			continue
+		}
+
+		receiver, err := pc.newRTPReceiver(incoming.kind)
+		if err != nil {
+			pc.log.Warnf("Could not add receiver for remote SSRC %d: %s", ssrc, err)
+			continue
+		}
+		pc.mu.Lock()
+		pc.streamReceivers = append(pc.streamReceivers, receiver)
+		pc.mu.Unlock()
+		pc.startReceiver(incoming, receiver)
+	}
+}
+
+// remoteIsPlanB reports if the remote description is negotiated as Plan-B.
+// With SDPSemanticsUnifiedPlan only sections named like Plan-B are, a
+// numbered section with several streams is received as unified-plan with a
+// receiver for each stream, see startRTPReceivers.
+func (pc *PeerConnection) remoteIsPlanB() bool {
+	if pc.configuration.SDPSemantics == SDPSemanticsUnifiedPlan {
+		return descriptionHasPlanBMids(pc.RemoteDescription())
+	}
+	return descriptionIsPlanB(pc.RemoteDescription())
+}
+
+func (pc *PeerConnection) getStreamReceivers() []*RTPReceiver {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return append([]*RTPReceiver{}, pc.streamReceivers...)
+}
+
+// stopStreamReceivers stops the receivers of the additional streams whose
+// SSRC isn't in incomingTracks anymore, and updates the details of the others
+func (pc *PeerConnection) stopStreamReceivers(incomingTracks map[uint32]trackDetails) {
+	pc.mu.Lock()
+	receivers := pc.streamReceivers
+	pc.streamReceivers = nil
+	stopped := []*RTPReceiver{}
+	for _, r := range receivers {
+		track := r.Track()
+		if track == nil {
+			continue
+		}
+
+		track.mu.Lock()
+		incoming, ok := incomingTracks[track.ssrc]
+		if ok {
+			track.setDetails(incoming)
+		}
+		track.mu.Unlock()
+
+		if ok {
+			pc.streamReceivers = append(pc.streamReceivers, r)
+		} else {
+			stopped = append(stopped, r)
+		}
+	}
+	pc.mu.Unlock()
+
+	for _, r := range stopped {
+		if err := r.Stop(); err != nil {
+			pc.log.Warnf("Failed to stop RtpReceiver: %s", err)
+		}
+		pc.api.hooks.emit(Event{Type: EventTrackRemoved, PeerConnection: pc, Track: r.Track()})
 	}
 }
 
@@ -985,7 +1632,8 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks map[uint32]trackDetai
 func (pc *PeerConnection) startRTPSenders(currentTransceivers []*RTPTransceiver) {
 	for _, transceiver := range currentTransceivers {
 		// TODO(sgotti) when in future we'll avoid replacing a transceiver sender just check the transceiver negotiation status
-		if transceiver.Sender() != nil && transceiver.Sender().isNegotiated() && !transceiver.Sender().hasSent() {
+		if !transceiver.stopped.get() && transceiver.Sender() != nil && transceiver.Sender().isNegotiated() && !transceiver.Sender().hasSent() {
+			pc.setSenderHeaderExtensions(transceiver)
 			err := transceiver.Sender().Send(RTPSendParameters{
 				Encodings: RTPEncodingParameters{
 					RTPCodingParameters{
@@ -1000,11 +1648,40 @@ func (pc *PeerConnection) startRTPSenders(currentTransceivers []*RTPTransceiver)
 	}
 }
 
-// Start SCTP subsystem
-func (pc *PeerConnection) startSCTP() {
+// setSenderHeaderExtensions makes the sender of transceiver send its MID, RID,
+// video orientation, playout delay and capture time in the header extensions
+// the remote negotiated for its media section, and gives it the codecs of the
+// section
+func (pc *PeerConnection) setSenderHeaderExtensions(transceiver *RTPTransceiver) {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return
+	}
+
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if getMidValue(media) != transceiver.Mid() {
+			continue
+		}
+		extensions := headerExtensionParametersFromMedia(media)
+		sender := transceiver.Sender()
+		sender.setHeaderExtensions(transceiver.Mid(), sender.encodingRID(),
+			headerExtensionID(extensions, sdesMidURI), headerExtensionID(extensions, sdesRTPStreamIDURI))
+		sender.setCaptureTimeExtensionID(headerExtensionID(extensions, absCaptureTimeURI))
+		sender.setRemoteCodecs(codecParametersFromMedia(transceiver.kind, media))
+		if transceiver.kind == RTPCodecTypeVideo {
+			sender.setVideoOrientationExtensionID(headerExtensionID(extensions, videoOrientationURI))
+			sender.setPlayoutDelayExtensionID(headerExtensionID(extensions, playoutDelayURI))
+		}
+		return
+	}
+}
+
+// Start SCTP subsystem, remoteMaxMessageSize is the max-message-size of the
+// remote description
+func (pc *PeerConnection) startSCTP(remoteMaxMessageSize uint32) {
 	// Start sctp
 	if err := pc.sctpTransport.Start(SCTPCapabilities{
-		MaxMessageSize: 0,
+		MaxMessageSize: remoteMaxMessageSize,
 	}); err != nil {
 		pc.log.Warnf("Failed to start SCTP: %s", err)
 		if err = pc.sctpTransport.Stop(); err != nil {
@@ -1037,42 +1714,110 @@ func (pc *PeerConnection) startSCTP() {
 	pc.sctpTransport.lock.Unlock()
 }
 
-// drainSRTP pulls and discards RTP/RTCP packets that don't match any a:ssrc lines
-// If the remote SDP was only one media section the ssrc doesn't have to be explicitly declared
-func (pc *PeerConnection) drainSRTP() {
-	handleUndeclaredSSRC := func(ssrc uint32) bool {
-		if remoteDescription := pc.RemoteDescription(); remoteDescription != nil {
-			if len(remoteDescription.parsed.MediaDescriptions) == 1 {
-				onlyMediaSection := remoteDescription.parsed.MediaDescriptions[0]
-				for _, a := range onlyMediaSection.Attributes {
-					if a.Key == ssrcStr {
-						return false
-					}
-				}
+// handleUndeclaredSSRC starts a receiver for a stream whose SSRC the remote
+// didn't signal. If the remote SDP was only one media section the stream is
+// its Track, otherwise its first packet is read to route it to a media
+// section by its MID header extension or payload type, and is the first one
+// its Track reads. RTX repair flows have no Track of their own. The first
+// packet is returned when it was read for a stream that wasn't handled.
+func (pc *PeerConnection) handleUndeclaredSSRC(stream rtp.ReadStream, ssrc uint32) (bool, *rtp.Packet) {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil {
+		return false, nil
+	}
+
+	if len(remoteDescription.parsed.MediaDescriptions) == 1 {
+		pc.undeclaredSSRCMu.Lock()
+		defer pc.undeclaredSSRCMu.Unlock()
+
+		onlyMediaSection := remoteDescription.parsed.MediaDescriptions[0]
+		for _, a := range onlyMediaSection.Attributes {
+			if a.Key == ssrcStr {
+				return false, nil
+			}
+		}
 
-				incoming := trackDetails{
-					ssrc: ssrc,
-					kind: RTPCodecTypeVideo,
-				}
-				if onlyMediaSection.MediaName.Media == RTPCodecTypeAudio.String() {
-					incoming.kind = RTPCodecTypeAudio
-				}
+		incoming := trackDetails{
+			ssrc: ssrc,
+			kind: RTPCodecTypeVideo,
+		}
+		if onlyMediaSection.MediaName.Media == RTPCodecTypeAudio.String() {
+			incoming.kind = RTPCodecTypeAudio
+		}
 
-				t, err := pc.AddTransceiverFromKind(incoming.kind, RtpTransceiverInit{
-					Direction: RTPTransceiverDirectionSendrecv,
-				})
-				if err != nil {
-					pc.log.Warnf("Could not add transceiver for remote SSRC %d: %s", ssrc, err)
-					return false
-				}
-				pc.startReceiver(incoming, t.Receiver())
-				return true
-			}
+		t, err := pc.AddTransceiverFromKind(incoming.kind, RtpTransceiverInit{
+			Direction: RTPTransceiverDirectionSendrecv,
+		})
+		if err != nil {
+			pc.log.Warnf("Could not add transceiver for remote SSRC %d: %s", ssrc, err)
+			return false, nil
 		}
+		pc.startReceiver(incoming, t.Receiver())
+		return true, nil
+	}
 
-		return false
+	b := make([]byte, receiveMTU)
+	n, err := stream.Read(b)
+	if err != nil {
+		return false, nil
+	}
+	first := &rtp.Packet{}
+	if err = first.Unmarshal(b[:n]); err != nil {
+		return false, nil
+	}
+
+	media := mediaSectionForPacket(remoteDescription.parsed, &first.Header)
+	if media == nil {
+		return false, first
+	}
+	incoming := trackDetails{
+		mid:              getMidValue(media),
+		kind:             NewRTPCodecType(media.MediaName.Media),
+		ssrc:             ssrc,
+		codecs:           codecParametersFromMedia(NewRTPCodecType(media.MediaName.Media), media),
+		headerExtensions: headerExtensionParametersFromMedia(media),
+	}
+	for _, c := range incoming.codecs {
+		if c.PayloadType == first.PayloadType && strings.HasSuffix(strings.ToLower(c.MimeType), "/rtx") {
+			pc.log.Debugf("Ignoring RTX repair flow ssrc(%d) of mid %s", ssrc, incoming.mid)
+			return true, nil
+		}
+	}
+
+	pc.undeclaredSSRCMu.Lock()
+	defer pc.undeclaredSSRCMu.Unlock()
+	for _, t := range pc.GetTransceivers() {
+		if t.Mid() != incoming.mid || t.kind != incoming.kind ||
+			(t.Direction() != RTPTransceiverDirectionRecvonly && t.Direction() != RTPTransceiverDirectionSendrecv) ||
+			t.Receiver() == nil || t.Receiver().haveReceived() {
+			continue
+		}
+
+		t.Receiver().unreadRTP(b[:n])
+		pc.startReceiver(incoming, t.Receiver())
+		return true, nil
+	}
+	return false, first
+}
+
+// routeUndeclaredSSRC hands a stream whose SSRC the remote didn't signal to
+// a receiver or to the OnUnhandledRTP handler. It runs in its own goroutine,
+// the first packet of the stream may take a while.
+func (pc *PeerConnection) routeUndeclaredSSRC(stream rtp.ReadStream, ssrc uint32) {
+	handled, first := pc.handleUndeclaredSSRC(stream, ssrc)
+	if handled {
+		return
 	}
+	if pc.unhandledRTPHandler() != nil {
+		pc.readUnhandledRTP(stream, first)
+		return
+	}
+	pc.log.Warnf("Incoming unhandled RTP ssrc(%d), OnTrack will not be fired", ssrc)
+}
 
+// drainSRTP pulls and discards RTP/RTCP packets that don't match any a:ssrc lines,
+// see handleUndeclaredSSRC for the streams that are still received
+func (pc *PeerConnection) drainSRTP() {
 	go func() {
 		for {
 			rtpSession, err := pc.dtlsTransport.RTPSession()
@@ -1081,15 +1826,13 @@ func (pc *PeerConnection) drainSRTP() {
 				return
 			}
 
-			_, ssrc, err := rtpSession.AcceptStream()
+			stream, ssrc, err := rtpSession.AcceptStream()
 			if err != nil {
 				pc.log.Warnf("Failed to accept RTP %v", err)
 				return
 			}
 
-			if !handleUndeclaredSSRC(ssrc) {
-				pc.log.Warnf("Incoming unhandled RTP ssrc(%d), OnTrack will not be fired", ssrc)
-			}
+			go pc.routeUndeclaredSSRC(stream, ssrc)
 		}
 	}()
 
@@ -1126,12 +1869,32 @@ func (pc *PeerConnection) RemoteDescription() *SessionDescription {
 }
 
 // AddICECandidate accepts an ICE candidate string and adds it
-// to the existing set of candidates
+// to the existing set of candidates. Candidates may keep arriving after
+// SetRemoteDescription for as long as the remote is gathering. A candidate
+// with an empty Candidate string signals the end of remote candidates.
 func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
-	if pc.RemoteDescription() == nil {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil {
 		return &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
 	}
 
+	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-addicecandidate (step #4.3)
+	if candidate.UsernameFragment != "" {
+		remoteUfrag, _, _, err := extractICEDetails(remoteDescription.parsed)
+		if err != nil {
+			return err
+		}
+		if candidate.UsernameFragment != remoteUfrag {
+			return &rtcerr.OperationError{Err: ErrICECandidateUfragMismatch}
+		}
+	}
+
+	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-addicecandidate (step #4.4)
+	if candidate.Candidate == "" {
+		pc.log.Debug("remote signaled end-of-candidates")
+		return nil
+	}
+
 	candidateValue := strings.TrimPrefix(candidate.Candidate, "candidate:")
 	attribute := sdp.NewAttribute("candidate", candidateValue)
 	sdpCandidate, err := attribute.ToICECandidate()
@@ -1144,6 +1907,7 @@ func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
 		return err
 	}
 
+	pc.log.Debugf("adding remote ICE candidate: %s", iceCandidate)
 	return pc.iceTransport.AddRemoteCandidate(iceCandidate)
 }
 
@@ -1181,7 +1945,7 @@ func (pc *PeerConnection) GetReceivers() []*RTPReceiver {
 			result = append(result, transceiver.Receiver())
 		}
 	}
-	return result
+	return append(result, pc.streamReceivers...)
 }
 
 // GetTransceivers returns the RTCRtpTransceiver that are currently attached to this RTCPeerConnection
@@ -1200,7 +1964,7 @@ func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
 
 	var transceiver *RTPTransceiver
 	for _, t := range pc.GetTransceivers() {
-		if !t.stopped && t.kind == track.Kind() && t.Sender() == nil {
+		if !t.stopped.get() && t.kind == track.Kind() && t.Sender() == nil {
 			transceiver = t
 			break
 		}
@@ -1215,6 +1979,8 @@ func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
 		if err := transceiver.setSendingTrack(track); err != nil {
 			return nil, err
 		}
+		pc.api.hooks.emit(Event{Type: EventTrackAdded, PeerConnection: pc, Track: track})
+		pc.onNegotiationNeeded()
 		return sender, nil
 	}
 
@@ -1223,6 +1989,7 @@ func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
 		return nil, err
 	}
 
+	pc.api.hooks.emit(Event{Type: EventTrackAdded, PeerConnection: pc, Track: track})
 	return transceiver.Sender(), nil
 }
 
@@ -1248,14 +2015,24 @@ func (pc *PeerConnection) RemoveTrack(sender *RTPSender) error {
 
 	if transceiver == nil {
 		return &rtcerr.InvalidAccessError{Err: ErrSenderNotCreatedByConnection}
-	} else if err := sender.Stop(); err != nil {
+	}
+	track := sender.Track()
+	if err := sender.Stop(); err != nil {
 		return err
 	}
 
-	return transceiver.setSendingTrack(nil)
+	if err := transceiver.setSendingTrack(nil); err != nil {
+		return err
+	}
+	pc.api.hooks.emit(Event{Type: EventTrackRemoved, PeerConnection: pc, Track: track})
+
+	pc.onNegotiationNeeded()
+	return nil
 }
 
-// AddTransceiverFromKind Create a new RTCRtpTransceiver(SendRecv or RecvOnly) and add it to the set of transceivers.
+// AddTransceiverFromKind Create a new RTCRtpTransceiver and add it to the set of transceivers.
+// A sending direction creates a Track for the transceiver, recvonly and inactive
+// transceivers only receive once negotiated.
 func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpTransceiverInit) (*RTPTransceiver, error) {
 	if pc.isClosed.get() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
@@ -1263,16 +2040,16 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 
 	direction := RTPTransceiverDirectionSendrecv
 	if len(init) > 1 {
-		return nil, fmt.Errorf("AddTransceiverFromKind only accepts one RtpTransceiverInit")
+		return nil, ErrOneTransceiverInit
 	} else if len(init) == 1 {
 		direction = init[0].Direction
 	}
 
 	switch direction {
-	case RTPTransceiverDirectionSendrecv:
-		codecs := pc.api.mediaEngine.GetCodecsByKind(kind)
+	case RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly:
+		codecs := pc.getMediaEngine().GetCodecsByKind(kind)
 		if len(codecs) == 0 {
-			return nil, fmt.Errorf("no %s codecs found", kind.String())
+			return nil, errorWithDetail(ErrCodecNotFound, fmt.Sprintf("no %s codecs", kind.String()))
 		}
 
 		track, err := pc.NewTrack(codecs[0].PayloadType, util.RandUint32(), util.MathRandAlpha(trackDefaultIDLength), util.MathRandAlpha(trackDefaultLabelLength))
@@ -1282,70 +2059,69 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 
 		return pc.AddTransceiverFromTrack(track, init...)
 
-	case RTPTransceiverDirectionRecvonly:
-		receiver, err := pc.api.NewRTPReceiver(kind, pc.dtlsTransport)
+	case RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionInactive:
+		receiver, err := pc.newRTPReceiver(kind)
 		if err != nil {
 			return nil, err
 		}
 
-		return pc.newRTPTransceiver(
+		t := pc.newRTPTransceiver(
 			receiver,
 			nil,
-			RTPTransceiverDirectionRecvonly,
+			direction,
 			kind,
-		), nil
+		)
+		pc.onNegotiationNeeded()
+		return t, nil
 	default:
-		return nil, fmt.Errorf("AddTransceiverFromKind currently only supports recvonly and sendrecv")
+		return nil, errorWithDetail(ErrTransceiverDirectionNotSupported, direction.String())
 	}
 }
 
-// AddTransceiverFromTrack Creates a new send only transceiver and add it to the set of
+// AddTransceiverFromTrack Creates a new sendrecv or sendonly transceiver and add it to the set of
+// transceivers. The SendEncodings of the RtpTransceiverInit describe the Track, a single encoding is
+// sent and simulcast is rejected with ErrRTPEncodingSimulcast.
 func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTransceiverInit) (*RTPTransceiver, error) {
 	if pc.isClosed.get() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
 	direction := RTPTransceiverDirectionSendrecv
+	var encodings []RTPEncodingParameters
 	if len(init) > 1 {
-		return nil, fmt.Errorf("AddTransceiverFromTrack only accepts one RtpTransceiverInit")
+		return nil, ErrOneTransceiverInit
 	} else if len(init) == 1 {
 		direction = init[0].Direction
+		encodings = init[0].SendEncodings
 	}
 
+	var receiver *RTPReceiver
 	switch direction {
 	case RTPTransceiverDirectionSendrecv:
-		receiver, err := pc.api.NewRTPReceiver(track.Kind(), pc.dtlsTransport)
-		if err != nil {
-			return nil, err
-		}
-
-		sender, err := pc.api.NewRTPSender(track, pc.dtlsTransport)
-		if err != nil {
+		var err error
+		if receiver, err = pc.newRTPReceiver(track.Kind()); err != nil {
 			return nil, err
 		}
-
-		return pc.newRTPTransceiver(
-			receiver,
-			sender,
-			RTPTransceiverDirectionSendrecv,
-			track.Kind(),
-		), nil
-
 	case RTPTransceiverDirectionSendonly:
-		sender, err := pc.api.NewRTPSender(track, pc.dtlsTransport)
-		if err != nil {
-			return nil, err
-		}
-
-		return pc.newRTPTransceiver(
-			nil,
-			sender,
-			RTPTransceiverDirectionSendonly,
-			track.Kind(),
-		), nil
 	default:
-		return nil, fmt.Errorf("AddTransceiverFromTrack currently only supports sendonly and sendrecv")
+		return nil, errorWithDetail(ErrTransceiverDirectionNotSupported, direction.String())
+	}
+
+	sender, err := pc.api.NewRTPSender(track, pc.dtlsTransport)
+	if err != nil {
+		return nil, err
+	} else if err = sender.setEncodings(encodings); err != nil {
+		return nil, err
 	}
+
+	t := pc.newRTPTransceiver(
+		receiver,
+		sender,
+		direction,
+		track.Kind(),
+	)
+	pc.onNegotiationNeeded()
+	return t, nil
 }
 
 // CreateDataChannel creates a new DataChannel object with the given label
@@ -1414,8 +2190,14 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 	pc.sctpTransport.lock.Lock()
 	pc.sctpTransport.dataChannels = append(pc.sctpTransport.dataChannels, d)
 	pc.sctpTransport.dataChannelsRequested++
+	isFirstDataChannel := pc.sctpTransport.dataChannelsRequested == 1
 	pc.sctpTransport.lock.Unlock()
 
+	// Only the first DataChannel needs an application media section
+	if isFirstDataChannel {
+		pc.onNegotiationNeeded()
+	}
+
 	// If SCTP already connected open all the channels
 	if pc.sctpTransport.State() == SCTPTransportStateConnected {
 		if err = d.open(pc.sctpTransport); err != nil {
@@ -1428,17 +2210,38 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 
 // SetIdentityProvider is used to configure an identity provider to generate identity assertions
 func (pc *PeerConnection) SetIdentityProvider(provider string) error {
-	return fmt.Errorf("TODO SetIdentityProvider")
+	return ErrIdentityProviderNotSupported
 }
 
 // WriteRTCP sends a user provided RTCP packet to the connected peer
-// If no peer is connected the packet is discarded
+// If no peer is connected the packet is discarded. With
+// SettingEngine.SetRTCPBatchInterval the packets are queued and sent with
+// the next batch.
 func (pc *PeerConnection) WriteRTCP(pkts []rtcp.Packet) error {
+	if pc.rtcpBatcher != nil {
+		return pc.rtcpBatcher.enqueue(pkts)
+	}
+
 	raw, err := rtcp.Marshal(pkts)
 	if err != nil {
 		return err
 	}
+	return pc.writeRTCP(raw)
+}
 
+// WriteApplicationData sends data to the remote as raw DTLS application data,
+// see DTLSTransport.WriteApplicationData
+func (pc *PeerConnection) WriteApplicationData(data []byte) (int, error) {
+	return pc.dtlsTransport.WriteApplicationData(data)
+}
+
+// OnApplicationData sets a handler called with the raw DTLS application data
+// the remote sent, see DTLSTransport.OnApplicationData
+func (pc *PeerConnection) OnApplicationData(f func([]byte)) {
+	pc.dtlsTransport.OnApplicationData(f)
+}
+
+func (pc *PeerConnection) writeRTCP(raw []byte) error {
 	rtcpSession, err := pc.dtlsTransport.RTCPSession()
 	if err != nil {
 		return nil
@@ -1446,7 +2249,7 @@ func (pc *PeerConnection) WriteRTCP(pkts []rtcp.Packet) error {
 
 	writeStream, err := rtcpSession.OpenWriteStream()
 	if err != nil {
-		return fmt.Errorf("WriteRTCP failed to open WriteStream: %v", err)
+		return errorWithDetail(ErrRTCPWriteStream, err.Error())
 	}
 
 	if _, err := writeStream.Write(raw); err != nil {
@@ -1455,8 +2258,19 @@ func (pc *PeerConnection) WriteRTCP(pkts []rtcp.Packet) error {
 	return nil
 }
 
-// Close ends the PeerConnection
+// Close ends the PeerConnection right away, data still buffered by the
+// DataChannels is dropped. See CloseWithContext.
 func (pc *PeerConnection) Close() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return pc.CloseWithContext(ctx)
+}
+
+// CloseWithContext ends the PeerConnection in order: it sends an RTCP BYE for
+// the SSRCs being sent, waits until the remote acknowledged the data buffered
+// by the DataChannels or ctx is done, then closes the DTLSTransport with a
+// close_notify alert and releases the sockets of the ICETransport.
+func (pc *PeerConnection) CloseWithContext(ctx context.Context) error {
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #2)
 	if pc.isClosed.get() {
 		return nil
@@ -1466,7 +2280,19 @@ func (pc *PeerConnection) Close() error {
 	pc.isClosed.set(true)
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #4)
+	pc.mu.Lock()
 	pc.signalingState = SignalingStateClosed
+	select {
+	case <-pc.closed:
+	default:
+		close(pc.closed)
+	}
+	pc.mu.Unlock()
+
+	if err := pc.sendGoodbye(); err != nil {
+		pc.log.Warnf("Failed to send RTCP BYE: %v", err)
+	}
+	pc.drainDataChannels(ctx)
 
 	// Try closing everything and collect the errors
 	// Shutdown strategy:
@@ -1476,10 +2302,20 @@ func (pc *PeerConnection) Close() error {
 	//    continue the chain the Mux has to be closed.
 	closeErrs := make([]error, 4)
 
+	if pc.rtcpBatcher != nil {
+		pc.rtcpBatcher.close()
+	}
+	if pc.networkMonitor != nil {
+		pc.networkMonitor.close()
+	}
+
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #5)
 	for _, t := range pc.rtpTransceivers {
 		closeErrs = append(closeErrs, t.Stop())
 	}
+	for _, r := range pc.streamReceivers {
+		closeErrs = append(closeErrs, r.Stop())
+	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #6)
 	if pc.sctpTransport != nil {
@@ -1504,23 +2340,79 @@ func (pc *PeerConnection) Close() error {
 	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #12)
-	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
+	pc.updateConnectionState()
 
 	return util.FlattenErrs(closeErrs)
 }
 
 // NewTrack Creates a new Track
 func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label string) (*Track, error) {
-	codec, err := pc.api.mediaEngine.getCodec(payloadType)
+	codec, err := pc.getMediaEngine().getCodec(payloadType)
 	if err != nil {
 		return nil, err
 	} else if codec.Payloader == nil {
-		return nil, fmt.Errorf("codec payloader not set")
+		return nil, ErrCodecPayloaderNotSet
 	}
 
 	return NewTrack(payloadType, ssrc, id, label, codec)
 }
 
+// sendGoodbye sends an RTCP BYE for every SSRC the RTPSenders have sent
+// on, RFC 3550 6.6
+func (pc *PeerConnection) sendGoodbye() error {
+	var ssrcs []uint32
+	seen := map[uint32]bool{}
+	for _, t := range pc.GetTransceivers() {
+		sender := t.Sender()
+		if sender == nil || !sender.hasSent() {
+			continue
+		}
+
+		candidates := []uint32{}
+		if track := sender.Track(); track != nil {
+			candidates = append(candidates, track.SSRC())
+		}
+		for _, encoding := range sender.Encodings() {
+			candidates = append(candidates, encoding.SSRC)
+		}
+		for _, ssrc := range candidates {
+			if ssrc != 0 && !seen[ssrc] {
+				seen[ssrc] = true
+				ssrcs = append(ssrcs, ssrc)
+			}
+		}
+	}
+	if len(ssrcs) == 0 {
+		return nil
+	}
+
+	// A BYE is always sent as a compound packet behind a report
+	raw, err := rtcp.Marshal([]rtcp.Packet{
+		&rtcp.ReceiverReport{SSRC: ssrcs[0]},
+		&rtcp.Goodbye{Sources: ssrcs},
+	})
+	if err != nil {
+		return err
+	}
+	return pc.writeRTCP(raw)
+}
+
+// drainDataChannels waits until the remote acknowledged everything the
+// DataChannels buffered, or ctx is done
+func (pc *PeerConnection) drainDataChannels(ctx context.Context) {
+	if pc.sctpTransport == nil {
+		return
+	}
+
+	for pc.sctpTransport.bufferedAmount() != 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(closeDrainInterval):
+		}
+	}
+}
+
 func (pc *PeerConnection) newRTPTransceiver(
 	receiver *RTPReceiver,
 	sender *RTPSender,
@@ -1574,6 +2466,8 @@ func (pc *PeerConnection) PendingRemoteDescription() *SessionDescription {
 // SignalingState attribute returns the signaling state of the
 // PeerConnection instance.
 func (pc *PeerConnection) SignalingState() SignalingState {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
 	return pc.signalingState
 }
 
@@ -1649,15 +2543,17 @@ func (pc *PeerConnection) GetStats() StatsReport {
 		DataChannelsClosed:    dataChannelsClosed,
 		DataChannelsOpened:    dataChannelsOpened,
 		DataChannelsRequested: dataChannelsRequested,
+		CNAME:                 pc.cname,
 	}
 	pc.mu.Unlock()
 
+	pc.collectLatencyStats(statsCollector)
 	statsCollector.Collect(stats.ID, stats)
 	return statsCollector.Ready()
 }
 
 // Start all transports. PeerConnection now has enough state
-func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, remoteUfrag, remotePwd, fingerprint, fingerprintHash string) {
+func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, remoteUfrag, remotePwd string, fingerprints []DTLSFingerprint) {
 	// Start the ice transport
 	err := pc.iceTransport.Start(
 		pc.iceGatherer,
@@ -1674,15 +2570,17 @@ func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, re
 	}
 
 	// Start the dtls transport
+	handshakeStart := time.Now()
 	err = pc.dtlsTransport.Start(DTLSParameters{
 		Role:         dtlsRole,
-		Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
+		Fingerprints: fingerprints,
 	})
-	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
+	pc.updateConnectionState()
 	if err != nil {
 		pc.log.Warnf("Failed to start manager: %s", err)
 		return
 	}
+	pc.api.hooks.emit(Event{Type: EventDTLSHandshakeCompleted, PeerConnection: pc, Duration: time.Since(handshakeStart)})
 }
 
 func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDescription) {
@@ -1697,9 +2595,7 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 			t.Receiver().Track().mu.Lock()
 			ssrc := t.Receiver().Track().ssrc
 			if _, ok := trackDetails[ssrc]; ok {
-				incoming := trackDetails[ssrc]
-				t.Receiver().Track().id = incoming.id
-				t.Receiver().Track().label = incoming.label
+				t.Receiver().Track().setDetails(trackDetails[ssrc])
 				t.Receiver().Track().mu.Unlock()
 				continue
 			}
@@ -1709,14 +2605,16 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 				pc.log.Warnf("Failed to stop RtpReceiver: %s", err)
 				continue
 			}
+			pc.api.hooks.emit(Event{Type: EventTrackRemoved, PeerConnection: pc, Track: t.Receiver().Track()})
 
-			receiver, err := pc.api.NewRTPReceiver(t.Receiver().kind, pc.dtlsTransport)
+			receiver, err := pc.newRTPReceiver(t.Receiver().kind)
 			if err != nil {
 				pc.log.Warnf("Failed to create new RtpReceiver: %s", err)
 				continue
 			}
 			t.setReceiver(receiver)
 		}
+		pc.stopStreamReceivers(trackDetails)
 	}
 
 	pc.startRTPReceivers(trackDetails, currentTransceivers)
@@ -1724,15 +2622,34 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 
 	if !isRenegotiation {
 		pc.drainSRTP()
-		if haveApplicationMediaSection(remoteDesc.parsed) {
-			pc.startSCTP()
+		go pc.sendSourceDescriptions()
+		localDesc := pc.LocalDescription()
+		if haveApplicationMediaSection(remoteDesc.parsed) && (localDesc == nil || haveApplicationMediaSection(localDesc.parsed)) {
+			pc.startSCTP(extractMaxMessageSize(remoteDesc.parsed))
 		}
 	}
 }
 
+func (pc *PeerConnection) getMediaEngine() *MediaEngine {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return pc.mediaEngine
+}
+
+// newRTPReceiver creates a RTPReceiver that uses the codecs of the
+// PeerConnection
+func (pc *PeerConnection) newRTPReceiver(kind RTPCodecType) (*RTPReceiver, error) {
+	receiver, err := pc.api.NewRTPReceiver(kind, pc.dtlsTransport)
+	if err != nil {
+		return nil, err
+	}
+	receiver.mediaEngine = pc.getMediaEngine()
+	return receiver, nil
+}
+
 // GetRegisteredRTPCodecs gets a list of registered RTPCodec from the underlying constructed MediaEngine
 func (pc *PeerConnection) GetRegisteredRTPCodecs(kind RTPCodecType) []*RTPCodec {
-	return pc.api.mediaEngine.GetCodecsByKind(kind)
+	return pc.getMediaEngine().GetCodecsByKind(kind)
 }
 
 // generateUnmatchedSDP generates an SDP that doesn't take remote state into account
@@ -1742,8 +2659,11 @@ func (pc *PeerConnection) generateUnmatchedSDP(useIdentity bool) (*sdp.SessionDe
 	if errJSEP != nil {
 		return nil, errJSEP
 	}
+	if bitrate := pc.MaxBitrate(); bitrate != 0 {
+		d.Bandwidth = bandwidthLimit(bitrate)
+	}
 
-	if err := addFingerprints(d, pc.configuration.Certificates[0]); err != nil {
+	if err := addFingerprints(d, pc.configuration.Certificates); err != nil {
 		return nil, err
 	}
 
@@ -1781,30 +2701,44 @@ func (pc *PeerConnection) generateUnmatchedSDP(useIdentity bool) (*sdp.SessionDe
 		if len(audio) > 0 {
 			mediaSections = append(mediaSections, mediaSection{id: "audio", transceivers: audio})
 		}
-		mediaSections = append(mediaSections, mediaSection{id: "data", data: true})
+		mediaSections = append(mediaSections, mediaSection{id: "data", data: true, maxMessageSize: pc.api.settingEngine.sctp.maxMessageSize})
 	} else {
 		for _, t := range pc.GetTransceivers() {
+			if t.stopped.get() && t.Mid() == "" {
+				continue
+			}
 			if t.Sender() != nil {
 				t.Sender().setNegotiated()
 			}
-			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}})
+			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}, headerExtensions: defaultHeaderExtensions(t.kind)})
 		}
 
-		mediaSections = append(mediaSections, mediaSection{id: strconv.Itoa(len(mediaSections)), data: true})
+		mediaSections = append(mediaSections, mediaSection{id: strconv.Itoa(len(mediaSections)), data: true, maxMessageSize: pc.api.settingEngine.sctp.maxMessageSize})
 	}
 
-	return populateSDP(d, isPlanB, pc.api.settingEngine.candidates.ICELite, pc.api.mediaEngine, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	// Only the initial offer marks sections, JSEP 5.2.1
+	applyBundlePolicy(mediaSections, pc.configuration.BundlePolicy)
+	for i := range mediaSections {
+		mediaSections[i].rtcpMuxOnly = pc.rtcpMuxRequired
+	}
+
+	return populateSDP(d, isPlanB, pc.api.settingEngine.candidates.ICELite, pc.getMediaEngine(), pc.cname, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState())
 }
 
 // generateMatchedSDP generates a SDP and takes the remote state into account
 // this is used everytime we have a RemoteDescription
-func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched bool, connectionRole sdp.ConnectionRole) (*sdp.SessionDescription, error) {
+// Remote sections that are in rejectedMids, or that can't be negotiated, are
+// rejected in place
+func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched bool, connectionRole sdp.ConnectionRole, rejectedMids map[string]bool) (*sdp.SessionDescription, error) {
 	d, errJSEP := sdp.NewJSEPSessionDescription(useIdentity)
 	if errJSEP != nil {
 		return nil, errJSEP
 	}
+	if bitrate := pc.MaxBitrate(); bitrate != 0 {
+		d.Bandwidth = bandwidthLimit(bitrate)
+	}
 
-	if err := addFingerprints(d, pc.configuration.Certificates[0]); err != nil {
+	if err := addFingerprints(d, pc.configuration.Certificates); err != nil {
 		return nil, err
 	}
 
@@ -1820,23 +2754,29 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 
 	var t *RTPTransceiver
 	localTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
-	detectedPlanB := descriptionIsPlanB(pc.RemoteDescription())
+	detectedPlanB := pc.remoteIsPlanB()
 	mediaSections := []mediaSection{}
 
 	for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
 		midValue := getMidValue(media)
 		if midValue == "" {
-			return nil, fmt.Errorf("RemoteDescription contained media section without mid value")
+			return nil, ErrMediaSectionMissingMid
 		}
 
-		if media.MediaName.Media == mediaSectionApplication {
-			mediaSections = append(mediaSections, mediaSection{id: midValue, data: true})
+		kind := NewRTPCodecType(media.MediaName.Media)
+		direction := getPeerDirection(media)
+		isData := media.MediaName.Media == mediaSectionApplication
+		if rejectedMids[midValue] || !isData && (kind == 0 || direction == RTPTransceiverDirection(Unknown)) {
+			mediaName := media.MediaName
+			mediaSections = append(mediaSections, mediaSection{id: midValue, rejected: &mediaName})
+			if kind != 0 {
+				_, localTransceivers = findByMid(midValue, localTransceivers)
+			}
 			continue
 		}
 
-		kind := NewRTPCodecType(media.MediaName.Media)
-		direction := getPeerDirection(media)
-		if kind == 0 || direction == RTPTransceiverDirection(Unknown) {
+		if isData {
+			mediaSections = append(mediaSections, mediaSection{id: midValue, data: true, maxMessageSize: pc.api.settingEngine.sctp.maxMessageSize})
 			continue
 		}
 
@@ -1873,23 +2813,31 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 			}
 			t, localTransceivers = findByMid(midValue, localTransceivers)
 			if t == nil {
-				return nil, fmt.Errorf("cannot find transceiver with mid %q", midValue)
+				return nil, errorWithDetail(ErrTransceiverNotFound, strconv.Quote(midValue))
 			}
 			if t.Sender() != nil {
 				t.Sender().setNegotiated()
 			}
 			mediaTransceivers := []*RTPTransceiver{t}
-			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers})
+			section := mediaSection{id: midValue, transceivers: mediaTransceivers, headerExtensions: supportedHeaderExtensions(kind, headerExtensionParametersFromMedia(media))}
+			if !includeUnmatched {
+				// We are answering, only accept what the remote offered
+				section.direction = t.Direction().intersect(direction.reverse())
+			}
+			mediaSections = append(mediaSections, section)
 		}
 	}
 
 	// If we are offering also include unmatched local transceivers
 	if !detectedPlanB && includeUnmatched {
 		for _, t := range localTransceivers {
+			if t.stopped.get() && t.Mid() == "" {
+				continue
+			}
 			if t.Sender() != nil {
 				t.Sender().setNegotiated()
 			}
-			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}})
+			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}, headerExtensions: defaultHeaderExtensions(t.kind)})
 		}
 	}
 
@@ -1897,5 +2845,5 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 		pc.log.Info("Plan-B Offer detected; responding with Plan-B Answer")
 	}
 
-	return populateSDP(d, detectedPlanB, pc.api.settingEngine.candidates.ICELite, pc.api.mediaEngine, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	return populateSDP(d, detectedPlanB, pc.api.settingEngine.candidates.ICELite, pc.getMediaEngine(), pc.cname, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState())
 }
@@ -3,9 +3,12 @@
 package webrtc
 
 import (
+	"bytes"
+	"context"
 	"testing"
 	"time"
 
+	"github.com/pion/rtcp"
 	"github.com/pion/transport/test"
 	"github.com/stretchr/testify/assert"
 )
@@ -164,3 +167,77 @@ func TestPeerConnection_Close_DuringICE(t *testing.T) {
 		t.Error("pcOffer.Close() Timeout")
 	}
 }
+
+func TestPeerConnection_CloseWithContext(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// 1 MiB still buffered when CloseWithContext is called
+	message := bytes.Repeat([]byte{0xAB}, 16*1024)
+	const messageCount = 64
+	received := make(chan struct{})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		count := 0
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.Equal(t, message, msg.Data)
+			if count++; count == messageCount {
+				close(received)
+			}
+		})
+	})
+
+	goodbye := make(chan *rtcp.Goodbye, 1)
+	onTrack := make(chan struct{})
+	pcAnswer.OnTrack(func(_ *Track, r *RTPReceiver) {
+		close(onTrack)
+		for {
+			pkts, readErr := r.ReadRTCP()
+			if readErr != nil {
+				return
+			}
+			for _, pkt := range pkts {
+				if bye, ok := pkt.(*rtcp.Goodbye); ok {
+					goodbye <- bye
+					return
+				}
+			}
+		}
+	})
+
+	dc, err := pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(onTrack, t, []*Track{track})
+	<-opened
+
+	for i := 0; i < messageCount; i++ {
+		assert.NoError(t, dc.Send(message))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.NoError(t, pcOffer.CloseWithContext(ctx))
+
+	assert.Equal(t, []uint32{track.SSRC()}, (<-goodbye).Sources)
+	<-received
+
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -12,11 +12,14 @@ import (
 	"math/big"
 	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/pion/ice"
+	"github.com/pion/sdp/v2"
 	"github.com/pion/transport/test"
 	"github.com/pion/webrtc/v2/internal/util"
 	"github.com/pion/webrtc/v2/pkg/rtcerr"
@@ -880,3 +883,651 @@ func TestPopulateLocalCandidates(t *testing.T) {
 		assert.NoError(t, pc.Close())
 	})
 }
+
+func TestPeerConnection_ICERestart(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	messages := make(chan string, 1)
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != expectedLabel {
+			return
+		}
+		d.OnMessage(func(msg DataChannelMessage) {
+			messages <- string(msg.Data)
+		})
+	})
+
+	dc, err := pcOffer.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+
+	dcOpened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(dcOpened)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-dcOpened
+
+	assert.NoError(t, dc.SendText("before restart"))
+	assert.Equal(t, "before restart", <-messages)
+
+	offerRestarted, answerRestarted := make(chan struct{}), make(chan struct{})
+	pcOffer.OnICERestartComplete(func() {
+		close(offerRestarted)
+	})
+	pcAnswer.OnICERestartComplete(func() {
+		close(answerRestarted)
+	})
+
+	offerParams, err := pcOffer.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+	answerParams, err := pcAnswer.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	assert.NoError(t, err)
+
+	offerUfrag, _, _, err := extractICEDetails(offer.parsed)
+	assert.NoError(t, err)
+	assert.NotEqual(t, offerParams.UsernameFragment, offerUfrag)
+
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+
+	answerUfrag, _, _, err := extractICEDetails(answer.parsed)
+	assert.NoError(t, err)
+	assert.NotEqual(t, answerParams.UsernameFragment, answerUfrag)
+
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	<-offerRestarted
+	<-answerRestarted
+
+	// DTLS and SCTP state must have survived the restart
+	assert.NoError(t, dc.SendText("after restart"))
+	assert.Equal(t, "after restart", <-messages)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_AddICECandidate_EndOfCandidates(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetTrickle(true)
+
+	api := NewAPI(WithSettingEngine(s))
+	offerPC, answerPC, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = offerPC.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+
+	offerGatheringDone, answerGatheringDone := make(chan struct{}), make(chan struct{})
+	trickle := func(from, to *PeerConnection, done chan struct{}) {
+		from.OnICECandidate(func(c *ICECandidate) {
+			if c == nil {
+				assert.NoError(t, to.AddICECandidate(ICECandidateInit{}))
+				close(done)
+				return
+			}
+			assert.NoError(t, to.AddICECandidate(c.ToJSON()))
+		})
+	}
+	trickle(offerPC, answerPC, offerGatheringDone)
+	trickle(answerPC, offerPC, answerGatheringDone)
+
+	connected := make(chan struct{})
+	answerPC.OnICEConnectionStateChange(func(s ICEConnectionState) {
+		if s == ICEConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	// Each side applies the remote description before it starts gathering,
+	// so trickled candidates never arrive ahead of it
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, answerPC.SetRemoteDescription(offer))
+	assert.NoError(t, offerPC.SetLocalDescription(offer))
+
+	answer, err := answerPC.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, offerPC.SetRemoteDescription(answer))
+	assert.NoError(t, answerPC.SetLocalDescription(answer))
+
+	<-offerGatheringDone
+	<-answerGatheringDone
+	<-connected
+
+	candidate := ICECandidateInit{
+		Candidate:        "candidate:1 1 udp 2130706431 192.168.1.1 4000 typ host",
+		UsernameFragment: "not-the-remote-ufrag",
+	}
+	err = offerPC.AddICECandidate(candidate)
+	assert.Equal(t, &rtcerr.OperationError{Err: ErrICECandidateUfragMismatch}, err)
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
+func TestPeerConnection_Rollback(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	// Roll back a local offer, the transceiver is no longer associated
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.Equal(t, SignalingStateHaveLocalOffer, pcOffer.SignalingState())
+
+	assert.NoError(t, pcOffer.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.Equal(t, SignalingStateStable, pcOffer.SignalingState())
+	assert.Nil(t, pcOffer.PendingLocalDescription())
+	assert.Equal(t, "", pcOffer.GetTransceivers()[0].Mid())
+
+	// Roll back a remote offer, the transceiver created for it is removed
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	assert.Equal(t, SignalingStateHaveRemoteOffer, pcAnswer.SignalingState())
+	assert.Len(t, pcAnswer.GetTransceivers(), 1)
+
+	assert.NoError(t, pcAnswer.SetRemoteDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.Equal(t, SignalingStateStable, pcAnswer.SignalingState())
+	assert.Nil(t, pcAnswer.PendingRemoteDescription())
+	assert.Len(t, pcAnswer.GetTransceivers(), 0)
+
+	// Rollback is only valid with a pending offer
+	assert.Error(t, pcAnswer.SetRemoteDescription(SessionDescription{Type: SDPTypeRollback}))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// Assert that colliding offers don't wedge the signaling state, the peer
+// receiving an offer while it has one pending rolls its own back
+func TestPeerConnection_Rollback_Glare(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcImpolite, pcPolite, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = pcImpolite.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = pcPolite.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	impoliteOffer, err := pcImpolite.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcImpolite.SetLocalDescription(impoliteOffer))
+
+	politeOffer, err := pcPolite.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcPolite.SetLocalDescription(politeOffer))
+
+	// The impolite peer ignores the colliding offer, the polite one yields
+	assert.NoError(t, pcPolite.SetRemoteDescription(impoliteOffer))
+	assert.Equal(t, SignalingStateHaveRemoteOffer, pcPolite.SignalingState())
+	assert.Nil(t, pcPolite.PendingLocalDescription())
+	assert.Equal(t, impoliteOffer.SDP, pcPolite.PendingRemoteDescription().SDP)
+
+	answer, err := pcPolite.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcPolite.SetLocalDescription(answer))
+	assert.NoError(t, pcImpolite.SetRemoteDescription(answer))
+
+	assert.Equal(t, SignalingStateStable, pcPolite.SignalingState())
+	assert.Equal(t, SignalingStateStable, pcImpolite.SignalingState())
+
+	// The polite peer's transceiver was reused for the remote media section
+	assert.Len(t, pcPolite.GetTransceivers(), 1)
+	assert.Equal(t, pcImpolite.GetTransceivers()[0].Mid(), pcPolite.GetTransceivers()[0].Mid())
+
+	pcImpolite.ops.Done()
+	pcPolite.ops.Done()
+
+	assert.NoError(t, pcImpolite.Close())
+	assert.NoError(t, pcPolite.Close())
+}
+
+// Assert that an invalid colliding offer is rejected before the pending local
+// offer is rolled back
+func TestPeerConnection_Rollback_InvalidOffer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+
+	assert.Error(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: "invalid"}))
+	assert.Equal(t, SignalingStateHaveLocalOffer, pc.SignalingState())
+	assert.Equal(t, offer.SDP, pc.PendingLocalDescription().SDP)
+	assert.Equal(t, "0", pc.GetTransceivers()[0].Mid())
+
+	assert.NoError(t, pc.Close())
+}
+
+// Assert that rolling back an offer restarting ICE keeps the credentials of
+// the connection
+func TestPeerConnection_Rollback_ICERestart(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	connected := make(chan struct{})
+	pcOffer.OnICEConnectionStateChange(func(state ICEConnectionState) {
+		if state == ICEConnectionStateConnected {
+			close(connected)
+		}
+	})
+	_, err = pcOffer.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-connected
+
+	params, err := pcOffer.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.True(t, pcOffer.iceTransport.restartPending())
+
+	assert.NoError(t, pcOffer.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.False(t, pcOffer.iceTransport.restartPending())
+
+	restored, err := pcOffer.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+	assert.Equal(t, params, restored)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_Certificates(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	generated, err := GenerateCertificate(sk)
+	assert.NoError(t, err)
+
+	// Certificates are reused through their PEM encoding
+	pems, err := generated.PEM()
+	assert.NoError(t, err)
+	certificate, err := CertificateFromPEM(pems)
+	assert.NoError(t, err)
+
+	sk384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		NotBefore:          time.Now(),
+		NotAfter:           time.Now().AddDate(0, 1, 0),
+		SignatureAlgorithm: x509.ECDSAWithSHA384,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, sk384.Public(), sk384)
+	assert.NoError(t, err)
+	x509Cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	certificate384 := CertificateFromX509(sk384, x509Cert)
+
+	pcOffer, err := NewPeerConnection(Configuration{Certificates: []Certificate{*certificate, certificate384}})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Certificate{*certificate, certificate384}, pcOffer.dtlsTransport.GetCertificates())
+
+	connected := make(chan struct{})
+	pcAnswer.OnConnectionStateChange(func(s PeerConnectionState) {
+		if s == PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	// Every fingerprint of every certificate is advertised
+	fingerprints, err := extractFingerprints(offer.parsed)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(fingerprints))
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-connected
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_ConnectionStateFailed(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetICETimeouts(time.Second, time.Second, 250*time.Millisecond)
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	states := make(chan PeerConnectionState, 10)
+	pcAnswer.OnConnectionStateChange(func(s PeerConnectionState) {
+		states <- s
+	})
+
+	// A handler of the user on the DTLSTransport doesn't replace the
+	// aggregation of the connection state
+	dtlsConnected := make(chan struct{})
+	pcAnswer.dtlsTransport.OnStateChange(func(s DTLSTransportState) {
+		if s == DTLSTransportStateConnected {
+			close(dtlsConnected)
+		}
+	})
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	waitState := func(expected PeerConnectionState) {
+		for s := range states {
+			if s == expected {
+				return
+			}
+		}
+	}
+	waitState(PeerConnectionStateConnected)
+	<-dtlsConnected
+
+	// Without the offer the ICE connection of the answer times out and
+	// eventually fails
+	assert.NoError(t, pcOffer.Close())
+	waitState(PeerConnectionStateDisconnected)
+	waitState(PeerConnectionStateFailed)
+	assert.Equal(t, PeerConnectionStateFailed, pcAnswer.ConnectionState())
+	assert.Equal(t, ICETransportState(ICETransportStateFailed), pcAnswer.iceTransport.State())
+
+	// Consent to send expired with the failure
+	_, err = pcAnswer.iceTransport.NewEndpoint(func([]byte) bool { return false }).Write([]byte{0x00})
+	assert.Equal(t, ErrICEConsentExpired, err)
+
+	assert.NoError(t, pcAnswer.Close())
+	assert.Equal(t, PeerConnectionStateClosed, pcAnswer.ConnectionState())
+}
+
+func TestPeerConnection_BundlePolicy(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, err := api.NewPeerConnection(Configuration{BundlePolicy: BundlePolicyMaxBundle, RTCPMuxPolicy: RTCPMuxPolicyRequire})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	connected := make(chan struct{})
+	pcAnswer.OnConnectionStateChange(func(s PeerConnectionState) {
+		if s == PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-connected
+
+	// Only the first section of the offer carries the transport
+	for i, m := range pcOffer.currentLocalDescription.parsed.MediaDescriptions {
+		_, isBundleOnly := m.Attribute(sdpAttributeBundleOnly)
+		assert.Equal(t, i > 0, isBundleOnly)
+		assert.Equal(t, i > 0, m.MediaName.Port.Value == 0)
+
+		_, isRTCPMuxOnly := m.Attribute(sdpAttributeRTCPMuxOnly)
+		assert.Equal(t, m.MediaName.Media != mediaSectionApplication, isRTCPMuxOnly)
+
+		_, haveCandidates := m.Attribute("candidate")
+		assert.Equal(t, i == 0, haveCandidates)
+	}
+
+	// The answer accepts every bundled section
+	for _, m := range pcAnswer.currentLocalDescription.parsed.MediaDescriptions {
+		assert.False(t, isMediaSectionRejected(m))
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_AnswerRejectedMids(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	video, err := pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(&AnswerOptions{RejectedMids: []string{"1", "2"}})
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+
+	// The rejected sections keep their place and mid, only the first is bundled
+	media := answer.parsed.MediaDescriptions
+	assert.Equal(t, 3, len(media))
+	for i, kind := range []string{"audio", "video", mediaSectionApplication} {
+		assert.Equal(t, kind, media[i].MediaName.Media)
+		assert.Equal(t, strconv.Itoa(i), getMidValue(media[i]))
+		assert.Equal(t, i > 0, isMediaSectionRejected(media[i]))
+	}
+	assert.Equal(t, media[2].MediaName.Protos, offer.parsed.MediaDescriptions[2].MediaName.Protos)
+	group, _ := answer.parsed.Attribute(sdp.AttrKeyGroup)
+	assert.Equal(t, "BUNDLE 0", group)
+
+	for _, transceiver := range pcAnswer.GetTransceivers() {
+		assert.Equal(t, transceiver.Mid() == "1", transceiver.stopped.get())
+	}
+	assert.False(t, haveApplicationMediaSection(answer.parsed))
+
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+	assert.True(t, video.stopped.get())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_RTCPMuxPolicy(t *testing.T) {
+	offerPC, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = offerPC.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	offer.SDP = strings.Replace(offer.SDP, "a=rtcp-mux\r\n", "", -1)
+
+	// Negotiate and the default policy accept the remote, RTCP stays on the
+	// RTP transport
+	for _, test := range []struct {
+		policy   RTCPMuxPolicy
+		expected error
+	}{
+		{RTCPMuxPolicyRequire, &rtcerr.InvalidAccessError{Err: ErrSessionDescriptionMissingRTCPMux}},
+		{RTCPMuxPolicyNegotiate, nil},
+		{RTCPMuxPolicy(Unknown), nil},
+	} {
+		answerPC, err := NewPeerConnection(Configuration{RTCPMuxPolicy: test.policy})
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, answerPC.SetRemoteDescription(offer))
+		assert.NoError(t, answerPC.Close())
+	}
+
+	assert.NoError(t, offerPC.Close())
+}
+
+func TestPeerConnection_MaxBitrate(t *testing.T) {
+	offerPC, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	answerPC, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := offerPC.NewTrack(DefaultPayloadTypeOpus, 1234, "audio", "pion")
+	assert.NoError(t, err)
+	audio, err := offerPC.AddTransceiverFromTrack(track)
+	assert.NoError(t, err)
+	video, err := offerPC.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	video.SetMaxBitrate(500000)
+	answerPC.SetMaxBitrate(64000)
+
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, offer.SDP, "b=AS:64")
+	assert.Contains(t, offer.SDP, "b=TIAS:500000\r\nb=AS:500\r\n")
+	assert.NoError(t, offerPC.SetLocalDescription(offer))
+	assert.NoError(t, answerPC.SetRemoteDescription(offer))
+
+	answer, err := answerPC.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "b=TIAS:64000\r\nb=AS:64\r\n")
+	assert.NoError(t, answerPC.SetLocalDescription(answer))
+
+	// The limit of the session of the answer caps the sender of the offer
+	assert.Equal(t, uint64(0), audio.Sender().MaxBitrate())
+	assert.NoError(t, offerPC.SetRemoteDescription(answer))
+	assert.Equal(t, uint64(64000), audio.Sender().MaxBitrate())
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
+func TestPeerConnection_LocalDescriptionContext(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	desc, err := pc.LocalDescriptionContext(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, desc.SDP, "a=candidate:")
+	assert.NoError(t, pc.Close())
+
+	// Trickle ICE only gathers once SetLocalDescription is called
+	s := SettingEngine{}
+	s.SetTrickle(true)
+	pc, err = NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pc.LocalDescriptionContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.NoError(t, pc.Close())
+}
+
+// RestartICE is still pending after an offer that couldn't be created
+func TestPeerConnection_RestartICE_OfferFailed(t *testing.T) {
+	errTransform := fmt.Errorf("transform failed")
+	failTransform := true
+	s := SettingEngine{}
+	s.SetLocalSDPTransformer(func(SDPType, *sdp.SessionDescription) error {
+		if failTransform {
+			return errTransform
+		}
+		return nil
+	})
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	pc.RestartICE()
+	_, err = pc.CreateOffer(nil)
+	assert.Equal(t, errTransform, err)
+	assert.True(t, pc.iceRestartNeeded.get())
+
+	failTransform = false
+	_, err = pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.False(t, pc.iceRestartNeeded.get())
+
+	assert.NoError(t, pc.Close())
+}
@@ -25,6 +25,7 @@ type PeerConnection struct {
 	onICEConnectionStateChangeHandler *js.Func
 	onICECandidateHandler             *js.Func
 	onICEGatheringStateChangeHandler  *js.Func
+	onNegotiationNeededHandler        *js.Func
 
 	// A reference to the associated API state used by this connection
 	api *API
@@ -71,6 +72,21 @@ func (pc *PeerConnection) OnSignalingStateChange(f func(SignalingState)) {
 	pc.underlying.Set("onsignalingstatechange", onSignalingStateChangeHandler)
 }
 
+// OnNegotiationNeeded sets an event handler which is invoked when a change has
+// occurred which requires session negotiation
+func (pc *PeerConnection) OnNegotiationNeeded(f func()) {
+	if pc.onNegotiationNeededHandler != nil {
+		oldHandler := pc.onNegotiationNeededHandler
+		defer oldHandler.Release()
+	}
+	onNegotiationNeededHandler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go f()
+		return js.Undefined()
+	})
+	pc.onNegotiationNeededHandler = &onNegotiationNeededHandler
+	pc.underlying.Set("onnegotiationneeded", onNegotiationNeededHandler)
+}
+
 // OnDataChannel sets an event handler which is invoked when a data
 // channel message arrives from a remote peer.
 func (pc *PeerConnection) OnDataChannel(f func(*DataChannel)) {
@@ -391,6 +407,9 @@ func (pc *PeerConnection) Close() (err error) {
 	if pc.onICEGatheringStateChangeHandler != nil {
 		pc.onICEGatheringStateChangeHandler.Release()
 	}
+	if pc.onNegotiationNeededHandler != nil {
+		pc.onNegotiationNeededHandler.Release()
+	}
 
 	return nil
 }
@@ -452,6 +471,81 @@ func (pc *PeerConnection) ConnectionState() PeerConnectionState {
 	return newPeerConnectionState(rawState)
 }
 
+// GetSenders returns the RTPSender that are currently attached to this PeerConnection
+func (pc *PeerConnection) GetSenders() []*RTPSender {
+	senders := pc.underlying.Call("getSenders")
+	result := make([]*RTPSender, senders.Length())
+	for i := range result {
+		result[i] = &RTPSender{underlying: senders.Index(i)}
+	}
+	return result
+}
+
+// GetReceivers returns the RTPReceivers that are currently attached to this RTCPeerConnection
+func (pc *PeerConnection) GetReceivers() []*RTPReceiver {
+	receivers := pc.underlying.Call("getReceivers")
+	result := make([]*RTPReceiver, receivers.Length())
+	for i := range result {
+		result[i] = &RTPReceiver{underlying: receivers.Index(i)}
+	}
+	return result
+}
+
+// GetTransceivers returns the RTCRtpTransceiver that are currently attached to this RTCPeerConnection
+func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
+	transceivers := pc.underlying.Call("getTransceivers")
+	result := make([]*RTPTransceiver, transceivers.Length())
+	for i := range result {
+		result[i] = &RTPTransceiver{underlying: transceivers.Index(i)}
+	}
+	return result
+}
+
+// AddTransceiver Create a new RTCRtpTransceiver and add it to the set of transceivers.
+// Deprecated: Use AddTransceiverFromKind
+func (pc *PeerConnection) AddTransceiver(trackOrKind RTPCodecType, init ...RtpTransceiverInit) (*RTPTransceiver, error) {
+	return pc.AddTransceiverFromKind(trackOrKind, init...)
+}
+
+// AddTransceiverFromKind Create a new RTCRtpTransceiver and add it to the set of transceivers.
+func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpTransceiverInit) (_ *RTPTransceiver, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = recoveryToError(e)
+		}
+	}()
+
+	if len(init) > 1 {
+		return nil, ErrOneTransceiverInit
+	}
+	var initValue js.Value
+	if len(init) == 1 {
+		initValue = rtpTransceiverInitToValue(init[0])
+	} else {
+		initValue = js.Undefined()
+	}
+	transceiver := pc.underlying.Call("addTransceiver", kind.String(), initValue)
+	return &RTPTransceiver{underlying: transceiver}, nil
+}
+
+// GetStats return data providing statistics about the overall connection,
+// as reported by the browser
+func (pc *PeerConnection) GetStats() StatsReport {
+	report := StatsReport{}
+	statsValue, err := awaitPromise(pc.underlying.Call("getStats"))
+	if err != nil {
+		return report
+	}
+
+	forEach := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		report[args[1].String()] = valueToStats(args[0])
+		return js.Undefined()
+	})
+	defer forEach.Release()
+	statsValue.Call("forEach", forEach)
+	return report
+}
+
 // Converts a Configuration to js.Value so it can be passed
 // through to the JavaScript WebRTC API. Any zero values are converted to
 // js.Undefined(), which will result in the default value being used.
@@ -637,3 +731,16 @@ func dataChannelInitToValue(options *DataChannelInit) js.Value {
 		"id":                uint16PointerToValue(options.ID),
 	})
 }
+
+func rtpTransceiverInitToValue(init RtpTransceiverInit) js.Value {
+	encodings := make([]interface{}, len(init.SendEncodings))
+	for i, encoding := range init.SendEncodings {
+		encodings[i] = map[string]interface{}{
+			"rid": stringToValueOrUndefined(encoding.RID),
+		}
+	}
+	return js.ValueOf(map[string]interface{}{
+		"direction":     init.Direction.String(),
+		"sendEncodings": encodings,
+	})
+}
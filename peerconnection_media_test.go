@@ -5,9 +5,12 @@ package webrtc
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math/rand"
+	"net"
 	"reflect"
 	"strings"
 	"sync"
@@ -15,9 +18,12 @@ import (
 	"time"
 
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/sdp/v2"
 	"github.com/pion/transport/test"
 	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/rtpdump"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -541,6 +547,164 @@ func TestUndeclaredSSRC(t *testing.T) {
 	assert.NoError(t, pcAnswer.Close())
 }
 
+// signalPairWithoutSSRCs negotiates with an offer stripped of its SSRC lines,
+// and waits for both PeerConnections to connect
+func signalPairWithoutSSRCs(t *testing.T, pcOffer, pcAnswer *PeerConnection) {
+	connected := make(chan struct{}, 2)
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		pc.OnConnectionStateChange(func(state PeerConnectionState) {
+			if state == PeerConnectionStateConnected {
+				connected <- struct{}{}
+			}
+		})
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+
+	filteredSDP := ""
+	for _, l := range strings.Split(offer.SDP, "\r\n") {
+		if l != "" && !strings.HasPrefix(l, "a=ssrc") {
+			filteredSDP += l + "\r\n"
+		}
+	}
+	offer.SDP = filteredSDP
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	<-connected
+	<-connected
+}
+
+// With several media sections a stream without SSRC is routed by its first
+// packet, which is still the first one its Track reads
+func TestUndeclaredSSRC_Routed(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	vp8Writer, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion2")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(vp8Writer)
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	firstRead := make(chan *rtp.Packet, 1)
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		p, readErr := track.ReadRTP()
+		assert.NoError(t, readErr)
+		firstRead <- p
+	})
+
+	signalPairWithoutSSRCs(t, pcOffer, pcAnswer)
+	for !sender.hasSent() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The payload counts the packets, nothing is written before both ends
+	// can receive
+	var first *rtp.Packet
+	for i := 0; first == nil; i++ {
+		assert.NoError(t, vp8Writer.WriteRTP(&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    DefaultPayloadTypeVP8,
+				SequenceNumber: uint16(i),
+				Timestamp:      uint32(i) * 3000,
+				SSRC:           vp8Writer.SSRC(),
+			},
+			Payload: []byte{byte(i)},
+		}))
+
+		select {
+		case first = <-firstRead:
+		case <-time.After(25 * time.Millisecond):
+		}
+	}
+	assert.Equal(t, []byte{0}, first.Payload)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// A stream that can't be routed to a media section is delivered to the
+// OnUnhandledRTP handler, from its first packet on
+func TestOnUnhandledRTP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	vp8Writer, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion2")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(vp8Writer)
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		t.Error("OnTrack fired for a stream that can't be routed")
+	})
+	unhandled := make(chan *rtp.Packet, 64)
+	pcAnswer.OnUnhandledRTP(func(p *rtp.Packet) {
+		unhandled <- p
+	})
+
+	signalPairWithoutSSRCs(t, pcOffer, pcAnswer)
+	for !sender.hasSent() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The payload type isn't one of the video section, the payload counts
+	// the packets
+	const packetCount = 5
+	var received []*rtp.Packet
+	for i := 0; len(received) < packetCount; i++ {
+		assert.NoError(t, vp8Writer.WriteRTP(&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    DefaultPayloadTypeOpus,
+				SequenceNumber: uint16(i),
+				Timestamp:      uint32(i) * 960,
+				SSRC:           vp8Writer.SSRC(),
+			},
+			Payload: []byte{byte(i)},
+		}))
+
+		select {
+		case p := <-unhandled:
+			received = append(received, p)
+		case <-time.After(25 * time.Millisecond):
+		}
+	}
+	for i, p := range received {
+		assert.Equal(t, vp8Writer.SSRC(), p.SSRC)
+		assert.Equal(t, []byte{byte(i)}, p.Payload)
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
 func TestOfferRejectionMissingCodec(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
@@ -803,7 +967,7 @@ func TestAddTransceiverAddTrack_NewRTPSender_Error(t *testing.T) {
 	assert.NoError(t, err)
 
 	_, err = pc.AddTrack(track)
-	assert.Error(t, err, "DTLSTransport must not be nil")
+	assert.Equal(t, ErrNilTransport, err)
 
 	assert.Equal(t, 1, len(pc.GetTransceivers()))
 
@@ -875,7 +1039,7 @@ func TestAddTransceiverFromKind(t *testing.T) {
 	assert.NoError(t, pc.Close())
 }
 
-func TestAddTransceiverFromKindFailsSendOnly(t *testing.T) {
+func TestAddTransceiverFromKindSendOnly(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
 
@@ -883,19 +1047,61 @@ func TestAddTransceiverFromKindFailsSendOnly(t *testing.T) {
 	defer report()
 
 	pc, err := NewPeerConnection(Configuration{})
-	if err != nil {
-		t.Error(err.Error())
-	}
+	assert.NoError(t, err)
 
 	transceiver, err := pc.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{
 		Direction: RTPTransceiverDirectionSendonly,
 	})
+	assert.NoError(t, err)
+	assert.Nil(t, transceiver.Receiver())
+	assert.NotNil(t, transceiver.Sender().Track())
 
-	if transceiver != nil {
-		t.Error("AddTransceiverFromKind shouldn't succeed with Direction RTPTransceiverDirectionSendonly")
-	}
+	inactive, err := pc.AddTransceiverFromKind(RTPCodecTypeAudio, RtpTransceiverInit{
+		Direction: RTPTransceiverDirectionInactive,
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, inactive.Sender())
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.True(t, offerMediaHasDirection(offer, RTPCodecTypeVideo, RTPTransceiverDirectionSendonly))
+	assert.True(t, offerMediaHasDirection(offer, RTPCodecTypeAudio, RTPTransceiverDirectionInactive))
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestAddTransceiverSendEncodings(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pc.NewTrack(DefaultPayloadTypeVP8, 0xDEADBEEF, "track-id", "track-label")
+	assert.NoError(t, err)
+
+	_, err = pc.AddTransceiverFromTrack(track, RtpTransceiverInit{
+		Direction:     RTPTransceiverDirectionSendonly,
+		SendEncodings: []RTPEncodingParameters{{RTPCodingParameters{RID: "f"}}, {RTPCodingParameters{RID: "h"}}},
+	})
+	assert.Equal(t, &rtcerr.NotSupportedError{Err: ErrRTPEncodingSimulcast}, err)
+
+	transceiver, err := pc.AddTransceiverFromTrack(track, RtpTransceiverInit{
+		Direction:     RTPTransceiverDirectionSendonly,
+		SendEncodings: []RTPEncodingParameters{{RTPCodingParameters{RID: "f"}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0xDEADBEEF), transceiver.Sender().Encodings()[0].SSRC)
+	assert.Equal(t, "f", transceiver.Sender().encodingRID())
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=rid:f send")
+	assert.NotContains(t, offer.SDP, "a=simulcast")
 
-	assert.NotNil(t, err)
 	assert.NoError(t, pc.Close())
 }
 
@@ -1240,3 +1446,254 @@ func TestPeerConnection_Start_Right_Receiver(t *testing.T) {
 	assert.NoError(t, pcOffer.Close())
 	assert.NoError(t, pcAnswer.Close())
 }
+
+func TestTrackClone(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+
+	_, err = vp8Track.Clone()
+	assert.Error(t, err)
+
+	tracksDone, tracksDoneFunc := context.WithCancel(context.Background())
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		assert.Equal(t, []string{"bar"}, track.StreamIDs())
+
+		clones := []*Track{track}
+		for i := 0; i < 2; i++ {
+			clone, cloneErr := track.Clone()
+			assert.NoError(t, cloneErr)
+			assert.Equal(t, track.SSRC(), clone.SSRC())
+			assert.Equal(t, track.Codec(), clone.Codec())
+			clones = append(clones, clone)
+		}
+
+		// Every Track gets its own copy of the packets
+		var wg sync.WaitGroup
+		for _, clone := range clones {
+			wg.Add(1)
+			go func(clone *Track) {
+				defer wg.Done()
+				for i := 0; i < 5; i++ {
+					packet, readErr := clone.ReadRTP()
+					assert.NoError(t, readErr)
+					assert.Equal(t, track.SSRC(), packet.SSRC)
+				}
+			}(clone)
+		}
+		wg.Wait()
+		tracksDoneFunc()
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(tracksDone.Done(), t, []*Track{vp8Track})
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_OnRTCP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+	rtpSender, err := pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+
+	senderGotPLI, senderGotPLIFunc := context.WithCancel(context.Background())
+	rtpSender.OnRTCP(RTCPHandlers{
+		OnPLI: func(p *rtcp.PictureLossIndication) {
+			assert.Equal(t, vp8Track.SSRC(), p.MediaSSRC)
+			senderGotPLIFunc()
+		},
+	})
+
+	receiverGotNACK, receiverGotNACKFunc := context.WithCancel(context.Background())
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		receiver.OnRTCP(RTCPHandlers{
+			OnNACK: func(p *rtcp.TransportLayerNack) {
+				receiverGotNACKFunc()
+			},
+		})
+
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: track.SSRC()}}))
+				assert.NoError(t, pcOffer.WriteRTCP([]rtcp.Packet{&rtcp.TransportLayerNack{MediaSSRC: track.SSRC(), Nacks: []rtcp.NackPair{{PacketID: 1}}}}))
+			case <-senderGotPLI.Done():
+				<-receiverGotNACK.Done()
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(senderGotPLI.Done(), t, []*Track{vp8Track})
+	<-receiverGotNACK.Done()
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_PacketCapture(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	assert.Error(t, pcOffer.dtlsTransport.StartCapture(&bytes.Buffer{}, PacketCaptureFormat(Unknown)))
+
+	// Captures can start before the transport is connected
+	pcapCapture, rtpdumpCapture := &bytes.Buffer{}, &bytes.Buffer{}
+	assert.NoError(t, pcOffer.dtlsTransport.StartCapture(pcapCapture, PacketCaptureFormatPCAP))
+	assert.NoError(t, pcAnswer.dtlsTransport.StartCapture(rtpdumpCapture, PacketCaptureFormatRTPDump))
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+	rtpSender, err := pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+
+	senderGotPLI, senderGotPLIFunc := context.WithCancel(context.Background())
+	rtpSender.OnRTCP(RTCPHandlers{
+		OnPLI: func(p *rtcp.PictureLossIndication) {
+			senderGotPLIFunc()
+		},
+	})
+
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: track.SSRC()}}))
+			case <-senderGotPLI.Done():
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(senderGotPLI.Done(), t, []*Track{vp8Track})
+
+	localAddr, _ := pcOffer.dtlsTransport.captureAddr(false)
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	pcOffer.dtlsTransport.StopCapture()
+	pcAnswer.dtlsTransport.StopCapture()
+
+	// The offerer sent RTP and received RTCP
+	sentRTP, receivedRTCP := false, false
+	data := pcapCapture.Bytes()[24:]
+	for len(data) >= 16 {
+		length := int(binary.LittleEndian.Uint32(data[8:]))
+		datagram := data[16 : 16+length]
+		data = data[16+length:]
+
+		// The pair is IPv4 or IPv6 depending on the host
+		source, udp := net.IP(datagram[12:16]), datagram[20:]
+		if datagram[0]>>4 == 6 {
+			source, udp = net.IP(datagram[8:24]), datagram[40:]
+		}
+		payload := udp[8:]
+		outbound := source.Equal(localAddr.IP) && int(binary.BigEndian.Uint16(udp)) == localAddr.Port
+		switch {
+		case outbound && payload[1]&0x7F == DefaultPayloadTypeVP8:
+			assert.Equal(t, vp8Track.SSRC(), binary.BigEndian.Uint32(payload[8:]))
+			sentRTP = true
+		case !outbound && payload[1] == uint8(rtcp.TypePayloadSpecificFeedback):
+			receivedRTCP = true
+		}
+	}
+	assert.True(t, sentRTP)
+	assert.True(t, receivedRTCP)
+
+	// The answerer received RTP and sent RTCP
+	reader, _, err := rtpdump.NewReader(rtpdumpCapture)
+	assert.NoError(t, err)
+	rtpCount, rtcpCount := 0, 0
+	for {
+		packet, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		if packet.IsRTCP {
+			rtcpCount++
+		} else {
+			rtpCount++
+		}
+	}
+	assert.NotZero(t, rtpCount)
+	assert.NotZero(t, rtcpCount)
+}
+
+func TestPeerConnection_Media_ReadContext(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrack := make(chan struct{})
+	pcAnswer.OnTrack(func(remote *Track, r *RTPReceiver) {
+		defer close(onTrack)
+
+		packet, readErr := remote.ReadRTPContext(context.Background())
+		assert.NoError(t, readErr)
+		assert.Equal(t, track.SSRC(), packet.SSRC)
+
+		// Nothing is sent on the RTCP of the receiver
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, readErr = r.ReadContext(ctx, make([]byte, receiveMTU))
+		assert.Equal(t, context.DeadlineExceeded, readErr)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(onTrack, t, []*Track{track})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = sender.ReadContext(ctx, make([]byte, receiveMTU))
+	assert.Equal(t, context.Canceled, err)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
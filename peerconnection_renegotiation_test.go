@@ -695,3 +695,194 @@ func TestPeerConnection_Renegotiation_NoApplication(t *testing.T) {
 	assert.NoError(t, pcOffer.Close())
 	assert.NoError(t, pcAnswer.Close())
 }
+
+func TestPeerConnection_Renegotiation_OnNegotiationNeeded(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var negotiationNeededCount int32
+	negotiationNeeded := make(chan struct{}, 10)
+	pcOffer.OnNegotiationNeeded(func() {
+		atomic.AddInt32(&negotiationNeededCount, 1)
+		negotiationNeeded <- struct{}{}
+	})
+
+	// The first DataChannel and a transceiver are collapsed into a single event
+	_, err = pcOffer.CreateDataChannel("initial_data_channel", nil)
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	<-negotiationNeeded
+	pcOffer.ops.Done()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&negotiationNeededCount))
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	pcOffer.ops.Done()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&negotiationNeededCount))
+
+	// Adding a Track after the connection is established renegotiates
+	onTrackFired, onTrackFiredFunc := context.WithCancel(context.Background())
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		onTrackFiredFunc()
+	})
+
+	pcOffer.OnNegotiationNeeded(func() {
+		offer, err := pcOffer.CreateOffer(nil)
+		assert.NoError(t, err)
+		assert.NoError(t, pcOffer.SetLocalDescription(offer))
+		assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+		answer, err := pcAnswer.CreateAnswer(nil)
+		assert.NoError(t, err)
+		assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+		assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+		negotiationNeeded <- struct{}{}
+	})
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+
+	<-negotiationNeeded
+	sendVideoUntilDone(onTrackFired.Done(), t, []*Track{vp8Track})
+
+	pcOffer.ops.Done()
+	assert.False(t, pcOffer.checkNegotiationNeeded())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// Assert that RemoveTrack and RTPTransceiver.Stop change the direction
+// and rejection of the media section in the next negotiation
+func TestPeerConnection_Renegotiation_StopTransceiver(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	addTrack := func(pc *PeerConnection) *RTPSender {
+		track, trackErr := pc.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+		assert.NoError(t, trackErr)
+
+		sender, trackErr := pc.AddTrack(track)
+		assert.NoError(t, trackErr)
+		return sender
+	}
+
+	negotiate := func() (offer, answer SessionDescription) {
+		offer, offerErr := pcOffer.CreateOffer(nil)
+		assert.NoError(t, offerErr)
+		assert.NoError(t, pcOffer.SetLocalDescription(offer))
+		assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+		answer, offerErr = pcAnswer.CreateAnswer(nil)
+		assert.NoError(t, offerErr)
+		assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+		assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+		return offer, answer
+	}
+
+	sender := addTrack(pcOffer)
+	addTrack(pcAnswer)
+
+	_, answer := negotiate()
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, getPeerDirection(answer.parsed.MediaDescriptions[0]))
+
+	// The offerer stops sending, the answerer can only send
+	assert.NoError(t, pcOffer.RemoveTrack(sender))
+	offer, answer := negotiate()
+	assert.Equal(t, RTPTransceiverDirectionRecvonly, getPeerDirection(offer.parsed.MediaDescriptions[0]))
+	assert.Equal(t, RTPTransceiverDirectionSendonly, getPeerDirection(answer.parsed.MediaDescriptions[0]))
+
+	// Stopping the transceiver rejects its media section on both sides
+	assert.NoError(t, pcOffer.GetTransceivers()[0].Stop())
+	offer, answer = negotiate()
+	assert.True(t, isMediaSectionRejected(offer.parsed.MediaDescriptions[0]))
+	assert.True(t, isMediaSectionRejected(answer.parsed.MediaDescriptions[0]))
+	assert.Equal(t, RTPTransceiverDirectionInactive, pcAnswer.GetTransceivers()[0].Direction())
+	assert.False(t, pcAnswer.checkNegotiationNeeded())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// Both peers offer at the same time, the polite one rolls back and the
+// change it wanted is negotiated afterwards
+func TestNegotiationManager_Glare(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcPolite, pcImpolite, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	polite, impolite := NewNegotiationManager(pcPolite, true), NewNegotiationManager(pcImpolite, false)
+
+	// The messages are held until both peers have sent their offer
+	toPolite, toImpolite := make(chan SignalingMessage, 10), make(chan SignalingMessage, 10)
+	polite.OnSignalingMessage(func(msg SignalingMessage) { toImpolite <- msg })
+	impolite.OnSignalingMessage(func(msg SignalingMessage) { toPolite <- msg })
+
+	dataChannelOpened, dataChannelOpenedFunc := context.WithCancel(context.Background())
+	pcImpolite.OnDataChannel(func(d *DataChannel) {
+		d.OnOpen(dataChannelOpenedFunc)
+	})
+	_, err = pcPolite.CreateDataChannel("polite", nil)
+	assert.NoError(t, err)
+
+	_, err = pcImpolite.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	for len(toPolite) == 0 || len(toImpolite) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var wg sync.WaitGroup
+	relay := func(messages chan SignalingMessage, m *NegotiationManager) {
+		defer wg.Done()
+		for msg := range messages {
+			assert.NoError(t, m.HandleSignalingMessage(msg))
+		}
+	}
+	wg.Add(2)
+	go relay(toPolite, polite)
+	go relay(toImpolite, impolite)
+
+	<-dataChannelOpened.Done()
+	for pcPolite.SignalingState() != SignalingStateStable || pcImpolite.SignalingState() != SignalingStateStable {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The impolite offer won, its transceiver was negotiated
+	assert.Len(t, pcPolite.GetTransceivers(), 1)
+	assert.Len(t, pcImpolite.GetTransceivers(), 1)
+
+	assert.NoError(t, pcPolite.Close())
+	assert.NoError(t, pcImpolite.Close())
+	close(toPolite)
+	close(toImpolite)
+	wg.Wait()
+}
@@ -0,0 +1,244 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/webrtc/v2/internal/util"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+var errGatheringTimeout = errors.New("ICE gathering timed out")
+
+// PeerConnectionPoolConfig configures a PeerConnectionPool, the zero values
+// select the defaults
+type PeerConnectionPoolConfig struct {
+	// Configuration is the one of the PeerConnections
+	Configuration Configuration
+
+	// Size is how many PeerConnections are kept ready, 1 by default
+	Size int
+
+	// MaxIdle is how long a ready PeerConnection is kept before it is
+	// replaced, as the NAT bindings of its server reflexive candidates expire.
+	// 1 minute by default.
+	MaxIdle time.Duration
+}
+
+// PeerConnectionPool keeps PeerConnections ready to be used, with their
+// certificate generated and their ICE candidates gathered, so an incoming
+// call or viewer is answered without waiting for either. Get takes one and
+// the pool creates its replacement in the background:
+//
+//	pool, err := api.NewPeerConnectionPool(webrtc.PeerConnectionPoolConfig{Size: 10})
+//	...
+//	pc, err := pool.Get()
+//	err = pc.SetRemoteDescription(offer)
+//	answer, err := pc.CreateAnswer(nil)
+//
+// The candidates were gathered before any handler could be set, with trickle
+// ICE they are only signaled in the local description, which is complete once
+// the PeerConnection is taken.
+type PeerConnectionPool struct {
+	api    *API
+	config PeerConnectionPoolConfig
+	log    logging.LeveledLogger
+
+	mu      sync.Mutex
+	ready   []*pooledPeerConnection
+	warming int
+	closed  bool
+}
+
+type pooledPeerConnection struct {
+	pc    *PeerConnection
+	timer *time.Timer
+}
+
+// NewPeerConnectionPool creates a PeerConnectionPool and the PeerConnections
+// it keeps. The first one is created right away to check the Configuration,
+// the others in the background.
+func (api *API) NewPeerConnectionPool(config PeerConnectionPoolConfig) (*PeerConnectionPool, error) {
+	if config.Size <= 0 {
+		config.Size = defaultPeerConnectionPoolSize
+	}
+	if config.MaxIdle <= 0 {
+		config.MaxIdle = defaultPeerConnectionPoolMaxIdle
+	}
+
+	p := &PeerConnectionPool{
+		api:    api,
+		config: config,
+		log:    api.settingEngine.LoggerFactory.NewLogger("pc"),
+	}
+	pc, err := p.newPeerConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addReady(pc)
+	p.refill()
+	return p, nil
+}
+
+// Get takes a ready PeerConnection out of the pool, or creates one when none
+// is ready
+func (p *PeerConnectionPool) Get() (*PeerConnection, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+
+	var pc *PeerConnection
+	if n := len(p.ready); n != 0 {
+		// The newest, whose candidates are the freshest
+		entry := p.ready[n-1]
+		p.ready = p.ready[:n-1]
+		entry.timer.Stop()
+		pc = entry.pc
+	}
+	p.refill()
+	p.mu.Unlock()
+
+	if pc != nil {
+		return pc, nil
+	}
+	return p.api.NewPeerConnection(p.config.Configuration)
+}
+
+// Ready returns how many PeerConnections are ready to be taken
+func (p *PeerConnectionPool) Ready() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.ready)
+}
+
+// Close closes the PeerConnections of the pool, the ones taken out of it
+// aren't affected
+func (p *PeerConnectionPool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	ready := p.ready
+	p.ready = nil
+	p.mu.Unlock()
+
+	var errs []error
+	for _, entry := range ready {
+		entry.timer.Stop()
+		if err := entry.pc.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return util.FlattenErrs(errs)
+}
+
+// newPeerConnection creates a PeerConnection and gathers its candidates
+func (p *PeerConnectionPool) newPeerConnection() (*PeerConnection, error) {
+	pc, err := p.api.NewPeerConnection(p.config.Configuration)
+	if err != nil {
+		return nil, err
+	}
+	if err := pc.gatherAhead(); err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+	return pc, nil
+}
+
+// refill creates the PeerConnections missing from the pool, p.mu must be held
+func (p *PeerConnectionPool) refill() {
+	for ; !p.closed && len(p.ready)+p.warming < p.config.Size; p.warming++ {
+		go p.warm()
+	}
+}
+
+func (p *PeerConnectionPool) warm() {
+	pc, err := p.newPeerConnection()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.warming--
+	switch {
+	case err != nil:
+		// The next Get tries again, failures don't spin
+		p.log.Warnf("Failed to create a pooled PeerConnection: %v", err)
+	case p.closed:
+		go pc.Close() //nolint:errcheck
+	default:
+		p.addReady(pc)
+	}
+}
+
+// addReady adds pc to the ready PeerConnections until it is taken or gets
+// too old, p.mu must be held
+func (p *PeerConnectionPool) addReady(pc *PeerConnection) {
+	entry := &pooledPeerConnection{pc: pc}
+	entry.timer = time.AfterFunc(p.config.MaxIdle, func() {
+		p.expire(entry)
+	})
+	p.ready = append(p.ready, entry)
+}
+
+// expire replaces entry with a fresh PeerConnection, unless it was taken
+func (p *PeerConnectionPool) expire(entry *pooledPeerConnection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, e := range p.ready {
+		if e == entry {
+			p.ready = append(p.ready[:i], p.ready[i+1:]...)
+			go entry.pc.Close() //nolint:errcheck
+			p.refill()
+			return
+		}
+	}
+}
+
+// gatherAhead gathers the local candidates before SetLocalDescription, when
+// trickle ICE would only start gathering then, and waits for gathering to
+// complete for at most poolGatheringTimeout. Without trickle ICE they were
+// gathered when the PeerConnection was created.
+func (pc *PeerConnection) gatherAhead() error {
+	if !pc.api.settingEngine.candidates.ICETrickle {
+		return nil
+	}
+
+	// The pool hasn't handed pc out yet, nothing else sets the handler
+	done := make(chan struct{})
+	var doneOnce sync.Once
+	pc.OnICEGatheringStateChange(func(state ICEGathererState) {
+		if state == ICEGathererStateComplete || state == ICEGathererStateClosed {
+			doneOnce.Do(func() { close(done) })
+		}
+	})
+	defer pc.OnICEGatheringStateChange(nil)
+
+	if pc.iceGatherer.State() == ICEGathererStateNew {
+		if err := pc.iceGatherer.Gather(); err != nil {
+			return err
+		}
+	}
+
+	if pc.ICEGatheringState() != ICEGatheringStateComplete {
+		timer := time.NewTimer(poolGatheringTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+		case <-timer.C:
+			return errGatheringTimeout
+		}
+	}
+	if pc.isClosed.get() || pc.iceGatherer.State() == ICEGathererStateClosed {
+		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+	return nil
+}
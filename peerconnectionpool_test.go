@@ -0,0 +1,48 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerConnectionPool(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetTrickle(true)
+	pool, err := NewAPI(WithSettingEngine(s)).NewPeerConnectionPool(PeerConnectionPoolConfig{Size: 2})
+	assert.NoError(t, err)
+
+	for pool.Ready() != 2 {
+		time.Sleep(gatheringPollInterval)
+	}
+
+	// The candidates are in the first offer, without waiting for them
+	pc, err := pool.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, ICEGatheringStateComplete, pc.ICEGatheringState())
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+	assert.Contains(t, pc.LocalDescription().SDP, "a=candidate:")
+	assert.Contains(t, pc.LocalDescription().SDP, "a=end-of-candidates")
+
+	// Taken PeerConnections are replaced, and outlive the pool
+	for pool.Ready() != 2 {
+		time.Sleep(gatheringPollInterval)
+	}
+	assert.NoError(t, pool.Close())
+	assert.Equal(t, 0, pool.Ready())
+	_, err = pool.Get()
+	assert.Error(t, err)
+
+	assert.Equal(t, SignalingStateHaveLocalOffer, pc.SignalingState())
+	assert.NoError(t, pc.Close())
+}
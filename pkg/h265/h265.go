@@ -0,0 +1,220 @@
+// Package h265 implements the RTP payload format for H.265/HEVC video
+// https://tools.ietf.org/html/rfc7798
+//
+// Only the single stream mode without DONL fields (sprop-max-don-diff=0)
+// is supported, PACI packets are rejected.
+package h265
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	naluHeaderSize = 2
+	fuHeaderSize   = 1
+	apNALUSize     = 2
+
+	naluTypeAUD  = 35
+	naluTypeFD   = 38
+	naluTypeAP   = 48
+	naluTypeFU   = 49
+	naluTypePACI = 50
+
+	fuStartBitmask = 0x80
+	fuEndBitmask   = 0x40
+	fuTypeBitmask  = 0x3F
+)
+
+var (
+	errShortPacket       = errors.New("packet is not large enough")
+	errAPSizeOverflow    = errors.New("AP declared NAL unit size is larger than buffer")
+	errUnhandledNALUType = errors.New("NAL unit type is currently not handled")
+)
+
+func annexbNALUStartCode() []byte { return []byte{0x00, 0x00, 0x00, 0x01} }
+
+// naluType returns the type field of the two byte NAL unit header
+//
+//	+---------------+---------------+
+//	|0|1|2|3|4|5|6|7|0|1|2|3|4|5|6|7|
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|F|   Type    |  LayerId  | TID |
+//	+-------------+-----------------+
+func naluType(header []byte) uint8 {
+	return (header[0] >> 1) & 0x3F
+}
+
+// emitNalus calls emit for every NAL unit of an Annex B byte stream
+func emitNalus(nals []byte, emit func([]byte)) {
+	nextInd := func(nalu []byte, start int) (indStart int, indLen int) {
+		zeroCount := 0
+
+		for i, b := range nalu[start:] {
+			if b == 0 {
+				zeroCount++
+				continue
+			} else if b == 1 && zeroCount >= 2 {
+				return start + i - zeroCount, zeroCount + 1
+			}
+			zeroCount = 0
+		}
+		return -1, -1
+	}
+
+	nextIndStart, nextIndLen := nextInd(nals, 0)
+	if nextIndStart == -1 {
+		emit(nals)
+		return
+	}
+
+	for nextIndStart != -1 {
+		prevStart := nextIndStart + nextIndLen
+		nextIndStart, nextIndLen = nextInd(nals, prevStart)
+		if nextIndStart != -1 {
+			emit(nals[prevStart:nextIndStart])
+		} else {
+			// Emit until end of stream, no end indicator found
+			emit(nals[prevStart:])
+		}
+	}
+}
+
+// Payloader payloads H265 access units in Annex B format
+type Payloader struct{}
+
+// Payload fragments an H265 access unit across one or more byte arrays.
+// NAL units that don't fit in mtu are sent as Fragmentation Units.
+func (p *Payloader) Payload(mtu int, payload []byte) [][]byte {
+	var payloads [][]byte
+	if payload == nil {
+		return payloads
+	}
+
+	emitNalus(payload, func(nalu []byte) {
+		if len(nalu) <= naluHeaderSize {
+			return
+		}
+
+		switch naluType(nalu) {
+		case naluTypeAUD, naluTypeFD:
+			return
+		}
+
+		// Single NAL unit packet
+		if len(nalu) <= mtu {
+			payloads = append(payloads, append([]byte{}, nalu...))
+			return
+		}
+
+		maxFragmentSize := mtu - naluHeaderSize - fuHeaderSize
+		if maxFragmentSize <= 0 {
+			return
+		}
+
+		// The PayloadHdr keeps F, LayerId and TID of the fragmented NAL unit
+		payloadHeader := []byte{
+			(nalu[0] & 0x81) | (naluTypeFU << 1),
+			nalu[1],
+		}
+
+		naluData := nalu[naluHeaderSize:]
+		for i := 0; i < len(naluData); i += maxFragmentSize {
+			fragment := naluData[i:]
+			if len(fragment) > maxFragmentSize {
+				fragment = fragment[:maxFragmentSize]
+			}
+
+			// +---------------+
+			// |0|1|2|3|4|5|6|7|
+			// +-+-+-+-+-+-+-+-+
+			// |S|E|  FuType   |
+			// +---------------+
+			fuHeader := naluType(nalu)
+			if i == 0 {
+				fuHeader |= fuStartBitmask
+			} else if i+len(fragment) == len(naluData) {
+				fuHeader |= fuEndBitmask
+			}
+
+			out := make([]byte, 0, naluHeaderSize+fuHeaderSize+len(fragment))
+			out = append(out, payloadHeader...)
+			out = append(out, fuHeader)
+			out = append(out, fragment...)
+			payloads = append(payloads, out)
+		}
+	})
+
+	return payloads
+}
+
+// Packet depacketizes H265 RTP payloads into an Annex B byte stream
+type Packet struct{}
+
+// Unmarshal parses the passed byte slice and returns the NAL units it carries,
+// each prefixed with a start code. Fragmentation Units after the first are
+// returned without a start code so they can be concatenated.
+func (p *Packet) Unmarshal(payload []byte) ([]byte, error) {
+	if len(payload) <= naluHeaderSize {
+		return nil, errShortPacket
+	}
+
+	switch naluType(payload) {
+	case naluTypeAP:
+		result := []byte{}
+		offset := naluHeaderSize
+		for offset < len(payload) {
+			if len(payload) < offset+apNALUSize {
+				return nil, errShortPacket
+			}
+			naluSize := int(binary.BigEndian.Uint16(payload[offset:]))
+			offset += apNALUSize
+
+			if len(payload) < offset+naluSize {
+				return nil, errAPSizeOverflow
+			}
+
+			result = append(result, annexbNALUStartCode()...)
+			result = append(result, payload[offset:offset+naluSize]...)
+			offset += naluSize
+		}
+		return result, nil
+
+	case naluTypeFU:
+		if len(payload) <= naluHeaderSize+fuHeaderSize {
+			return nil, errShortPacket
+		}
+
+		fuHeader := payload[naluHeaderSize]
+		if fuHeader&fuStartBitmask == 0 {
+			return payload[naluHeaderSize+fuHeaderSize:], nil
+		}
+
+		// Rebuild the header of the fragmented NAL unit from the PayloadHdr
+		result := append(annexbNALUStartCode(),
+			(payload[0]&0x81)|((fuHeader&fuTypeBitmask)<<1),
+			payload[1],
+		)
+		return append(result, payload[naluHeaderSize+fuHeaderSize:]...), nil
+
+	case naluTypePACI:
+		return nil, errUnhandledNALUType
+	}
+
+	return append(annexbNALUStartCode(), payload...), nil
+}
+
+// PartitionHeadChecker checks whether a packet starts a NAL unit
+type PartitionHeadChecker struct{}
+
+// IsPartitionHead returns false for Fragmentation Units that continue a NAL unit
+func (c *PartitionHeadChecker) IsPartitionHead(payload []byte) bool {
+	if len(payload) <= naluHeaderSize {
+		return false
+	}
+
+	if naluType(payload) == naluTypeFU {
+		return payload[naluHeaderSize]&fuStartBitmask != 0
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package h265
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ rtp.Payloader            = &Payloader{}
+	_ rtp.Depacketizer         = &Packet{}
+	_ rtp.PartitionHeadChecker = &PartitionHeadChecker{}
+)
+
+func TestPayloader(t *testing.T) {
+	p := &Payloader{}
+
+	assert.Nil(t, p.Payload(100, nil))
+
+	// VPS and an access unit delimiter, the delimiter is dropped
+	vps := []byte{0x40, 0x01, 0x0c, 0x01}
+	aud := []byte{0x46, 0x01, 0x10}
+	stream := append(append(append([]byte{0x00, 0x00, 0x00, 0x01}, vps...), 0x00, 0x00, 0x01), aud...)
+	assert.Equal(t, [][]byte{vps}, p.Payload(100, stream))
+
+	// An IDR slice larger than the MTU is fragmented
+	idr := append([]byte{0x26, 0x01}, bytes.Repeat([]byte{0xAA}, 10)...)
+	payloads := p.Payload(7, idr)
+	assert.Equal(t, [][]byte{
+		{0x62, 0x01, 0x93, 0xAA, 0xAA, 0xAA, 0xAA},
+		{0x62, 0x01, 0x13, 0xAA, 0xAA, 0xAA, 0xAA},
+		{0x62, 0x01, 0x53, 0xAA, 0xAA},
+	}, payloads)
+
+	// No room for the headers
+	assert.Nil(t, p.Payload(3, idr))
+}
+
+func TestPacket_Unmarshal(t *testing.T) {
+	p := &Packet{}
+
+	_, err := p.Unmarshal(nil)
+	assert.Equal(t, errShortPacket, err)
+
+	// Single NAL unit
+	out, err := p.Unmarshal([]byte{0x40, 0x01, 0x0c})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c}, out)
+
+	// Aggregation packet with a VPS and a SPS
+	out, err = p.Unmarshal([]byte{0x60, 0x01, 0x00, 0x02, 0x40, 0x01, 0x00, 0x03, 0x42, 0x01, 0x01})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x00, 0x00, 0x00, 0x01, 0x42, 0x01, 0x01}, out)
+
+	_, err = p.Unmarshal([]byte{0x60, 0x01, 0x00, 0x05, 0x40, 0x01})
+	assert.Equal(t, errAPSizeOverflow, err)
+
+	_, err = p.Unmarshal([]byte{0x64, 0x01, 0x00, 0x00})
+	assert.Equal(t, errUnhandledNALUType, err)
+
+	// Fragmentation units round trip through the Payloader
+	idr := append([]byte{0x26, 0x01}, bytes.Repeat([]byte{0xAA}, 10)...)
+	checker := &PartitionHeadChecker{}
+	reassembled := []byte{}
+	for i, payload := range (&Payloader{}).Payload(7, idr) {
+		assert.Equal(t, i == 0, checker.IsPartitionHead(payload))
+
+		out, err = p.Unmarshal(payload)
+		assert.NoError(t, err)
+		reassembled = append(reassembled, out...)
+	}
+	assert.Equal(t, append([]byte{0x00, 0x00, 0x00, 0x01}, idr...), reassembled)
+}
@@ -5,12 +5,18 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
 )
 
 const (
 	ivfFileHeaderSignature = "DKIF"
 	ivfFileHeaderSize      = 32
 	ivfFrameHeaderSize     = 12
+
+	// VP8 and VP9 have a 90kHz RTP clock
+	videoClockRate = 90000
 )
 
 // IVFFileHeader 32-byte header for IVF files
@@ -39,6 +45,7 @@ type IVFFrameHeader struct {
 type IVFReader struct {
 	stream               io.Reader
 	bytesReadSuccesfully int64
+	header               *IVFFileHeader
 }
 
 // NewWith returns a new IVF reader and IVF file header
@@ -56,6 +63,7 @@ func NewWith(in io.Reader) (*IVFReader, *IVFFileHeader, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	reader.header = header
 
 	return reader, header, nil
 }
@@ -99,6 +107,45 @@ func (i *IVFReader) ParseNextFrame() ([]byte, *IVFFrameHeader, error) {
 	return payload, header, nil
 }
 
+// Play writes the remaining frames to w, like a local Track, at the pace
+// given by their timestamps. It returns nil once every frame is written.
+func (i *IVFReader) Play(w media.SampleWriter) error {
+	if i.header.TimebaseDenominator == 0 {
+		return fmt.Errorf("invalid timebase denominator")
+	}
+
+	// Duration of n timebase units
+	timebase := func(n uint64) time.Duration {
+		return time.Duration(n * uint64(i.header.TimebaseNumerator) * uint64(time.Second) / uint64(i.header.TimebaseDenominator))
+	}
+
+	var start time.Time
+	var firstTimestamp, lastTimestamp uint64
+	for {
+		frame, header, err := i.ParseNextFrame()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// The first frame lasts one timebase unit
+		units := uint64(1)
+		if start.IsZero() {
+			start, firstTimestamp = time.Now(), header.Timestamp
+		} else if header.Timestamp > lastTimestamp {
+			units = header.Timestamp - lastTimestamp
+		}
+		lastTimestamp = header.Timestamp
+
+		time.Sleep(time.Until(start.Add(timebase(header.Timestamp - firstTimestamp))))
+		samples := units * uint64(i.header.TimebaseNumerator) * videoClockRate / uint64(i.header.TimebaseDenominator)
+		if err := w.WriteSample(media.Sample{Data: frame, Samples: uint32(samples)}); err != nil {
+			return err
+		}
+	}
+}
+
 // parseFileHeader reads 32 bytes from stream and returns
 // IVF file header. This is always called before ParseNextFrame()
 func (i *IVFReader) parseFileHeader() (*IVFFileHeader, error) {
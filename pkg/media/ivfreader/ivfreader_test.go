@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/pion/webrtc/v2/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -165,3 +167,33 @@ func TestIVFReader_EOFWhenNoFramesLeft(t *testing.T) {
 
 	assert.Equal(fmt.Errorf("EOF"), err)
 }
+
+type sampleRecorder struct {
+	samples []media.Sample
+}
+
+func (r *sampleRecorder) WriteSample(s media.Sample) error {
+	r.samples = append(r.samples, s)
+	return nil
+}
+
+func TestIVFReader_Play(t *testing.T) {
+	frame := func(timestamp byte, payload byte) *[]byte {
+		return &[]byte{0x01, 0x00, 0x00, 0x00, timestamp, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, payload}
+	}
+
+	// Timebase is 1/30s
+	reader, _, err := NewWith(buildIVFContainer(frame(0, 0xA), frame(1, 0xB), frame(2, 0xC)))
+	assert.NoError(t, err)
+
+	recorder := &sampleRecorder{}
+	start := time.Now()
+	assert.NoError(t, reader.Play(recorder))
+	assert.True(t, time.Since(start) >= 2*time.Second/30)
+
+	assert.Equal(t, []media.Sample{
+		{Data: []byte{0xA}, Samples: 3000},
+		{Data: []byte{0xB}, Samples: 3000},
+		{Data: []byte{0xC}, Samples: 3000},
+	}, recorder.samples)
+}
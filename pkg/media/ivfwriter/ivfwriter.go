@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
@@ -16,15 +17,37 @@ type IVFWriter struct {
 	ioWriter     io.Writer
 	count        uint64
 	currentFrame []byte
+
+	fourCC       string
+	depacketizer rtp.Depacketizer
+}
+
+// An Option configures an IVFWriter
+type Option func(i *IVFWriter) error
+
+// WithCodec sets the codec of the RTP packets, VP8 or VP9 like the codec names
+// of the MediaEngine. VP8 is the default.
+func WithCodec(codec string) Option {
+	return func(i *IVFWriter) error {
+		switch strings.ToLower(codec) {
+		case "vp8":
+			i.fourCC, i.depacketizer = "VP80", &codecs.VP8Packet{}
+		case "vp9":
+			i.fourCC, i.depacketizer = "VP90", &codecs.VP9Packet{}
+		default:
+			return fmt.Errorf("ivf does not support codec %q", codec)
+		}
+		return nil
+	}
 }
 
 // New builds a new IVF writer
-func New(fileName string) (*IVFWriter, error) {
+func New(fileName string, opts ...Option) (*IVFWriter, error) {
 	f, err := os.Create(fileName)
 	if err != nil {
 		return nil, err
 	}
-	writer, err := NewWith(f)
+	writer, err := NewWith(f, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +56,7 @@ func New(fileName string) (*IVFWriter, error) {
 }
 
 // NewWith initialize a new IVF writer with an io.Writer output
-func NewWith(out io.Writer) (*IVFWriter, error) {
+func NewWith(out io.Writer, opts ...Option) (*IVFWriter, error) {
 	if out == nil {
 		return nil, fmt.Errorf("file not opened")
 	}
@@ -41,6 +64,11 @@ func NewWith(out io.Writer) (*IVFWriter, error) {
 	writer := &IVFWriter{
 		ioWriter: out,
 	}
+	for _, opt := range append([]Option{WithCodec("VP8")}, opts...) {
+		if err := opt(writer); err != nil {
+			return nil, err
+		}
+	}
 	if err := writer.writeHeader(); err != nil {
 		return nil, err
 	}
@@ -52,7 +80,7 @@ func (i *IVFWriter) writeHeader() error {
 	copy(header[0:], []byte("DKIF"))                // DKIF
 	binary.LittleEndian.PutUint16(header[4:], 0)    // Version
 	binary.LittleEndian.PutUint16(header[6:], 32)   // Header size
-	copy(header[8:], []byte("VP80"))                // FOURCC
+	copy(header[8:], []byte(i.fourCC))              // FOURCC
 	binary.LittleEndian.PutUint16(header[12:], 640) // Width in pixels
 	binary.LittleEndian.PutUint16(header[14:], 480) // Height in pixels
 	binary.LittleEndian.PutUint32(header[16:], 30)  // Framerate denominator
@@ -70,12 +98,12 @@ func (i *IVFWriter) WriteRTP(packet *rtp.Packet) error {
 		return fmt.Errorf("file not opened")
 	}
 
-	vp8Packet := codecs.VP8Packet{}
-	if _, err := vp8Packet.Unmarshal(packet.Payload); err != nil {
+	payload, err := i.depacketizer.Unmarshal(packet.Payload)
+	if err != nil {
 		return err
 	}
 
-	i.currentFrame = append(i.currentFrame, vp8Packet.Payload[0:]...)
+	i.currentFrame = append(i.currentFrame, payload...)
 
 	if !packet.Marker {
 		return nil
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media/ivfreader"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -124,3 +125,25 @@ func TestIVFWriter_AddPacketAndClose(t *testing.T) {
 		}
 	}
 }
+
+func TestIVFWriter_VP9(t *testing.T) {
+	_, err := NewWith(&bytes.Buffer{}, WithCodec("H264"))
+	assert.Error(t, err)
+
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithCodec("VP9"))
+	assert.NoError(t, err)
+
+	// A frame in two packets, with the B and E bits of the VP9 payload descriptor
+	assert.NoError(t, writer.WriteRTP(&rtp.Packet{Payload: []byte{0x08, 0xAA}}))
+	assert.NoError(t, writer.WriteRTP(&rtp.Packet{Header: rtp.Header{Marker: true}, Payload: []byte{0x04, 0xBB}}))
+	assert.NoError(t, writer.Close())
+
+	reader, header, err := ivfreader.NewWith(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "VP90", header.FourCC)
+
+	frame, _, err := reader.ParseNextFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xAA, 0xBB}, frame)
+}
@@ -1,7 +1,8 @@
-// Package media provides media writer and filters
+// Package media provides media writer, filters and codec interfaces
 package media
 
 import (
+	"io"
 	"time"
 
 	"github.com/pion/rtp"
@@ -29,3 +30,48 @@ type Writer interface {
 	// Note: Close implementation must be idempotent
 	Close() error
 }
+
+// SampleWriter is a sink of samples, like a local webrtc.Track
+type SampleWriter interface {
+	WriteSample(s Sample) error
+}
+
+// RTPReader is a source of RTP packets, like a remote webrtc.Track
+type RTPReader interface {
+	ReadRTP() (*rtp.Packet, error)
+}
+
+// Record writes the packets of r to w until r ends, then closes w. It returns
+// nil when r ends with io.EOF, like a Track whose receiver stopped.
+func Record(r RTPReader, w Writer) error {
+	for {
+		packet, err := r.ReadRTP()
+		if err != nil {
+			if closeErr := w.Close(); err == io.EOF {
+				return closeErr
+			}
+			return err
+		}
+
+		if err := w.WriteRTP(packet); err != nil {
+			_ = w.Close()
+			return err
+		}
+	}
+}
+
+// Encoder compresses raw frames into the samples of a codec. Video frames
+// are I420, audio frames are interleaved signed 16-bit PCM.
+type Encoder interface {
+	// Encode returns no data when the codec needs more input for a sample
+	Encode(frame []byte) ([]byte, error)
+	Close() error
+}
+
+// Decoder decompresses the samples of a codec into raw frames, in the same
+// formats an Encoder takes
+type Decoder interface {
+	// Decode returns no data when the codec needs more input for a frame
+	Decode(sample []byte) ([]byte, error)
+	Close() error
+}
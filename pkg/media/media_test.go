@@ -1,9 +1,12 @@
 package media_test
 
 import (
+	"errors"
+	"io"
 	"testing"
 	"time"
 
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v2/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
@@ -11,3 +14,47 @@ import (
 func TestNSamples(t *testing.T) {
 	assert.Equal(t, media.NSamples(20*time.Millisecond, 48000), uint32(48000*0.02))
 }
+
+type fakeRTPReader struct {
+	packets []*rtp.Packet
+	err     error
+}
+
+func (r *fakeRTPReader) ReadRTP() (*rtp.Packet, error) {
+	if len(r.packets) == 0 {
+		return nil, r.err
+	}
+
+	p := r.packets[0]
+	r.packets = r.packets[1:]
+	return p, nil
+}
+
+type fakeWriter struct {
+	packets []*rtp.Packet
+	closed  bool
+}
+
+func (w *fakeWriter) WriteRTP(p *rtp.Packet) error {
+	w.packets = append(w.packets, p)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestRecord(t *testing.T) {
+	packets := []*rtp.Packet{{Header: rtp.Header{SequenceNumber: 1}}, {Header: rtp.Header{SequenceNumber: 2}}}
+
+	w := &fakeWriter{}
+	assert.NoError(t, media.Record(&fakeRTPReader{packets: packets, err: io.EOF}, w))
+	assert.Equal(t, packets, w.packets)
+	assert.True(t, w.closed)
+
+	errRead := errors.New("read failed")
+	w = &fakeWriter{}
+	assert.Equal(t, errRead, media.Record(&fakeRTPReader{err: errRead}, w))
+	assert.True(t, w.closed)
+}
@@ -0,0 +1,531 @@
+// Package mp4writer implements fragmented MP4 (ISO-BMFF) media container writer
+package mp4writer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+const (
+	videoClockRate = 90000
+	audioClockRate = 48000
+
+	// Audio only files have no key frames to start fragments on
+	audioFragmentDuration = audioClockRate
+
+	movieTimescale = 1000
+
+	// trun sample_flags, ISO/IEC 14496-12 8.8.3.1
+	sampleFlagsSync    = 0x02000000 // sample_depends_on=2
+	sampleFlagsNonSync = 0x01010000 // sample_depends_on=1, sample_is_non_sync_sample
+
+	naluTypeIDR = 5
+	naluTypeSPS = 7
+	naluTypePPS = 8
+	naluTypeAUD = 9
+)
+
+// TrackParams describes a track of the MP4 file
+type TrackParams struct {
+	// Codec is H264 or opus, like the codec names of the MediaEngine
+	Codec string
+
+	// Width and Height of a video track
+	Width, Height int
+
+	// SampleRate and Channels of an audio track
+	SampleRate int
+	Channels   int
+}
+
+type sample struct {
+	data     []byte
+	pts      int64
+	keyFrame bool
+}
+
+type track struct {
+	id      uint32
+	params  TrackParams
+	isVideo bool
+
+	// H264 parameter sets, the init segment waits for them
+	sps, pps []byte
+
+	started       bool
+	lastTimestamp uint32
+	pts           int64 // of the last sample, from the first one of the track
+
+	pending      []sample
+	lastDuration uint32
+}
+
+// MP4Writer is used to take depacketized samples and write them to a
+// fragmented MP4 on disk, which can be played as is or served for HLS/DASH
+type MP4Writer struct {
+	stream io.Writer
+	fd     *os.File
+	tracks []*track
+
+	initWritten    bool
+	sequenceNumber uint32
+}
+
+// New builds a new MP4 writer
+func New(fileName string, tracks ...TrackParams) (*MP4Writer, error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewWith(f, tracks...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	writer.fd = f
+	return writer, nil
+}
+
+// NewWith initialize a new MP4 writer with an io.Writer output
+func NewWith(out io.Writer, tracks ...TrackParams) (*MP4Writer, error) {
+	if out == nil {
+		return nil, fmt.Errorf("file not opened")
+	} else if len(tracks) == 0 {
+		return nil, fmt.Errorf("mp4 must have at least one track")
+	}
+
+	writer := &MP4Writer{stream: out}
+	for i, params := range tracks {
+		t := &track{id: uint32(i + 1), params: params}
+		switch strings.ToLower(params.Codec) {
+		case "h264":
+			t.isVideo = true
+		case "opus":
+			if t.params.SampleRate == 0 {
+				t.params.SampleRate = audioClockRate
+			}
+			if t.params.Channels == 0 {
+				t.params.Channels = 2
+			}
+		default:
+			return nil, fmt.Errorf("mp4 does not support codec %q", params.Codec)
+		}
+		writer.tracks = append(writer.tracks, t)
+	}
+
+	return writer, nil
+}
+
+func (t *track) clockRate() uint32 {
+	if t.isVideo {
+		return videoClockRate
+	}
+	return audioClockRate
+}
+
+/*
+    ref: ISO/IEC 14496-12, ISO/IEC 14496-15 and https://opus-codec.org/docs/opus_in_isobmff.html
+
+    ftyp
+    moov  one trak per track, written once the H264 parameter sets are known
+    moof  one traf per track
+    mdat
+    moof  started on each H264 key frame
+    mdat
+    ...
+*/
+
+// WriteSample adds a sample to the track at index trackIndex of the
+// TrackParams, timestamp is its RTP timestamp, as returned by
+// SampleBuilder.PopWithTimestamp. H264 samples are Annex B, the samples
+// before the first key frame with parameter sets are dropped.
+func (w *MP4Writer) WriteSample(trackIndex int, s media.Sample, timestamp uint32) error {
+	if w.stream == nil {
+		return fmt.Errorf("file not opened")
+	} else if trackIndex < 0 || trackIndex >= len(w.tracks) {
+		return fmt.Errorf("mp4 has no track %d", trackIndex)
+	} else if len(s.Data) == 0 {
+		return nil
+	}
+
+	t := w.tracks[trackIndex]
+	data, keyFrame := s.Data, true
+	if t.isVideo {
+		data, keyFrame = t.annexBToAVCC(s.Data)
+		if len(data) == 0 {
+			return nil
+		}
+	}
+
+	if !w.initWritten {
+		if err := w.writeInit(); err != nil {
+			return err
+		} else if !w.initWritten {
+			return nil
+		}
+	}
+
+	if !t.started {
+		if !keyFrame {
+			return nil
+		}
+		t.started = true
+		t.lastTimestamp = timestamp
+	}
+
+	// RTP timestamps wrap around
+	t.pts += int64(int32(timestamp - t.lastTimestamp))
+	t.lastTimestamp = timestamp
+
+	if w.shouldFragment(t, keyFrame) {
+		if err := w.writeFragment(t, t.pts); err != nil {
+			return err
+		}
+	}
+
+	t.pending = append(t.pending, sample{data: data, pts: t.pts, keyFrame: keyFrame})
+	return nil
+}
+
+// annexBToAVCC converts a sample to length prefixed NAL units, and keeps the
+// parameter sets of its first key frame
+func (t *track) annexBToAVCC(annexB []byte) ([]byte, bool) {
+	avcc := []byte{}
+	keyFrame := false
+
+	for _, nalu := range splitAnnexB(annexB) {
+		switch nalu[0] & 0x1F {
+		case naluTypeAUD:
+			continue
+		case naluTypeSPS:
+			if t.sps == nil && len(nalu) >= 4 {
+				t.sps = append([]byte{}, nalu...)
+			}
+		case naluTypePPS:
+			if t.pps == nil {
+				t.pps = append([]byte{}, nalu...)
+			}
+		case naluTypeIDR:
+			keyFrame = true
+		}
+
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(nalu)))
+		avcc = append(append(avcc, length...), nalu...)
+	}
+
+	return avcc, keyFrame
+}
+
+func splitAnnexB(annexB []byte) [][]byte {
+	nalus := [][]byte{}
+	start := -1
+	for i := 0; i+2 < len(annexB); i++ {
+		if annexB[i] != 0 || annexB[i+1] != 0 || annexB[i+2] != 1 {
+			continue
+		}
+
+		if start >= 0 {
+			end := i
+			if end > start && annexB[end-1] == 0 {
+				end-- // 4 bytes start code
+			}
+			if end > start {
+				nalus = append(nalus, annexB[start:end])
+			}
+		}
+		start = i + 3
+		i += 2
+	}
+
+	if start >= 0 && start < len(annexB) {
+		nalus = append(nalus, annexB[start:])
+	}
+	return nalus
+}
+
+// shouldFragment tells if the sample of t starts a new fragment
+func (w *MP4Writer) shouldFragment(t *track, keyFrame bool) bool {
+	if len(t.pending) == 0 {
+		return false
+	} else if t.isVideo {
+		return keyFrame
+	}
+
+	for _, track := range w.tracks {
+		if track.isVideo {
+			return false
+		}
+	}
+	return t.pts-t.pending[0].pts >= audioFragmentDuration
+}
+
+func (w *MP4Writer) writeInit() error {
+	for _, t := range w.tracks {
+		if t.isVideo && (t.sps == nil || t.pps == nil) {
+			return nil
+		}
+	}
+
+	traks, trexs := [][]byte{}, [][]byte{}
+	for _, t := range w.tracks {
+		traks = append(traks, t.trak())
+		trexs = append(trexs, fullBox("trex", 0, 0, u32(t.id), u32(1), u32(0), u32(0), u32(0)))
+	}
+
+	mvhd := fullBox("mvhd", 0, 0,
+		u32(0), u32(0), // creation_time, modification_time
+		u32(movieTimescale), u32(0), // duration is in the fragments
+		u32(0x00010000), u16(0x0100), make([]byte, 10), // rate, volume
+		matrix(), make([]byte, 24),
+		u32(uint32(len(w.tracks)+1)), // next_track_ID
+	)
+	moov := box("moov", append(append([][]byte{mvhd}, traks...), box("mvex", trexs...))...)
+
+	ftyp := box("ftyp", []byte("isom"), u32(0x200), []byte("isomiso5iso6mp41"))
+	if err := w.write(ftyp, moov); err != nil {
+		return err
+	}
+	w.initWritten = true
+	return nil
+}
+
+func (t *track) trak() []byte {
+	handler, mediaHeader, volume := "soun", fullBox("smhd", 0, 0, u16(0), u16(0)), uint16(0x0100)
+	if t.isVideo {
+		handler, mediaHeader, volume = "vide", fullBox("vmhd", 0, 1, make([]byte, 8)), 0
+	}
+
+	return box("trak",
+		fullBox("tkhd", 0, 3, // track_enabled, track_in_movie
+			u32(0), u32(0), u32(t.id), u32(0), u32(0), // duration is in the fragments
+			make([]byte, 8), u16(0), u16(0), u16(volume), u16(0),
+			matrix(), u32(uint32(t.params.Width)<<16), u32(uint32(t.params.Height)<<16),
+		),
+		box("mdia",
+			fullBox("mdhd", 0, 0, u32(0), u32(0), u32(t.clockRate()), u32(0), u16(0x55C4), u16(0)), // und
+			fullBox("hdlr", 0, 0, u32(0), []byte(handler), make([]byte, 12), []byte("pion-webrtc\x00")),
+			box("minf",
+				mediaHeader,
+				box("dinf", fullBox("dref", 0, 0, u32(1), fullBox("url ", 0, 1))),
+				box("stbl",
+					fullBox("stsd", 0, 0, u32(1), t.sampleEntry()),
+					fullBox("stts", 0, 0, u32(0)),
+					fullBox("stsc", 0, 0, u32(0)),
+					fullBox("stsz", 0, 0, u32(0), u32(0)),
+					fullBox("stco", 0, 0, u32(0)),
+				),
+			),
+		),
+	)
+}
+
+func (t *track) sampleEntry() []byte {
+	// reserved, data_reference_index
+	header := append(make([]byte, 6), u16(1)...)
+
+	if t.isVideo {
+		avcC := box("avcC",
+			[]byte{1, t.sps[1], t.sps[2], t.sps[3], 0xFF, 0xE1}, // 4 bytes NAL unit lengths, 1 SPS
+			u16(uint16(len(t.sps))), t.sps,
+			[]byte{1}, u16(uint16(len(t.pps))), t.pps,
+		)
+		return box("avc1", header,
+			make([]byte, 16), u16(uint16(t.params.Width)), u16(uint16(t.params.Height)),
+			u32(0x00480000), u32(0x00480000), u32(0), u16(1), // 72 dpi, 1 frame per sample
+			make([]byte, 32), u16(0x0018), u16(0xFFFF), // compressorname, depth
+			avcC,
+		)
+	}
+
+	dOps := box("dOps",
+		[]byte{0, uint8(t.params.Channels)}, u16(0), u32(uint32(t.params.SampleRate)), // no pre-skip
+		u16(0), []byte{0}, // output gain, mapping family 0
+	)
+	return box("Opus", header,
+		make([]byte, 8), u16(uint16(t.params.Channels)), u16(16), u16(0), u16(0),
+		u32(audioClockRate<<16), dOps,
+	)
+}
+
+// traf are the samples of a track in a fragment
+type traf struct {
+	track      *track
+	samples    []sample
+	dts        []int64
+	durations  []uint32
+	dataOffset int
+}
+
+// writeFragment writes the pending samples of all tracks. next is the
+// presentation timestamp of the sample of trigger that starts the next
+// fragment, it gives the duration of the last one. The last sample of the
+// other tracks waits for the next fragment to know its duration.
+func (w *MP4Writer) writeFragment(trigger *track, next int64) error {
+	trafs := []*traf{}
+	for _, t := range w.tracks {
+		samples := t.pending
+		if t != trigger && trigger != nil && len(samples) != 0 {
+			samples = samples[:len(samples)-1]
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		t.pending = append([]sample{}, t.pending[len(samples):]...)
+		trafs = append(trafs, newTraf(t, samples, t == trigger, next))
+	}
+	if len(trafs) == 0 {
+		return nil
+	}
+
+	mdat := [][]byte{}
+	for _, f := range trafs {
+		for _, s := range f.samples {
+			mdat = append(mdat, s.data)
+		}
+	}
+
+	w.sequenceNumber++
+
+	// Data offsets are from the start of the moof, whose size doesn't depend on them
+	moofSize := len(w.moof(trafs))
+	offset := moofSize + 8
+	for _, f := range trafs {
+		f.dataOffset = offset
+		for _, s := range f.samples {
+			offset += len(s.data)
+		}
+	}
+
+	return w.write(w.moof(trafs), box("mdat", mdat...))
+}
+
+// newTraf computes the decode timestamps of samples, they are their sorted
+// presentation timestamps as decoding order can differ with B-frames
+func newTraf(t *track, samples []sample, hasNext bool, next int64) *traf {
+	f := &traf{track: t, samples: samples}
+	for _, s := range samples {
+		f.dts = append(f.dts, s.pts)
+	}
+	sort.Slice(f.dts, func(i, j int) bool { return f.dts[i] < f.dts[j] })
+
+	for i := range samples {
+		switch {
+		case i+1 < len(samples):
+			t.lastDuration = uint32(f.dts[i+1] - f.dts[i])
+		case hasNext:
+			t.lastDuration = uint32(next - f.dts[i])
+		case len(t.pending) != 0:
+			t.lastDuration = uint32(t.pending[0].pts - f.dts[i])
+		}
+		f.durations = append(f.durations, t.lastDuration)
+	}
+	return f
+}
+
+func (w *MP4Writer) moof(trafs []*traf) []byte {
+	children := [][]byte{fullBox("mfhd", 0, 0, u32(w.sequenceNumber))}
+	for _, f := range trafs {
+		entries := [][]byte{}
+		for i, s := range f.samples {
+			flags := uint32(sampleFlagsSync)
+			if !s.keyFrame {
+				flags = sampleFlagsNonSync
+			}
+			compositionOffset := int32(s.pts - f.dts[i])
+			entries = append(entries, u32(f.durations[i]), u32(uint32(len(s.data))), u32(flags), u32(uint32(compositionOffset)))
+		}
+
+		children = append(children, box("traf",
+			fullBox("tfhd", 0, 0x020000, u32(f.track.id)), // default-base-is-moof
+			fullBox("tfdt", 1, 0, u64(uint64(f.dts[0]))),
+			// data-offset, sample-duration, sample-size, sample-flags and
+			// sample-composition-time-offset, signed in version 1
+			fullBox("trun", 1, 0x000F01, append([][]byte{u32(uint32(len(f.samples))), u32(uint32(f.dataOffset))}, entries...)...),
+		))
+	}
+	return box("moof", children...)
+}
+
+func (w *MP4Writer) write(boxes ...[]byte) error {
+	for _, b := range boxes {
+		if _, err := w.stream.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the recording
+func (w *MP4Writer) Close() error {
+	if w.stream == nil {
+		// Returns no error as it may be convenient to call
+		// Close() multiple times
+		return nil
+	}
+
+	defer func() {
+		w.stream = nil
+		w.fd = nil
+	}()
+
+	if err := w.writeFragment(nil, 0); err != nil {
+		return err
+	}
+
+	if w.fd != nil {
+		return w.fd.Close()
+	}
+	return nil
+}
+
+func box(typ string, children ...[]byte) []byte {
+	b := &bytes.Buffer{}
+	b.Write(u32(0))
+	b.WriteString(typ)
+	for _, c := range children {
+		b.Write(c)
+	}
+
+	data := b.Bytes()
+	binary.BigEndian.PutUint32(data, uint32(len(data)))
+	return data
+}
+
+func fullBox(typ string, version uint8, flags uint32, children ...[]byte) []byte {
+	return box(typ, append([][]byte{u32(uint32(version)<<24 | flags)}, children...)...)
+}
+
+// matrix is the identity transformation matrix of mvhd and tkhd
+func matrix() []byte {
+	return bytes.Join([][]byte{
+		u32(0x00010000), u32(0), u32(0),
+		u32(0), u32(0x00010000), u32(0),
+		u32(0), u32(0), u32(0x40000000),
+	}, nil)
+}
+
+func u16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func u64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
@@ -0,0 +1,218 @@
+package mp4writer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type mp4Box struct {
+	typ    string
+	offset int // of the box in its parent
+	data   []byte
+}
+
+// parseBoxes reads the boxes of b, without descending into them
+func parseBoxes(t *testing.T, b []byte) []mp4Box {
+	boxes := []mp4Box{}
+	for i := 0; i+8 <= len(b); {
+		size := int(binary.BigEndian.Uint32(b[i:]))
+		if !assert.True(t, size >= 8 && i+size <= len(b), "box %q overflows", b[i+4:i+8]) {
+			return boxes
+		}
+		boxes = append(boxes, mp4Box{string(b[i+4 : i+8]), i, b[i+8 : i+size]})
+		i += size
+	}
+	return boxes
+}
+
+func find(boxes []mp4Box, typ string) []mp4Box {
+	found := []mp4Box{}
+	for _, b := range boxes {
+		if b.typ == typ {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+type trunSample struct {
+	duration, size, flags uint32
+	compositionOffset     int32
+}
+
+func parseTraf(t *testing.T, traf []byte) (trackID uint32, baseDecodeTime uint64, dataOffset int, samples []trunSample) {
+	boxes := parseBoxes(t, traf)
+	trackID = binary.BigEndian.Uint32(find(boxes, "tfhd")[0].data[4:])
+	baseDecodeTime = binary.BigEndian.Uint64(find(boxes, "tfdt")[0].data[4:])
+
+	trun := find(boxes, "trun")[0].data
+	count := int(binary.BigEndian.Uint32(trun[4:]))
+	dataOffset = int(int32(binary.BigEndian.Uint32(trun[8:])))
+	for i := 0; i < count; i++ {
+		entry := trun[12+16*i:]
+		samples = append(samples, trunSample{
+			binary.BigEndian.Uint32(entry),
+			binary.BigEndian.Uint32(entry[4:]),
+			binary.BigEndian.Uint32(entry[8:]),
+			int32(binary.BigEndian.Uint32(entry[12:])),
+		})
+	}
+	return
+}
+
+var (
+	sps       = []byte{0x67, 0x42, 0xC0, 0x1F, 0xDA}
+	pps       = []byte{0x68, 0xCE, 0x3C, 0x80}
+	idr       = []byte{0x65, 0x88, 0x84}
+	nonIDR    = []byte{0x41, 0x9A, 0x02}
+	startCode = []byte{0x00, 0x00, 0x00, 0x01}
+)
+
+func annexB(nalus ...[]byte) []byte {
+	return append(startCode, bytes.Join(nalus, startCode)...)
+}
+
+func TestMP4Writer(t *testing.T) {
+	_, err := NewWith(nil, TrackParams{Codec: "H264"})
+	assert.Error(t, err)
+	_, err = NewWith(&bytes.Buffer{})
+	assert.Error(t, err)
+	_, err = NewWith(&bytes.Buffer{}, TrackParams{Codec: "VP8"})
+	assert.Error(t, err)
+
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer,
+		TrackParams{Codec: "H264", Width: 640, Height: 480},
+		TrackParams{Codec: "opus"},
+	)
+	assert.NoError(t, err)
+	assert.Error(t, writer.WriteSample(2, media.Sample{Data: idr}, 0))
+
+	// Nothing is written before the H264 parameter sets
+	assert.NoError(t, writer.WriteSample(1, media.Sample{Data: []byte{0xFC}}, 100))
+	assert.NoError(t, writer.WriteSample(0, media.Sample{Data: annexB(nonIDR)}, 1000))
+	assert.Zero(t, buffer.Len())
+
+	// Two GOPs, the first one has B-frames. The RTP timestamps start close
+	// to wrapping around.
+	video := func(i uint32, nalus ...[]byte) {
+		assert.NoError(t, writer.WriteSample(0, media.Sample{Data: annexB(nalus...)}, 0xFFFFF000+i*3000))
+	}
+	audio := func(i uint32) {
+		assert.NoError(t, writer.WriteSample(1, media.Sample{Data: []byte{0xFC, byte(i)}}, 0xFFFFFF00+i*960))
+	}
+
+	video(0, sps, pps, idr)
+	audio(0)
+	video(3, nonIDR)
+	audio(1)
+	video(1, nonIDR)
+	audio(2)
+	video(2, nonIDR)
+	video(4, idr)
+	audio(3)
+	video(5, nonIDR)
+	audio(4)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, writer.Close())
+
+	boxes := parseBoxes(t, buffer.Bytes())
+	types := []string{}
+	for _, b := range boxes {
+		types = append(types, b.typ)
+	}
+	assert.Equal(t, []string{"ftyp", "moov", "moof", "mdat", "moof", "mdat"}, types)
+	if len(types) != 6 {
+		return
+	}
+
+	moov := parseBoxes(t, boxes[1].data)
+	traks := find(moov, "trak")
+	assert.Len(t, traks, 2)
+	assert.Len(t, find(moov, "mvex"), 1)
+	assert.True(t, bytes.Contains(traks[0].data, append([]byte("avcC"), 1, 0x42, 0xC0, 0x1F)))
+	assert.True(t, bytes.Contains(traks[1].data, []byte("dOps")))
+
+	type expectedTraf struct {
+		trackID        uint32
+		baseDecodeTime uint64
+		durations      []uint32
+		offsets        []int32
+	}
+	for i, expected := range [][]expectedTraf{
+		{
+			// Decode order differs from presentation order, 0 3 1 2
+			{1, 0, []uint32{3000, 3000, 3000, 3000}, []int32{0, 6000, -3000, -3000}},
+			// The last audio sample waits for the next one to know its duration
+			{2, 0, []uint32{960, 960}, []int32{0, 0}},
+		},
+		{
+			{1, 12000, []uint32{3000, 3000}, []int32{0, 0}},
+			{2, 1920, []uint32{960, 960, 960}, []int32{0, 0, 0}},
+		},
+	} {
+		moof, mdat := boxes[2+2*i], boxes[3+2*i]
+		trafs := find(parseBoxes(t, moof.data), "traf")
+		if !assert.Len(t, trafs, 2) {
+			continue
+		}
+
+		for j, traf := range trafs {
+			trackID, baseDecodeTime, dataOffset, samples := parseTraf(t, traf.data)
+			assert.Equal(t, expected[j].trackID, trackID)
+			assert.Equal(t, expected[j].baseDecodeTime, baseDecodeTime)
+
+			durations, offsets := []uint32{}, []int32{}
+			for _, s := range samples {
+				durations = append(durations, s.duration)
+				offsets = append(offsets, s.compositionOffset)
+			}
+			assert.Equal(t, expected[j].durations, durations)
+			assert.Equal(t, expected[j].offsets, offsets)
+
+			// The data offset points in the mdat, from the start of the moof
+			dataStart := dataOffset - (mdat.offset + 8 - moof.offset)
+			if trackID == 1 {
+				assert.Equal(t, uint32(sampleFlagsSync), samples[0].flags)
+				assert.Equal(t, uint32(sampleFlagsNonSync), samples[1].flags)
+				// Length prefixed NAL units
+				if i == 0 {
+					avcc := bytes.Join([][]byte{{0, 0, 0, 5}, sps, {0, 0, 0, 4}, pps, {0, 0, 0, 3}, idr}, nil)
+					assert.Equal(t, avcc, mdat.data[dataStart:dataStart+int(samples[0].size)])
+				}
+			} else {
+				assert.Equal(t, []byte{0xFC, byte(2 * i)}, mdat.data[dataStart:dataStart+2])
+			}
+		}
+	}
+}
+
+func TestSplitAnnexB(t *testing.T) {
+	assert.Equal(t, [][]byte{sps, pps, idr}, splitAnnexB(append(annexB(sps, pps), append([]byte{0x00, 0x00, 0x01}, idr...)...)))
+	assert.Equal(t, [][]byte{}, splitAnnexB([]byte{0x65}))
+}
+
+func TestMP4Writer_File(t *testing.T) {
+	fileName := os.TempDir() + "/pion-mp4writer-test.mp4"
+	writer, err := New(fileName, TrackParams{Codec: "opus"})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.Remove(fileName))
+	}()
+
+	// Audio only files start fragments every second
+	for i := uint32(0); i < 120; i++ {
+		assert.NoError(t, writer.WriteSample(0, media.Sample{Data: []byte{0xFC}}, i*960))
+	}
+	assert.NoError(t, writer.Close())
+
+	data, err := ioutil.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Len(t, find(parseBoxes(t, data), "moof"), 3)
+}
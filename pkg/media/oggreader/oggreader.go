@@ -2,16 +2,24 @@
 package oggreader
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"io"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
 )
 
 const (
 	pageHeaderTypeBeginningOfStream = 0x02
 	pageHeaderSignature             = "OggS"
 
-	idPageSignature = "OpusHead"
+	idPageSignature      = "OpusHead"
+	commentPageSignature = "OpusTags"
+
+	// Opus granule positions are always at 48kHz
+	opusClockRate = 48000
 
 	pageHeaderLen       = 27
 	idPagePayloadLength = 19
@@ -29,7 +37,7 @@ var (
 
 // OggReader is used to read Ogg files and return page payloads
 type OggReader struct {
-	stream               io.ReadSeeker
+	stream               io.Reader
 	bytesReadSuccesfully int64
 	checksumTable        *[256]uint32
 	doChecksum           bool
@@ -64,12 +72,12 @@ type OggPageHeader struct {
 }
 
 // NewWith returns a new Ogg reader and Ogg header
-// with an io.ReadSeeker input
-func NewWith(in io.ReadSeeker) (*OggReader, *OggHeader, error) {
+// with an io.Reader input, the stream is never seeked so it may be a pipe
+func NewWith(in io.Reader) (*OggReader, *OggHeader, error) {
 	return newWith(in /* doChecksum */, true)
 }
 
-func newWith(in io.ReadSeeker, doChecksum bool) (*OggReader, *OggHeader, error) {
+func newWith(in io.Reader, doChecksum bool) (*OggReader, *OggHeader, error) {
 	if in == nil {
 		return nil, nil, errNilStream
 	}
@@ -189,6 +197,35 @@ func (o *OggReader) ParseNextPage() ([]byte, *OggPageHeader, error) {
 	return payload, pageHeader, nil
 }
 
+// Play writes the remaining pages to w, like a local Track, at the pace given
+// by their granule positions. It returns nil once every page is written.
+func (o *OggReader) Play(w media.SampleWriter) error {
+	start := time.Now()
+	var lastGranule uint64
+	for {
+		payload, pageHeader, err := o.ParseNextPage()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		} else if bytes.HasPrefix(payload, []byte(commentPageSignature)) {
+			continue
+		}
+
+		// The granule position is the end of the page, it is written when it starts
+		time.Sleep(time.Until(start.Add(time.Duration(lastGranule) * time.Second / opusClockRate)))
+
+		samples := uint32(0)
+		if pageHeader.GranulePosition > lastGranule {
+			samples = uint32(pageHeader.GranulePosition - lastGranule)
+			lastGranule = pageHeader.GranulePosition
+		}
+		if err := w.WriteSample(media.Sample{Data: payload, Samples: samples}); err != nil {
+			return err
+		}
+	}
+}
+
 // ResetReader resets the internal stream of OggReader. This is useful
 // for live streams, where the end of the file might be read without the
 // data being finished.
@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"io"
 	"testing"
+	"time"
 
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/oggwriter"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -98,3 +102,40 @@ func TestOggReader_ParseErrors(t *testing.T) {
 		assert.Equal(t, err, errChecksumMismatch)
 	})
 }
+
+type sampleRecorder struct {
+	samples []media.Sample
+}
+
+func (r *sampleRecorder) WriteSample(s media.Sample) error {
+	r.samples = append(r.samples, s)
+	return nil
+}
+
+func TestOggReader_Play(t *testing.T) {
+	ogg := &bytes.Buffer{}
+	writer, err := oggwriter.NewWith(ogg, 48000, 2)
+	assert.NoError(t, err)
+
+	// 20ms pages, the first granule position starts from 1
+	for i, payload := range []byte{0xA, 0xB, 0xC} {
+		assert.NoError(t, writer.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Timestamp: 961 + uint32(i)*960},
+			Payload: []byte{payload},
+		}))
+	}
+
+	reader, _, err := NewWith(bytes.NewReader(ogg.Bytes()))
+	assert.NoError(t, err)
+
+	recorder := &sampleRecorder{}
+	start := time.Now()
+	assert.NoError(t, reader.Play(recorder))
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+
+	assert.Equal(t, []media.Sample{
+		{Data: []byte{0xA}, Samples: 961},
+		{Data: []byte{0xB}, Samples: 960},
+		{Data: []byte{0xC}, Samples: 960},
+	}, recorder.samples)
+}
@@ -0,0 +1,94 @@
+// Package pacer writes samples read faster than real time, like from a file
+// or a transcoder, to a Track at the rate they are played back
+package pacer
+
+import (
+	"context"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// DefaultMaxLag is the MaxLag of a new Pacer
+const DefaultMaxLag = time.Second
+
+// Pacer is a media.SampleWriter that holds every sample back until it is due.
+// Each sample is due once the durations of all samples before it elapsed
+// since the first one, so the time spent reading and writing the samples and
+// the inaccuracy of sleeping don't add up to a drift like sleeping the
+// duration of every sample does.
+type Pacer struct {
+	// MaxLag is how late a sample may be before the Pacer gives up catching
+	// up, when the writer was blocked, and restarts pacing from the sample.
+	// Without it the samples would be written in a burst until the schedule
+	// is met again.
+	MaxLag time.Duration
+
+	w         media.SampleWriter
+	clockRate int
+
+	started bool
+	start   time.Time
+	elapsed time.Duration
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// New creates a Pacer writing to w, a Track of a codec with clockRate
+func New(w media.SampleWriter, clockRate int) *Pacer {
+	return &Pacer{
+		MaxLag:    DefaultMaxLag,
+		w:         w,
+		clockRate: clockRate,
+		now:       time.Now,
+		sleep:     sleepContext,
+	}
+}
+
+// WriteSample waits until s is due and writes it
+func (p *Pacer) WriteSample(s media.Sample) error {
+	return p.WriteSampleContext(context.Background(), s)
+}
+
+// WriteSampleContext waits until s is due and writes it, it returns the error
+// of ctx if ctx is done first
+func (p *Pacer) WriteSampleContext(ctx context.Context, s media.Sample) error {
+	now := p.now()
+	if !p.started {
+		p.started = true
+		p.start = now
+	}
+
+	due := p.start.Add(p.elapsed)
+	if wait := due.Sub(now); wait > 0 {
+		if err := p.sleep(ctx, wait); err != nil {
+			return err
+		}
+	} else if p.MaxLag > 0 && -wait > p.MaxLag {
+		p.start = now
+		p.elapsed = 0
+	}
+
+	p.elapsed += time.Duration(s.Samples) * time.Second / time.Duration(p.clockRate)
+	return p.w.WriteSample(s)
+}
+
+// Reset restarts pacing, the next sample is written immediately. It is
+// called when the source jumps, like when a file is looped.
+func (p *Pacer) Reset() {
+	p.started = false
+	p.elapsed = 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
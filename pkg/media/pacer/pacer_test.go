@@ -0,0 +1,72 @@
+package pacer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now     time.Time
+	written []time.Duration
+}
+
+func (c *fakeClock) sleep(_ context.Context, d time.Duration) error {
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func (c *fakeClock) WriteSample(media.Sample) error {
+	c.written = append(c.written, c.now.Sub(time.Time{}))
+	return nil
+}
+
+func newFakePacer() (*Pacer, *fakeClock) {
+	c := &fakeClock{}
+	p := New(c, 1000)
+	p.now = func() time.Time { return c.now }
+	p.sleep = c.sleep
+	return p, c
+}
+
+func TestPacer(t *testing.T) {
+	p, c := newFakePacer()
+	sample := media.Sample{Samples: 20} // 20ms
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, p.WriteSample(sample))
+		// Time spent by the caller doesn't delay the following samples
+		c.now = c.now.Add(5 * time.Millisecond)
+	}
+	assert.Equal(t, []time.Duration{0, 20 * time.Millisecond, 40 * time.Millisecond}, c.written)
+
+	// A late sample is written immediately and the next one catches up
+	c.now = c.now.Add(30 * time.Millisecond)
+	assert.NoError(t, p.WriteSample(sample))
+	assert.NoError(t, p.WriteSample(sample))
+	assert.Equal(t, []time.Duration{75 * time.Millisecond, 80 * time.Millisecond}, c.written[3:])
+
+	// Too late, pacing restarts from the sample
+	c.now = c.now.Add(2 * time.Second)
+	assert.NoError(t, p.WriteSample(sample))
+	assert.NoError(t, p.WriteSample(sample))
+	assert.Equal(t, c.written[5]+20*time.Millisecond, c.written[6])
+
+	p.Reset()
+	assert.NoError(t, p.WriteSample(sample))
+	assert.Equal(t, c.written[6], c.written[7])
+}
+
+func TestPacerContext(t *testing.T) {
+	c := &fakeClock{}
+	p := New(c, 1000)
+	assert.NoError(t, p.WriteSample(media.Sample{Samples: 1000}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, p.WriteSampleContext(ctx, media.Sample{}))
+	assert.Len(t, c.written, 1)
+}
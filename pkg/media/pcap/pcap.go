@@ -0,0 +1,141 @@
+// Package pcap implements a writer for the libpcap file format, framing each
+// packet in fake IP and UDP headers so tools like Wireshark can dissect it.
+// The format is documented at https://wiki.wireshark.org/Development/LibpcapFileFormat
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	magicNumber   = 0xA1B2C3D4
+	versionMajor  = 2
+	versionMinor  = 4
+	snapLength    = 65535
+	linkTypeRaw   = 101
+	headerLen     = 24
+	recordLen     = 16
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+	udpHeaderLen  = 8
+	protocolUDP   = 17
+	defaultTTL    = 64
+)
+
+var (
+	errInvalidAddress = errors.New("pcap: source and destination must both be IPv4 or IPv6")
+	errPacketTooLarge = errors.New("pcap: packet is too large for UDP")
+)
+
+// Packet is a UDP datagram to write
+type Packet struct {
+	Time        time.Time
+	Source      net.UDPAddr
+	Destination net.UDPAddr
+	Payload     []byte
+}
+
+// Writer writes the libpcap file format
+type Writer struct {
+	writerMu sync.Mutex
+	writer   io.Writer
+}
+
+// NewWriter makes a new Writer and immediately writes the global header
+func NewWriter(w io.Writer) (*Writer, error) {
+	header := make([]byte, headerLen)
+	binary.LittleEndian.PutUint32(header[0:], magicNumber)
+	binary.LittleEndian.PutUint16(header[4:], versionMajor)
+	binary.LittleEndian.PutUint16(header[6:], versionMinor)
+	// Timezone offset and timestamp accuracy stay zero
+	binary.LittleEndian.PutUint32(header[16:], snapLength)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeRaw)
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &Writer{writer: w}, nil
+}
+
+// WritePacket writes a Packet to the output, with a microsecond timestamp
+func (w *Writer) WritePacket(p Packet) error {
+	datagram, err := marshalDatagram(p)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, recordLen, recordLen+len(datagram))
+	micros := p.Time.UnixNano() / int64(time.Microsecond)
+	binary.LittleEndian.PutUint32(record[0:], uint32(micros/1e6))
+	binary.LittleEndian.PutUint32(record[4:], uint32(micros%1e6))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(datagram)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(datagram)))
+	record = append(record, datagram...)
+
+	w.writerMu.Lock()
+	defer w.writerMu.Unlock()
+
+	_, err = w.writer.Write(record)
+	return err
+}
+
+// marshalDatagram builds the IP packet carrying p. The UDP checksum is left
+// out, which is allowed for IPv4 and accepted by Wireshark for IPv6.
+func marshalDatagram(p Packet) ([]byte, error) {
+	udpLen := udpHeaderLen + len(p.Payload)
+	if udpLen > 0xFFFF {
+		return nil, errPacketTooLarge
+	}
+
+	var ip []byte
+	src4, dst4 := p.Source.IP.To4(), p.Destination.IP.To4()
+	src16, dst16 := p.Source.IP.To16(), p.Destination.IP.To16()
+	switch {
+	case src4 != nil && dst4 != nil:
+		ip = make([]byte, ipv4HeaderLen)
+		ip[0] = 0x45 // Version 4, 5 words of header
+		binary.BigEndian.PutUint16(ip[2:], uint16(ipv4HeaderLen+udpLen))
+		ip[8] = defaultTTL
+		ip[9] = protocolUDP
+		copy(ip[12:], src4)
+		copy(ip[16:], dst4)
+		binary.BigEndian.PutUint16(ip[10:], ipv4Checksum(ip))
+	case src4 == nil && dst4 == nil && src16 != nil && dst16 != nil:
+		ip = make([]byte, ipv6HeaderLen)
+		ip[0] = 0x60 // Version 6
+		binary.BigEndian.PutUint16(ip[4:], uint16(udpLen))
+		ip[6] = protocolUDP
+		ip[7] = defaultTTL
+		copy(ip[8:], src16)
+		copy(ip[24:], dst16)
+	default:
+		return nil, errInvalidAddress
+	}
+
+	udp := make([]byte, udpHeaderLen)
+	binary.BigEndian.PutUint16(udp[0:], uint16(p.Source.Port))
+	binary.BigEndian.PutUint16(udp[2:], uint16(p.Destination.Port))
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpLen))
+
+	datagram := make([]byte, 0, len(ip)+udpLen)
+	datagram = append(datagram, ip...)
+	datagram = append(datagram, udp...)
+	return append(datagram, p.Payload...), nil
+}
+
+func ipv4Checksum(header []byte) uint16 {
+	sum := uint32(0)
+	for i := 0; i < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xFFFF {
+		sum = sum&0xFFFF + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,88 @@
+package pcap
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+
+	writer, err := NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.WritePacket(Packet{
+		Time:        time.Unix(9, 1500),
+		Source:      net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 5000},
+		Destination: net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 6000},
+		Payload:     []byte{0x80},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{
+		// global header
+		0xd4, 0xc3, 0xb2, 0xa1,
+		0x02, 0x00, 0x04, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0x00, 0x00,
+		0x65, 0x00, 0x00, 0x00,
+		// record header
+		0x09, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00,
+		0x1d, 0x00, 0x00, 0x00,
+		0x1d, 0x00, 0x00, 0x00,
+		// IPv4
+		0x45, 0x00, 0x00, 0x1d,
+		0x00, 0x00, 0x00, 0x00,
+		0x40, 0x11, 0xf9, 0x7c,
+		0xc0, 0xa8, 0x00, 0x01,
+		0xc0, 0xa8, 0x00, 0x02,
+		// UDP
+		0x13, 0x88, 0x17, 0x70,
+		0x00, 0x09, 0x00, 0x00,
+		0x80,
+	}
+
+	if got, want := buf.Bytes(), expected; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrote %x, want %x", got, want)
+	}
+}
+
+func TestWriterIPv6(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+
+	writer, err := NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.WritePacket(Packet{
+		Source:      net.UDPAddr{IP: net.IPv6loopback, Port: 1},
+		Destination: net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2},
+	}); err != errInvalidAddress {
+		t.Fatalf("got %v, want %v", err, errInvalidAddress)
+	}
+
+	if err := writer.WritePacket(Packet{
+		Source:      net.UDPAddr{IP: net.IPv6loopback, Port: 1},
+		Destination: net.UDPAddr{IP: net.IPv6loopback, Port: 2},
+		Payload:     []byte{0x80, 0x00},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	datagram := buf.Bytes()[headerLen+recordLen:]
+	if len(datagram) != ipv6HeaderLen+udpHeaderLen+2 {
+		t.Fatalf("wrote %d bytes", len(datagram))
+	}
+	if datagram[0] != 0x60 || datagram[6] != protocolUDP {
+		t.Fatalf("invalid IPv6 header %x", datagram[:ipv6HeaderLen])
+	}
+}
@@ -0,0 +1,142 @@
+package pipe
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// frameHeaderLen is the length of the header of a frame, its presentation
+// timestamp in nanoseconds and the length of its data, both big-endian
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                   presentation timestamp                      |
+//	|                                                               |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                            length                             |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                          data ...                             |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const frameHeaderLen = 12
+
+// maxFrameLen bounds the frames a FrameReader accepts, a corrupted length
+// must not allocate gigabytes
+const maxFrameLen = 16 * 1024 * 1024
+
+// FrameWriter writes frames with their presentation timestamps to a pipe,
+// the timestamp of the first frame is zero
+type FrameWriter struct {
+	w         io.Writer
+	clockRate uint32
+
+	started       bool
+	lastTimestamp uint32
+	ticks         uint64
+}
+
+// NewFrameWriter creates a FrameWriter writing to w, the RTP timestamps of
+// the media written to it advance at clockRate
+func NewFrameWriter(w io.Writer, clockRate uint32) *FrameWriter {
+	return &FrameWriter{w: w, clockRate: clockRate}
+}
+
+// WriteFrame writes a frame with its presentation timestamp
+func (f *FrameWriter) WriteFrame(data []byte, pts time.Duration) error {
+	frame := make([]byte, frameHeaderLen+len(data))
+	binary.BigEndian.PutUint64(frame, uint64(pts))
+	binary.BigEndian.PutUint32(frame[8:], uint32(len(data)))
+	copy(frame[frameHeaderLen:], data)
+
+	_, err := f.w.Write(frame)
+	return err
+}
+
+// WriteRTP writes the payload of packet as a frame, timestamped by its RTP
+// timestamp. It suits codecs with a frame per packet like Opus, video has to
+// be depacketized with a samplebuilder and written with WriteFrame.
+func (f *FrameWriter) WriteRTP(packet *rtp.Packet) error {
+	if f.clockRate == 0 {
+		return errZeroClockRate
+	}
+
+	// The timestamps are unwrapped, packets from before the last one go back
+	if f.started {
+		f.ticks += uint64(int64(int32(packet.Timestamp - f.lastTimestamp)))
+	}
+	f.started = true
+	f.lastTimestamp = packet.Timestamp
+
+	return f.WriteFrame(packet.Payload, time.Duration(f.ticks*uint64(time.Second)/uint64(f.clockRate)))
+}
+
+// Close implements media.Writer, the pipe is closed by its owner
+func (f *FrameWriter) Close() error {
+	return nil
+}
+
+// FrameReader reads the frames a FrameWriter writes, like the ones a program
+// pulls from an appsink
+type FrameReader struct {
+	r io.Reader
+}
+
+// NewFrameReader creates a FrameReader reading from r
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: r}
+}
+
+// ReadFrame returns the next frame and its presentation timestamp
+func (f *FrameReader) ReadFrame() ([]byte, time.Duration, error) {
+	header := make([]byte, frameHeaderLen)
+	if _, err := io.ReadFull(f.r, header); err != nil {
+		return nil, 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[8:])
+	if length > maxFrameLen {
+		return nil, 0, errFrameTooLarge
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(f.r, data); err != nil {
+		return nil, 0, err
+	}
+	return data, time.Duration(binary.BigEndian.Uint64(header)), nil
+}
+
+// PlayFrames writes the frames of r to w, like a local Track of a codec with
+// clockRate, until r ends. A frame lasts until the presentation timestamp
+// of the one after it, so each is written once the next one arrived.
+func PlayFrames(r *FrameReader, w media.SampleWriter, clockRate int) error {
+	var (
+		last    []byte
+		lastPTS time.Duration
+	)
+	for {
+		data, pts, err := r.ReadFrame()
+		if err == io.EOF {
+			if last == nil {
+				return nil
+			}
+			// The last frame lasts as long as nothing tells otherwise
+			return w.WriteSample(media.Sample{Data: last})
+		} else if err != nil {
+			return err
+		}
+
+		if last != nil {
+			duration := pts - lastPTS
+			if duration < 0 {
+				duration = 0
+			}
+			if err := w.WriteSample(media.Sample{Data: last, Samples: media.NSamples(duration, clockRate)}); err != nil {
+				return err
+			}
+		}
+		last, lastPTS = data, pts
+	}
+}
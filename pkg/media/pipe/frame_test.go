@@ -0,0 +1,44 @@
+package pipe
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrames(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewFrameWriter(buf, 48000)
+
+	// The timestamps wrap around
+	for _, timestamp := range []uint32{0xFFFFFC40, 0x00000000, 0x000003C0} {
+		assert.NoError(t, w.WriteRTP(&rtp.Packet{Header: rtp.Header{Timestamp: timestamp}, Payload: []byte{byte(timestamp)}}))
+	}
+	assert.NoError(t, w.Close())
+
+	r := NewFrameReader(bytes.NewReader(buf.Bytes()))
+	for i, expected := range []time.Duration{0, 20 * time.Millisecond, 40 * time.Millisecond} {
+		data, pts, err := r.ReadFrame()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, pts)
+		assert.Equal(t, []byte{[]byte{0x40, 0x00, 0xC0}[i]}, data)
+	}
+
+	recorder := &sampleRecorder{}
+	assert.NoError(t, PlayFrames(NewFrameReader(bytes.NewReader(buf.Bytes())), recorder, 48000))
+	assert.Equal(t, []media.Sample{
+		{Data: []byte{0x40}, Samples: 960},
+		{Data: []byte{0x00}, Samples: 960},
+		{Data: []byte{0xC0}},
+	}, recorder.samples)
+
+	assert.Equal(t, errZeroClockRate, NewFrameWriter(buf, 0).WriteRTP(&rtp.Packet{}))
+
+	tooLarge := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF}
+	_, _, err := NewFrameReader(bytes.NewReader(tooLarge)).ReadFrame()
+	assert.Equal(t, errFrameTooLarge, err)
+}
@@ -0,0 +1,137 @@
+package pipe
+
+import (
+	"bufio"
+	"io"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/pacer"
+)
+
+const (
+	h264ClockRate = 90000
+
+	h264NALUTypeMask       = 0x1F
+	h264FirstMBInSliceZero = 0x80
+)
+
+var annexBStartCode = []byte{0x00, 0x00, 0x00, 0x01}
+
+// H264Reader splits an Annex-B H264 stream into access units, the NAL
+// units of one picture
+type H264Reader struct {
+	r       *bufio.Reader
+	started bool
+	pending []byte
+}
+
+// NewH264Reader creates an H264Reader reading from r
+func NewH264Reader(r io.Reader) *H264Reader {
+	return &H264Reader{r: bufio.NewReader(r)}
+}
+
+// NextAccessUnit returns the NAL units of the next access unit, each
+// preceded by a start code. It returns io.EOF once the stream ended.
+func (h *H264Reader) NextAccessUnit() ([]byte, error) {
+	accessUnit := []byte{}
+	hasVCL := false
+	for {
+		nalu := h.pending
+		h.pending = nil
+		if nalu == nil {
+			var err error
+			if nalu, err = h.nextNALU(); err == io.EOF && len(accessUnit) != 0 {
+				return accessUnit, nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		// The first slice of a picture or the NAL units in front of it start
+		// the next access unit, H.264 7.4.1.2.3
+		nalType := nalu[0] & h264NALUTypeMask
+		isVCL := nalType >= 1 && nalType <= 5
+		startsAccessUnit := (isVCL && len(nalu) > 1 && nalu[1]&h264FirstMBInSliceZero != 0) ||
+			(nalType >= 6 && nalType <= 9) || (nalType >= 14 && nalType <= 18)
+		if hasVCL && startsAccessUnit {
+			h.pending = nalu
+			return accessUnit, nil
+		}
+
+		accessUnit = append(accessUnit, annexBStartCode...)
+		accessUnit = append(accessUnit, nalu...)
+		hasVCL = hasVCL || isVCL
+	}
+}
+
+// nextNALU returns the next NAL unit without its start code
+func (h *H264Reader) nextNALU() ([]byte, error) {
+	nalu := []byte{}
+	zeros := 0
+	for {
+		b, err := h.r.ReadByte()
+		if err == io.EOF && h.started && len(nalu) != 0 {
+			return nalu, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		// A start code, the zeros in front of it don't belong to the NAL unit
+		if b == 0x01 && zeros >= 2 {
+			if h.started {
+				if nalu = nalu[:len(nalu)-zeros]; len(nalu) != 0 {
+					return nalu, nil
+				}
+			}
+			h.started = true
+			zeros = 0
+			continue
+		}
+
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		if h.started {
+			nalu = append(nalu, b)
+		}
+	}
+}
+
+// PlayH264 writes the access units of the Annex-B H264 stream r to w, like
+// a local Track, until r ends. With a frameRate every access unit lasts a
+// frame and they are written at real time, so a process may produce them
+// faster. Without a frameRate, for live encoders that drop frames, an access
+// unit lasts the time since the one before it arrived.
+func PlayH264(r io.Reader, w media.SampleWriter, frameRate float64) error {
+	reader := NewH264Reader(r)
+	if frameRate > 0 {
+		w = pacer.New(w, h264ClockRate)
+	}
+
+	var last time.Time
+	for {
+		accessUnit, err := reader.NextAccessUnit()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var duration time.Duration
+		if frameRate > 0 {
+			duration = time.Duration(float64(time.Second) / frameRate)
+		} else if now := time.Now(); !last.IsZero() {
+			duration = now.Sub(last)
+			last = now
+		} else {
+			last = now
+		}
+
+		if err := w.WriteSample(media.Sample{Data: accessUnit, Samples: media.NSamples(duration, h264ClockRate)}); err != nil {
+			return err
+		}
+	}
+}
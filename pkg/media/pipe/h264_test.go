@@ -0,0 +1,65 @@
+package pipe
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type sampleRecorder struct {
+	samples []media.Sample
+}
+
+func (r *sampleRecorder) WriteSample(s media.Sample) error {
+	r.samples = append(r.samples, s)
+	return nil
+}
+
+// Leading zeros, an SPS, a PPS and an IDR slice with a three byte start
+// code, then two slices of one picture, then a slice of the next picture
+var testH264 = []byte{
+	0x00, 0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00,
+	0x00, 0x00, 0x01, 0x68, 0xCE,
+	0x00, 0x00, 0x01, 0x65, 0x88, 0x84,
+	0x00, 0x00, 0x00, 0x01, 0x41, 0x9A, 0x01,
+	0x00, 0x00, 0x00, 0x01, 0x41, 0x1A, 0x02,
+	0x00, 0x00, 0x00, 0x01, 0x41, 0x9A, 0x03,
+}
+
+func TestH264Reader(t *testing.T) {
+	r := NewH264Reader(bytes.NewReader(testH264))
+	for _, expected := range [][]byte{
+		{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x00, 0x00, 0x01, 0x68, 0xCE, 0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x84},
+		{0x00, 0x00, 0x00, 0x01, 0x41, 0x9A, 0x01, 0x00, 0x00, 0x00, 0x01, 0x41, 0x1A, 0x02},
+		{0x00, 0x00, 0x00, 0x01, 0x41, 0x9A, 0x03},
+	} {
+		accessUnit, err := r.NextAccessUnit()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, accessUnit)
+	}
+
+	_, err := r.NextAccessUnit()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestPlayH264(t *testing.T) {
+	r := &sampleRecorder{}
+	start := time.Now()
+	assert.NoError(t, PlayH264(bytes.NewReader(testH264), r, 50))
+
+	// Written at real time, the last frame goes out two frames after the first
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+	assert.Len(t, r.samples, 3)
+	for _, s := range r.samples {
+		assert.Equal(t, uint32(1800), s.Samples)
+	}
+
+	r = &sampleRecorder{}
+	assert.NoError(t, PlayH264(bytes.NewReader(testH264), r, 0))
+	assert.Len(t, r.samples, 3)
+	assert.Equal(t, uint32(0), r.samples[0].Samples)
+}
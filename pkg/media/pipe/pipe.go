@@ -0,0 +1,24 @@
+// Package pipe bridges Tracks to external encoders and decoders, like
+// FFmpeg and GStreamer, through the pipes of their processes.
+//
+// Media is written to a process in the formats they read from a pipe, H264
+// as Annex-B with h264writer and Opus as Ogg with oggwriter, see
+// media.Record. Media a process prints is played to a local Track with
+// PlayH264 or oggreader.Play, like the one of
+//
+//	ffmpeg -re -i input.mp4 -an -c:v libx264 -bsf:v h264_mp4toannexb -f h264 pipe:1
+//	ffmpeg -re -i input.mp4 -vn -c:a libopus -page_duration 20000 -f ogg pipe:1
+//
+// Other formats are exchanged as frames with their presentation timestamps,
+// see FrameWriter and FrameReader, which programs embedding GStreamer push
+// to an appsrc and pull from an appsink.
+package pipe
+
+import (
+	"errors"
+)
+
+var (
+	errFrameTooLarge = errors.New("pipe: frame is too large")
+	errZeroClockRate = errors.New("pipe: clock rate must not be zero")
+)
@@ -0,0 +1,173 @@
+// Package placeholder generates media to keep a Track sending before its
+// real source is ready, like when a call connects before the camera is
+// opened. Silence is written as precoded Opus frames, video frames are
+// generated raw and compressed by a media.Encoder, see pkg/media/vpx.
+//
+// Once the real source is ready the Generator is stopped and the source
+// writes to the same Track, or to another one swapped in with
+// RTPSender.ReplaceTrack.
+package placeholder
+
+import (
+	"io"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+const (
+	opusClockRate     = 48000
+	opusFrameDuration = 20 * time.Millisecond
+)
+
+// opusSilence is a 20ms Opus frame of silence, in CELT fullband mode
+var opusSilence = []byte{0xF8, 0xFF, 0xFE}
+
+// Generator writes placeholder samples until it is stopped
+type Generator struct {
+	closed chan struct{}
+	done   chan struct{}
+}
+
+// StartSilence writes 20ms of Opus silence to w, a Track of an Opus codec,
+// every 20ms
+func StartSilence(w media.SampleWriter) *Generator {
+	sample := media.Sample{Data: opusSilence, Samples: media.NSamples(opusFrameDuration, opusClockRate)}
+	return start(w, opusFrameDuration, func() (media.Sample, error) {
+		return sample, nil
+	})
+}
+
+// StartVideo writes frame, an I420 frame like the ones of SolidColor and
+// ColorBars, to w frameRate times a second. Every frame is compressed by
+// encoder, which is closed when the Generator stops. clockRate is the one of
+// the codec of w, 90000 for the video codecs of WebRTC.
+func StartVideo(w media.SampleWriter, encoder media.Encoder, frame []byte, frameRate float64, clockRate int) *Generator {
+	interval := time.Duration(float64(time.Second) / frameRate)
+	samples := media.NSamples(interval, clockRate)
+
+	g := start(w, interval, func() (media.Sample, error) {
+		encoded, err := encoder.Encode(frame)
+		return media.Sample{Data: encoded, Samples: samples}, err
+	})
+	go func() {
+		<-g.done
+		_ = encoder.Close()
+	}()
+	return g
+}
+
+func start(w media.SampleWriter, interval time.Duration, next func() (media.Sample, error)) *Generator {
+	g := &Generator{
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(g.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-g.closed:
+				return
+			case <-ticker.C:
+			}
+
+			sample, err := next()
+			if err != nil {
+				return
+			} else if len(sample.Data) == 0 {
+				// The encoder needs more input
+				continue
+			}
+
+			// io.ErrClosedPipe only means the Track isn't sent yet
+			if err := w.WriteSample(sample); err != nil && err != io.ErrClosedPipe {
+				return
+			}
+		}
+	}()
+	return g
+}
+
+// Stop stops writing and waits for the last write to return
+func (g *Generator) Stop() {
+	select {
+	case <-g.closed:
+	default:
+		close(g.closed)
+	}
+	<-g.done
+}
+
+// Black returns a black I420 frame of width by height pixels
+func Black(width, height int) []byte {
+	return SolidColor(width, height, 16, 128, 128)
+}
+
+// SolidColor returns an I420 frame of width by height pixels of the color
+// with the luma y and the chroma cb and cr
+func SolidColor(width, height int, y, cb, cr uint8) []byte {
+	frame := make([]byte, i420Size(width, height))
+	lumaSize := width * height
+	chromaSize := (len(frame) - lumaSize) / 2
+
+	fill(frame[:lumaSize], y)
+	fill(frame[lumaSize:lumaSize+chromaSize], cb)
+	fill(frame[lumaSize+chromaSize:], cr)
+	return frame
+}
+
+// colorBars are white, yellow, cyan, green, magenta, red, blue and black in
+// BT.601 YCbCr
+var colorBars = [][3]uint8{
+	{235, 128, 128},
+	{210, 16, 146},
+	{170, 166, 16},
+	{145, 54, 34},
+	{106, 202, 222},
+	{81, 90, 240},
+	{41, 240, 110},
+	{16, 128, 128},
+}
+
+// ColorBars returns an I420 frame of width by height pixels of eight
+// vertical color bars, a test pattern that shows whether colors and
+// orientation survive the way to the remote
+func ColorBars(width, height int) []byte {
+	frame := make([]byte, i420Size(width, height))
+	chromaWidth, chromaHeight := (width+1)/2, (height+1)/2
+	cbPlane := frame[width*height:]
+	crPlane := cbPlane[chromaWidth*chromaHeight:]
+
+	bar := func(x int) [3]uint8 {
+		return colorBars[x*len(colorBars)/width]
+	}
+	for row := 0; row < height; row++ {
+		for x := 0; x < width; x++ {
+			frame[row*width+x] = bar(x)[0]
+		}
+	}
+	for row := 0; row < chromaHeight; row++ {
+		for x := 0; x < chromaWidth; x++ {
+			color := bar(2 * x)
+			cbPlane[row*chromaWidth+x] = color[1]
+			crPlane[row*chromaWidth+x] = color[2]
+		}
+	}
+	return frame
+}
+
+func i420Size(width, height int) int {
+	chromaWidth, chromaHeight := (width+1)/2, (height+1)/2
+	return width*height + 2*chromaWidth*chromaHeight
+}
+
+func fill(b []byte, v uint8) {
+	for i := range b {
+		b[i] = v
+	}
+}
@@ -0,0 +1,91 @@
+package placeholder
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type sampleRecorder struct {
+	mu      sync.Mutex
+	samples []media.Sample
+	err     error
+}
+
+func (r *sampleRecorder) WriteSample(s media.Sample) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples = append(r.samples, s)
+	return r.err
+}
+
+func (r *sampleRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.samples)
+}
+
+type passthroughEncoder struct {
+	closed chan struct{}
+}
+
+func (e *passthroughEncoder) Encode(frame []byte) ([]byte, error) {
+	return frame[:1], nil
+}
+
+func (e *passthroughEncoder) Close() error {
+	close(e.closed)
+	return nil
+}
+
+func TestStartSilence(t *testing.T) {
+	// A Track that isn't sent yet doesn't stop the Generator
+	r := &sampleRecorder{err: io.ErrClosedPipe}
+	g := StartSilence(r)
+	for r.count() < 3 {
+		time.Sleep(opusFrameDuration)
+	}
+	g.Stop()
+	g.Stop()
+
+	count := r.count()
+	time.Sleep(2 * opusFrameDuration)
+	assert.Equal(t, count, r.count())
+	assert.Equal(t, media.Sample{Data: opusSilence, Samples: 960}, r.samples[0])
+}
+
+func TestStartVideo(t *testing.T) {
+	r := &sampleRecorder{}
+	encoder := &passthroughEncoder{closed: make(chan struct{})}
+
+	g := StartVideo(r, encoder, Black(4, 4), 100, 90000)
+	for r.count() < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	g.Stop()
+	<-encoder.closed
+
+	assert.Equal(t, media.Sample{Data: []byte{16}, Samples: 900}, r.samples[0])
+}
+
+func TestFrames(t *testing.T) {
+	// Odd sizes round the chroma planes up
+	frame := SolidColor(3, 3, 1, 2, 3)
+	assert.Equal(t, []byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3}, frame)
+
+	frame = ColorBars(16, 2)
+	assert.Equal(t, i420Size(16, 2), len(frame))
+	for x := 0; x < 16; x++ {
+		assert.Equal(t, colorBars[x/2][0], frame[x])
+		assert.Equal(t, colorBars[x/2][0], frame[16+x])
+	}
+	cb, cr := frame[32:40], frame[40:48]
+	for x := 0; x < 8; x++ {
+		assert.Equal(t, colorBars[x][1], cb[x])
+		assert.Equal(t, colorBars[x][2], cr[x])
+	}
+}
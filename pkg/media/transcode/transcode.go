@@ -0,0 +1,146 @@
+// Package transcode re-encodes the media of a remote Track to send it with
+// another codec, bitrate or resolution, like for viewers that can't take
+// the original. Run rebuilds the samples of the remote Track, passes them
+// through a Transcoder and writes the results to a local Track, translating
+// the timestamps between the clock rates of the codecs.
+package transcode
+
+import (
+	"errors"
+	"io"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/samplebuilder"
+)
+
+// defaultMaxLate is the MaxLate of a Config without one
+const defaultMaxLate = 64
+
+var errZeroClockRate = errors.New("transcode: clock rates must not be zero")
+
+// Transcoder turns the samples of one codec into the ones of another
+type Transcoder interface {
+	// Transcode returns no data when the codec needs more input for a sample
+	Transcode(sample []byte) ([]byte, error)
+	Close() error
+}
+
+// Processor changes the raw frames between decoding and encoding, like
+// scaling video or changing the volume of audio
+type Processor interface {
+	Process(frame []byte) ([]byte, error)
+}
+
+// ProcessorFunc adapts a function to a Processor
+type ProcessorFunc func(frame []byte) ([]byte, error)
+
+// Process implements Processor
+func (f ProcessorFunc) Process(frame []byte) ([]byte, error) {
+	return f(frame)
+}
+
+type pipeline struct {
+	decoder    media.Decoder
+	processors []Processor
+	encoder    media.Encoder
+}
+
+// New creates a Transcoder decoding the samples with decoder, processing the
+// frames with processors in order and encoding them with encoder. Closing it
+// closes the decoder and the encoder.
+func New(decoder media.Decoder, encoder media.Encoder, processors ...Processor) Transcoder {
+	return &pipeline{decoder: decoder, processors: processors, encoder: encoder}
+}
+
+func (p *pipeline) Transcode(sample []byte) ([]byte, error) {
+	frame, err := p.decoder.Decode(sample)
+	if err != nil || len(frame) == 0 {
+		return nil, err
+	}
+
+	for _, processor := range p.processors {
+		if frame, err = processor.Process(frame); err != nil || len(frame) == 0 {
+			return nil, err
+		}
+	}
+	return p.encoder.Encode(frame)
+}
+
+func (p *pipeline) Close() error {
+	decoderErr := p.decoder.Close()
+	if err := p.encoder.Close(); err != nil {
+		return err
+	}
+	return decoderErr
+}
+
+// Config configures Run
+type Config struct {
+	// Depacketizer and InClockRate are the ones of the codec of the remote
+	// Track, like codecs.VP8Packet and 90000
+	Depacketizer rtp.Depacketizer
+	InClockRate  uint32
+
+	// OutClockRate is the one of the codec of the local Track
+	OutClockRate uint32
+
+	// MaxLate is how many packets the samples are rebuilt across before a
+	// lost packet is given up on, 64 if zero
+	MaxLate uint16
+}
+
+// Run transcodes the samples of r, like a remote Track, with t and writes
+// them to w, like a local Track, until r ends. It returns nil when r ends
+// with io.EOF. t is not closed.
+//
+// A sample lasts until the timestamp of the input of the next one, so each
+// is written once the next one is transcoded. Samples written to a local
+// Track that isn't sent yet are dropped.
+func Run(r media.RTPReader, w media.SampleWriter, t Transcoder, config Config) error {
+	if config.InClockRate == 0 || config.OutClockRate == 0 {
+		return errZeroClockRate
+	}
+	maxLate := config.MaxLate
+	if maxLate == 0 {
+		maxLate = defaultMaxLate
+	}
+	builder := samplebuilder.New(maxLate, config.Depacketizer)
+
+	var (
+		pending          []byte
+		pendingTimestamp uint32
+	)
+	for {
+		packet, err := r.ReadRTP()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		builder.Push(packet)
+		for {
+			sample, timestamp := builder.PopWithTimestamp()
+			if sample == nil {
+				break
+			}
+
+			transcoded, err := t.Transcode(sample.Data)
+			if err != nil {
+				return err
+			} else if len(transcoded) == 0 {
+				continue
+			}
+
+			if pending != nil {
+				ticks := uint64(timestamp - pendingTimestamp)
+				out := media.Sample{Data: pending, Samples: uint32(ticks * uint64(config.OutClockRate) / uint64(config.InClockRate))}
+				if err := w.WriteSample(out); err != nil && err != io.ErrClosedPipe {
+					return err
+				}
+			}
+			pending, pendingTimestamp = transcoded, timestamp
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package transcode
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type packetSource []*rtp.Packet
+
+func (s *packetSource) ReadRTP() (*rtp.Packet, error) {
+	if len(*s) == 0 {
+		return nil, io.EOF
+	}
+	p := (*s)[0]
+	*s = (*s)[1:]
+	return p, nil
+}
+
+type sampleRecorder struct {
+	samples []media.Sample
+}
+
+func (r *sampleRecorder) WriteSample(s media.Sample) error {
+	r.samples = append(r.samples, s)
+	return io.ErrClosedPipe
+}
+
+// codec doubles every byte when decoding and adds a byte when encoding, it
+// needs two samples for the first frame
+type codec struct {
+	decoded int
+	closed  int
+}
+
+func (c *codec) Decode(sample []byte) ([]byte, error) {
+	if c.decoded++; c.decoded == 1 {
+		return nil, nil
+	}
+	return append(sample, sample...), nil
+}
+
+func (c *codec) Encode(frame []byte) ([]byte, error) {
+	return append(frame, 0xFF), nil
+}
+
+func (c *codec) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	c := &codec{}
+	tr := New(c, c, ProcessorFunc(func(frame []byte) ([]byte, error) {
+		return frame[1:], nil
+	}))
+
+	// Five 20ms Opus samples, the timestamps wrap around
+	source := &packetSource{}
+	for i := uint32(0); i < 5; i++ {
+		*source = append(*source, &rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: uint16(10 + i), Timestamp: 0xFFFFF4C0 + 960*i},
+			Payload: []byte{byte(i)},
+		})
+	}
+
+	r := &sampleRecorder{}
+	assert.NoError(t, Run(source, r, tr, Config{Depacketizer: &codecs.OpusPacket{}, InClockRate: 48000, OutClockRate: 8000}))
+	assert.NoError(t, tr.Close())
+	assert.Equal(t, 2, c.closed)
+
+	// The samples are popped once the next one is pushed and the first packet
+	// can't be told apart from the middle of a sample, the second one is
+	// swallowed by the decoder and the fourth one is transcoded but pending
+	assert.Equal(t, []media.Sample{{Data: []byte{0x02, 0xFF}, Samples: 160}}, r.samples)
+}
+
+func TestRunErrors(t *testing.T) {
+	assert.Equal(t, errZeroClockRate, Run(&packetSource{}, &sampleRecorder{}, New(&codec{}, &codec{}), Config{}))
+
+	errProcess := errors.New("process failed")
+	tr := New(&codec{decoded: 1}, &codec{}, ProcessorFunc(func([]byte) ([]byte, error) {
+		return nil, errProcess
+	}))
+	source := &packetSource{
+		{Header: rtp.Header{SequenceNumber: 10, Timestamp: 0}, Payload: []byte{1}},
+		{Header: rtp.Header{SequenceNumber: 11, Timestamp: 960}, Payload: []byte{2}},
+		{Header: rtp.Header{SequenceNumber: 12, Timestamp: 1920}, Payload: []byte{3}},
+	}
+	assert.Equal(t, errProcess, Run(source, &sampleRecorder{}, tr, Config{Depacketizer: &codecs.OpusPacket{}, InClockRate: 48000, OutClockRate: 48000}))
+}
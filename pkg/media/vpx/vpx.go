@@ -0,0 +1,273 @@
+// +build vpx
+
+// Package vpx implements media.Encoder and media.Decoder for VP8 with libvpx.
+// It needs cgo and the libvpx development files, and is only built with the
+// vpx build tag so the rest of Pion WebRTC stays pure Go. Run its tests with
+// go test -tags vpx, the VPX workflow does against the libvpx of Ubuntu.
+package vpx
+
+/*
+#cgo pkg-config: vpx
+#include <string.h>
+#include <stdlib.h>
+#include <vpx/vpx_encoder.h>
+#include <vpx/vpx_decoder.h>
+#include <vpx/vp8cx.h>
+#include <vpx/vp8dx.h>
+
+// The init functions are macros and the packet data is in a union, neither
+// can be used from Go directly
+
+static vpx_codec_err_t encoderInit(vpx_codec_ctx_t *ctx, unsigned int w, unsigned int h, unsigned int kbps, int fps, unsigned int kfMaxDist) {
+	vpx_codec_enc_cfg_t cfg;
+	vpx_codec_err_t err = vpx_codec_enc_config_default(vpx_codec_vp8_cx(), &cfg, 0);
+	if (err != VPX_CODEC_OK) {
+		return err;
+	}
+
+	cfg.g_w = w;
+	cfg.g_h = h;
+	cfg.g_timebase.num = 1;
+	cfg.g_timebase.den = fps;
+	cfg.g_lag_in_frames = 0;
+	cfg.g_error_resilient = VPX_ERROR_RESILIENT_DEFAULT;
+	cfg.rc_end_usage = VPX_CBR;
+	cfg.rc_target_bitrate = kbps;
+	cfg.kf_mode = VPX_KF_AUTO;
+	cfg.kf_max_dist = kfMaxDist;
+	return vpx_codec_enc_init(ctx, vpx_codec_vp8_cx(), &cfg, 0);
+}
+
+static vpx_codec_err_t encodeFrame(vpx_codec_ctx_t *ctx, unsigned char *frame, unsigned int w, unsigned int h, vpx_codec_pts_t pts, int forceKeyFrame) {
+	vpx_image_t img;
+	if (vpx_img_wrap(&img, VPX_IMG_FMT_I420, w, h, 1, frame) == NULL) {
+		return VPX_CODEC_INVALID_PARAM;
+	}
+	return vpx_codec_encode(ctx, &img, pts, 1, forceKeyFrame ? VPX_EFLAG_FORCE_KF : 0, VPX_DL_REALTIME);
+}
+
+// nextFrame returns the next compressed frame packet, NULL when there is none left
+static const vpx_codec_cx_pkt_t *nextFrame(vpx_codec_ctx_t *ctx, vpx_codec_iter_t *iter) {
+	const vpx_codec_cx_pkt_t *pkt;
+	while ((pkt = vpx_codec_get_cx_data(ctx, iter)) != NULL) {
+		if (pkt->kind == VPX_CODEC_CX_FRAME_PKT) {
+			return pkt;
+		}
+	}
+	return NULL;
+}
+
+static void *frameData(const vpx_codec_cx_pkt_t *pkt) { return pkt->data.frame.buf; }
+static size_t frameSize(const vpx_codec_cx_pkt_t *pkt) { return pkt->data.frame.sz; }
+
+static vpx_codec_err_t decoderInit(vpx_codec_ctx_t *ctx) {
+	return vpx_codec_dec_init(ctx, vpx_codec_vp8_dx(), NULL, 0);
+}
+
+// copyImage writes the planes of img to dst without their stride padding
+static void copyImage(const vpx_image_t *img, unsigned char *dst) {
+	int plane;
+	unsigned int row;
+	for (plane = 0; plane < 3; plane++) {
+		unsigned int w = plane == 0 ? img->d_w : (img->d_w + 1) / 2;
+		unsigned int h = plane == 0 ? img->d_h : (img->d_h + 1) / 2;
+		for (row = 0; row < h; row++) {
+			memcpy(dst, img->planes[plane] + row * img->stride[plane], w);
+			dst += w;
+		}
+	}
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+var (
+	errInvalidParams = errors.New("vpx: width, height, bit rate and frame rate must be positive")
+	errFrameSize     = errors.New("vpx: frame size doesn't match the I420 size of the encoder")
+	errClosed        = errors.New("vpx: codec is closed")
+)
+
+// i420Size is the size of a w x h I420 frame
+func i420Size(w, h int) int {
+	return w*h + 2*((w+1)/2)*((h+1)/2)
+}
+
+func codecError(ctx *C.vpx_codec_ctx_t, err C.vpx_codec_err_t) error {
+	msg := C.GoString(C.vpx_codec_err_to_string(err))
+	if detail := C.vpx_codec_error_detail(ctx); detail != nil {
+		msg += ": " + C.GoString(detail)
+	}
+	return fmt.Errorf("vpx: %s", msg)
+}
+
+// EncoderParams configures an Encoder
+type EncoderParams struct {
+	Width, Height int
+
+	// BitRate is the target in bits per second
+	BitRate int
+
+	// FrameRate is the expected number of frames per second
+	FrameRate int
+
+	// KeyFrameInterval is the maximum number of frames between two key
+	// frames, libvpx picks it when 0
+	KeyFrameInterval int
+}
+
+// Encoder compresses I420 frames into VP8 samples
+type Encoder struct {
+	mu            sync.Mutex
+	ctx           *C.vpx_codec_ctx_t
+	params        EncoderParams
+	raw           unsafe.Pointer
+	pts           C.vpx_codec_pts_t
+	forceKeyFrame bool
+}
+
+// NewVP8Encoder creates a VP8 Encoder tuned for real-time, its frames must be
+// I420 of the configured size
+func NewVP8Encoder(params EncoderParams) (*Encoder, error) {
+	if params.Width <= 0 || params.Height <= 0 || params.BitRate <= 0 || params.FrameRate <= 0 {
+		return nil, errInvalidParams
+	}
+
+	keyFrameInterval := params.KeyFrameInterval
+	if keyFrameInterval <= 0 {
+		keyFrameInterval = 9999
+	}
+
+	ctx := (*C.vpx_codec_ctx_t)(C.calloc(1, C.sizeof_vpx_codec_ctx_t))
+	if err := C.encoderInit(ctx, C.uint(params.Width), C.uint(params.Height), C.uint(params.BitRate/1000), C.int(params.FrameRate), C.uint(keyFrameInterval)); err != C.VPX_CODEC_OK {
+		defer C.free(unsafe.Pointer(ctx))
+		return nil, codecError(ctx, err)
+	}
+
+	return &Encoder{
+		ctx:    ctx,
+		params: params,
+		raw:    C.malloc(C.size_t(i420Size(params.Width, params.Height))),
+	}, nil
+}
+
+// ForceKeyFrame makes the next encoded frame a key frame, for example when
+// the remote peer sent a PLI
+func (e *Encoder) ForceKeyFrame() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.forceKeyFrame = true
+}
+
+// Encode compresses an I420 frame
+func (e *Encoder) Encode(frame []byte) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.ctx == nil {
+		return nil, errClosed
+	}
+
+	size := i420Size(e.params.Width, e.params.Height)
+	if len(frame) != size {
+		return nil, errFrameSize
+	}
+	copy((*[1 << 30]byte)(e.raw)[:size:size], frame)
+
+	forceKeyFrame := C.int(0)
+	if e.forceKeyFrame {
+		forceKeyFrame = 1
+		e.forceKeyFrame = false
+	}
+
+	if err := C.encodeFrame(e.ctx, (*C.uchar)(e.raw), C.uint(e.params.Width), C.uint(e.params.Height), e.pts, forceKeyFrame); err != C.VPX_CODEC_OK {
+		return nil, codecError(e.ctx, err)
+	}
+	e.pts++
+
+	var encoded []byte
+	var iter C.vpx_codec_iter_t
+	for pkt := C.nextFrame(e.ctx, &iter); pkt != nil; pkt = C.nextFrame(e.ctx, &iter) {
+		encoded = append(encoded, C.GoBytes(C.frameData(pkt), C.int(C.frameSize(pkt)))...)
+	}
+	return encoded, nil
+}
+
+// Close releases the libvpx encoder
+func (e *Encoder) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.ctx == nil {
+		return nil
+	}
+
+	C.vpx_codec_destroy(e.ctx)
+	C.free(unsafe.Pointer(e.ctx))
+	C.free(e.raw)
+	e.ctx = nil
+	return nil
+}
+
+// Decoder decompresses VP8 samples into I420 frames
+type Decoder struct {
+	mu  sync.Mutex
+	ctx *C.vpx_codec_ctx_t
+}
+
+// NewVP8Decoder creates a VP8 Decoder, the frame size is taken from the stream
+func NewVP8Decoder() (*Decoder, error) {
+	ctx := (*C.vpx_codec_ctx_t)(C.calloc(1, C.sizeof_vpx_codec_ctx_t))
+	if err := C.decoderInit(ctx); err != C.VPX_CODEC_OK {
+		defer C.free(unsafe.Pointer(ctx))
+		return nil, codecError(ctx, err)
+	}
+
+	return &Decoder{ctx: ctx}, nil
+}
+
+// Decode decompresses a VP8 sample into an I420 frame
+func (d *Decoder) Decode(sample []byte) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ctx == nil {
+		return nil, errClosed
+	} else if len(sample) == 0 {
+		return nil, nil
+	}
+
+	if err := C.vpx_codec_decode(d.ctx, (*C.uint8_t)(unsafe.Pointer(&sample[0])), C.uint(len(sample)), nil, 0); err != C.VPX_CODEC_OK {
+		return nil, codecError(d.ctx, err)
+	}
+
+	var iter C.vpx_codec_iter_t
+	img := C.vpx_codec_get_frame(d.ctx, &iter)
+	if img == nil {
+		return nil, nil
+	}
+
+	frame := make([]byte, i420Size(int(img.d_w), int(img.d_h)))
+	C.copyImage(img, (*C.uchar)(unsafe.Pointer(&frame[0])))
+	return frame, nil
+}
+
+// Close releases the libvpx decoder
+func (d *Decoder) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ctx == nil {
+		return nil
+	}
+
+	C.vpx_codec_destroy(d.ctx)
+	C.free(unsafe.Pointer(d.ctx))
+	d.ctx = nil
+	return nil
+}
@@ -0,0 +1,62 @@
+// +build vpx
+
+package vpx
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ media.Encoder = &Encoder{}
+	_ media.Decoder = &Decoder{}
+)
+
+func isKeyFrame(sample []byte) bool {
+	return len(sample) != 0 && sample[0]&0x01 == 0
+}
+
+func TestVP8EncodeDecode(t *testing.T) {
+	_, err := NewVP8Encoder(EncoderParams{Width: 64, Height: 48})
+	assert.Equal(t, errInvalidParams, err)
+
+	encoder, err := NewVP8Encoder(EncoderParams{Width: 64, Height: 48, BitRate: 200000, FrameRate: 30})
+	assert.NoError(t, err)
+	decoder, err := NewVP8Decoder()
+	assert.NoError(t, err)
+
+	_, err = encoder.Encode(make([]byte, 10))
+	assert.Equal(t, errFrameSize, err)
+
+	// A mid-gray frame
+	frame := make([]byte, i420Size(64, 48))
+	for i := range frame {
+		frame[i] = 0x80
+	}
+
+	for i := 0; i < 5; i++ {
+		if i == 3 {
+			encoder.ForceKeyFrame()
+		}
+
+		sample, err := encoder.Encode(frame)
+		assert.NoError(t, err)
+		assert.Equal(t, i == 0 || i == 3, isKeyFrame(sample))
+
+		decoded, err := decoder.Decode(sample)
+		assert.NoError(t, err)
+		if assert.Len(t, decoded, len(frame)) {
+			assert.InDelta(t, 0x80, decoded[len(decoded)/2], 4)
+		}
+	}
+
+	assert.NoError(t, encoder.Close())
+	assert.NoError(t, decoder.Close())
+
+	_, err = encoder.Encode(frame)
+	assert.Equal(t, errClosed, err)
+	_, err = decoder.Decode([]byte{0x00})
+	assert.Equal(t, errClosed, err)
+}
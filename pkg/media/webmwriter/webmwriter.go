@@ -0,0 +1,547 @@
+// Package webmwriter implements WebM media container writer
+package webmwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// EBML element IDs, see https://www.matroska.org/technical/elements.html
+const (
+	idEBML               = 0x1A45DFA3
+	idEBMLVersion        = 0x4286
+	idEBMLReadVersion    = 0x42F7
+	idEBMLMaxIDLength    = 0x42F2
+	idEBMLMaxSizeLength  = 0x42F3
+	idDocType            = 0x4282
+	idDocTypeVersion     = 0x4287
+	idDocTypeReadVersion = 0x4285
+	idVoid               = 0xEC
+
+	idSegment      = 0x18538067
+	idSeekHead     = 0x114D9B74
+	idSeek         = 0x4DBB
+	idSeekID       = 0x53AB
+	idSeekPosition = 0x53AC
+
+	idInfo          = 0x1549A966
+	idTimecodeScale = 0x2AD7B1
+	idDuration      = 0x4489
+	idMuxingApp     = 0x4D80
+	idWritingApp    = 0x5741
+
+	idTracks            = 0x1654AE6B
+	idTrackEntry        = 0xAE
+	idTrackNumber       = 0xD7
+	idTrackUID          = 0x73C5
+	idTrackType         = 0x83
+	idCodecID           = 0x86
+	idCodecPrivate      = 0x63A2
+	idSeekPreRoll       = 0x56BB
+	idVideo             = 0xE0
+	idPixelWidth        = 0xB0
+	idPixelHeight       = 0xBA
+	idAudio             = 0xE1
+	idSamplingFrequency = 0xB5
+	idChannels          = 0x9F
+
+	idCluster     = 0x1F43B675
+	idTimecode    = 0xE7
+	idSimpleBlock = 0xA3
+
+	idCues               = 0x1C53BB6B
+	idCuePoint           = 0xBB
+	idCueTime            = 0xB3
+	idCueTrackPositions  = 0xB7
+	idCueTrack           = 0xF7
+	idCueClusterPosition = 0xF1
+)
+
+const (
+	trackTypeVideo = 1
+	trackTypeAudio = 2
+
+	// Timecodes are in milliseconds
+	timecodeScale = 1000000
+
+	// Block timecodes are int16 offsets from their cluster's
+	maxClusterDuration = math.MaxInt16
+	// Audio only files have no key frames to start clusters on
+	audioClusterDuration = 5000
+
+	opusSeekPreRoll = 80000000 // 80ms, recommended by the WebM Opus mapping
+	muxingApp       = "pion-webrtc"
+
+	// Size of the Void elements reserved for the Duration and the Cues Seek,
+	// they are overwritten on Close when the output can seek
+	durationSize = 11
+	cuesSeekSize = 21
+
+	// Size of the Segment size, it is written unknown then updated on Close
+	segmentSizeLength = 8
+)
+
+// TrackParams describes a track of the WebM file
+type TrackParams struct {
+	// Codec is VP8, VP9 or opus, like the codec names of the MediaEngine
+	Codec string
+
+	// Width and Height of a video track
+	Width, Height int
+
+	// SampleRate and Channels of an audio track
+	SampleRate int
+	Channels   int
+}
+
+type track struct {
+	number    uint64
+	codecID   string
+	clockRate uint64
+	isVideo   bool
+
+	started   bool
+	timestamp uint64 // in clockRate units
+}
+
+type cuePoint struct {
+	time     uint64
+	track    uint64
+	position uint64
+}
+
+// WebMWriter is used to take depacketized samples and write them to a WebM on disk
+type WebMWriter struct {
+	stream io.Writer
+	fd     *os.File
+	tracks []*track
+
+	// Offset of the next byte to write, and of the elements updated on Close
+	position            uint64
+	segmentSizePosition uint64
+	segmentDataPosition uint64
+	durationPosition    uint64
+	cuesSeekPosition    uint64
+
+	cluster         *bytes.Buffer
+	clusterTimecode uint64
+	duration        uint64
+	cues            []cuePoint
+}
+
+// New builds a new WebM writer
+func New(fileName string, tracks ...TrackParams) (*WebMWriter, error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewWith(f, tracks...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	writer.fd = f
+	return writer, nil
+}
+
+// NewWith initialize a new WebM writer with an io.Writer output. The file is
+// only seekable when out is an io.WriteSeeker, as the Cues and the duration
+// are written on Close.
+func NewWith(out io.Writer, tracks ...TrackParams) (*WebMWriter, error) {
+	if out == nil {
+		return nil, fmt.Errorf("file not opened")
+	} else if len(tracks) == 0 {
+		return nil, fmt.Errorf("webm must have at least one track")
+	}
+
+	writer := &WebMWriter{stream: out}
+	for i, params := range tracks {
+		t := &track{number: uint64(i + 1)}
+		switch strings.ToLower(params.Codec) {
+		case "vp8":
+			t.codecID, t.clockRate, t.isVideo = "V_VP8", 90000, true
+		case "vp9":
+			t.codecID, t.clockRate, t.isVideo = "V_VP9", 90000, true
+		case "opus":
+			t.codecID, t.clockRate = "A_OPUS", 48000
+		default:
+			return nil, fmt.Errorf("webm does not support codec %q", params.Codec)
+		}
+		writer.tracks = append(writer.tracks, t)
+	}
+
+	if err := writer.writeHeaders(tracks); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+/*
+    ref: https://www.webmproject.org/docs/container/
+
+    EBML
+    Segment
+    +-- SeekHead  Info, Tracks and Cues positions
+    +-- Info      Duration
+    +-- Tracks
+    +-- Cluster   Timecode, SimpleBlock...
+    +-- Cluster   started on each video key frame
+    +-- ...
+    +-- Cues      position of the Clusters starting with a key frame
+*/
+
+func (w *WebMWriter) writeHeaders(params []TrackParams) error {
+	header := element(idEBML,
+		uintElement(idEBMLVersion, 1),
+		uintElement(idEBMLReadVersion, 1),
+		uintElement(idEBMLMaxIDLength, 4),
+		uintElement(idEBMLMaxSizeLength, 8),
+		element(idDocType, []byte("webm")),
+		uintElement(idDocTypeVersion, 4),
+		uintElement(idDocTypeReadVersion, 2),
+	)
+
+	// The Segment size is unknown until Close
+	header = append(header, idBytes(idSegment)...)
+	w.segmentSizePosition = uint64(len(header))
+	header = append(header, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	w.segmentDataPosition = uint64(len(header))
+
+	info := element(idInfo,
+		uintElement(idTimecodeScale, timecodeScale),
+		element(idMuxingApp, []byte(muxingApp)),
+		element(idWritingApp, []byte(muxingApp)),
+		voidElement(durationSize),
+	)
+	trackEntries := [][]byte{}
+	for i, t := range w.tracks {
+		trackEntries = append(trackEntries, trackEntry(t, params[i]))
+	}
+	tracks := element(idTracks, trackEntries...)
+
+	seekHead := element(idSeekHead,
+		seekElement(idInfo, 0),
+		seekElement(idTracks, 0),
+		voidElement(cuesSeekSize),
+	)
+	// Both Seek entries have a fixed size, they can be filled in afterwards
+	infoPosition := uint64(len(seekHead))
+	tracksPosition := infoPosition + uint64(len(info))
+	seekHead = element(idSeekHead,
+		seekElement(idInfo, infoPosition),
+		seekElement(idTracks, tracksPosition),
+		voidElement(cuesSeekSize),
+	)
+
+	w.cuesSeekPosition = w.segmentDataPosition + uint64(len(seekHead)-cuesSeekSize)
+	w.durationPosition = w.segmentDataPosition + tracksPosition - durationSize
+
+	for _, b := range [][]byte{header, seekHead, info, tracks} {
+		if err := w.write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func trackEntry(t *track, params TrackParams) []byte {
+	children := [][]byte{
+		uintElement(idTrackNumber, t.number),
+		uintElement(idTrackUID, t.number),
+		element(idCodecID, []byte(t.codecID)),
+	}
+
+	if t.isVideo {
+		children = append(children,
+			uintElement(idTrackType, trackTypeVideo),
+			element(idVideo,
+				uintElement(idPixelWidth, uint64(params.Width)),
+				uintElement(idPixelHeight, uint64(params.Height)),
+			),
+		)
+		return element(idTrackEntry, children...)
+	}
+
+	sampleRate, channels := params.SampleRate, params.Channels
+	if sampleRate == 0 {
+		sampleRate = 48000
+	}
+	if channels == 0 {
+		channels = 2
+	}
+
+	// OpusHead, without pre-skip as WebRTC Opus streams have none
+	opusHead := make([]byte, 19)
+	copy(opusHead, "OpusHead")
+	opusHead[8] = 1
+	opusHead[9] = uint8(channels)
+	binary.LittleEndian.PutUint32(opusHead[12:], uint32(sampleRate))
+
+	children = append(children,
+		uintElement(idTrackType, trackTypeAudio),
+		element(idCodecPrivate, opusHead),
+		uintElement(idSeekPreRoll, opusSeekPreRoll),
+		element(idAudio,
+			floatElement(idSamplingFrequency, float64(sampleRate)),
+			uintElement(idChannels, uint64(channels)),
+		),
+	)
+	return element(idTrackEntry, children...)
+}
+
+// WriteSample adds a sample to the track at index trackIndex of the
+// TrackParams. The first sample of every track is at the start of the file,
+// the following ones are sample.Samples after the previous one, like the
+// samples popped from a SampleBuilder.
+func (w *WebMWriter) WriteSample(trackIndex int, sample media.Sample) error {
+	if w.stream == nil {
+		return fmt.Errorf("file not opened")
+	} else if trackIndex < 0 || trackIndex >= len(w.tracks) {
+		return fmt.Errorf("webm has no track %d", trackIndex)
+	} else if len(sample.Data) == 0 {
+		return nil
+	}
+
+	t := w.tracks[trackIndex]
+	if t.started {
+		t.timestamp += uint64(sample.Samples)
+	}
+	t.started = true
+
+	timecode := t.timestamp * 1000 / t.clockRate
+	keyFrame := isKeyFrame(t, sample.Data)
+
+	if err := w.startCluster(t, timecode, keyFrame); err != nil {
+		return err
+	}
+
+	flags := byte(0)
+	if keyFrame {
+		flags |= 0x80
+	}
+	block := append(sizeBytes(t.number), 0, 0, flags)
+	binary.BigEndian.PutUint16(block[len(block)-3:], uint16(int16(int64(timecode)-int64(w.clusterTimecode))))
+	w.cluster.Write(element(idSimpleBlock, block, sample.Data))
+
+	if timecode > w.duration {
+		w.duration = timecode
+	}
+	return nil
+}
+
+// startCluster ends the current Cluster when the block of t doesn't belong in it
+func (w *WebMWriter) startCluster(t *track, timecode uint64, keyFrame bool) error {
+	hasVideo := false
+	for _, track := range w.tracks {
+		hasVideo = hasVideo || track.isVideo
+	}
+
+	if w.cluster != nil {
+		elapsed := int64(timecode) - int64(w.clusterTimecode)
+		switch {
+		case elapsed < math.MinInt16 || elapsed > maxClusterDuration:
+		case t.isVideo && keyFrame:
+		case !hasVideo && elapsed >= audioClusterDuration:
+		default:
+			return nil
+		}
+
+		if err := w.writeCluster(); err != nil {
+			return err
+		}
+	}
+
+	if (t.isVideo && keyFrame) || !hasVideo {
+		w.cues = append(w.cues, cuePoint{
+			time:     timecode,
+			track:    t.number,
+			position: w.position - w.segmentDataPosition,
+		})
+	}
+
+	w.cluster = &bytes.Buffer{}
+	w.clusterTimecode = timecode
+	w.cluster.Write(uintElement(idTimecode, timecode))
+	return nil
+}
+
+func (w *WebMWriter) writeCluster() error {
+	if w.cluster == nil {
+		return nil
+	}
+
+	cluster := element(idCluster, w.cluster.Bytes())
+	w.cluster = nil
+	return w.write(cluster)
+}
+
+func (w *WebMWriter) write(b []byte) error {
+	n, err := w.stream.Write(b)
+	w.position += uint64(n)
+	return err
+}
+
+// Close stops the recording
+func (w *WebMWriter) Close() error {
+	if w.stream == nil {
+		// Returns no error as it may be convenient to call
+		// Close() multiple times
+		return nil
+	}
+
+	defer func() {
+		w.stream = nil
+		w.fd = nil
+	}()
+
+	if err := w.writeCluster(); err != nil {
+		return err
+	}
+
+	cuesPosition := w.position - w.segmentDataPosition
+	if len(w.cues) != 0 {
+		cuePoints := [][]byte{}
+		for _, c := range w.cues {
+			cuePoints = append(cuePoints, element(idCuePoint,
+				uintElement(idCueTime, c.time),
+				element(idCueTrackPositions,
+					uintElement(idCueTrack, c.track),
+					uintElement(idCueClusterPosition, c.position),
+				),
+			))
+		}
+		if err := w.write(element(idCues, cuePoints...)); err != nil {
+			return err
+		}
+	}
+
+	if ws, ok := w.stream.(io.WriteSeeker); ok {
+		if err := w.updateHeaders(ws, cuesPosition); err != nil {
+			return err
+		}
+	}
+
+	if w.fd != nil {
+		return w.fd.Close()
+	}
+	return nil
+}
+
+// updateHeaders writes the Segment size, the Duration and the Cues Seek
+// that were unknown when the headers were written
+func (w *WebMWriter) updateHeaders(ws io.WriteSeeker, cuesPosition uint64) error {
+	patch := func(position uint64, data []byte) error {
+		if _, err := ws.Seek(int64(position), io.SeekStart); err != nil {
+			return err
+		}
+		_, err := ws.Write(data)
+		return err
+	}
+
+	if err := patch(w.segmentSizePosition, fixedSizeBytes(w.position-w.segmentDataPosition, segmentSizeLength)); err != nil {
+		return err
+	} else if err := patch(w.durationPosition, floatElement(idDuration, float64(w.duration))); err != nil {
+		return err
+	} else if len(w.cues) != 0 {
+		if err := patch(w.cuesSeekPosition, seekElement(idCues, cuesPosition)); err != nil {
+			return err
+		}
+	}
+
+	_, err := ws.Seek(0, io.SeekEnd)
+	return err
+}
+
+func isKeyFrame(t *track, data []byte) bool {
+	switch t.codecID {
+	case "V_VP8":
+		// Frame tag, https://tools.ietf.org/html/rfc6386#section-9.1
+		return data[0]&0x01 == 0
+	case "V_VP9":
+		// Uncompressed header, frame_marker then profile, show_existing_frame and frame_type
+		profile := (data[0]>>5)&0x01 | (data[0]>>3)&0x02
+		shift := uint(3)
+		if profile == 3 {
+			shift-- // reserved_zero
+		}
+		return (data[0]>>shift)&0x01 == 0 && (data[0]>>(shift-1))&0x01 == 0
+	default:
+		return true
+	}
+}
+
+// idBytes writes an element ID, its length is given by its leading bits
+func idBytes(id uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, id)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
+
+// sizeBytes writes v as the shortest EBML variable size integer
+func sizeBytes(v uint64) []byte {
+	length := 1
+	for length < 8 && v >= 1<<(7*uint(length))-1 {
+		length++
+	}
+	return fixedSizeBytes(v, length)
+}
+
+func fixedSizeBytes(v uint64, length int) []byte {
+	b := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	b[0] |= 0x80 >> uint(length-1)
+	return b
+}
+
+func element(id uint32, children ...[]byte) []byte {
+	size := 0
+	for _, c := range children {
+		size += len(c)
+	}
+
+	b := append(idBytes(id), sizeBytes(uint64(size))...)
+	for _, c := range children {
+		b = append(b, c...)
+	}
+	return b
+}
+
+func uintElement(id uint32, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return element(id, b)
+}
+
+func floatElement(id uint32, v float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return element(id, b)
+}
+
+// seekElement always has the same size, so it can replace a Void
+func seekElement(id uint32, position uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, position)
+	return element(idSeek, element(idSeekID, idBytes(id)), element(idSeekPosition, b))
+}
+
+// voidElement is a placeholder of size bytes, size must be less than 128
+func voidElement(size int) []byte {
+	return element(idVoid, make([]byte, size-2))
+}
@@ -0,0 +1,229 @@
+package webmwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// seekBuffer is an in memory io.WriteSeeker
+type seekBuffer struct {
+	data     []byte
+	position int
+}
+
+func (b *seekBuffer) Write(p []byte) (int, error) {
+	if end := b.position + len(p); end > len(b.data) {
+		b.data = append(b.data, make([]byte, end-len(b.data))...)
+	}
+	copy(b.data[b.position:], p)
+	b.position += len(p)
+	return len(p), nil
+}
+
+func (b *seekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		b.position = int(offset)
+	case io.SeekEnd:
+		b.position = len(b.data) + int(offset)
+	}
+	return int64(b.position), nil
+}
+
+type ebmlElement struct {
+	id   uint32
+	data []byte
+}
+
+func readVint(b []byte, keepMarker bool) (uint64, int) {
+	length := 1
+	for length <= 8 && b[0]&(0x80>>uint(length-1)) == 0 {
+		length++
+	}
+
+	v := uint64(b[0])
+	if !keepMarker {
+		v &= 0xFF >> uint(length)
+	}
+	for _, c := range b[1:length] {
+		v = v<<8 | uint64(c)
+	}
+	return v, length
+}
+
+// parseElements reads the elements of b, without descending into them
+func parseElements(t *testing.T, b []byte) []ebmlElement {
+	elements := []ebmlElement{}
+	for i := 0; i < len(b); {
+		id, idLength := readVint(b[i:], true)
+		size, sizeLength := readVint(b[i+idLength:], false)
+		start := i + idLength + sizeLength
+		if !assert.True(t, start+int(size) <= len(b), "element %x overflows", id) {
+			return elements
+		}
+		elements = append(elements, ebmlElement{uint32(id), b[start : start+int(size)]})
+		i = start + int(size)
+	}
+	return elements
+}
+
+func find(elements []ebmlElement, id uint32) []ebmlElement {
+	found := []ebmlElement{}
+	for _, e := range elements {
+		if e.id == id {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func readUint(b []byte) uint64 {
+	v := uint64(0)
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func TestWebMWriter(t *testing.T) {
+	_, err := NewWith(nil, TrackParams{Codec: "VP8"})
+	assert.Error(t, err)
+	_, err = NewWith(&bytes.Buffer{})
+	assert.Error(t, err)
+	_, err = NewWith(&bytes.Buffer{}, TrackParams{Codec: "H264"})
+	assert.Error(t, err)
+
+	buffer := &seekBuffer{}
+	writer, err := NewWith(buffer,
+		TrackParams{Codec: "VP8", Width: 640, Height: 480},
+		TrackParams{Codec: "opus", SampleRate: 48000, Channels: 2},
+	)
+	assert.NoError(t, err)
+
+	assert.Error(t, writer.WriteSample(2, media.Sample{Data: []byte{0x00}}))
+
+	keyFrame, interFrame := []byte{0x10, 0x02}, []byte{0x11, 0x02}
+	for i := 0; i < 60; i++ {
+		// 30fps video with a key frame every second, 20ms audio
+		frame := interFrame
+		if i%30 == 0 {
+			frame = keyFrame
+		}
+		assert.NoError(t, writer.WriteSample(0, media.Sample{Data: frame, Samples: 3000}))
+		assert.NoError(t, writer.WriteSample(1, media.Sample{Data: []byte{0xFC}, Samples: 960}))
+	}
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, writer.Close())
+	assert.Error(t, writer.WriteSample(0, media.Sample{Data: keyFrame}))
+
+	top := parseElements(t, buffer.data)
+	if !assert.Len(t, top, 2) {
+		return
+	}
+	assert.Equal(t, uint32(idEBML), top[0].id)
+	assert.Equal(t, []byte("webm"), find(parseElements(t, top[0].data), idDocType)[0].data)
+
+	// The Segment size was updated on Close
+	segment := top[1]
+	assert.Equal(t, uint32(idSegment), segment.id)
+	children := parseElements(t, segment.data)
+
+	info := parseElements(t, find(children, idInfo)[0].data)
+	duration := find(info, idDuration)
+	if assert.Len(t, duration, 1) {
+		assert.Equal(t, float64(1966), math.Float64frombits(binary.BigEndian.Uint64(duration[0].data)))
+	}
+
+	tracks := find(parseElements(t, find(children, idTracks)[0].data), idTrackEntry)
+	if assert.Len(t, tracks, 2) {
+		assert.Equal(t, []byte("V_VP8"), find(parseElements(t, tracks[0].data), idCodecID)[0].data)
+		assert.Equal(t, []byte("A_OPUS"), find(parseElements(t, tracks[1].data), idCodecID)[0].data)
+	}
+
+	// Every SeekHead entry points to its element
+	seeks := find(parseElements(t, find(children, idSeekHead)[0].data), idSeek)
+	assert.Len(t, seeks, 3)
+	for _, seek := range seeks {
+		fields := parseElements(t, seek.data)
+		id, position := readUint(find(fields, idSeekID)[0].data), readUint(find(fields, idSeekPosition)[0].data)
+		target := parseElements(t, segment.data[position:])[0]
+		assert.Equal(t, uint32(id), target.id)
+	}
+
+	// A Cluster starts on each key frame, and has a Cue
+	clusters := find(children, idCluster)
+	if assert.Len(t, clusters, 2) {
+		for i, cluster := range clusters {
+			blocks := parseElements(t, cluster.data)
+			assert.Equal(t, uint64(i*1000), readUint(find(blocks, idTimecode)[0].data))
+
+			first := find(blocks, idSimpleBlock)[0].data
+			assert.Equal(t, byte(0x81), first[0], "track 1")
+			assert.Equal(t, byte(0x80), first[3]&0x80, "key frame")
+		}
+	}
+
+	cuePoints := find(parseElements(t, find(children, idCues)[0].data), idCuePoint)
+	if assert.Len(t, cuePoints, 2) {
+		for i, cuePoint := range cuePoints {
+			fields := parseElements(t, cuePoint.data)
+			positions := parseElements(t, find(fields, idCueTrackPositions)[0].data)
+			position := readUint(find(positions, idCueClusterPosition)[0].data)
+			assert.Equal(t, clusters[i].id, parseElements(t, segment.data[position:])[0].id)
+			assert.Equal(t, uint64(i*1000), readUint(find(fields, idCueTime)[0].data))
+		}
+	}
+}
+
+func TestWebMWriter_AudioOnly(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, TrackParams{Codec: "opus"})
+	assert.NoError(t, err)
+
+	// 12 seconds, a Cluster every 5 seconds
+	for i := 0; i < 600; i++ {
+		assert.NoError(t, writer.WriteSample(0, media.Sample{Data: []byte{0xFC}, Samples: 960}))
+	}
+	assert.NoError(t, writer.Close())
+
+	// Without seeking, the Segment size stays unknown
+	data := buffer.Bytes()
+	_, idLength := readVint(data, true)
+	headerSize, sizeLength := readVint(data[idLength:], false)
+	segmentStart := idLength + sizeLength + int(headerSize)
+
+	id, _ := readVint(data[segmentStart:], true)
+	assert.Equal(t, uint64(idSegment), id)
+	size, _ := readVint(data[segmentStart+4:], false)
+	assert.Equal(t, uint64(1<<56-1), size)
+
+	children := parseElements(t, data[segmentStart+12:])
+	assert.Len(t, find(children, idCluster), 3)
+	assert.Len(t, find(parseElements(t, find(children, idCues)[0].data), idCuePoint), 3)
+}
+
+func TestWebMWriter_VP9KeyFrame(t *testing.T) {
+	vp9 := &track{codecID: "V_VP9"}
+	assert.True(t, isKeyFrame(vp9, []byte{0x82, 0x49, 0x83}))
+	assert.False(t, isKeyFrame(vp9, []byte{0x86, 0x00}))
+	assert.False(t, isKeyFrame(vp9, []byte{0x88}), "show_existing_frame")
+	assert.True(t, isKeyFrame(vp9, []byte{0xB0, 0x00}), "profile 3")
+}
+
+func TestWebMWriter_File(t *testing.T) {
+	writer, err := New(os.TempDir()+"/pion-webmwriter-test.webm", TrackParams{Codec: "VP9", Width: 320, Height: 240})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.Remove(os.TempDir()+"/pion-webmwriter-test.webm"))
+	}()
+
+	assert.NoError(t, writer.WriteSample(0, media.Sample{Data: []byte{0x82, 0x49, 0x83}}))
+	assert.NoError(t, writer.Close())
+}
@@ -0,0 +1,334 @@
+// +build !js
+
+// Package mediadevices captures local media into Tracks, like getUserMedia
+// and getDisplayMedia in the browser.
+//
+// Devices are Drivers registered with RegisterDriver. The only one shipped,
+// NewReaderDriver, reads raw frames from a pipe or a file, so any program
+// writing them (ffmpeg, arecord, a screen grabber) can be captured. Native
+// backends (V4L2, AVFoundation, DirectShow) and encoders live outside of this
+// package, so Pion WebRTC itself doesn't depend on cgo.
+package mediadevices
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/internal/util"
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+const idLength = 16
+
+var (
+	errDeviceNotFound = errors.New("no capture device matches the constraints")
+	errNoEncoder      = errors.New("constraints must have an Encoder")
+	errNoCursor       = errors.New("display capture device can't include the cursor")
+	errFrameSize      = errors.New("frame size must be positive")
+)
+
+// MediaDeviceKind is the kind of media a capture device produces
+type MediaDeviceKind int
+
+const (
+	// VideoInput is a camera
+	VideoInput MediaDeviceKind = iota + 1
+	// AudioInput is a microphone
+	AudioInput
+	// DisplayInput is a screen or a window, captured with GetDisplayMedia
+	DisplayInput
+)
+
+// MediaDeviceInfo describes a capture device
+type MediaDeviceInfo struct {
+	DeviceID string
+	Kind     MediaDeviceKind
+	Label    string
+}
+
+// Driver is a capture device, backends register one per device with RegisterDriver
+type Driver interface {
+	Info() MediaDeviceInfo
+	Open() error
+	Close() error
+
+	// ReadFrame blocks until the next raw frame and returns it with its duration
+	ReadFrame() (frame []byte, duration time.Duration, err error)
+}
+
+// CursorDriver is implemented by DisplayInput Drivers that can draw the mouse
+// cursor into the captured frames
+type CursorDriver interface {
+	Driver
+
+	// SetCursor is called before Open
+	SetCursor(include bool) error
+}
+
+// EncoderBuilder creates the Encoder compressing the raw frames of each
+// captured track, see pkg/media/vpx for a VP8 one
+type EncoderBuilder interface {
+	BuildEncoder(info MediaDeviceInfo) (media.Encoder, error)
+}
+
+var (
+	driversLock sync.RWMutex
+	drivers     []Driver
+)
+
+// RegisterDriver makes a capture device available to GetUserMedia
+func RegisterDriver(d Driver) {
+	driversLock.Lock()
+	defer driversLock.Unlock()
+	drivers = append(drivers, d)
+}
+
+// UnregisterDriver removes a capture device registered with RegisterDriver,
+// the streams already capturing from it are not closed
+func UnregisterDriver(d Driver) {
+	driversLock.Lock()
+	defer driversLock.Unlock()
+
+	for i := range drivers {
+		if drivers[i] == d {
+			drivers = append(drivers[:i:i], drivers[i+1:]...)
+			return
+		}
+	}
+}
+
+// EnumerateDevices lists the registered capture devices
+func EnumerateDevices() []MediaDeviceInfo {
+	driversLock.RLock()
+	defer driversLock.RUnlock()
+
+	infos := make([]MediaDeviceInfo, 0, len(drivers))
+	for _, d := range drivers {
+		infos = append(infos, d.Info())
+	}
+	return infos
+}
+
+// TrackConstraints selects the device and encoding of a captured track
+type TrackConstraints struct {
+	// DeviceID picks a device, the first one of the kind is used when empty
+	DeviceID string
+
+	// PayloadType is the payload type of the Encoder's codec in the MediaEngine
+	PayloadType uint8
+	Encoder     EncoderBuilder
+}
+
+// MediaStreamConstraints are the tracks requested from GetUserMedia, nil
+// skips a kind
+type MediaStreamConstraints struct {
+	Video *TrackConstraints
+	Audio *TrackConstraints
+}
+
+// DisplayMediaConstraints are the display track requested from GetDisplayMedia
+type DisplayMediaConstraints struct {
+	Video TrackConstraints
+
+	// FrameRate caps the frames per second sent, frames coming faster are
+	// dropped before being encoded. Every frame is sent when 0.
+	FrameRate float64
+
+	// Cursor includes the mouse cursor in the frames, the Driver must be a CursorDriver
+	Cursor bool
+}
+
+// MediaStream is a set of Tracks captured from local devices
+type MediaStream struct {
+	ID     string
+	Tracks []*webrtc.Track
+
+	captures []*capture
+}
+
+// Close stops capturing and closes the devices and encoders
+func (s *MediaStream) Close() error {
+	var closeErr error
+	for _, c := range s.captures {
+		if err := c.close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
+
+// GetUserMedia opens the devices matching constraints and returns a stream
+// whose Tracks can be given to pc.AddTrack. Frames are encoded and written
+// to the Tracks until the stream is closed.
+func GetUserMedia(pc *webrtc.PeerConnection, constraints MediaStreamConstraints) (*MediaStream, error) {
+	stream := &MediaStream{ID: util.MathRandAlpha(idLength)}
+
+	for _, c := range []struct {
+		kind        MediaDeviceKind
+		constraints *TrackConstraints
+	}{
+		{VideoInput, constraints.Video},
+		{AudioInput, constraints.Audio},
+	} {
+		if c.constraints == nil {
+			continue
+		}
+
+		capture, err := startCapture(pc, stream.ID, findDriver(c.kind, c.constraints.DeviceID), c.constraints, 0)
+		if err != nil {
+			_ = stream.Close()
+			return nil, err
+		}
+		stream.captures = append(stream.captures, capture)
+		stream.Tracks = append(stream.Tracks, capture.track)
+	}
+
+	return stream, nil
+}
+
+// GetDisplayMedia opens the screen or window matching constraints and returns
+// a stream with its video Track, like GetUserMedia does for cameras.
+func GetDisplayMedia(pc *webrtc.PeerConnection, constraints DisplayMediaConstraints) (*MediaStream, error) {
+	stream := &MediaStream{ID: util.MathRandAlpha(idLength)}
+
+	driver := findDriver(DisplayInput, constraints.Video.DeviceID)
+	if cursorDriver, ok := driver.(CursorDriver); ok {
+		if err := cursorDriver.SetCursor(constraints.Cursor); err != nil {
+			return nil, err
+		}
+	} else if driver != nil && constraints.Cursor {
+		return nil, errNoCursor
+	}
+
+	var frameInterval time.Duration
+	if constraints.FrameRate > 0 {
+		frameInterval = time.Duration(float64(time.Second) / constraints.FrameRate)
+	}
+
+	capture, err := startCapture(pc, stream.ID, driver, &constraints.Video, frameInterval)
+	if err != nil {
+		return nil, err
+	}
+	stream.captures = append(stream.captures, capture)
+	stream.Tracks = append(stream.Tracks, capture.track)
+
+	return stream, nil
+}
+
+func findDriver(kind MediaDeviceKind, deviceID string) Driver {
+	driversLock.RLock()
+	defer driversLock.RUnlock()
+
+	for _, d := range drivers {
+		if info := d.Info(); info.Kind == kind && (deviceID == "" || info.DeviceID == deviceID) {
+			return d
+		}
+	}
+	return nil
+}
+
+// capture pumps the frames of a Driver through an Encoder into a Track
+type capture struct {
+	driver  Driver
+	encoder media.Encoder
+	track   *webrtc.Track
+
+	// frames closer than frameInterval to the last sent one are dropped
+	frameInterval time.Duration
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      chan struct{}
+}
+
+func startCapture(pc *webrtc.PeerConnection, streamID string, driver Driver, constraints *TrackConstraints, frameInterval time.Duration) (*capture, error) {
+	if constraints.Encoder == nil {
+		return nil, errNoEncoder
+	}
+
+	if driver == nil {
+		return nil, errDeviceNotFound
+	}
+
+	track, err := pc.NewTrack(constraints.PayloadType, util.RandUint32(), util.MathRandAlpha(idLength), streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := constraints.Encoder.BuildEncoder(driver.Info())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := driver.Open(); err != nil {
+		_ = encoder.Close()
+		return nil, err
+	}
+
+	c := &capture{
+		driver:        driver,
+		encoder:       encoder,
+		track:         track,
+		frameInterval: frameInterval,
+		closed:        make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go c.run()
+	return c, nil
+}
+
+func (c *capture) run() {
+	defer close(c.done)
+
+	clockRate := int(c.track.Codec().ClockRate)
+
+	// elapsed is the time covered since the last sent frame, so dropped
+	// frames are accounted for in the timestamps
+	var elapsed time.Duration
+	for {
+		frame, duration, err := c.driver.ReadFrame()
+		if err != nil {
+			return
+		}
+
+		select {
+		case <-c.closed:
+			return
+		default:
+		}
+
+		if elapsed += duration; elapsed < c.frameInterval {
+			continue
+		}
+
+		encoded, err := c.encoder.Encode(frame)
+		if err != nil || len(encoded) == 0 {
+			// Drop the frame, the encoder may need more input
+			continue
+		}
+
+		// io.ErrClosedPipe only means the Track isn't sent yet
+		if err := c.track.WriteSample(media.Sample{Data: encoded, Samples: media.NSamples(elapsed, clockRate)}); err != nil && err != io.ErrClosedPipe {
+			return
+		}
+		elapsed = 0
+	}
+}
+
+func (c *capture) close() (err error) {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+
+		// Closing the device unblocks ReadFrame
+		err = c.driver.Close()
+		<-c.done
+
+		if encoderErr := c.encoder.Close(); err == nil {
+			err = encoderErr
+		}
+	})
+	return err
+}
@@ -0,0 +1,181 @@
+// +build !js
+
+package mediadevices
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/internal/util"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver produces a frame every millisecond until closed
+type fakeDriver struct {
+	info   MediaDeviceInfo
+	opened bool
+	frames int
+	closed chan struct{}
+}
+
+func (d *fakeDriver) Info() MediaDeviceInfo { return d.info }
+
+func (d *fakeDriver) Open() error {
+	d.opened = true
+	d.closed = make(chan struct{})
+	return nil
+}
+
+func (d *fakeDriver) Close() error {
+	close(d.closed)
+	return nil
+}
+
+func (d *fakeDriver) ReadFrame() ([]byte, time.Duration, error) {
+	select {
+	case <-d.closed:
+		return nil, 0, io.EOF
+	case <-time.After(time.Millisecond):
+		d.frames++
+		return []byte{0x01, 0x02}, time.Millisecond, nil
+	}
+}
+
+type fakeDisplayDriver struct {
+	fakeDriver
+	cursor bool
+}
+
+func (d *fakeDisplayDriver) SetCursor(include bool) error {
+	d.cursor = include
+	return nil
+}
+
+type fakeEncoder struct {
+	encoded int
+	closed  bool
+}
+
+func (e *fakeEncoder) Encode(frame []byte) ([]byte, error) {
+	e.encoded++
+	return frame, nil
+}
+
+func (e *fakeEncoder) Close() error {
+	e.closed = true
+	return nil
+}
+
+type fakeEncoderBuilder struct {
+	encoder *fakeEncoder
+}
+
+func (b *fakeEncoderBuilder) BuildEncoder(MediaDeviceInfo) (media.Encoder, error) {
+	return b.encoder, nil
+}
+
+func TestGetUserMedia(t *testing.T) {
+	camera := &fakeDriver{info: MediaDeviceInfo{DeviceID: util.MathRandAlpha(idLength), Kind: VideoInput, Label: "Fake Camera"}}
+	RegisterDriver(camera)
+	defer UnregisterDriver(camera)
+	assert.Contains(t, EnumerateDevices(), camera.info)
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	_, err = GetUserMedia(pc, MediaStreamConstraints{Audio: &TrackConstraints{Encoder: &fakeEncoderBuilder{}}})
+	assert.Equal(t, errDeviceNotFound, err)
+
+	_, err = GetUserMedia(pc, MediaStreamConstraints{Video: &TrackConstraints{}})
+	assert.Equal(t, errNoEncoder, err)
+
+	encoder := &fakeEncoder{}
+	stream, err := GetUserMedia(pc, MediaStreamConstraints{
+		Video: &TrackConstraints{
+			DeviceID:    camera.info.DeviceID,
+			PayloadType: webrtc.DefaultPayloadTypeVP8,
+			Encoder:     &fakeEncoderBuilder{encoder},
+		},
+	})
+	assert.NoError(t, err)
+	assert.True(t, camera.opened)
+
+	if assert.Len(t, stream.Tracks, 1) {
+		assert.Equal(t, stream.ID, stream.Tracks[0].Label())
+		assert.Equal(t, webrtc.RTPCodecTypeVideo, stream.Tracks[0].Kind())
+
+		_, err = pc.AddTrack(stream.Tracks[0])
+		assert.NoError(t, err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, stream.Close())
+	assert.True(t, encoder.closed)
+	assert.NotZero(t, encoder.encoded)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestGetDisplayMedia(t *testing.T) {
+	window := &fakeDriver{info: MediaDeviceInfo{DeviceID: util.MathRandAlpha(idLength), Kind: DisplayInput, Label: "Fake Window"}}
+	RegisterDriver(window)
+	defer UnregisterDriver(window)
+	screen := &fakeDisplayDriver{fakeDriver: fakeDriver{info: MediaDeviceInfo{DeviceID: util.MathRandAlpha(idLength), Kind: DisplayInput, Label: "Fake Screen"}}}
+	RegisterDriver(screen)
+	defer UnregisterDriver(screen)
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	_, err = GetDisplayMedia(pc, DisplayMediaConstraints{
+		Video:  TrackConstraints{DeviceID: window.info.DeviceID, Encoder: &fakeEncoderBuilder{}},
+		Cursor: true,
+	})
+	assert.Equal(t, errNoCursor, err)
+	assert.False(t, window.opened)
+
+	encoder := &fakeEncoder{}
+	stream, err := GetDisplayMedia(pc, DisplayMediaConstraints{
+		Video: TrackConstraints{
+			DeviceID:    screen.info.DeviceID,
+			PayloadType: webrtc.DefaultPayloadTypeVP8,
+			Encoder:     &fakeEncoderBuilder{encoder},
+		},
+		FrameRate: 250,
+		Cursor:    true,
+	})
+	assert.NoError(t, err)
+	assert.True(t, screen.opened)
+	assert.True(t, screen.cursor)
+
+	if assert.Len(t, stream.Tracks, 1) {
+		assert.Equal(t, webrtc.RTPCodecTypeVideo, stream.Tracks[0].Kind())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, stream.Close())
+
+	// The driver produces a frame every millisecond, only one out of four is sent
+	assert.NotZero(t, encoder.encoded)
+	assert.True(t, encoder.encoded <= screen.frames/4)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestUnregisterDriver(t *testing.T) {
+	first := &fakeDriver{info: MediaDeviceInfo{DeviceID: util.MathRandAlpha(idLength), Kind: AudioInput}}
+	second := &fakeDriver{info: MediaDeviceInfo{DeviceID: util.MathRandAlpha(idLength), Kind: AudioInput}}
+	RegisterDriver(first)
+	RegisterDriver(second)
+
+	UnregisterDriver(first)
+	assert.NotContains(t, EnumerateDevices(), first.info)
+	assert.Contains(t, EnumerateDevices(), second.info)
+	assert.Equal(t, second, findDriver(AudioInput, ""))
+
+	UnregisterDriver(second)
+	assert.Nil(t, findDriver(AudioInput, second.info.DeviceID))
+}
@@ -0,0 +1,54 @@
+// +build !js
+
+package mediadevices
+
+import (
+	"io"
+	"time"
+)
+
+// readerDriver captures the raw frames read from an io.ReadCloser
+type readerDriver struct {
+	info          MediaDeviceInfo
+	reader        io.ReadCloser
+	frameSize     int
+	frameDuration time.Duration
+}
+
+// NewReaderDriver creates a Driver reading frames of frameSize bytes from
+// reader, each lasting frameDuration. The frames are read as they come, the
+// writer sets the pace, like ffmpeg or arecord writing to a named pipe.
+// Closing the Driver closes reader.
+func NewReaderDriver(info MediaDeviceInfo, reader io.ReadCloser, frameSize int, frameDuration time.Duration) Driver {
+	return &readerDriver{
+		info:          info,
+		reader:        reader,
+		frameSize:     frameSize,
+		frameDuration: frameDuration,
+	}
+}
+
+func (d *readerDriver) Info() MediaDeviceInfo {
+	return d.info
+}
+
+func (d *readerDriver) Open() error {
+	if d.frameSize <= 0 {
+		return errFrameSize
+	}
+	return nil
+}
+
+func (d *readerDriver) Close() error {
+	return d.reader.Close()
+}
+
+// ReadFrame returns io.EOF once reader ends, io.ErrUnexpectedEOF if it ends
+// inside a frame
+func (d *readerDriver) ReadFrame() ([]byte, time.Duration, error) {
+	frame := make([]byte, d.frameSize)
+	if _, err := io.ReadFull(d.reader, frame); err != nil {
+		return nil, 0, err
+	}
+	return frame, d.frameDuration, nil
+}
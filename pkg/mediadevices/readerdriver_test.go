@@ -0,0 +1,80 @@
+// +build !js
+
+package mediadevices
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderDriver(t *testing.T) {
+	reader, writer := io.Pipe()
+	info := MediaDeviceInfo{DeviceID: util.MathRandAlpha(idLength), Kind: AudioInput, Label: "Pipe"}
+	driver := NewReaderDriver(info, reader, 4, 20*time.Millisecond)
+	assert.Equal(t, info, driver.Info())
+	assert.NoError(t, driver.Open())
+
+	go func() {
+		// Frames don't have to be written in one piece
+		_, _ = writer.Write([]byte{1, 2, 3, 4, 5})
+		_, _ = writer.Write([]byte{6, 7, 8})
+		_, _ = writer.Write([]byte{9})
+		_ = writer.Close()
+	}()
+
+	frame, duration, err := driver.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4}, frame)
+	assert.Equal(t, 20*time.Millisecond, duration)
+
+	frame, _, err = driver.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{5, 6, 7, 8}, frame)
+
+	_, _, err = driver.ReadFrame()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.NoError(t, driver.Close())
+
+	assert.Equal(t, errFrameSize, NewReaderDriver(info, reader, 0, time.Millisecond).Open())
+}
+
+func TestReaderDriverGetUserMedia(t *testing.T) {
+	reader, writer := io.Pipe()
+	microphone := NewReaderDriver(MediaDeviceInfo{DeviceID: util.MathRandAlpha(idLength), Kind: AudioInput}, reader, 2, 20*time.Millisecond)
+	RegisterDriver(microphone)
+	defer UnregisterDriver(microphone)
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	encoder := &fakeEncoder{}
+	stream, err := GetUserMedia(pc, MediaStreamConstraints{
+		Audio: &TrackConstraints{
+			DeviceID:    microphone.Info().DeviceID,
+			PayloadType: webrtc.DefaultPayloadTypeOpus,
+			Encoder:     &fakeEncoderBuilder{encoder},
+		},
+	})
+	assert.NoError(t, err)
+
+	// The pipe blocks until the capture reads each frame
+	for i := 0; i < 3; i++ {
+		_, err = writer.Write([]byte{0x01, 0x02})
+		assert.NoError(t, err)
+	}
+
+	// Closing the stream closes the pipe, unblocking the capture
+	assert.NoError(t, stream.Close())
+	assert.True(t, encoder.closed)
+	assert.True(t, encoder.encoded >= 2)
+
+	_, err = writer.Write([]byte{0x01, 0x02})
+	assert.Equal(t, io.ErrClosedPipe, err)
+
+	assert.NoError(t, pc.Close())
+}
@@ -0,0 +1,236 @@
+// +build !js
+
+// Package metrics exports the statistics of PeerConnections in the
+// Prometheus text exposition format, so they can be scraped without a
+// GetStats polling loop in the application. Every connection is added under
+// a label, which becomes the "label" of all of its series.
+//
+// Transport counters and round trip time are read from GetStats on every
+// scrape. NACK, PLI and packet loss come from RTCP, and ICE state transitions
+// from the state change handler, both of which the application wires to the
+// Connection returned by Add:
+//
+//	c := exporter.Add("viewer-1", pc)
+//	pc.OnICEConnectionStateChange(c.ObserveICEConnectionState)
+//	sender.OnRTCP(webrtc.RTCPHandlers{OnPacket: c.ObserveRTCP})
+//	http.Handle("/metrics", exporter)
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v2"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter serves the metrics of the PeerConnections added to it
+type Exporter struct {
+	mu    sync.Mutex
+	conns map[string]*Connection
+}
+
+// NewExporter creates an Exporter without any PeerConnection
+func NewExporter() *Exporter {
+	return &Exporter{conns: map[string]*Connection{}}
+}
+
+// Add starts exporting the metrics of pc under label, replacing the
+// connection previously added with the same label.
+func (e *Exporter) Add(label string, pc *webrtc.PeerConnection) *Connection {
+	c := &Connection{
+		label:          label,
+		pc:             pc,
+		iceTransitions: map[webrtc.ICEConnectionState]uint64{},
+		packetsLost:    map[uint32]uint32{},
+	}
+
+	e.mu.Lock()
+	e.conns[label] = c
+	e.mu.Unlock()
+	return c
+}
+
+// Remove stops exporting the metrics of the connection added under label
+func (e *Exporter) Remove(label string) {
+	e.mu.Lock()
+	delete(e.conns, label)
+	e.mu.Unlock()
+}
+
+// ServeHTTP writes the current metrics of all connections
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_, _ = e.WriteTo(w)
+}
+
+// WriteTo writes the current metrics of all connections to w
+func (e *Exporter) WriteTo(w io.Writer) (int64, error) {
+	e.mu.Lock()
+	conns := make([]*Connection, 0, len(e.conns))
+	for _, c := range e.conns {
+		conns = append(conns, c)
+	}
+	e.mu.Unlock()
+	sort.Slice(conns, func(i, j int) bool { return conns[i].label < conns[j].label })
+
+	samples := make([]sample, 0, len(conns))
+	for _, c := range conns {
+		samples = append(samples, c.sample())
+	}
+
+	b := &strings.Builder{}
+	writeFamily(b, "webrtc_peer_connections", "gauge", "Number of exported peer connections.")
+	fmt.Fprintf(b, "webrtc_peer_connections %d\n", len(samples))
+
+	writeFamily(b, "webrtc_ice_connection_state", "gauge", "Current ICE connection state, 1 for the state the connection is in.")
+	for _, s := range samples {
+		fmt.Fprintf(b, "webrtc_ice_connection_state{label=%q,state=%q} 1\n", s.label, s.iceState)
+	}
+
+	writeFamily(b, "webrtc_ice_state_transitions_total", "counter", "ICE connection state changes by the state entered.")
+	for _, s := range samples {
+		for _, t := range s.iceTransitions {
+			fmt.Fprintf(b, "webrtc_ice_state_transitions_total{label=%q,state=%q} %d\n", s.label, t.state, t.count)
+		}
+	}
+
+	writeFamily(b, "webrtc_packets_total", "counter", "Packets on the selected candidate pair by direction.")
+	for _, s := range samples {
+		fmt.Fprintf(b, "webrtc_packets_total{label=%q,direction=\"sent\"} %d\n", s.label, s.packetsSent)
+		fmt.Fprintf(b, "webrtc_packets_total{label=%q,direction=\"received\"} %d\n", s.label, s.packetsReceived)
+	}
+
+	writeFamily(b, "webrtc_bytes_total", "counter", "Bytes on the ICE transport by direction.")
+	for _, s := range samples {
+		fmt.Fprintf(b, "webrtc_bytes_total{label=%q,direction=\"sent\"} %d\n", s.label, s.bytesSent)
+		fmt.Fprintf(b, "webrtc_bytes_total{label=%q,direction=\"received\"} %d\n", s.label, s.bytesReceived)
+	}
+
+	writeFamily(b, "webrtc_round_trip_time_seconds", "gauge", "Latest round trip time of the selected candidate pair.")
+	for _, s := range samples {
+		fmt.Fprintf(b, "webrtc_round_trip_time_seconds{label=%q} %g\n", s.label, s.roundTripTime)
+	}
+
+	writeFamily(b, "webrtc_packets_lost_total", "counter", "RTP packets lost as reported by RTCP reception reports.")
+	for _, s := range samples {
+		fmt.Fprintf(b, "webrtc_packets_lost_total{label=%q} %d\n", s.label, s.packetsLost)
+	}
+
+	writeFamily(b, "webrtc_nack_total", "counter", "RTCP NACK packets observed.")
+	for _, s := range samples {
+		fmt.Fprintf(b, "webrtc_nack_total{label=%q} %d\n", s.label, s.nacks)
+	}
+
+	writeFamily(b, "webrtc_pli_total", "counter", "RTCP PLI packets observed.")
+	for _, s := range samples {
+		fmt.Fprintf(b, "webrtc_pli_total{label=%q} %d\n", s.label, s.plis)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeFamily(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Connection holds the metrics of one exported PeerConnection
+type Connection struct {
+	label string
+	pc    *webrtc.PeerConnection
+
+	mu             sync.Mutex
+	iceTransitions map[webrtc.ICEConnectionState]uint64
+	packetsLost    map[uint32]uint32 // cumulative loss by media SSRC
+	nacks          uint64
+	plis           uint64
+}
+
+// ObserveICEConnectionState counts a change of the ICE connection state, it
+// can be passed to PeerConnection.OnICEConnectionStateChange as is.
+func (c *Connection) ObserveICEConnectionState(state webrtc.ICEConnectionState) {
+	c.mu.Lock()
+	c.iceTransitions[state]++
+	c.mu.Unlock()
+}
+
+// ObserveRTCP counts the NACKs, PLIs and reported losses of an RTCP packet,
+// it can be used as the OnPacket handler of webrtc.RTCPHandlers.
+func (c *Connection) ObserveRTCP(pkt rtcp.Packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch p := pkt.(type) {
+	case *rtcp.TransportLayerNack:
+		c.nacks++
+	case *rtcp.PictureLossIndication:
+		c.plis++
+	case *rtcp.SenderReport:
+		c.observeReports(p.Reports)
+	case *rtcp.ReceiverReport:
+		c.observeReports(p.Reports)
+	}
+}
+
+// observeReports keeps the cumulative loss of every SSRC, c.mu must be held
+func (c *Connection) observeReports(reports []rtcp.ReceptionReport) {
+	for _, r := range reports {
+		c.packetsLost[r.SSRC] = r.TotalLost
+	}
+}
+
+type iceTransition struct {
+	state webrtc.ICEConnectionState
+	count uint64
+}
+
+type sample struct {
+	label          string
+	iceState       webrtc.ICEConnectionState
+	iceTransitions []iceTransition
+
+	packetsSent, packetsReceived uint32
+	bytesSent, bytesReceived     uint64
+	roundTripTime                float64
+
+	packetsLost uint64
+	nacks, plis uint64
+}
+
+func (c *Connection) sample() sample {
+	s := sample{label: c.label, iceState: c.pc.ICEConnectionState()}
+
+	for _, stats := range c.pc.GetStats() {
+		switch stats := stats.(type) {
+		case webrtc.TransportStats:
+			s.bytesSent += stats.BytesSent
+			s.bytesReceived += stats.BytesReceived
+		case webrtc.ICECandidatePairStats:
+			if stats.Nominated {
+				s.packetsSent += stats.PacketsSent
+				s.packetsReceived += stats.PacketsReceived
+				s.roundTripTime = stats.CurrentRoundTripTime
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for state, count := range c.iceTransitions {
+		s.iceTransitions = append(s.iceTransitions, iceTransition{state, count})
+	}
+	sort.Slice(s.iceTransitions, func(i, j int) bool { return s.iceTransitions[i].state < s.iceTransitions[j].state })
+	for _, lost := range c.packetsLost {
+		s.packetsLost += uint64(lost)
+	}
+	s.nacks, s.plis = c.nacks, c.plis
+	return s
+}
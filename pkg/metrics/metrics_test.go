@@ -0,0 +1,56 @@
+// +build !js
+
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporter(t *testing.T) {
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	e := NewExporter()
+	c := e.Add("viewer", pc)
+	c.ObserveICEConnectionState(webrtc.ICEConnectionStateChecking)
+	c.ObserveICEConnectionState(webrtc.ICEConnectionStateConnected)
+	c.ObserveRTCP(&rtcp.TransportLayerNack{})
+	c.ObserveRTCP(&rtcp.TransportLayerNack{})
+	c.ObserveRTCP(&rtcp.PictureLossIndication{})
+	c.ObserveRTCP(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1, TotalLost: 3}, {SSRC: 2, TotalLost: 1}}})
+	c.ObserveRTCP(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1, TotalLost: 5}}})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, contentType, w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"webrtc_peer_connections 1",
+		`webrtc_ice_connection_state{label="viewer",state="new"} 1`,
+		`webrtc_ice_state_transitions_total{label="viewer",state="checking"} 1`,
+		`webrtc_ice_state_transitions_total{label="viewer",state="connected"} 1`,
+		`webrtc_packets_total{label="viewer",direction="sent"} 0`,
+		`webrtc_bytes_total{label="viewer",direction="received"} 0`,
+		`webrtc_packets_lost_total{label="viewer"} 6`,
+		`webrtc_nack_total{label="viewer"} 2`,
+		`webrtc_pli_total{label="viewer"} 1`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+
+	e.Remove("viewer")
+	b := &strings.Builder{}
+	_, err = e.WriteTo(b)
+	assert.NoError(t, err)
+	assert.Contains(t, b.String(), "webrtc_peer_connections 0\n")
+	assert.NotContains(t, b.String(), "viewer")
+
+	assert.NoError(t, pc.Close())
+}
@@ -0,0 +1,66 @@
+// +build !js
+
+package mobile
+
+import (
+	"github.com/pion/webrtc/v2"
+)
+
+// DataChannelObserver receives the events of a DataChannel
+type DataChannelObserver interface {
+	OnOpen()
+	OnClose()
+
+	// OnMessage is called with every message, isString tells if it was
+	// sent as text
+	OnMessage(data []byte, isString bool)
+}
+
+// DataChannel sends and receives messages with the remote
+type DataChannel struct {
+	d *webrtc.DataChannel
+}
+
+// SetObserver delivers the events of the DataChannel to observer
+func (d *DataChannel) SetObserver(observer DataChannelObserver) {
+	d.d.OnOpen(observer.OnOpen)
+	d.d.OnClose(observer.OnClose)
+	d.d.OnMessage(func(msg webrtc.DataChannelMessage) {
+		observer.OnMessage(msg.Data, msg.IsString)
+	})
+}
+
+// Label returns the label of the DataChannel
+func (d *DataChannel) Label() string {
+	return d.d.Label()
+}
+
+// ID returns the stream identifier of the DataChannel, -1 until it is
+// negotiated
+func (d *DataChannel) ID() int {
+	id := d.d.ID()
+	if id == nil {
+		return -1
+	}
+	return int(*id)
+}
+
+// ReadyState returns the state of the DataChannel, like "open"
+func (d *DataChannel) ReadyState() string {
+	return d.d.ReadyState().String()
+}
+
+// Send sends a binary message
+func (d *DataChannel) Send(data []byte) error {
+	return d.d.Send(data)
+}
+
+// SendText sends a text message
+func (d *DataChannel) SendText(text string) error {
+	return d.d.SendText(text)
+}
+
+// Close closes the DataChannel
+func (d *DataChannel) Close() error {
+	return d.d.Close()
+}
@@ -0,0 +1,111 @@
+// +build !js
+
+// Package mobile is a facade over PeerConnection, Track and DataChannel that
+// gomobile can bind, so iOS and Android apps can embed the stack:
+//
+//	gomobile bind -target=android github.com/pion/webrtc/v2/pkg/mobile
+//	gomobile bind -target=ios github.com/pion/webrtc/v2/pkg/mobile
+//
+// Its signatures only use the types gomobile supports: signed integers,
+// strings, byte slices, errors, and the structs and interfaces of this
+// package. Enums are passed as their string values, like "offer" or
+// "connected", and the events are delivered to the observers the app
+// implements, from goroutines of the stack.
+package mobile
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pion/webrtc/v2"
+)
+
+var (
+	errUnknownSDPType   = errors.New("mobile: unknown session description type")
+	errUnknownCodec     = errors.New("mobile: unknown codec")
+	errUnknownKind      = errors.New("mobile: kind must be audio or video")
+	errUnknownDirection = errors.New("mobile: unknown transceiver direction")
+)
+
+// Configuration configures a PeerConnection
+type Configuration struct {
+	config webrtc.Configuration
+}
+
+// NewConfiguration creates an empty Configuration, with no ICE server
+func NewConfiguration() *Configuration {
+	return &Configuration{}
+}
+
+// AddICEServer adds a STUN or TURN server, username and credential are only
+// used by TURN servers
+func (c *Configuration) AddICEServer(url, username, credential string) {
+	server := webrtc.ICEServer{URLs: []string{url}}
+	if username != "" || credential != "" {
+		server.Username = username
+		server.Credential = credential
+		server.CredentialType = webrtc.ICECredentialTypePassword
+	}
+	c.config.ICEServers = append(c.config.ICEServers, server)
+}
+
+// SetRelayOnly only uses the candidates of the TURN servers when true
+func (c *Configuration) SetRelayOnly(relayOnly bool) {
+	if relayOnly {
+		c.config.ICETransportPolicy = webrtc.ICETransportPolicyRelay
+	} else {
+		c.config.ICETransportPolicy = webrtc.ICETransportPolicyAll
+	}
+}
+
+// SessionDescription is an offer or an answer
+type SessionDescription struct {
+	// Type is "offer", "pranswer", "answer" or "rollback"
+	Type string
+	SDP  string
+}
+
+// NewSessionDescription creates a SessionDescription, like one received
+// from the remote
+func NewSessionDescription(typ, sdp string) *SessionDescription {
+	return &SessionDescription{Type: typ, SDP: sdp}
+}
+
+func newSessionDescription(desc webrtc.SessionDescription) *SessionDescription {
+	return &SessionDescription{Type: desc.Type.String(), SDP: desc.SDP}
+}
+
+func (s *SessionDescription) toWebRTC() (webrtc.SessionDescription, error) {
+	for _, typ := range []webrtc.SDPType{
+		webrtc.SDPTypeOffer, webrtc.SDPTypePranswer, webrtc.SDPTypeAnswer, webrtc.SDPTypeRollback,
+	} {
+		if typ.String() == s.Type {
+			return webrtc.SessionDescription{Type: typ, SDP: s.SDP}, nil
+		}
+	}
+	return webrtc.SessionDescription{}, errUnknownSDPType
+}
+
+// defaultCodecs are the payload types the default codecs are registered
+// with, by name
+var defaultCodecs = []struct {
+	name        string
+	payloadType uint8
+}{
+	{webrtc.Opus, webrtc.DefaultPayloadTypeOpus},
+	{webrtc.PCMU, webrtc.DefaultPayloadTypePCMU},
+	{webrtc.PCMA, webrtc.DefaultPayloadTypePCMA},
+	{webrtc.G722, webrtc.DefaultPayloadTypeG722},
+	{webrtc.VP8, webrtc.DefaultPayloadTypeVP8},
+	{webrtc.VP9, webrtc.DefaultPayloadTypeVP9},
+	{webrtc.H264, webrtc.DefaultPayloadTypeH264},
+}
+
+func codecPayloadType(codec string) (uint8, error) {
+	for _, c := range defaultCodecs {
+		if strings.EqualFold(c.name, codec) {
+			return c.payloadType, nil
+		}
+	}
+	return 0, errUnknownCodec
+}
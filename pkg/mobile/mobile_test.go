@@ -0,0 +1,130 @@
+// +build !js
+
+package mobile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+type peerObserver struct {
+	candidates chan [3]interface{}
+	channels   chan *DataChannel
+}
+
+func newPeerObserver() *peerObserver {
+	return &peerObserver{candidates: make(chan [3]interface{}, 100), channels: make(chan *DataChannel, 1)}
+}
+
+func (o *peerObserver) OnICECandidate(candidate, sdpMid string, sdpMLineIndex int) {
+	o.candidates <- [3]interface{}{candidate, sdpMid, sdpMLineIndex}
+}
+
+func (o *peerObserver) OnConnectionStateChange(state string) {}
+
+func (o *peerObserver) OnTrack(track *Track) {}
+
+func (o *peerObserver) OnDataChannel(channel *DataChannel) {
+	o.channels <- channel
+}
+
+type channelObserver struct {
+	open     chan struct{}
+	messages chan string
+}
+
+func (o *channelObserver) OnOpen() {
+	close(o.open)
+}
+
+func (o *channelObserver) OnClose() {}
+
+func (o *channelObserver) OnMessage(data []byte, isString bool) {
+	if isString {
+		o.messages <- string(data)
+	}
+}
+
+// forwardCandidates adds the candidates of from to to until gathering completes
+func forwardCandidates(t *testing.T, from *peerObserver, to *PeerConnection) {
+	for c := range from.candidates {
+		if c[0] == "" {
+			return
+		}
+		assert.NoError(t, to.AddICECandidate(c[0].(string), c[1].(string), c[2].(int)))
+	}
+}
+
+func TestPeerConnection(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offererObserver, answererObserver := newPeerObserver(), newPeerObserver()
+	offerer, err := NewPeerConnection(nil, offererObserver)
+	assert.NoError(t, err)
+	answerer, err := NewPeerConnection(NewConfiguration(), answererObserver)
+	assert.NoError(t, err)
+
+	_, err = offerer.NewTrack("AV1", "video", "pion")
+	assert.Equal(t, errUnknownCodec, err)
+	track, err := offerer.NewTrack("vp8", "video", "pion")
+	assert.NoError(t, err)
+	assert.Equal(t, "video", track.Kind())
+	assert.Equal(t, "VP8", track.Codec())
+	assert.NoError(t, offerer.AddTrack(track))
+
+	channel, err := offerer.CreateDataChannel("data", true)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, channel.ID())
+	observer := &channelObserver{open: make(chan struct{}), messages: make(chan string, 1)}
+	channel.SetObserver(observer)
+
+	assert.Equal(t, errUnknownSDPType, offerer.SetLocalDescription(NewSessionDescription("hello", "")))
+	offer, err := offerer.CreateOffer()
+	assert.NoError(t, err)
+	assert.Equal(t, "offer", offer.Type)
+	assert.Contains(t, offer.SDP, "VP8")
+	assert.NoError(t, offerer.SetLocalDescription(offer))
+	assert.NoError(t, answerer.SetRemoteDescription(offer))
+
+	answer, err := answerer.CreateAnswer()
+	assert.NoError(t, err)
+	assert.NoError(t, answerer.SetLocalDescription(answer))
+	assert.NoError(t, offerer.SetRemoteDescription(answerer.LocalDescription()))
+
+	forwardCandidates(t, offererObserver, answerer)
+	forwardCandidates(t, answererObserver, offerer)
+
+	// The messages of the offerer reach the DataChannel of the answerer
+	<-observer.open
+	remote := <-answererObserver.channels
+	assert.Equal(t, "data", remote.Label())
+	remoteObserver := &channelObserver{open: make(chan struct{}), messages: make(chan string, 1)}
+	remote.SetObserver(remoteObserver)
+	assert.NoError(t, channel.SendText("hello"))
+	assert.Equal(t, "hello", <-remoteObserver.messages)
+	assert.Equal(t, "open", channel.ReadyState())
+
+	assert.NoError(t, offerer.Close())
+	assert.NoError(t, answerer.Close())
+}
+
+func TestAddTransceiver(t *testing.T) {
+	pc, err := NewPeerConnection(nil, newPeerObserver())
+	assert.NoError(t, err)
+
+	assert.Equal(t, errUnknownKind, pc.AddTransceiver("text", "recvonly"))
+	assert.Equal(t, errUnknownDirection, pc.AddTransceiver("video", "receive"))
+	assert.NoError(t, pc.AddTransceiver("video", "recvonly"))
+
+	offer, err := pc.CreateOffer()
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=recvonly")
+	assert.NoError(t, pc.Close())
+}
@@ -0,0 +1,191 @@
+// +build !js
+
+package mobile
+
+import (
+	"github.com/pion/randutil"
+	"github.com/pion/webrtc/v2"
+)
+
+// PeerConnectionObserver receives the events of a PeerConnection
+type PeerConnectionObserver interface {
+	// OnICECandidate is called with every local candidate, as the candidate
+	// attribute, the mid and the index of its media section. An empty
+	// candidate signals that gathering is complete.
+	OnICECandidate(candidate, sdpMid string, sdpMLineIndex int)
+
+	// OnConnectionStateChange is called with the new state, like
+	// "connected" or "failed"
+	OnConnectionStateChange(state string)
+
+	// OnTrack is called with every Track the remote sends
+	OnTrack(track *Track)
+
+	// OnDataChannel is called with every DataChannel the remote creates
+	OnDataChannel(channel *DataChannel)
+}
+
+// PeerConnection is a connection to a remote peer
+type PeerConnection struct {
+	pc *webrtc.PeerConnection
+}
+
+// NewPeerConnection creates a PeerConnection with the default codecs, whose
+// events are delivered to observer. config can be nil.
+func NewPeerConnection(config *Configuration, observer PeerConnectionObserver) (*PeerConnection, error) {
+	configuration := webrtc.Configuration{}
+	if config != nil {
+		configuration = config.config
+	}
+
+	pc, err := webrtc.NewPeerConnection(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		if c == nil {
+			observer.OnICECandidate("", "", 0)
+			return
+		}
+		init := c.ToJSON()
+		sdpMid, sdpMLineIndex := "", 0
+		if init.SDPMid != nil {
+			sdpMid = *init.SDPMid
+		}
+		if init.SDPMLineIndex != nil {
+			sdpMLineIndex = int(*init.SDPMLineIndex)
+		}
+		observer.OnICECandidate(init.Candidate, sdpMid, sdpMLineIndex)
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		observer.OnConnectionStateChange(state.String())
+	})
+	pc.OnTrack(func(track *webrtc.Track, _ *webrtc.RTPReceiver) {
+		observer.OnTrack(&Track{track: track})
+	})
+	pc.OnDataChannel(func(d *webrtc.DataChannel) {
+		observer.OnDataChannel(&DataChannel{d: d})
+	})
+	return &PeerConnection{pc: pc}, nil
+}
+
+// CreateOffer creates an offer, to pass to SetLocalDescription
+func (p *PeerConnection) CreateOffer() (*SessionDescription, error) {
+	offer, err := p.pc.CreateOffer(nil)
+	if err != nil {
+		return nil, err
+	}
+	return newSessionDescription(offer), nil
+}
+
+// CreateAnswer creates an answer to the remote offer, to pass to
+// SetLocalDescription
+func (p *PeerConnection) CreateAnswer() (*SessionDescription, error) {
+	answer, err := p.pc.CreateAnswer(nil)
+	if err != nil {
+		return nil, err
+	}
+	return newSessionDescription(answer), nil
+}
+
+// SetLocalDescription sets the local offer or answer, and starts gathering
+// the candidates
+func (p *PeerConnection) SetLocalDescription(desc *SessionDescription) error {
+	d, err := desc.toWebRTC()
+	if err != nil {
+		return err
+	}
+	return p.pc.SetLocalDescription(d)
+}
+
+// SetRemoteDescription sets the offer or answer of the remote
+func (p *PeerConnection) SetRemoteDescription(desc *SessionDescription) error {
+	d, err := desc.toWebRTC()
+	if err != nil {
+		return err
+	}
+	return p.pc.SetRemoteDescription(d)
+}
+
+// LocalDescription returns the local description, with the candidates
+// gathered so far, or nil if none is set
+func (p *PeerConnection) LocalDescription() *SessionDescription {
+	desc := p.pc.LocalDescription()
+	if desc == nil {
+		return nil
+	}
+	return newSessionDescription(*desc)
+}
+
+// AddICECandidate adds a candidate of the remote, as given to its
+// OnICECandidate
+func (p *PeerConnection) AddICECandidate(candidate, sdpMid string, sdpMLineIndex int) error {
+	index := uint16(sdpMLineIndex)
+	return p.pc.AddICECandidate(webrtc.ICECandidateInit{
+		Candidate:     candidate,
+		SDPMid:        &sdpMid,
+		SDPMLineIndex: &index,
+	})
+}
+
+// NewTrack creates a Track sending codec, one of opus, PCMU, PCMA, G722,
+// VP8, VP9 or H264, to add to PeerConnections with AddTrack
+func (p *PeerConnection) NewTrack(codec, id, label string) (*Track, error) {
+	payloadType, err := codecPayloadType(codec)
+	if err != nil {
+		return nil, err
+	}
+
+	ssrc, generator := uint32(0), randutil.NewMathRandomGenerator()
+	for ssrc == 0 {
+		ssrc = generator.Uint32()
+	}
+	track, err := p.pc.NewTrack(payloadType, ssrc, id, label)
+	if err != nil {
+		return nil, err
+	}
+	return &Track{track: track}, nil
+}
+
+// AddTrack sends track to the remote
+func (p *PeerConnection) AddTrack(track *Track) error {
+	_, err := p.pc.AddTrack(track.track)
+	return err
+}
+
+// AddTransceiver adds a transceiver of kind, "audio" or "video", with
+// direction, like "recvonly" to receive a Track without sending one
+func (p *PeerConnection) AddTransceiver(kind, direction string) error {
+	k := webrtc.NewRTPCodecType(kind)
+	if k == 0 {
+		return errUnknownKind
+	}
+	d := webrtc.NewRTPTransceiverDirection(direction)
+	if d == webrtc.RTPTransceiverDirection(webrtc.Unknown) {
+		return errUnknownDirection
+	}
+
+	_, err := p.pc.AddTransceiverFromKind(k, webrtc.RtpTransceiverInit{Direction: d})
+	return err
+}
+
+// CreateDataChannel creates a DataChannel, whose messages are retransmitted
+// until they are delivered, in order if ordered is true
+func (p *PeerConnection) CreateDataChannel(label string, ordered bool) (*DataChannel, error) {
+	d, err := p.pc.CreateDataChannel(label, &webrtc.DataChannelInit{Ordered: &ordered})
+	if err != nil {
+		return nil, err
+	}
+	return &DataChannel{d: d}, nil
+}
+
+// ConnectionState returns the state of the connection, like "connected"
+func (p *PeerConnection) ConnectionState() string {
+	return p.pc.ConnectionState().String()
+}
+
+// Close closes the connection
+func (p *PeerConnection) Close() error {
+	return p.pc.Close()
+}
@@ -0,0 +1,70 @@
+// +build !js
+
+package mobile
+
+import (
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// receiveMTU is the size of the buffer ReadRTP reads a packet into
+const receiveMTU = 1460
+
+// Track is a local Track created by PeerConnection.NewTrack, or a remote one
+// given to PeerConnectionObserver.OnTrack
+type Track struct {
+	track *webrtc.Track
+}
+
+// ID returns the ID of the Track
+func (t *Track) ID() string {
+	return t.track.ID()
+}
+
+// Label returns the label of the Track
+func (t *Track) Label() string {
+	return t.track.Label()
+}
+
+// Kind returns "audio" or "video"
+func (t *Track) Kind() string {
+	return t.track.Kind().String()
+}
+
+// Codec returns the name of the codec of the Track, like "opus" or "VP8"
+func (t *Track) Codec() string {
+	return t.track.Codec().Name
+}
+
+// SSRC returns the SSRC of the Track
+func (t *Track) SSRC() int64 {
+	return int64(t.track.SSRC())
+}
+
+// PayloadType returns the payload type of the Track
+func (t *Track) PayloadType() int {
+	return int(t.track.PayloadType())
+}
+
+// WriteSample packetizes and sends a frame of a local Track, samples is its
+// duration in units of the clock rate of the codec, like 960 for 20ms of
+// opus
+func (t *Track) WriteSample(data []byte, samples int) error {
+	return t.track.WriteSample(media.Sample{Data: data, Samples: uint32(samples)})
+}
+
+// WriteRTP sends an RTP packet on a local Track
+func (t *Track) WriteRTP(packet []byte) error {
+	_, err := t.track.Write(packet)
+	return err
+}
+
+// ReadRTP reads the next RTP packet of a remote Track
+func (t *Track) ReadRTP() ([]byte, error) {
+	b := make([]byte, receiveMTU)
+	n, err := t.track.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	return b[:n], nil
+}
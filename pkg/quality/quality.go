@@ -0,0 +1,309 @@
+// Package quality adapts the encoding of a sent Track to the bandwidth and
+// loss reported for it. A Controller walks a ladder of encodings, from the
+// lowest to the highest bitrate, and calls the registered Handlers to change
+// the bitrate, resolution and frame rate of the encoder:
+//
+//	c, err := quality.New(quality.Config{SSRC: track.SSRC(), Ladder: []quality.Rung{
+//		{Bitrate: 150000, Width: 320, Height: 180, FrameRate: 15},
+//		{Bitrate: 500000, Width: 640, Height: 360, FrameRate: 30},
+//		{Bitrate: 1500000, Width: 1280, Height: 720, FrameRate: 30},
+//	}})
+//	c.Register(quality.Handlers{OnBitrate: encoder.SetBitrate})
+//	sender.OnRTCP(webrtc.RTCPHandlers{OnPacket: c.ObserveRTCP})
+//
+// REMB and the fraction lost of receiver reports are read from RTCP, the
+// output of other bandwidth estimators is given to ObserveEstimate. The
+// bandwidth limit the remote negotiated caps the estimates:
+//
+//	c.SetMaxBitrate(sender.MaxBitrate())
+//
+// It steps down as soon as the estimate falls below the bitrate of the
+// encoding, or the loss exceeds the LossThreshold. It only steps up once the
+// estimate exceeded the next bitrate by the UpgradeMargin, with little loss,
+// for the UpgradeHold, so it doesn't flap around a bitrate.
+package quality
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	defaultUpgradeMargin     = 0.15
+	defaultUpgradeHold       = 5 * time.Second
+	defaultUpgradeLoss       = 0.02
+	defaultLossThreshold     = 0.1
+	defaultDowngradeInterval = 2 * time.Second
+)
+
+var (
+	errEmptyLadder    = errors.New("quality: ladder has no rungs")
+	errUnsortedLadder = errors.New("quality: ladder bitrates must increase")
+	errStartRung      = errors.New("quality: start rung is not in the ladder")
+)
+
+// Rung is an encoding of the ladder. Zero Width, Height or FrameRate leave
+// that setting of the encoder as it is.
+type Rung struct {
+	// Bitrate is in bits per second
+	Bitrate   uint64
+	Width     int
+	Height    int
+	FrameRate float64
+}
+
+// Handlers change the encoder to a Rung. Only the handlers of the settings
+// that differ from the previous Rung are called.
+type Handlers struct {
+	OnBitrate    func(bitrate uint64)
+	OnResolution func(width, height int)
+	OnFrameRate  func(frameRate float64)
+}
+
+func (h Handlers) dispatch(from *Rung, to Rung) {
+	if h.OnBitrate != nil && (from == nil || from.Bitrate != to.Bitrate) {
+		h.OnBitrate(to.Bitrate)
+	}
+	if h.OnResolution != nil && to.Width != 0 && to.Height != 0 &&
+		(from == nil || from.Width != to.Width || from.Height != to.Height) {
+		h.OnResolution(to.Width, to.Height)
+	}
+	if h.OnFrameRate != nil && to.FrameRate != 0 && (from == nil || from.FrameRate != to.FrameRate) {
+		h.OnFrameRate(to.FrameRate)
+	}
+}
+
+// Config configures a Controller, the zero values of the thresholds select
+// their defaults
+type Config struct {
+	// Ladder is the encodings, sorted by increasing bitrate
+	Ladder []Rung
+
+	// Start is the index of the Rung to start at, the lowest by default
+	Start int
+
+	// SSRC is the one of the sent Track, RTCP about other ones is ignored.
+	// Zero takes all of it.
+	SSRC uint32
+
+	// UpgradeMargin is how much the estimate has to exceed the bitrate of the
+	// next Rung to step up, 0.15 for 15% by default
+	UpgradeMargin float64
+
+	// UpgradeHold is how long the estimate has to stay above it, 5 seconds
+	// by default
+	UpgradeHold time.Duration
+
+	// UpgradeLoss is the highest fraction lost to step up at, 0.02 by default
+	UpgradeLoss float64
+
+	// LossThreshold is the fraction lost above which it steps down, 0.1 by
+	// default
+	LossThreshold float64
+
+	// DowngradeInterval is how long it waits for a step down to show in the
+	// loss before taking another one for loss, 2 seconds by default
+	DowngradeInterval time.Duration
+}
+
+// Controller picks the Rung of the ladder for the latest bandwidth estimate
+// and loss
+type Controller struct {
+	mu sync.Mutex
+
+	config   Config
+	handlers []Handlers
+	now      func() time.Time
+
+	current       int
+	estimate      uint64
+	hasEstimate   bool
+	maxBitrate    uint64
+	loss          float64
+	upgradeSince  time.Time
+	lastDowngrade time.Time
+}
+
+// New creates a Controller at the Start of config.Ladder
+func New(config Config) (*Controller, error) {
+	if len(config.Ladder) == 0 {
+		return nil, errEmptyLadder
+	}
+	for i := 1; i < len(config.Ladder); i++ {
+		if config.Ladder[i].Bitrate <= config.Ladder[i-1].Bitrate {
+			return nil, errUnsortedLadder
+		}
+	}
+	if config.Start < 0 || config.Start >= len(config.Ladder) {
+		return nil, errStartRung
+	}
+
+	if config.UpgradeMargin == 0 {
+		config.UpgradeMargin = defaultUpgradeMargin
+	}
+	if config.UpgradeHold == 0 {
+		config.UpgradeHold = defaultUpgradeHold
+	}
+	if config.UpgradeLoss == 0 {
+		config.UpgradeLoss = defaultUpgradeLoss
+	}
+	if config.LossThreshold == 0 {
+		config.LossThreshold = defaultLossThreshold
+	}
+	if config.DowngradeInterval == 0 {
+		config.DowngradeInterval = defaultDowngradeInterval
+	}
+
+	config.Ladder = append([]Rung{}, config.Ladder...)
+	return &Controller{config: config, now: time.Now, current: config.Start}, nil
+}
+
+// Register adds handlers to the ones the changes of Rung are dispatched to,
+// and calls them with the current Rung. Handlers are called synchronously,
+// from the Observe call that changed the Rung.
+func (c *Controller) Register(h Handlers) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handlers = append(c.handlers, h)
+	h.dispatch(nil, c.config.Ladder[c.current])
+}
+
+// Rung returns the current Rung
+func (c *Controller) Rung() Rung {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.config.Ladder[c.current]
+}
+
+// ObserveEstimate takes the available bandwidth estimated for the Track, in
+// bits per second
+func (c *Controller) ObserveEstimate(bitrate uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.estimate, c.hasEstimate = bitrate, true
+	c.update()
+}
+
+// SetMaxBitrate caps the estimates at bitrate bits per second, like the
+// limit of RTPSender.MaxBitrate. 0 removes the cap.
+func (c *Controller) SetMaxBitrate(bitrate uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxBitrate = bitrate
+	c.update()
+}
+
+// ObserveLoss takes the fraction of the packets of the Track lost, from 0 to 1
+func (c *Controller) ObserveLoss(fraction float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loss = fraction
+	c.update()
+}
+
+// ObserveRTCP takes the REMB and receiver reports about the Track, like
+// from the OnPacket handler of an RTPSender
+func (c *Controller) ObserveRTCP(pkt rtcp.Packet) {
+	switch p := pkt.(type) {
+	case *rtcp.ReceiverEstimatedMaximumBitrate:
+		if c.config.SSRC == 0 || containsSSRC(p.SSRCs, c.config.SSRC) {
+			c.ObserveEstimate(p.Bitrate)
+		}
+	case *rtcp.ReceiverReport:
+		c.observeReports(p.Reports)
+	case *rtcp.SenderReport:
+		c.observeReports(p.Reports)
+	}
+}
+
+func (c *Controller) observeReports(reports []rtcp.ReceptionReport) {
+	for _, report := range reports {
+		if c.config.SSRC == 0 || report.SSRC == c.config.SSRC {
+			c.ObserveLoss(float64(report.FractionLost) / 256)
+			return
+		}
+	}
+}
+
+// update must be called with the lock held
+func (c *Controller) update() {
+	now := c.now()
+	ladder := c.config.Ladder
+	target := c.current
+	estimate, hasEstimate := c.limitedEstimate()
+
+	switch {
+	case hasEstimate && estimate < ladder[c.current].Bitrate:
+		// The highest Rung that fits, or the lowest
+		for target > 0 && ladder[target].Bitrate > estimate {
+			target--
+		}
+	case c.loss > c.config.LossThreshold && target > 0 && now.Sub(c.lastDowngrade) >= c.config.DowngradeInterval:
+		target--
+	}
+	if target < c.current {
+		c.lastDowngrade = now
+		c.upgradeSince = time.Time{}
+		c.change(target)
+		return
+	}
+
+	if !c.canUpgrade() {
+		c.upgradeSince = time.Time{}
+		return
+	}
+	if c.upgradeSince.IsZero() {
+		c.upgradeSince = now
+	}
+	if now.Sub(c.upgradeSince) >= c.config.UpgradeHold {
+		c.upgradeSince = time.Time{}
+		c.change(c.current + 1)
+	}
+}
+
+func (c *Controller) canUpgrade() bool {
+	estimate, hasEstimate := c.limitedEstimate()
+	if !hasEstimate || c.current == len(c.config.Ladder)-1 || c.loss > c.config.UpgradeLoss {
+		return false
+	}
+	next := c.config.Ladder[c.current+1]
+	return float64(estimate) >= float64(next.Bitrate)*(1+c.config.UpgradeMargin)
+}
+
+// limitedEstimate returns the estimate capped at the maxBitrate, the cap is
+// the estimate until there is one
+func (c *Controller) limitedEstimate() (uint64, bool) {
+	switch {
+	case !c.hasEstimate:
+		return c.maxBitrate, c.maxBitrate != 0
+	case c.maxBitrate != 0 && c.maxBitrate < c.estimate:
+		return c.maxBitrate, true
+	default:
+		return c.estimate, true
+	}
+}
+
+func (c *Controller) change(index int) {
+	from := c.config.Ladder[c.current]
+	c.current = index
+	for _, h := range c.handlers {
+		h.dispatch(&from, c.config.Ladder[index])
+	}
+}
+
+func containsSSRC(ssrcs []uint32, ssrc uint32) bool {
+	for _, s := range ssrcs {
+		if s == ssrc {
+			return true
+		}
+	}
+	return false
+}
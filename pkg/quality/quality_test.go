@@ -0,0 +1,109 @@
+package quality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLadder = []Rung{
+	{Bitrate: 150000, Width: 320, Height: 180, FrameRate: 15},
+	{Bitrate: 500000, Width: 640, Height: 360, FrameRate: 30},
+	{Bitrate: 1500000, Width: 1280, Height: 720, FrameRate: 30},
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{})
+	assert.Equal(t, errEmptyLadder, err)
+	_, err = New(Config{Ladder: []Rung{{Bitrate: 2}, {Bitrate: 1}}})
+	assert.Equal(t, errUnsortedLadder, err)
+	_, err = New(Config{Ladder: testLadder, Start: 3})
+	assert.Equal(t, errStartRung, err)
+}
+
+func TestController(t *testing.T) {
+	c, err := New(Config{Ladder: testLadder, Start: 1, SSRC: 5000})
+	assert.NoError(t, err)
+	now := time.Unix(0, 0)
+	c.now = func() time.Time { return now }
+
+	bitrates := []uint64{}
+	resolutions := [][2]int{}
+	frameRates := []float64{}
+	c.Register(Handlers{
+		OnBitrate:    func(bitrate uint64) { bitrates = append(bitrates, bitrate) },
+		OnResolution: func(width, height int) { resolutions = append(resolutions, [2]int{width, height}) },
+		OnFrameRate:  func(frameRate float64) { frameRates = append(frameRates, frameRate) },
+	})
+	assert.Equal(t, []uint64{500000}, bitrates)
+
+	// Estimates below the current bitrate step down at once, to the Rung that
+	// fits, other SSRCs are ignored
+	c.ObserveRTCP(&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 100000, SSRCs: []uint32{1}})
+	assert.Equal(t, testLadder[1], c.Rung())
+	c.ObserveRTCP(&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 400000, SSRCs: []uint32{5000}})
+	assert.Equal(t, testLadder[0], c.Rung())
+	assert.Equal(t, []uint64{500000, 150000}, bitrates)
+	assert.Equal(t, [][2]int{{640, 360}, {320, 180}}, resolutions)
+	assert.Equal(t, []float64{30, 15}, frameRates)
+
+	// Stepping up takes the margin for the whole hold
+	c.ObserveEstimate(560000)
+	now = now.Add(10 * time.Second)
+	c.ObserveEstimate(560000)
+	assert.Equal(t, testLadder[0], c.Rung(), "below the margin")
+
+	c.ObserveEstimate(600000)
+	now = now.Add(3 * time.Second)
+	c.ObserveEstimate(600000)
+	c.ObserveRTCP(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 5000, FractionLost: 26}}})
+	now = now.Add(3 * time.Second)
+	c.ObserveEstimate(600000)
+	assert.Equal(t, testLadder[0], c.Rung(), "the loss restarted the hold")
+
+	c.ObserveLoss(0)
+	now = now.Add(5 * time.Second)
+	c.ObserveEstimate(600000)
+	assert.Equal(t, testLadder[1], c.Rung())
+	assert.Equal(t, []float64{30, 15, 30}, frameRates)
+
+	// Loss steps down one Rung per interval
+	c.ObserveEstimate(5000000)
+	now = now.Add(5 * time.Second)
+	c.ObserveEstimate(5000000)
+	assert.Equal(t, testLadder[2], c.Rung())
+	assert.Equal(t, []float64{30, 15, 30}, frameRates, "the frame rate didn't change")
+
+	c.ObserveLoss(0.2)
+	assert.Equal(t, testLadder[1], c.Rung())
+	now = now.Add(time.Second)
+	c.ObserveLoss(0.2)
+	assert.Equal(t, testLadder[1], c.Rung())
+	now = now.Add(time.Second)
+	c.ObserveLoss(0.2)
+	assert.Equal(t, testLadder[0], c.Rung())
+	assert.Equal(t, []uint64{500000, 150000, 500000, 1500000, 500000, 150000}, bitrates)
+}
+
+func TestControllerMaxBitrate(t *testing.T) {
+	c, err := New(Config{Ladder: testLadder, Start: 2})
+	assert.NoError(t, err)
+	now := time.Unix(0, 0)
+	c.now = func() time.Time { return now }
+
+	// The cap is the estimate until there is one
+	c.SetMaxBitrate(600000)
+	assert.Equal(t, testLadder[1], c.Rung())
+
+	c.ObserveEstimate(5000000)
+	now = now.Add(10 * time.Second)
+	c.ObserveEstimate(5000000)
+	assert.Equal(t, testLadder[1], c.Rung())
+
+	c.SetMaxBitrate(0)
+	now = now.Add(10 * time.Second)
+	c.ObserveEstimate(5000000)
+	assert.Equal(t, testLadder[2], c.Rung())
+}
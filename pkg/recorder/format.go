@@ -0,0 +1,121 @@
+// +build !js
+
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media/rtpdump"
+)
+
+// format is how the records of a stream are stored in its segments. Records
+// are appended one write at a time, so a segment cut short by a crash is
+// complete up to its last whole record.
+type format interface {
+	ext() string
+	header(start time.Time) ([]byte, error)
+
+	// scan returns the length of the whole records at the start of r, their
+	// count and the offset of the last one
+	scan(r io.Reader) (size int64, records int, last time.Duration, err error)
+}
+
+// rtpdumpFormat stores the packets of a Track as they were received, in the
+// RTPDump format of rtptools
+type rtpdumpFormat struct{}
+
+// rtpdumpPreamble is the first line of a segment, it has no source address
+const rtpdumpPreamble = "#!rtpplay1.0 0.0.0.0/0\n"
+
+func (rtpdumpFormat) ext() string {
+	return ".rtpdump"
+}
+
+func (rtpdumpFormat) header(start time.Time) ([]byte, error) {
+	header, err := rtpdump.Header{Start: start, Source: net.IPv4zero}.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(rtpdumpPreamble), header...), nil
+}
+
+func (rtpdumpFormat) record(offset time.Duration, packet []byte) ([]byte, error) {
+	return rtpdump.Packet{Offset: offset, Payload: packet}.Marshal()
+}
+
+func (rtpdumpFormat) scan(r io.Reader) (int64, int, time.Duration, error) {
+	reader, header, err := rtpdump.NewReader(r)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	// Segments start with the preamble and header written by header
+	head, err := rtpdumpFormat{}.header(header.Start)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	size, records, last := int64(len(head)), 0, time.Duration(0)
+	for {
+		packet, err := reader.Next()
+		if err != nil {
+			// A packet cut short fails to parse like the end of the file does
+			return size, records, last, nil
+		}
+		size += int64(len(packet.Payload)) + 8
+		records++
+		last = packet.Offset
+	}
+}
+
+// Message is a record of the segments of a DataChannel, a line of JSON
+type Message struct {
+	// Offset is the time since the start of the segment
+	Offset   time.Duration `json:"offset"`
+	IsString bool          `json:"isString,omitempty"`
+	Data     []byte        `json:"data"`
+}
+
+// messagesFormat stores the messages of a DataChannel as lines of JSON
+type messagesFormat struct{}
+
+func (messagesFormat) ext() string {
+	return ".jsonl"
+}
+
+func (messagesFormat) header(time.Time) ([]byte, error) {
+	return nil, nil
+}
+
+func (messagesFormat) record(offset time.Duration, isString bool, data []byte) ([]byte, error) {
+	line, err := json.Marshal(Message{Offset: offset, IsString: isString, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+func (messagesFormat) scan(r io.Reader) (int64, int, time.Duration, error) {
+	reader := bufio.NewReader(r)
+	size, records, last := int64(0), 0, time.Duration(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, records, last, nil
+		} else if err != nil {
+			return 0, 0, 0, err
+		}
+
+		var m Message
+		if err := json.Unmarshal(bytes.TrimSpace(line), &m); err != nil {
+			return size, records, last, nil
+		}
+		size += int64(len(line))
+		records++
+		last = m.Offset
+	}
+}
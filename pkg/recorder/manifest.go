@@ -0,0 +1,197 @@
+// +build !js
+
+package recorder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestFile is the name of the manifest in the directory of a session
+const ManifestFile = "manifest.json"
+
+// Manifest describes a recorded session and the segments of its streams. It
+// is rewritten atomically whenever a segment starts or ends, so it always
+// lists every segment on disk.
+type Manifest struct {
+	ID    string    `json:"id"`
+	Start time.Time `json:"start"`
+	// End is nil while the session is recorded, or when its recording crashed
+	// and wasn't recovered
+	End *time.Time `json:"end,omitempty"`
+
+	Tracks       []*TrackManifest       `json:"tracks"`
+	DataChannels []*DataChannelManifest `json:"dataChannels"`
+}
+
+// TrackManifest describes a recorded Track, its segments are RTPDump files
+// of the packets received
+type TrackManifest struct {
+	ID          string     `json:"id"`
+	Label       string     `json:"label"`
+	Kind        string     `json:"kind"`
+	SSRC        uint32     `json:"ssrc"`
+	RID         string     `json:"rid,omitempty"`
+	PayloadType uint8      `json:"payloadType"`
+	Codec       string     `json:"codec"`
+	ClockRate   uint32     `json:"clockRate"`
+	Channels    uint16     `json:"channels,omitempty"`
+	Fmtp        string     `json:"fmtp,omitempty"`
+	Segments    []*Segment `json:"segments"`
+}
+
+// DataChannelManifest describes a recorded DataChannel, its segments are
+// lines of JSON encoded Messages
+type DataChannelManifest struct {
+	Label    string     `json:"label"`
+	ID       *uint16    `json:"id,omitempty"`
+	Segments []*Segment `json:"segments"`
+}
+
+// Segment is a file of the records of a stream
+type Segment struct {
+	File  string    `json:"file"`
+	Start time.Time `json:"start"`
+	// End, Size and Records are set once the segment is finalized
+	End     *time.Time `json:"end,omitempty"`
+	Size    int64      `json:"size,omitempty"`
+	Records int        `json:"records,omitempty"`
+}
+
+// ReadManifest reads the manifest of the session recorded in dir
+func ReadManifest(dir string) (*Manifest, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(raw, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeManifest replaces the manifest of dir with m. It is written to a
+// temporary file that is renamed over the manifest, so the manifest is never
+// seen half written.
+func writeManifest(dir string, m *Manifest) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, ManifestFile+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(raw); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, ManifestFile))
+}
+
+// Recover finalizes the session recorded in dir after its recording crashed.
+// The segments that weren't finalized are cut after their last whole record
+// and marked finalized, the ones without any header are removed. The session
+// ends with its last record. Sessions that ended are returned as they are.
+func Recover(dir string) (*Manifest, error) {
+	m, err := ReadManifest(dir)
+	if err != nil || m.End != nil {
+		return m, err
+	}
+
+	end := m.Start
+	recoverSegments := func(segments []*Segment, f format) ([]*Segment, error) {
+		recovered := segments[:0]
+		for _, segment := range segments {
+			if segment.End == nil {
+				ok, err := recoverSegment(dir, segment, f)
+				if err != nil {
+					return nil, err
+				} else if !ok {
+					continue
+				}
+			}
+			if segment.End.After(end) {
+				end = *segment.End
+			}
+			recovered = append(recovered, segment)
+		}
+		return recovered, nil
+	}
+
+	for _, t := range m.Tracks {
+		if t.Segments, err = recoverSegments(t.Segments, rtpdumpFormat{}); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range m.DataChannels {
+		if d.Segments, err = recoverSegments(d.Segments, messagesFormat{}); err != nil {
+			return nil, err
+		}
+	}
+
+	m.End = &end
+	if err := writeManifest(dir, m); err != nil {
+		return nil, err
+	}
+	removeTempManifests(dir)
+	return m, nil
+}
+
+// recoverSegment cuts segment after its last whole record, it returns false
+// when the segment was removed
+func recoverSegment(dir string, segment *Segment, f format) (bool, error) {
+	path := filepath.Join(dir, segment.File)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	size, records, last, err := f.scan(file)
+	if closeErr := file.Close(); closeErr != nil {
+		return false, closeErr
+	}
+	if err != nil {
+		// The segment started too late to get its header
+		return false, os.Remove(path)
+	}
+
+	if err := os.Truncate(path, size); err != nil {
+		return false, err
+	}
+	end := segment.Start.Add(last)
+	segment.End = &end
+	segment.Size = size
+	segment.Records = records
+	return true, nil
+}
+
+// removeTempManifests removes the temporary files of manifests a crash left
+func removeTempManifests(dir string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ManifestFile+".") {
+			_ = os.Remove(filepath.Join(dir, file.Name()))
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// +build !js
+
+// Package recorder records the sessions of PeerConnections to disk. Every
+// remote Track is stored as RTPDump segments of the packets received and,
+// when enabled, every DataChannel as segments of its messages in lines of
+// JSON. A session is a directory of these segments and a manifest describing
+// the streams, codecs and segments:
+//
+//	r, err := recorder.Attach(pc, recorder.Config{Dir: "recordings"})
+//	...
+//	defer r.Close()
+//
+// Segments are appended to one record at a time and the manifest is replaced
+// atomically, so a recording that crashed is readable up to its last record.
+// Recover finalizes such a session.
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v2"
+)
+
+const (
+	receiveMTU = 1460
+
+	sessionIDLayout = "20060102-150405.000000000"
+)
+
+var errClosed = errors.New("recorder: closed")
+
+// Config configures a Recorder
+type Config struct {
+	// Dir is the directory the sessions are recorded in, each in a
+	// directory named after its ID
+	Dir string
+
+	// SessionID is the ID of the session, the time it starts if empty
+	SessionID string
+
+	// DataChannels is whether the messages of DataChannels are recorded
+	DataChannels bool
+
+	// MaxSegmentSize and MaxSegmentDuration limit the segments of a stream,
+	// a new one is started when the next record doesn't fit. Zero means
+	// unlimited. Video segments may start in the middle of a picture group,
+	// a Picture Loss Indication asks for a keyframe whenever one starts.
+	MaxSegmentSize     int64
+	MaxSegmentDuration time.Duration
+
+	// OnError is called with the errors that stop recording a stream
+	OnError func(err error)
+}
+
+// Recorder records a session of a PeerConnection
+type Recorder struct {
+	mu sync.Mutex
+
+	pc     *webrtc.PeerConnection
+	config Config
+	dir    string
+	now    func() time.Time
+
+	manifest   *Manifest
+	segmenters []*segmenter
+	closed     bool
+}
+
+// New starts recording a session of pc in a new directory of config.Dir.
+// Streams are recorded with RecordTrack and RecordDataChannel, like from the
+// handlers of pc.
+func New(pc *webrtc.PeerConnection, config Config) (*Recorder, error) {
+	return newRecorder(pc, config, time.Now)
+}
+
+// Attach starts recording a session of pc like New, and records all of its
+// Tracks and, when config.DataChannels is set, DataChannels. It replaces the
+// OnTrack and OnDataChannel handlers of pc.
+func Attach(pc *webrtc.PeerConnection, config Config) (*Recorder, error) {
+	r, err := New(pc, config)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.OnTrack(func(track *webrtc.Track, _ *webrtc.RTPReceiver) {
+		r.RecordTrack(track)
+	})
+	if config.DataChannels {
+		pc.OnDataChannel(r.RecordDataChannel)
+	}
+	return r, nil
+}
+
+func newRecorder(pc *webrtc.PeerConnection, config Config, now func() time.Time) (*Recorder, error) {
+	start := now()
+	id := config.SessionID
+	if id == "" {
+		id = start.UTC().Format(sessionIDLayout)
+	}
+
+	dir := filepath.Join(config.Dir, id)
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		pc:       pc,
+		config:   config,
+		dir:      dir,
+		now:      now,
+		manifest: &Manifest{ID: id, Start: start, Tracks: []*TrackManifest{}, DataChannels: []*DataChannelManifest{}},
+	}
+	if err := writeManifest(dir, r.manifest); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Dir returns the directory the session is recorded in
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+// RecordTrack records the packets of a remote Track until it ends or the
+// Recorder is closed. It blocks, and must be the only reader of track.
+func (r *Recorder) RecordTrack(track *webrtc.Track) {
+	t := &TrackManifest{
+		ID:          track.ID(),
+		Label:       track.Label(),
+		Kind:        track.Kind().String(),
+		SSRC:        track.SSRC(),
+		RID:         track.RID(),
+		PayloadType: track.PayloadType(),
+		Segments:    []*Segment{},
+	}
+	if codec := track.Codec(); codec != nil {
+		t.Codec = codec.Name
+		t.ClockRate = codec.ClockRate
+		t.Channels = codec.Channels
+		t.Fmtp = codec.SDPFmtpLine
+	}
+
+	var s *segmenter
+	if err := r.updateManifest(func() {
+		s = r.addSegmenter("track", len(r.manifest.Tracks), &t.Segments, rtpdumpFormat{})
+		r.manifest.Tracks = append(r.manifest.Tracks, t)
+	}); err != nil {
+		r.handleError(err)
+		return
+	}
+
+	isVideo := track.Kind() == webrtc.RTPCodecTypeVideo
+	b := make([]byte, receiveMTU)
+	for {
+		n, err := track.Read(b)
+		if err != nil {
+			if err != io.EOF {
+				r.handleError(err)
+			}
+			break
+		}
+
+		started, err := s.write(r.now(), func(offset time.Duration) ([]byte, error) {
+			return rtpdumpFormat{}.record(offset, b[:n])
+		})
+		if err == errClosed {
+			return
+		} else if err != nil {
+			r.handleError(err)
+			break
+		}
+		if started && isVideo && r.pc != nil {
+			// The error only means the PeerConnection is closing
+			_ = r.pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: track.SSRC()}})
+		}
+	}
+
+	if err := s.close(r.now()); err != nil {
+		r.handleError(err)
+	}
+}
+
+// RecordDataChannel records the messages of d until it closes or the Recorder
+// is closed. It replaces the OnMessage and OnClose handlers of d.
+func (r *Recorder) RecordDataChannel(d *webrtc.DataChannel) {
+	dc := &DataChannelManifest{Label: d.Label(), ID: d.ID(), Segments: []*Segment{}}
+
+	var s *segmenter
+	if err := r.updateManifest(func() {
+		s = r.addSegmenter("datachannel", len(r.manifest.DataChannels), &dc.Segments, messagesFormat{})
+		r.manifest.DataChannels = append(r.manifest.DataChannels, dc)
+	}); err != nil {
+		r.handleError(err)
+		return
+	}
+
+	d.OnMessage(func(msg webrtc.DataChannelMessage) {
+		_, err := s.write(r.now(), func(offset time.Duration) ([]byte, error) {
+			return messagesFormat{}.record(offset, msg.IsString, msg.Data)
+		})
+		if err != nil && err != errClosed {
+			r.handleError(err)
+		}
+	})
+	d.OnClose(func() {
+		if err := s.close(r.now()); err != nil {
+			r.handleError(err)
+		}
+	})
+}
+
+// addSegmenter must be called with the manifest being updated
+func (r *Recorder) addSegmenter(kind string, index int, segments *[]*Segment, f format) *segmenter {
+	s := &segmenter{
+		r:        r,
+		segments: segments,
+		name:     fmt.Sprintf("%s-%d", kind, index),
+		format:   f,
+		closed:   r.closed,
+	}
+	r.segmenters = append(r.segmenters, s)
+	return s
+}
+
+// updateManifest applies update to the manifest and writes it
+func (r *Recorder) updateManifest(update func()) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	update()
+	return writeManifest(r.dir, r.manifest)
+}
+
+func (r *Recorder) handleError(err error) {
+	if r.config.OnError != nil {
+		r.config.OnError(err)
+	}
+}
+
+// Close finalizes the segments being recorded and ends the session
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	segmenters := r.segmenters
+	r.mu.Unlock()
+
+	end := r.now()
+	var closeErr error
+	for _, s := range segmenters {
+		if err := s.close(end); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+
+	if err := r.updateManifest(func() {
+		r.manifest.End = &end
+	}); err != nil {
+		return err
+	}
+	return closeErr
+}
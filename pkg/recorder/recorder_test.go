@@ -0,0 +1,166 @@
+// +build !js
+
+package recorder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/media/rtpdump"
+	"github.com/stretchr/testify/assert"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestRecorder(t *testing.T, config Config) (*Recorder, *clock) {
+	dir, err := ioutil.TempDir("", "recorder")
+	assert.NoError(t, err)
+	config.Dir = dir
+	config.SessionID = "session"
+
+	c := &clock{now: time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)}
+	r, err := newRecorder(nil, config, c.Now)
+	assert.NoError(t, err)
+	return r, c
+}
+
+func addTrack(t *testing.T, r *Recorder) *segmenter {
+	var s *segmenter
+	assert.NoError(t, r.updateManifest(func() {
+		tm := &TrackManifest{ID: "video", Kind: "video", Codec: "VP8", ClockRate: 90000, Segments: []*Segment{}}
+		s = r.addSegmenter("track", len(r.manifest.Tracks), &tm.Segments, rtpdumpFormat{})
+		r.manifest.Tracks = append(r.manifest.Tracks, tm)
+	}))
+	return s
+}
+
+func writePacket(t *testing.T, s *segmenter, c *clock, packet []byte) bool {
+	started, err := s.write(c.now, func(offset time.Duration) ([]byte, error) {
+		return rtpdumpFormat{}.record(offset, packet)
+	})
+	assert.NoError(t, err)
+	return started
+}
+
+func TestRecorderRotation(t *testing.T) {
+	// The header of a segment is 39 bytes and a packet record 8 + 4
+	r, c := newTestRecorder(t, Config{MaxSegmentSize: 39 + 2*12, MaxSegmentDuration: time.Second})
+	defer os.RemoveAll(filepath.Dir(r.Dir())) // nolint: errcheck
+
+	_, err := newRecorder(nil, Config{Dir: filepath.Dir(r.Dir()), SessionID: "session"}, c.Now)
+	assert.Error(t, err, "sessions are not overwritten")
+
+	s := addTrack(t, r)
+	assert.True(t, writePacket(t, s, c, []byte{0x80, 0x60, 0, 1}))
+	c.now = c.now.Add(20 * time.Millisecond)
+	assert.False(t, writePacket(t, s, c, []byte{0x80, 0x60, 0, 2}))
+	// Too large for the first segment
+	assert.True(t, writePacket(t, s, c, []byte{0x80, 0x60, 0, 3}))
+	// Too late for the second segment
+	c.now = c.now.Add(time.Second)
+	assert.True(t, writePacket(t, s, c, []byte{0x80, 0x60, 0, 4}))
+
+	m, err := ReadManifest(r.Dir())
+	assert.NoError(t, err)
+	assert.Nil(t, m.End)
+	segments := m.Tracks[0].Segments
+	assert.Len(t, segments, 3)
+	assert.Equal(t, "track-0-0.rtpdump", segments[0].File)
+	assert.Equal(t, int64(63), segments[0].Size)
+	assert.Equal(t, 2, segments[0].Records)
+	assert.Equal(t, 1, segments[1].Records)
+	assert.Nil(t, segments[2].End)
+
+	c.now = c.now.Add(time.Second)
+	assert.NoError(t, r.Close())
+	assert.NoError(t, r.Close())
+	_, err = s.write(c.now, nil)
+	assert.Equal(t, errClosed, err)
+
+	m, err = ReadManifest(r.Dir())
+	assert.NoError(t, err)
+	assert.True(t, m.End.Equal(c.now))
+	assert.Equal(t, 1, m.Tracks[0].Segments[2].Records)
+
+	f, err := os.Open(filepath.Join(r.Dir(), "track-0-0.rtpdump"))
+	assert.NoError(t, err)
+	defer f.Close() // nolint: errcheck
+	reader, header, err := rtpdump.NewReader(f)
+	assert.NoError(t, err)
+	assert.True(t, header.Start.Equal(segments[0].Start))
+	_, err = reader.Next()
+	assert.NoError(t, err)
+	packet, err := reader.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 20*time.Millisecond, packet.Offset)
+	assert.Equal(t, []byte{0x80, 0x60, 0, 2}, packet.Payload)
+}
+
+func TestRecover(t *testing.T) {
+	r, c := newTestRecorder(t, Config{})
+	defer os.RemoveAll(filepath.Dir(r.Dir())) // nolint: errcheck
+
+	s := addTrack(t, r)
+	writePacket(t, s, c, []byte{0x80, 0x60, 0, 1})
+	c.now = c.now.Add(40 * time.Millisecond)
+	writePacket(t, s, c, []byte{0x80, 0x60, 0, 2})
+
+	var messages *segmenter
+	assert.NoError(t, r.updateManifest(func() {
+		dc := &DataChannelManifest{Label: "chat", Segments: []*Segment{}}
+		messages = r.addSegmenter("datachannel", 0, &dc.Segments, messagesFormat{})
+		r.manifest.DataChannels = append(r.manifest.DataChannels, dc)
+	}))
+	_, err := messages.write(c.now, func(offset time.Duration) ([]byte, error) {
+		return messagesFormat{}.record(offset, true, []byte("hello"))
+	})
+	assert.NoError(t, err)
+
+	// The recording crashes while writing a packet and a message
+	appendFile(t, filepath.Join(r.Dir(), "track-0-0.rtpdump"), []byte{0, 12, 0, 12, 0, 0})
+	appendFile(t, filepath.Join(r.Dir(), "datachannel-0-0.jsonl"), []byte(`{"offset":1`))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(r.Dir(), ManifestFile+".123"), nil, 0644))
+
+	m, err := Recover(r.Dir())
+	assert.NoError(t, err)
+	assert.True(t, m.End.Equal(c.now))
+
+	segment := m.Tracks[0].Segments[0]
+	assert.Equal(t, 2, segment.Records)
+	assert.Equal(t, int64(39+2*12), segment.Size)
+	assert.True(t, segment.End.Equal(c.now))
+	info, err := os.Stat(filepath.Join(r.Dir(), segment.File))
+	assert.NoError(t, err)
+	assert.Equal(t, segment.Size, info.Size())
+
+	segment = m.DataChannels[0].Segments[0]
+	assert.Equal(t, 1, segment.Records)
+	raw, err := ioutil.ReadFile(filepath.Join(r.Dir(), segment.File))
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"offset\":0,\"isString\":true,\"data\":\"aGVsbG8=\"}\n", string(raw))
+
+	_, err = os.Stat(filepath.Join(r.Dir(), ManifestFile+".123"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Recovering again changes nothing
+	again, err := Recover(r.Dir())
+	assert.NoError(t, err)
+	assert.Equal(t, m, again)
+}
+
+func appendFile(t *testing.T, path string, b []byte) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	_, err = f.Write(b)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+}
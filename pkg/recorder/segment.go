@@ -0,0 +1,143 @@
+// +build !js
+
+package recorder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// segmenter writes the records of a stream to its segments, starting a new
+// one when the current one reaches the size or duration limit of the Config
+type segmenter struct {
+	mu sync.Mutex
+
+	r        *Recorder
+	segments *[]*Segment
+	name     string
+	format   format
+
+	file    *os.File
+	current *Segment
+	size    int64
+	records int
+	closed  bool
+}
+
+// write encodes a record at with encode, which is given the offset of at in
+// the segment the record is written to. It returns whether that segment was
+// started by the record.
+func (s *segmenter) write(at time.Time, encode func(offset time.Duration) ([]byte, error)) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false, errClosed
+	}
+
+	if s.current != nil {
+		record, err := encode(at.Sub(s.current.Start))
+		if err != nil {
+			return false, err
+		}
+		if !s.full(at, len(record)) {
+			return false, s.append(record)
+		}
+		if err := s.finish(at); err != nil {
+			return false, err
+		}
+	}
+
+	if err := s.start(at); err != nil {
+		return false, err
+	}
+	record, err := encode(0)
+	if err != nil {
+		return true, err
+	}
+	return true, s.append(record)
+}
+
+// full is whether a record of size written at doesn't fit in the current
+// segment. A segment always takes its first record, however large.
+func (s *segmenter) full(at time.Time, size int) bool {
+	if s.records == 0 {
+		return false
+	}
+	if d := s.r.config.MaxSegmentDuration; d > 0 && at.Sub(s.current.Start) >= d {
+		return true
+	}
+	if max := s.r.config.MaxSegmentSize; max > 0 && s.size+int64(size) > max {
+		return true
+	}
+	return false
+}
+
+func (s *segmenter) start(at time.Time) error {
+	file := fmt.Sprintf("%s-%d%s", s.name, len(*s.segments), s.format.ext())
+	f, err := os.OpenFile(filepath.Join(s.r.dir, file), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	header, err := s.format.header(at)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err := f.Write(header); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	s.file, s.size, s.records = f, int64(len(header)), 0
+	s.current = &Segment{File: file, Start: at}
+	return s.r.updateManifest(func() {
+		*s.segments = append(*s.segments, s.current)
+	})
+}
+
+func (s *segmenter) append(record []byte) error {
+	n, err := s.file.Write(record)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	s.records++
+	return nil
+}
+
+// finish syncs the current segment and marks it finalized in the manifest
+func (s *segmenter) finish(at time.Time) error {
+	syncErr := s.file.Sync()
+	if err := s.file.Close(); err != nil {
+		return err
+	} else if syncErr != nil {
+		return syncErr
+	}
+
+	segment, size, records := s.current, s.size, s.records
+	s.file, s.current = nil, nil
+	return s.r.updateManifest(func() {
+		segment.End = &at
+		segment.Size = size
+		segment.Records = records
+	})
+}
+
+// close finishes the current segment, the segmenter takes no more records
+func (s *segmenter) close(at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.current == nil {
+		return nil
+	}
+	return s.finish(at)
+}
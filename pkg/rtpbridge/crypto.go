@@ -0,0 +1,87 @@
+// +build !js
+
+package rtpbridge
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pion/srtp"
+)
+
+const (
+	// CryptoSuiteAES128HMACSHA180 is the one SDES crypto suite supported,
+	// the one every SIP endpoint offering SRTP supports
+	CryptoSuiteAES128HMACSHA180 = "AES_CM_128_HMAC_SHA1_80"
+
+	srtpMasterKeyLen  = 16
+	srtpMasterSaltLen = 14
+)
+
+// CryptoAttribute is an SDP crypto attribute, the keys an endpoint encrypts
+// its SRTP with, RFC 4568
+type CryptoAttribute struct {
+	Tag   int
+	Suite string
+	Key   []byte
+	Salt  []byte
+}
+
+// NewCryptoAttribute generates a random key and salt
+func NewCryptoAttribute(tag int) (*CryptoAttribute, error) {
+	keying := make([]byte, srtpMasterKeyLen+srtpMasterSaltLen)
+	if _, err := rand.Read(keying); err != nil {
+		return nil, err
+	}
+	return &CryptoAttribute{
+		Tag:   tag,
+		Suite: CryptoSuiteAES128HMACSHA180,
+		Key:   keying[:srtpMasterKeyLen],
+		Salt:  keying[srtpMasterKeyLen:],
+	}, nil
+}
+
+// ParseCryptoAttribute parses the value of an SDP crypto attribute, like
+// "1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz|2^20|1:32".
+// The lifetime and the master key index are ignored.
+func ParseCryptoAttribute(value string) (*CryptoAttribute, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return nil, errMalformedCrypto
+	}
+
+	tag, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, errMalformedCrypto
+	}
+	if fields[1] != CryptoSuiteAES128HMACSHA180 {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedCryptoSuite, fields[1])
+	}
+	if !strings.HasPrefix(fields[2], "inline:") {
+		return nil, errMalformedCrypto
+	}
+
+	encoded := strings.SplitN(strings.TrimPrefix(fields[2], "inline:"), "|", 2)[0]
+	keying, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(keying) != srtpMasterKeyLen+srtpMasterSaltLen {
+		return nil, errMalformedCrypto
+	}
+	return &CryptoAttribute{
+		Tag:   tag,
+		Suite: fields[1],
+		Key:   keying[:srtpMasterKeyLen],
+		Salt:  keying[srtpMasterKeyLen:],
+	}, nil
+}
+
+// String returns the value of the SDP crypto attribute
+func (c *CryptoAttribute) String() string {
+	return fmt.Sprintf("%d %s inline:%s", c.Tag, c.Suite, base64.StdEncoding.EncodeToString(append(append([]byte{}, c.Key...), c.Salt...)))
+}
+
+func (c *CryptoAttribute) context() (*srtp.Context, error) {
+	return srtp.CreateContext(c.Key, c.Salt, srtp.ProtectionProfileAes128CmHmacSha1_80)
+}
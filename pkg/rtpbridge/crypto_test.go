@@ -0,0 +1,37 @@
+// +build !js
+
+package rtpbridge
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCryptoAttribute(t *testing.T) {
+	c, err := ParseCryptoAttribute("1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz|2^20|1:32")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c.Tag)
+	assert.Equal(t, []byte("YS___semctl () {"), c.Key)
+	assert.Len(t, c.Salt, 14)
+	assert.Equal(t, "1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz", c.String())
+
+	generated, err := NewCryptoAttribute(2)
+	assert.NoError(t, err)
+	parsed, err := ParseCryptoAttribute(generated.String())
+	assert.NoError(t, err)
+	assert.Equal(t, generated, parsed)
+
+	_, err = ParseCryptoAttribute("1 AES_CM_128_HMAC_SHA1_32 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz")
+	assert.True(t, errors.Is(err, errUnsupportedCryptoSuite))
+	for _, value := range []string{
+		"",
+		"a AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz",
+		"1 AES_CM_128_HMAC_SHA1_80 WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz",
+		"1 AES_CM_128_HMAC_SHA1_80 inline:WVNf",
+	} {
+		_, err = ParseCryptoAttribute(value)
+		assert.Equal(t, errMalformedCrypto, err)
+	}
+}
@@ -0,0 +1,97 @@
+// +build !js
+
+package rtpbridge
+
+import (
+	"io"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// RTPWriter is a sink of RTP packets, like a local webrtc.Track or a Leg
+type RTPWriter interface {
+	WriteRTP(p *rtp.Packet) error
+}
+
+// Remapper rewrites the packets forwarded from one side of a bridge to the
+// other, whose payload types and SSRC are negotiated separately
+type Remapper struct {
+	// PayloadTypes maps the payload types of the reader to the ones of the
+	// writer, packets of payload types it has no entry for are dropped. Nil
+	// keeps every payload type.
+	PayloadTypes map[uint8]uint8
+
+	// SSRC, if not zero, replaces the SSRC of the packets, like with the one
+	// of the local Track they are written to. The sequence numbers and the
+	// timestamps continue when the SSRC of the reader changes, like on a
+	// re-INVITE, so the writer sees a single stream.
+	SSRC uint32
+
+	// ClockRate is the one of the payload, 8000 for G.711. It lets the
+	// timestamps continue at the right pace when the SSRC of the reader
+	// changes.
+	ClockRate uint32
+
+	started             bool
+	sourceSSRC          uint32
+	seqOffset, tsOffset uint32
+	lastSeq, lastTS     uint32
+	lastTime            time.Time
+}
+
+// Remap rewrites p, it reports false if p is to be dropped
+func (r *Remapper) Remap(p *rtp.Packet) bool {
+	if r.PayloadTypes != nil {
+		payloadType, ok := r.PayloadTypes[p.PayloadType]
+		if !ok {
+			return false
+		}
+		p.PayloadType = payloadType
+	}
+
+	if r.SSRC == 0 {
+		return true
+	}
+
+	now := time.Now()
+	if r.started && p.SSRC != r.sourceSSRC {
+		ticks := uint32(1)
+		if r.ClockRate != 0 {
+			ticks = uint32(uint64(now.Sub(r.lastTime)) * uint64(r.ClockRate) / uint64(time.Second))
+		}
+		r.seqOffset = r.lastSeq + 1 - uint32(p.SequenceNumber)
+		r.tsOffset = r.lastTS + ticks - p.Timestamp
+	}
+	r.started = true
+	r.sourceSSRC = p.SSRC
+
+	p.SSRC = r.SSRC
+	p.SequenceNumber += uint16(r.seqOffset)
+	p.Timestamp += r.tsOffset
+	r.lastSeq, r.lastTS, r.lastTime = uint32(p.SequenceNumber), p.Timestamp, now
+	return true
+}
+
+// Forward copies the packets of r to w, rewritten by remap if it isn't nil,
+// until reading or writing fails. It returns nil when r ends with io.EOF,
+// like a remote Track whose receiver stopped. Packets written to a local
+// Track that isn't sent yet are dropped.
+func Forward(r media.RTPReader, w RTPWriter, remap *Remapper) error {
+	for {
+		packet, err := r.ReadRTP()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if remap != nil && !remap.Remap(packet) {
+			continue
+		}
+		if err := w.WriteRTP(packet); err != nil && err != io.ErrClosedPipe {
+			return err
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// +build !js
+
+package rtpbridge
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type packetSource []*rtp.Packet
+
+func (s *packetSource) ReadRTP() (*rtp.Packet, error) {
+	if len(*s) == 0 {
+		return nil, io.EOF
+	}
+	p := (*s)[0]
+	*s = (*s)[1:]
+	return p, nil
+}
+
+type packetSink struct {
+	packets []*rtp.Packet
+	err     error
+}
+
+func (s *packetSink) WriteRTP(p *rtp.Packet) error {
+	s.packets = append(s.packets, p)
+	return s.err
+}
+
+func TestRemapper(t *testing.T) {
+	r := &Remapper{PayloadTypes: map[uint8]uint8{0: 0, 101: 126}, SSRC: 1234, ClockRate: 8000}
+
+	remapped := func(payloadType uint8, ssrc uint32, seq uint16, ts uint32) *rtp.Packet {
+		p := &rtp.Packet{Header: rtp.Header{PayloadType: payloadType, SSRC: ssrc, SequenceNumber: seq, Timestamp: ts}}
+		if !r.Remap(p) {
+			return nil
+		}
+		return p
+	}
+
+	assert.Nil(t, remapped(8, 1, 10, 160))
+
+	p := remapped(101, 1, 10, 160)
+	assert.Equal(t, uint8(126), p.PayloadType)
+	assert.Equal(t, uint32(1234), p.SSRC)
+	assert.Equal(t, uint16(10), p.SequenceNumber)
+	assert.Equal(t, uint32(160), p.Timestamp)
+
+	// A new source continues the stream
+	p = remapped(0, 2, 60000, 90000)
+	assert.Equal(t, uint32(1234), p.SSRC)
+	assert.Equal(t, uint16(11), p.SequenceNumber)
+	assert.True(t, p.Timestamp >= 160)
+	first := p.Timestamp
+
+	p = remapped(0, 2, 60001, 90160)
+	assert.Equal(t, uint16(12), p.SequenceNumber)
+	assert.Equal(t, first+160, p.Timestamp)
+
+	// Without SSRC only payload types are remapped
+	r = &Remapper{}
+	p = remapped(8, 1, 10, 160)
+	assert.Equal(t, uint8(8), p.PayloadType)
+	assert.Equal(t, uint32(1), p.SSRC)
+}
+
+func TestForward(t *testing.T) {
+	source := &packetSource{
+		{Header: rtp.Header{PayloadType: 0}},
+		{Header: rtp.Header{PayloadType: 13}},
+		{Header: rtp.Header{PayloadType: 101}},
+	}
+
+	// A Track that isn't sent yet doesn't end forwarding
+	sink := &packetSink{err: io.ErrClosedPipe}
+	assert.NoError(t, Forward(source, sink, &Remapper{PayloadTypes: map[uint8]uint8{0: 0, 101: 126}}))
+	assert.Len(t, sink.packets, 2)
+	assert.Equal(t, uint8(126), sink.packets[1].PayloadType)
+
+	errWrite := errors.New("write failed")
+	source = &packetSource{{}}
+	assert.Equal(t, errWrite, Forward(source, &packetSink{err: errWrite}, nil))
+}
@@ -0,0 +1,135 @@
+// +build !js
+
+package rtpbridge
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/srtp"
+)
+
+// LegConfig configures a Leg
+type LegConfig struct {
+	// Remote is the address of the connection and the media port of the
+	// remote SDP. Without it packets are sent to where the ones of the remote
+	// come from, as they are with SymmetricRTP.
+	Remote net.Addr
+
+	// SymmetricRTP sends to where the packets of the remote come from, which
+	// gets through the NAT a remote may be behind
+	SymmetricRTP bool
+
+	// LocalCrypto and RemoteCrypto are the crypto attributes of the local and
+	// the remote SDP. Both are nil for plain RTP.
+	LocalCrypto  *CryptoAttribute
+	RemoteCrypto *CryptoAttribute
+}
+
+// Leg is a media leg of RTP, or SDES-SRTP, over UDP. RTCP that is muxed on
+// the port is dropped.
+type Leg struct {
+	conn         net.PacketConn
+	symmetricRTP bool
+
+	mu     sync.Mutex
+	remote net.Addr
+
+	// srtp.Context is not safe for concurrent use, encrypt is guarded by
+	// encryptMu and decrypt is only used by ReadRTP
+	encryptMu sync.Mutex
+	encrypt   *srtp.Context
+	decrypt   *srtp.Context
+}
+
+// NewLeg creates a Leg on conn, a UDP socket bound to the media port of the
+// local SDP
+func NewLeg(conn net.PacketConn, config LegConfig) (*Leg, error) {
+	l := &Leg{
+		conn:         conn,
+		symmetricRTP: config.SymmetricRTP || config.Remote == nil,
+		remote:       config.Remote,
+	}
+
+	if (config.LocalCrypto == nil) != (config.RemoteCrypto == nil) {
+		return nil, errCryptoMismatch
+	} else if config.LocalCrypto == nil {
+		return l, nil
+	}
+
+	var err error
+	if l.encrypt, err = config.LocalCrypto.context(); err != nil {
+		return nil, err
+	}
+	if l.decrypt, err = config.RemoteCrypto.context(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ReadRTP returns the next RTP packet of the remote, packets that fail to
+// decrypt or to parse are dropped
+func (l *Leg) ReadRTP() (*rtp.Packet, error) {
+	b := make([]byte, receiveMTU)
+	for {
+		n, addr, err := l.conn.ReadFrom(b)
+		if err != nil {
+			return nil, err
+		}
+		if n < 2 || (b[1] >= rtcpPayloadTypeMin && b[1] <= rtcpPayloadTypeMax) {
+			continue
+		}
+
+		raw := b[:n]
+		if l.decrypt != nil {
+			header := &rtp.Header{}
+			if raw, err = l.decrypt.DecryptRTP(nil, raw, header); err != nil {
+				continue
+			}
+		}
+
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(raw); err != nil {
+			continue
+		}
+
+		if l.symmetricRTP {
+			l.mu.Lock()
+			l.remote = addr
+			l.mu.Unlock()
+		}
+		return packet, nil
+	}
+}
+
+// WriteRTP sends a packet to the remote
+func (l *Leg) WriteRTP(p *rtp.Packet) error {
+	l.mu.Lock()
+	remote := l.remote
+	l.mu.Unlock()
+	if remote == nil {
+		return errNoRemote
+	}
+
+	raw, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+	if l.encrypt != nil {
+		l.encryptMu.Lock()
+		raw, err = l.encrypt.EncryptRTP(nil, raw, &p.Header)
+		l.encryptMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = l.conn.WriteTo(raw, remote)
+	return err
+}
+
+// Close closes the socket of the Leg
+func (l *Leg) Close() error {
+	return l.conn.Close()
+}
@@ -0,0 +1,64 @@
+// +build !js
+
+package rtpbridge
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func listenUDP(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	return conn
+}
+
+func TestLeg(t *testing.T) {
+	for _, srtp := range []bool{false, true} {
+		var gatewayCrypto, phoneCrypto *CryptoAttribute
+		if srtp {
+			var err error
+			gatewayCrypto, err = NewCryptoAttribute(1)
+			assert.NoError(t, err)
+			phoneCrypto, err = NewCryptoAttribute(1)
+			assert.NoError(t, err)
+		}
+
+		gatewayConn, phoneConn := listenUDP(t), listenUDP(t)
+
+		// The gateway learns the address of the phone from its packets
+		gateway, err := NewLeg(gatewayConn, LegConfig{LocalCrypto: gatewayCrypto, RemoteCrypto: phoneCrypto})
+		assert.NoError(t, err)
+		phone, err := NewLeg(phoneConn, LegConfig{Remote: gatewayConn.LocalAddr(), LocalCrypto: phoneCrypto, RemoteCrypto: gatewayCrypto})
+		assert.NoError(t, err)
+
+		packet := &rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: 0, SequenceNumber: 1, SSRC: 5000}, Payload: []byte{0xFF, 0xFF}}
+		assert.Equal(t, errNoRemote, gateway.WriteRTP(packet))
+
+		// Muxed RTCP is dropped
+		_, err = phoneConn.WriteTo([]byte{0x80, 0xC8, 0, 0}, gatewayConn.LocalAddr())
+		assert.NoError(t, err)
+
+		assert.NoError(t, phone.WriteRTP(packet))
+		received, err := gateway.ReadRTP()
+		assert.NoError(t, err)
+		assert.Equal(t, packet.Payload, received.Payload)
+		assert.Equal(t, packet.SSRC, received.SSRC)
+
+		packet.SequenceNumber++
+		assert.NoError(t, gateway.WriteRTP(packet))
+		received, err = phone.ReadRTP()
+		assert.NoError(t, err)
+		assert.Equal(t, uint16(2), received.SequenceNumber)
+		assert.Equal(t, packet.Payload, received.Payload)
+
+		assert.NoError(t, gateway.Close())
+		assert.NoError(t, phone.Close())
+	}
+
+	_, err := NewLeg(listenUDP(t), LegConfig{LocalCrypto: &CryptoAttribute{}})
+	assert.Equal(t, errCryptoMismatch, err)
+}
@@ -0,0 +1,34 @@
+// +build !js
+
+// Package rtpbridge bridges WebRTC to plain RTP media legs, like the one of
+// a SIP call, to build telephony gateways on. A Leg sends and receives RTP
+// over UDP, encrypted with SDES-SRTP if keys were exchanged in the SDP of the
+// call. Forward copies the packets between the remote Tracks, Legs and local
+// Tracks, remapping payload types and SSRCs.
+//
+// Nothing is transcoded: the PeerConnection and the leg have to agree on a
+// codec, like G.711 with NewRTPPassthroughAudioCodec or Opus. RFC 4733
+// telephone-events pass through like any other payload type, so DTMF is
+// carried as long as the Remapper maps its payload type. The SIP signaling
+// itself is left to a SIP stack.
+package rtpbridge
+
+import (
+	"errors"
+)
+
+const (
+	receiveMTU = 1460
+
+	// rtcpPayloadTypeMin and rtcpPayloadTypeMax bound the packet types of
+	// RTCP, which tell it apart from RTP on a muxed port, RFC 5761 4
+	rtcpPayloadTypeMin = 192
+	rtcpPayloadTypeMax = 223
+)
+
+var (
+	errUnsupportedCryptoSuite = errors.New("rtpbridge: unsupported crypto suite")
+	errMalformedCrypto        = errors.New("rtpbridge: malformed crypto attribute")
+	errCryptoMismatch         = errors.New("rtpbridge: both or none of the crypto attributes are needed")
+	errNoRemote               = errors.New("rtpbridge: remote address of the leg is unknown")
+)
@@ -0,0 +1,497 @@
+// +build !js
+
+package rtsp
+
+import (
+	"bufio"
+	"crypto/md5" // nolint: gosec
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
+)
+
+// Client opens sessions on RTSP servers
+type Client struct {
+	// Timeout limits dialing and every request until the session plays,
+	// 10 seconds if zero
+	Timeout time.Duration
+
+	// UserAgent, if not empty, is sent with every request
+	UserAgent string
+}
+
+// Session is an RTSP session opened by Client.Dial. Its streams are set up
+// with Setup, then Play starts them and ReadRTP returns their packets.
+type Session struct {
+	client *Client
+	conn   net.Conn
+	reader *bufio.Reader
+
+	uri  string
+	base string
+	user *url.Userinfo
+
+	medias   []Media
+	channels map[uint8]int
+
+	mu        sync.Mutex
+	cseq      int
+	id        string
+	keepalive time.Duration
+	challenge map[string]string
+	playing   bool
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type message struct {
+	// status is the status code of a response, zero for a request
+	status int
+	line   string
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// Dial connects to the server of rawurl and describes its streams
+func (c *Client) Dial(rawurl string) (*Session, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	} else if u.Scheme != "rtsp" {
+		return nil, errNotRTSP
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	conn, err := net.DialTimeout("tcp", host, c.timeout())
+	if err != nil {
+		return nil, err
+	}
+
+	user := u.User
+	u.User = nil
+	s := &Session{
+		client:    c,
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		uri:       u.String(),
+		base:      u.String(),
+		user:      user,
+		channels:  map[uint8]int{},
+		keepalive: defaultSessionTimeout / 2,
+		closed:    make(chan struct{}),
+	}
+
+	if err := s.describe(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return defaultTimeout
+	}
+	return c.Timeout
+}
+
+// Medias returns the streams the server offers
+func (s *Session) Medias() []Media {
+	return append([]Media{}, s.medias...)
+}
+
+// Setup sets up the stream of the Media at index of Medias, its packets are
+// returned by ReadRTP once the Session plays
+func (s *Session) Setup(index int) error {
+	if index < 0 || index >= len(s.medias) {
+		return fmt.Errorf("rtsp: no media %d", index)
+	}
+
+	channel := uint8(2 * len(s.channels))
+	res, err := s.request("SETUP", s.controlURL(s.medias[index].control), map[string]string{
+		"Transport": fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", channel, channel+1),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The server may choose other channels
+	for _, param := range strings.Split(res.header.Get("Transport"), ";") {
+		if strings.HasPrefix(param, "interleaved=") {
+			rtpChannel := strings.SplitN(strings.TrimPrefix(param, "interleaved="), "-", 2)[0]
+			if parsed, parseErr := strconv.ParseUint(rtpChannel, 10, 8); parseErr == nil {
+				channel = uint8(parsed)
+			}
+		}
+	}
+	s.channels[channel] = index
+
+	id := res.header.Get("Session")
+	if id == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	params := strings.Split(id, ";")
+	s.id = strings.TrimSpace(params[0])
+	for _, param := range params[1:] {
+		param = strings.TrimSpace(param)
+		if !strings.HasPrefix(param, "timeout=") {
+			continue
+		}
+		if timeout, parseErr := strconv.Atoi(strings.TrimPrefix(param, "timeout=")); parseErr == nil && timeout > 0 {
+			s.keepalive = time.Duration(timeout) * time.Second / 2
+		}
+	}
+	return nil
+}
+
+// Play starts the streams that are set up, and keeps the session alive
+// until it is closed
+func (s *Session) Play() error {
+	if _, err := s.request("PLAY", s.base, map[string]string{"Range": "npt=0.000-"}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.playing = true
+	keepalive := s.keepalive
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(keepalive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.closed:
+				return
+			case <-ticker.C:
+			}
+
+			// The response is skipped by ReadRTP
+			if err := s.send("OPTIONS", s.base, nil); err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// ReadRTP returns the next RTP packet of the streams that are set up, and
+// the index of the Media it belongs to. RTCP packets of the server are
+// dropped.
+func (s *Session) ReadRTP() (int, *rtp.Packet, error) {
+	s.mu.Lock()
+	playing := s.playing
+	s.mu.Unlock()
+	if !playing {
+		return 0, nil, errNotPlaying
+	}
+
+	for {
+		marker, err := s.reader.Peek(1)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		// Responses to keepalives and requests of the server
+		if marker[0] != interleavedMarker {
+			if _, err = readMessage(s.reader); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		header := make([]byte, 4)
+		if _, err = io.ReadFull(s.reader, header); err != nil {
+			return 0, nil, err
+		}
+		payload := make([]byte, binary.BigEndian.Uint16(header[2:]))
+		if _, err = io.ReadFull(s.reader, payload); err != nil {
+			return 0, nil, err
+		}
+
+		index, ok := s.channels[header[1]]
+		if !ok {
+			continue
+		}
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(payload); err != nil {
+			continue
+		}
+		return index, packet, nil
+	}
+}
+
+// Close tears the session down and closes the connection
+func (s *Session) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		_ = s.send("TEARDOWN", s.base, nil)
+		err = s.conn.Close()
+	})
+	return err
+}
+
+func (s *Session) describe() error {
+	res, err := s.request("DESCRIBE", s.uri, map[string]string{"Accept": "application/sdp"})
+	if err != nil {
+		return err
+	}
+
+	if base := res.header.Get("Content-Base"); base != "" {
+		s.base = base
+	} else if location := res.header.Get("Content-Location"); location != "" {
+		s.base = location
+	}
+
+	description := &sdp.SessionDescription{}
+	if err := description.Unmarshal(res.body); err != nil {
+		return err
+	}
+	if control, ok := description.Attribute("control"); ok {
+		s.base = s.controlURL(control)
+	}
+
+	s.medias = parseMedias(description)
+	return nil
+}
+
+// controlURL resolves the control attribute of a media section against the
+// base URL of the session
+func (s *Session) controlURL(control string) string {
+	switch {
+	case control == "" || control == "*":
+		return s.base
+	case strings.HasPrefix(strings.ToLower(control), "rtsp://"):
+		return control
+	case strings.HasSuffix(s.base, "/"):
+		return s.base + control
+	default:
+		return s.base + "/" + control
+	}
+}
+
+// request sends a request and waits for its response, it is only used until
+// the session plays since ReadRTP owns the connection afterwards
+func (s *Session) request(method, uri string, header map[string]string) (*message, error) {
+	timeout := s.client.timeout()
+	for attempt := 0; ; attempt++ {
+		if err := s.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+		if err := s.send(method, uri, header); err != nil {
+			return nil, err
+		}
+		res, err := readMessage(s.reader)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.conn.SetDeadline(time.Time{}); err != nil {
+			return nil, err
+		}
+
+		if res.status == 401 && attempt == 0 && s.user != nil {
+			s.mu.Lock()
+			s.challenge = parseChallenge(res.header["Www-Authenticate"])
+			s.mu.Unlock()
+			continue
+		} else if res.status != 200 {
+			return nil, fmt.Errorf("rtsp: %s %s: %s", method, uri, res.line)
+		}
+		return res, nil
+	}
+}
+
+func (s *Session) send(method, uri string, header map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cseq++
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\r\nCSeq: %d\r\n", method, uri, rtspVersion, s.cseq)
+	if s.client.UserAgent != "" {
+		fmt.Fprintf(&b, "User-Agent: %s\r\n", s.client.UserAgent)
+	}
+	if s.id != "" {
+		fmt.Fprintf(&b, "Session: %s\r\n", s.id)
+	}
+	if authorization := s.authorization(method, uri); authorization != "" {
+		fmt.Fprintf(&b, "Authorization: %s\r\n", authorization)
+	}
+	for key, value := range header {
+		fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+	}
+	b.WriteString("\r\n")
+
+	_, err := io.WriteString(s.conn, b.String())
+	return err
+}
+
+// authorization answers the last challenge of the server, Digest
+// authentication without quality of protection, RFC 2069, is all cameras
+// commonly ask for
+func (s *Session) authorization(method, uri string) string {
+	if s.challenge == nil || s.user == nil {
+		return ""
+	}
+
+	username := s.user.Username()
+	password, _ := s.user.Password()
+	if s.challenge["scheme"] == "basic" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	}
+
+	realm, nonce := s.challenge["realm"], s.challenge["nonce"]
+	ha1 := md5Hex(username + ":" + realm + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+	return fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		username, realm, nonce, uri, md5Hex(ha1+":"+nonce+":"+ha2))
+}
+
+// parseChallenge parses the WWW-Authenticate headers of a response,
+// preferring Digest to Basic
+func parseChallenge(headers []string) map[string]string {
+	var challenge map[string]string
+	for _, header := range headers {
+		fields := strings.SplitN(strings.TrimSpace(header), " ", 2)
+		scheme := strings.ToLower(fields[0])
+		if scheme != "digest" && scheme != "basic" {
+			continue
+		}
+
+		params := map[string]string{"scheme": scheme}
+		if len(fields) == 2 {
+			for _, param := range splitParams(fields[1]) {
+				kv := strings.SplitN(param, "=", 2)
+				if len(kv) == 2 {
+					params[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+				}
+			}
+		}
+		if challenge == nil || scheme == "digest" {
+			challenge = params
+		}
+	}
+	return challenge
+}
+
+// splitParams splits the comma separated parameters of a challenge, commas
+// in quoted values don't separate
+func splitParams(s string) []string {
+	var params []string
+	quoted, start := false, 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, s[start:])
+}
+
+func readMessage(r *bufio.Reader) (*message, error) {
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &message{line: line, header: header}
+	if strings.HasPrefix(line, rtspVersion) {
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 2 {
+			return nil, errMalformedMessage
+		}
+		if m.status, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, errMalformedMessage
+		}
+	}
+
+	if length := header.Get("Content-Length"); length != "" {
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 0 {
+			return nil, errMalformedMessage
+		} else if n > maxBodySize {
+			return nil, errBodyTooLarge
+		}
+		m.body = make([]byte, n)
+		if _, err := io.ReadFull(r, m.body); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func parseMedias(description *sdp.SessionDescription) []Media {
+	medias := []Media{}
+	for _, section := range description.MediaDescriptions {
+		if len(section.MediaName.Formats) == 0 {
+			continue
+		}
+		payloadType, err := strconv.ParseUint(section.MediaName.Formats[0], 10, 8)
+		if err != nil {
+			continue
+		}
+
+		// Static payload types may come without rtpmap
+		media := staticMedias[uint8(payloadType)]
+		media.Kind, media.PayloadType = section.MediaName.Media, uint8(payloadType)
+		media.control, _ = section.Attribute("control")
+		for _, attribute := range section.Attributes {
+			fields := strings.SplitN(attribute.Value, " ", 2)
+			if len(fields) != 2 || fields[0] != section.MediaName.Formats[0] {
+				continue
+			}
+
+			switch attribute.Key {
+			case "rtpmap":
+				// <encoding name>/<clock rate>[/<channels>]
+				encoding := strings.Split(fields[1], "/")
+				media.Codec = strings.ToUpper(encoding[0])
+				if len(encoding) > 1 {
+					clockRate, _ := strconv.ParseUint(encoding[1], 10, 32)
+					media.ClockRate = uint32(clockRate)
+				}
+				if len(encoding) > 2 {
+					channels, _ := strconv.ParseUint(encoding[2], 10, 16)
+					media.Channels = uint16(channels)
+				}
+			case "fmtp":
+				media.Fmtp = fields[1]
+			}
+		}
+		medias = append(medias, media)
+	}
+	return medias
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s)) // nolint: gosec
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,151 @@
+// +build !js
+
+package rtsp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSDP = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=camera\r\n" +
+	"t=0 0\r\n" +
+	"a=control:*\r\n" +
+	"m=video 0 RTP/AVP 96\r\n" +
+	"a=rtpmap:96 H264/90000\r\n" +
+	"a=fmtp:96 packetization-mode=1;sprop-parameter-sets=Z0IAH5WoFAFuQA==,aM48gA==\r\n" +
+	"a=control:trackID=1\r\n" +
+	"m=audio 0 RTP/AVP 0\r\n" +
+	"a=control:trackID=2\r\n"
+
+// serveCamera answers the requests of one client like a camera asking for
+// Digest authentication, it returns the request lines it got
+func serveCamera(t *testing.T, listener net.Listener) <-chan []string {
+	requests := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close() // nolint: errcheck
+
+		lines := []string{}
+		reader := bufio.NewReader(conn)
+		for {
+			req, err := readMessage(reader)
+			if err != nil {
+				requests <- lines
+				return
+			}
+			lines = append(lines, req.line)
+
+			fields := strings.Fields(req.line)
+			method, uri := fields[0], fields[1]
+			if !strings.Contains(req.header.Get("Authorization"), `response="`+digestResponse(method, uri)+`"`) {
+				fmt.Fprintf(conn, "RTSP/1.0 401 Unauthorized\r\nCSeq: %s\r\nWWW-Authenticate: Basic realm=\"camera\"\r\nWWW-Authenticate: Digest realm=\"camera\", nonce=\"abc,def\"\r\n\r\n", req.header.Get("CSeq"))
+				continue
+			}
+
+			fmt.Fprintf(conn, "RTSP/1.0 200 OK\r\nCSeq: %s\r\n", req.header.Get("CSeq"))
+			switch method {
+			case "DESCRIBE":
+				fmt.Fprintf(conn, "Content-Base: rtsp://%s/stream/\r\nContent-Length: %d\r\n\r\n%s", listener.Addr(), len(testSDP), testSDP)
+			case "SETUP":
+				fmt.Fprintf(conn, "Session: 1234;timeout=1\r\nTransport: RTP/AVP/TCP;unicast;interleaved=4-5\r\n\r\n")
+			case "PLAY":
+				fmt.Fprintf(conn, "\r\n")
+				writeInterleaved(t, conn, 5, []byte{0x80, 0xC8, 0, 0}) // RTCP
+				packet := &rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 7}, Payload: []byte{0x65}}
+				raw, err := packet.Marshal()
+				assert.NoError(t, err)
+				writeInterleaved(t, conn, 4, raw)
+			default:
+				fmt.Fprintf(conn, "\r\n")
+			}
+		}
+	}()
+	return requests
+}
+
+func digestResponse(method, uri string) string {
+	ha1 := md5Hex("user:camera:pass")
+	return md5Hex(ha1 + ":abc,def:" + md5Hex(method+":"+uri))
+}
+
+func writeInterleaved(t *testing.T, conn net.Conn, channel uint8, payload []byte) {
+	header := []byte{interleavedMarker, channel, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	_, err := conn.Write(append(header, payload...))
+	assert.NoError(t, err)
+}
+
+func TestSession(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close() // nolint: errcheck
+	requests := serveCamera(t, listener)
+
+	_, err = (&Client{}).Dial("http://" + listener.Addr().String())
+	assert.Equal(t, errNotRTSP, err)
+
+	s, err := (&Client{Timeout: time.Second}).Dial("rtsp://user:pass@" + listener.Addr().String() + "/stream")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Media{
+		{Kind: "video", PayloadType: 96, Codec: "H264", ClockRate: 90000, Fmtp: "packetization-mode=1;sprop-parameter-sets=Z0IAH5WoFAFuQA==,aM48gA==", control: "trackID=1"},
+		{Kind: "audio", PayloadType: 0, Codec: "PCMU", ClockRate: 8000, Channels: 1, control: "trackID=2"},
+	}, s.Medias())
+
+	_, _, err = s.ReadRTP()
+	assert.Equal(t, errNotPlaying, err)
+
+	assert.Error(t, s.Setup(2))
+	assert.NoError(t, s.Setup(0))
+	assert.NoError(t, s.Play())
+
+	// The RTCP packet is dropped, the server chose other channels
+	index, packet, err := s.ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, index)
+	assert.Equal(t, uint16(7), packet.SequenceNumber)
+
+	// The session timeout of a second asks for a keepalive every 500ms
+	time.Sleep(700 * time.Millisecond)
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Close())
+
+	// Only the first request is challenged
+	base := "rtsp://" + listener.Addr().String() + "/stream/"
+	assert.Equal(t, []string{
+		"DESCRIBE rtsp://" + listener.Addr().String() + "/stream RTSP/1.0",
+		"DESCRIBE rtsp://" + listener.Addr().String() + "/stream RTSP/1.0",
+		"SETUP " + base + "trackID=1 RTSP/1.0",
+		"PLAY " + base + " RTSP/1.0",
+		"OPTIONS " + base + " RTSP/1.0",
+		"TEARDOWN " + base + " RTSP/1.0",
+	}, <-requests)
+}
+
+func TestReadMessage_BodyTooLarge(t *testing.T) {
+	response := fmt.Sprintf("RTSP/1.0 200 OK\r\nContent-Length: %d\r\n\r\n", maxBodySize+1)
+	_, err := readMessage(bufio.NewReader(strings.NewReader(response)))
+	assert.Equal(t, errBodyTooLarge, err)
+}
+
+func TestParseChallenge(t *testing.T) {
+	assert.Nil(t, parseChallenge([]string{"Bearer realm=\"camera\""}))
+	assert.Equal(t, map[string]string{"scheme": "basic", "realm": "camera"}, parseChallenge([]string{"Basic realm=\"camera\""}))
+	assert.Equal(t, map[string]string{"scheme": "digest", "realm": "a, b", "nonce": "c"}, parseChallenge([]string{
+		"Digest realm=\"a, b\", nonce=\"c\"",
+		"Basic realm=\"camera\"",
+	}))
+}
@@ -0,0 +1,121 @@
+// +build !js
+
+package rtsp
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	h264NALUTypeIDR   = 5
+	h264NALUTypeSPS   = 7
+	h264NALUTypeSTAPA = 24
+	h264NALUTypeFUA   = 28
+
+	h264NALUTypeMask = 0x1F
+	h264FUAStartBit  = 0x80
+)
+
+// forwarder rewrites the packets of a stream for the Track they are written to
+type forwarder struct {
+	payloadType uint8
+	ssrc        uint32
+	write       func(*rtp.Packet) error
+
+	// parameterSets are the SPS and PPS of the sprop-parameter-sets of an
+	// H264 stream. Cameras often only announce them in the SDP, but browsers
+	// can't decode an IDR picture without them, so they are sent in front of
+	// every IDR picture the camera doesn't send them with.
+	parameterSets [][]byte
+	sawSPS        bool
+
+	// seqOffset counts the packets inserted into the stream
+	seqOffset uint16
+}
+
+func (f *forwarder) forward(p *rtp.Packet) error {
+	p.PayloadType = f.payloadType
+	p.SSRC = f.ssrc
+
+	if len(f.parameterSets) != 0 && len(p.Payload) != 0 {
+		if err := f.insertParameterSets(p); err != nil {
+			return err
+		}
+	}
+
+	p.SequenceNumber += f.seqOffset
+	return f.write(p)
+}
+
+func (f *forwarder) insertParameterSets(p *rtp.Packet) error {
+	switch nalType := p.Payload[0] & h264NALUTypeMask; nalType {
+	case h264NALUTypeSPS:
+		f.sawSPS = true
+		return nil
+	case h264NALUTypeSTAPA:
+		for _, nalu := range splitSTAPA(p.Payload) {
+			if nalu[0]&h264NALUTypeMask == h264NALUTypeSPS {
+				f.sawSPS = true
+			}
+		}
+		return nil
+	case h264NALUTypeIDR:
+	case h264NALUTypeFUA:
+		if len(p.Payload) < 2 || p.Payload[1]&h264FUAStartBit == 0 || p.Payload[1]&h264NALUTypeMask != h264NALUTypeIDR {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	if f.sawSPS {
+		f.sawSPS = false
+		return nil
+	}
+	for _, parameterSet := range f.parameterSets {
+		inserted := &rtp.Packet{Header: p.Header, Payload: parameterSet}
+		inserted.Marker = false
+		inserted.SequenceNumber += f.seqOffset
+		f.seqOffset++
+		if err := f.write(inserted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitSTAPA returns the NAL units of an STAP-A payload, RFC 6184 5.7.1
+func splitSTAPA(payload []byte) [][]byte {
+	nalus := [][]byte{}
+	for i := 1; i+2 < len(payload); {
+		size := int(payload[i])<<8 | int(payload[i+1])
+		i += 2
+		if size == 0 || i+size > len(payload) {
+			break
+		}
+		nalus = append(nalus, payload[i:i+size])
+		i += size
+	}
+	return nalus
+}
+
+// parseParameterSets parses the sprop-parameter-sets of the fmtp of an H264
+// stream, RFC 6184 8.1
+func parseParameterSets(fmtp string) [][]byte {
+	parameterSets := [][]byte{}
+	for _, param := range strings.Split(fmtp, ";") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 || kv[0] != "sprop-parameter-sets" {
+			continue
+		}
+		for _, encoded := range strings.Split(kv[1], ",") {
+			if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(decoded) != 0 {
+				parameterSets = append(parameterSets, decoded)
+			}
+		}
+	}
+	return parameterSets
+}
@@ -0,0 +1,60 @@
+// +build !js
+
+package rtsp
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwarder(t *testing.T) {
+	sps, pps := []byte{0x67, 0x42}, []byte{0x68, 0xCE}
+	written := []*rtp.Packet{}
+	f := &forwarder{
+		payloadType:   102,
+		ssrc:          5000,
+		parameterSets: parseParameterSets("packetization-mode=1; sprop-parameter-sets=Z0I=,aM4="),
+		write: func(p *rtp.Packet) error {
+			written = append(written, p)
+			return nil
+		},
+	}
+	assert.Equal(t, [][]byte{sps, pps}, f.parameterSets)
+
+	forward := func(seq uint16, payload ...byte) {
+		assert.NoError(t, f.forward(&rtp.Packet{
+			Header:  rtp.Header{PayloadType: 96, SSRC: 1, SequenceNumber: seq, Timestamp: 3000, Marker: true},
+			Payload: payload,
+		}))
+	}
+
+	// The parameter sets are sent in front of the IDR picture
+	forward(10, 0x65, 0xAA)
+	assert.Len(t, written, 3)
+	assert.Equal(t, sps, written[0].Payload)
+	assert.Equal(t, pps, written[1].Payload)
+	for i, p := range written {
+		assert.Equal(t, uint8(102), p.PayloadType)
+		assert.Equal(t, uint32(5000), p.SSRC)
+		assert.Equal(t, uint32(3000), p.Timestamp)
+		assert.Equal(t, uint16(10+i), p.SequenceNumber)
+		assert.Equal(t, i == 2, p.Marker)
+	}
+
+	// A camera sending them in-band isn't repeated, an STAP-A of SPS and PPS
+	// followed by the start of an FU-A of an IDR picture
+	forward(11, 0x78, 0x00, 0x02, 0x67, 0x42, 0x00, 0x02, 0x68, 0xCE)
+	forward(12, 0x7C, 0x85, 0xAA)
+	assert.Len(t, written, 5)
+	assert.Equal(t, uint16(14), written[4].SequenceNumber)
+
+	// Other pictures pass
+	forward(13, 0x41, 0xAA)
+	forward(14, 0x7C, 0x05, 0xAA)
+	assert.Len(t, written, 7)
+	forward(15, 0x7C, 0x85, 0xAA)
+	assert.Len(t, written, 10)
+	assert.Equal(t, uint16(19), written[9].SequenceNumber)
+}
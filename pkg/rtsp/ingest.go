@@ -0,0 +1,98 @@
+// +build !js
+
+package rtsp
+
+import (
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// Ingest republishes the streams of a Session as Tracks. A stream is
+// republished when the MediaEngine has a codec of the same name and clock
+// rate, the packets are forwarded as they are so nothing is transcoded.
+//
+// Browsers decode the H264 of cameras, but not their AAC. AAC streams are
+// only forwarded to peers that negotiate it, when the MediaEngine has an
+// MPEG4-GENERIC codec.
+type Ingest struct {
+	session    *Session
+	tracks     []*webrtc.Track
+	forwarders map[int]*forwarder
+}
+
+// NewIngest sets up the streams of s that have a codec in mediaEngine and
+// creates a Track for each, to be added to PeerConnections of an API with
+// the same MediaEngine
+func NewIngest(s *Session, mediaEngine *webrtc.MediaEngine) (*Ingest, error) {
+	i := &Ingest{session: s, forwarders: map[int]*forwarder{}}
+	for index, media := range s.Medias() {
+		codec := findCodec(mediaEngine, media)
+		if codec == nil {
+			continue
+		}
+
+		track, err := webrtc.NewTrack(codec.PayloadType, rand.Uint32(), media.Kind, "rtsp", codec) // nolint: gosec
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Setup(index); err != nil {
+			return nil, err
+		}
+
+		f := &forwarder{payloadType: codec.PayloadType, ssrc: track.SSRC(), write: track.WriteRTP}
+		if strings.EqualFold(media.Codec, webrtc.H264) {
+			f.parameterSets = parseParameterSets(media.Fmtp)
+		}
+		i.tracks = append(i.tracks, track)
+		i.forwarders[index] = f
+	}
+
+	if len(i.tracks) == 0 {
+		return nil, errNoStreams
+	}
+	return i, nil
+}
+
+// Tracks returns the Tracks the streams are republished on
+func (i *Ingest) Tracks() []*webrtc.Track {
+	return append([]*webrtc.Track{}, i.tracks...)
+}
+
+// Run plays the Session and forwards its packets to the Tracks until the
+// Session is closed or fails, the error ending it is returned
+func (i *Ingest) Run() error {
+	if err := i.session.Play(); err != nil {
+		return err
+	}
+
+	for {
+		index, packet, err := i.session.ReadRTP()
+		if err != nil {
+			return err
+		}
+
+		f, ok := i.forwarders[index]
+		if !ok {
+			continue
+		}
+		// io.ErrClosedPipe only means no PeerConnection sends the Track yet
+		if err := f.forward(packet); err != nil && err != io.ErrClosedPipe {
+			return err
+		}
+	}
+}
+
+func findCodec(mediaEngine *webrtc.MediaEngine, media Media) *webrtc.RTPCodec {
+	if media.Codec == "" {
+		return nil
+	}
+	for _, codec := range mediaEngine.GetCodecsByName(media.Codec) {
+		if codec.ClockRate == media.ClockRate {
+			return codec
+		}
+	}
+	return nil
+}
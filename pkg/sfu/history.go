@@ -0,0 +1,77 @@
+// +build !js
+
+package sfu
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// sentPacket is a packet sent to a subscriber and the one of the publisher
+// it was rewritten from
+type sentPacket struct {
+	packet         *rtp.Packet
+	sourceSSRC     uint32
+	sourceSequence uint16
+}
+
+// history keeps the last packets sent to a subscriber, indexed by their
+// sequence number
+type history struct {
+	mu      sync.Mutex
+	packets []*sentPacket
+}
+
+func newHistory(size int) *history {
+	return &history{packets: make([]*sentPacket, size)}
+}
+
+// add is the OnForward handler of the LayerSwitcher
+func (h *history) add(source, forwarded *rtp.Packet) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.packets[int(forwarded.SequenceNumber)%len(h.packets)] = &sentPacket{
+		packet:         forwarded,
+		sourceSSRC:     source.SSRC,
+		sourceSequence: source.SequenceNumber,
+	}
+}
+
+// get returns the packet sent with sequenceNumber, nil if it wasn't or is
+// too old
+func (h *history) get(sequenceNumber uint16) *rtp.Packet {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if sent := h.at(sequenceNumber); sent != nil {
+		return sent.packet
+	}
+	return nil
+}
+
+// source returns the SSRC and the sequence number of the packet of the
+// publisher that would have been sent with sequenceNumber, from the packets
+// sent before it. The sequence numbers only jump on a change of layer, so the
+// gap is the same on both sides.
+func (h *history) source(sequenceNumber uint16) (uint32, uint16, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for gap := uint16(1); gap <= maxNACKGap && int(gap) < len(h.packets); gap++ {
+		if sent := h.at(sequenceNumber - gap); sent != nil {
+			return sent.sourceSSRC, sent.sourceSequence + gap, true
+		}
+	}
+	return 0, 0, false
+}
+
+// at must be called with the lock held
+func (h *history) at(sequenceNumber uint16) *sentPacket {
+	sent := h.packets[int(sequenceNumber)%len(h.packets)]
+	if sent == nil || sent.packet.SequenceNumber != sequenceNumber {
+		return nil
+	}
+	return sent
+}
@@ -0,0 +1,43 @@
+// +build !js
+
+package sfu
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	h := newHistory(4)
+	forward := func(sourceSSRC uint32, sourceSequenceNumber, sequenceNumber uint16) {
+		h.add(
+			&rtp.Packet{Header: rtp.Header{SSRC: sourceSSRC, SequenceNumber: sourceSequenceNumber}},
+			&rtp.Packet{Header: rtp.Header{SSRC: 5000, SequenceNumber: sequenceNumber}},
+		)
+	}
+
+	forward(1, 100, 65534)
+	forward(1, 102, 0)
+	forward(2, 7, 1)
+	assert.Equal(t, uint16(65534), h.get(65534).SequenceNumber)
+	assert.Nil(t, h.get(65535), "the packet lost before the Router")
+	assert.Nil(t, h.get(4), "never sent")
+
+	// Missing packets map to the ones of the layer sent before them
+	ssrc, sourceSequenceNumber, ok := h.source(65535)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), ssrc)
+	assert.Equal(t, uint16(101), sourceSequenceNumber)
+	ssrc, sourceSequenceNumber, ok = h.source(3)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), ssrc)
+	assert.Equal(t, uint16(9), sourceSequenceNumber)
+
+	// Older packets are overwritten
+	forward(2, 8, 2)
+	assert.Nil(t, h.get(65534))
+	_, _, ok = h.source(10)
+	assert.False(t, ok)
+}
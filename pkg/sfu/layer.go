@@ -0,0 +1,154 @@
+// +build !js
+
+package sfu
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+// layer is a remote Track of a Publication, its only one or one of its
+// simulcast layers. The feedback of every subscriber about it goes through
+// it, to be sent to the publisher at most once per interval.
+type layer struct {
+	track     *webrtc.Track
+	publisher *webrtc.PeerConnection
+	config    *Config
+
+	mu                  sync.Mutex
+	lastKeyframeRequest time.Time
+	requested           map[uint16]time.Time
+
+	windowStart time.Time
+	windowBytes int
+	bitrate     uint64
+}
+
+func newLayer(track *webrtc.Track, publisher *webrtc.PeerConnection, config *Config) *layer {
+	return &layer{
+		track:     track,
+		publisher: publisher,
+		config:    config,
+		requested: map[uint16]time.Time{},
+	}
+}
+
+// observe measures the bitrate with a packet read
+func (l *layer) observe(p *rtp.Packet, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.windowStart.IsZero() {
+		l.windowStart = now
+	}
+	l.windowBytes += p.MarshalSize()
+	if elapsed := now.Sub(l.windowStart); elapsed >= bitrateWindow {
+		l.bitrate = uint64(float64(l.windowBytes*8) / elapsed.Seconds())
+		l.windowStart, l.windowBytes = now, 0
+	}
+}
+
+// measuredBitrate returns the bitrate of the last bitrateWindow, in bits per
+// second
+func (l *layer) measuredBitrate() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.bitrate
+}
+
+// requestKeyframe sends a PLI to the publisher, unless one was sent less
+// than a KeyframeInterval ago
+func (l *layer) requestKeyframe() error {
+	l.mu.Lock()
+	now := time.Now()
+	if now.Sub(l.lastKeyframeRequest) < l.config.KeyframeInterval {
+		l.mu.Unlock()
+		return nil
+	}
+	l.lastKeyframeRequest = now
+	l.mu.Unlock()
+
+	return l.publisher.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: l.track.SSRC()}})
+}
+
+// requestRetransmission sends a NACK to the publisher for the sequence
+// numbers that weren't requested in the last NACKInterval
+func (l *layer) requestRetransmission(sequenceNumbers []uint16) error {
+	l.mu.Lock()
+	now := time.Now()
+	for sequenceNumber, requested := range l.requested {
+		if now.Sub(requested) >= l.config.NACKInterval {
+			delete(l.requested, sequenceNumber)
+		}
+	}
+	missing := []uint16{}
+	for _, sequenceNumber := range sequenceNumbers {
+		if _, ok := l.requested[sequenceNumber]; !ok {
+			l.requested[sequenceNumber] = now
+			missing = append(missing, sequenceNumber)
+		}
+	}
+	l.mu.Unlock()
+
+	if len(missing) == 0 {
+		return nil
+	}
+	return l.publisher.WriteRTCP([]rtcp.Packet{&rtcp.TransportLayerNack{
+		MediaSSRC: l.track.SSRC(),
+		Nacks:     nackPairs(missing),
+	}})
+}
+
+// nackPairs packs sequenceNumbers into NackPairs, a packet ID and a bitmask
+// of the 16 following it, RFC 4585 6.2.1
+func nackPairs(sequenceNumbers []uint16) []rtcp.NackPair {
+	sorted := append([]uint16{}, sequenceNumbers...)
+	sort.Slice(sorted, func(i, j int) bool {
+		// In the order of RTP, across a wrap around
+		return int16(sorted[i]-sorted[j]) < 0
+	})
+
+	pairs := []rtcp.NackPair{}
+	for _, sequenceNumber := range sorted {
+		if n := len(pairs); n != 0 {
+			pair := &pairs[n-1]
+			if diff := sequenceNumber - pair.PacketID; diff == 0 {
+				continue
+			} else if diff <= 16 {
+				pair.LostPackets |= rtcp.PacketBitmap(1 << (diff - 1))
+				continue
+			}
+		}
+		pairs = append(pairs, rtcp.NackPair{PacketID: sequenceNumber})
+	}
+	return pairs
+}
+
+// selectLayer returns the index of the layer with the highest bitrate that
+// fits in estimate, or of the lowest. Layers that aren't sending, whose
+// bitrate is 0, are only picked when none is.
+func selectLayer(bitrates []uint64, estimate uint64) int {
+	selected := -1
+	for i, bitrate := range bitrates {
+		if bitrate == 0 {
+			continue
+		}
+		switch {
+		case selected == -1:
+			selected = i
+		case bitrate <= estimate && (bitrates[selected] > estimate || bitrate > bitrates[selected]):
+			selected = i
+		case bitrates[selected] > estimate && bitrate < bitrates[selected]:
+			selected = i
+		}
+	}
+	if selected == -1 {
+		return 0
+	}
+	return selected
+}
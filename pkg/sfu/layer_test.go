@@ -0,0 +1,41 @@
+// +build !js
+
+package sfu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNACKPairs(t *testing.T) {
+	assert.Equal(t, []rtcp.NackPair{
+		{PacketID: 65530, LostPackets: 0x8051},
+		{PacketID: 20},
+	}, nackPairs([]uint16{20, 1, 65530, 65531, 65535, 1, 10}))
+}
+
+func TestSelectLayer(t *testing.T) {
+	bitrates := []uint64{1500000, 150000, 0, 500000}
+	assert.Equal(t, 1, selectLayer(bitrates, 0), "the lowest when none fits")
+	assert.Equal(t, 1, selectLayer(bitrates, 400000))
+	assert.Equal(t, 3, selectLayer(bitrates, 500000))
+	assert.Equal(t, 0, selectLayer(bitrates, 10000000))
+	assert.Equal(t, 0, selectLayer([]uint64{0, 0}, 10000000), "none is sending")
+}
+
+func TestLayerBitrate(t *testing.T) {
+	l := newLayer(nil, nil, &Config{})
+	now := time.Unix(0, 0)
+	p := &rtp.Packet{Payload: make([]byte, 1238)}
+	for i := 0; i < 100; i++ {
+		l.observe(p, now)
+		now = now.Add(10 * time.Millisecond)
+	}
+	assert.Equal(t, uint64(0), l.measuredBitrate(), "the window isn't over")
+	l.observe(p, now)
+	assert.Equal(t, uint64(101*1250*8), l.measuredBitrate())
+}
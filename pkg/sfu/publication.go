@@ -0,0 +1,171 @@
+// +build !js
+
+package sfu
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v2"
+)
+
+// Publication is a Track published to a Router, with its simulcast layers
+type Publication struct {
+	router    *Router
+	id, label string
+	kind      webrtc.RTPCodecType
+	publisher *webrtc.PeerConnection
+
+	mu     sync.RWMutex
+	layers []*layer
+
+	// subscriptions is replaced instead of modified, the forwarding loops
+	// iterate over it without the lock
+	subscriptions []*Subscription
+}
+
+// ID returns the ID of the Track
+func (p *Publication) ID() string {
+	return p.id
+}
+
+// Label returns the label of the Track
+func (p *Publication) Label() string {
+	return p.label
+}
+
+// Kind returns the kind of the Track
+func (p *Publication) Kind() webrtc.RTPCodecType {
+	return p.kind
+}
+
+// Publisher returns the PeerConnection the Track is received on
+func (p *Publication) Publisher() *webrtc.PeerConnection {
+	return p.publisher
+}
+
+// Layers returns the RIDs of the simulcast layers received, a single empty
+// one without simulcast
+func (p *Publication) Layers() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rids := make([]string, 0, len(p.layers))
+	for _, l := range p.layers {
+		rids = append(rids, l.track.RID())
+	}
+	return rids
+}
+
+// Subscriptions returns the subscriptions to the Track
+func (p *Publication) Subscriptions() []*Subscription {
+	return append([]*Subscription{}, p.loadSubscriptions()...)
+}
+
+func (p *Publication) addLayer(l *layer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.layers = append(p.layers, l)
+}
+
+// removeLayer reports if l was the last layer
+func (p *Publication) removeLayer(l *layer) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.layers {
+		if p.layers[i] == l {
+			p.layers = append(p.layers[:i:i], p.layers[i+1:]...)
+			break
+		}
+	}
+	return len(p.layers) == 0
+}
+
+func (p *Publication) loadLayers() []*layer {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.layers
+}
+
+// layer returns the layer of ssrc, nil if it ended
+func (p *Publication) layer(ssrc uint32) *layer {
+	for _, l := range p.loadLayers() {
+		if l.track.SSRC() == ssrc {
+			return l
+		}
+	}
+	return nil
+}
+
+func (p *Publication) loadSubscriptions() []*Subscription {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.subscriptions
+}
+
+// subscribe adds a local Track to pc and forwards the Track to it
+func (p *Publication) subscribe(pc *webrtc.PeerConnection) (*Subscription, error) {
+	layers := p.loadLayers()
+	if len(layers) == 0 {
+		return nil, errNoPublication
+	}
+
+	// The layers only differ by their SSRC and RID
+	first := layers[0].track
+	track, err := webrtc.NewTrack(first.PayloadType(), rand.Uint32(), p.id, p.label, first.Codec()) // nolint: gosec
+	if err != nil {
+		return nil, err
+	}
+	sender, err := pc.AddTrack(track)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Subscription{
+		publication: p,
+		pc:          pc,
+		track:       track,
+		sender:      sender,
+		history:     newHistory(p.router.config.HistorySize),
+	}
+	s.switcher = webrtc.NewLayerSwitcher(track, s.requestKeyframe)
+	s.switcher.OnForward(s.history.add)
+	sender.OnRTCP(webrtc.RTCPHandlers{
+		OnNACK: s.handleNACK,
+		OnPLI:  func(*rtcp.PictureLossIndication) { s.handleKeyframeRequest() },
+		OnFIR:  func(*rtcp.FullIntraRequest) { s.handleKeyframeRequest() },
+		OnREMB: func(remb *rtcp.ReceiverEstimatedMaximumBitrate) { s.selectLayer(remb.Bitrate) },
+	})
+	// Like when the subscriber PeerConnection is closed
+	sender.OnStop(func() { p.removeSubscription(s) })
+
+	// The subscriptions are closed once the last layer is removed
+	p.mu.Lock()
+	ended := len(p.layers) == 0
+	if !ended {
+		p.subscriptions = append(p.subscriptions[:len(p.subscriptions):len(p.subscriptions)], s)
+	}
+	p.mu.Unlock()
+	if ended {
+		_ = pc.RemoveTrack(sender)
+		return nil, errNoPublication
+	}
+
+	// Until the first REMB, the lowest layer
+	s.selectLayer(0)
+	return s, nil
+}
+
+func (p *Publication) removeSubscription(s *Subscription) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.subscriptions {
+		if p.subscriptions[i] == s {
+			p.subscriptions = append(p.subscriptions[:i:i], p.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// +build !js
+
+package sfu
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/webrtc/v2"
+)
+
+// Config configures a Router, the zero values select the defaults
+type Config struct {
+	// KeyframeInterval is how long the keyframe requests of the subscribers
+	// of a layer are coalesced for, 500 milliseconds by default
+	KeyframeInterval time.Duration
+
+	// NACKInterval is how long a packet requested from the publisher isn't
+	// requested again for, 100 milliseconds by default
+	NACKInterval time.Duration
+
+	// HistorySize is how many of the packets sent to each subscriber are kept
+	// to answer its NACKs, 512 by default
+	HistorySize int
+
+	// LoggerFactory creates the logger of the Router, the default one of
+	// pion/logging by default
+	LoggerFactory logging.LoggerFactory
+}
+
+// Router forwards the Tracks of publisher PeerConnections to subscriber
+// PeerConnections. The Tracks are published by their ID, the simulcast
+// layers of a Track share it and are published together.
+type Router struct {
+	config Config
+	log    logging.LeveledLogger
+
+	mu                   sync.RWMutex
+	publications         map[string]*Publication
+	onPublicationHandler func(*Publication)
+}
+
+// New creates a Router
+func New(config Config) *Router {
+	if config.KeyframeInterval <= 0 {
+		config.KeyframeInterval = defaultKeyframeInterval
+	}
+	if config.NACKInterval <= 0 {
+		config.NACKInterval = defaultNACKInterval
+	}
+	if config.HistorySize <= 0 {
+		config.HistorySize = defaultHistorySize
+	}
+	if config.LoggerFactory == nil {
+		config.LoggerFactory = logging.NewDefaultLoggerFactory()
+	}
+
+	return &Router{
+		config:       config,
+		log:          config.LoggerFactory.NewLogger("sfu"),
+		publications: map[string]*Publication{},
+	}
+}
+
+// AddPublisher publishes the remote Tracks of pc as they arrive. It sets the
+// OnTrack handler of pc, use Publish from another handler instead.
+func (r *Router) AddPublisher(pc *webrtc.PeerConnection) {
+	pc.OnTrack(func(track *webrtc.Track, _ *webrtc.RTPReceiver) {
+		if err := r.Publish(pc, track); err != nil {
+			r.log.Warnf("Failed to publish track %s: %v", track.ID(), err)
+		}
+	})
+}
+
+// Publish publishes track, a remote Track of pc, and forwards its packets
+// until it ends. A Track with the ID of a Track published by pc is another
+// simulcast layer of it.
+func (r *Router) Publish(pc *webrtc.PeerConnection, track *webrtc.Track) error {
+	l := newLayer(track, pc, &r.config)
+
+	r.mu.Lock()
+	publication, ok := r.publications[track.ID()]
+	if !ok {
+		publication = &Publication{
+			router:    r,
+			id:        track.ID(),
+			label:     track.Label(),
+			kind:      track.Kind(),
+			publisher: pc,
+		}
+		r.publications[track.ID()] = publication
+	} else if publication.publisher != pc {
+		r.mu.Unlock()
+		return errDuplicateTrack
+	}
+	publication.addLayer(l)
+	hdlr := r.onPublicationHandler
+	r.mu.Unlock()
+
+	if !ok && hdlr != nil {
+		go hdlr(publication)
+	}
+
+	go r.forward(publication, l)
+	return nil
+}
+
+// OnPublication sets a handler called when a Track is published, once for
+// all its layers
+func (r *Router) OnPublication(f func(*Publication)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onPublicationHandler = f
+}
+
+// Publication returns the Publication of the Track with id, nil if none is
+// published
+func (r *Router) Publication(id string) *Publication {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.publications[id]
+}
+
+// Publications returns the Tracks published
+func (r *Router) Publications() []*Publication {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	publications := make([]*Publication, 0, len(r.publications))
+	for _, publication := range r.publications {
+		publications = append(publications, publication)
+	}
+	return publications
+}
+
+// Subscribe sends the Track published with id to pc, in a new local Track
+// added to it. The OnRTCP and OnStop handlers of its RTPSender are set to
+// answer the feedback of pc and to end the Subscription. pc has to be
+// renegotiated for the Track to be sent.
+func (r *Router) Subscribe(pc *webrtc.PeerConnection, id string) (*Subscription, error) {
+	publication := r.Publication(id)
+	if publication == nil {
+		return nil, errNoPublication
+	}
+	return publication.subscribe(pc)
+}
+
+// forward copies the packets of l to the subscriptions of publication until
+// its Track ends. After the last layer the subscriptions are closed.
+func (r *Router) forward(publication *Publication, l *layer) {
+	for {
+		p, err := l.track.ReadRTP()
+		if err != nil {
+			r.log.Debugf("Stopped forwarding track %s: %v", publication.id, err)
+			break
+		}
+		l.observe(p, time.Now())
+
+		for _, s := range publication.loadSubscriptions() {
+			// Each subscriber fails on its own, like before its
+			// PeerConnection was negotiated
+			if err := s.switcher.WriteRTP(p); err != nil {
+				r.log.Tracef("Failed to forward to a subscriber of %s: %v", publication.id, err)
+			}
+		}
+	}
+
+	// Layers are added with r.mu held, the last one can't be replaced before
+	// the Publication is unregistered
+	r.mu.Lock()
+	ended := publication.removeLayer(l)
+	if ended && r.publications[publication.id] == publication {
+		delete(r.publications, publication.id)
+	}
+	r.mu.Unlock()
+	if !ended {
+		return
+	}
+
+	for _, s := range publication.Subscriptions() {
+		// The subscriber PeerConnection may be closed already
+		if err := s.Close(); err != nil {
+			r.log.Debugf("Failed to close a subscription of %s: %v", publication.id, err)
+		}
+	}
+}
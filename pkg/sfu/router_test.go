@@ -0,0 +1,161 @@
+// +build !js
+
+package sfu
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPeerConnection(t *testing.T, s webrtc.SettingEngine) *webrtc.PeerConnection {
+	mediaEngine := webrtc.MediaEngine{}
+	mediaEngine.RegisterDefaultCodecs()
+	pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(mediaEngine), webrtc.WithSettingEngine(s)).NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	return pc
+}
+
+func newReceiver(t *testing.T, s webrtc.SettingEngine) *webrtc.PeerConnection {
+	pc := newPeerConnection(t, s)
+	_, err := pc.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RtpTransceiverInit{
+		Direction: webrtc.RTPTransceiverDirectionRecvonly,
+	})
+	assert.NoError(t, err)
+	return pc
+}
+
+// signal negotiates with the candidates in the descriptions
+func signal(t *testing.T, offerer, answerer *webrtc.PeerConnection) {
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, offerer.SetLocalDescription(offer))
+	desc, err := offerer.LocalDescriptionContext(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, answerer.SetRemoteDescription(*desc))
+
+	answer, err := answerer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, answerer.SetLocalDescription(answer))
+	desc, err = answerer.LocalDescriptionContext(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, offerer.SetRemoteDescription(*desc))
+}
+
+func TestRouter(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// Only the keyframe requested by the subscription reaches the publisher
+	router := New(Config{KeyframeInterval: time.Hour})
+	published := make(chan *Publication, 1)
+	router.OnPublication(func(p *Publication) { published <- p })
+
+	publisher, routerIn := newPeerConnection(t, webrtc.SettingEngine{}), newReceiver(t, webrtc.SettingEngine{})
+	track, err := publisher.NewTrack(webrtc.DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := publisher.AddTrack(track)
+	assert.NoError(t, err)
+	plis := make(chan struct{}, 16)
+	sender.OnRTCP(webrtc.RTCPHandlers{
+		OnPLI: func(*rtcp.PictureLossIndication) { plis <- struct{}{} },
+	})
+	router.AddPublisher(routerIn)
+	signal(t, publisher, routerIn)
+
+	// Every sample is a VP8 keyframe
+	stopWriting, writerDone := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		for {
+			select {
+			case <-stopWriting:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			_ = track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1})
+		}
+	}()
+	publication := <-published
+	assert.Equal(t, "video", publication.ID())
+
+	// The retransmissions repeat the sequence numbers
+	viewerSettings := webrtc.SettingEngine{}
+	viewerSettings.DisableSRTPReplayProtection(true)
+	routerOut, viewer := newPeerConnection(t, webrtc.SettingEngine{}), newReceiver(t, viewerSettings)
+	subscription, err := router.Subscribe(routerOut, "video")
+	assert.NoError(t, err)
+	assert.Equal(t, []*Subscription{subscription}, publication.Subscriptions())
+
+	viewerTracks := make(chan *webrtc.Track, 1)
+	viewer.OnTrack(func(remote *webrtc.Track, _ *webrtc.RTPReceiver) { viewerTracks <- remote })
+	signal(t, routerOut, viewer)
+
+	remote := <-viewerTracks
+	received := make(chan uint16, 64)
+	go func() {
+		for {
+			p, readErr := remote.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			select {
+			case received <- p.SequenceNumber:
+			default:
+			}
+		}
+	}()
+	first := <-received
+	<-plis
+
+	// The PLIs of the viewer are coalesced with the first request, the NACK
+	// is answered with the packet sent
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, viewer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: remote.SSRC()}}))
+	}
+	assert.NoError(t, viewer.WriteRTCP([]rtcp.Packet{&rtcp.TransportLayerNack{
+		MediaSSRC: remote.SSRC(),
+		Nacks:     []rtcp.NackPair{{PacketID: first}},
+	}}))
+	for sequenceNumber := range received {
+		if sequenceNumber == first {
+			break
+		}
+	}
+	select {
+	case <-plis:
+		t.Error("a PLI of the viewer reached the publisher")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Closing the subscriber removes its subscription
+	assert.NoError(t, viewer.Close())
+	assert.NoError(t, routerOut.Close())
+	for len(publication.Subscriptions()) != 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The end of the publication closes the other subscriptions
+	other := newPeerConnection(t, webrtc.SettingEngine{})
+	otherSubscription, err := router.Subscribe(other, "video")
+	assert.NoError(t, err)
+
+	close(stopWriting)
+	<-writerDone
+	assert.NoError(t, publisher.Close())
+	assert.NoError(t, routerIn.Close())
+	for router.Publication("video") != nil || otherSubscription.Sender().Track() != nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Empty(t, publication.Subscriptions())
+	assert.NoError(t, other.Close())
+}
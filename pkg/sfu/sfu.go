@@ -0,0 +1,44 @@
+// +build !js
+
+// Package sfu routes the tracks of publishing PeerConnections to subscribing
+// ones, the selective forwarding unit usually assembled by hand from the
+// RTPReceivers and RTPSenders. A Router keeps the registry of the published
+// tracks, each subscriber gets its own local Track fed by a
+// webrtc.LayerSwitcher, and the RTCP feedback of the subscribers is
+// aggregated before it reaches the publisher:
+//
+//	router := sfu.New(sfu.Config{})
+//	router.AddPublisher(publisher)
+//	...
+//	subscription, err := router.Subscribe(viewer, "video")
+//
+// NACKs are answered with the packets sent to the subscriber, the ones the
+// Router never received are requested from the publisher once per
+// NACKInterval, however many subscribers miss them. PLIs and FIRs are
+// forwarded as at most one PLI per KeyframeInterval. Each subscriber is sent
+// the simulcast layer with the highest bitrate that fits in its REMB.
+package sfu
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	defaultKeyframeInterval = 500 * time.Millisecond
+	defaultNACKInterval     = 100 * time.Millisecond
+	defaultHistorySize      = 512
+
+	// bitrateWindow is how long the bytes of a layer are counted for to
+	// measure its bitrate
+	bitrateWindow = time.Second
+
+	// maxNACKGap is how many packets before a missing one are looked at to
+	// find which packet of the publisher it is
+	maxNACKGap = 32
+)
+
+var (
+	errNoPublication  = errors.New("sfu: no track is published with this ID")
+	errDuplicateTrack = errors.New("sfu: track ID is published by another PeerConnection")
+)
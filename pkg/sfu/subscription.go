@@ -0,0 +1,122 @@
+// +build !js
+
+package sfu
+
+import (
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v2"
+)
+
+// Subscription is a Publication sent to a subscriber PeerConnection
+type Subscription struct {
+	publication *Publication
+	pc          *webrtc.PeerConnection
+	track       *webrtc.Track
+	sender      *webrtc.RTPSender
+	switcher    *webrtc.LayerSwitcher
+	history     *history
+}
+
+// Publication returns the Publication subscribed to
+func (s *Subscription) Publication() *Publication {
+	return s.publication
+}
+
+// Track returns the local Track the Publication is sent in
+func (s *Subscription) Track() *webrtc.Track {
+	return s.track
+}
+
+// Sender returns the RTPSender of the Track
+func (s *Subscription) Sender() *webrtc.RTPSender {
+	return s.sender
+}
+
+// Layer returns the RID of the simulcast layer sent, or switched to when
+// none is sent yet
+func (s *Subscription) Layer() string {
+	current, target := s.switcher.Layers()
+	if current == 0 {
+		current = target
+	}
+	if l := s.publication.layer(current); l != nil {
+		return l.track.RID()
+	}
+	return ""
+}
+
+// Close stops sending the Publication and removes the Track from the
+// subscriber PeerConnection, which has to be renegotiated. A Subscription is
+// closed as well when the Publication ends, and is removed when its
+// RTPSender is stopped, like when the subscriber PeerConnection is closed.
+func (s *Subscription) Close() error {
+	s.publication.removeSubscription(s)
+	return s.pc.RemoveTrack(s.sender)
+}
+
+// requestKeyframe is the one of the LayerSwitcher
+func (s *Subscription) requestKeyframe(ssrc uint32) error {
+	if l := s.publication.layer(ssrc); l != nil {
+		return l.requestKeyframe()
+	}
+	return nil
+}
+
+// handleKeyframeRequest requests a keyframe of the layer sent, a switch to
+// another layer already requested one of it
+func (s *Subscription) handleKeyframeRequest() {
+	current, _ := s.switcher.Layers()
+	if current == 0 {
+		return
+	}
+	if err := s.requestKeyframe(current); err != nil {
+		s.publication.router.log.Warnf("Failed to request a keyframe of %s: %v", s.publication.id, err)
+	}
+}
+
+// handleNACK retransmits the packets that were sent, and requests the other
+// ones from the publisher
+func (s *Subscription) handleNACK(nack *rtcp.TransportLayerNack) {
+	missing := map[uint32][]uint16{}
+	for i := range nack.Nacks {
+		for _, sequenceNumber := range nack.Nacks[i].PacketList() {
+			if p := s.history.get(sequenceNumber); p != nil {
+				if err := s.track.WriteRTP(p); err != nil {
+					return
+				}
+				continue
+			}
+			if ssrc, sourceSequenceNumber, ok := s.history.source(sequenceNumber); ok {
+				missing[ssrc] = append(missing[ssrc], sourceSequenceNumber)
+			}
+		}
+	}
+
+	for ssrc, sequenceNumbers := range missing {
+		l := s.publication.layer(ssrc)
+		if l == nil {
+			continue
+		}
+		if err := l.requestRetransmission(sequenceNumbers); err != nil {
+			s.publication.router.log.Warnf("Failed to request a retransmission of %s: %v", s.publication.id, err)
+		}
+	}
+}
+
+// selectLayer switches to the layer that fits in estimate, in bits per
+// second
+func (s *Subscription) selectLayer(estimate uint64) {
+	layers := s.publication.loadLayers()
+	if len(layers) == 0 {
+		return
+	}
+
+	bitrates := make([]uint64, len(layers))
+	for i, l := range layers {
+		bitrates[i] = l.measuredBitrate()
+	}
+	selected := layers[selectLayer(bitrates, estimate)]
+	if err := s.switcher.SetTarget(selected.track.SSRC()); err != nil {
+		s.publication.router.log.Warnf("Failed to request a keyframe of %s: %v", s.publication.id, err)
+	}
+}
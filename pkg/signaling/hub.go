@@ -0,0 +1,199 @@
+package signaling
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/randutil"
+)
+
+const (
+	peerIDLength = 16
+	peerIDRunes  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	// maxPendingMessages caps the messages waiting for the next peer of a
+	// room, the ones sent after are dropped
+	maxPendingMessages = 256
+)
+
+// newPeerID returns a peer ID nobody can guess, anyone knowing it can read
+// the messages of the peer
+func newPeerID() (string, error) {
+	return randutil.GenerateCryptoRandomString(peerIDLength, peerIDRunes)
+}
+
+// hub relays messages between the peers of rooms. A message sent while a
+// peer is alone in its room waits for the next peer to join, so do the
+// messages a peer leaves without reading. A room without peers is kept for
+// them until it expires.
+type hub struct {
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+type room struct {
+	peers   map[string]*peer
+	pending [][]byte
+
+	// lastActive is when the last peer left or a message was left pending
+	lastActive time.Time
+}
+
+// addPending keeps messages for the next peer to join, up to
+// maxPendingMessages
+func (r *room) addPending(messages ...[]byte) {
+	if space := maxPendingMessages - len(r.pending); len(messages) > space {
+		messages = messages[:space]
+	}
+	r.pending = append(r.pending, messages...)
+	r.lastActive = time.Now()
+}
+
+type peer struct {
+	queue    [][]byte
+	notify   chan struct{}
+	lastSeen time.Time
+}
+
+// join returns the peer id of room, adding it if needed
+func (h *hub) join(roomName, id string) *peer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.rooms == nil {
+		h.rooms = map[string]*room{}
+	}
+	r, ok := h.rooms[roomName]
+	if !ok {
+		r = &room{peers: map[string]*peer{}}
+		h.rooms[roomName] = r
+	}
+
+	p, ok := r.peers[id]
+	if !ok {
+		p = &peer{queue: r.pending, notify: make(chan struct{}, 1)}
+		r.pending = nil
+		r.peers[id] = p
+		if len(p.queue) != 0 {
+			p.notify <- struct{}{}
+		}
+	}
+	p.lastSeen = time.Now()
+	return p
+}
+
+// leave removes the peer id of room, and room once empty
+func (h *hub) leave(roomName, id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.rooms[roomName]
+	if !ok {
+		return false
+	}
+	p, ok := r.peers[id]
+	if !ok {
+		return false
+	}
+
+	h.remove(roomName, r, id, p)
+	return true
+}
+
+// expire removes the peers not seen since before, and the rooms left without
+// peers since before with the messages pending in them
+func (h *hub) expire(before time.Time) {
+	h.mu.Lock()
+	for roomName, r := range h.rooms {
+		for id, p := range r.peers {
+			if p.lastSeen.Before(before) {
+				h.remove(roomName, r, id, p)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	h.expireRooms(before)
+}
+
+// expireRooms removes the rooms left without peers since before
+func (h *hub) expireRooms(before time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for roomName, r := range h.rooms {
+		if len(r.peers) == 0 && r.lastActive.Before(before) {
+			delete(h.rooms, roomName)
+		}
+	}
+}
+
+// remove removes the peer id from r, its unread messages go to the next peer
+// to join. h.mu must be held.
+func (h *hub) remove(roomName string, r *room, id string, p *peer) {
+	delete(r.peers, id)
+	r.addPending(p.queue...)
+	p.queue = nil
+
+	if len(r.peers) == 0 && len(r.pending) == 0 {
+		delete(h.rooms, roomName)
+	}
+}
+
+// send queues message for every peer of room but from
+func (h *hub) send(roomName, from string, message []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.rooms[roomName]
+	if !ok {
+		return
+	}
+
+	sent := false
+	for id, p := range r.peers {
+		if id == from {
+			continue
+		}
+		p.queue = append(p.queue, message)
+		select {
+		case p.notify <- struct{}{}:
+		default:
+		}
+		sent = true
+	}
+	if !sent {
+		r.addPending(message)
+	}
+}
+
+// next waits for the next message of p until done is closed or timeout
+// expires, a zero timeout waits forever
+func (h *hub) next(p *peer, done <-chan struct{}, timeout time.Duration) ([]byte, bool) {
+	var timer <-chan time.Time
+	if timeout != 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	for {
+		h.mu.Lock()
+		if len(p.queue) != 0 {
+			message := p.queue[0]
+			p.queue = p.queue[1:]
+			p.lastSeen = time.Now()
+			h.mu.Unlock()
+			return message, true
+		}
+		h.mu.Unlock()
+
+		select {
+		case <-p.notify:
+		case <-done:
+			return nil, false
+		case <-timer:
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package signaling
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPollTimeout = 30 * time.Second
+	pollPeerParam      = "peer"
+	maxPollMessageSize = 1 << 16
+)
+
+// PollServer is an http.Handler exchanging messages between the clients of
+// the same path with HTTP long-polling, for networks where WebSockets do not
+// go through. Every request names its client with the peer query parameter:
+//
+// POST sends the JSON body to the other clients.
+// GET waits for the next message, 204 No Content means none came in Timeout.
+// DELETE leaves the room.
+//
+// Clients that do not poll for three Timeouts are removed. So are the
+// messages left for the next client of a room without clients, once that
+// long has passed.
+type PollServer struct {
+	// Timeout of a GET without messages, 30 seconds if zero
+	Timeout time.Duration
+
+	hub hub
+}
+
+// NewPollServer creates a PollServer
+func NewPollServer() *PollServer {
+	return &PollServer{}
+}
+
+// ServeHTTP handles the requests of the clients
+func (s *PollServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get(pollPeerParam)
+	if id == "" {
+		http.Error(w, "missing peer", http.StatusBadRequest)
+		return
+	}
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = defaultPollTimeout
+	}
+	s.hub.expire(time.Now().Add(-3 * timeout))
+
+	switch r.Method {
+	case http.MethodPost:
+		message, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPollMessageSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.hub.join(r.URL.Path, id)
+		s.hub.send(r.URL.Path, id, message)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		p := s.hub.join(r.URL.Path, id)
+		message, ok := s.hub.next(p, r.Context().Done(), timeout)
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(message)
+	case http.MethodDelete:
+		if !s.hub.leave(r.URL.Path, id) {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// pollConn is a Conn to a PollServer
+type pollConn struct {
+	client *http.Client
+	url    string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closeOnce sync.Once
+}
+
+// DialHTTP joins the room of a PollServer at url, using client to send the
+// requests or http.DefaultClient if nil. Its timeout has to be longer than
+// the one of the server.
+func DialHTTP(rawURL string, client *http.Client) (Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newPeerID()
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set(pollPeerParam, id)
+	u.RawQuery = query.Encode()
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &pollConn{client: client, url: u.String(), ctx: ctx, cancel: cancel}, nil
+}
+
+func (c *pollConn) Send(m Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.do(http.MethodPost, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (c *pollConn) Receive() (Message, error) {
+	for {
+		res, err := c.do(http.MethodGet, nil)
+		if err != nil {
+			return Message{}, err
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		if closeErr := res.Body.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return Message{}, err
+		}
+		if res.StatusCode == http.StatusNoContent {
+			continue
+		}
+
+		m := Message{}
+		return m, json.Unmarshal(body, &m)
+	}
+}
+
+// Close stops any Receive in progress and leaves the room
+func (c *pollConn) Close() (err error) {
+	c.closeOnce.Do(func() {
+		c.cancel()
+
+		var req *http.Request
+		if req, err = http.NewRequest(http.MethodDelete, c.url, nil); err != nil {
+			return
+		}
+		var res *http.Response
+		if res, err = c.client.Do(req); err != nil {
+			return
+		}
+		err = res.Body.Close()
+	})
+	return
+}
+
+func (c *pollConn) do(method string, body *bytes.Reader) (*http.Response, error) {
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequest(method, c.url, body)
+	} else {
+		req, err = http.NewRequest(method, c.url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req.WithContext(c.ctx))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("signaling: unexpected status %s", res.Status)
+	}
+	return res, nil
+}
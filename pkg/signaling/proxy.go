@@ -0,0 +1,106 @@
+package signaling
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/proxy"
+	"golang.org/x/net/websocket"
+)
+
+var (
+	errProxyScheme  = errors.New("signaling: proxy scheme must be http or socks5")
+	errProxyConnect = errors.New("signaling: proxy refused CONNECT")
+)
+
+// DialWebSocketProxy is DialWebSocket through the proxy at proxyURL, an HTTP
+// proxy reached with CONNECT or a SOCKS5 one. The user info of proxyURL is
+// sent to the proxy as credentials. The HTTP long-poll client is proxied
+// through the Transport of the http.Client given to DialHTTP instead.
+func DialWebSocketProxy(rawURL string, proxyURL *url.URL) (Conn, error) {
+	origin := "http" + strings.TrimPrefix(rawURL, "ws")
+	config, err := websocket.NewConfig(rawURL, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	port := config.Location.Port()
+	if port == "" {
+		port = "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+	}
+	addr := net.JoinHostPort(config.Location.Hostname(), port)
+
+	conn, err := dialProxy(proxyURL, addr)
+	if err != nil {
+		return nil, err
+	}
+	if config.Location.Scheme == "wss" {
+		conn = tls.Client(conn, &tls.Config{ServerName: config.Location.Hostname()})
+	}
+
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &websocketConn{ws: ws}, nil
+}
+
+// dialProxy opens a TCP connection to addr through the proxy at proxyURL
+func dialProxy(proxyURL *url.URL, addr string) (net.Conn, error) {
+	switch proxyURL.Scheme {
+	case "socks5":
+		dialer, err := proxy.FromURL(proxyURL, proxy.Direct)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.Dial("tcp", addr)
+	case "http":
+	default:
+		return nil, errProxyScheme
+	}
+
+	conn, err := net.Dial("tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err = req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// The proxy sends nothing past its response until the tunnel is used,
+	// so the reader buffers nothing that belongs to the tunnel
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, errProxyConnect
+	}
+	return conn, nil
+}
@@ -0,0 +1,154 @@
+// Package signaling contains ready-made ways to exchange session
+// descriptions and candidates between two peers: a WebSocket relay, an HTTP
+// long-poll server and the clients of both, plus a compact base64 encoding
+// of the messages for copy and paste.
+//
+// Peers meet in rooms, named by the path of the URL they connect to.
+package signaling
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pion/webrtc/v2"
+)
+
+var (
+	errUnexpectedDescription = errors.New("signaling: unexpected session description type")
+)
+
+// Message is exchanged between peers, it carries a SessionDescription or
+// an ICE candidate
+type Message struct {
+	Description *webrtc.SessionDescription `json:"description,omitempty"`
+	Candidate   *webrtc.ICECandidateInit   `json:"candidate,omitempty"`
+}
+
+// Conn exchanges Messages with the other peers of a room
+type Conn interface {
+	Send(m Message) error
+	// Receive blocks until a Message arrives or the Conn is closed
+	Receive() (Message, error)
+	Close() error
+}
+
+// Encode encodes obj as base64 JSON
+func Encode(obj interface{}) (string, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// EncodeCompressed encodes obj as base64 gzipped JSON, it is shorter for
+// session descriptions, which helps with terminal input limits
+func EncodeCompressed(obj interface{}) (string, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err = gz.Write(b); err != nil {
+		return "", err
+	}
+	if err = gz.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Decode decodes the output of Encode or EncodeCompressed into obj
+func Decode(in string, obj interface{}) error {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(in))
+	if err != nil {
+		return err
+	}
+
+	// JSON never starts with the gzip magic number
+	if len(b) >= 2 && b[0] == 0x1f && b[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		if b, err = ioutil.ReadAll(gz); err != nil {
+			return err
+		}
+	}
+
+	return json.Unmarshal(b, obj)
+}
+
+// Offer negotiates pc as the offerer over conn: it sends an offer and waits
+// for the answer. Candidates received before the answer are added after it.
+//
+// Candidates gathered by pc are not sent, with trickle disabled they are
+// part of the offer. Otherwise send them from PeerConnection.OnICECandidate.
+func Offer(pc *webrtc.PeerConnection, conn Conn) error {
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return err
+	}
+	if err = pc.SetLocalDescription(offer); err != nil {
+		return err
+	}
+	if err = conn.Send(Message{Description: pc.LocalDescription()}); err != nil {
+		return err
+	}
+
+	return receiveDescription(pc, conn, webrtc.SDPTypeAnswer)
+}
+
+// Answer negotiates pc as the answerer over conn: it waits for an offer and
+// sends the answer. Candidates are handled like in Offer.
+func Answer(pc *webrtc.PeerConnection, conn Conn) error {
+	if err := receiveDescription(pc, conn, webrtc.SDPTypeOffer); err != nil {
+		return err
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	if err = pc.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	return conn.Send(Message{Description: pc.LocalDescription()})
+}
+
+func receiveDescription(pc *webrtc.PeerConnection, conn Conn, sdpType webrtc.SDPType) error {
+	candidates := []webrtc.ICECandidateInit{}
+	for {
+		m, err := conn.Receive()
+		if err != nil {
+			return err
+		}
+
+		if m.Candidate != nil {
+			candidates = append(candidates, *m.Candidate)
+		}
+		if m.Description == nil {
+			continue
+		}
+		if m.Description.Type != sdpType {
+			return errUnexpectedDescription
+		}
+
+		if err = pc.SetRemoteDescription(*m.Description); err != nil {
+			return err
+		}
+		for _, candidate := range candidates {
+			if err = pc.AddICECandidate(candidate); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
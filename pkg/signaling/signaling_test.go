@@ -0,0 +1,229 @@
+// +build !js
+
+package signaling
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	desc := webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: strings.Repeat("a=candidate:foo\r\n", 20)}
+
+	encoded, err := Encode(desc)
+	assert.NoError(t, err)
+	compressed, err := EncodeCompressed(desc)
+	assert.NoError(t, err)
+	assert.True(t, len(compressed) < len(encoded))
+
+	for _, in := range []string{encoded, compressed, compressed + "\n"} {
+		decoded := webrtc.SessionDescription{}
+		assert.NoError(t, Decode(in, &decoded))
+		assert.Equal(t, desc, decoded)
+	}
+
+	assert.Error(t, Decode("not base64", &webrtc.SessionDescription{}))
+}
+
+func TestHub_Pending(t *testing.T) {
+	h := hub{}
+	first := h.join("room", "a")
+	h.send("room", "a", []byte("1"))
+	h.send("room", "a", []byte("2"))
+
+	// Messages sent while alone go to the next peer to join
+	second := h.join("room", "b")
+	for _, expected := range []string{"1", "2"} {
+		message, ok := h.next(second, nil, time.Second)
+		assert.True(t, ok)
+		assert.Equal(t, expected, string(message))
+	}
+
+	h.send("room", "b", []byte("3"))
+	message, ok := h.next(first, nil, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, "3", string(message))
+
+	_, ok = h.next(first, nil, 10*time.Millisecond)
+	assert.False(t, ok)
+
+	assert.True(t, h.leave("room", "a"))
+	assert.False(t, h.leave("room", "a"))
+	h.expire(time.Now().Add(time.Second))
+	assert.Empty(t, h.rooms)
+}
+
+func TestHub_Leave(t *testing.T) {
+	h := hub{}
+	h.join("room", "a")
+	h.join("room", "b")
+	h.send("room", "a", []byte("1"))
+
+	// The messages b didn't read go to the peer replacing it
+	assert.True(t, h.leave("room", "b"))
+	third := h.join("room", "c")
+	message, ok := h.next(third, nil, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(message))
+
+	// Also when it expires
+	h.send("room", "a", []byte("2"))
+	third.lastSeen = time.Time{}
+	h.expire(time.Now().Add(-time.Second))
+	fourth := h.join("room", "d")
+	message, ok = h.next(fourth, nil, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, "2", string(message))
+}
+
+func TestHub_ExpirePending(t *testing.T) {
+	h := hub{}
+	h.join("room", "a")
+	for i := 0; i < maxPendingMessages+1; i++ {
+		h.send("room", "a", []byte{byte(i)})
+	}
+
+	// The pending messages are capped, the first ones are kept
+	assert.True(t, h.leave("room", "a"))
+	assert.Len(t, h.rooms["room"].pending, maxPendingMessages)
+	assert.Equal(t, []byte{0}, h.rooms["room"].pending[0])
+
+	// The room stays for the next peer until it expires
+	h.expire(time.Now().Add(-time.Second))
+	assert.Contains(t, h.rooms, "room")
+	h.expire(time.Now().Add(time.Second))
+	assert.Empty(t, h.rooms)
+}
+
+// negotiate connects two PeerConnections through the Conns returned by dial
+func negotiate(t *testing.T, dial func() (Conn, error)) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	pcOffer, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	connected := make(chan struct{})
+	pcAnswer.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+		if state == webrtc.ICEConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	offerConn, err := dial()
+	assert.NoError(t, err)
+	offerErr := make(chan error)
+	go func() {
+		offerErr <- Offer(pcOffer, offerConn)
+	}()
+
+	// The answerer joins after the offer was sent
+	time.Sleep(50 * time.Millisecond)
+	answerConn, err := dial()
+	assert.NoError(t, err)
+	assert.NoError(t, Answer(pcAnswer, answerConn))
+	assert.NoError(t, <-offerErr)
+	<-connected
+
+	assert.NoError(t, offerConn.Close())
+	assert.NoError(t, answerConn.Close())
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestWebSocket(t *testing.T) {
+	server := httptest.NewServer(NewRelay())
+	defer server.Close()
+
+	negotiate(t, func() (Conn, error) {
+		return DialWebSocket("ws" + strings.TrimPrefix(server.URL, "http") + "/room")
+	})
+}
+
+// connectProxy tunnels CONNECT requests carrying the credentials user:pass
+func connectProxy(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		upstream, err := net.Dial("tcp", r.Host)
+		assert.NoError(t, err)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		assert.NoError(t, err)
+		_, err = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		assert.NoError(t, err)
+
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		_ = conn.Close()
+	}))
+}
+
+func TestWebSocketProxy(t *testing.T) {
+	server := httptest.NewServer(NewRelay())
+	defer server.Close()
+	proxyServer := connectProxy(t)
+	defer proxyServer.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/room"
+	proxyURL, err := url.Parse(proxyServer.URL)
+	assert.NoError(t, err)
+
+	_, err = DialWebSocketProxy(wsURL, proxyURL)
+	assert.Equal(t, errProxyConnect, err)
+
+	proxyURL.User = url.UserPassword("user", "pass")
+	negotiate(t, func() (Conn, error) {
+		return DialWebSocketProxy(wsURL, proxyURL)
+	})
+
+	_, err = DialWebSocketProxy(wsURL, &url.URL{Scheme: "ftp", Host: proxyURL.Host})
+	assert.Equal(t, errProxyScheme, err)
+}
+
+func TestHTTPPoll(t *testing.T) {
+	pollServer := NewPollServer()
+	pollServer.Timeout = 100 * time.Millisecond
+	server := httptest.NewServer(pollServer)
+	defer server.Close()
+
+	negotiate(t, func() (Conn, error) {
+		return DialHTTP(server.URL+"/room", nil)
+	})
+
+	res, err := http.Get(server.URL + "/room")
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// Nothing to receive
+	conn, err := DialHTTP(server.URL+"/empty", nil)
+	assert.NoError(t, err)
+	closeErr := make(chan error)
+	go func() {
+		time.Sleep(250 * time.Millisecond)
+		closeErr <- conn.Close()
+	}()
+	_, err = conn.Receive()
+	assert.Error(t, err)
+	assert.NoError(t, <-closeErr)
+}
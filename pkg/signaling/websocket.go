@@ -0,0 +1,95 @@
+package signaling
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// relayPendingTimeout is how long the messages left for the next client of a
+// path without clients are kept
+const relayPendingTimeout = time.Minute
+
+// Relay is an http.Handler relaying the messages of WebSocket clients to the
+// other clients connected to the same path. Any origin is accepted.
+type Relay struct {
+	hub hub
+}
+
+// NewRelay creates a Relay
+func NewRelay() *Relay {
+	return &Relay{}
+}
+
+// ServeHTTP upgrades the request to a WebSocket
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	websocket.Server{Handler: r.serve}.ServeHTTP(w, req)
+}
+
+func (r *Relay) serve(ws *websocket.Conn) {
+	id, err := newPeerID()
+	if err != nil {
+		_ = ws.Close()
+		return
+	}
+	roomName := ws.Request().URL.Path
+	r.hub.expireRooms(time.Now().Add(-relayPendingTimeout))
+	p := r.hub.join(roomName, id)
+	defer r.hub.leave(roomName, id)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			message, ok := r.hub.next(p, done, 0)
+			if !ok {
+				return
+			}
+			// Relayed as text, like the JSON the clients send
+			if err := websocket.Message.Send(ws, string(message)); err != nil {
+				_ = ws.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		var message []byte
+		if err := websocket.Message.Receive(ws, &message); err != nil {
+			return
+		}
+		r.hub.send(roomName, id, message)
+	}
+}
+
+// websocketConn is a Conn to a Relay
+type websocketConn struct {
+	ws *websocket.Conn
+}
+
+// DialWebSocket connects to the room of a Relay, url uses the ws or wss
+// scheme
+func DialWebSocket(url string) (Conn, error) {
+	origin := "http" + strings.TrimPrefix(url, "ws")
+	ws, err := websocket.Dial(url, "", origin)
+	if err != nil {
+		return nil, err
+	}
+	return &websocketConn{ws: ws}, nil
+}
+
+func (c *websocketConn) Send(m Message) error {
+	return websocket.JSON.Send(c.ws, m)
+}
+
+func (c *websocketConn) Receive() (Message, error) {
+	m := Message{}
+	err := websocket.JSON.Receive(c.ws, &m)
+	return m, err
+}
+
+func (c *websocketConn) Close() error {
+	return c.ws.Close()
+}
@@ -0,0 +1,171 @@
+// +build !js
+
+package whep
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/pion/webrtc/v2"
+)
+
+// Client pulls tracks from WHEP endpoints
+type Client struct {
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	// Token, if not empty, is presented to the endpoint as a Bearer token
+	Token string
+}
+
+// Session is a session created by Client.Play
+type Session struct {
+	client *Client
+	url    string
+}
+
+// Play negotiates pc with the WHEP endpoint, the tracks it sends arrive
+// through the OnTrack handler of pc. pc needs a recvonly transceiver for
+// every track to pull, see PeerConnection.AddTransceiverFromKind.
+//
+// The returned Session ends the session on the server when closed, pc has
+// to be closed separately.
+func (c *Client) Play(endpoint string, pc *webrtc.PeerConnection) (*Session, error) {
+	return c.PlayContext(context.Background(), endpoint, pc)
+}
+
+// PlayContext is Play with a context, that bounds the ICE gathering of pc
+// and the request to the endpoint
+func (c *Client) PlayContext(ctx context.Context, endpoint string, pc *webrtc.PeerConnection) (*Session, error) {
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = pc.SetLocalDescription(offer); err != nil {
+		return nil, err
+	}
+	localDescription, err := pc.LocalDescriptionContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(localDescription.SDP))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentTypeSDP)
+
+	res, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close() // nolint:errcheck
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("whep: unexpected status %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	if mediaType, _, parseErr := mime.ParseMediaType(res.Header.Get("Content-Type")); parseErr != nil || mediaType != contentTypeSDP {
+		return nil, errNotContentType
+	}
+
+	// The Location may be relative to the endpoint
+	location, err := res.Location()
+	if err == http.ErrNoLocation {
+		return nil, errNoLocation
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: string(body)}); err != nil {
+		return nil, err
+	}
+
+	return &Session{client: c, url: location.String()}, nil
+}
+
+// URL returns the URL of the session on the server
+func (s *Session) URL() string {
+	return s.url
+}
+
+// Close ends the session on the server
+func (s *Session) Close() error {
+	req, err := http.NewRequest(http.MethodDelete, s.url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() // nolint:errcheck
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("whep: unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// ICEServers parses the ICE servers advertised in the Link headers of a
+// response of a WHEP endpoint, like the one to an OPTIONS request
+func ICEServers(header http.Header) []webrtc.ICEServer {
+	servers := []webrtc.ICEServer{}
+	for _, values := range header["Link"] {
+		for _, link := range strings.Split(values, ",") {
+			if server, ok := parseICEServerLink(link); ok {
+				servers = append(servers, server)
+			}
+		}
+	}
+	return servers
+}
+
+func parseICEServerLink(link string) (webrtc.ICEServer, bool) {
+	params := strings.Split(link, ";")
+	target := strings.TrimSpace(params[0])
+	if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+		return webrtc.ICEServer{}, false
+	}
+
+	server := webrtc.ICEServer{URLs: []string{target[1 : len(target)-1]}}
+	isICEServer := false
+	for _, param := range params[1:] {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Trim(kv[1], "\"")
+		switch strings.ToLower(kv[0]) {
+		case "rel":
+			isICEServer = value == "ice-server"
+		case "username":
+			server.Username = value
+		case "credential":
+			server.Credential = value
+			server.CredentialType = webrtc.ICECredentialTypePassword
+		}
+	}
+	return server, isICEServer
+}
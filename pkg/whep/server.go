@@ -0,0 +1,252 @@
+// +build !js
+
+package whep
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pion/randutil"
+	"github.com/pion/webrtc/v2"
+)
+
+// Server is an http.Handler serving WHEP sessions. Every viewer gets its own
+// PeerConnection, answering its offer with the codecs and payload types it
+// offered, and sending the tracks returned by Tracks.
+//
+// A POST to any path served creates a session, the session URL is that path
+// followed by the session ID.
+type Server struct {
+	// SettingEngine and Configuration of the PeerConnections. The ICEServers
+	// are also advertised to viewers in Link headers.
+	SettingEngine webrtc.SettingEngine
+	Configuration webrtc.Configuration
+
+	// Tracks returns the tracks sent to the viewer making the request r, for
+	// example the ones of the stream named by its path. An error is reported
+	// to the viewer as 404 Not Found.
+	//
+	// Tracks can be shared by any number of viewers. Their payload type has
+	// to be the one the viewers offer for their codec.
+	Tracks func(r *http.Request) ([]*webrtc.Track, error)
+
+	// Token, if not empty, is the Bearer token viewers have to present
+	Token string
+
+	// OnSession, if set, is called with the PeerConnection of every new
+	// session once it is answered
+	OnSession func(r *http.Request, pc *webrtc.PeerConnection)
+
+	mu       sync.Mutex
+	api      *webrtc.API
+	sessions map[string]*webrtc.PeerConnection
+}
+
+// ServeHTTP handles POST of offers and DELETE of sessions
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.createSession(w, r)
+	case http.MethodDelete:
+		s.deleteSession(w, r)
+	case http.MethodOptions:
+		s.writeICEServers(w)
+		w.Header().Set("Allow", "OPTIONS, POST, DELETE")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		// Trickle and ICE restarts with PATCH are not supported
+		w.Header().Set("Allow", "OPTIONS, POST, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// authorized checks the Bearer token of r in constant time, so its
+// comparison doesn't leak how much of Token was guessed
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.Token)) == 1
+}
+
+// Close ends every session
+func (s *Server) Close() error {
+	s.mu.Lock()
+	sessions := s.sessions
+	s.sessions = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for _, pc := range sessions {
+		if err := pc.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("whep: failed to close %d sessions: %v", len(errs), errs[0])
+	}
+	return nil
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != contentTypeSDP {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSDPSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tracks, err := s.Tracks(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	id, err := randutil.GenerateCryptoRandomString(sessionIDLen, sessionIDRunes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pc, err := s.getAPI().NewPeerConnection(s.Configuration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	localDescription, status, err := answer(r.Context(), pc, string(body), tracks)
+	if err != nil {
+		_ = pc.Close()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			s.removeSession(id, pc)
+		}
+	})
+
+	s.mu.Lock()
+	if s.sessions == nil {
+		s.sessions = map[string]*webrtc.PeerConnection{}
+	}
+	s.sessions[id] = pc
+	s.mu.Unlock()
+
+	if s.OnSession != nil {
+		s.OnSession(r, pc)
+	}
+
+	// The request path is used before any http.StripPrefix
+	requestPath := r.URL.Path
+	if requestURI, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		requestPath = requestURI.Path
+	}
+
+	s.writeICEServers(w)
+	w.Header().Set("Content-Type", contentTypeSDP)
+	w.Header().Set("Location", path.Join(requestPath, id))
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(localDescription.SDP))
+}
+
+// answer negotiates the offer of a viewer on pc, returning the answer with
+// every candidate gathered before ctx is done, or the HTTP status of a
+// failure
+func answer(ctx context.Context, pc *webrtc.PeerConnection, offer string, tracks []*webrtc.Track) (*webrtc.SessionDescription, int, error) {
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	for _, track := range tracks {
+		if _, err := pc.AddTrack(track); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err = pc.SetLocalDescription(answer); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	localDescription, err := pc.LocalDescriptionContext(ctx)
+	if err != nil {
+		return nil, http.StatusServiceUnavailable, err
+	}
+	return localDescription, http.StatusCreated, nil
+}
+
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request) {
+	id := path.Base(r.URL.Path)
+
+	s.mu.Lock()
+	pc, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if err := pc.Close(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) removeSession(id string, pc *webrtc.PeerConnection) {
+	s.mu.Lock()
+	if s.sessions[id] == pc {
+		delete(s.sessions, id)
+	}
+	s.mu.Unlock()
+
+	_ = pc.Close()
+}
+
+func (s *Server) getAPI() *webrtc.API {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.api == nil {
+		settingEngine := s.SettingEngine
+		settingEngine.PopulateMediaEngineFromRemoteOffer(true)
+		s.api = webrtc.NewAPI(webrtc.WithSettingEngine(settingEngine))
+	}
+	return s.api
+}
+
+// writeICEServers advertises the ICEServers of the Configuration
+func (s *Server) writeICEServers(w http.ResponseWriter) {
+	for _, server := range s.Configuration.ICEServers {
+		for _, u := range server.URLs {
+			link := fmt.Sprintf("<%s>; rel=\"ice-server\"", u)
+			if credential, ok := server.Credential.(string); ok && strings.HasPrefix(u, "turn") {
+				link += fmt.Sprintf("; username=%q; credential=%q; credential-type=\"password\"", server.Username, credential)
+			}
+			w.Header().Add("Link", link)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// +build !js
+
+// Package whep implements the WebRTC-HTTP Egress Protocol, an HTTP signaling
+// exchange for viewers pulling the tracks of a broadcast. The viewer POSTs
+// an SDP offer to the endpoint of a stream and gets the answer back, along
+// with the URL of its session. A DELETE of that URL ends the session.
+//
+// Candidates are not trickled, the offer and the answer carry all of them,
+// so the PeerConnections have to keep trickle disabled. They are sent once
+// ICE gathering is complete.
+// The protocol is documented at https://datatracker.ietf.org/doc/draft-murillo-whep/
+package whep
+
+import (
+	"errors"
+)
+
+const (
+	contentTypeSDP = "application/sdp"
+	maxSDPSize     = 1 << 16
+	sessionIDLen   = 16
+	sessionIDRunes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+var (
+	errNoLocation     = errors.New("whep: response has no Location")
+	errNotContentType = errors.New("whep: response is not application/sdp")
+)
@@ -0,0 +1,145 @@
+// +build !js
+
+package whep
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func newViewer(t *testing.T) *webrtc.PeerConnection {
+	mediaEngine := webrtc.MediaEngine{}
+	mediaEngine.RegisterDefaultCodecs()
+	pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(mediaEngine)).NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RtpTransceiverInit{
+		Direction: webrtc.RTPTransceiverDirectionRecvonly,
+	})
+	assert.NoError(t, err)
+	return pc
+}
+
+func TestWHEP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	track, err := webrtc.NewTrack(webrtc.DefaultPayloadTypeVP8, 1234, "video", "pion", webrtc.NewRTPVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	server := &Server{
+		Configuration: webrtc.Configuration{
+			ICEServers: []webrtc.ICEServer{
+				{URLs: []string{"stun:stun.example.com:3478"}},
+				{URLs: []string{"turn:turn.example.com:3478"}, Username: "user", Credential: "pass"},
+			},
+		},
+		Tracks: func(r *http.Request) ([]*webrtc.Track, error) {
+			if r.URL.Path != "/stream" {
+				return nil, errors.New("no such stream")
+			}
+			return []*webrtc.Track{track}, nil
+		},
+		Token: "secret",
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/whep/", http.StripPrefix("/whep", server))
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	client := &Client{Token: "secret"}
+	pc := newViewer(t)
+
+	onTrackFired := make(chan struct{})
+	pc.OnTrack(func(remote *webrtc.Track, receiver *webrtc.RTPReceiver) {
+		assert.Equal(t, track.SSRC(), remote.SSRC())
+		close(onTrackFired)
+	})
+
+	session, err := client.Play(httpServer.URL+"/whep/stream", pc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, strings.HasPrefix(session.URL(), httpServer.URL+"/whep/stream/"))
+
+	func() {
+		for {
+			assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			select {
+			case <-onTrackFired:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}()
+
+	assert.NoError(t, session.Close())
+	assert.Error(t, session.Close(), "the session is gone")
+	assert.NoError(t, pc.Close())
+
+	// The ICE servers are advertised
+	req, err := http.NewRequest(http.MethodOptions, httpServer.URL+"/whep/stream", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Equal(t, []webrtc.ICEServer{
+		{URLs: []string{"stun:stun.example.com:3478"}},
+		{URLs: []string{"turn:turn.example.com:3478"}, Username: "user", Credential: "pass", CredentialType: webrtc.ICECredentialTypePassword},
+	}, ICEServers(res.Header))
+
+	assert.NoError(t, server.Close())
+}
+
+func TestWHEP_Errors(t *testing.T) {
+	server := &Server{
+		Tracks: func(r *http.Request) ([]*webrtc.Track, error) {
+			return nil, errors.New("no such stream")
+		},
+		Token: "secret",
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	for _, testCase := range []struct {
+		method, contentType, token, body string
+		expectedStatus                   int
+	}{
+		{http.MethodPost, contentTypeSDP, "", "", http.StatusUnauthorized},
+		{http.MethodPost, contentTypeSDP, "wrong", "", http.StatusUnauthorized},
+		{http.MethodPost, "text/plain", "secret", "", http.StatusUnsupportedMediaType},
+		{http.MethodPost, contentTypeSDP, "secret", "v=0", http.StatusNotFound},
+		{http.MethodPost, contentTypeSDP, "secret", strings.Repeat("a", maxSDPSize+1), http.StatusBadRequest},
+		{http.MethodDelete, "", "secret", "", http.StatusNotFound},
+		{http.MethodPatch, "application/trickle-ice-sdpfrag", "secret", "", http.StatusMethodNotAllowed},
+	} {
+		req, err := http.NewRequest(testCase.method, httpServer.URL+"/stream", strings.NewReader(testCase.body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", testCase.contentType)
+		if testCase.token != "" {
+			req.Header.Set("Authorization", "Bearer "+testCase.token)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, testCase.expectedStatus, res.StatusCode, "%v", testCase)
+	}
+
+	// The client reports failures
+	pc := newViewer(t)
+	_, err := (&Client{Token: "secret"}).Play(httpServer.URL+"/stream", pc)
+	assert.Error(t, err)
+	assert.NoError(t, pc.Close())
+}
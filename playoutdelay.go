@@ -0,0 +1,48 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+)
+
+// The playout delays are sent as two 12 bit counts of playoutDelayUnit
+const (
+	playoutDelayUnit = 10 * time.Millisecond
+	playoutDelayMax  = 0xFFF * playoutDelayUnit
+)
+
+// PlayoutDelay is the range of delay between the capture and the render of
+// the frames the receiver is asked to keep, sent in the playout-delay header
+// extension. A Max of 0 asks to render the frames as soon as they are
+// decoded, without a jitter buffer, like cloud gaming or remote control want.
+type PlayoutDelay struct {
+	Min, Max time.Duration
+}
+
+func (d PlayoutDelay) marshal() []byte {
+	min, max := d.Min/playoutDelayUnit, d.Max/playoutDelayUnit
+	return []byte{byte(min >> 4), byte(min<<4) | byte(max>>8), byte(max)}
+}
+
+// SetPlayoutDelay makes the sender send d in the last packet of every video
+// frame, when the remote negotiated the playout-delay header extension.
+// Delays are rounded down to 10 milliseconds.
+func (r *RTPSender) SetPlayoutDelay(d PlayoutDelay) error {
+	if d.Min < 0 || d.Min > d.Max || d.Max > playoutDelayMax {
+		return ErrInvalidPlayoutDelay
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.playoutDelay = &d
+	return nil
+}
+
+// setPlayoutDelayExtensionID makes the sender send its playout delay in the
+// header extension with the id the remote negotiated, 0 for none
+func (r *RTPSender) setPlayoutDelayExtensionID(id uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.playoutDelayExtensionID = id
+}
@@ -0,0 +1,35 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlayoutDelayMarshal(t *testing.T) {
+	assert.Equal(t, []byte{0x00, 0x00, 0x00}, PlayoutDelay{}.marshal())
+	assert.Equal(t, []byte{0x00, 0xA0, 0x64}, PlayoutDelay{Min: 100 * time.Millisecond, Max: time.Second}.marshal())
+	assert.Equal(t, []byte{0xFF, 0xFF, 0xFF}, PlayoutDelay{Min: playoutDelayMax, Max: playoutDelayMax}.marshal())
+}
+
+func TestRTPSenderPlayoutDelay(t *testing.T) {
+	s := &RTPSender{}
+	assert.Equal(t, ErrInvalidPlayoutDelay, s.SetPlayoutDelay(PlayoutDelay{Min: time.Second}))
+	assert.Equal(t, ErrInvalidPlayoutDelay, s.SetPlayoutDelay(PlayoutDelay{Max: time.Minute}))
+	assert.Equal(t, ErrInvalidPlayoutDelay, s.SetPlayoutDelay(PlayoutDelay{Min: -time.Second}))
+
+	header := &rtp.Header{Version: 2, SSRC: 1000, Marker: true}
+	assert.NoError(t, s.SetPlayoutDelay(PlayoutDelay{}))
+	assert.Equal(t, header, s.withHeaderExtensions(header), "nothing negotiated")
+
+	s.setPlayoutDelayExtensionID(7)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00}, s.withHeaderExtensions(header).GetExtension(7))
+	assert.False(t, header.Extension, "the header of the Track is shared")
+
+	middle := &rtp.Header{Version: 2, SSRC: 1000}
+	assert.Equal(t, middle, s.withHeaderExtensions(middle), "only the last packet of a frame")
+}
@@ -0,0 +1,227 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// ProberConfig configures a Prober, the zero values select the defaults
+type ProberConfig struct {
+	// StartBitrate is the rate of the first cluster in bits per second,
+	// 300 kbps by default
+	StartBitrate uint64
+
+	// MaxBitrate is the rate probing stops at, 0 for none
+	MaxBitrate uint64
+
+	// ClusterDuration is how long the padding of a cluster is sent, 500
+	// milliseconds by default
+	ClusterDuration time.Duration
+
+	// Multiplier is the rate of the next cluster relative to the estimate
+	// that followed the last one, 2 by default
+	Multiplier float64
+
+	// SuccessRatio is the fraction of the rate of a cluster the estimate has
+	// to reach to probe further, 0.8 by default
+	SuccessRatio float64
+
+	// DropRatio is the fraction of the highest estimate an estimate has to
+	// fall below to be a drop, 0.7 by default
+	DropRatio float64
+
+	// RecoveryDelay is how long after a drop the estimate before it is
+	// probed, 3 seconds by default
+	RecoveryDelay time.Duration
+}
+
+// Prober discovers the bandwidth available to an RTPSender faster than the
+// estimator of the remote ramps up on its own. It sends probe clusters,
+// padding-only RTP packets at a given rate on top of the media, so the remote
+// estimates the bandwidth at the rate probed instead of at the rate of the
+// media. Every cluster the estimate follows is followed by one at Multiplier
+// times the estimate, until the estimate falls behind or reaches MaxBitrate.
+//
+// Start probes after the connection is established. After a drop of the
+// estimate, the estimate before it is probed again once RecoveryDelay passed,
+// so short congestion doesn't leave the media at a low rate for long.
+// Estimates are read from the REMB of the RTCP, or given to ObserveEstimate:
+//
+//	p := webrtc.NewProber(sender, webrtc.ProberConfig{MaxBitrate: 2500000})
+//	sender.OnRTCP(webrtc.RTCPHandlers{OnPacket: p.ObserveRTCP})
+//	p.Start()
+type Prober struct {
+	mu sync.Mutex
+
+	config ProberConfig
+	ssrc   uint32
+	send   func(size int) (int, error)
+
+	// probing is set while a cluster is sent, awaiting once it was sent and
+	// the estimate that follows it decides about the next one
+	probing  bool
+	awaiting bool
+	target   uint64
+	highest  uint64
+	recovery *time.Timer
+
+	closeOnce sync.Once
+	closed    chan interface{}
+}
+
+// NewProber creates a Prober sending its padding on the stream of sender
+func NewProber(sender *RTPSender, config ProberConfig) *Prober {
+	return newProber(sender.track.SSRC(), sender.sendPadding, config)
+}
+
+func newProber(ssrc uint32, send func(size int) (int, error), config ProberConfig) *Prober {
+	if config.StartBitrate == 0 {
+		config.StartBitrate = defaultProbeStartBitrate
+	}
+	if config.ClusterDuration == 0 {
+		config.ClusterDuration = defaultProbeClusterDuration
+	}
+	if config.Multiplier == 0 {
+		config.Multiplier = defaultProbeMultiplier
+	}
+	if config.SuccessRatio == 0 {
+		config.SuccessRatio = defaultProbeSuccessRatio
+	}
+	if config.DropRatio == 0 {
+		config.DropRatio = defaultProbeDropRatio
+	}
+	if config.RecoveryDelay == 0 {
+		config.RecoveryDelay = defaultProbeRecoveryDelay
+	}
+
+	return &Prober{
+		config: config,
+		ssrc:   ssrc,
+		send:   send,
+		closed: make(chan interface{}),
+	}
+}
+
+// Start sends a cluster at the StartBitrate, unless one is being sent
+func (p *Prober) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.probing && !p.awaiting {
+		p.probe(p.config.StartBitrate)
+	}
+}
+
+// Probing tells if a cluster is being sent or its estimate awaited
+func (p *Prober) Probing() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.probing || p.awaiting
+}
+
+// ObserveEstimate takes the bandwidth the remote estimated, in bits per
+// second
+func (p *Prober) ObserveEstimate(bitrate uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	highest := p.highest
+	if bitrate > p.highest {
+		p.highest = bitrate
+	}
+
+	switch {
+	case p.awaiting:
+		p.awaiting = false
+		if float64(bitrate) < float64(p.target)*p.config.SuccessRatio {
+			return
+		}
+		next := uint64(float64(bitrate) * p.config.Multiplier)
+		if p.config.MaxBitrate != 0 && next > p.config.MaxBitrate {
+			next = p.config.MaxBitrate
+		}
+		if next > bitrate && next > p.target {
+			p.probe(next)
+		}
+	case !p.probing && p.recovery == nil && float64(bitrate) < float64(highest)*p.config.DropRatio:
+		// The drop is the new reference for the next one
+		p.highest = bitrate
+		p.recovery = time.AfterFunc(p.config.RecoveryDelay, func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			p.recovery = nil
+			if !p.isClosed() && !p.probing && !p.awaiting {
+				p.probe(highest)
+			}
+		})
+	}
+}
+
+// ObserveRTCP takes the REMB about the stream of the RTPSender, like from
+// the OnPacket handler of the RTPSender
+func (p *Prober) ObserveRTCP(pkt rtcp.Packet) {
+	if remb, ok := pkt.(*rtcp.ReceiverEstimatedMaximumBitrate); ok {
+		for _, ssrc := range remb.SSRCs {
+			if ssrc == p.ssrc {
+				p.ObserveEstimate(remb.Bitrate)
+				return
+			}
+		}
+	}
+}
+
+// Close stops the cluster being sent and any further probing
+func (p *Prober) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.recovery != nil {
+		p.recovery.Stop()
+		p.recovery = nil
+	}
+}
+
+func (p *Prober) isClosed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// probe starts sending a cluster at bitrate, p.mu must be held
+func (p *Prober) probe(bitrate uint64) {
+	if p.isClosed() {
+		return
+	}
+	p.probing = true
+	p.target = bitrate
+	go p.sendCluster(bitrate)
+}
+
+func (p *Prober) sendCluster(bitrate uint64) {
+	pacer := NewPacer(bitrate, maxPaddingSize, bandwidthLimitInterval)
+	deadline := time.Now().Add(p.config.ClusterDuration)
+	for time.Now().Before(deadline) {
+		if !pacer.wait(maxPaddingSize, p.closed) {
+			break
+		}
+		if _, err := p.send(maxPaddingSize); err != nil {
+			break
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.probing = false
+	p.awaiting = !p.isClosed()
+}
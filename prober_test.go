@@ -0,0 +1,68 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProber(t *testing.T) {
+	var mu sync.Mutex
+	sent := 0
+	p := newProber(5000, func(size int) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent += size
+		return size, nil
+	}, ProberConfig{
+		StartBitrate:    400000,
+		MaxBitrate:      1000000,
+		ClusterDuration: 100 * time.Millisecond,
+		RecoveryDelay:   10 * time.Millisecond,
+	})
+	defer p.Close()
+
+	awaitCluster := func() uint64 {
+		for {
+			p.mu.Lock()
+			awaiting, target := p.awaiting, p.target
+			p.mu.Unlock()
+			if awaiting {
+				return target
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	p.Start()
+	assert.True(t, p.Probing())
+	assert.Equal(t, uint64(400000), awaitCluster())
+	mu.Lock()
+	// 5000 bytes in 100ms at 400 kbps, and the initial burst
+	assert.InDelta(t, 5000, sent, 1500)
+	mu.Unlock()
+
+	// The estimate followed, the next cluster is at twice the estimate. REMB
+	// of other streams is ignored.
+	p.ObserveRTCP(&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 100000, SSRCs: []uint32{1}})
+	assert.True(t, p.Probing())
+	p.ObserveRTCP(&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 380000, SSRCs: []uint32{5000}})
+	assert.Equal(t, uint64(760000), awaitCluster())
+
+	// Capped at the MaxBitrate
+	p.ObserveEstimate(700000)
+	assert.Equal(t, uint64(1000000), awaitCluster())
+
+	// The estimate didn't follow, probing stops
+	p.ObserveEstimate(750000)
+	assert.False(t, p.Probing())
+
+	// After a drop the highest estimate before it is probed
+	p.ObserveEstimate(200000)
+	assert.Equal(t, uint64(750000), awaitCluster())
+}
@@ -8,7 +8,6 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
-	"errors"
 	"strings"
 	"sync"
 	"time"
@@ -153,13 +152,13 @@ func (t *QUICTransport) validateFingerPrint(remoteParameters QUICParameters, rem
 		}
 	}
 
-	return errors.New("no matching fingerprint")
+	return ErrNoMatchingFingerprint
 }
 
 func (t *QUICTransport) ensureICEConn() error {
 	if t.iceTransport == nil ||
 		t.iceTransport.State() == ICETransportStateNew {
-		return errors.New("ICE connection not started")
+		return ErrICEConnectionNotStarted
 	}
 
 	return nil
@@ -0,0 +1,142 @@
+// +build !js
+
+package webrtc
+
+import "github.com/pion/webrtc/v2/pkg/rtcerr"
+
+// newRateLimiter creates the Pacer enforcing a limit of bitrate bits per
+// second, nil for no limit. It sends bandwidthLimitBurst of the limit at once
+// after being idle.
+func newRateLimiter(bitrate uint64) *Pacer {
+	if bitrate == 0 {
+		return nil
+	}
+	burst := int(float64(bitrate) / 8 * bandwidthLimitBurst.Seconds())
+	if burst < receiveMTU {
+		burst = receiveMTU
+	}
+	return NewPacer(bitrate, burst, bandwidthLimitInterval)
+}
+
+// waitRateLimits blocks until size bytes may be sent under all the limiters,
+// nil ones are skipped. It returns false if cancel is closed first.
+func waitRateLimits(size int, cancel <-chan interface{}, limiters ...*Pacer) bool {
+	for _, l := range limiters {
+		if l != nil && !l.wait(size, cancel) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasLimiter tells if any of the limiters is set
+func hasLimiter(limiters []*Pacer) bool {
+	for _, l := range limiters {
+		if l != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// SetEgressRateLimit limits the RTP and the DataChannel messages sent over
+// the DTLSTransport to bitrate bits per second, on top of the limits of each
+// RTPSender and DataChannel. RTP packets over the limit wait in the queue of
+// their RTPSender, DataChannel writes block. 0 removes the limit.
+func (t *DTLSTransport) SetEgressRateLimit(bitrate uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.egressRateLimit = bitrate
+	t.egressLimiter = newRateLimiter(bitrate)
+}
+
+// EgressRateLimit returns the limit set with SetEgressRateLimit
+func (t *DTLSTransport) EgressRateLimit() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.egressRateLimit
+}
+
+func (t *DTLSTransport) getEgressLimiter() *Pacer {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.egressLimiter
+}
+
+// SetEgressRateLimit limits the media and the DataChannel messages the
+// PeerConnection sends to bitrate bits per second, so a session can't exceed
+// its share of the bandwidth of a server. RTP packets over the limit wait in
+// the queue of their RTPSender, DataChannel writes block. 0 removes the
+// limit, the default is the one of the SettingEngine.
+func (pc *PeerConnection) SetEgressRateLimit(bitrate uint64) {
+	pc.dtlsTransport.SetEgressRateLimit(bitrate)
+}
+
+// SetRateLimit limits the packets sent by the RTPSender, padding included,
+// to bitrate bits per second. Unlike the Pacer, which spaces out packets at
+// an estimate that changes all the time, it enforces a quota. The packets
+// over the limit wait in the queue of the RTPSender like the ones of the
+// Pacer, writing to the Track doesn't block. 0 removes the limit.
+func (r *RTPSender) SetRateLimit(bitrate uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rateLimit = bitrate
+	r.rateLimiter = newRateLimiter(bitrate)
+}
+
+// RateLimit returns the limit set with SetRateLimit
+func (r *RTPSender) RateLimit() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.rateLimit
+}
+
+// rateLimiters returns the limiters of the packets sent: the bandwidth limit
+// of the remote, the rate limit and the egress limit of the transport. r.mu
+// must be held.
+func (r *RTPSender) rateLimiters() []*Pacer {
+	limiters := []*Pacer{r.limiter, r.rateLimiter}
+	if t, ok := r.transport.(*DTLSTransport); ok {
+		limiters = append(limiters, t.getEgressLimiter())
+	}
+	return limiters
+}
+
+// SetRateLimit limits the messages sent on the DataChannel to bitrate bits
+// per second. Send blocks while it is over the limit, and fails if the
+// DataChannel is closed meanwhile. 0 removes the limit.
+func (d *DataChannel) SetRateLimit(bitrate uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.rateLimit = bitrate
+	d.rateLimiter = newRateLimiter(bitrate)
+}
+
+// RateLimit returns the limit set with SetRateLimit
+func (d *DataChannel) RateLimit() uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.rateLimit
+}
+
+// waitRateLimits blocks until a message of size bytes may be sent under the
+// rate limit and the egress limit of the transport, or the DataChannel is
+// closing
+func (d *DataChannel) waitRateLimits(size int) error {
+	d.mu.RLock()
+	limiters := []*Pacer{d.rateLimiter}
+	if d.sctpTransport != nil {
+		if t := d.sctpTransport.Transport(); t != nil {
+			limiters = append(limiters, t.getEgressLimiter())
+		}
+	}
+	d.mu.RUnlock()
+
+	if !waitRateLimits(size, d.closed, limiters...) {
+		return &rtcerr.InvalidStateError{Err: ErrDataChannelNotOpen}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	assert.Nil(t, newRateLimiter(0))
+
+	l := newRateLimiter(8000)
+	assert.Equal(t, uint64(8000), l.Bitrate())
+	assert.Equal(t, float64(receiveMTU), l.burst, "at least a packet is sent at once")
+	assert.Equal(t, float64(125000), newRateLimiter(10000000).burst)
+}
+
+func TestWaitRateLimits(t *testing.T) {
+	// 1460 bytes per 100ms
+	limiter := newRateLimiter(116800)
+	assert.True(t, waitRateLimits(receiveMTU, nil, nil, limiter))
+
+	start := time.Now()
+	assert.True(t, waitRateLimits(receiveMTU, nil, limiter, nil))
+	assert.True(t, time.Since(start) >= 50*time.Millisecond, "the burst was used up")
+
+	cancel := make(chan interface{})
+	close(cancel)
+	assert.False(t, waitRateLimits(receiveMTU, cancel, limiter))
+}
+
+func TestRateLimits(t *testing.T) {
+	transport := &DTLSTransport{}
+	transport.SetEgressRateLimit(1000000)
+	assert.Equal(t, uint64(1000000), transport.EgressRateLimit())
+
+	s := &RTPSender{transport: transport}
+	s.SetRateLimit(500000)
+	assert.Equal(t, uint64(500000), s.RateLimit())
+	s.setMaxBitrate(2000000)
+
+	s.mu.RLock()
+	limiters := s.rateLimiters()
+	s.mu.RUnlock()
+	bitrates := []uint64{}
+	for _, l := range limiters {
+		bitrates = append(bitrates, l.Bitrate())
+	}
+	assert.Equal(t, []uint64{2000000, 500000, 1000000}, bitrates)
+
+	s.SetRateLimit(0)
+	transport.SetEgressRateLimit(0)
+	s.mu.RLock()
+	assert.Equal(t, []*Pacer{s.limiter, nil, nil}, s.rateLimiters())
+	s.mu.RUnlock()
+
+	d := &DataChannel{closed: make(chan interface{})}
+	d.SetRateLimit(64000)
+	assert.Equal(t, uint64(64000), d.RateLimit())
+	assert.NoError(t, d.waitRateLimits(100))
+
+	// A send over the limit gives up once the DataChannel is closing
+	d.SetRateLimit(8)
+	assert.NoError(t, d.waitRateLimits(receiveMTU))
+	go d.setReadyState(DataChannelStateClosing)
+	assert.Error(t, d.waitRateLimits(receiveMTU))
+}
@@ -0,0 +1,102 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/transport/packetio"
+)
+
+// readContext reads from buffer like buffer.Read, until ctx is done. buffer
+// must only be read by one caller at a time.
+func readContext(ctx context.Context, buffer *packetio.Buffer, b []byte) (int, error) {
+	if ctx.Done() == nil {
+		return buffer.Read(b)
+	}
+
+	unwatch := watchContext(ctx, buffer)
+	if err := ctx.Err(); err != nil {
+		unwatch()
+		return 0, err
+	}
+	n, err := buffer.Read(b)
+	unwatch()
+	_ = buffer.SetReadDeadline(time.Time{})
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() && ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	return n, err
+}
+
+// readContexts are the contexts readContext calls wait on, by their Done
+// channel. A single goroutine watches a context for all the reads using it,
+// instead of one per read, as the reads of every Track of an SFU commonly
+// share the context of their session.
+var readContexts = struct {
+	sync.Mutex
+	watchers map[<-chan struct{}]*contextWatcher
+}{watchers: map[<-chan struct{}]*contextWatcher{}}
+
+// contextWatcher cancels the reads of the buffers waiting on a context once
+// it is done. It stops after readContextLinger without reads, so a context
+// that is never canceled doesn't keep it.
+type contextWatcher struct {
+	buffers map[*packetio.Buffer]struct{}
+	used    bool
+}
+
+// watchContext cancels the reads of buffer once ctx is done, until the
+// returned func is called
+func watchContext(ctx context.Context, buffer *packetio.Buffer) func() {
+	done := ctx.Done()
+
+	readContexts.Lock()
+	w, ok := readContexts.watchers[done]
+	if !ok {
+		w = &contextWatcher{buffers: map[*packetio.Buffer]struct{}{}}
+		readContexts.watchers[done] = w
+		go w.run(done)
+	}
+	w.buffers[buffer] = struct{}{}
+	w.used = true
+	readContexts.Unlock()
+
+	return func() {
+		readContexts.Lock()
+		delete(w.buffers, buffer)
+		readContexts.Unlock()
+	}
+}
+
+func (w *contextWatcher) run(done <-chan struct{}) {
+	ticker := time.NewTicker(readContextLinger)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			readContexts.Lock()
+			delete(readContexts.watchers, done)
+			for buffer := range w.buffers {
+				// A deadline in the past wakes up the blocked Read
+				_ = buffer.SetReadDeadline(time.Unix(0, 1))
+			}
+			readContexts.Unlock()
+			return
+		case <-ticker.C:
+			readContexts.Lock()
+			if len(w.buffers) == 0 && !w.used {
+				delete(readContexts.watchers, done)
+				readContexts.Unlock()
+				return
+			}
+			w.used = false
+			readContexts.Unlock()
+		}
+	}
+}
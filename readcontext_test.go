@@ -0,0 +1,83 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/packetio"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadContext(t *testing.T) {
+	buffer := packetio.NewBuffer()
+	b := make([]byte, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := readContext(ctx, buffer, b)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// The deadline used to cancel doesn't stick to the buffer
+	_, err = buffer.Write([]byte{1, 2, 3})
+	assert.NoError(t, err)
+	n, err := readContext(context.Background(), buffer, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, b[:n])
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = buffer.Write([]byte{4})
+	}()
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	n, err = readContext(ctx, buffer, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{4}, b[:n])
+}
+
+func TestReadContextSharedWatcher(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	watchers := func() int {
+		readContexts.Lock()
+		defer readContexts.Unlock()
+		return len(readContexts.watchers)
+	}
+
+	// The reads of many buffers with the same context share its watcher
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	for i := 0; i < 10; i++ {
+		go func() {
+			_, err := readContext(ctx, packetio.NewBuffer(), make([]byte, 10))
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, watchers())
+
+	cancel()
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, context.Canceled, <-errs)
+	}
+	assert.Equal(t, 0, watchers())
+
+	// The watcher of a context that isn't canceled stops once unused
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	buffer := packetio.NewBuffer()
+	for i := 0; i < 10; i++ {
+		_, err := buffer.Write([]byte{byte(i)})
+		assert.NoError(t, err)
+		_, err = readContext(ctx, buffer, make([]byte, 10))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, watchers())
+	time.Sleep(3 * readContextLinger)
+	assert.Equal(t, 0, watchers())
+}
@@ -0,0 +1,162 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+)
+
+// rtcpBatcher queues the RTCP packets written to a PeerConnection and sends
+// them together as compound packets once per interval, RFC 3550 6.1
+type rtcpBatcher struct {
+	interval time.Duration
+	write    func([]byte) error
+	log      logging.LeveledLogger
+
+	// ssrc is the sender SSRC of the ReceiverReport that starts a compound
+	// packet without a report of its own
+	ssrc uint32
+
+	// reducedSize is set once the remote accepted reduced-size RTCP, RFC 5506.
+	// Feedback is then sent without a report in front of it.
+	reducedSize atomicBool
+
+	mu      sync.Mutex
+	pending []batchedRTCP
+	timer   *time.Timer
+	closed  bool
+}
+
+// batchedRTCP is a marshaled packet waiting for the next batch
+type batchedRTCP struct {
+	raw    []byte
+	report bool
+}
+
+func newRTCPBatcher(interval time.Duration, ssrc uint32, write func([]byte) error, log logging.LeveledLogger) *rtcpBatcher {
+	return &rtcpBatcher{
+		interval: interval,
+		ssrc:     ssrc,
+		write:    write,
+		log:      log,
+	}
+}
+
+// marshalBatch marshals pkts, failing for all of them if one fails
+func marshalBatch(pkts []rtcp.Packet) ([]batchedRTCP, error) {
+	batch := make([]batchedRTCP, 0, len(pkts))
+	for _, pkt := range pkts {
+		raw, err := pkt.Marshal()
+		if err != nil {
+			return nil, err
+		}
+
+		switch pkt.(type) {
+		case *rtcp.SenderReport, *rtcp.ReceiverReport:
+			batch = append(batch, batchedRTCP{raw: raw, report: true})
+		default:
+			batch = append(batch, batchedRTCP{raw: raw})
+		}
+	}
+	return batch, nil
+}
+
+// enqueue queues pkts to be sent with the next batch. They are marshaled
+// right away, so the caller gets the errors of invalid packets.
+func (b *rtcpBatcher) enqueue(pkts []rtcp.Packet) error {
+	batch, err := marshalBatch(pkts)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return io.ErrClosedPipe
+	}
+
+	b.pending = append(b.pending, batch...)
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, b.flush)
+	}
+	return nil
+}
+
+// flush sends the queued packets
+func (b *rtcpBatcher) flush() {
+	b.mu.Lock()
+	pkts := b.pending
+	b.pending = nil
+	b.timer = nil
+	b.mu.Unlock()
+
+	datagrams, err := b.compound(pkts)
+	if err != nil {
+		b.log.Warnf("Failed to batch %d RTCP packets: %v", len(pkts), err)
+		return
+	}
+	for i, datagram := range datagrams {
+		if err := b.write(datagram); err != nil {
+			b.log.Warnf("Failed to send %d of %d RTCP datagrams: %v", len(datagrams)-i, len(datagrams), err)
+			return
+		}
+	}
+}
+
+// compound packs pkts into as few datagrams of at most rtpOutboundMTU bytes
+// as possible. Reports go first and without reduced-size RTCP every datagram
+// starts with one, adding an empty ReceiverReport when there is none.
+func (b *rtcpBatcher) compound(pkts []batchedRTCP) ([][]byte, error) {
+	var reports, feedback [][]byte
+	for _, pkt := range pkts {
+		if pkt.report {
+			reports = append(reports, pkt.raw)
+		} else {
+			feedback = append(feedback, pkt.raw)
+		}
+	}
+
+	var emptyReport []byte
+	if !b.reducedSize.get() {
+		var err error
+		if emptyReport, err = (&rtcp.ReceiverReport{SSRC: b.ssrc}).Marshal(); err != nil {
+			return nil, err
+		}
+	}
+
+	var datagrams [][]byte
+	var datagram []byte
+	for i, raw := range append(reports, feedback...) {
+		if len(datagram) != 0 && len(datagram)+len(raw) > rtpOutboundMTU {
+			datagrams = append(datagrams, datagram)
+			datagram = nil
+		}
+		if len(datagram) == 0 && emptyReport != nil && i >= len(reports) {
+			datagram = append(datagram, emptyReport...)
+		}
+		datagram = append(datagram, raw...)
+	}
+	if len(datagram) != 0 {
+		datagrams = append(datagrams, datagram)
+	}
+	return datagrams, nil
+}
+
+// close drops the queued packets and rejects new ones
+func (b *rtcpBatcher) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
@@ -0,0 +1,91 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCPBatcher(t *testing.T) {
+	written := make(chan []byte, 10)
+	b := newRTCPBatcher(10*time.Millisecond, 1234, func(raw []byte) error {
+		written <- raw
+		return nil
+	}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+
+	pli := &rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 2}
+	nack := &rtcp.TransportLayerNack{SenderSSRC: 1, MediaSSRC: 2, Nacks: []rtcp.NackPair{{PacketID: 5}}}
+	assert.NoError(t, b.enqueue([]rtcp.Packet{pli}))
+	assert.NoError(t, b.enqueue([]rtcp.Packet{nack}))
+
+	pkts, err := rtcp.Unmarshal(<-written)
+	assert.NoError(t, err)
+	assert.Equal(t, []rtcp.Packet{&rtcp.ReceiverReport{SSRC: 1234, ProfileExtensions: []byte{}}, pli, nack}, pkts)
+
+	// Reports go first, without an empty one in front
+	rr := &rtcp.ReceiverReport{SSRC: 5678, ProfileExtensions: []byte{}}
+	assert.NoError(t, b.enqueue([]rtcp.Packet{pli, rr}))
+	pkts, err = rtcp.Unmarshal(<-written)
+	assert.NoError(t, err)
+	assert.Equal(t, []rtcp.Packet{rr, pli}, pkts)
+
+	b.reducedSize.set(true)
+	assert.NoError(t, b.enqueue([]rtcp.Packet{pli}))
+	pkts, err = rtcp.Unmarshal(<-written)
+	assert.NoError(t, err)
+	assert.Equal(t, []rtcp.Packet{pli}, pkts)
+
+	b.close()
+	assert.Equal(t, io.ErrClosedPipe, b.enqueue([]rtcp.Packet{pli}))
+}
+
+func TestRTCPBatcher_MTU(t *testing.T) {
+	b := newRTCPBatcher(time.Second, 1234, nil, logging.NewDefaultLoggerFactory().NewLogger("test"))
+
+	var pkts []rtcp.Packet
+	for i := 0; i < 200; i++ {
+		pkts = append(pkts, &rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: uint32(i)})
+	}
+
+	batch, err := marshalBatch(pkts)
+	assert.NoError(t, err)
+	datagrams, err := b.compound(batch)
+	assert.NoError(t, err)
+	assert.True(t, len(datagrams) > 1)
+
+	var received []rtcp.Packet
+	for _, datagram := range datagrams {
+		assert.True(t, len(datagram) <= rtpOutboundMTU)
+
+		compound, unmarshalErr := rtcp.Unmarshal(datagram)
+		assert.NoError(t, unmarshalErr)
+		assert.Equal(t, &rtcp.ReceiverReport{SSRC: 1234, ProfileExtensions: []byte{}}, compound[0])
+		received = append(received, compound[1:]...)
+	}
+	assert.Equal(t, pkts, received)
+}
+
+func TestRTCPBatcher_MarshalError(t *testing.T) {
+	written := make(chan []byte, 10)
+	b := newRTCPBatcher(10*time.Millisecond, 1234, func(raw []byte) error {
+		written <- raw
+		return nil
+	}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+	defer b.close()
+
+	// A ReceiverReport with more than 31 reports can't be marshaled
+	pli := &rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 2}
+	assert.Error(t, b.enqueue([]rtcp.Packet{pli, &rtcp.ReceiverReport{Reports: make([]rtcp.ReceptionReport, 32)}}))
+	assert.NoError(t, b.enqueue([]rtcp.Packet{pli}))
+
+	// The packets queued before and after the invalid ones are still sent
+	pkts, err := rtcp.Unmarshal(<-written)
+	assert.NoError(t, err)
+	assert.Equal(t, []rtcp.Packet{&rtcp.ReceiverReport{SSRC: 1234, ProfileExtensions: []byte{}}, pli}, pkts)
+}
@@ -0,0 +1,151 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/packetio"
+)
+
+// RTCPHandlers are the callbacks incoming RTCP packets are dispatched to by
+// RTPSender.OnRTCP and RTPReceiver.OnRTCP. Handlers that are nil are skipped.
+type RTCPHandlers struct {
+	OnSenderReport   func(*rtcp.SenderReport)
+	OnReceiverReport func(*rtcp.ReceiverReport)
+	OnNACK           func(*rtcp.TransportLayerNack)
+	OnPLI            func(*rtcp.PictureLossIndication)
+	OnFIR            func(*rtcp.FullIntraRequest)
+	OnREMB           func(*rtcp.ReceiverEstimatedMaximumBitrate)
+	OnTWCC           func(*rtcp.TransportLayerCC)
+
+	// OnPacket is called for every packet, including the ones of other types
+	OnPacket func(rtcp.Packet)
+}
+
+func (h RTCPHandlers) dispatch(pkt rtcp.Packet) {
+	if h.OnPacket != nil {
+		h.OnPacket(pkt)
+	}
+
+	switch p := pkt.(type) {
+	case *rtcp.SenderReport:
+		if h.OnSenderReport != nil {
+			h.OnSenderReport(p)
+		}
+	case *rtcp.ReceiverReport:
+		if h.OnReceiverReport != nil {
+			h.OnReceiverReport(p)
+		}
+	case *rtcp.TransportLayerNack:
+		if h.OnNACK != nil {
+			h.OnNACK(p)
+		}
+	case *rtcp.PictureLossIndication:
+		if h.OnPLI != nil {
+			h.OnPLI(p)
+		}
+	case *rtcp.FullIntraRequest:
+		if h.OnFIR != nil {
+			h.OnFIR(p)
+		}
+	case *rtcp.ReceiverEstimatedMaximumBitrate:
+		if h.OnREMB != nil {
+			h.OnREMB(p)
+		}
+	case *rtcp.TransportLayerCC:
+		if h.OnTWCC != nil {
+			h.OnTWCC(p)
+		}
+	}
+}
+
+// rtcpReadLoop reads the RTCP of an RTPSender or RTPReceiver in the
+// background, for the handlers set by OnRTCP and for ReadContext, whose reads
+// of the stream can't be canceled otherwise. It is started by the first of
+// them, so a stream never has more than one reading goroutine, and runs until
+// reading fails, when the RTPSender or RTPReceiver is stopped.
+type rtcpReadLoop struct {
+	mu       sync.Mutex
+	started  bool
+	ended    bool
+	handlers RTCPHandlers
+
+	// buffer gets every packet once ReadContext was called
+	buffer *packetio.Buffer
+}
+
+func (l *rtcpReadLoop) setHandlers(handlers RTCPHandlers, read func([]byte) (int, error)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.handlers = handlers
+	l.start(read)
+}
+
+// readBuffer returns the buffer of the packets read, the first call creates
+// it and starts the loop if needed
+func (l *rtcpReadLoop) readBuffer(read func([]byte) (int, error)) *packetio.Buffer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buffer == nil {
+		l.buffer = packetio.NewBuffer()
+		l.buffer.SetLimitSize(trackBufferSize)
+		if l.ended {
+			_ = l.buffer.Close()
+		}
+	}
+	l.start(read)
+	return l.buffer
+}
+
+// getBuffer returns the buffer, or nil if readBuffer wasn't called
+func (l *rtcpReadLoop) getBuffer() *packetio.Buffer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buffer
+}
+
+// start starts the loop unless it was. l.mu must be held.
+func (l *rtcpReadLoop) start(read func([]byte) (int, error)) {
+	if l.started {
+		return
+	}
+	l.started = true
+	go l.run(read)
+}
+
+func (l *rtcpReadLoop) run(read func([]byte) (int, error)) {
+	b := make([]byte, receiveMTU)
+	for {
+		i, err := read(b)
+
+		l.mu.Lock()
+		handlers, buffer := l.handlers, l.buffer
+		l.ended = err != nil
+		l.mu.Unlock()
+
+		if err != nil {
+			if buffer != nil {
+				_ = buffer.Close()
+			}
+			return
+		}
+
+		if buffer != nil {
+			// A full buffer drops the packet
+			_, _ = buffer.Write(b[:i])
+		}
+
+		pkts, err := rtcp.Unmarshal(b[:i])
+		if err != nil {
+			// Skip what can't be parsed, the next packet may be fine
+			continue
+		}
+		for _, pkt := range pkts {
+			handlers.dispatch(pkt)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCPHandlers_Dispatch(t *testing.T) {
+	var got []rtcp.Packet
+	var typed []string
+	h := RTCPHandlers{
+		OnSenderReport: func(*rtcp.SenderReport) { typed = append(typed, "sr") },
+		OnPLI:          func(*rtcp.PictureLossIndication) { typed = append(typed, "pli") },
+		OnREMB:         func(*rtcp.ReceiverEstimatedMaximumBitrate) { typed = append(typed, "remb") },
+		OnPacket:       func(p rtcp.Packet) { got = append(got, p) },
+	}
+
+	pkts := []rtcp.Packet{
+		&rtcp.SenderReport{},
+		&rtcp.PictureLossIndication{},
+		&rtcp.ReceiverEstimatedMaximumBitrate{},
+		&rtcp.TransportLayerNack{}, // no handler set
+		&rtcp.Goodbye{},
+	}
+	for _, p := range pkts {
+		h.dispatch(p)
+	}
+
+	assert.Equal(t, pkts, got)
+	assert.Equal(t, []string{"sr", "pli", "remb"}, typed)
+
+	// An empty set of handlers ignores everything
+	RTCPHandlers{}.dispatch(&rtcp.SenderReport{})
+}
+
+func TestRTCPReadLoop(t *testing.T) {
+	raw, err := rtcp.Marshal([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 1}})
+	assert.NoError(t, err)
+
+	packets := make(chan []byte, 1)
+	read := func(b []byte) (int, error) {
+		p, ok := <-packets
+		if !ok {
+			return 0, io.EOF
+		}
+		return copy(b, p), nil
+	}
+
+	// The handlers and the buffer share the one loop reading the stream
+	l := &rtcpReadLoop{}
+	assert.Nil(t, l.getBuffer())
+	plis := make(chan uint32, 1)
+	l.setHandlers(RTCPHandlers{
+		OnPLI: func(p *rtcp.PictureLossIndication) { plis <- p.MediaSSRC },
+	}, read)
+	buffer := l.readBuffer(read)
+	assert.Equal(t, buffer, l.getBuffer())
+
+	packets <- raw
+	assert.Equal(t, uint32(1), <-plis)
+	b := make([]byte, 100)
+	n, err := buffer.Read(b)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, b[:n])
+
+	// A failed read of the stream closes the buffer
+	close(packets)
+	_, err = buffer.Read(b)
+	assert.Error(t, err)
+
+	// Also the one of a loop that already ended
+	ended := &rtcpReadLoop{}
+	ended.setHandlers(RTCPHandlers{}, func([]byte) (int, error) { return 0, io.EOF })
+	for {
+		ended.mu.Lock()
+		done := ended.ended
+		ended.mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	_, err = ended.readBuffer(read).Read(b)
+	assert.Error(t, err)
+}
@@ -9,11 +9,18 @@ const (
 	// RTP and RTCP candidates. If the remote-endpoint is capable of
 	// multiplexing RTCP, multiplex RTCP on the RTP candidates. If it is not,
 	// use both the RTP and RTCP candidates separately.
+	//
+	// Pion doesn't allocate a separate RTCP transport: remote descriptions
+	// without rtcp-mux are accepted, but only the RTCP the remote sends on the
+	// RTP transport is received.
 	RTCPMuxPolicyNegotiate RTCPMuxPolicy = iota + 1
 
 	// RTCPMuxPolicyRequire indicates to gather ICE candidates only for
 	// RTP and multiplex RTCP on the RTP candidates. If the remote endpoint is
 	// not capable of rtcp-mux, session negotiation will fail.
+	//
+	// It is the default, but only rejects remote descriptions without
+	// rtcp-mux when set in the Configuration.
 	RTCPMuxPolicyRequire
 )
 
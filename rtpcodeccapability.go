@@ -0,0 +1,10 @@
+package webrtc
+
+// RTPCodecCapability provides information about codec capabilities.
+type RTPCodecCapability struct {
+	MimeType     string
+	ClockRate    uint32
+	Channels     uint16
+	SDPFmtpLine  string
+	RTCPFeedback []RTCPFeedback
+}
@@ -0,0 +1,17 @@
+package webrtc
+
+// RTPCodecParameters is a codec negotiated for a stream, the RTPCodecCapability
+// the remote described for a payload type in its session description.
+// https://w3c.github.io/webrtc-pc/#dom-rtcrtpcodecparameters
+type RTPCodecParameters struct {
+	RTPCodecCapability
+	PayloadType uint8
+}
+
+// RTPHeaderExtensionParameter is a RFC5285 RTP header extension negotiated
+// for a stream, and the ID it is sent with.
+// https://w3c.github.io/webrtc-pc/#dom-rtcrtpheaderextensionparameters
+type RTPHeaderExtensionParameter struct {
+	URI string
+	ID  int
+}
@@ -3,14 +3,19 @@
 package webrtc
 
 import (
-	"fmt"
+	"context"
 	"io"
 	"sync"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
+	"github.com/pion/transport/packetio"
 )
 
+// trackBufferSize is the maximum amount of RTP buffered for each cloned Track,
+// packets are dropped for the Tracks that don't read fast enough
+const trackBufferSize = 1000 * 1000
+
 // RTPReceiver allows an application to inspect the receipt of a Track
 type RTPReceiver struct {
 	kind      RTPCodecType
@@ -24,6 +29,28 @@ type RTPReceiver struct {
 	rtpReadStream  rtp.ReadStream
 	rtcpReadStream rtcp.ReadStream
 
+	// unread holds packets read from rtpReadStream before the Tracks, the
+	// next reads return them first
+	unread [][]byte
+
+	// Once a Track is cloned every Track reads the RTP packets from its own buffer
+	trackBuffers []*packetio.Buffer
+	clones       []*Track
+
+	// Once OnRTCP or ReadContext is called the RTCP is read in the
+	// background, Read and ReadContext read it from a buffer
+	rtcpReadLoop rtcpReadLoop
+
+	payloadTransform PayloadTransform
+
+	onDTMFToneHdlr func(DTMFTone)
+	dtmfDetector   dtmfDetector
+	dtmfTones      dtmfToneQueue
+
+	// mediaEngine holds the codecs, the ones of the PeerConnection that
+	// created the RTPReceiver
+	mediaEngine *MediaEngine
+
 	// A reference to the associated api object
 	api *API
 }
@@ -31,15 +58,16 @@ type RTPReceiver struct {
 // NewRTPReceiver constructs a new RTPReceiver
 func (api *API) NewRTPReceiver(kind RTPCodecType, transport Transport) (*RTPReceiver, error) {
 	if transport == nil {
-		return nil, fmt.Errorf("DTLSTransport must not be nil")
+		return nil, ErrNilTransport
 	}
 
 	return &RTPReceiver{
-		kind:      kind,
-		transport: transport,
-		api:       api,
-		closed:    make(chan interface{}),
-		received:  make(chan interface{}),
+		kind:        kind,
+		transport:   transport,
+		mediaEngine: api.mediaEngine,
+		api:         api,
+		closed:      make(chan interface{}),
+		received:    make(chan interface{}),
 	}, nil
 }
 
@@ -58,17 +86,36 @@ func (r *RTPReceiver) Track() *Track {
 	return r.track
 }
 
+// SetPayloadTransform sets a PayloadTransform applied to the payload of every
+// packet read from the Tracks of the RTPReceiver, nil reads the payloads
+// unchanged. A Read of a packet the transform fails on returns its error, the
+// next Read continues with the following packet.
+func (r *RTPReceiver) SetPayloadTransform(t PayloadTransform) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloadTransform = t
+}
+
 // Receive initialize the track and starts all the transports
 func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	select {
 	case <-r.received:
-		return fmt.Errorf("Receive has already been called")
+		return ErrReceiveAlreadyCalled
 	default:
 	}
 	defer close(r.received)
 
+	// An unknown codec fails before any stream is opened
+	var codec *RTPCodec
+	if payloadType := parameters.Encodings.PayloadType; payloadType != 0 {
+		var err error
+		if codec, err = r.mediaEngine.getCodec(payloadType); err != nil {
+			return err
+		}
+	}
+
 	rtpSession, err := r.transport.RTPSession()
 	if err != nil {
 		return err
@@ -101,6 +148,14 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 		receiver: r,
 	}
 
+	// A PeerConnection learns the payload type from the first packet, users
+	// of the ORTC API signal it
+	if codec != nil {
+		r.track.payloadType = parameters.Encodings.PayloadType
+		r.track.kind = codec.Type
+		r.track.codec = codec
+	}
+
 	return nil
 }
 
@@ -108,21 +163,149 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 func (r *RTPReceiver) Read(b []byte) (n int, err error) {
 	select {
 	case <-r.received:
-		return r.rtcpReadStream.Read(b)
+		if buffer := r.rtcpReadLoop.getBuffer(); buffer != nil {
+			return buffer.Read(b)
+		}
+		return r.readRTCPStream(b)
+	case <-r.closed:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+// readRTCP reads the incoming RTCP from the stream, once Receive was called
+func (r *RTPReceiver) readRTCP(b []byte) (n int, err error) {
+	select {
+	case <-r.received:
+		return r.readRTCPStream(b)
+	case <-r.closed:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+// readRTCPStream reads the incoming RTCP and ends the Tracks when it is the
+// BYE of their SSRC
+func (r *RTPReceiver) readRTCPStream(b []byte) (int, error) {
+	n, err := r.rtcpReadStream.Read(b)
+	if err != nil {
+		return n, err
+	}
+
+	pkts, unmarshalErr := rtcp.Unmarshal(b[:n])
+	if unmarshalErr != nil {
+		return n, nil
+	}
+	for _, pkt := range pkts {
+		bye, ok := pkt.(*rtcp.Goodbye)
+		if !ok {
+			continue
+		}
+		for _, ssrc := range bye.Sources {
+			if ssrc == r.track.SSRC() {
+				r.endTracks()
+			}
+		}
+	}
+	return n, nil
+}
+
+// endTracks ends the Track of the RTPReceiver and its clones
+func (r *RTPReceiver) endTracks() {
+	r.mu.RLock()
+	tracks := append([]*Track{r.track}, r.clones...)
+	r.mu.RUnlock()
+
+	for _, t := range tracks {
+		if t != nil {
+			t.end()
+		}
+	}
+}
+
+// ReadContext reads incoming RTCP for this RTPReceiver like Read, until ctx
+// is done. It must not be called concurrently with Read or ReadContext.
+func (r *RTPReceiver) ReadContext(ctx context.Context, b []byte) (n int, err error) {
+	select {
+	case <-r.received:
+		return readContext(ctx, r.rtcpReadLoop.readBuffer(r.readRTCP), b)
 	case <-r.closed:
 		return 0, io.ErrClosedPipe
+	case <-ctx.Done():
+		return 0, ctx.Err()
 	}
 }
 
 // ReadRTCP is a convenience method that wraps Read and unmarshals for you
 func (r *RTPReceiver) ReadRTCP() ([]rtcp.Packet, error) {
-	b := make([]byte, receiveMTU)
-	i, err := r.Read(b)
+	b, err := readPooled(r.Read)
 	if err != nil {
 		return nil, err
 	}
 
-	return rtcp.Unmarshal(b[:i])
+	return rtcp.Unmarshal(b)
+}
+
+// OnRTCP reads the incoming RTCP in the background and dispatches every
+// packet to handlers, until the RTPReceiver is stopped. Calling it again replaces
+// the handlers. It must not be combined with Read or ReadRTCP, ReadContext
+// gets the packets dispatched as well.
+func (r *RTPReceiver) OnRTCP(handlers RTCPHandlers) {
+	r.rtcpReadLoop.setHandlers(handlers, r.readRTCP)
+}
+
+// OnDTMFTone sets an event handler which is called when a DTMF tone, sent as
+// RFC 4733 telephone-events, starts and when it ends. The packets arrive as
+// the Tracks of the RTPReceiver are read, so a Track has to be read
+// continuously. A telephone-event codec has to be negotiated, see
+// NewRTPTelephoneEventCodec. The handler is called from one goroutine at a
+// time, with the starts and ends in the order they arrived.
+func (r *RTPReceiver) OnDTMFTone(f func(DTMFTone)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onDTMFToneHdlr = f
+}
+
+// detectDTMF reports the DTMF tones of a telephone-event packet, it is
+// called once for every incoming packet
+func (r *RTPReceiver) detectDTMF(b []byte) {
+	r.mu.RLock()
+	hdlr := r.onDTMFToneHdlr
+	r.mu.RUnlock()
+	if hdlr == nil || r.kind != RTPCodecTypeAudio {
+		return
+	}
+
+	p := &rtp.Packet{}
+	if err := p.Unmarshal(b); err != nil {
+		return
+	}
+	for _, codec := range r.mediaEngine.GetCodecsByName(TelephoneEvent) {
+		if codec.PayloadType != p.PayloadType {
+			continue
+		}
+		if tone, ok := r.dtmfDetector.detect(p, codec.ClockRate); ok {
+			r.dtmfTones.push(tone, hdlr)
+		}
+		return
+	}
+}
+
+// writeRTCP sends pkts about the incoming RTP to the remote
+func (r *RTPReceiver) writeRTCP(pkts []rtcp.Packet) error {
+	raw, err := rtcp.Marshal(pkts)
+	if err != nil {
+		return err
+	}
+
+	rtcpSession, err := r.Transport().RTCPSession()
+	if err != nil {
+		return err
+	}
+	writeStream, err := rtcpSession.OpenWriteStream()
+	if err != nil {
+		return err
+	}
+	_, err = writeStream.Write(raw)
+	return err
 }
 
 func (r *RTPReceiver) haveReceived() bool {
@@ -161,11 +344,157 @@ func (r *RTPReceiver) Stop() error {
 	}
 
 	close(r.closed)
+	if r.track != nil {
+		for _, t := range append([]*Track{r.track}, r.clones...) {
+			t.end()
+		}
+	}
 	return nil
 }
 
 // readRTP should only be called by a track, this only exists so we can keep state in one place
 func (r *RTPReceiver) readRTP(b []byte) (n int, err error) {
 	<-r.received
+	if n, err = r.readStream(b); err != nil {
+		return n, err
+	}
+	r.detectDTMF(b[:n])
+	return r.transformRTP(b, n)
+}
+
+// readStream reads the next incoming RTP packet, the unread ones first
+func (r *RTPReceiver) readStream(b []byte) (int, error) {
+	r.mu.Lock()
+	if len(r.unread) != 0 {
+		raw := r.unread[0]
+		r.unread = r.unread[1:]
+		r.mu.Unlock()
+
+		if len(b) < len(raw) {
+			return 0, io.ErrShortBuffer
+		}
+		return copy(b, raw), nil
+	}
+	r.mu.Unlock()
+
 	return r.rtpReadStream.Read(b)
 }
+
+// unreadRTP makes the next read return raw, a packet of the stream read
+// before the Tracks
+func (r *RTPReceiver) unreadRTP(raw []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unread = append([][]byte{raw}, r.unread...)
+}
+
+// peekPayloadType blocks until a packet arrives and returns its payload type,
+// the packet is still read by the Track
+func (r *RTPReceiver) peekPayloadType() (uint8, error) {
+	<-r.received
+
+	b := make([]byte, receiveMTU)
+	n, err := r.readStream(b)
+	if err != nil {
+		return 0, err
+	}
+	header := &rtp.Header{}
+	if err := header.Unmarshal(b[:n]); err != nil {
+		return 0, err
+	}
+	r.unreadRTP(b[:n])
+	return header.PayloadType, nil
+}
+
+// transformRTP applies the PayloadTransform to a packet a Track read
+func (r *RTPReceiver) transformRTP(b []byte, n int) (int, error) {
+	r.mu.RLock()
+	payloadTransform := r.payloadTransform
+	r.mu.RUnlock()
+
+	if payloadTransform == nil {
+		return n, nil
+	}
+	return transformRTP(payloadTransform, b, n)
+}
+
+// bufferTrack makes t read from its own buffer of the incoming RTP packets,
+// if it doesn't already, and returns the buffer
+func (r *RTPReceiver) bufferTrack(t *Track) (*packetio.Buffer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.closed:
+		return nil, io.ErrClosedPipe
+	default:
+	}
+
+	t.mu.RLock()
+	buffer := t.buffer
+	t.mu.RUnlock()
+	if buffer != nil {
+		return buffer, nil
+	}
+
+	if len(r.trackBuffers) == 0 {
+		go r.fanOutRTP()
+	}
+	buffer = r.newTrackBuffer()
+	t.setBuffer(buffer)
+	return buffer, nil
+}
+
+// addTrackBuffer gives t its own buffer of the incoming RTP packets. The first
+// call also moves the Track returned by Track() to a buffer and starts
+// copying the packets to all of them.
+func (r *RTPReceiver) addTrackBuffer(t *Track) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	if len(r.trackBuffers) == 0 {
+		r.track.setBuffer(r.newTrackBuffer())
+		go r.fanOutRTP()
+	}
+	t.setBuffer(r.newTrackBuffer())
+	r.clones = append(r.clones, t)
+	return nil
+}
+
+func (r *RTPReceiver) newTrackBuffer() *packetio.Buffer {
+	buffer := packetio.NewBuffer()
+	buffer.SetLimitSize(trackBufferSize)
+	r.trackBuffers = append(r.trackBuffers, buffer)
+	return buffer
+}
+
+// fanOutRTP copies the incoming RTP packets to the buffer of every Track
+func (r *RTPReceiver) fanOutRTP() {
+	b := make([]byte, receiveMTU)
+	for {
+		i, err := r.readStream(b)
+
+		r.mu.RLock()
+		buffers := r.trackBuffers
+		r.mu.RUnlock()
+
+		if err != nil {
+			for _, buffer := range buffers {
+				_ = buffer.Close()
+			}
+			return
+		}
+
+		r.detectDTMF(b[:i])
+		for _, buffer := range buffers {
+			// A full buffer only drops the packet for its Track
+			_, _ = buffer.Write(b[:i])
+		}
+	}
+}
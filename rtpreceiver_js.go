@@ -0,0 +1,33 @@
+// +build js,wasm
+
+package webrtc
+
+import (
+	"syscall/js"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+// RTPReceiver allows an application to inspect the receipt of a Track
+type RTPReceiver struct {
+	// Pointer to the underlying JavaScript RTCRtpReceiver object.
+	underlying js.Value
+}
+
+// JSValue returns the underlying RTCRtpReceiver
+func (r *RTPReceiver) JSValue() js.Value {
+	return r.underlying
+}
+
+// Read reads incoming RTCP for this RTPReceiver. Browsers don't expose RTCP,
+// it returns a NotSupportedError.
+func (r *RTPReceiver) Read(b []byte) (n int, err error) {
+	return 0, &rtcerr.NotSupportedError{Err: ErrRTPNotAccessible}
+}
+
+// ReadRTCP is a convenience method that wraps Read and unmarshal for you.
+// Browsers don't expose RTCP, it returns a NotSupportedError.
+func (r *RTPReceiver) ReadRTCP() ([]rtcp.Packet, error) {
+	return nil, &rtcerr.NotSupportedError{Err: ErrRTPNotAccessible}
+}
@@ -1,14 +1,19 @@
+//go:build !js
 // +build !js
 
 package webrtc
 
 import (
-	"fmt"
+	"context"
 	"io"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/pion/logging"
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
 // RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
@@ -18,6 +23,49 @@ type RTPSender struct {
 
 	transport Transport
 
+	// encodings declared with AddTransceiver, at most one since only the
+	// stream of the Track is sent
+	encodings []RTPEncodingParameters
+
+	payloadTransform PayloadTransform
+	pacer            *Pacer
+	dtmf             *DTMFSender
+
+	// remoteCodecs are the codecs of the media section in the remote
+	// description
+	remoteCodecs []RTPCodecParameters
+
+	// maxBitrate is the bandwidth limit of the remote, limiter enforces it
+	maxBitrate uint64
+	limiter    *Pacer
+
+	// rateLimit is the quota of the sender, rateLimiter enforces it
+	rateLimit   uint64
+	rateLimiter *Pacer
+
+	// mid and rid are sent in the header extensions the remote negotiated,
+	// an ID of 0 sends none
+	mid, rid                       string
+	midExtensionID, ridExtensionID uint8
+
+	// orientation and playoutDelay are sent in the last packet of every frame
+	// once set
+	orientation             *VideoOrientation
+	orientationExtensionID  uint8
+	playoutDelay            *PlayoutDelay
+	playoutDelayExtensionID uint8
+
+	// captureTime stamps the first packet of a frame every
+	// absCaptureTimeInterval
+	captureTime            captureTimeStamper
+	captureTimeExtensionID uint8
+
+	// padding makes room in the stream for the padding-only packets of a
+	// Prober and of the minBitrate
+	padding           paddingSequencer
+	minBitrate        uint64
+	minBitrateRunning bool
+
 	// TODO(sgotti) remove this when in future we'll avoid replacing
 	// a transceiver sender since we can just check the
 	// transceiver negotiation status
@@ -25,22 +73,35 @@ type RTPSender struct {
 
 	// A reference to the associated api object
 	api *API
+	log logging.LeveledLogger
 
 	mu                     sync.RWMutex
 	sendCalled, stopCalled chan interface{}
+	onStopHdlr             func()
+
+	// pacedQueue holds the packets waiting for the Pacer and the rate limits,
+	// they are sent by a goroutine started with the first one
+	pacedQueue     chan *pacedPacket
+	pacedQueueOnce sync.Once
+
+	// Once OnRTCP or ReadContext is called the RTCP is read in the
+	// background, Read and ReadContext read it from a buffer
+	rtcpReadLoop rtcpReadLoop
 }
 
 // NewRTPSender constructs a new RTPSender
 func (api *API) NewRTPSender(track *Track, transport Transport) (*RTPSender, error) {
 	if transport == nil {
-		return nil, fmt.Errorf("DTLSTransport must not be nil")
+		return nil, ErrNilTransport
 	}
 
 	r := &RTPSender{
 		transport:  transport,
 		api:        api,
+		log:        api.settingEngine.LoggerFactory.NewLogger("ortc"),
 		sendCalled: make(chan interface{}),
 		stopCalled: make(chan interface{}),
+		pacedQueue: make(chan *pacedPacket, pacedQueueSize),
 	}
 
 	err := r.setTrack(track)
@@ -63,6 +124,86 @@ func (r *RTPSender) setNegotiated() {
 	r.negotiated = true
 }
 
+func (r *RTPSender) clearNegotiated() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.negotiated = false
+}
+
+func (r *RTPSender) setEncodings(encodings []RTPEncodingParameters) error {
+	// The Track is the only stream sent, layers the remote negotiated would
+	// never arrive
+	if len(encodings) > 1 {
+		return &rtcerr.NotSupportedError{Err: ErrRTPEncodingSimulcast}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.encodings = append([]RTPEncodingParameters{}, encodings...)
+	if len(r.encodings) != 0 && r.encodings[0].SSRC == 0 {
+		r.encodings[0].SSRC = r.track.SSRC()
+	}
+	return nil
+}
+
+// Encodings returns the encoding this RTPSender was created with, if any
+func (r *RTPSender) Encodings() []RTPEncodingParameters {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]RTPEncodingParameters{}, r.encodings...)
+}
+
+// encodingRID returns the RID the stream is sent with, the one of its encoding or
+// else the one of the Track
+func (r *RTPSender) encodingRID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.encodings) != 0 && r.encodings[0].RID != "" {
+		return r.encodings[0].RID
+	}
+	return r.track.RID()
+}
+
+// SetPayloadTransform sets a PayloadTransform applied to the payload of every
+// packet sent, nil sends the payloads unchanged.
+func (r *RTPSender) SetPayloadTransform(t PayloadTransform) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloadTransform = t
+}
+
+// SetPacer sets the Pacer that spaces out the packets sent, nil sends them as
+// soon as they are written. The packets the Pacer delays wait in a queue of
+// the RTPSender, so writing to the Track never blocks on it; they are dropped
+// once the queue is full.
+func (r *RTPSender) SetPacer(p *Pacer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pacer = p
+}
+
+// MaxBitrate returns the bandwidth limit in bits per second the remote set in
+// its session description for the media of the RTPSender, 0 if there is
+// none. The packets sent are delayed to stay under it, in addition to the
+// Pacer, so the encoder should be kept below it.
+func (r *RTPSender) MaxBitrate() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.maxBitrate
+}
+
+func (r *RTPSender) setMaxBitrate(bitrate uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if bitrate == r.maxBitrate {
+		return
+	}
+	r.maxBitrate = bitrate
+	r.limiter = newRateLimiter(bitrate)
+}
+
 // Transport returns the currently-configured *DTLSTransport or nil
 // if one has not yet been configured
 func (r *RTPSender) Transport() Transport {
@@ -71,6 +212,55 @@ func (r *RTPSender) Transport() Transport {
 	return r.transport
 }
 
+// DTMF returns the DTMFSender playing DTMF tones on the stream of an audio
+// RTPSender, it is nil for video
+func (r *RTPSender) DTMF() *DTMFSender {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.track == nil || r.track.Kind() != RTPCodecTypeAudio {
+		return nil
+	}
+	if r.dtmf == nil {
+		r.dtmf = newDTMFSender(r)
+	}
+	return r.dtmf
+}
+
+// telephoneEventCodec returns the Track DTMF tones are sent on and the
+// payload type of the telephone-event codec with its clock rate, it reports
+// false unless the RTPSender is sending and the remote accepts such a codec
+func (r *RTPSender) telephoneEventCodec() (*Track, uint8, bool) {
+	r.mu.RLock()
+	track := r.track
+	remoteCodecs := r.remoteCodecs
+	r.mu.RUnlock()
+
+	select {
+	case <-r.stopCalled:
+		return nil, 0, false
+	default:
+	}
+	if track == nil || !r.hasSent() {
+		return nil, 0, false
+	}
+
+	for _, codec := range remoteCodecs {
+		if strings.EqualFold(codec.MimeType, "audio/"+TelephoneEvent) && codec.ClockRate == track.Codec().ClockRate {
+			return track, codec.PayloadType, true
+		}
+	}
+	return nil, 0, false
+}
+
+// setRemoteCodecs sets the codecs the remote accepts in the media section of
+// the RTPSender
+func (r *RTPSender) setRemoteCodecs(codecs []RTPCodecParameters) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remoteCodecs = codecs
+}
+
 // Track returns the RTCRtpTransceiver track, or nil
 func (r *RTPSender) Track() *Track {
 	r.mu.RLock()
@@ -85,14 +275,14 @@ func (r *RTPSender) ReplaceTrack(newTrack *Track) error {
 
 	select {
 	case <-r.stopCalled:
-		return fmt.Errorf("RTPSender has been stopped")
+		return ErrSenderStopped
 	default:
 	}
 
 	if newTrack == nil {
-		return fmt.Errorf("New track must not be nil")
+		return ErrNilTrack
 	} else if newTrack.Kind() != r.track.Kind() {
-		return fmt.Errorf("New track kind does not match original")
+		return ErrTrackKindMismatch
 	}
 
 	err := checkNegotiationTrigger(r.track, newTrack)
@@ -106,20 +296,21 @@ func (r *RTPSender) ReplaceTrack(newTrack *Track) error {
 
 func (r *RTPSender) setTrack(track *Track) error {
 	if track == nil {
-		return fmt.Errorf("Track must not be nil")
+		return ErrNilTrack
 	}
 
 	track.mu.Lock()
 	defer track.mu.Unlock()
 
 	if track.receiver != nil {
-		return fmt.Errorf("RTPSender can not be constructed with remote track")
+		return ErrSenderWithRemoteTrack
 	}
 	track.totalSenderCount++
 
 	if r.hasSent() {
 		track.activeSenders = append(track.activeSenders, r)
 	}
+	track.updateFanOut()
 
 	r.track = track
 	return nil
@@ -138,6 +329,7 @@ func (r *RTPSender) removeTrack() {
 		}
 	}
 	r.track.activeSenders = filtered
+	r.track.updateFanOut()
 	r.track = nil
 }
 
@@ -147,7 +339,7 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 	defer r.mu.Unlock()
 
 	if r.hasSent() {
-		return fmt.Errorf("Send has already been called")
+		return ErrSendAlreadyCalled
 	}
 
 	rtcpSession, err := r.transport.RTCPSession()
@@ -162,6 +354,7 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 
 	r.track.mu.Lock()
 	r.track.activeSenders = append(r.track.activeSenders, r)
+	r.track.updateFanOut()
 	r.track.mu.Unlock()
 
 	close(r.sendCalled)
@@ -181,6 +374,9 @@ func (r *RTPSender) Stop() error {
 
 	r.removeTrack()
 	close(r.stopCalled)
+	if hdlr := r.onStopHdlr; hdlr != nil {
+		go hdlr()
+	}
 
 	if r.hasSent() {
 		return r.rtcpReadStream.Close()
@@ -189,8 +385,59 @@ func (r *RTPSender) Stop() error {
 	return nil
 }
 
+// OnStop sets a handler called once the RTPSender is stopped: by Stop, by
+// RemoveTrack or when its PeerConnection is closed. It is called right away
+// if the RTPSender already is.
+func (r *RTPSender) OnStop(f func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onStopHdlr = f
+	select {
+	case <-r.stopCalled:
+		if f != nil {
+			go f()
+		}
+	default:
+	}
+}
+
 // Read reads incoming RTCP for this RTPReceiver
 func (r *RTPSender) Read(b []byte) (n int, err error) {
+	select {
+	case <-r.sendCalled:
+		if buffer := r.rtcpReadLoop.getBuffer(); buffer != nil {
+			return buffer.Read(b)
+		}
+		return r.rtcpReadStream.Read(b)
+	case <-r.stopCalled:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+// ReadContext reads incoming RTCP for this RTPSender like Read, until ctx is
+// done. It must not be called concurrently with Read or ReadContext.
+func (r *RTPSender) ReadContext(ctx context.Context, b []byte) (n int, err error) {
+	select {
+	case <-r.sendCalled:
+		return readContext(ctx, r.rtcpReadLoop.readBuffer(r.readRTCP), b)
+	case <-r.stopCalled:
+		return 0, io.ErrClosedPipe
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// OnRTCP reads the incoming RTCP in the background and dispatches every
+// packet to handlers, until the RTPSender is stopped. Calling it again replaces
+// the handlers. It must not be combined with Read or ReadRTCP, ReadContext
+// gets the packets dispatched as well.
+func (r *RTPSender) OnRTCP(handlers RTCPHandlers) {
+	r.rtcpReadLoop.setHandlers(handlers, r.readRTCP)
+}
+
+// readRTCP reads the incoming RTCP from the stream, once Send was called
+func (r *RTPSender) readRTCP(b []byte) (n int, err error) {
 	select {
 	case <-r.sendCalled:
 		return r.rtcpReadStream.Read(b)
@@ -201,13 +448,12 @@ func (r *RTPSender) Read(b []byte) (n int, err error) {
 
 // ReadRTCP is a convenience method that wraps Read and unmarshals for you
 func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, error) {
-	b := make([]byte, receiveMTU)
-	i, err := r.Read(b)
+	b, err := readPooled(r.Read)
 	if err != nil {
 		return nil, err
 	}
 
-	return rtcp.Unmarshal(b[:i])
+	return rtcp.Unmarshal(b)
 }
 
 // SendRTP sends a RTP packet on this RTPSender
@@ -217,9 +463,15 @@ func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, error) {
 // retransmissions to a single RTPSender. in /v3 this will go away, only use this API if you really
 // need it.
 func (r *RTPSender) SendRTP(header *rtp.Header, payload []byte) (int, error) {
+	return r.sendRTP(header, payload, true)
+}
+
+// sendRTP sends a packet, shifting its sequence number by the packets the
+// RTPSender sent on its own unless it is one of them
+func (r *RTPSender) sendRTP(header *rtp.Header, payload []byte, shift bool) (int, error) {
 	select {
 	case <-r.stopCalled:
-		return 0, fmt.Errorf("RTPSender has been stopped")
+		return 0, ErrSenderStopped
 	case <-r.sendCalled:
 		rtpSession, err := r.transport.RTPSession()
 		if err != nil {
@@ -231,8 +483,172 @@ func (r *RTPSender) SendRTP(header *rtp.Header, payload []byte) (int, error) {
 			return 0, err
 		}
 
-		return writeStream.WriteRTP(header, payload)
+		r.mu.RLock()
+		payloadTransform := r.payloadTransform
+		pacer := r.pacer
+		limiters := r.rateLimiters()
+		header = r.withHeaderExtensions(header)
+		r.mu.RUnlock()
+		if shift {
+			header = r.padding.shift(header)
+		}
+		if payloadTransform != nil {
+			if payload, err = payloadTransform.Transform(header, payload); err != nil {
+				return 0, err
+			}
+		}
+
+		if pacer == nil && !hasLimiter(limiters) && len(r.pacedQueue) == 0 {
+			n, err := writeStream.WriteRTP(header, payload)
+			if err == nil {
+				r.padding.addSent(n)
+			}
+			return n, err
+		}
+
+		// The packet is queued, the caller may reuse header and payload once
+		// SendRTP returns
+		p, err := newPacedPacket(writeStream, header, payload, pacer, limiters)
+		if err != nil {
+			return 0, err
+		}
+		if !r.queuePaced(p) {
+			// A throttled RTPSender must not hold up the other senders of
+			// the Track
+			r.log.Tracef("Dropped a packet of SSRC %d, the paced queue is full", header.SSRC)
+			return 0, nil
+		}
+		return p.size, nil
+	}
+}
+
+// pacedPacket is a packet in the queue of a paced or rate limited RTPSender
+type pacedPacket struct {
+	writeStream rtp.WriteStream
+	header      *rtp.Header
+	payload     []byte
+	size        int
+	pacer       *Pacer
+	limiters    []*Pacer
+}
+
+// newPacedPacket copies header and payload, which are shared by the senders
+// of a Track
+func newPacedPacket(writeStream rtp.WriteStream, header *rtp.Header, payload []byte, pacer *Pacer, limiters []*Pacer) (*pacedPacket, error) {
+	raw, err := header.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	h := &rtp.Header{}
+	if err = h.Unmarshal(raw); err != nil {
+		return nil, err
+	}
+
+	return &pacedPacket{
+		writeStream: writeStream,
+		header:      h,
+		payload:     append([]byte{}, payload...),
+		size:        len(raw) + len(payload),
+		pacer:       pacer,
+		limiters:    limiters,
+	}, nil
+}
+
+// queuePaced queues p to be sent by sendPaced, it returns false when the
+// queue is full
+func (r *RTPSender) queuePaced(p *pacedPacket) bool {
+	r.pacedQueueOnce.Do(func() {
+		go r.sendPaced()
+	})
+	select {
+	case r.pacedQueue <- p:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendPaced sends the queued packets as the Pacer and the rate limits allow,
+// until the RTPSender is stopped
+func (r *RTPSender) sendPaced() {
+	for {
+		var p *pacedPacket
+		select {
+		case <-r.stopCalled:
+			return
+		case p = <-r.pacedQueue:
+		}
+
+		if p.pacer != nil && !p.pacer.wait(p.size, r.stopCalled) {
+			return
+		}
+		if !waitRateLimits(p.size, r.stopCalled, p.limiters...) {
+			return
+		}
+		if n, err := p.writeStream.WriteRTP(p.header, p.payload); err == nil {
+			r.padding.addSent(n)
+		}
+	}
+}
+
+// setHeaderExtensions makes the packets carry mid and rid in the header
+// extensions with the IDs the remote negotiated, 0 for none
+func (r *RTPSender) setHeaderExtensions(mid, rid string, midExtensionID, ridExtensionID uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mid, r.rid = mid, rid
+	r.midExtensionID, r.ridExtensionID = midExtensionID, ridExtensionID
+}
+
+// withHeaderExtensions returns a copy of header with the MID, RID, video
+// orientation, playout delay and capture time extensions, the header itself
+// is shared by the senders of a Track. r.mu must be held.
+func (r *RTPSender) withHeaderExtensions(header *rtp.Header) *rtp.Header {
+	midID, ridID := r.midExtensionID, r.ridExtensionID
+	orientationID, playoutDelayID := r.orientationExtensionID, r.playoutDelayExtensionID
+	captureTimeID := r.captureTimeExtensionID
+	if r.mid == "" {
+		midID = 0
+	}
+	if r.rid == "" {
+		ridID = 0
+	}
+	if r.orientation == nil || !header.Marker {
+		orientationID = 0
+	}
+	if r.playoutDelay == nil || !header.Marker {
+		playoutDelayID = 0
+	}
+	var captureTime AbsoluteCaptureTime
+	if captureTimeID != 0 {
+		var ok bool
+		if captureTime, ok = r.captureTime.stamp(header, time.Now()); !ok {
+			captureTimeID = 0
+		}
+	}
+	if midID == 0 && ridID == 0 && orientationID == 0 && playoutDelayID == 0 && captureTimeID == 0 {
+		return header
+	}
+
+	h := *header
+	h.Extensions = append([]rtp.Extension{}, header.Extensions...)
+	if midID != 0 {
+		_ = h.SetExtension(midID, []byte(r.mid))
+	}
+	if ridID != 0 {
+		_ = h.SetExtension(ridID, []byte(r.rid))
+	}
+	if orientationID != 0 {
+		_ = h.SetExtension(orientationID, []byte{r.orientation.marshal()})
+	}
+	if playoutDelayID != 0 {
+		_ = h.SetExtension(playoutDelayID, r.playoutDelay.marshal())
+	}
+	if captureTimeID != 0 {
+		_ = h.SetExtension(captureTimeID, captureTime.marshal())
 	}
+	return &h
 }
 
 // hasSent tells if data has been ever sent for this instance
@@ -250,7 +666,7 @@ func checkNegotiationTrigger(track, newTrack *Track) error {
 	newCodec := newTrack.Codec()
 
 	if codec.Type == RTPCodecTypeAudio && codec.Channels != newCodec.Channels {
-		return fmt.Errorf("New track has different number of channels from original")
+		return ErrTrackChannelsMismatch
 	}
 
 	// TODO: check more triggers
@@ -0,0 +1,91 @@
+// +build js,wasm
+
+package webrtc
+
+import (
+	"syscall/js"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+// RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
+type RTPSender struct {
+	// Pointer to the underlying JavaScript RTCRtpSender object.
+	underlying js.Value
+}
+
+// JSValue returns the underlying RTCRtpSender
+func (r *RTPSender) JSValue() js.Value {
+	return r.underlying
+}
+
+// SendRTP sends a RTP packet on this RTPSender. Browsers only send the media
+// of their tracks, it returns a NotSupportedError.
+func (r *RTPSender) SendRTP(header *rtp.Header, payload []byte) (int, error) {
+	return 0, &rtcerr.NotSupportedError{Err: ErrRTPNotAccessible}
+}
+
+// Read reads incoming RTCP for this RTPSender. Browsers don't expose RTCP,
+// it returns a NotSupportedError.
+func (r *RTPSender) Read(b []byte) (n int, err error) {
+	return 0, &rtcerr.NotSupportedError{Err: ErrRTPNotAccessible}
+}
+
+// ReadRTCP is a convenience method that wraps Read and unmarshals for you.
+// Browsers don't expose RTCP, it returns a NotSupportedError.
+func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, error) {
+	return nil, &rtcerr.NotSupportedError{Err: ErrRTPNotAccessible}
+}
+
+// MaxBitrate returns the bandwidth limit of the encodings, in bits per
+// second, 0 if there is none
+func (r *RTPSender) MaxBitrate() uint64 {
+	encodings := r.underlying.Call("getParameters").Get("encodings")
+	if jsValueIsUndefined(encodings) || encodings.Length() == 0 {
+		return 0
+	}
+	maxBitrate := encodings.Index(0).Get("maxBitrate")
+	if jsValueIsUndefined(maxBitrate) || jsValueIsNull(maxBitrate) {
+		return 0
+	}
+	return uint64(maxBitrate.Float())
+}
+
+// setMaxBitrate limits the bandwidth of the encodings to bitrate bits per
+// second, 0 removes the limit
+func (r *RTPSender) setMaxBitrate(bitrate uint64) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = recoveryToError(e)
+		}
+	}()
+
+	parameters := r.underlying.Call("getParameters")
+	encodings := parameters.Get("encodings")
+	if jsValueIsUndefined(encodings) {
+		return nil
+	}
+	for i := 0; i < encodings.Length(); i++ {
+		if bitrate == 0 {
+			encodings.Index(i).Delete("maxBitrate")
+		} else {
+			encodings.Index(i).Set("maxBitrate", float64(bitrate))
+		}
+	}
+	_, err = awaitPromise(r.underlying.Call("setParameters", parameters))
+	return err
+}
+
+// Stop irreversibly stops the RTPSender
+func (r *RTPSender) Stop() (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = recoveryToError(e)
+		}
+	}()
+
+	_, err = awaitPromise(r.underlying.Call("replaceTrack", js.Null()))
+	return err
+}
@@ -14,8 +14,15 @@ type RTPTransceiver struct {
 	receiver  atomic.Value // *RTPReceiver
 	direction atomic.Value // RTPTransceiverDirection
 
-	stopped bool
+	maxBitrate       atomic.Value // uint64
+	remoteMaxBitrate atomic.Value // uint64
+
+	stopped atomicBool
 	kind    RTPCodecType
+
+	// createdByRemote is set for transceivers created by applying a remote offer,
+	// rolling back that offer removes them again.
+	createdByRemote bool
 }
 
 // Sender returns the RTPTransceiver's RTPSender if it has one
@@ -28,6 +35,9 @@ func (t *RTPTransceiver) Sender() *RTPSender {
 }
 
 func (t *RTPTransceiver) setSender(s *RTPSender) {
+	if s != nil {
+		s.setMaxBitrate(t.loadBitrate(&t.remoteMaxBitrate))
+	}
 	t.sender.Store(s)
 }
 
@@ -49,6 +59,12 @@ func (t *RTPTransceiver) setMid(mid string) error {
 	return nil
 }
 
+// clearMid disassociates the RTPTransceiver from its media section, this only
+// happens when the description that associated it is rolled back.
+func (t *RTPTransceiver) clearMid() {
+	t.mid.Store("")
+}
+
 // Mid gets the Transceiver's mid value. When not already set, this value will be set in CreateOffer or CreateAnswer.
 func (t *RTPTransceiver) Mid() string {
 	if v := t.mid.Load(); v != nil {
@@ -67,8 +83,41 @@ func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
 	return t.direction.Load().(RTPTransceiverDirection)
 }
 
-// Stop irreversibly stops the RTPTransceiver
+// SetMaxBitrate limits the bandwidth the remote may send the media of the
+// RTPTransceiver with, in bits per second. The limit is advertised in the
+// media section from the next offer or answer on, 0 removes it.
+func (t *RTPTransceiver) SetMaxBitrate(bitrate uint64) {
+	t.maxBitrate.Store(bitrate)
+}
+
+// MaxBitrate returns the limit set with SetMaxBitrate
+func (t *RTPTransceiver) MaxBitrate() uint64 {
+	return t.loadBitrate(&t.maxBitrate)
+}
+
+// setRemoteMaxBitrate applies the limit of the remote description to the
+// RTPSender, and to the ones set later
+func (t *RTPTransceiver) setRemoteMaxBitrate(bitrate uint64) {
+	t.remoteMaxBitrate.Store(bitrate)
+	if s := t.Sender(); s != nil {
+		s.setMaxBitrate(bitrate)
+	}
+}
+
+func (t *RTPTransceiver) loadBitrate(v *atomic.Value) uint64 {
+	if bitrate := v.Load(); bitrate != nil {
+		return bitrate.(uint64)
+	}
+	return 0
+}
+
+// Stop irreversibly stops the RTPTransceiver, its media section is rejected
+// the next time the PeerConnection is negotiated.
 func (t *RTPTransceiver) Stop() error {
+	if t.stopped.get() {
+		return nil
+	}
+
 	if t.Sender() != nil {
 		if err := t.Sender().Stop(); err != nil {
 			return err
@@ -80,6 +129,7 @@ func (t *RTPTransceiver) Stop() error {
 		}
 	}
 
+	t.stopped.set(true)
 	t.setDirection(RTPTransceiverDirectionInactive)
 	return nil
 }
@@ -142,7 +192,7 @@ func satisfyTypeAndDirection(remoteKind RTPCodecType, remoteDirection RTPTransce
 	for _, possibleDirection := range getPreferredDirections() {
 		for i := range localTransceivers {
 			t := localTransceivers[i]
-			if t.Mid() == "" && t.kind == remoteKind && possibleDirection == t.Direction() {
+			if t.Mid() == "" && !t.stopped.get() && t.kind == remoteKind && possibleDirection == t.Direction() {
 				return t, append(localTransceivers[:i], localTransceivers[i+1:]...)
 			}
 		}
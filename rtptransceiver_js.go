@@ -0,0 +1,66 @@
+// +build js,wasm
+
+package webrtc
+
+import (
+	"syscall/js"
+)
+
+// RTPTransceiver represents a combination of an RTPSender and an RTPReceiver that share a common mid.
+type RTPTransceiver struct {
+	// Pointer to the underlying JavaScript RTCRtpTransceiver object.
+	underlying js.Value
+}
+
+// JSValue returns the underlying RTCRtpTransceiver
+func (t *RTPTransceiver) JSValue() js.Value {
+	return t.underlying
+}
+
+// Sender returns the RTPTransceiver's RTPSender if it has one
+func (t *RTPTransceiver) Sender() *RTPSender {
+	return &RTPSender{underlying: t.underlying.Get("sender")}
+}
+
+// Receiver returns the RTPTransceiver's RTPReceiver if it has one
+func (t *RTPTransceiver) Receiver() *RTPReceiver {
+	return &RTPReceiver{underlying: t.underlying.Get("receiver")}
+}
+
+// Mid gets the Transceiver's mid value. When not already set, this value will be set in CreateOffer or CreateAnswer.
+func (t *RTPTransceiver) Mid() string {
+	return valueToStringOrZero(t.underlying.Get("mid"))
+}
+
+// Kind returns RTPTransceiver's kind.
+func (t *RTPTransceiver) Kind() RTPCodecType {
+	return NewRTPCodecType(t.underlying.Get("receiver").Get("track").Get("kind").String())
+}
+
+// Direction returns the RTPTransceiver's current direction
+func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
+	return NewRTPTransceiverDirection(t.underlying.Get("direction").String())
+}
+
+// SetMaxBitrate limits the bandwidth the sender uses to bitrate bits per
+// second, 0 removes the limit
+func (t *RTPTransceiver) SetMaxBitrate(bitrate uint64) {
+	_ = t.Sender().setMaxBitrate(bitrate)
+}
+
+// MaxBitrate returns the bandwidth limit of the sender, 0 if there is none
+func (t *RTPTransceiver) MaxBitrate() uint64 {
+	return t.Sender().MaxBitrate()
+}
+
+// Stop irreversibly stops the RTPTransceiver
+func (t *RTPTransceiver) Stop() (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = recoveryToError(e)
+		}
+	}()
+
+	t.underlying.Call("stop")
+	return nil
+}
@@ -60,3 +60,41 @@ func (t RTPTransceiverDirection) String() string {
 		return ErrUnknownType.Error()
 	}
 }
+
+// reverse returns the direction as seen from the other end of the connection
+func (t RTPTransceiverDirection) reverse() RTPTransceiverDirection {
+	switch t {
+	case RTPTransceiverDirectionSendonly:
+		return RTPTransceiverDirectionRecvonly
+	case RTPTransceiverDirectionRecvonly:
+		return RTPTransceiverDirectionSendonly
+	default:
+		return t
+	}
+}
+
+// intersect returns the direction that is allowed by both t and other,
+// this is used to pick the direction of an answer (JSEP 5.3.1)
+func (t RTPTransceiverDirection) intersect(other RTPTransceiverDirection) RTPTransceiverDirection {
+	send := t.hasSend() && other.hasSend()
+	recv := t.hasRecv() && other.hasRecv()
+
+	switch {
+	case send && recv:
+		return RTPTransceiverDirectionSendrecv
+	case send:
+		return RTPTransceiverDirectionSendonly
+	case recv:
+		return RTPTransceiverDirectionRecvonly
+	default:
+		return RTPTransceiverDirectionInactive
+	}
+}
+
+func (t RTPTransceiverDirection) hasSend() bool {
+	return t == RTPTransceiverDirectionSendrecv || t == RTPTransceiverDirectionSendonly
+}
+
+func (t RTPTransceiverDirection) hasRecv() bool {
+	return t == RTPTransceiverDirectionSendrecv || t == RTPTransceiverDirectionRecvonly
+}
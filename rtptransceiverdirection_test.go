@@ -47,3 +47,25 @@ func TestRTPTransceiverDirection_String(t *testing.T) {
 		)
 	}
 }
+
+func TestRTPTransceiverDirection_Intersect(t *testing.T) {
+	testCases := []struct {
+		local, remote     RTPTransceiverDirection
+		expectedDirection RTPTransceiverDirection
+	}{
+		{RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendrecv},
+		{RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionSendonly},
+		{RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly, RTPTransceiverDirectionRecvonly},
+		{RTPTransceiverDirectionSendonly, RTPTransceiverDirectionSendonly, RTPTransceiverDirectionInactive},
+		{RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionRecvonly},
+		{RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionInactive, RTPTransceiverDirectionInactive},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedDirection,
+			testCase.local.intersect(testCase.remote.reverse()),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -2,7 +2,12 @@ package webrtc
 
 // RtpTransceiverInit dictionary is used when calling the WebRTC function addTransceiver() to provide configuration options for the new transceiver.
 type RtpTransceiverInit struct {
-	Direction     RTPTransceiverDirection
+	Direction RTPTransceiverDirection
+
+	// SendEncodings declares the encoding of the Track. Only a single encoding
+	// can be sent, its RID is advertised and sent in the RTP header extension.
+	// Simulcast isn't available, more than one is rejected with
+	// ErrRTPEncodingSimulcast. In the browser they are passed to addTransceiver.
 	SendEncodings []RTPEncodingParameters
 	// Streams       []*Track
 }
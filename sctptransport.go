@@ -15,7 +15,16 @@ import (
 	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
-const sctpMaxChannels = uint16(65535)
+const (
+	sctpMaxChannels = uint16(65535)
+
+	// sctpDefaultMaxMessageSize is assumed for remote peers that don't
+	// advertise max-message-size, RFC 8841 6.1
+	sctpDefaultMaxMessageSize = 65536
+
+	// sctpMaxOutboundMessageSize is the largest message pion/sctp can send
+	sctpMaxOutboundMessageSize = math.MaxUint16
+)
 
 // SCTPTransport provides details about the SCTP transport.
 type SCTPTransport struct {
@@ -63,7 +72,7 @@ func (api *API) NewSCTPTransport(dtls *DTLSTransport) *SCTPTransport {
 		log:           api.settingEngine.LoggerFactory.NewLogger("ortc"),
 	}
 
-	res.updateMessageSize()
+	res.updateMessageSize(0)
 	res.updateMaxChannels()
 
 	return res
@@ -80,7 +89,7 @@ func (r *SCTPTransport) Transport() *DTLSTransport {
 // GetCapabilities returns the SCTPCapabilities of the SCTPTransport.
 func (r *SCTPTransport) GetCapabilities() SCTPCapabilities {
 	return SCTPCapabilities{
-		MaxMessageSize: 0,
+		MaxMessageSize: r.api.settingEngine.sctp.maxMessageSize,
 	}
 }
 
@@ -93,12 +102,14 @@ func (r *SCTPTransport) Start(remoteCaps SCTPCapabilities) error {
 	}
 
 	sctpAssociation, err := sctp.Client(sctp.Config{
-		NetConn:       r.Transport().conn,
-		LoggerFactory: r.api.settingEngine.LoggerFactory,
+		NetConn:              r.Transport().applicationDataConn,
+		MaxReceiveBufferSize: r.api.settingEngine.sctp.maxReceiveBufferSize,
+		LoggerFactory:        r.api.settingEngine.LoggerFactory,
 	})
 	if err != nil {
 		return err
 	}
+	r.updateMessageSize(remoteCaps.MaxMessageSize)
 
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -111,6 +122,18 @@ func (r *SCTPTransport) Start(remoteCaps SCTPCapabilities) error {
 	return nil
 }
 
+// bufferedAmount returns the bytes buffered by all DataChannels
+func (r *SCTPTransport) bufferedAmount() uint64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var amount uint64
+	for _, d := range r.dataChannels {
+		amount += d.BufferedAmount()
+	}
+	return amount
+}
+
 // Stop stops the SCTPTransport
 func (r *SCTPTransport) Stop() error {
 	r.lock.Lock()
@@ -147,6 +170,9 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 			if err != io.EOF {
 				r.log.Errorf("Failed to accept data channel: %v", err)
 				r.onError(err)
+			} else if r.closedUnexpectedly(a) {
+				r.log.Warn("SCTP association closed unexpectedly")
+				r.onError(ErrSCTPAssociationClosed)
 			}
 			return
 		}
@@ -193,6 +219,10 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 			return
 		}
 
+		rtcDC.mu.Lock()
+		rtcDC.sctpTransport = r
+		rtcDC.mu.Unlock()
+
 		<-r.onDataChannel(rtcDC)
 		rtcDC.handleOpen(dc)
 
@@ -207,8 +237,23 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 	}
 }
 
+// closedUnexpectedly marks the transport closed if the association a was
+// closed without a call to Stop, by the remote peer or the network
+func (r *SCTPTransport) closedUnexpectedly(a *sctp.Association) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.association != a {
+		return false
+	}
+	r.association = nil
+	r.state = SCTPTransportStateClosed
+	return true
+}
+
 // OnError sets an event handler which is invoked when
-// the SCTP connection error occurs.
+// the SCTP connection error occurs, including the association closing
+// without a call to Stop.
 func (r *SCTPTransport) OnError(f func(err error)) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -264,14 +309,17 @@ func (r *SCTPTransport) onDataChannel(dc *DataChannel) (done chan struct{}) {
 	return
 }
 
-func (r *SCTPTransport) updateMessageSize() {
+// updateMessageSize sets the max message size from the one advertised by
+// the remote peer, 0 if it advertised none
+func (r *SCTPTransport) updateMessageSize(remoteMaxMessageSize uint32) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	var remoteMaxMessageSize float64 = 65536 // pion/webrtc#758
-	var canSendSize float64 = 65536          // pion/webrtc#758
+	if remoteMaxMessageSize == 0 {
+		remoteMaxMessageSize = sctpDefaultMaxMessageSize
+	}
 
-	r.maxMessageSize = r.calcMessageSize(remoteMaxMessageSize, canSendSize)
+	r.maxMessageSize = r.calcMessageSize(float64(remoteMaxMessageSize), sctpMaxOutboundMessageSize)
 }
 
 func (r *SCTPTransport) calcMessageSize(remoteMaxMessageSize, canSendSize float64) float64 {
@@ -299,6 +347,15 @@ func (r *SCTPTransport) updateMaxChannels() {
 	r.maxChannels = &val
 }
 
+// MaxMessageSize is the largest message that can be passed to
+// DataChannel.Send, the smaller of what the remote peer accepts and what the
+// SCTPTransport can send.
+func (r *SCTPTransport) MaxMessageSize() float64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.maxMessageSize
+}
+
 // MaxChannels is the maximum number of RTCDataChannels that can be open simultaneously.
 func (r *SCTPTransport) MaxChannels() uint16 {
 	r.lock.Lock()
@@ -4,20 +4,28 @@ package webrtc
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/pion/logging"
+	"github.com/pion/rtp"
 	"github.com/pion/sdp/v2"
 )
 
 type trackDetails struct {
-	mid   string
-	kind  RTPCodecType
-	label string
-	id    string
-	ssrc  uint32
+	mid       string
+	kind      RTPCodecType
+	label     string
+	id        string
+	streamIDs []string
+	rid       string
+	ssrc      uint32
+
+	codecs           []RTPCodecParameters
+	headerExtensions []RTPHeaderExtensionParameter
 }
 
 // extract all trackDetails from an SDP.
@@ -46,6 +54,24 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) m
 		if codecType == 0 {
 			continue
 		}
+		codecs := codecParametersFromMedia(codecType, media)
+		headerExtensions := headerExtensionParametersFromMedia(media)
+
+		// A media section can be associated with several streams, and a single
+		// simulcast encoding can be identified by its RID
+		streamIDs, rids := []string{}, []string{}
+		for _, attr := range media.Attributes {
+			switch split := strings.Split(attr.Value, " "); {
+			case attr.Key == sdp.AttrKeyMsid && len(split) == 2 && split[0] != "-":
+				streamIDs = append(streamIDs, split[0])
+			case attr.Key == "rid" && len(split) >= 2 && split[1] == "send":
+				rids = append(rids, split[0])
+			}
+		}
+		rid := ""
+		if len(rids) == 1 {
+			rid = rids[0]
+		}
 
 		for _, attr := range media.Attributes {
 			switch attr.Key {
@@ -77,7 +103,7 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) m
 			// figure this out automatically when an ontrack event is emitted on RTCPeerConnection.
 			case sdp.AttrKeyMsid:
 				split := strings.Split(attr.Value, " ")
-				if len(split) == 2 {
+				if len(split) == 2 && trackLabel == "" {
 					trackLabel = split[0]
 					trackID = split[1]
 				}
@@ -103,7 +129,23 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) m
 
 				// Plan B might send multiple a=ssrc lines under a single m= section. This is also why a single trackDetails{}
 				// is not defined at the top of the loop over s.MediaDescriptions.
-				incomingTracks[uint32(ssrc)] = trackDetails{midValue, codecType, trackLabel, trackID, uint32(ssrc)}
+				trackStreamIDs := streamIDs
+				if len(trackStreamIDs) == 0 && trackLabel != "" && trackLabel != "-" {
+					trackStreamIDs = []string{trackLabel}
+				}
+
+				incomingTracks[uint32(ssrc)] = trackDetails{
+					mid:       midValue,
+					kind:      codecType,
+					label:     trackLabel,
+					id:        trackID,
+					streamIDs: trackStreamIDs,
+					rid:       rid,
+					ssrc:      uint32(ssrc),
+
+					codecs:           codecs,
+					headerExtensions: headerExtensions,
+				}
 			}
 		}
 	}
@@ -111,6 +153,120 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) m
 	return incomingTracks
 }
 
+// codecParametersFromMedia returns the codecs of the formats of media, in the
+// order of preference of the remote. The rtpmap of the static audio payload
+// types is optional.
+func codecParametersFromMedia(codecType RTPCodecType, media *sdp.MediaDescription) []RTPCodecParameters {
+	// The codecs of a session description are looked up across its media
+	// sections, the payload types of this one are only valid in it
+	s := &sdp.SessionDescription{MediaDescriptions: []*sdp.MediaDescription{media}}
+
+	codecs := []RTPCodecParameters{}
+	for _, format := range media.MediaName.Formats {
+		pt, err := strconv.ParseUint(format, 10, 8)
+		if err != nil {
+			continue
+		}
+
+		payloadType := uint8(pt)
+		codec, err := s.GetCodecForPayloadType(payloadType)
+		if err != nil {
+			staticCodec, ok := staticAudioPayloadTypes[payloadType]
+			if !ok || codecType != RTPCodecTypeAudio {
+				continue
+			}
+			codec = staticCodec
+		}
+
+		var channels uint16
+		if c, err := strconv.ParseUint(codec.EncodingParameters, 10, 16); err == nil {
+			channels = uint16(c)
+		}
+		feedback := []RTCPFeedback{}
+		for _, fb := range codec.RTCPFeedback {
+			split := strings.SplitN(fb, " ", 2)
+			f := RTCPFeedback{Type: split[0]}
+			if len(split) == 2 {
+				f.Parameter = split[1]
+			}
+			feedback = append(feedback, f)
+		}
+
+		codecs = append(codecs, RTPCodecParameters{
+			RTPCodecCapability: RTPCodecCapability{
+				MimeType:     codecType.String() + "/" + codec.Name,
+				ClockRate:    codec.ClockRate,
+				Channels:     channels,
+				SDPFmtpLine:  codec.Fmtp,
+				RTCPFeedback: feedback,
+			},
+			PayloadType: payloadType,
+		})
+	}
+	return codecs
+}
+
+// headerExtensionParametersFromMedia returns the header extensions of the
+// a=extmap lines of media
+func headerExtensionParametersFromMedia(media *sdp.MediaDescription) []RTPHeaderExtensionParameter {
+	extensions := []RTPHeaderExtensionParameter{}
+	for _, attr := range media.Attributes {
+		if attr.Key != sdpAttributeExtmap {
+			continue
+		}
+
+		// a=extmap:<id>[/<direction>] <uri> [<attributes>]
+		split := strings.Fields(attr.Value)
+		if len(split) < 2 {
+			continue
+		}
+		id, err := strconv.Atoi(strings.SplitN(split[0], "/", 2)[0])
+		if err != nil {
+			continue
+		}
+		extensions = append(extensions, RTPHeaderExtensionParameter{URI: split[1], ID: id})
+	}
+	return extensions
+}
+
+// mediaSectionForPacket returns the media section of desc a stream whose
+// SSRC wasn't signaled belongs to, from the header of its first packet. The
+// MID header extension names it, otherwise only a single media section may
+// offer the payload type of the packet.
+func mediaSectionForPacket(desc *sdp.SessionDescription, header *rtp.Header) *sdp.MediaDescription {
+	payloadType := strconv.Itoa(int(header.PayloadType))
+	var byPayloadType *sdp.MediaDescription
+	payloadTypeMatches := 0
+
+	for _, media := range desc.MediaDescriptions {
+		if NewRTPCodecType(media.MediaName.Media) == 0 || isMediaSectionRejected(media) {
+			continue
+		}
+
+		for _, ext := range headerExtensionParametersFromMedia(media) {
+			if ext.URI != sdesMidURI || !header.Extension {
+				continue
+			}
+			if mid := header.GetExtension(uint8(ext.ID)); len(mid) != 0 && string(mid) == getMidValue(media) {
+				return media
+			}
+		}
+
+		for _, format := range media.MediaName.Formats {
+			if format == payloadType {
+				byPayloadType = media
+				payloadTypeMatches++
+				break
+			}
+		}
+	}
+
+	if payloadTypeMatches != 1 {
+		return nil
+	}
+	return byPayloadType
+}
+
 func addCandidatesToMediaDescriptions(candidates []ICECandidate, m *sdp.MediaDescription, iceGatheringState ICEGatheringState) {
 	appendCandidateIfNew := func(c sdp.ICECandidate, attributes []sdp.Attribute) {
 		marshaled := c.Marshal()
@@ -145,7 +301,7 @@ func addCandidatesToMediaDescriptions(candidates []ICECandidate, m *sdp.MediaDes
 	m.WithPropertyAttribute("end-of-candidates")
 }
 
-func addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState) {
+func addDataMediaSection(d *sdp.SessionDescription, midValue string, maxMessageSize uint32, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState) {
 	media := (&sdp.MediaDescription{
 		MediaName: sdp.MediaName{
 			Media:   mediaSectionApplication,
@@ -166,18 +322,41 @@ func addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams I
 		WithPropertyAttribute(RTPTransceiverDirectionSendrecv.String()).
 		WithPropertyAttribute("sctpmap:5000 webrtc-datachannel 1024").
 		WithICECredentials(iceParams.UsernameFragment, iceParams.Password)
+	if maxMessageSize != 0 {
+		media.WithValueAttribute(sdpAttributeMaxMessageSize, strconv.FormatUint(uint64(maxMessageSize), 10))
+	}
 
 	addCandidatesToMediaDescriptions(candidates, media, iceGatheringState)
 	d.WithMedia(media)
 }
 
-func addFingerprints(d *sdp.SessionDescription, c Certificate) error {
-	fingerprints, err := c.GetFingerprints()
-	if err != nil {
-		return err
+// extractMaxMessageSize returns the max-message-size of the data section of
+// desc, RFC 8841 6.1, or 0 if it has none
+func extractMaxMessageSize(desc *sdp.SessionDescription) uint32 {
+	for _, m := range desc.MediaDescriptions {
+		if m.MediaName.Media != mediaSectionApplication {
+			continue
+		}
+		if value, ok := m.Attribute(sdpAttributeMaxMessageSize); ok {
+			if size, err := strconv.ParseUint(value, 10, 32); err == nil {
+				return uint32(size)
+			}
+		}
 	}
-	for _, fingerprint := range fingerprints {
-		d.WithFingerprint(fingerprint.Algorithm, strings.ToUpper(fingerprint.Value))
+	return 0
+}
+
+// addFingerprints advertises the fingerprints of every certificate, the
+// remote peer accepts any of them
+func addFingerprints(d *sdp.SessionDescription, certificates []Certificate) error {
+	for _, c := range certificates {
+		fingerprints, err := c.GetFingerprints()
+		if err != nil {
+			return err
+		}
+		for _, fingerprint := range fingerprints {
+			d.WithFingerprint(fingerprint.Algorithm, strings.ToUpper(fingerprint.Value))
+		}
 	}
 	return nil
 }
@@ -194,6 +373,9 @@ func populateLocalCandidates(sessionDescription *SessionDescription, i *ICEGathe
 
 	parsed := sessionDescription.parsed
 	for _, m := range parsed.MediaDescriptions {
+		if _, isBundleOnly := m.Attribute(sdpAttributeBundleOnly); isBundleOnly {
+			continue
+		}
 		addCandidatesToMediaDescriptions(candidates, m, iceGatheringState)
 	}
 	sdp, err := parsed.Marshal()
@@ -207,12 +389,27 @@ func populateLocalCandidates(sessionDescription *SessionDescription, i *ICEGathe
 	}
 }
 
-func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *MediaEngine, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState, transceivers ...*RTPTransceiver) (bool, error) {
+func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *MediaEngine, cname, midValue string, direction RTPTransceiverDirection, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState, transceivers ...*RTPTransceiver) (bool, error) {
 	if len(transceivers) < 1 {
 		return false, fmt.Errorf("addTransceiverSDP() called with 0 transceivers")
 	}
 	// Use the first transceiver to generate the section attributes
 	t := transceivers[0]
+
+	if t.stopped.get() && !isPlanB {
+		// A stopped transceiver rejects its media section, the mid is kept so
+		// the remote can tell which one it was (JSEP 5.2.2)
+		addRejectedMediaSection(d, sdp.MediaName{
+			Media:   t.kind.String(),
+			Protos:  []string{"UDP", "TLS", "RTP", "SAVPF"},
+			Formats: []string{"0"},
+		}, midValue)
+		return false, nil
+	}
+
+	if direction == RTPTransceiverDirection(Unknown) {
+		direction = t.Direction()
+	}
 	media := sdp.NewJSEPMediaDescription(t.kind.String(), []string{}).
 		WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()).
 		WithValueAttribute(sdp.AttrKeyMID, midValue).
@@ -220,6 +417,10 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *Med
 		WithPropertyAttribute(sdp.AttrKeyRTCPMux).
 		WithPropertyAttribute(sdp.AttrKeyRTCPRsize)
 
+	if bitrate := t.MaxBitrate(); bitrate != 0 {
+		media.Bandwidth = bandwidthLimit(bitrate)
+	}
+
 	codecs := mediaEngine.GetCodecsByKind(t.kind)
 	for _, codec := range codecs {
 		media.WithCodec(codec.PayloadType, codec.Name, codec.ClockRate, codec.Channels, codec.SDPFmtpLine)
@@ -247,15 +448,16 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *Med
 	for _, mt := range transceivers {
 		if mt.Sender() != nil && mt.Sender().track != nil {
 			track := mt.Sender().track
-			media = media.WithMediaSource(track.SSRC(), track.Label() /* cname */, track.Label() /* streamLabel */, track.ID())
+			media = media.WithMediaSource(track.SSRC(), cname, track.Label() /* streamLabel */, track.ID())
 			if !isPlanB {
 				media = media.WithPropertyAttribute("msid:" + track.Label() + " " + track.ID())
+				addSendEncodingsToMediaDescription(media, mt.Sender().Encodings())
 				break
 			}
 		}
 	}
 
-	media = media.WithPropertyAttribute(t.Direction().String())
+	media = media.WithPropertyAttribute(direction.String())
 
 	addCandidatesToMediaDescriptions(candidates, media, iceGatheringState)
 	d.WithMedia(media)
@@ -263,14 +465,90 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *Med
 	return true, nil
 }
 
+// addSendEncodingsToMediaDescription declares the RIDs of the encodings
+// (draft-ietf-mmusic-rid). The sender has a single encoding, it isn't offered
+// as simulcast.
+func addSendEncodingsToMediaDescription(media *sdp.MediaDescription, encodings []RTPEncodingParameters) {
+	for _, encoding := range encodings {
+		if encoding.RID != "" {
+			media.WithValueAttribute("rid", encoding.RID+" send")
+		}
+	}
+}
+
+// bandwidthLimit returns the bandwidth lines limiting media to bitrate bits
+// per second, TIAS (RFC 3890) and AS in kilobits per second for the
+// endpoints that don't know TIAS
+func bandwidthLimit(bitrate uint64) []sdp.Bandwidth {
+	return []sdp.Bandwidth{
+		{Type: sdpBandwidthTIAS, Bandwidth: bitrate},
+		{Type: sdpBandwidthAS, Bandwidth: (bitrate + 999) / 1000},
+	}
+}
+
+// extractBandwidthLimit returns the limit in bits per second of bandwidths,
+// 0 if there is none. TIAS is preferred over AS.
+func extractBandwidthLimit(bandwidths []sdp.Bandwidth) uint64 {
+	var limit uint64
+	for _, b := range bandwidths {
+		switch {
+		case b.Experimental:
+		case b.Type == sdpBandwidthTIAS:
+			return b.Bandwidth
+		case b.Type == sdpBandwidthAS:
+			limit = b.Bandwidth * 1000
+		}
+	}
+	return limit
+}
+
+// minBandwidthLimit returns the lower of two limits, where 0 is no limit
+func minBandwidthLimit(a, b uint64) uint64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
 type mediaSection struct {
 	id           string
 	transceivers []*RTPTransceiver
 	data         bool
+
+	// maxMessageSize is advertised in data sections if not zero
+	maxMessageSize uint32
+
+	// bundleOnly sections are only negotiated if the remote bundles them,
+	// rtcpMuxOnly sections only if the remote multiplexes RTCP
+	bundleOnly  bool
+	rtcpMuxOnly bool
+
+	// direction overrides the direction of the transceivers, used when answering
+	direction RTPTransceiverDirection
+
+	// headerExtensions are the SDES header extensions advertised
+	headerExtensions []RTPHeaderExtensionParameter
+
+	// rejected is the offered media of a section the answer rejects
+	rejected *sdp.MediaName
+}
+
+// addRejectedMediaSection adds a section for mediaName with a zero port, that
+// keeps its mid and isn't bundled
+func addRejectedMediaSection(d *sdp.SessionDescription, mediaName sdp.MediaName, midValue string) {
+	mediaName.Port = sdp.RangedPort{Value: 0}
+	media := &sdp.MediaDescription{
+		MediaName:  mediaName,
+		Attributes: []sdp.Attribute{sdp.NewAttribute(sdp.AttrKeyMID, midValue)},
+	}
+	if mediaName.Media != mediaSectionApplication {
+		media.WithPropertyAttribute(RTPTransceiverDirectionInactive.String())
+	}
+	d.WithMedia(media)
 }
 
 // populateSDP serializes a PeerConnections state into an SDP
-func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, mediaEngine *MediaEngine, connectionRole sdp.ConnectionRole, candidates []ICECandidate, iceParams ICEParameters, mediaSections []mediaSection, iceGatheringState ICEGatheringState) (*sdp.SessionDescription, error) {
+func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, mediaEngine *MediaEngine, cname string, connectionRole sdp.ConnectionRole, candidates []ICECandidate, iceParams ICEParameters, mediaSections []mediaSection, iceGatheringState ICEGatheringState) (*sdp.SessionDescription, error) {
 	var err error
 
 	bundleValue := "BUNDLE"
@@ -280,6 +558,9 @@ func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, mediaE
 		bundleCount++
 	}
 
+	// A bundle-only section needs an earlier section that carries the transport
+	haveTransport := false
+
 	for _, m := range mediaSections {
 		if m.data && len(m.transceivers) != 0 {
 			return nil, fmt.Errorf("invalid Media Section. Media + DataChannel both enabled")
@@ -288,14 +569,32 @@ func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, mediaE
 		}
 
 		shouldAddID := true
-		if m.data {
-			addDataMediaSection(d, m.id, iceParams, candidates, connectionRole, iceGatheringState)
-		} else if shouldAddID, err = addTransceiverSDP(d, isPlanB, mediaEngine, m.id, iceParams, candidates, connectionRole, iceGatheringState, m.transceivers...); err != nil {
+		if m.rejected != nil {
+			addRejectedMediaSection(d, *m.rejected, m.id)
+			shouldAddID = false
+		} else if m.data {
+			addDataMediaSection(d, m.id, m.maxMessageSize, iceParams, candidates, connectionRole, iceGatheringState)
+		} else if shouldAddID, err = addTransceiverSDP(d, isPlanB, mediaEngine, cname, m.id, m.direction, iceParams, candidates, connectionRole, iceGatheringState, m.transceivers...); err != nil {
 			return nil, err
 		}
 
 		if shouldAddID {
 			appendBundle(m.id)
+
+			media := d.MediaDescriptions[len(d.MediaDescriptions)-1]
+			if m.bundleOnly && haveTransport {
+				setBundleOnly(media)
+			} else {
+				haveTransport = true
+			}
+			if m.rtcpMuxOnly && !m.data {
+				media.WithPropertyAttribute(sdpAttributeRTCPMuxOnly)
+			}
+			if !isPlanB && !m.data {
+				for _, ext := range m.headerExtensions {
+					media.WithValueAttribute(sdpAttributeExtmap, fmt.Sprintf("%d %s", ext.ID, ext.URI))
+				}
+			}
 		}
 	}
 
@@ -306,6 +605,69 @@ func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, mediaE
 	return d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue), nil
 }
 
+// setBundleOnly turns media into a bundle-only section, with a zero port and
+// without candidates, JSEP 5.2.1
+func setBundleOnly(media *sdp.MediaDescription) {
+	media.MediaName.Port = sdp.RangedPort{Value: 0}
+
+	attributes := media.Attributes[:0]
+	for _, a := range media.Attributes {
+		if !a.IsICECandidate() && a.Key != "end-of-candidates" {
+			attributes = append(attributes, a)
+		}
+	}
+	media.Attributes = append(attributes, sdp.NewPropertyAttribute(sdpAttributeBundleOnly))
+}
+
+// applyBundlePolicy selects the sections of an initial offer that are only
+// negotiated if the remote bundles them. max-bundle offers a transport in the
+// first section only, balanced in the first section of each media type.
+func applyBundlePolicy(mediaSections []mediaSection, policy BundlePolicy) {
+	offeredKinds := map[string]bool{}
+	for i := range mediaSections {
+		kind := mediaSectionApplication
+		if !mediaSections[i].data && len(mediaSections[i].transceivers) != 0 {
+			kind = mediaSections[i].transceivers[0].kind.String()
+		}
+
+		switch policy {
+		case BundlePolicyMaxBundle:
+			mediaSections[i].bundleOnly = i > 0
+		case BundlePolicyBalanced:
+			mediaSections[i].bundleOnly = offeredKinds[kind]
+		}
+		offeredKinds[kind] = true
+	}
+}
+
+// haveRTCPMux reports if every audio and video section of desc that is not
+// rejected multiplexes RTCP with RTP
+func haveRTCPMux(desc *sdp.SessionDescription) bool {
+	for _, m := range desc.MediaDescriptions {
+		if m.MediaName.Media == mediaSectionApplication || isMediaSectionRejected(m) {
+			continue
+		}
+		if _, ok := m.Attribute(sdp.AttrKeyRTCPMux); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// haveRTCPReducedSize reports if every audio and video section of desc that
+// is not rejected accepts reduced-size RTCP, RFC 5506
+func haveRTCPReducedSize(desc *sdp.SessionDescription) bool {
+	for _, m := range desc.MediaDescriptions {
+		if m.MediaName.Media == mediaSectionApplication || isMediaSectionRejected(m) {
+			continue
+		}
+		if _, ok := m.Attribute(sdp.AttrKeyRTCPRsize); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func getMidValue(media *sdp.MediaDescription) string {
 	for _, attr := range media.Attributes {
 		if attr.Key == "mid" {
@@ -320,15 +682,79 @@ func descriptionIsPlanB(desc *SessionDescription) bool {
 		return false
 	}
 
-	detectionRegex := regexp.MustCompile(`(?i)^(audio|video|data)$`)
 	for _, media := range desc.parsed.MediaDescriptions {
-		if len(detectionRegex.FindStringSubmatch(getMidValue(media))) == 2 {
+		if isPlanBMid(getMidValue(media)) {
+			return true
+		}
+
+		// Some gateways number their sections like unified-plan, but still
+		// send several streams in one of them
+		if media.MediaName.Media != mediaSectionApplication && mediaSectionStreamCount(media) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// descriptionHasPlanBMids reports if desc names its media sections like
+// Plan-B, the sections of unified-plan are numbered
+func descriptionHasPlanBMids(desc *SessionDescription) bool {
+	if desc == nil || desc.parsed == nil {
+		return false
+	}
+
+	for _, media := range desc.parsed.MediaDescriptions {
+		if isPlanBMid(getMidValue(media)) {
 			return true
 		}
 	}
 	return false
 }
 
+var planBMidRegex = regexp.MustCompile(`(?i)^(audio|video|data)$`)
+
+func isPlanBMid(mid string) bool {
+	return planBMidRegex.MatchString(mid)
+}
+
+// mediaSectionStreamCount returns the number of streams signaled with ssrc
+// attributes in media. Only the first ssrc of an ssrc-group is counted, the
+// others are its RTX, FEC or simulcast layers.
+func mediaSectionStreamCount(media *sdp.MediaDescription) int {
+	ssrcs := map[string]bool{}
+	grouped := map[string]bool{}
+	for _, a := range media.Attributes {
+		fields := strings.Fields(a.Value)
+		switch {
+		case a.Key == ssrcStr && len(fields) > 0:
+			ssrcs[fields[0]] = true
+		case a.Key == sdpAttributeSSRCGroup && len(fields) > 2:
+			for _, ssrc := range fields[2:] {
+				grouped[ssrc] = true
+			}
+		}
+	}
+
+	count := 0
+	for ssrc := range ssrcs {
+		if !grouped[ssrc] {
+			count++
+		}
+	}
+	return count
+}
+
+// isMediaSectionRejected reports if the media section has been rejected or
+// stopped, sections that are only bundled also have a zero port.
+func isMediaSectionRejected(media *sdp.MediaDescription) bool {
+	if media.MediaName.Port.Value != 0 {
+		return false
+	}
+
+	_, isBundleOnly := media.Attribute(sdpAttributeBundleOnly)
+	return !isBundleOnly
+}
+
 func getPeerDirection(media *sdp.MediaDescription) RTPTransceiverDirection {
 	for _, a := range media.Attributes {
 		if direction := NewRTPTransceiverDirection(a.Key); direction != RTPTransceiverDirection(Unknown) {
@@ -338,34 +764,48 @@ func getPeerDirection(media *sdp.MediaDescription) RTPTransceiverDirection {
 	return RTPTransceiverDirection(Unknown)
 }
 
-func extractFingerprint(desc *sdp.SessionDescription) (string, string, error) {
-	fingerprints := []string{}
-
-	if fingerprint, haveFingerprint := desc.Attribute("fingerprint"); haveFingerprint {
-		fingerprints = append(fingerprints, fingerprint)
+// extractFingerprints returns the fingerprints of the remote certificate.
+// There can be several, see RFC 8122 5, but every media section has to list
+// the same ones as the session.
+func extractFingerprints(desc *sdp.SessionDescription) ([]DTLSFingerprint, error) {
+	fingerprintSets := [][]string{}
+	addSet := func(attributes []sdp.Attribute) {
+		fingerprints := []string{}
+		for _, a := range attributes {
+			if a.Key == "fingerprint" {
+				fingerprints = append(fingerprints, a.Value)
+			}
+		}
+		if len(fingerprints) != 0 {
+			sort.Strings(fingerprints)
+			fingerprintSets = append(fingerprintSets, fingerprints)
+		}
 	}
 
+	addSet(desc.Attributes)
 	for _, m := range desc.MediaDescriptions {
-		if fingerprint, haveFingerprint := m.Attribute("fingerprint"); haveFingerprint {
-			fingerprints = append(fingerprints, fingerprint)
-		}
+		addSet(m.Attributes)
 	}
 
-	if len(fingerprints) < 1 {
-		return "", "", ErrSessionDescriptionNoFingerprint
+	if len(fingerprintSets) < 1 {
+		return nil, ErrSessionDescriptionNoFingerprint
 	}
 
-	for _, m := range fingerprints {
-		if m != fingerprints[0] {
-			return "", "", ErrSessionDescriptionConflictingFingerprints
+	for _, fingerprints := range fingerprintSets {
+		if !reflect.DeepEqual(fingerprints, fingerprintSets[0]) {
+			return nil, ErrSessionDescriptionConflictingFingerprints
 		}
 	}
 
-	parts := strings.Split(fingerprints[0], " ")
-	if len(parts) != 2 {
-		return "", "", ErrSessionDescriptionInvalidFingerprint
+	res := make([]DTLSFingerprint, 0, len(fingerprintSets[0]))
+	for _, fingerprint := range fingerprintSets[0] {
+		parts := strings.Split(fingerprint, " ")
+		if len(parts) != 2 {
+			return nil, ErrSessionDescriptionInvalidFingerprint
+		}
+		res = append(res, DTLSFingerprint{Algorithm: parts[0], Value: parts[1]})
 	}
-	return parts[1], parts[0], nil
+	return res, nil
 }
 
 func extractICEDetails(desc *sdp.SessionDescription) (string, string, []ICECandidate, error) {
@@ -428,7 +868,7 @@ func extractICEDetails(desc *sdp.SessionDescription) (string, string, []ICECandi
 
 func haveApplicationMediaSection(desc *sdp.SessionDescription) bool {
 	for _, m := range desc.MediaDescriptions {
-		if m.MediaName.Media == mediaSectionApplication {
+		if m.MediaName.Media == mediaSectionApplication && !isMediaSectionRejected(m) {
 			return true
 		}
 	}
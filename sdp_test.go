@@ -5,6 +5,7 @@ package webrtc
 import (
 	"testing"
 
+	"github.com/pion/rtp"
 	"github.com/pion/sdp/v2"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,10 +16,9 @@ func TestExtractFingerprint(t *testing.T) {
 			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo bar"}},
 		}
 
-		fingerprint, hash, err := extractFingerprint(s)
+		fingerprints, err := extractFingerprints(s)
 		assert.NoError(t, err)
-		assert.Equal(t, fingerprint, "bar")
-		assert.Equal(t, hash, "foo")
+		assert.Equal(t, []DTLSFingerprint{{Algorithm: "foo", Value: "bar"}}, fingerprints)
 	})
 
 	t.Run("Good Media Fingerprint", func(t *testing.T) {
@@ -28,16 +28,15 @@ func TestExtractFingerprint(t *testing.T) {
 			},
 		}
 
-		fingerprint, hash, err := extractFingerprint(s)
+		fingerprints, err := extractFingerprints(s)
 		assert.NoError(t, err)
-		assert.Equal(t, fingerprint, "bar")
-		assert.Equal(t, hash, "foo")
+		assert.Equal(t, []DTLSFingerprint{{Algorithm: "foo", Value: "bar"}}, fingerprints)
 	})
 
 	t.Run("No Fingerprint", func(t *testing.T) {
 		s := &sdp.SessionDescription{}
 
-		_, _, err := extractFingerprint(s)
+		_, err := extractFingerprints(s)
 		assert.Equal(t, ErrSessionDescriptionNoFingerprint, err)
 	})
 
@@ -46,7 +45,7 @@ func TestExtractFingerprint(t *testing.T) {
 			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo"}},
 		}
 
-		_, _, err := extractFingerprint(s)
+		_, err := extractFingerprints(s)
 		assert.Equal(t, ErrSessionDescriptionInvalidFingerprint, err)
 	})
 
@@ -58,9 +57,36 @@ func TestExtractFingerprint(t *testing.T) {
 			},
 		}
 
-		_, _, err := extractFingerprint(s)
+		_, err := extractFingerprints(s)
 		assert.Equal(t, ErrSessionDescriptionConflictingFingerprints, err)
 	})
+
+	t.Run("Multiple Fingerprints", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			Attributes: []sdp.Attribute{
+				{Key: "fingerprint", Value: "sha-384 baz"},
+				{Key: "fingerprint", Value: "sha-256 bar"},
+			},
+			MediaDescriptions: []*sdp.MediaDescription{
+				{Attributes: []sdp.Attribute{
+					{Key: "fingerprint", Value: "sha-256 bar"},
+					{Key: "fingerprint", Value: "sha-384 baz"},
+				}},
+				{Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "sha-256 bar"}}},
+			},
+		}
+
+		_, err := extractFingerprints(s)
+		assert.Equal(t, ErrSessionDescriptionConflictingFingerprints, err)
+
+		s.MediaDescriptions = s.MediaDescriptions[:1]
+		fingerprints, err := extractFingerprints(s)
+		assert.NoError(t, err)
+		assert.Equal(t, []DTLSFingerprint{
+			{Algorithm: "sha-256", Value: "bar"},
+			{Algorithm: "sha-384", Value: "baz"},
+		}, fingerprints)
+	})
 }
 
 func TestExtractICEDetails(t *testing.T) {
@@ -191,6 +217,8 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 						{Key: "mid", Value: "3"},
 						{Key: "sendonly"},
 						{Key: "msid", Value: "video_stream_id video_trk_id"},
+						{Key: "msid", Value: "other_stream_id video_trk_id"},
+						{Key: "rid", Value: "hi send"},
 						{Key: "ssrc", Value: "5000"},
 					},
 				},
@@ -208,6 +236,8 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 			assert.Equal(t, RTPCodecTypeAudio, track.kind)
 			assert.Equal(t, uint32(2000), track.ssrc)
 			assert.Equal(t, "audio_trk_label", track.label)
+			assert.Equal(t, []string{"audio_trk_label"}, track.streamIDs)
+			assert.Equal(t, "", track.rid)
 		}
 		if track, ok := tracks[3000]; !ok {
 			assert.Fail(t, "missing video track with ssrc:3000")
@@ -226,6 +256,8 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 			assert.Equal(t, uint32(5000), track.ssrc)
 			assert.Equal(t, "video_trk_id", track.id)
 			assert.Equal(t, "video_stream_id", track.label)
+			assert.Equal(t, []string{"video_stream_id", "other_stream_id"}, track.streamIDs)
+			assert.Equal(t, "hi", track.rid)
 		}
 	})
 
@@ -255,6 +287,81 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 
 		assert.Equal(t, 0, len(trackDetailsFromSDP(nil, s)))
 	})
+
+	t.Run("codecs and header extensions of the media section", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			MediaDescriptions: []*sdp.MediaDescription{
+				{
+					MediaName: sdp.MediaName{
+						Media:   "audio",
+						Formats: []string{"111", "0"},
+					},
+					Attributes: []sdp.Attribute{
+						{Key: "mid", Value: "0"},
+						{Key: "sendrecv"},
+						{Key: "rtpmap", Value: "111 opus/48000/2"},
+						{Key: "fmtp", Value: "111 minptime=10;useinbandfec=1"},
+						{Key: "rtcp-fb", Value: "111 transport-cc"},
+						{Key: "extmap", Value: "1 urn:ietf:params:rtp-hdrext:ssrc-audio-level"},
+						{Key: "ssrc", Value: "1000 msid:audio_stream audio_trk"},
+					},
+				},
+				{
+					MediaName: sdp.MediaName{
+						Media:   "video",
+						Formats: []string{"96"},
+					},
+					Attributes: []sdp.Attribute{
+						{Key: "mid", Value: "1"},
+						{Key: "sendrecv"},
+						{Key: "rtpmap", Value: "96 VP8/90000"},
+						{Key: "rtcp-fb", Value: "96 nack pli"},
+						{Key: "extmap", Value: "3/sendrecv urn:ietf:params:rtp-hdrext:sdes:mid"},
+						{Key: "ssrc", Value: "2000 msid:video_stream video_trk"},
+					},
+				},
+			},
+		}
+
+		tracks := trackDetailsFromSDP(nil, s)
+		assert.Equal(t, []RTPCodecParameters{
+			{
+				RTPCodecCapability: RTPCodecCapability{
+					MimeType:     "audio/opus",
+					ClockRate:    48000,
+					Channels:     2,
+					SDPFmtpLine:  "minptime=10;useinbandfec=1",
+					RTCPFeedback: []RTCPFeedback{{Type: "transport-cc"}},
+				},
+				PayloadType: 111,
+			},
+			{
+				RTPCodecCapability: RTPCodecCapability{
+					MimeType:     "audio/" + PCMU,
+					ClockRate:    8000,
+					RTCPFeedback: []RTCPFeedback{},
+				},
+				PayloadType: 0,
+			},
+		}, tracks[1000].codecs)
+		assert.Equal(t, []RTPHeaderExtensionParameter{
+			{URI: "urn:ietf:params:rtp-hdrext:ssrc-audio-level", ID: 1},
+		}, tracks[1000].headerExtensions)
+
+		assert.Equal(t, []RTPCodecParameters{
+			{
+				RTPCodecCapability: RTPCodecCapability{
+					MimeType:     "video/VP8",
+					ClockRate:    90000,
+					RTCPFeedback: []RTCPFeedback{{Type: "nack", Parameter: "pli"}},
+				},
+				PayloadType: 96,
+			},
+		}, tracks[2000].codecs)
+		assert.Equal(t, []RTPHeaderExtensionParameter{
+			{URI: "urn:ietf:params:rtp-hdrext:sdes:mid", ID: 3},
+		}, tracks[2000].headerExtensions)
+	})
 }
 
 func TestHaveApplicationMediaSection(t *testing.T) {
@@ -290,3 +397,150 @@ func TestHaveApplicationMediaSection(t *testing.T) {
 		assert.True(t, haveApplicationMediaSection(s))
 	})
 }
+
+func TestExtractMaxMessageSize(t *testing.T) {
+	s := &sdp.SessionDescription{
+		MediaDescriptions: []*sdp.MediaDescription{
+			{
+				MediaName:  sdp.MediaName{Media: "audio"},
+				Attributes: []sdp.Attribute{{Key: sdpAttributeMaxMessageSize, Value: "10"}},
+			},
+			{
+				MediaName: sdp.MediaName{Media: mediaSectionApplication},
+			},
+		},
+	}
+	assert.Equal(t, uint32(0), extractMaxMessageSize(s))
+
+	s.MediaDescriptions[1].Attributes = []sdp.Attribute{{Key: sdpAttributeMaxMessageSize, Value: "262144"}}
+	assert.Equal(t, uint32(262144), extractMaxMessageSize(s))
+
+	s.MediaDescriptions[1].Attributes = []sdp.Attribute{{Key: sdpAttributeMaxMessageSize, Value: "foo"}}
+	assert.Equal(t, uint32(0), extractMaxMessageSize(s))
+}
+
+func TestBandwidthLimit(t *testing.T) {
+	assert.Equal(t, []sdp.Bandwidth{
+		{Type: sdpBandwidthTIAS, Bandwidth: 1500},
+		{Type: sdpBandwidthAS, Bandwidth: 2},
+	}, bandwidthLimit(1500))
+	assert.Equal(t, uint64(1500), extractBandwidthLimit(bandwidthLimit(1500)))
+
+	assert.Equal(t, uint64(0), extractBandwidthLimit(nil))
+	assert.Equal(t, uint64(0), extractBandwidthLimit([]sdp.Bandwidth{{Type: "CT", Bandwidth: 10}}))
+	assert.Equal(t, uint64(0), extractBandwidthLimit([]sdp.Bandwidth{{Experimental: true, Type: sdpBandwidthTIAS, Bandwidth: 10}}))
+	assert.Equal(t, uint64(64000), extractBandwidthLimit([]sdp.Bandwidth{{Type: sdpBandwidthAS, Bandwidth: 64}}))
+
+	assert.Equal(t, uint64(5), minBandwidthLimit(0, 5))
+	assert.Equal(t, uint64(5), minBandwidthLimit(5, 0))
+	assert.Equal(t, uint64(3), minBandwidthLimit(5, 3))
+	assert.Equal(t, uint64(0), minBandwidthLimit(0, 0))
+}
+
+func TestApplyBundlePolicy(t *testing.T) {
+	audio := &RTPTransceiver{kind: RTPCodecTypeAudio}
+	video := &RTPTransceiver{kind: RTPCodecTypeVideo}
+	newSections := func() []mediaSection {
+		return []mediaSection{
+			{id: "0", transceivers: []*RTPTransceiver{audio}},
+			{id: "1", transceivers: []*RTPTransceiver{video}},
+			{id: "2", transceivers: []*RTPTransceiver{video}},
+			{id: "3", data: true},
+		}
+	}
+	bundleOnly := func(mediaSections []mediaSection) []bool {
+		out := []bool{}
+		for _, m := range mediaSections {
+			out = append(out, m.bundleOnly)
+		}
+		return out
+	}
+
+	for _, test := range []struct {
+		policy   BundlePolicy
+		expected []bool
+	}{
+		{BundlePolicyMaxCompat, []bool{false, false, false, false}},
+		{BundlePolicyBalanced, []bool{false, false, true, false}},
+		{BundlePolicyMaxBundle, []bool{false, true, true, true}},
+	} {
+		mediaSections := newSections()
+		applyBundlePolicy(mediaSections, test.policy)
+		assert.Equal(t, test.expected, bundleOnly(mediaSections), test.policy.String())
+	}
+}
+
+func TestHaveRTCPMux(t *testing.T) {
+	s := &sdp.SessionDescription{
+		MediaDescriptions: []*sdp.MediaDescription{
+			{
+				MediaName:  sdp.MediaName{Media: "audio", Port: sdp.RangedPort{Value: 9}},
+				Attributes: []sdp.Attribute{{Key: sdp.AttrKeyRTCPMux}},
+			},
+			{
+				MediaName: sdp.MediaName{Media: mediaSectionApplication, Port: sdp.RangedPort{Value: 9}},
+			},
+			{
+				// Rejected
+				MediaName: sdp.MediaName{Media: "video"},
+			},
+		},
+	}
+	assert.True(t, haveRTCPMux(s))
+
+	s.MediaDescriptions[2].Attributes = []sdp.Attribute{{Key: sdpAttributeBundleOnly}}
+	assert.False(t, haveRTCPMux(s))
+}
+
+func TestHaveRTCPReducedSize(t *testing.T) {
+	s := &sdp.SessionDescription{
+		MediaDescriptions: []*sdp.MediaDescription{
+			{
+				MediaName:  sdp.MediaName{Media: "audio", Port: sdp.RangedPort{Value: 9}},
+				Attributes: []sdp.Attribute{{Key: sdp.AttrKeyRTCPMux}, {Key: sdp.AttrKeyRTCPRsize}},
+			},
+			{
+				MediaName: sdp.MediaName{Media: mediaSectionApplication, Port: sdp.RangedPort{Value: 9}},
+			},
+		},
+	}
+	assert.True(t, haveRTCPReducedSize(s))
+
+	s.MediaDescriptions = append(s.MediaDescriptions, &sdp.MediaDescription{
+		MediaName:  sdp.MediaName{Media: "video", Port: sdp.RangedPort{Value: 9}},
+		Attributes: []sdp.Attribute{{Key: sdp.AttrKeyRTCPMux}},
+	})
+	assert.False(t, haveRTCPReducedSize(s))
+}
+
+func TestMediaSectionForPacket(t *testing.T) {
+	newMedia := func(kind, mid string, formats ...string) *sdp.MediaDescription {
+		return &sdp.MediaDescription{
+			MediaName: sdp.MediaName{Media: kind, Port: sdp.RangedPort{Value: 9}, Formats: formats},
+			Attributes: []sdp.Attribute{
+				{Key: "mid", Value: mid},
+				{Key: "extmap", Value: "4 " + sdesMidURI},
+			},
+		}
+	}
+	s := &sdp.SessionDescription{
+		MediaDescriptions: []*sdp.MediaDescription{
+			newMedia("audio", "0", "111"),
+			newMedia("video", "1", "96", "97"),
+			newMedia("video", "2", "96", "98"),
+		},
+	}
+
+	header := &rtp.Header{Version: 2, PayloadType: 96}
+	assert.Nil(t, mediaSectionForPacket(s, header), "payload type offered by two media sections")
+
+	assert.NoError(t, header.SetExtension(4, []byte("2")))
+	assert.Equal(t, s.MediaDescriptions[2], mediaSectionForPacket(s, header))
+
+	header = &rtp.Header{Version: 2, PayloadType: 97}
+	assert.Equal(t, s.MediaDescriptions[1], mediaSectionForPacket(s, header))
+	header = &rtp.Header{Version: 2, PayloadType: 111}
+	assert.Equal(t, s.MediaDescriptions[0], mediaSectionForPacket(s, header))
+	header = &rtp.Header{Version: 2, PayloadType: 100}
+	assert.Nil(t, mediaSectionForPacket(s, header))
+}
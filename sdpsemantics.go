@@ -17,7 +17,9 @@ const (
 
 	// SDPSemanticsUnifiedPlanWithFallback prefers unified-plan
 	// offers and answers, but will respond to a plan-b offer
-	// with a plan-b answer
+	// with a plan-b answer. An offer is plan-b if its sections are
+	// named audio, video and data, or if a section carries several
+	// streams.
 	SDPSemanticsUnifiedPlanWithFallback
 )
 
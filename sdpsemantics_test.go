@@ -335,3 +335,110 @@ func TestSDPSemantics_UnifiedPlanWithFallback(t *testing.T) {
 	assert.NoError(t, apc.Close())
 	assert.NoError(t, opc.Close())
 }
+
+func TestSDPSemantics_PlanBNumericMids(t *testing.T) {
+	const planBOffer = `v=0
+o=- 4648475892259889561 3 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0 1
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=ice-ufrag:1/MvHwjAyVf27aLu
+a=ice-pwd:3dBU7cFOBl120v33cynDvN1E
+a=fingerprint:sha-256 75:74:5A:A6:A4:E5:52:F4:A7:67:4C:01:C7:EE:91:3F:21:3D:A2:E3:53:7B:6F:30:86:F2:30:AA:65:FB:04:24
+a=setup:actpass
+a=mid:0
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:111 opus/48000/2
+a=ssrc:1001 cname:gateway
+a=ssrc:1001 msid:stream audio1
+m=video 9 UDP/TLS/RTP/SAVPF 96 97
+c=IN IP4 0.0.0.0
+a=ice-ufrag:1/MvHwjAyVf27aLu
+a=ice-pwd:3dBU7cFOBl120v33cynDvN1E
+a=fingerprint:sha-256 75:74:5A:A6:A4:E5:52:F4:A7:67:4C:01:C7:EE:91:3F:21:3D:A2:E3:53:7B:6F:30:86:F2:30:AA:65:FB:04:24
+a=setup:actpass
+a=mid:1
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+a=rtpmap:97 rtx/90000
+a=fmtp:97 apt=96
+a=ssrc-group:FID 2001 2002
+a=ssrc:2001 msid:stream video1
+a=ssrc:2002 msid:stream video1
+a=ssrc:3001 msid:stream video2
+`
+	parsed := &sdp.SessionDescription{}
+	assert.NoError(t, parsed.Unmarshal([]byte(planBOffer)))
+	assert.Equal(t, 1, mediaSectionStreamCount(parsed.MediaDescriptions[0]))
+	assert.Equal(t, 2, mediaSectionStreamCount(parsed.MediaDescriptions[1]))
+	assert.True(t, descriptionIsPlanB(&SessionDescription{parsed: parsed}))
+
+	// One stream per section with RTX is unified-plan
+	parsed.MediaDescriptions[1].Attributes = parsed.MediaDescriptions[1].Attributes[:len(parsed.MediaDescriptions[1].Attributes)-1]
+	assert.False(t, descriptionIsPlanB(&SessionDescription{parsed: parsed}))
+
+	pc, err := NewPeerConnection(Configuration{SDPSemantics: SDPSemanticsUnifiedPlanWithFallback})
+	assert.NoError(t, err)
+
+	for i := uint32(1); i <= 2; i++ {
+		track, trackErr := pc.NewTrack(DefaultPayloadTypeVP8, i, "video", "pion")
+		assert.NoError(t, trackErr)
+		_, trackErr = pc.AddTrack(track)
+		assert.NoError(t, trackErr)
+	}
+
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: planBOffer}))
+	answer, err := pc.CreateAnswer(nil)
+	assert.NoError(t, err)
+
+	// The answer keeps the numbered sections and sends both tracks in the video one
+	assert.Equal(t, []string{"audio", "video"}, getMdNames(answer.parsed))
+	assert.Equal(t, "1", getMidValue(answer.parsed.MediaDescriptions[1]))
+	assert.Len(t, extractSsrcList(answer.parsed.MediaDescriptions[1]), 2)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestSDPSemantics_UnifiedPlanMultiStreamSection(t *testing.T) {
+	const offer = `v=0
+o=- 4648475892259889561 3 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:1/MvHwjAyVf27aLu
+a=ice-pwd:3dBU7cFOBl120v33cynDvN1E
+a=fingerprint:sha-256 75:74:5A:A6:A4:E5:52:F4:A7:67:4C:01:C7:EE:91:3F:21:3D:A2:E3:53:7B:6F:30:86:F2:30:AA:65:FB:04:24
+a=setup:actpass
+a=mid:0
+a=sendonly
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+a=ssrc:2001 msid:stream video1
+a=ssrc:3001 msid:stream video2
+`
+	parsed := &sdp.SessionDescription{}
+	assert.NoError(t, parsed.Unmarshal([]byte(offer)))
+	assert.True(t, descriptionIsPlanB(&SessionDescription{parsed: parsed}))
+	assert.False(t, descriptionHasPlanBMids(&SessionDescription{parsed: parsed}))
+	assert.Len(t, trackDetailsFromSDP(nil, parsed), 2)
+
+	// Only sections named like Plan-B make strict unified-plan refuse it, this
+	// one is answered with a single transceiver receiving both streams
+	pc, err := NewPeerConnection(Configuration{SDPSemantics: SDPSemanticsUnifiedPlan})
+	assert.NoError(t, err)
+
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: offer}))
+	assert.False(t, pc.remoteIsPlanB())
+	answer, err := pc.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"video"}, getMdNames(answer.parsed))
+	assert.Len(t, pc.GetTransceivers(), 1)
+
+	assert.NoError(t, pc.Close())
+}
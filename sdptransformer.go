@@ -0,0 +1,27 @@
+// +build !js
+
+package webrtc
+
+import (
+	"github.com/pion/sdp/v2"
+)
+
+// SDPTransformer edits a session description in place, as an escape hatch for
+// the quirks of remote implementations. Returning an error fails the call
+// that applied it.
+type SDPTransformer func(sdpType SDPType, desc *sdp.SessionDescription) error
+
+// transformDescription applies transform to the parsed desc and replaces its
+// SDP with the result
+func transformDescription(desc *SessionDescription, transform SDPTransformer) error {
+	if err := transform(desc.Type, desc.parsed); err != nil {
+		return err
+	}
+
+	sdpBytes, err := desc.parsed.Marshal()
+	if err != nil {
+		return err
+	}
+	desc.SDP = string(sdpBytes)
+	return nil
+}
@@ -0,0 +1,89 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
+// MediaSectionDescription is a typed view of a media section of a
+// SessionDescription, so what it negotiates can be inspected without parsing
+// the SDP
+type MediaSectionDescription struct {
+	MID string
+	// Kind is 0 for the application section of the DataChannels
+	Kind RTPCodecType
+	// Direction is the one of the section, sendrecv when it has none
+	Direction RTPTransceiverDirection
+	// Rejected sections have a port of 0 and aren't bundled
+	Rejected bool
+
+	// Codecs are in the order of preference of the section
+	Codecs           []RTPCodecParameters
+	HeaderExtensions []RTPHeaderExtensionParameter
+	// SSRCs are the streams the section signals, in the order of their
+	// first a=ssrc line
+	SSRCs      []uint32
+	Candidates []ICECandidate
+}
+
+// MediaSections returns the media sections of sd in order
+func (sd *SessionDescription) MediaSections() ([]MediaSectionDescription, error) {
+	parsed := sd.parsed
+	if parsed == nil {
+		parsed = &sdp.SessionDescription{}
+		if err := parsed.Unmarshal([]byte(sd.SDP)); err != nil {
+			return nil, err
+		}
+	}
+
+	sections := []MediaSectionDescription{}
+	for _, media := range parsed.MediaDescriptions {
+		section := MediaSectionDescription{
+			MID:              getMidValue(media),
+			Kind:             NewRTPCodecType(media.MediaName.Media),
+			Direction:        getPeerDirection(media),
+			Rejected:         isMediaSectionRejected(media),
+			Codecs:           []RTPCodecParameters{},
+			HeaderExtensions: headerExtensionParametersFromMedia(media),
+			SSRCs:            []uint32{},
+			Candidates:       []ICECandidate{},
+		}
+		if section.Direction == RTPTransceiverDirection(Unknown) {
+			section.Direction = RTPTransceiverDirectionSendrecv
+		}
+		if section.Kind != 0 {
+			section.Codecs = codecParametersFromMedia(section.Kind, media)
+		}
+
+		seen := map[uint32]bool{}
+		for _, a := range media.Attributes {
+			switch {
+			case a.Key == ssrcStr:
+				ssrc, err := strconv.ParseUint(strings.Fields(a.Value + " ")[0], 10, 32)
+				if err != nil {
+					return nil, err
+				}
+				if !seen[uint32(ssrc)] {
+					seen[uint32(ssrc)] = true
+					section.SSRCs = append(section.SSRCs, uint32(ssrc))
+				}
+			case a.IsICECandidate():
+				sdpCandidate, err := a.ToICECandidate()
+				if err != nil {
+					return nil, err
+				}
+				candidate, err := newICECandidateFromSDP(sdpCandidate)
+				if err != nil {
+					return nil, err
+				}
+				section.Candidates = append(section.Candidates, candidate)
+			}
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
@@ -0,0 +1,71 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionDescriptionMediaSections(t *testing.T) {
+	desc := SessionDescription{Type: SDPTypeOffer, SDP: `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0 1
+m=audio 9 UDP/TLS/RTP/SAVPF 111 0
+c=IN IP4 0.0.0.0
+a=mid:0
+a=sendonly
+a=rtpmap:111 opus/48000/2
+a=rtcp-fb:111 transport-cc
+a=extmap:4 urn:ietf:params:rtp-hdrext:sdes:mid
+a=ssrc:1000 cname:foo
+a=ssrc:1000 msid:stream audio
+a=candidate:1 1 udp 2130706431 192.168.1.2 50000 typ host
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=mid:1
+a=rtpmap:96 VP8/90000
+a=ssrc:2000 cname:foo
+a=ssrc:2001 cname:foo
+m=application 0 DTLS/SCTP 5000
+c=IN IP4 0.0.0.0
+a=mid:2
+`}
+
+	sections, err := desc.MediaSections()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(sections))
+
+	audio := sections[0]
+	assert.Equal(t, "0", audio.MID)
+	assert.Equal(t, RTPCodecTypeAudio, audio.Kind)
+	assert.Equal(t, RTPTransceiverDirectionSendonly, audio.Direction)
+	assert.False(t, audio.Rejected)
+	assert.Equal(t, 2, len(audio.Codecs))
+	assert.Equal(t, uint8(111), audio.Codecs[0].PayloadType)
+	assert.Equal(t, "audio/opus", audio.Codecs[0].MimeType)
+	assert.Equal(t, uint8(0), audio.Codecs[1].PayloadType)
+	assert.Equal(t, []RTPHeaderExtensionParameter{{URI: sdesMidURI, ID: 4}}, audio.HeaderExtensions)
+	assert.Equal(t, []uint32{1000}, audio.SSRCs)
+	assert.Equal(t, 1, len(audio.Candidates))
+	assert.Equal(t, "192.168.1.2", audio.Candidates[0].Address)
+	assert.Equal(t, uint16(50000), audio.Candidates[0].Port)
+
+	video := sections[1]
+	assert.Equal(t, RTPCodecTypeVideo, video.Kind)
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, video.Direction, "sendrecv without a direction")
+	assert.Equal(t, []uint32{2000, 2001}, video.SSRCs)
+	assert.Equal(t, 0, len(video.Candidates))
+
+	application := sections[2]
+	assert.Equal(t, "2", application.MID)
+	assert.Equal(t, RTPCodecType(0), application.Kind)
+	assert.True(t, application.Rejected)
+	assert.Equal(t, 0, len(application.Codecs))
+
+	_, err = (&SessionDescription{SDP: "invalid"}).MediaSections()
+	assert.Error(t, err)
+}
@@ -4,11 +4,14 @@ package webrtc
 
 import (
 	"errors"
+	"net"
 	"time"
 
+	"github.com/pion/dtls/v2"
 	"github.com/pion/ice"
 	"github.com/pion/logging"
 	"github.com/pion/transport/vnet"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
 // SettingEngine allows influencing behavior in ways that are not
@@ -25,6 +28,7 @@ type SettingEngine struct {
 	timeout struct {
 		ICEConnection                *time.Duration
 		ICEKeepalive                 *time.Duration
+		ICEFailed                    *time.Duration
 		ICECandidateSelectionTimeout *time.Duration
 		ICEHostAcceptanceMinWait     *time.Duration
 		ICESrflxAcceptanceMinWait    *time.Duration
@@ -32,28 +36,59 @@ type SettingEngine struct {
 		ICERelayAcceptanceMinWait    *time.Duration
 	}
 	candidates struct {
-		ICELite                        bool
-		ICETrickle                     bool
-		ICENetworkTypes                []NetworkType
-		InterfaceFilter                func(string) bool
-		NAT1To1IPs                     []string
-		NAT1To1IPCandidateType         ICECandidateType
-		GenerateMulticastDNSCandidates bool
-		MulticastDNSHostName           string
-		UsernameFragment               string
-		Password                       string
+		ICELite                bool
+		ICETrickle             bool
+		ICENetworkTypes        []NetworkType
+		InterfaceFilter        func(string) bool
+		IPFilter               func(net.IP) bool
+		DisableHost            bool
+		NAT1To1IPs             []string
+		NAT1To1IPCandidateType ICECandidateType
+		MulticastDNSMode       ice.MulticastDNSMode
+		MulticastDNSHostName   string
+		UsernameFragment       string
+		Password               string
+		StaticCandidates       []ICECandidate
 	}
 	replayProtection struct {
 		DTLS  *uint
 		SRTP  *uint
 		SRTCP *uint
 	}
+	rtcp struct {
+		batchInterval time.Duration
+	}
+	sctp struct {
+		maxMessageSize       uint32
+		maxReceiveBufferSize uint32
+	}
+	dtls struct {
+		cipherSuites           []dtls.CipherSuiteID
+		remoteFingerprints     []DTLSFingerprint
+		srtpProtectionProfiles []dtls.SRTPProtectionProfile
+		retransmissionInterval time.Duration
+		handshakeTimeout       time.Duration
+	}
+	sdpTransformers struct {
+		local  SDPTransformer
+		remote SDPTransformer
+	}
+	egressRateLimit                           uint64
 	answeringDTLSRole                         DTLSRole
 	disableCertificateFingerprintVerification bool
 	disableSRTPReplayProtection               bool
 	disableSRTCPReplayProtection              bool
 	vnet                                      *vnet.Net
-	LoggerFactory                             logging.LoggerFactory
+	iceCredentialProvider                     ICECredentialProvider
+	populateMediaEngineFromRemoteOffer        bool
+	networkMonitorInterval                    time.Duration
+
+	// LoggerFactory creates the loggers used throughout the stack, scoped
+	// "pc" for negotiation, "ice" for candidate gathering, and "ortc" for
+	// the ICE, DTLS and SRTP transports. Provide an adapter to route them
+	// into another logging framework, or use logging.DefaultLoggerFactory
+	// with per-scope levels to enable debug tracing.
+	LoggerFactory logging.LoggerFactory
 }
 
 // DetachDataChannels enables detaching data channels. When enabled
@@ -70,6 +105,33 @@ func (e *SettingEngine) SetConnectionTimeout(connectionTimeout, keepAlive time.D
 	e.timeout.ICEKeepalive = &keepAlive
 }
 
+// SetICETimeouts sets the ICE timeouts of the connection. The ICETransport
+// becomes disconnected once nothing was received from the remote for
+// disconnectedTimeout, and failed once it stayed disconnected for
+// failedTimeout. A failed ICETransport no longer has consent to send and
+// drops outgoing packets until it receives from the remote again or an ICE
+// restart completes. keepAliveInterval is how long the selected pair may be
+// idle before a binding request is sent to check consent.
+//
+// A remote peer that went away is so detected after at most
+// disconnectedTimeout and fails after disconnectedTimeout+failedTimeout. A
+// timeout of 0 disables the transition.
+func (e *SettingEngine) SetICETimeouts(disconnectedTimeout, failedTimeout, keepAliveInterval time.Duration) {
+	e.timeout.ICEConnection = &disconnectedTimeout
+	e.timeout.ICEFailed = &failedTimeout
+	e.timeout.ICEKeepalive = &keepAliveInterval
+}
+
+// SetRTCPBatchInterval makes PeerConnection.WriteRTCP queue the packets and
+// send everything written during interval as one compound packet, instead
+// of one datagram per call. Unless the remote accepted reduced-size RTCP
+// (RFC 5506) every compound packet starts with a report, an empty
+// ReceiverReport if none was written. An interval of 0, the default, sends
+// the packets right away.
+func (e *SettingEngine) SetRTCPBatchInterval(interval time.Duration) {
+	e.rtcp.batchInterval = interval
+}
+
 // SetCandidateSelectionTimeout sets the max ICECandidateSelectionTimeout
 func (e *SettingEngine) SetCandidateSelectionTimeout(t time.Duration) {
 	e.timeout.ICECandidateSelectionTimeout = &t
@@ -115,6 +177,10 @@ func (e *SettingEngine) SetLite(lite bool) {
 
 // SetTrickle configures whether or not the ice agent should gather candidates
 // via the trickle method or synchronously.
+//
+// With trickle enabled CreateOffer and CreateAnswer return right away, gathering
+// starts on SetLocalDescription and every candidate is emitted via OnICECandidate.
+// The remote side passes them to AddICECandidate as they arrive.
 func (e *SettingEngine) SetTrickle(trickle bool) {
 	e.candidates.ICETrickle = trickle
 }
@@ -125,6 +191,14 @@ func (e *SettingEngine) SetNetworkTypes(candidateTypes []NetworkType) {
 	e.candidates.ICENetworkTypes = candidateTypes
 }
 
+// DisableHostCandidates keeps host candidates out of gathering, so the local
+// addresses of the machine are never sent to the remote peer. Only server
+// reflexive and relay candidates are used. Use ICETransportPolicyRelay in the
+// Configuration to hide the public address as well.
+func (e *SettingEngine) DisableHostCandidates(isDisabled bool) {
+	e.candidates.DisableHost = isDisabled
+}
+
 // SetInterfaceFilter sets the filtering functions when gathering ICE candidates
 // This can be used to exclude certain network interfaces from ICE. Which may be
 // useful if you know a certain interface will never succeed, or if you wish to reduce
@@ -133,6 +207,18 @@ func (e *SettingEngine) SetInterfaceFilter(filter func(string) bool) {
 	e.candidates.InterfaceFilter = filter
 }
 
+// SetIPFilter sets the filtering function when gathering ICE candidates
+// This can be used to restrict ICE to certain IP ranges, for example to keep
+// Docker bridge or VPN addresses out of the candidates. Host candidates are
+// filtered by their address and server reflexive candidates by the local address
+// they were gathered from. Candidates are only kept if the filter returns true.
+// The ICE agent doesn't bind host candidates on the rejected addresses, so no
+// connectivity check is sent from them. With a virtual network set by SetVNet
+// the filter only applies to the candidates signaled.
+func (e *SettingEngine) SetIPFilter(filter func(net.IP) bool) {
+	e.candidates.IPFilter = filter
+}
+
 // SetNAT1To1IPs sets a list of external IP addresses of 1:1 (D)NAT
 // and a candidate type for which the external IP address is used.
 // This is useful when you are host a server using Pion on an AWS EC2 instance
@@ -149,6 +235,9 @@ func (e *SettingEngine) SetInterfaceFilter(filter func(string) bool) {
 //		A server reflexive candidate with the given public IP address will be added
 // to the SDP.
 //
+// On hosts with multiple interfaces an entry can also be a "public/private" pair,
+// like "203.0.113.10/10.0.0.10", which maps only the given private IP address.
+//
 // Please note that if you choose ICECandidateTypeHost, then the private IP address
 // won't be advertised with the peer. Also, this option cannot be used along with mDNS.
 //
@@ -182,14 +271,42 @@ func (e *SettingEngine) SetAnsweringDTLSRole(role DTLSRole) error {
 //
 // VNet is a virtual network layer for Pion, allowing users to simulate
 // different topologies, latency, loss and jitter. This can be useful for
-// learning WebRTC concepts or testing your application in a lab environment
+// learning WebRTC concepts or testing your application in a lab environment.
+//
+// All the traffic of the PeerConnections and ORTC transports created with
+// the API goes through the Net, so no real socket is opened. Latency and
+// jitter are set with the MinDelay and MaxJitter of the vnet.RouterConfig,
+// NAT behavior with its NATType, and loss with a filter added through
+// Router.AddChunkFilter. A Net behind a 1:1 NAT needs SetNAT1To1IPs to
+// advertise its external address.
 func (e *SettingEngine) SetVNet(vnet *vnet.Net) {
 	e.vnet = vnet
 }
 
 // GenerateMulticastDNSCandidates instructs pion/ice to generate host candidates with mDNS hostnames instead of IP Addresses
+//
+// This is shorthand for SetICEMulticastDNSMode with ice.MulticastDNSModeQueryAndGather, or
+// ice.MulticastDNSModeQueryOnly when false.
 func (e *SettingEngine) GenerateMulticastDNSCandidates(generateMulticastDNSCandidates bool) {
-	e.candidates.GenerateMulticastDNSCandidates = generateMulticastDNSCandidates
+	if generateMulticastDNSCandidates {
+		e.candidates.MulticastDNSMode = ice.MulticastDNSModeQueryAndGather
+	} else {
+		e.candidates.MulticastDNSMode = ice.MulticastDNSModeQueryOnly
+	}
+}
+
+// SetICEMulticastDNSMode controls if pion/ice queries and generates mDNS ICE Candidates
+//
+// ice.MulticastDNSModeDisabled:
+//		Remote .local candidates are discarded and local host candidates use IP Addresses.
+// ice.MulticastDNSModeQueryOnly:
+//		Remote .local candidates are resolved and local host candidates use IP Addresses.
+//		This is the default.
+// ice.MulticastDNSModeQueryAndGather:
+//		Remote .local candidates are resolved and local host candidates are obfuscated
+//		behind a random .local hostname, matching the behavior of modern browsers.
+func (e *SettingEngine) SetICEMulticastDNSMode(multicastDNSMode ice.MulticastDNSMode) {
+	e.candidates.MulticastDNSMode = multicastDNSMode
 }
 
 // SetMulticastDNSHostName sets a static HostName to be used by pion/ice instead of generating one on startup
@@ -208,11 +325,161 @@ func (e *SettingEngine) SetICECredentials(usernameFragment, password string) {
 	e.candidates.Password = password
 }
 
+// SetICECredentialProvider sets a callback that fetches the TURN credentials of an
+// ICEServer every time candidates are gathered, instead of using the static ones
+// from the Configuration.
+//
+// This is useful for time-limited credentials like the ones handed out by a TURN
+// REST API. An ICE restart fetches new credentials, so a long running session can
+// keep using TURN after the credentials it started with have expired.
+func (e *SettingEngine) SetICECredentialProvider(provider ICECredentialProvider) {
+	e.iceCredentialProvider = provider
+}
+
+// SetNetworkMonitorInterval makes PeerConnections poll the addresses of the
+// local interfaces once per interval. When they change OnNetworkChanged
+// fires and, once negotiated, RestartICE is called, so a NegotiationManager
+// or the OnNegotiationNeeded handler moves the connection to the new network.
+// Zero, the default, disables the polling.
+func (e *SettingEngine) SetNetworkMonitorInterval(interval time.Duration) {
+	e.networkMonitorInterval = interval
+}
+
+// SetICECandidates sets candidates that are signaled before anything is
+// gathered, in every local description and first when trickling. This lets a
+// server that hands out pre-allocated ports negotiate them right away: pin
+// the port with SetEphemeralUDPPortRange and SetICECredentials, and advertise
+// the address packets for that port reach it on.
+//
+// A zero Foundation or Priority is computed like for a gathered candidate.
+func (e *SettingEngine) SetICECandidates(candidates []ICECandidate) error {
+	static := make([]ICECandidate, 0, len(candidates))
+	for _, c := range candidates {
+		iceCandidate, err := c.toICE()
+		if err != nil {
+			return err
+		}
+		gathered, err := newICECandidateFromICE(iceCandidate)
+		if err != nil {
+			return err
+		}
+		if c.Foundation == "" {
+			c.Foundation = gathered.Foundation
+		}
+		if c.Priority == 0 {
+			c.Priority = gathered.Priority
+		}
+		static = append(static, c)
+	}
+	e.candidates.StaticCandidates = static
+	return nil
+}
+
+// PopulateMediaEngineFromRemoteOffer makes an answering PeerConnection use
+// the codecs and payload types of the first remote offer instead of the
+// MediaEngine of the API, see MediaEngine.PopulateFromSDP. Tracks have to be
+// created with one of the offered payload types. The offer has to be set
+// before any transceiver is added, else SetRemoteDescription fails with
+// ErrMediaEngineInUse.
+func (e *SettingEngine) PopulateMediaEngineFromRemoteOffer(populate bool) {
+	e.populateMediaEngineFromRemoteOffer = populate
+}
+
+// SetLocalSDPTransformer sets a callback that edits the offers and answers
+// the PeerConnection creates, before CreateOffer and CreateAnswer marshal
+// them. What they return, and so what SetLocalDescription applies, is the
+// transformed description.
+func (e *SettingEngine) SetLocalSDPTransformer(transformer SDPTransformer) {
+	e.sdpTransformers.local = transformer
+}
+
+// SetRemoteSDPTransformer sets a callback that edits the remote descriptions
+// once SetRemoteDescription parsed them, before anything is negotiated from
+// them. RemoteDescription returns the transformed description.
+func (e *SettingEngine) SetRemoteSDPTransformer(transformer SDPTransformer) {
+	e.sdpTransformers.remote = transformer
+}
+
 // DisableCertificateFingerprintVerification disables fingerprint verification after DTLS Handshake has finished
 func (e *SettingEngine) DisableCertificateFingerprintVerification(isDisabled bool) {
 	e.disableCertificateFingerprintVerification = isDisabled
 }
 
+// SetDTLSCipherSuites restricts the cipher suites the DTLS handshake can
+// negotiate, in order of preference. The cipher suites have to match the
+// key type of the certificates. The dtls defaults are used when none are set.
+// pion/dtls doesn't report the cipher suite negotiated, setting a single one
+// is the way to know it.
+func (e *SettingEngine) SetDTLSCipherSuites(cipherSuites ...dtls.CipherSuiteID) {
+	e.dtls.cipherSuites = cipherSuites
+}
+
+// SetDTLSRemoteFingerprints restricts the certificates the remote peer may
+// present to the ones matching one of fingerprints, on top of the check
+// against the remote SessionDescription. The DTLSTransport fails before any
+// media flows with other certificates. This check is also done when
+// DisableCertificateFingerprintVerification is set.
+func (e *SettingEngine) SetDTLSRemoteFingerprints(fingerprints ...DTLSFingerprint) {
+	e.dtls.remoteFingerprints = fingerprints
+}
+
+// SetDTLSRetransmissionInterval sets how long the DTLS handshake waits for
+// the reply to a flight before sending it again. Links with a round trip time
+// close to the dtls default of one second, like satellite ones, need a longer
+// interval to not flood the peer with retransmissions.
+func (e *SettingEngine) SetDTLSRetransmissionInterval(interval time.Duration) {
+	e.dtls.retransmissionInterval = interval
+}
+
+// SetDTLSHandshakeTimeout sets how long the DTLS handshake may take before
+// the DTLSTransport fails, the dtls default is 30 seconds.
+func (e *SettingEngine) SetDTLSHandshakeTimeout(timeout time.Duration) {
+	e.dtls.handshakeTimeout = timeout
+}
+
+// SetSRTPProtectionProfiles sets the SRTP protection profiles offered in the
+// DTLS handshake, in order of preference. Only SRTP_AES128_CM_HMAC_SHA1_80 is
+// supported for now, the AEAD AES-GCM profiles are rejected until the srtp
+// package implements them.
+func (e *SettingEngine) SetSRTPProtectionProfiles(profiles ...dtls.SRTPProtectionProfile) error {
+	for _, profile := range profiles {
+		if _, ok := srtpProtectionProfiles[profile]; !ok {
+			return &rtcerr.NotSupportedError{Err: ErrSRTPProtectionProfile}
+		}
+	}
+
+	e.dtls.srtpProtectionProfiles = profiles
+	return nil
+}
+
+func (e *SettingEngine) getSRTPProtectionProfiles() []dtls.SRTPProtectionProfile {
+	if len(e.dtls.srtpProtectionProfiles) != 0 {
+		return e.dtls.srtpProtectionProfiles
+	}
+	return []dtls.SRTPProtectionProfile{dtls.SRTP_AES128_CM_HMAC_SHA1_80}
+}
+
+// SetSCTPMaxMessageSize sets the largest DataChannel message the remote peer
+// may send, it is advertised with the max-message-size SDP attribute. Larger
+// messages are rejected by the sender. Remote peers which don't advertise it
+// are assumed to accept 65536 bytes.
+func (e *SettingEngine) SetSCTPMaxMessageSize(size uint32) {
+	e.sctp.maxMessageSize = size
+}
+
+// SetSCTPMaxReceiveBufferSize sets the size of the SCTP receive buffer, which
+// limits the data in flight from the remote peer. The default is 1 MiB.
+func (e *SettingEngine) SetSCTPMaxReceiveBufferSize(size uint32) {
+	e.sctp.maxReceiveBufferSize = size
+}
+
+// SetEgressRateLimit limits the media and the DataChannel messages each
+// PeerConnection sends to bitrate bits per second, see
+// PeerConnection.SetEgressRateLimit. 0, the default, sets no limit.
+func (e *SettingEngine) SetEgressRateLimit(bitrate uint64) {
+	e.egressRateLimit = bitrate
+}
+
 // SetDTLSReplayProtectionWindow sets a replay attack protection window size of DTLS connection.
 func (e *SettingEngine) SetDTLSReplayProtectionWindow(n uint) {
 	e.replayProtection.DTLS = &n
@@ -6,6 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/dtls/v2"
+	"github.com/pion/ice"
+	"github.com/pion/sdp/v2"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,6 +54,20 @@ func TestSetConnectionTimeout(t *testing.T) {
 	}
 }
 
+func TestSetICETimeouts(t *testing.T) {
+	s := SettingEngine{}
+	s.SetICETimeouts(2*time.Second, 5*time.Second, 500*time.Millisecond)
+
+	if s.timeout.ICEConnection == nil ||
+		*s.timeout.ICEConnection != 2*time.Second ||
+		s.timeout.ICEFailed == nil ||
+		*s.timeout.ICEFailed != 5*time.Second ||
+		s.timeout.ICEKeepalive == nil ||
+		*s.timeout.ICEKeepalive != 500*time.Millisecond {
+		t.Fatalf("ICE Timeouts do not reflect requested values.")
+	}
+}
+
 func TestDetachDataChannels(t *testing.T) {
 	s := SettingEngine{}
 
@@ -84,6 +102,20 @@ func TestSetNAT1To1IPs(t *testing.T) {
 	}
 }
 
+func TestSetICEMulticastDNSMode(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, ice.MulticastDNSMode(0), s.candidates.MulticastDNSMode)
+
+	s.SetICEMulticastDNSMode(ice.MulticastDNSModeDisabled)
+	assert.Equal(t, ice.MulticastDNSModeDisabled, s.candidates.MulticastDNSMode)
+
+	s.GenerateMulticastDNSCandidates(true)
+	assert.Equal(t, ice.MulticastDNSModeQueryAndGather, s.candidates.MulticastDNSMode)
+
+	s.GenerateMulticastDNSCandidates(false)
+	assert.Equal(t, ice.MulticastDNSModeQueryOnly, s.candidates.MulticastDNSMode)
+}
+
 func TestSetAnsweringDTLSRole(t *testing.T) {
 	s := SettingEngine{}
 	assert.Error(t, s.SetAnsweringDTLSRole(DTLSRoleAuto), "SetAnsweringDTLSRole can only be called with DTLSRoleClient or DTLSRoleServer")
@@ -116,3 +148,85 @@ func TestSetReplayProtection(t *testing.T) {
 		t.Errorf("Failed to set SRTCP replay protection window")
 	}
 }
+
+func TestSetSRTPProtectionProfiles(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, []dtls.SRTPProtectionProfile{dtls.SRTP_AES128_CM_HMAC_SHA1_80}, s.getSRTPProtectionProfiles())
+
+	err := s.SetSRTPProtectionProfiles(dtls.SRTP_AEAD_AES_128_GCM, dtls.SRTP_AES128_CM_HMAC_SHA1_80)
+	assert.Equal(t, &rtcerr.NotSupportedError{Err: ErrSRTPProtectionProfile}, err)
+
+	assert.NoError(t, s.SetSRTPProtectionProfiles(dtls.SRTP_AES128_CM_HMAC_SHA1_80))
+	assert.Equal(t, []dtls.SRTPProtectionProfile{dtls.SRTP_AES128_CM_HMAC_SHA1_80}, s.getSRTPProtectionProfiles())
+}
+
+func TestSetDTLSTimeouts(t *testing.T) {
+	s := SettingEngine{}
+	s.SetDTLSRetransmissionInterval(3 * time.Second)
+	s.SetDTLSHandshakeTimeout(time.Minute)
+
+	assert.Equal(t, 3*time.Second, s.dtls.retransmissionInterval)
+	assert.Equal(t, time.Minute, s.dtls.handshakeTimeout)
+}
+
+func TestSetSDPTransformers(t *testing.T) {
+	offerSettings := SettingEngine{}
+	offerSettings.SetLocalSDPTransformer(func(sdpType SDPType, desc *sdp.SessionDescription) error {
+		assert.Equal(t, SDPTypeOffer, sdpType)
+		desc.WithValueAttribute("x-local", "1")
+		return nil
+	})
+	offerPC, err := NewAPI(WithSettingEngine(offerSettings)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerSettings := SettingEngine{}
+	answerSettings.SetRemoteSDPTransformer(func(sdpType SDPType, desc *sdp.SessionDescription) error {
+		assert.Equal(t, SDPTypeOffer, sdpType)
+		_, ok := desc.Attribute("x-local")
+		assert.True(t, ok)
+		desc.WithValueAttribute("x-remote", "1")
+		return nil
+	})
+	answerPC, err := NewAPI(WithSettingEngine(answerSettings)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = offerPC.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=x-local:1")
+	assert.NoError(t, offerPC.SetLocalDescription(offer))
+
+	assert.NoError(t, answerPC.SetRemoteDescription(offer))
+	assert.Contains(t, answerPC.RemoteDescription().SDP, "a=x-remote:1")
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
+func TestSetICECandidates(t *testing.T) {
+	s := SettingEngine{}
+	assert.Error(t, s.SetICECandidates([]ICECandidate{{Address: "203.0.113.1", Port: 5000}}))
+
+	assert.NoError(t, s.SetICECandidates([]ICECandidate{
+		{Typ: ICECandidateTypeHost, Protocol: ICEProtocolUDP, Address: "203.0.113.1", Port: 5000},
+	}))
+	assert.Equal(t, 1, len(s.candidates.StaticCandidates))
+	assert.NotEqual(t, "", s.candidates.StaticCandidates[0].Foundation)
+	assert.NotEqual(t, uint32(0), s.candidates.StaticCandidates[0].Priority)
+
+	s.SetICECredentials("serverUfragServerUfrag", "serverPwdServerPwdServerPwd")
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	// The candidate is signaled before anything is gathered
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=ice-ufrag:serverUfragServerUfrag")
+	assert.Contains(t, offer.SDP, "203.0.113.1 5000 typ host")
+
+	assert.NoError(t, pc.Close())
+}
@@ -111,6 +111,16 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 		}
 	}
 
+	// have-local-offer->SetLocal(rollback)->stable
+	// have-remote-offer->SetRemote(rollback)->stable
+	if sdpType == SDPTypeRollback {
+		if next == SignalingStateStable &&
+			((cur == SignalingStateHaveLocalOffer && op == stateChangeOpSetLocal) ||
+				(cur == SignalingStateHaveRemoteOffer && op == stateChangeOpSetRemote)) {
+			return next, nil
+		}
+	}
+
 	// 4.3.1 valid state transitions
 	switch cur {
 	case SignalingStateStable:
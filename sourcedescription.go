@@ -0,0 +1,100 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// The SDES items that bind a source to a media section and a simulcast
+// encoding, RFC 8843 15.1 and RFC 8852 4.1
+const (
+	sdesRTPStreamID rtcp.SDESType = 12
+	sdesMID         rtcp.SDESType = 15
+)
+
+// defaultSDESHeaderExtensions are the header extensions offered in the media
+// sections that the remote didn't describe yet
+func defaultSDESHeaderExtensions() []RTPHeaderExtensionParameter {
+	return []RTPHeaderExtensionParameter{
+		{URI: sdesMidURI, ID: sdesMidExtensionID},
+		{URI: sdesRTPStreamIDURI, ID: sdesRTPStreamIDExtensionID},
+	}
+}
+
+// sdesHeaderExtensions returns the SDES header extensions of extensions
+func sdesHeaderExtensions(extensions []RTPHeaderExtensionParameter) []RTPHeaderExtensionParameter {
+	sdes := []RTPHeaderExtensionParameter{}
+	for _, ext := range extensions {
+		if ext.URI == sdesMidURI || ext.URI == sdesRTPStreamIDURI {
+			sdes = append(sdes, ext)
+		}
+	}
+	return sdes
+}
+
+// headerExtensionID returns the ID of the header extension uri in
+// extensions, 0 if it wasn't negotiated
+func headerExtensionID(extensions []RTPHeaderExtensionParameter, uri string) uint8 {
+	for _, ext := range extensions {
+		if ext.URI == uri && ext.ID > 0 && ext.ID < 256 {
+			return uint8(ext.ID)
+		}
+	}
+	return 0
+}
+
+// sourceDescription returns the SDES of the senders that are sending: the
+// CNAME of the PeerConnection and the MID and RID their packets carry
+func (pc *PeerConnection) sourceDescription() *rtcp.SourceDescription {
+	sdes := &rtcp.SourceDescription{}
+	for _, t := range pc.GetTransceivers() {
+		sender := t.Sender()
+		if sender == nil || !sender.hasSent() {
+			continue
+		}
+
+		sender.mu.RLock()
+		track, mid, rid := sender.track, sender.mid, sender.rid
+		sender.mu.RUnlock()
+		if track == nil {
+			continue
+		}
+
+		chunk := rtcp.SourceDescriptionChunk{
+			Source: track.SSRC(),
+			Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: pc.cname}},
+		}
+		if mid != "" {
+			chunk.Items = append(chunk.Items, rtcp.SourceDescriptionItem{Type: sdesMID, Text: mid})
+		}
+		if rid != "" {
+			chunk.Items = append(chunk.Items, rtcp.SourceDescriptionItem{Type: sdesRTPStreamID, Text: rid})
+		}
+		sdes.Chunks = append(sdes.Chunks, chunk)
+	}
+	return sdes
+}
+
+// sendSourceDescriptions sends the SDES of the senders every sdesInterval,
+// until the PeerConnection is closed
+func (pc *PeerConnection) sendSourceDescriptions() {
+	ticker := time.NewTicker(sdesInterval)
+	defer ticker.Stop()
+
+	for {
+		if sdes := pc.sourceDescription(); len(sdes.Chunks) != 0 {
+			if err := pc.WriteRTCP([]rtcp.Packet{sdes}); err != nil {
+				pc.log.Debugf("Failed to send SDES: %v", err)
+			}
+		}
+
+		select {
+		case <-pc.closed:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceDescription(t *testing.T) {
+	sendCalled := make(chan interface{})
+	close(sendCalled)
+
+	sending := &RTPSender{track: &Track{ssrc: 1000}, sendCalled: sendCalled}
+	sending.setHeaderExtensions("0", "hi", 4, 5)
+	idle := &RTPSender{track: &Track{ssrc: 2000}, sendCalled: make(chan interface{})}
+
+	pc := &PeerConnection{cname: "cname"}
+	for _, s := range []*RTPSender{sending, idle} {
+		transceiver := &RTPTransceiver{}
+		transceiver.setSender(s)
+		pc.rtpTransceivers = append(pc.rtpTransceivers, transceiver)
+	}
+
+	assert.Equal(t, &rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{
+		Source: 1000,
+		Items: []rtcp.SourceDescriptionItem{
+			{Type: rtcp.SDESCNAME, Text: "cname"},
+			{Type: sdesMID, Text: "0"},
+			{Type: sdesRTPStreamID, Text: "hi"},
+		},
+	}}}, pc.sourceDescription())
+}
+
+func TestRTPSenderHeaderExtensions(t *testing.T) {
+	s := &RTPSender{}
+	header := &rtp.Header{Version: 2, SSRC: 1000}
+	assert.Equal(t, header, s.withHeaderExtensions(header), "nothing negotiated")
+
+	s.setHeaderExtensions("1", "", 4, 5)
+	withMid := s.withHeaderExtensions(header)
+	assert.Equal(t, []byte("1"), withMid.GetExtension(4))
+	assert.Nil(t, withMid.GetExtension(5), "no RID to send")
+	assert.False(t, header.Extension, "the header of the Track is shared")
+
+	assert.Equal(t, uint8(4), headerExtensionID(defaultSDESHeaderExtensions(), sdesMidURI))
+	assert.Equal(t, uint8(0), headerExtensionID(nil, sdesMidURI))
+	assert.Equal(t, []RTPHeaderExtensionParameter{{URI: sdesRTPStreamIDURI, ID: 9}}, sdesHeaderExtensions([]RTPHeaderExtensionParameter{
+		{URI: "urn:ietf:params:rtp-hdrext:ssrc-audio-level", ID: 1},
+		{URI: sdesRTPStreamIDURI, ID: 9},
+	}))
+}
+
+func TestSendSourceDescriptions_Close(t *testing.T) {
+	pc := &PeerConnection{closed: make(chan interface{})}
+
+	done := make(chan struct{})
+	go func() {
+		pc.sendSourceDescriptions()
+		close(done)
+	}()
+
+	// Returns without waiting for the next sdesInterval
+	close(pc.closed)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sendSourceDescriptions didn't return on close")
+	}
+}
@@ -327,6 +327,11 @@ type InboundRTPStreamStats struct {
 	// these numbers are not expected to match the numbers seen on sending. Not all
 	// OSes make this information available.
 	PerDSCPPacketsReceived map[string]uint32 `json:"perDscpPacketsReceived"`
+
+	// EndToEndLatency is the latest time in seconds from the capture of a frame
+	// to the read of its packet, measured with the abs-capture-time header
+	// extension. It isn't part of the WebRTC statistics.
+	EndToEndLatency float64 `json:"endToEndLatency,omitempty"`
 }
 
 // QualityLimitationReason lists the reason for limiting the resolution and/or framerate.
@@ -736,6 +741,10 @@ type PeerConnectionStats struct {
 	// DataChannelsAccepted represents the number of unique DataChannels signaled
 	// in a "datachannel" event on the PeerConnection.
 	DataChannelsAccepted uint32 `json:"dataChannelsAccepted"`
+
+	// CNAME is the RTCP canonical name of the streams the PeerConnection sends,
+	// signaled in the SDP and in RTCP SDES to group them.
+	CNAME string `json:"cname"`
 }
 
 // DataChannelStats contains statistics related to each DataChannel ID.
@@ -779,6 +788,11 @@ type DataChannelStats struct {
 	// BytesReceived represents the total number of bytes received on this
 	// datachannel not including headers or padding.
 	BytesReceived uint64 `json:"bytesReceived"`
+
+	// BufferedAmountHighWater is the highest bufferedAmount seen on this
+	// datachannel, a non-standard statistic. It is sampled after every Send
+	// and SendText, and when stats are read for detached datachannels.
+	BufferedAmountHighWater uint64 `json:"bufferedAmountHighWater"`
 }
 
 // MediaStreamStats contains statistics related to a specific MediaStream.
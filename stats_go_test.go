@@ -239,6 +239,11 @@ func TestPeerConnection_GetStats(t *testing.T) {
 	assert.Equal(t, DataChannelStateOpen, dcStatsOffer.State)
 	assert.Equal(t, uint32(1), dcStatsOffer.MessagesSent)
 	assert.Equal(t, uint64(len(msg)), dcStatsOffer.BytesSent)
+	assert.LessOrEqual(t, dcStatsOffer.BufferedAmountHighWater, uint64(len(msg)))
+	offerDCStats := offerDC.Stats()
+	assert.Equal(t, dcStatsOffer.ID, offerDCStats.ID)
+	assert.Equal(t, uint32(1), offerDCStats.MessagesSent)
+	assert.Equal(t, dcStatsOffer.BufferedAmountHighWater, offerDCStats.BufferedAmountHighWater)
 	assert.NotEmpty(t, findLocalCandidateStats(reportPCOffer))
 	assert.NotEmpty(t, findRemoteCandidateStats(reportPCOffer))
 	assert.NotEmpty(t, findCandidatePairStats(t, reportPCOffer))
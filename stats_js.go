@@ -0,0 +1,100 @@
+// +build js,wasm
+
+package webrtc
+
+import (
+	"encoding/json"
+	"reflect"
+	"syscall/js"
+)
+
+// valueToStats converts a stats object of an RTCStatsReport to the Stats of
+// its type. The stats of other types, or that don't decode, are returned as
+// a map.
+func valueToStats(val js.Value) Stats {
+	if s, err := valueToTypedStats(val); err == nil && s != nil {
+		return s
+	}
+
+	var m map[string]interface{}
+	_ = json.Unmarshal([]byte(js.Global().Get("JSON").Call("stringify", val).String()), &m)
+	return m
+}
+
+func valueToTypedStats(val js.Value) (Stats, error) {
+	switch statsType := StatsType(valueToStringOrZero(val.Get("type"))); statsType {
+	case StatsTypeCodec:
+		return unmarshalStatsValue(val, &CodecStats{})
+	case StatsTypeInboundRTP:
+		return unmarshalStatsValue(val, &InboundRTPStreamStats{})
+	case StatsTypeOutboundRTP:
+		return unmarshalStatsValue(val, &OutboundRTPStreamStats{})
+	case StatsTypeRemoteInboundRTP:
+		return unmarshalStatsValue(val, &RemoteInboundRTPStreamStats{})
+	case StatsTypeRemoteOutboundRTP:
+		return unmarshalStatsValue(val, &RemoteOutboundRTPStreamStats{})
+	case StatsTypeCSRC:
+		return unmarshalStatsValue(val, &RTPContributingSourceStats{})
+	case StatsTypePeerConnection:
+		return unmarshalStatsValue(val, &PeerConnectionStats{})
+	case StatsTypeDataChannel:
+		// The enums are strings in JavaScript, they are converted instead of decoded
+		return unmarshalStatsValue(val, &DataChannelStats{
+			State: newDataChannelState(valueToStringOrZero(val.Get("state"))),
+		}, "state")
+	case StatsTypeStream:
+		return unmarshalStatsValue(val, &MediaStreamStats{})
+	case StatsTypeTrack, StatsTypeSender, StatsTypeReceiver:
+		return valueToMediaStats(val, statsType)
+	case StatsTypeTransport:
+		return unmarshalStatsValue(val, &TransportStats{
+			ICERole:   newICERole(valueToStringOrZero(val.Get("iceRole"))),
+			DTLSState: newDTLSTransportState(valueToStringOrZero(val.Get("dtlsState"))),
+		}, "iceRole", "dtlsState")
+	case StatsTypeCandidatePair:
+		return unmarshalStatsValue(val, &ICECandidatePairStats{})
+	case StatsTypeLocalCandidate, StatsTypeRemoteCandidate:
+		s := &ICECandidateStats{}
+		s.NetworkType, _ = NewNetworkType(valueToStringOrZero(val.Get("networkType")))
+		s.CandidateType, _ = NewICECandidateType(valueToStringOrZero(val.Get("candidateType")))
+		return unmarshalStatsValue(val, s, "networkType", "candidateType")
+	case StatsTypeCertificate:
+		return unmarshalStatsValue(val, &CertificateStats{})
+	default:
+		return nil, nil
+	}
+}
+
+// valueToMediaStats converts the track, sender and receiver stats, whose
+// Stats depend on the kind of the track and if it is received
+func valueToMediaStats(val js.Value, statsType StatsType) (Stats, error) {
+	audio := valueToStringOrZero(val.Get("kind")) == "audio"
+	received := statsType == StatsTypeReceiver || val.Get("remoteSource").Truthy()
+
+	switch {
+	case received && audio:
+		return unmarshalStatsValue(val, &AudioReceiverStats{})
+	case received:
+		return unmarshalStatsValue(val, &VideoReceiverStats{})
+	case statsType == StatsTypeSender && audio:
+		return unmarshalStatsValue(val, &AudioSenderStats{})
+	case statsType == StatsTypeSender:
+		return unmarshalStatsValue(val, &VideoSenderStats{})
+	case audio:
+		return unmarshalStatsValue(val, &SenderAudioTrackAttachmentStats{})
+	default:
+		return unmarshalStatsValue(val, &SenderVideoTrackAttachmentStats{})
+	}
+}
+
+// unmarshalStatsValue decodes the JSON of val, without the fields in skip,
+// into the Stats s points to, and returns them
+func unmarshalStatsValue(val js.Value, s interface{}, skip ...string) (Stats, error) {
+	object := js.Global().Get("Object")
+	copied := object.Call("assign", object.New(), val)
+	for _, field := range skip {
+		copied.Delete(field)
+	}
+	err := json.Unmarshal([]byte(js.Global().Get("JSON").Call("stringify", copied).String()), s)
+	return reflect.ValueOf(s).Elem().Interface(), err
+}
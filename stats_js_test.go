@@ -0,0 +1,45 @@
+package webrtc
+
+import (
+	"syscall/js"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueToStats(t *testing.T) {
+	parse := func(s string) js.Value {
+		return js.Global().Get("JSON").Call("parse", s)
+	}
+
+	assert.Equal(t, TransportStats{
+		Timestamp:   1000,
+		Type:        StatsTypeTransport,
+		ID:          "T01",
+		BytesSent:   1200,
+		ICERole:     ICERoleControlling,
+		DTLSState:   DTLSTransportStateConnected,
+		PacketsSent: 3,
+	}, valueToStats(parse(`{"timestamp":1000,"type":"transport","id":"T01","bytesSent":1200,"packetsSent":3,"iceRole":"controlling","dtlsState":"connected"}`)))
+
+	assert.Equal(t, ICECandidateStats{
+		Type:          StatsTypeLocalCandidate,
+		ID:            "C01",
+		IP:            "192.168.20.128",
+		Port:          47298,
+		CandidateType: ICECandidateTypeHost,
+		NetworkType:   NetworkTypeUDP4,
+	}, valueToStats(parse(`{"type":"local-candidate","id":"C01","ip":"192.168.20.128","port":47298,"candidateType":"host","networkType":"udp4"}`)))
+
+	assert.Equal(t, AudioReceiverStats{
+		Type:       StatsTypeTrack,
+		ID:         "R01",
+		AudioLevel: 0.5,
+	}, valueToStats(parse(`{"type":"track","id":"R01","kind":"audio","remoteSource":true,"audioLevel":0.5}`)))
+
+	// Stats without a type of their own are kept as they are
+	assert.Equal(t, map[string]interface{}{
+		"type": "media-source",
+		"id":   "S01",
+	}, valueToStats(parse(`{"type":"media-source","id":"S01"}`)))
+}
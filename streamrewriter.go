@@ -0,0 +1,106 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// StreamRewriter rewrites the RTP packets of one or more source streams so
+// they form a single continuous stream, like the packets of a remote Track
+// forwarded to a local Track. It sets the SSRC and payload type of the output
+// and shifts sequence numbers and timestamps. When the source SSRC changes the
+// offsets are recomputed so the output continues where it stopped, wrapping
+// around like RTP does. Late packets of the previous source are dropped
+// instead of switching back to it.
+type StreamRewriter struct {
+	mu sync.Mutex
+
+	ssrc        uint32
+	payloadType uint8
+	clockRate   uint32
+
+	started      bool
+	sourceSSRC   uint32
+	lastSentTime time.Time
+
+	// The last sequence number received from the source, and from the one
+	// before it
+	lastSourceSequenceNumber   uint16
+	previousSSRC               uint32
+	previousLastSequenceNumber uint16
+	hasPreviousSource          bool
+
+	sequenceNumberOffset uint16
+	timestampOffset      uint32
+	lastSequenceNumber   uint16
+	lastTimestamp        uint32
+}
+
+// NewStreamRewriter creates a StreamRewriter emitting ssrc and payloadType,
+// clockRate is used to advance the timestamps across a change of source.
+func NewStreamRewriter(ssrc uint32, payloadType uint8, clockRate uint32) *StreamRewriter {
+	return &StreamRewriter{
+		ssrc:        ssrc,
+		payloadType: payloadType,
+		clockRate:   clockRate,
+	}
+}
+
+// NewStreamRewriterForTrack creates a StreamRewriter for packets written to
+// the local Track t
+func NewStreamRewriterForTrack(t *Track) *StreamRewriter {
+	return NewStreamRewriter(t.SSRC(), t.PayloadType(), t.Codec().ClockRate)
+}
+
+// Rewrite modifies the header of p in place. It returns false, leaving p
+// unmodified, when p is a late packet of the source switched from, one that
+// isn't newer than the last packet received from it. Such packets must be
+// dropped.
+func (r *StreamRewriter) Rewrite(p *rtp.Packet) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if !r.started {
+		r.started = true
+		r.sourceSSRC = p.SSRC
+		r.lastSourceSequenceNumber = p.SequenceNumber
+	} else if p.SSRC != r.sourceSSRC {
+		if r.hasPreviousSource && p.SSRC == r.previousSSRC && int16(p.SequenceNumber-r.previousLastSequenceNumber) <= 0 {
+			return false
+		}
+
+		// Continue right after the last packet sent, advancing the timestamp
+		// by the time elapsed since then
+		elapsed := uint32(now.Sub(r.lastSentTime).Seconds() * float64(r.clockRate))
+		if elapsed == 0 {
+			elapsed = 1
+		}
+
+		r.previousSSRC, r.previousLastSequenceNumber, r.hasPreviousSource = r.sourceSSRC, r.lastSourceSequenceNumber, true
+		r.sourceSSRC = p.SSRC
+		r.lastSourceSequenceNumber = p.SequenceNumber
+		r.sequenceNumberOffset = r.lastSequenceNumber + 1 - p.SequenceNumber
+		r.timestampOffset = r.lastTimestamp + elapsed - p.Timestamp
+	} else if int16(p.SequenceNumber-r.lastSourceSequenceNumber) > 0 {
+		r.lastSourceSequenceNumber = p.SequenceNumber
+	}
+
+	p.SSRC = r.ssrc
+	p.PayloadType = r.payloadType
+	p.SequenceNumber += r.sequenceNumberOffset
+	p.Timestamp += r.timestampOffset
+
+	// Only move forward, retransmitted or reordered packets keep their place
+	if !r.lastSentTime.IsZero() && int16(p.SequenceNumber-r.lastSequenceNumber) <= 0 {
+		return true
+	}
+	r.lastSequenceNumber = p.SequenceNumber
+	r.lastTimestamp = p.Timestamp
+	r.lastSentTime = now
+	return true
+}
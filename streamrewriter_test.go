@@ -0,0 +1,56 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamRewriter(t *testing.T) {
+	r := NewStreamRewriter(0xDEADBEEF, DefaultPayloadTypeVP8, 90000)
+
+	rewrite := func(ssrc uint32, sequenceNumber uint16, timestamp uint32) rtp.Header {
+		p := &rtp.Packet{Header: rtp.Header{SSRC: ssrc, PayloadType: 100, SequenceNumber: sequenceNumber, Timestamp: timestamp}}
+		assert.True(t, r.Rewrite(p))
+
+		assert.Equal(t, uint32(0xDEADBEEF), p.SSRC)
+		assert.Equal(t, uint8(DefaultPayloadTypeVP8), p.PayloadType)
+		return p.Header
+	}
+
+	// The first source is only relabeled, wrapping around as it does
+	h := rewrite(1, 65534, 4294967000)
+	assert.Equal(t, uint16(65534), h.SequenceNumber)
+	assert.Equal(t, uint32(4294967000), h.Timestamp)
+
+	h = rewrite(1, 1, 100)
+	assert.Equal(t, uint16(1), h.SequenceNumber)
+	assert.Equal(t, uint32(100), h.Timestamp)
+
+	// A reordered packet keeps its place
+	h = rewrite(1, 0, 50)
+	assert.Equal(t, uint16(0), h.SequenceNumber)
+
+	// A new source continues the sequence numbers and timestamps
+	h = rewrite(2, 5000, 123456)
+	assert.Equal(t, uint16(2), h.SequenceNumber)
+	assert.True(t, h.Timestamp > 100)
+	switchTimestamp := h.Timestamp
+
+	// A late packet of the previous source is dropped, it doesn't switch back
+	late := &rtp.Packet{Header: rtp.Header{SSRC: 1, SequenceNumber: 0, Timestamp: 50}}
+	assert.False(t, r.Rewrite(late))
+	assert.Equal(t, uint32(1), late.SSRC)
+
+	h = rewrite(2, 5001, 126456)
+	assert.Equal(t, uint16(3), h.SequenceNumber)
+	assert.Equal(t, switchTimestamp+3000, h.Timestamp)
+
+	// Switching back recomputes the offsets again
+	h = rewrite(1, 2, 200)
+	assert.Equal(t, uint16(4), h.SequenceNumber)
+	assert.True(t, h.Timestamp > switchTimestamp+3000)
+}
@@ -1,13 +1,18 @@
+//go:build !js
 // +build !js
 
 package webrtc
 
 import (
-	"fmt"
+	"context"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
+	"github.com/pion/transport/packetio"
 	"github.com/pion/webrtc/v2/pkg/media"
 )
 
@@ -17,6 +22,25 @@ const (
 	trackDefaultLabelLength = 16
 )
 
+// receiveBufferPool holds the buffers of the convenience read methods, which
+// copy what they read out of the buffer before putting it back
+var receiveBufferPool = sync.Pool{
+	New: func() interface{} { return new([receiveMTU]byte) },
+}
+
+// readPooled calls read with a pooled buffer and returns a copy of what was
+// read, sized to the packet instead of to receiveMTU
+func readPooled(read func([]byte) (int, error)) ([]byte, error) {
+	b := receiveBufferPool.Get().(*[receiveMTU]byte)
+	defer receiveBufferPool.Put(b)
+
+	i, err := read(b[:])
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b[:i]...), nil
+}
+
 // Track represents a single media track
 type Track struct {
 	mu sync.RWMutex
@@ -25,14 +49,128 @@ type Track struct {
 	payloadType uint8
 	kind        RTPCodecType
 	label       string
+	streamIDs   []string
+	rid         string
 	ssrc        uint32
 	codec       *RTPCodec
 
-	packetizer rtp.Packetizer
+	// remoteCodecs and headerExtensions are what the remote negotiated for
+	// the media section of a remote Track
+	remoteCodecs     []RTPCodecParameters
+	headerExtensions []RTPHeaderExtensionParameter
+
+	// orientation is the last one the packets of a remote video Track carried
+	orientation            VideoOrientation
+	onVideoOrientationHdlr func(VideoOrientation)
+
+	// captureTime is the last one the packets of a remote Track carried,
+	// latency the end-to-end latency measured from it
+	captureTime *AbsoluteCaptureTime
+	latency     time.Duration
+
+	packetizer  rtp.Packetizer
+	sequencer   rtp.Sequencer
+	layerFilter LayerFilter
+	disabled    bool
+
+	// skipped removes the packets dropped while disabled from the sequence
+	// numbers sent
+	skipped skippedSequencer
+
+	// timestamp is the RTP timestamp of the next sample, DTMF tones are sent
+	// on the timeline of the samples
+	timestamp uint32
+
+	muted        bool
+	muteTimer    *time.Timer
+	onMuteHdlr   func()
+	onUnmuteHdlr func()
+
+	ended       bool
+	endedIdle   bool // ended by trackEndedTimeout, not for good
+	endTimer    *time.Timer
+	onEndedHdlr func()
+
+	inactivityTimeout time.Duration
+	inactivityPLI     bool
+	inactivityTimer   *time.Timer
+	inactive          bool
+	onInactiveHdlr    func()
 
 	receiver         *RTPReceiver
+	buffer           *packetio.Buffer // set once the remote track has been cloned
 	activeSenders    []*RTPSender
 	totalSenderCount int // count of all senders (accounts for senders that have not been started yet)
+
+	// fanOut holds the *trackFanOut WriteRTP reads without taking mu
+	fanOut atomic.Value
+}
+
+// trackFanOut is what WriteRTP needs to send a packet. It is replaced rather
+// than modified, so forwarding at high packet rates to many senders doesn't
+// serialize on the Track mutex.
+type trackFanOut struct {
+	senders          []*RTPSender
+	totalSenderCount int
+	layerFilter      LayerFilter
+	disabled         bool
+}
+
+// updateFanOut publishes the senders, layerFilter and disabled to WriteRTP,
+// it must be called with mu held after changing them
+func (t *Track) updateFanOut() {
+	t.fanOut.Store(&trackFanOut{
+		senders:          t.activeSenders,
+		totalSenderCount: t.totalSenderCount,
+		layerFilter:      t.layerFilter,
+		disabled:         t.disabled,
+	})
+}
+
+func (t *Track) loadFanOut() *trackFanOut {
+	if fanOut, ok := t.fanOut.Load().(*trackFanOut); ok {
+		return fanOut
+	}
+	return &trackFanOut{}
+}
+
+// skippedSequencer hides the packets dropped while a Track is disabled from
+// the sequence numbers of the packets sent after
+type skippedSequencer struct {
+	mu                 sync.Mutex
+	started            bool
+	lastSequenceNumber uint16
+	offset             uint16
+}
+
+// skip drops the packet with sequenceNumber
+func (s *skippedSequencer) skip(sequenceNumber uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Nothing was sent yet, the remote can't see a gap
+	if s.started && int16(sequenceNumber-s.lastSequenceNumber) > 0 {
+		s.offset += sequenceNumber - s.lastSequenceNumber
+		s.lastSequenceNumber = sequenceNumber
+	}
+}
+
+// shift returns p with the skipped packets removed from its sequence number,
+// a copy once packets were skipped
+func (s *skippedSequencer) shift(p *rtp.Packet) *rtp.Packet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started || int16(p.SequenceNumber-s.lastSequenceNumber) > 0 {
+		s.started = true
+		s.lastSequenceNumber = p.SequenceNumber
+	}
+	if s.offset == 0 {
+		return p
+	}
+	shifted := *p
+	shifted.SequenceNumber -= s.offset
+	return &shifted
 }
 
 // ID gets the ID of the track
@@ -63,6 +201,21 @@ func (t *Track) Label() string {
 	return t.label
 }
 
+// StreamIDs gets the IDs of the streams a remote track belongs to, as
+// signaled by the msid attributes of the SDP
+func (t *Track) StreamIDs() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]string{}, t.streamIDs...)
+}
+
+// RID gets the RTP stream ID of a remote track, if the remote signaled one
+func (t *Track) RID() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.rid
+}
+
 // SSRC gets the SSRC of the track
 func (t *Track) SSRC() uint32 {
 	t.mu.RLock()
@@ -77,6 +230,34 @@ func (t *Track) Codec() *RTPCodec {
 	return t.codec
 }
 
+// CodecParameters returns the codec the Track is sent with. For a remote
+// Track it is the one the remote negotiated for the payload type of its
+// packets, with the fmtp and RTCP feedback of the remote, once the first
+// packet was received.
+func (t *Track) CodecParameters() RTPCodecParameters {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	// The codec of a remote Track is set once its payload type is known
+	if t.codec == nil {
+		return RTPCodecParameters{}
+	}
+	for _, c := range t.remoteCodecs {
+		if c.PayloadType == t.payloadType {
+			return c
+		}
+	}
+	return RTPCodecParameters{RTPCodecCapability: t.codec.RTPCodecCapability, PayloadType: t.payloadType}
+}
+
+// HeaderExtensions returns the RTP header extensions the remote negotiated
+// for the media section of a remote Track, and nil for a local Track
+func (t *Track) HeaderExtensions() []RTPHeaderExtensionParameter {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.headerExtensions
+}
+
 // Packetizer gets the Packetizer of the track
 func (t *Track) Packetizer() rtp.Packetizer {
 	t.mu.RLock()
@@ -84,30 +265,365 @@ func (t *Track) Packetizer() rtp.Packetizer {
 	return t.packetizer
 }
 
+// SetLayerFilter sets a LayerFilter that decides which of the packets written
+// to this local Track are sent, nil forwards all of them.
+func (t *Track) SetLayerFilter(f LayerFilter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.layerFilter = f
+	t.updateFanOut()
+}
+
 // Read reads data from the track. If this is a local track this will error
 func (t *Track) Read(b []byte) (n int, err error) {
 	t.mu.RLock()
 	r := t.receiver
+	buffer := t.buffer
 
 	if t.totalSenderCount != 0 || r == nil {
 		t.mu.RUnlock()
-		return 0, fmt.Errorf("this is a local track and must not be read from")
+		return 0, ErrReadLocalTrack
+	}
+	t.mu.RUnlock()
+
+	if buffer != nil {
+		n, err = buffer.Read(b)
+	} else {
+		n, err = r.readRTP(b)
+	}
+	t.observeRead(err)
+	if err != nil {
+		return n, err
+	}
+	t.observeHeaderExtensions(b[:n])
+	if buffer == nil {
+		return n, err
+	}
+	return r.transformRTP(b, n)
+}
+
+// ReadContext reads data from the track like Read, until ctx is done. The
+// first call moves the Track to a buffer of the incoming packets, it must not
+// be called concurrently with Read or ReadContext.
+func (t *Track) ReadContext(ctx context.Context, b []byte) (n int, err error) {
+	t.mu.RLock()
+	r := t.receiver
+	if t.totalSenderCount != 0 || r == nil {
+		t.mu.RUnlock()
+		return 0, ErrReadLocalTrack
+	}
+	t.mu.RUnlock()
+
+	select {
+	case <-r.received:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	buffer, err := r.bufferTrack(t)
+	if err != nil {
+		return 0, err
+	}
+	n, err = readContext(ctx, buffer, b)
+	// A canceled read says nothing about the incoming packets
+	if err == nil || ctx.Err() == nil {
+		t.observeRead(err)
+	}
+	if err != nil {
+		return n, err
+	}
+	t.observeHeaderExtensions(b[:n])
+	return r.transformRTP(b, n)
+}
+
+// SetEnabled stops sending the packets written to a local Track while
+// enabled is false, the packets are dropped as if they were never written.
+// The sequence numbers sent after skip them, so the remote doesn't see them
+// as lost. It notices the Track is muted when no packet arrives anymore.
+func (t *Track) SetEnabled(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.disabled = !enabled
+	t.updateFanOut()
+}
+
+// Enabled reports if the packets written to a local Track are sent
+func (t *Track) Enabled() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return !t.disabled
+}
+
+// Muted reports if no packet was read from a remote Track for a while
+func (t *Track) Muted() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.muted
+}
+
+// OnMute sets a handler called when no packet was read from a remote Track
+// for two seconds, like when the remote disabled it. Packets arrive as the
+// Track is read, so a Track has to be read continuously to detect it.
+func (t *Track) OnMute(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onMuteHdlr = f
+}
+
+// OnUnmute sets a handler called when a packet is read from a remote Track
+// again after it was muted
+func (t *Track) OnUnmute(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onUnmuteHdlr = f
+}
+
+// Ended reports if a remote Track ended, see OnEnded
+func (t *Track) Ended() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.ended
+}
+
+// OnEnded sets a handler called when a remote Track ends: the remote sent an
+// RTCP BYE for it, its RTPReceiver was stopped, or no packet was read from it
+// for 30 seconds. The BYE arrives as the RTCP of the RTPReceiver is read, see
+// RTPReceiver.OnRTCP. The first two end the Track for good, after 30 seconds
+// of silence it isn't ended anymore once a packet is read, and may end again.
+func (t *Track) OnEnded(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onEndedHdlr = f
+}
+
+// SetInactivityTimeout watches a remote Track for gaps of timeout without
+// packets, shorter or longer than the ones that mute and end it. The
+// OnInactive handler is called once per gap. With sendPLI a Picture Loss
+// Indication is sent for the Track as well, a sender that is still there
+// answers it with a keyframe, so a dead Track can be told from a muted one.
+// Zero stops watching. Packets arrive as the Track is read, so a Track has
+// to be read continuously.
+func (t *Track) SetInactivityTimeout(timeout time.Duration, sendPLI bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inactivityTimeout = timeout
+	t.inactivityPLI = sendPLI
+	if t.inactivityTimer != nil {
+		t.inactivityTimer.Stop()
+		t.inactivityTimer = nil
+	}
+	if timeout != 0 && t.muteTimer != nil {
+		t.inactivityTimer = time.AfterFunc(timeout, t.inactivityElapsed)
+	}
+}
+
+// OnInactive sets a handler called when no packet was read from a remote
+// Track for the timeout set by SetInactivityTimeout
+func (t *Track) OnInactive(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onInactiveHdlr = f
+}
+
+// observeRead tracks packet arrival for mute, inactivity and end detection,
+// err is the result of a read from the incoming packets
+func (t *Track) observeRead(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err != nil {
+		if t.muteTimer != nil {
+			t.muteTimer.Stop()
+		}
+		if t.endTimer != nil {
+			t.endTimer.Stop()
+		}
+		if t.inactivityTimer != nil {
+			t.inactivityTimer.Stop()
+		}
+		return
+	}
+
+	t.inactive = false
+	switch {
+	case t.inactivityTimeout == 0:
+	case t.inactivityTimer == nil:
+		t.inactivityTimer = time.AfterFunc(t.inactivityTimeout, t.inactivityElapsed)
+	default:
+		t.inactivityTimer.Reset(t.inactivityTimeout)
+	}
+
+	if t.muteTimer == nil {
+		t.muteTimer = time.AfterFunc(trackMuteTimeout, t.mute)
+		t.endTimer = time.AfterFunc(trackEndedTimeout, t.endIdle)
+	} else {
+		t.muteTimer.Reset(trackMuteTimeout)
+		t.endTimer.Reset(trackEndedTimeout)
+	}
+
+	if t.muted {
+		t.muted = false
+		if hdlr := t.onUnmuteHdlr; hdlr != nil {
+			go hdlr()
+		}
+	}
+
+	if t.endedIdle {
+		t.ended, t.endedIdle = false, false
+	}
+}
+
+func (t *Track) mute() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.muted {
+		return
+	}
+	t.muted = true
+	if hdlr := t.onMuteHdlr; hdlr != nil {
+		go hdlr()
+	}
+}
+
+func (t *Track) inactivityElapsed() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.inactive || t.inactivityTimeout == 0 {
+		return
+	}
+	t.inactive = true
+	if hdlr := t.onInactiveHdlr; hdlr != nil {
+		go hdlr()
+	}
+	if r := t.receiver; t.inactivityPLI && r != nil {
+		go func(ssrc uint32) {
+			_ = r.writeRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}})
+		}(t.ssrc)
+	}
+}
+
+// end ends the Track for good, for a BYE or a stopped RTPReceiver
+func (t *Track) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	wasEnded := t.ended
+	t.ended, t.endedIdle = true, false
+	if t.endTimer != nil {
+		t.endTimer.Stop()
+	}
+	if hdlr := t.onEndedHdlr; hdlr != nil && !wasEnded {
+		go hdlr()
+	}
+}
+
+// endIdle ends the Track until a packet is read again
+func (t *Track) endIdle() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ended {
+		return
+	}
+	t.ended, t.endedIdle = true, true
+	if hdlr := t.onEndedHdlr; hdlr != nil {
+		go hdlr()
+	}
+}
+
+// Clone returns a new remote Track receiving the same RTP packets as t. Each
+// Track reads the packets independently, so one incoming Track can be
+// forwarded by several consumers. A Read that is blocked while the first
+// clone is created may still receive the next packet only on t.
+func (t *Track) Clone() (*Track, error) {
+	t.mu.RLock()
+	r := t.receiver
+	clone := &Track{
+		id:          t.id,
+		payloadType: t.payloadType,
+		kind:        t.kind,
+		label:       t.label,
+		streamIDs:   t.streamIDs,
+		rid:         t.rid,
+		ssrc:        t.ssrc,
+		codec:       t.codec,
+		packetizer:  t.packetizer,
+		receiver:    r,
+
+		remoteCodecs:     t.remoteCodecs,
+		headerExtensions: t.headerExtensions,
 	}
 	t.mu.RUnlock()
 
-	return r.readRTP(b)
+	if r == nil {
+		return nil, ErrCloneLocalTrack
+	} else if err := r.addTrackBuffer(clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// setDetails updates a remote track with what the SDP signaled for it, t.mu must be held
+func (t *Track) setDetails(incoming trackDetails) {
+	t.id = incoming.id
+	t.label = incoming.label
+	t.streamIDs = incoming.streamIDs
+	t.rid = incoming.rid
+	t.remoteCodecs = incoming.codecs
+	t.headerExtensions = incoming.headerExtensions
+}
+
+func (t *Track) setBuffer(buffer *packetio.Buffer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buffer = buffer
 }
 
-// ReadRTP is a convenience method that wraps Read and unmarshals for you
+// ReadRTP is a convenience method that wraps Read and unmarshals for you.
+// The returned packet owns its memory, use ReadRTPInto to avoid allocating
+// for every packet.
 func (t *Track) ReadRTP() (*rtp.Packet, error) {
-	b := make([]byte, receiveMTU)
+	b, err := readPooled(t.Read)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &rtp.Packet{}
+	if err := r.Unmarshal(b); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReadRTPInto reads the next packet into b and unmarshals it into p without
+// allocating. b should be at least as large as the MTU, the payload and
+// header extensions of p point into b so it must not be reused while p is
+// in use.
+func (t *Track) ReadRTPInto(b []byte, p *rtp.Packet) error {
 	i, err := t.Read(b)
+	if err != nil {
+		return err
+	}
+
+	// Unmarshal appends to the header extensions of the previous packet
+	p.Extensions = p.Extensions[:0]
+	return p.Unmarshal(b[:i])
+}
+
+// ReadRTPContext is ReadRTP with a context, see ReadContext
+func (t *Track) ReadRTPContext(ctx context.Context) (*rtp.Packet, error) {
+	b, err := readPooled(func(b []byte) (int, error) {
+		return t.ReadContext(ctx, b)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	r := &rtp.Packet{}
-	if err := r.Unmarshal(b[:i]); err != nil {
+	if err := r.Unmarshal(b); err != nil {
 		return nil, err
 	}
 	return r, nil
@@ -132,6 +648,11 @@ func (t *Track) Write(b []byte) (n int, err error) {
 // WriteSample packetizes and writes to the track
 func (t *Track) WriteSample(s media.Sample) error {
 	packets := t.packetizer.Packetize(s.Data, s.Samples)
+	if len(packets) != 0 {
+		t.mu.Lock()
+		t.timestamp = packets[0].Timestamp + s.Samples
+		t.mu.Unlock()
+	}
 	for _, p := range packets {
 		err := t.WriteRTP(p)
 		if err != nil {
@@ -144,20 +665,30 @@ func (t *Track) WriteSample(s media.Sample) error {
 
 // WriteRTP writes RTP packets to the track
 func (t *Track) WriteRTP(p *rtp.Packet) error {
-	t.mu.RLock()
+	// The receiver is only set when the Track is created
 	if t.receiver != nil {
-		t.mu.RUnlock()
-		return fmt.Errorf("this is a remote track and must not be written to")
+		return ErrWriteRemoteTrack
 	}
-	senders := t.activeSenders
-	totalSenderCount := t.totalSenderCount
-	t.mu.RUnlock()
+	fanOut := t.loadFanOut()
 
-	if totalSenderCount == 0 {
+	if fanOut.totalSenderCount == 0 {
 		return io.ErrClosedPipe
+	} else if fanOut.disabled {
+		t.skipped.skip(p.SequenceNumber)
+		return nil
+	}
+	p = t.skipped.shift(p)
+
+	if fanOut.layerFilter != nil {
+		// The filter may rewrite the header, don't modify the packet of the caller
+		filtered := *p
+		if forward, err := fanOut.layerFilter.Filter(&filtered); err != nil || !forward {
+			return err
+		}
+		p = &filtered
 	}
 
-	for _, s := range senders {
+	for _, s := range fanOut.senders {
 		_, err := s.SendRTP(&p.Header, p.Payload)
 		if err != nil {
 			return err
@@ -170,15 +701,16 @@ func (t *Track) WriteRTP(p *rtp.Packet) error {
 // NewTrack initializes a new *Track
 func NewTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec) (*Track, error) {
 	if ssrc == 0 {
-		return nil, fmt.Errorf("SSRC supplied to NewTrack() must be non-zero")
+		return nil, ErrZeroSSRC
 	}
 
+	sequencer := rtp.NewRandomSequencer()
 	packetizer := rtp.NewPacketizer(
 		rtpOutboundMTU,
 		payloadType,
 		ssrc,
 		codec.Payloader,
-		rtp.NewRandomSequencer(),
+		sequencer,
 		codec.ClockRate,
 	)
 
@@ -190,19 +722,21 @@ func NewTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec)
 		ssrc:        ssrc,
 		codec:       codec,
 		packetizer:  packetizer,
+		sequencer:   sequencer,
 	}, nil
 }
 
-// determinePayloadType blocks and reads a single packet to determine the PayloadType for this Track
-// this is useful if we are dealing with a remote track and we can't announce it to the user until we know the payloadType
+// determinePayloadType blocks until the first packet arrives to determine the PayloadType for this Track
+// this is useful if we are dealing with a remote track and we can't announce it to the user until we know the payloadType.
+// The packet is still returned by the first read of the Track.
 func (t *Track) determinePayloadType() error {
-	r, err := t.ReadRTP()
+	payloadType, err := t.receiver.peekPayloadType()
 	if err != nil {
 		return err
 	}
 
 	t.mu.Lock()
-	t.payloadType = r.PayloadType
+	t.payloadType = payloadType
 	defer t.mu.Unlock()
 
 	return nil
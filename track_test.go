@@ -3,9 +3,14 @@
 package webrtc
 
 import (
+	"io"
 	"math/rand"
 	"testing"
+	"time"
 
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -126,5 +131,238 @@ func TestTrackReadWhenNotAdded(t *testing.T) {
 	assert.NoError(t, err)
 
 	_, err = track.Read([]byte{})
-	assert.Error(t, err)
+	assert.Equal(t, ErrReadLocalTrack, err)
+}
+
+func TestNewTrackZeroSSRC(t *testing.T) {
+	peerConnection, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = peerConnection.NewTrack(DefaultPayloadTypeOpus, 0, "audio", "pion")
+	assert.Equal(t, ErrZeroSSRC, err)
+
+	assert.NoError(t, peerConnection.Close())
+}
+
+func TestTrackSetEnabled(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+	assert.True(t, track.Enabled())
+
+	track.SetEnabled(false)
+	assert.False(t, track.Enabled())
+	track.SetEnabled(true)
+	assert.True(t, track.Enabled())
+}
+
+func TestTrackSetEnabled_Send(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	received := make(chan *rtp.Packet, 100)
+	pcAnswer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		for {
+			p, readErr := track.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			received <- p
+		}
+	})
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// The payload tells when a packet was written: 1 before disabling, 2
+	// while disabled, 3 after enabling again
+	var last *rtp.Packet
+	for last == nil {
+		assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{1}, Samples: 960}))
+		select {
+		case last = <-received:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	track.SetEnabled(false)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{2}, Samples: 960}))
+	}
+	track.SetEnabled(true)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{3}, Samples: 960}))
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for enabledAgain := 0; enabledAgain < 10; {
+		p := <-received
+		assert.NotEqual(t, byte(2), p.Payload[0], "packets written while disabled aren't sent")
+		assert.Equal(t, last.SequenceNumber+1, p.SequenceNumber, "no gap in the sequence numbers")
+		if p.Payload[0] == 3 {
+			enabledAgain++
+		}
+		last = p
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestTrackFanOut(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pc.NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion")
+	assert.NoError(t, err)
+	assert.Equal(t, io.ErrClosedPipe, track.WriteRTP(&rtp.Packet{}))
+
+	// WriteRTP sees the senders and settings as they change
+	_, err = pc.AddTrack(track)
+	assert.NoError(t, err)
+	fanOut := track.loadFanOut()
+	assert.Equal(t, 1, fanOut.totalSenderCount)
+	assert.Empty(t, fanOut.senders, "the sender isn't started")
+
+	track.SetEnabled(false)
+	assert.True(t, track.loadFanOut().disabled)
+	assert.False(t, fanOut.disabled, "published fan-outs aren't modified")
+	assert.NoError(t, track.WriteRTP(&rtp.Packet{}))
+	assert.NoError(t, pc.Close())
+}
+
+func TestTrackMute(t *testing.T) {
+	track := &Track{}
+
+	muted, unmuted := make(chan struct{}, 1), make(chan struct{}, 1)
+	track.OnMute(func() { muted <- struct{}{} })
+	track.OnUnmute(func() { unmuted <- struct{}{} })
+
+	track.observeRead(nil)
+	assert.False(t, track.Muted())
+
+	// Called when the timer fires
+	track.mute()
+	<-muted
+	assert.True(t, track.Muted())
+	track.mute()
+
+	track.observeRead(nil)
+	<-unmuted
+	assert.False(t, track.Muted())
+
+	// A read error stops watching for arrivals
+	track.observeRead(io.EOF)
+	assert.False(t, track.muteTimer.Stop())
+	assert.Empty(t, muted)
+}
+
+func TestTrackEnded(t *testing.T) {
+	track := &Track{ssrc: 5000}
+	clone := &Track{ssrc: 5000}
+	r := &RTPReceiver{track: track, clones: []*Track{clone}, closed: make(chan interface{}), received: make(chan interface{})}
+
+	ended := make(chan struct{}, 2)
+	track.OnEnded(func() { ended <- struct{}{} })
+	clone.OnEnded(func() { ended <- struct{}{} })
+
+	track.observeRead(nil)
+	assert.False(t, track.Ended())
+
+	// Called for a BYE, or when the timer fires
+	track.end()
+	<-ended
+	assert.True(t, track.Ended())
+	assert.False(t, track.endTimer.Stop(), "the timer is stopped")
+
+	// Stopping the RTPReceiver ends its clones too, each Track ends once
+	assert.NoError(t, r.Stop())
+	<-ended
+	assert.True(t, clone.Ended())
+	assert.Empty(t, ended)
+}
+
+func TestTrackEnded_Idle(t *testing.T) {
+	track := &Track{ssrc: 5000}
+	ended := make(chan struct{}, 2)
+	track.OnEnded(func() { ended <- struct{}{} })
+
+	// Called when no packet was read for trackEndedTimeout
+	track.observeRead(nil)
+	track.endIdle()
+	<-ended
+	assert.True(t, track.Ended())
+
+	// Packets resume, the Track can end again
+	track.observeRead(nil)
+	assert.False(t, track.Ended())
+	track.endIdle()
+	<-ended
+	assert.True(t, track.Ended())
+
+	// A BYE ends it for good
+	track.observeRead(nil)
+	track.end()
+	<-ended
+	track.observeRead(nil)
+	track.endIdle()
+	assert.True(t, track.Ended())
+	assert.Empty(t, ended)
+
+	track.observeRead(io.EOF)
+}
+
+func TestTrackInactivity(t *testing.T) {
+	track := &Track{}
+	inactive := make(chan struct{}, 1)
+	track.OnInactive(func() { inactive <- struct{}{} })
+
+	track.SetInactivityTimeout(20*time.Millisecond, false)
+	assert.Nil(t, track.inactivityTimer, "not watching before the first read")
+
+	track.observeRead(nil)
+	<-inactive
+	// Once per gap
+	track.inactivityElapsed()
+	assert.Empty(t, inactive)
+
+	track.observeRead(nil)
+	<-inactive
+
+	track.SetInactivityTimeout(0, false)
+	track.observeRead(nil)
+	assert.Nil(t, track.inactivityTimer)
+	track.inactivityElapsed()
+	assert.Empty(t, inactive)
+}
+
+func TestTrackCodecParameters(t *testing.T) {
+	local, err := NewTrack(DefaultPayloadTypeVP8, 1, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	params := local.CodecParameters()
+	assert.Equal(t, DefaultPayloadTypeVP8, params.PayloadType)
+	assert.Equal(t, "video/VP8", params.MimeType)
+	assert.Nil(t, local.HeaderExtensions())
+
+	remote := &Track{receiver: &RTPReceiver{}, payloadType: 96}
+	remote.setDetails(trackDetails{
+		codecs: []RTPCodecParameters{
+			{RTPCodecCapability: RTPCodecCapability{MimeType: "video/H264", SDPFmtpLine: "packetization-mode=1"}, PayloadType: 96},
+		},
+		headerExtensions: []RTPHeaderExtensionParameter{{URI: "urn:ietf:params:rtp-hdrext:sdes:mid", ID: 3}},
+	})
+	assert.Equal(t, RTPCodecParameters{}, remote.CodecParameters(), "the payload type isn't known yet")
+
+	remote.codec = NewRTPH264Codec(96, 90000)
+	params = remote.CodecParameters()
+	assert.Equal(t, "packetization-mode=1", params.SDPFmtpLine, "the fmtp of the remote is kept")
+	assert.Equal(t, []RTPHeaderExtensionParameter{{URI: "urn:ietf:params:rtp-hdrext:sdes:mid", ID: 3}}, remote.HeaderExtensions())
 }
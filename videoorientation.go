@@ -0,0 +1,96 @@
+// +build !js
+
+package webrtc
+
+// The bits of the coordination of video orientation, 3GPP TS 26.114 7.4.5
+const (
+	videoOrientationCamera   = 0x08
+	videoOrientationFlip     = 0x04
+	videoOrientationRotation = 0x03
+)
+
+// VideoOrientation is the orientation of the video sent in the
+// urn:3gpp:video-orientation header extension, usually by mobile devices
+// that send the frames as captured instead of rotating them.
+type VideoOrientation struct {
+	// Rotation is how many degrees the frames are rotated clockwise before
+	// rendering: 0, 90, 180 or 270
+	Rotation uint16
+	// Flip tells the frames are mirrored horizontally before rendering
+	Flip bool
+	// BackCamera tells the video comes from a back-facing camera
+	BackCamera bool
+}
+
+func (o VideoOrientation) marshal() byte {
+	b := byte(o.Rotation/90) & videoOrientationRotation
+	if o.Flip {
+		b |= videoOrientationFlip
+	}
+	if o.BackCamera {
+		b |= videoOrientationCamera
+	}
+	return b
+}
+
+func unmarshalVideoOrientation(b byte) VideoOrientation {
+	return VideoOrientation{
+		Rotation:   uint16(b&videoOrientationRotation) * 90,
+		Flip:       b&videoOrientationFlip != 0,
+		BackCamera: b&videoOrientationCamera != 0,
+	}
+}
+
+// VideoOrientation returns the orientation of the last packet read that
+// carried one, when the remote negotiated urn:3gpp:video-orientation for a
+// video Track. The zero VideoOrientation needs no rotation.
+func (t *Track) VideoOrientation() VideoOrientation {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.orientation
+}
+
+// OnVideoOrientation sets an event handler which is invoked when the
+// orientation of the packets read changes
+func (t *Track) OnVideoOrientation(f func(VideoOrientation)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onVideoOrientationHdlr = f
+}
+
+// observeOrientation updates the orientation with the one of a packet read
+func (t *Track) observeOrientation(orientation VideoOrientation) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if orientation == t.orientation {
+		return
+	}
+	t.orientation = orientation
+	if hdlr := t.onVideoOrientationHdlr; hdlr != nil {
+		go hdlr(orientation)
+	}
+}
+
+// SetVideoOrientation makes the sender send o in the last packet of every
+// frame, when the remote negotiated urn:3gpp:video-orientation. The receiver
+// rotates and flips the frames to render them, so the encoder doesn't have
+// to.
+func (r *RTPSender) SetVideoOrientation(o VideoOrientation) error {
+	if o.Rotation%90 != 0 || o.Rotation >= 360 {
+		return ErrInvalidVideoRotation
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orientation = &o
+	return nil
+}
+
+// setVideoOrientationExtensionID makes the sender send its orientation in the
+// header extension with the id the remote negotiated, 0 for none
+func (r *RTPSender) setVideoOrientationExtensionID(id uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orientationExtensionID = id
+}
@@ -0,0 +1,73 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVideoOrientationMarshal(t *testing.T) {
+	for _, o := range []VideoOrientation{
+		{},
+		{Rotation: 90},
+		{Rotation: 180, Flip: true},
+		{Rotation: 270, BackCamera: true},
+	} {
+		assert.Equal(t, o, unmarshalVideoOrientation(o.marshal()))
+	}
+	assert.Equal(t, byte(0x0D), VideoOrientation{Rotation: 90, Flip: true, BackCamera: true}.marshal())
+}
+
+func TestRTPSenderVideoOrientation(t *testing.T) {
+	s := &RTPSender{}
+	assert.Equal(t, ErrInvalidVideoRotation, s.SetVideoOrientation(VideoOrientation{Rotation: 45}))
+	assert.Equal(t, ErrInvalidVideoRotation, s.SetVideoOrientation(VideoOrientation{Rotation: 360}))
+
+	header := &rtp.Header{Version: 2, SSRC: 1000, Marker: true}
+	assert.NoError(t, s.SetVideoOrientation(VideoOrientation{Rotation: 90}))
+	assert.Equal(t, header, s.withHeaderExtensions(header), "nothing negotiated")
+
+	s.setVideoOrientationExtensionID(6)
+	assert.Equal(t, []byte{0x01}, s.withHeaderExtensions(header).GetExtension(6))
+	assert.False(t, header.Extension, "the header of the Track is shared")
+
+	middle := &rtp.Header{Version: 2, SSRC: 1000}
+	assert.Equal(t, middle, s.withHeaderExtensions(middle), "only the last packet of a frame")
+}
+
+func TestTrackVideoOrientation(t *testing.T) {
+	track := &Track{kind: RTPCodecTypeVideo, headerExtensions: []RTPHeaderExtensionParameter{{URI: videoOrientationURI, ID: 6}}}
+	changed := make(chan VideoOrientation, 1)
+	track.OnVideoOrientation(func(o VideoOrientation) { changed <- o })
+
+	marshal := func(orientation *VideoOrientation) []byte {
+		p := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 1000}, Payload: []byte{0x00}}
+		if orientation != nil {
+			assert.NoError(t, p.SetExtension(6, []byte{orientation.marshal()}))
+		}
+		b, err := p.Marshal()
+		assert.NoError(t, err)
+		return b
+	}
+
+	track.observeHeaderExtensions(marshal(nil))
+	assert.Equal(t, VideoOrientation{}, track.VideoOrientation())
+
+	rotated := VideoOrientation{Rotation: 270, BackCamera: true}
+	track.observeHeaderExtensions(marshal(&rotated))
+	assert.Equal(t, rotated, track.VideoOrientation())
+	select {
+	case o := <-changed:
+		assert.Equal(t, rotated, o)
+	case <-time.After(time.Second):
+		assert.Fail(t, "OnVideoOrientation wasn't called")
+	}
+
+	// Packets without the extension keep the orientation
+	track.observeHeaderExtensions(marshal(nil))
+	assert.Equal(t, rotated, track.VideoOrientation())
+}
@@ -0,0 +1,139 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/transport/test"
+	"github.com/pion/transport/vnet"
+	"github.com/stretchr/testify/assert"
+)
+
+// vnetConditions describe the links of the virtual network of newVNetPair
+type vnetConditions struct {
+	delay  time.Duration
+	jitter time.Duration
+	loss   float64 // probability a chunk crossing the WAN is dropped
+
+	// offererNAT puts the offerer behind a NAT on a LAN of its own
+	offererNAT *vnet.NATType
+}
+
+// newVNetPair creates two PeerConnections on a virtual network
+//
+//	+-----------------------------------------+
+//	|        wan:vnet.Router 1.2.3.0/24       |  delay, jitter, loss
+//	+-------------+-------------------+-------+
+//	              |                   |
+//	+-------------+-------+           |
+//	| lan:vnet.Router     |  natType  |
+//	| 10.0.0.0/24         |           |
+//	+-------------+-------+           |
+//	              |                   |
+//	+-------------+-------+ +---------+-----------+
+//	| offerer 10.0.0.1    | | answerer 1.2.3.5    |
+//	+---------------------+ +---------------------+
+func newVNetPair(t *testing.T, conditions vnetConditions) (pcOffer, pcAnswer *PeerConnection, wan *vnet.Router) {
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	wan, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "1.2.3.0/24",
+		MinDelay:      conditions.delay,
+		MaxJitter:     conditions.jitter,
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+
+	if conditions.loss > 0 {
+		var mu sync.Mutex
+		random := rand.New(rand.NewSource(1)) // nolint: gosec
+		wan.AddChunkFilter(func(vnet.Chunk) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return random.Float64() >= conditions.loss
+		})
+	}
+
+	offerSettingEngine := SettingEngine{LoggerFactory: loggerFactory}
+	offerVNet := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"1.2.3.4"}})
+	if conditions.offererNAT != nil {
+		lan, lanErr := vnet.NewRouter(&vnet.RouterConfig{
+			CIDR:          "10.0.0.0/24",
+			StaticIPs:     []string{"1.2.3.4/10.0.0.1"},
+			NATType:       conditions.offererNAT,
+			LoggerFactory: loggerFactory,
+		})
+		assert.NoError(t, lanErr)
+		assert.NoError(t, wan.AddRouter(lan))
+
+		offerVNet = vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"10.0.0.1"}})
+		assert.NoError(t, lan.AddNet(offerVNet))
+		offerSettingEngine.SetNAT1To1IPs([]string{"1.2.3.4"}, ICECandidateTypeHost)
+	} else {
+		assert.NoError(t, wan.AddNet(offerVNet))
+	}
+	offerSettingEngine.SetVNet(offerVNet)
+
+	answerSettingEngine := SettingEngine{LoggerFactory: loggerFactory}
+	answerVNet := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"1.2.3.5"}})
+	assert.NoError(t, wan.AddNet(answerVNet))
+	answerSettingEngine.SetVNet(answerVNet)
+
+	assert.NoError(t, wan.Start())
+
+	pcOffer, err = NewAPI(WithSettingEngine(offerSettingEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err = NewAPI(WithSettingEngine(answerSettingEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	return pcOffer, pcAnswer, wan
+}
+
+func TestVNet_DataChannel(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for _, tc := range []struct {
+		name       string
+		conditions vnetConditions
+	}{
+		{"Direct", vnetConditions{}},
+		{"DelayAndJitter", vnetConditions{delay: 20 * time.Millisecond, jitter: 10 * time.Millisecond}},
+		{"Loss", vnetConditions{loss: 0.1}},
+		{"NAT1To1", vnetConditions{offererNAT: &vnet.NATType{Mode: vnet.NATModeNAT1To1}}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			pcOffer, pcAnswer, wan := newVNetPair(t, tc.conditions)
+
+			received := make(chan struct{})
+			pcAnswer.OnDataChannel(func(d *DataChannel) {
+				d.OnMessage(func(msg DataChannelMessage) {
+					if string(msg.Data) == "ping" {
+						close(received)
+					}
+				})
+			})
+
+			dc, err := pcOffer.CreateDataChannel("data", nil)
+			assert.NoError(t, err)
+			dc.OnOpen(func() {
+				assert.NoError(t, dc.SendText("ping"))
+			})
+
+			assert.NoError(t, signalPair(pcOffer, pcAnswer))
+			<-received
+
+			closePairNow(t, pcOffer, pcAnswer)
+			assert.NoError(t, wan.Stop())
+		})
+	}
+}